@@ -4,17 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	nriapi "github.com/containerd/nri/pkg/api"
+	netattdefv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	"github.com/urfave/cli/v2"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
@@ -23,6 +35,7 @@ import (
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/driver"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/nri"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
@@ -44,11 +57,17 @@ func newApp() *cli.App {
 	cliFlags := []cli.Flag{
 		&cli.StringFlag{
 			Name:        "node-name",
-			Usage:       "The name of the node to be worked on.",
-			Required:    true,
+			Usage:       "The name of the node to be worked on. Required to run the driver.",
 			Destination: &flagsOptions.NodeName,
 			EnvVars:     []string{"NODE_NAME"},
 		},
+		&cli.StringFlag{
+			Name:        "driver-name",
+			Usage:       "Name the driver registers with. It is the CDI vendor of the devices and prefixes the device attributes, so several instances of the driver can run side by side. Must be a DNS subdomain.",
+			Value:       consts.DefaultDriverName,
+			Destination: &flagsOptions.DriverName,
+			EnvVars:     []string{"DRIVER_NAME"},
+		},
 		&cli.StringFlag{
 			Name:        "cdi-root",
 			Usage:       "Absolute path to the directory where CDI files will be generated.",
@@ -77,6 +96,12 @@ func newApp() *cli.App {
 			Destination: &flagsOptions.HealthcheckPort,
 			EnvVars:     []string{"HEALTHCHECK_PORT"},
 		},
+		&cli.StringSliceFlag{
+			Name:    "healthcheck-probes",
+			Usage:   "Probes run by the healthcheck service: kubelet-registration, discovery, cdi, nri, cni-binary. Can be repeated or comma-separated. The liveness service only runs kubelet-registration, the readiness service runs all of them and each probe is also served under its name.",
+			Value:   cli.NewStringSlice(healthcheckProbes()...),
+			EnvVars: []string{"HEALTHCHECK_PROBES"},
+		},
 		&cli.StringFlag{
 			Name:        "default-interface-prefix",
 			Usage:       "Default interface prefix to be used for the virtual functions.",
@@ -98,6 +123,286 @@ func newApp() *cli.App {
 			Destination: &flagsOptions.ConfigurationMode,
 			EnvVars:     []string{"CONFIGURATION_MODE"},
 		},
+		&cli.StringFlag{
+			Name:        "mode",
+			Usage:       "Driver mode: full or inventory. In inventory mode devices are discovered and published but prepare requests are refused.",
+			Value:       string(consts.DriverModeFull),
+			Destination: &flagsOptions.Mode,
+			EnvVars:     []string{"DRIVER_MODE"},
+		},
+		&cli.DurationFlag{
+			Name:        "stale-pod-gc-interval",
+			Usage:       "Interval between garbage collection passes releasing prepared claims of pods that no longer exist on the node, deleted pods being released on the next pass. Zero disables the garbage collection.",
+			Value:       10 * time.Minute,
+			Destination: &flagsOptions.StalePodGCInterval,
+			EnvVars:     []string{"STALE_POD_GC_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:        "allow-shared-claims",
+			Usage:       "Allow preparing claims reserved by several pods. A shared claim is prepared once and its devices are not attached to the pod networks.",
+			Value:       false,
+			Destination: &flagsOptions.AllowSharedClaims,
+			EnvVars:     []string{"ALLOW_SHARED_CLAIMS"},
+		},
+		&cli.DurationFlag{
+			Name:        "cni-check-interval",
+			Usage:       "Interval between CNI CHECK passes verifying the network attachments of prepared devices. Failures are reported as pod events. Zero disables the checks.",
+			Value:       0,
+			Destination: &flagsOptions.CNICheckInterval,
+			EnvVars:     []string{"CNI_CHECK_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "vf-statistics-interval",
+			Usage:       "Interval between publications of the traffic counters of the attached kernel bound VFs in the status of their claim. Zero disables them.",
+			Value:       0,
+			Destination: &flagsOptions.VFStatisticsInterval,
+			EnvVars:     []string{"VF_STATISTICS_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:        "ethtool-metrics",
+			Usage:       "Export the ethtool statistics of the VFs attached to pods as Prometheus metrics on the metrics server, read at scrape time.",
+			Value:       false,
+			Destination: &flagsOptions.EthtoolMetrics,
+			EnvVars:     []string{"ETHTOOL_METRICS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "cni-bin-dir",
+			Usage:   "Directory searched for CNI plugin binaries. Can be repeated or comma-separated, directories are searched in order.",
+			Value:   cli.NewStringSlice(consts.DefaultCNIBinDir),
+			EnvVars: []string{"CNI_BIN_DIR"},
+		},
+		&cli.DurationFlag{
+			Name:        "cni-timeout",
+			Usage:       "Timeout of each CNI ADD, DEL and CHECK operation. A VfConfig can override it with cniTimeout. Zero disables the timeout.",
+			Value:       30 * time.Second,
+			Destination: &flagsOptions.CNITimeout,
+			EnvVars:     []string{"CNI_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:        "cni-attach-workers",
+			Usage:       "Maximum number of devices of a pod attached concurrently with CNI ADD when its sandbox is created. 1 attaches them one at a time.",
+			Value:       4,
+			Destination: &flagsOptions.CNIAttachWorkers,
+			EnvVars:     []string{"CNI_ATTACH_WORKERS"},
+		},
+		&cli.BoolFlag{
+			Name:        "verify-interfaces",
+			Usage:       "Verify after CNI ADD that the interface of each kernel bound VF is in the pod network namespace with the reported MAC address and backed by the VF, failing the sandbox creation otherwise.",
+			Value:       true,
+			Destination: &flagsOptions.VerifyInterfaces,
+			EnvVars:     []string{"VERIFY_INTERFACES"},
+		},
+		&cli.StringFlag{
+			Name:        "dhcp-socket-path",
+			Usage:       "Socket of the CNI DHCP daemon used by NetworkAttachmentDefinitions with dhcp IPAM that don't set daemonSocketPath. The daemon must be running on the node.",
+			Value:       cni.DefaultDHCPSocketPath,
+			Destination: &flagsOptions.DHCPSocketPath,
+			EnvVars:     []string{"DHCP_SOCKET_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "default-netattachdef-namespace",
+			Usage:       "Namespace of the NetworkAttachmentDefinitions of the VfConfigs that don't set netAttachDefNamespace, so they can be hosted in a central namespace. Defaults to the namespace of the claim.",
+			Destination: &flagsOptions.DefaultNetAttachDefNamespace,
+			EnvVars:     []string{"DEFAULT_NETATTACHDEF_NAMESPACE"},
+		},
+		&cli.StringFlag{
+			Name:        "numa-alignment",
+			Usage:       "Handling of containers whose CPUs are not on the NUMA node of their VFs: none, warn (log and emit an event) or pin (restrict their cpuset and memory to the NUMA node of their VFs).",
+			Value:       string(consts.NUMAAlignmentNone),
+			Destination: &flagsOptions.NUMAAlignment,
+			EnvVars:     []string{"NUMA_ALIGNMENT"},
+		},
+		&cli.BoolFlag{
+			Name:        "enable-vfio-noiommu",
+			Usage:       "Load vfio in the unsafe no-IOMMU mode (enable_unsafe_noiommu_mode=1) so vfio-pci works on hosts without an IOMMU, such as VMs used in CI. Devices publish the vfioNoIOMMU attribute. Never use it in production.",
+			Value:       false,
+			Destination: &flagsOptions.EnableVFIONoIOMMU,
+			EnvVars:     []string{"ENABLE_VFIO_NOIOMMU"},
+		},
+		&cli.StringFlag{
+			Name:        "vfio-device-mode",
+			Usage:       "Octal mode of the /dev/vfio devices created in the containers, e.g. 0666 so non-root containers can open the VFIO group. Empty keeps the mode of the host devices. VfConfigs can override it.",
+			Destination: &flagsOptions.VFIODeviceMode,
+			EnvVars:     []string{"VFIO_DEVICE_MODE"},
+		},
+		&cli.IntFlag{
+			Name:        "vfio-device-uid",
+			Usage:       "Owner of the /dev/vfio devices created in the containers. -1 keeps the default of the runtime. VfConfigs can override it.",
+			Value:       -1,
+			Destination: &flagsOptions.VFIODeviceUID,
+			EnvVars:     []string{"VFIO_DEVICE_UID"},
+		},
+		&cli.IntFlag{
+			Name:        "vfio-device-gid",
+			Usage:       "Group of the /dev/vfio devices created in the containers. -1 keeps the default of the runtime. VfConfigs can override it.",
+			Value:       -1,
+			Destination: &flagsOptions.VFIODeviceGID,
+			EnvVars:     []string{"VFIO_DEVICE_GID"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "allowed-vf-drivers",
+			Usage:   "Drivers a VfConfig may bind VFs to, on top of the default kernel driver of the VF. Can be repeated or comma-separated. Claim configs are user-controlled, so only list drivers safe to hand to workloads.",
+			Value:   cli.NewStringSlice(consts.DefaultAllowedVFDrivers...),
+			EnvVars: []string{"ALLOWED_VF_DRIVERS"},
+		},
+		&cli.IntFlag{
+			Name:        "sysfs-write-retries",
+			Usage:       "Maximum number of attempts of the driver bind, unbind and probe sysfs writes failing with EBUSY or EAGAIN, e.g. while udev or the PF driver is touching the VF. Attempts are spaced with an exponential backoff from 100ms up to 2s.",
+			Value:       host.DefaultConfig().SysfsWriteBackoff.Steps,
+			Destination: &flagsOptions.SysfsWriteRetries,
+			EnvVars:     []string{"SYSFS_WRITE_RETRIES"},
+		},
+		&cli.DurationFlag{
+			Name:        "unbind-timeout",
+			Usage:       "Maximum duration of a VF driver unbind. A device whose unbind times out, e.g. with its driver stuck in remove, fails its prepare and is withdrawn from the ResourceSlice until the unbind completes. Zero waits forever.",
+			Value:       host.DefaultConfig().UnbindTimeout,
+			Destination: &flagsOptions.UnbindTimeout,
+			EnvVars:     []string{"UNBIND_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "reset-vf-on-unprepare",
+			Usage:       "Issue a function-level reset of the VFs of a claim when it is unprepared, so the next consumer gets them in a clean state even if the previous application crashed mid-configuration.",
+			Value:       false,
+			Destination: &flagsOptions.ResetVFOnUnprepare,
+			EnvVars:     []string{"RESET_VF_ON_UNPREPARE"},
+		},
+		&cli.DurationFlag{
+			Name:        "nri-registration-timeout",
+			Usage:       "NRI plugin registration timeout required by the driver. The runtime sets the actual timeout (containerd plugin_registration_timeout), a warning is logged when it is shorter. Zero disables the check.",
+			Value:       nriapi.DefaultPluginRegistrationTimeout,
+			Destination: &flagsOptions.NRIRegistrationTimeout,
+			EnvVars:     []string{"NRI_REGISTRATION_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:        "nri-request-timeout",
+			Usage:       "NRI plugin request timeout required by the driver, which must cover CNI ADD of all the devices of a pod in RunPodSandbox. The runtime sets the actual timeout (containerd plugin_request_timeout), a warning is logged when it is shorter. Zero disables the check.",
+			Value:       nriapi.DefaultPluginRequestTimeout,
+			Destination: &flagsOptions.NRIRequestTimeout,
+			EnvVars:     []string{"NRI_REQUEST_TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:        "attribute-schema",
+			Usage:       "Naming scheme of the published device attributes: v1 (original names), v2 (consistent names) or v1+v2 (both, to migrate DeviceClasses without downtime).",
+			Value:       string(consts.AttributeSchemaDual),
+			Destination: &flagsOptions.AttributeSchema,
+			EnvVars:     []string{"ATTRIBUTE_SCHEMA"},
+		},
+		&cli.StringFlag{
+			Name:        "env-templates",
+			Usage:       "JSON list of environment variables added to the containers of every prepared device, on top of the SRIOVNETWORK_* ones. Names and values are Go templates rendered with the device and claim, e.g. [{\"name\": \"PCIDEVICE_{{ envName .ResourceName }}\", \"value\": \"{{ .PCIAddress }}\"}].",
+			Destination: &flagsOptions.EnvTemplates,
+			EnvVars:     []string{"ENV_TEMPLATES"},
+		},
+		&cli.BoolFlag{
+			Name:        "exclude-primary-pfs",
+			Usage:       "Do not publish the VFs of the PFs carrying the default route or a node IP of the node, so workloads cannot take over the VFs of the management NIC. Disable only when the primary network does not use SR-IOV PFs the driver discovers.",
+			Value:       true,
+			Destination: &flagsOptions.ExcludePrimaryPFs,
+			EnvVars:     []string{"EXCLUDE_PRIMARY_PFS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "node-ip",
+			Usage:   "IP addresses of the node, e.g. the kubelet node IP, whose interfaces are excluded with --exclude-primary-pfs on top of the ones of the default routes. Can be repeated or comma-separated.",
+			EnvVars: []string{"NODE_IP"},
+		},
+		&cli.StringFlag{
+			Name:        "device-naming-scheme",
+			Usage:       "Naming scheme of the published devices: pci-address-dashes (e.g. 0000-08-00-2), pfname-vfid (e.g. ens1f0-vf2), stable-uuid (derived from the MAC address of the PF and the VF index) or serial-uuid (the uuid attribute, derived from the VPD serial number of the NIC, the PCI function of the PF and the VF index). The latter three survive PCI re-enumeration across reboots. Change it only on nodes without prepared claims.",
+			Value:       string(consts.DeviceNamingSchemePCIAddress),
+			Destination: &flagsOptions.DeviceNamingScheme,
+			EnvVars:     []string{"DEVICE_NAMING_SCHEME"},
+		},
+		&cli.StringFlag{
+			Name:        "sriov-operator-coexistence",
+			Usage:       "Behavior on nodes whose PFs are also managed by sriov-network-operator, found in the SriovNetworkNodeState of the node: ignore, validate (publish their VFs, report differences with the operator spec and refuse VfConfig drivers against it) or defer (leave these PFs to the operator and do not publish their VFs).",
+			Value:       string(consts.SriovOperatorCoexistenceValidate),
+			Destination: &flagsOptions.SriovOperatorCoexistence,
+			EnvVars:     []string{"SRIOV_OPERATOR_COEXISTENCE"},
+		},
+		&cli.StringFlag{
+			Name:        "device-plugin-checkpoint",
+			Usage:       "Checkpoint of the kubelet device manager, e.g. /var/lib/kubelet/device-plugins/kubelet_internal_checkpoint. When set, the VFs allocated to pods by the SR-IOV device plugin are not published, for migrations from the device plugin to DRA on the same nodes.",
+			Destination: &flagsOptions.DevicePluginCheckpoint,
+			EnvVars:     []string{"DEVICE_PLUGIN_CHECKPOINT"},
+		},
+		&cli.StringFlag{
+			Name:        "dpu-agent-endpoint",
+			Usage:       "gRPC endpoint of the agent running on the ARM cores of the DPU of the node, e.g. 192.168.100.2:50051 or unix:///var/run/dpu-agent.sock. When set, the driver runs in the DPU split-driver mode: it prepares the VFs on the host (VFIO, CDI) and delegates the programming of their representors and of the eswitch to the agent.",
+			Destination: &flagsOptions.DPUAgentEndpoint,
+			EnvVars:     []string{"DPU_AGENT_ENDPOINT"},
+		},
+		&cli.StringFlag{
+			Name:        "ovsdb-endpoint",
+			Usage:       "Endpoint of the database server of the Open vSwitch of the node, e.g. unix:/var/run/openvswitch/db.sock or tcp:127.0.0.1:6640. Required by the VfConfigs plugging the representors of switchdev VFs into OVS bridges (ovsBridge).",
+			Destination: &flagsOptions.OVSDBEndpoint,
+			EnvVars:     []string{"OVSDB_ENDPOINT"},
+		},
+		&cli.BoolFlag{
+			Name:        "node-labels",
+			Usage:       "Label the node with a summary of the discovered devices (whether it has VFs, their number overall and by PCI vendor ID, RDMA and switchdev capabilities), for schedulers and autoscalers that cannot read ResourceSlices. The labels are prefixed with the driver name.",
+			Value:       false,
+			Destination: &flagsOptions.NodeLabels,
+			EnvVars:     []string{"NODE_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:        "extended-resources",
+			Usage:       "Also publish the resourceName attributes set by SriovResourcePolicies as extended resources in the capacity of the node, counting the advertised devices not prepared for claims, so pods requesting them in resources.limits keep scheduling during a migration to DRA.",
+			Value:       false,
+			Destination: &flagsOptions.ExtendedResources,
+			EnvVars:     []string{"EXTENDED_RESOURCES"},
+		},
+		&cli.StringFlag{
+			Name:        "extended-resource-prefix",
+			Usage:       "Prefix of the extended resources published for resource names without one, see --extended-resources.",
+			Value:       consts.DefaultExtendedResourcePrefix,
+			Destination: &flagsOptions.ExtendedResourcePrefix,
+			EnvVars:     []string{"EXTENDED_RESOURCE_PREFIX"},
+		},
+		&cli.StringFlag{
+			Name:        "vhost-user-socket-root",
+			Usage:       "Host directory holding the vhost-user socket directory of each pod whose VfConfig sets vhostUserSocketDir, named after the pod UID.",
+			Value:       consts.DefaultVhostUserSocketRoot,
+			Destination: &flagsOptions.VhostUserSocketRoot,
+			EnvVars:     []string{"VHOST_USER_SOCKET_ROOT"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "excluded-devices",
+			Usage:   "Devices never published, e.g. reserved by other agents of the node for storage offload or OVN: PCI addresses of VFs, PCI addresses of PFs or PF netdev names, excluding all their VFs. Can be repeated or comma-separated.",
+			EnvVars: []string{"EXCLUDED_DEVICES"},
+		},
+		&cli.StringFlag{
+			Name:        "pod-uid",
+			Usage:       "UID of the driver pod. When set, a new driver pod registers with the kubelet while the previous one is still running, so devices stay published during upgrades. Requires Kubernetes 1.33 or newer.",
+			Destination: &flagsOptions.PodUID,
+			EnvVars:     []string{"POD_UID"},
+		},
+		&cli.DurationFlag{
+			Name:        "shutdown-timeout",
+			Usage:       "Maximum time waited on SIGTERM for the prepares, unprepares and CNI operations in progress to complete before stopping the NRI and kubelet plugins. New prepares are refused meanwhile. Keep it below the termination grace period of the pod. Zero does not wait.",
+			Value:       20 * time.Second,
+			Destination: &flagsOptions.ShutdownTimeout,
+			EnvVars:     []string{"SHUTDOWN_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "enable-debug-endpoints",
+			Usage:       "Serve debug endpoints, such as the list of prepared claims, on the metrics server.",
+			Value:       false,
+			Destination: &flagsOptions.EnableDebugEndpoints,
+			EnvVars:     []string{"ENABLE_DEBUG_ENDPOINTS"},
+		},
+		&cli.StringFlag{
+			Name:        "debug-socket",
+			Usage:       "Unix socket, only accessible to root, serving the state of the driver to the state subcommand. Empty disables it.",
+			Value:       consts.DefaultDebugSocketPath,
+			Destination: &flagsOptions.DebugSocketPath,
+			EnvVars:     []string{"DEBUG_SOCKET"},
+		},
+		&cli.StringFlag{
+			Name:        "pprof-bind-address",
+			Usage:       "Address, e.g. 127.0.0.1:6060, to serve the net/http/pprof profiles of the driver on. Empty disables profiling.",
+			Destination: &flagsOptions.PprofBindAddress,
+			EnvVars:     []string{"PPROF_BIND_ADDRESS"},
+		},
 	}
 	cliFlags = append(cliFlags, flagsOptions.KubeClientConfig.Flags()...)
 	cliFlags = append(cliFlags, flagsOptions.LoggingConfig.Flags()...)
@@ -109,12 +414,25 @@ func newApp() *cli.App {
 		HideHelpCommand: true,
 		Flags:           cliFlags,
 		Before: func(c *cli.Context) error {
-			if c.Args().Len() > 0 {
-				return fmt.Errorf("arguments not supported: %v", c.Args().Slice())
+			if err := validateDriverName(flagsOptions.DriverName); err != nil {
+				return err
 			}
+			consts.SetDriverName(flagsOptions.DriverName)
 			return flagsOptions.LoggingConfig.Apply()
 		},
+		Commands: []*cli.Command{newStateCommand(), newValidateFilterCommand()},
 		Action: func(c *cli.Context) error {
+			if c.Args().Len() > 0 {
+				return fmt.Errorf("arguments not supported: %v", c.Args().Slice())
+			}
+			// not a required flag, the subcommands do not need it
+			if flagsOptions.NodeName == "" {
+				return fmt.Errorf("node-name is required")
+			}
+			// only the plugin uses its flags, the subcommands must run with any of them
+			if err := validatePluginFlags(c, flagsOptions); err != nil {
+				return err
+			}
 			ctx := c.Context
 			clientSets, err := flagsOptions.KubeClientConfig.NewClientSets()
 			if err != nil {
@@ -133,6 +451,147 @@ func newApp() *cli.App {
 	return app
 }
 
+// validatePluginFlags validates the flags of the plugin command and reads its slice flags.
+func validatePluginFlags(c *cli.Context, flagsOptions *types.Flags) error {
+	if err := validateDriverMode(flagsOptions.Mode); err != nil {
+		return err
+	}
+	if err := devicestate.ValidateAttributeSchema(flagsOptions.AttributeSchema); err != nil {
+		return err
+	}
+	if err := devicestate.ValidateDeviceNamingScheme(flagsOptions.DeviceNamingScheme); err != nil {
+		return err
+	}
+	if err := devicestate.ValidateSriovOperatorCoexistence(flagsOptions.SriovOperatorCoexistence); err != nil {
+		return err
+	}
+	if err := nri.ValidateNUMAAlignment(flagsOptions.NUMAAlignment); err != nil {
+		return err
+	}
+	if flagsOptions.CNIAttachWorkers < 1 {
+		return fmt.Errorf("cni-attach-workers must be at least 1")
+	}
+	if flagsOptions.NRIRegistrationTimeout < 0 || flagsOptions.NRIRequestTimeout < 0 {
+		return fmt.Errorf("NRI timeouts must not be negative")
+	}
+	if _, err := devicestate.VFIODevicePermissionsFromFlags(flagsOptions); err != nil {
+		return err
+	}
+	if _, err := devicestate.ParseEnvTemplates(flagsOptions.EnvTemplates); err != nil {
+		return err
+	}
+	if flagsOptions.SysfsWriteRetries < 1 {
+		return fmt.Errorf("sysfs-write-retries must be at least 1")
+	}
+	if flagsOptions.UnbindTimeout < 0 {
+		return fmt.Errorf("unbind-timeout must not be negative")
+	}
+	if !filepath.IsAbs(flagsOptions.VhostUserSocketRoot) {
+		return fmt.Errorf("vhost-user-socket-root must be an absolute path")
+	}
+	if flagsOptions.DevicePluginCheckpoint != "" && !filepath.IsAbs(flagsOptions.DevicePluginCheckpoint) {
+		return fmt.Errorf("device-plugin-checkpoint must be an absolute path")
+	}
+	if flagsOptions.DebugSocketPath != "" && !filepath.IsAbs(flagsOptions.DebugSocketPath) {
+		return fmt.Errorf("debug-socket must be an absolute path")
+	}
+	if flagsOptions.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown-timeout must not be negative")
+	}
+	flagsOptions.HealthcheckProbes = c.StringSlice("healthcheck-probes")
+	if err := driver.ValidateHealthcheckProbes(flagsOptions.HealthcheckProbes); err != nil {
+		return err
+	}
+	if flagsOptions.ExtendedResources {
+		if err := driver.ValidateExtendedResourcePrefix(flagsOptions.ExtendedResourcePrefix); err != nil {
+			return err
+		}
+	}
+	flagsOptions.AllowedVFDrivers = c.StringSlice("allowed-vf-drivers")
+	flagsOptions.ExcludedDevices = c.StringSlice("excluded-devices")
+	flagsOptions.NodeIPs = c.StringSlice("node-ip")
+	for _, nodeIP := range flagsOptions.NodeIPs {
+		if net.ParseIP(nodeIP) == nil {
+			return fmt.Errorf("invalid node-ip %q", nodeIP)
+		}
+	}
+	flagsOptions.CNIBinDirs = c.StringSlice("cni-bin-dir")
+	if len(flagsOptions.CNIBinDirs) == 0 {
+		return fmt.Errorf("at least one CNI bin directory is required")
+	}
+	return nil
+}
+
+// watchNetAttachDefs adds NetworkAttachmentDefinitions to the controller manager cache and
+// makes the device state manager read them from it, keeping the prepare latency and the API
+// server load flat with the pod churn. Clusters without the NetworkAttachmentDefinition CRD,
+// using inline CNI configs only, keep reading them from the API server.
+func watchNetAttachDefs(ctx context.Context, mgr ctrl.Manager, deviceStateManager *devicestate.Manager) error {
+	logger := klog.FromContext(ctx)
+	netAttachDef := &netattdefv1.NetworkAttachmentDefinition{}
+	gvk, err := apiutil.GVKForObject(netAttachDef, mgr.GetScheme())
+	if err != nil {
+		return fmt.Errorf("failed to get NetworkAttachmentDefinition kind: %w", err)
+	}
+	if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			logger.Info("NetworkAttachmentDefinition CRD not installed, not caching NetworkAttachmentDefinitions")
+			return nil
+		}
+		return fmt.Errorf("failed to get NetworkAttachmentDefinition mapping: %w", err)
+	}
+	if _, err := mgr.GetCache().GetInformer(ctx, netAttachDef); err != nil {
+		return fmt.Errorf("failed to create NetworkAttachmentDefinition informer: %w", err)
+	}
+	deviceStateManager.SetNetAttachDefReader(mgr.GetCache())
+	return nil
+}
+
+// validateDriverName checks that the driver name is a DNS subdomain, as required for the driver
+// of a ResourceSlice and the attribute prefixes.
+func validateDriverName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid driver-name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// healthcheckProbes returns the names of all the probes of the healthcheck service.
+func healthcheckProbes() []string {
+	probes := make([]string, 0, len(consts.HealthcheckProbes))
+	for _, probe := range consts.HealthcheckProbes {
+		probes = append(probes, string(probe))
+	}
+	return probes
+}
+
+// validateDriverMode checks that the requested driver mode is supported.
+func validateDriverMode(mode string) error {
+	switch consts.DriverMode(mode) {
+	case consts.DriverModeFull, consts.DriverModeInventory:
+		return nil
+	default:
+		return fmt.Errorf("unsupported driver mode %q, expected %q or %q", mode, consts.DriverModeFull, consts.DriverModeInventory)
+	}
+}
+
+// drainPlugins refuses new prepares and sandbox attachments, then waits up to timeout for the
+// prepares, unprepares and CNI operations in progress to complete, so the plugins are not stopped
+// in the middle of them.
+func drainPlugins(logger klog.Logger, timeout time.Duration, dvr *driver.Driver, nriPlugin *nri.Plugin) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	logger.Info("Waiting for the operations in progress to complete", "timeout", timeout)
+	if err := dvr.Drain(ctx); err != nil {
+		logger.Error(err, "Prepares and unprepares still in progress, shutting down anyway")
+	}
+	if nriPlugin != nil {
+		if err := nriPlugin.Drain(ctx); err != nil {
+			logger.Error(err, "CNI operations still in progress, shutting down anyway")
+		}
+	}
+}
+
 // RunPlugin initializes and runs the sriov DRA plugin stack.
 func RunPlugin(ctx context.Context, config *types.Config) error {
 	// set the loggers
@@ -167,6 +626,20 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		return fmt.Errorf("unable to create CDI handler: %v", err)
 	}
 
+	hostConfig := host.DefaultConfig()
+	hostConfig.SysfsWriteBackoff.Steps = config.Flags.SysfsWriteRetries
+	hostConfig.UnbindTimeout = config.Flags.UnbindTimeout
+	if err := host.InitHelpers(hostConfig); err != nil {
+		return fmt.Errorf("unable to configure host helpers: %w", err)
+	}
+
+	if config.Flags.EnableVFIONoIOMMU {
+		klog.Info("Enabling the unsafe vfio no-IOMMU mode, devices bound to vfio-pci get no DMA protection")
+		if err := host.GetHelpers().EnableVFIONoIOMMU(); err != nil {
+			return fmt.Errorf("unable to enable vfio no-IOMMU mode: %w", err)
+		}
+	}
+
 	// create device state manager
 	deviceStateManager, err := devicestate.NewManager(config, cdiHandler, devicestate.NewDeviceInfoStore())
 	if err != nil {
@@ -187,6 +660,13 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 
 	// Set up the republish callback so the device state manager can trigger resource republishing
 	deviceStateManager.SetRepublishCallback(dvr.PublishResources)
+	go deviceStateManager.WatchDevicePluginCheckpoint(ctx)
+
+	if config.Flags.DebugSocketPath != "" {
+		if err := dvr.ServeDebugSocket(ctx, config.Flags.DebugSocketPath); err != nil {
+			return err
+		}
+	}
 
 	// create controller manager
 	restConfig, err := config.Flags.KubeClientConfig.NewClientSetConfig()
@@ -208,15 +688,32 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		},
 	}
 
+	metricsOpts := metricsserver.Options{}
+	if config.Flags.EnableDebugEndpoints {
+		metricsOpts.ExtraHandlers = map[string]http.Handler{
+			consts.DebugPreparedClaimsPath: podManager.DebugHandler(),
+		}
+	}
+
 	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
-		Scheme: flags.Scheme,
-		Logger: logger,
-		Cache:  cacheOpts,
+		Scheme:  flags.Scheme,
+		Logger:  logger,
+		Cache:   cacheOpts,
+		Metrics: metricsOpts,
+		// the profiles are served by the manager, see --pprof-bind-address
+		PprofBindAddress: config.Flags.PprofBindAddress,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create controller manager: %w", err)
 	}
 
+	// serve the net attach defs read on prepare from the manager cache
+	if !config.IsInventoryMode() && consts.ConfigurationMode(config.Flags.ConfigurationMode) != consts.ConfigurationModeMultus {
+		if err := watchNetAttachDefs(ctx, mgr, deviceStateManager); err != nil {
+			return err
+		}
+	}
+
 	// create and setup resource policy controller
 	resourcePolicyController := controller.NewSriovResourcePolicyReconciler(config.K8sClient.Client, config.Flags.NodeName, config.Flags.Namespace, deviceStateManager)
 	if err := resourcePolicyController.SetupWithManager(mgr); err != nil {
@@ -242,22 +739,51 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 	logger.Info("Cache synced")
 
 	// create cni runtime
-	cniRuntime := cni.New(consts.DriverName, []string{"/opt/cni/bin"})
+	cniRuntime := cni.New(consts.DriverName, config.Flags.CNIBinDirs, config.Flags.CNITimeout, config.Flags.DHCPSocketPath)
 
-	// register to NRI unless MULTUS mode is set
+	// register to NRI unless inventory mode is set, once the previous driver instance stopped
+	// during rolling updates, so a single NRI plugin handles the pods
 	var nriPlugin *nri.Plugin
-	if consts.ConfigurationMode(config.Flags.ConfigurationMode) != consts.ConfigurationModeMultus {
+	switch {
+	case config.IsInventoryMode():
+		logger.Info("NRI plugin disabled due to inventory driver mode")
+	case dvr.WaitForNodeTasks(ctx) != nil:
+		logger.Info("NRI plugin not started, the driver stopped before the previous instance")
+	case consts.ConfigurationMode(config.Flags.ConfigurationMode) == consts.ConfigurationModeMultus:
+		// Multus attaches the networks, the NRI plugin only narrows the devices of the containers
+		nriPlugin, err = nri.NewNRIPlugin(config, podManager, cniRuntime)
+		if err == nil {
+			err = nriPlugin.Start(ctx)
+		}
+		if err != nil {
+			// NRI is optional in MULTUS mode, the containers then get the devices of the whole pod
+			logger.Error(err, "NRI plugin not started, containers are not narrowed to their devices in MULTUS configuration mode")
+			nriPlugin = nil
+			break
+		}
+		dvr.SetHealthcheckProbe(consts.HealthcheckProbeNRI, nriPlugin.Status)
+		logger.Info("NRI plugin started for the containers in MULTUS configuration mode")
+	default:
 		nriPlugin, err = nri.NewNRIPlugin(config, podManager, cniRuntime)
 		if err != nil {
 			return fmt.Errorf("failed to create NRI plugin: %w", err)
 		}
+		// restore the devices of pods that disappeared while their networks were attached
+		nriPlugin.SetStalePodCallback(dvr.ReleaseStalePod)
+		// report not-ready while the sriov CNI plugin cannot attach devices
+		if err := cniRuntime.Status(ctx); err != nil {
+			logger.Error(err, "CNI plugins are not ready, networks cannot be attached")
+		}
+		dvr.SetHealthcheckProbe(consts.HealthcheckProbeCNIBinary, cniRuntime.Status)
+		if config.Flags.EthtoolMetrics {
+			ctrlmetrics.Registry.MustRegister(nriPlugin.EthtoolCollector())
+		}
 		err = nriPlugin.Start(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to start NRI plugin: %w", err)
 		}
+		dvr.SetHealthcheckProbe(consts.HealthcheckProbeNRI, nriPlugin.Status)
 		logger.Info("NRI plugin started")
-	} else {
-		logger.Info("NRI plugin disabled due to MULTUS configuration mode")
 	}
 
 	<-ctx.Done()
@@ -270,6 +796,10 @@ func RunPlugin(ctx context.Context, config *types.Config) error {
 		logger.Error(err, "error from context")
 	}
 	logger.V(1).Info("Shutting down")
+	drainPlugins(logger, config.Flags.ShutdownTimeout, dvr, nriPlugin)
+	if err := podManager.Flush(); err != nil {
+		logger.Error(err, "Unable to flush the checkpoint")
+	}
 	if nriPlugin != nil {
 		nriPlugin.Stop()
 	}
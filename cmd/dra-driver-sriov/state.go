@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/driver"
+)
+
+// newStateCommand returns the state subcommand, printing as JSON the discovered devices, the
+// resource filter applied by the SriovResourcePolicies, the prepared claims and the CDI specs of
+// the driver running on the node, read from its debug socket.
+func newStateCommand() *cli.Command {
+	var socketPath string
+	return &cli.Command{
+		Name:      "state",
+		Usage:     "Print the state of the driver running on the node as JSON, for debugging.",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "debug-socket",
+				Usage:       "Debug socket of the running driver.",
+				Value:       consts.DefaultDebugSocketPath,
+				Destination: &socketPath,
+				EnvVars:     []string{"DEBUG_SOCKET"},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() > 0 {
+				return fmt.Errorf("arguments not supported: %v", c.Args().Slice())
+			}
+			state, err := driver.GetDebugState(c.Context, socketPath)
+			if err != nil {
+				return err
+			}
+			_, err = c.App.Writer.Write(state)
+			return err
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/controller"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+)
+
+// newValidateFilterCommand returns the validate-filter subcommand, matching the
+// SriovResourcePolicies of a manifest against the devices of the host, or of an inventory file,
+// and printing as JSON which devices would be advertised with which resource names.
+func newValidateFilterCommand() *cli.Command {
+	var policiesPath, inventoryPath string
+	return &cli.Command{
+		Name:      "validate-filter",
+		Usage:     "Print which devices the SriovResourcePolicies of a manifest would advertise, with their resource names, as JSON.",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "policies",
+				Aliases:     []string{"f"},
+				Usage:       "YAML or JSON manifest of the SriovResourcePolicies and DeviceAttributes to validate, - for the standard input.",
+				Required:    true,
+				Destination: &policiesPath,
+			},
+			&cli.StringFlag{
+				Name:        "inventory",
+				Usage:       "JSON file of simulated devices keyed by name, e.g. the devices printed by the state subcommand. Defaults to the devices discovered on the host.",
+				Destination: &inventoryPath,
+			},
+			&cli.StringSliceFlag{
+				Name:  "node-label",
+				Usage: "Label key=value of the node the nodeSelectors of the policies are checked against. Can be repeated or comma-separated. Without labels the nodeSelectors are ignored.",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() > 0 {
+				return fmt.Errorf("arguments not supported: %v", c.Args().Slice())
+			}
+			nodeLabels, err := parseNodeLabels(c.StringSlice("node-label"))
+			if err != nil {
+				return err
+			}
+			policies, deviceAttrs, err := loadPolicies(c.App.Reader, policiesPath)
+			if err != nil {
+				return err
+			}
+			devices, err := loadInventory(inventoryPath)
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(c.App.Writer)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(controller.ValidatePolicies(devices, policies, deviceAttrs, nodeLabels))
+		},
+	}
+}
+
+// loadPolicies reads the policies of a manifest file, or of stdin when the path is -.
+func loadPolicies(stdin io.Reader, path string) ([]sriovdrav1alpha1.SriovResourcePolicy, []sriovdrav1alpha1.DeviceAttributes, error) {
+	reader := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open policies: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+	policies, deviceAttrs, err := controller.LoadPolicies(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load policies from %s: %w", path, err)
+	}
+	return policies, deviceAttrs, nil
+}
+
+// loadInventory reads the devices of an inventory file, or discovers the devices of the host when
+// the path is empty.
+func loadInventory(path string) (types.AllocatableDevices, error) {
+	if path == "" {
+		devices, err := devicestate.DiscoverSriovDevices()
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover devices: %w", err)
+		}
+		return devices, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+	devices := types.AllocatableDevices{}
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	return devices, nil
+}
+
+// parseNodeLabels parses key=value node labels, returning nil without labels.
+func parseNodeLabels(labels []string) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	nodeLabels := make(map[string]string, len(labels))
+	for _, label := range labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid node-label %q, expected key=value", label)
+		}
+		nodeLabels[key] = value
+	}
+	return nodeLabels, nil
+}
@@ -46,24 +46,33 @@ type SriovResourcePolicySpec struct {
 }
 
 // Config pairs a device selection (ResourceFilters) with an optional set of
-// extra attributes to apply (DeviceAttributesSelector). Devices matching the
-// filters are advertised regardless of whether a DeviceAttributesSelector is set.
+// extra attributes to apply (DeviceAttributesSelector and Attributes). Devices
+// matching the filters are advertised regardless of whether extra attributes are set.
 type Config struct {
 	// DeviceAttributesSelector selects DeviceAttributes objects by label.
 	// Attributes from all matching DeviceAttributes are merged and applied
 	// to devices selected by ResourceFilters. Optional.
 	DeviceAttributesSelector *metav1.LabelSelector `json:"deviceAttributesSelector,omitempty"`
-	ResourceFilters          []ResourceFilter      `json:"resourceFilters,omitempty"`
+	// Attributes are static attributes stamped onto the devices selected by
+	// ResourceFilters (e.g. "tier": {"string": "gold"}). They take precedence
+	// over attributes resolved through DeviceAttributesSelector. Optional.
+	Attributes      map[resourceapi.QualifiedName]resourceapi.DeviceAttribute `json:"attributes,omitempty"`
+	ResourceFilters []ResourceFilter                                          `json:"resourceFilters,omitempty"`
 }
 
 // ResourceFilter is a filter for a resource
 type ResourceFilter struct {
 	Vendors        []string `json:"vendors,omitempty"`
 	Devices        []string `json:"devices,omitempty"`
+	PfDevices      []string `json:"pfDevices,omitempty"`
 	PciAddresses   []string `json:"pciAddresses,omitempty"`
 	PfNames        []string `json:"pfNames,omitempty"`
 	PfPciAddresses []string `json:"pfPciAddresses,omitempty"`
 	Drivers        []string `json:"drivers,omitempty"`
+	// EswitchModes are the eswitch modes of the PF of the VFs, legacy or switchdev.
+	EswitchModes []string `json:"eswitchModes,omitempty"`
+	// LinkTypes are the link types of the PF of the VFs, ethernet or infiniband.
+	LinkTypes []string `json:"linkTypes,omitempty"`
 }
 
 // +genclient
@@ -35,6 +35,13 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make(map[resourcev1.QualifiedName]resourcev1.DeviceAttribute, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.ResourceFilters != nil {
 		in, out := &in.ResourceFilters, &out.ResourceFilters
 		*out = make([]ResourceFilter, len(*in))
@@ -147,6 +154,11 @@ func (in *ResourceFilter) DeepCopyInto(out *ResourceFilter) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PfDevices != nil {
+		in, out := &in.PfDevices, &out.PfDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.PciAddresses != nil {
 		in, out := &in.PciAddresses, &out.PciAddresses
 		*out = make([]string, len(*in))
@@ -167,6 +179,16 @@ func (in *ResourceFilter) DeepCopyInto(out *ResourceFilter) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.EswitchModes != nil {
+		in, out := &in.EswitchModes, &out.EswitchModes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LinkTypes != nil {
+		in, out := &in.LinkTypes, &out.LinkTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFilter.
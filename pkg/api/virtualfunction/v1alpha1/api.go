@@ -17,10 +17,15 @@
 package v1alpha1
 
 import (
+	"maps"
+	"path"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/utils/ptr"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 )
@@ -30,6 +35,18 @@ const (
 	Version   = "v1alpha1"
 
 	VfConfigKind = "VfConfig"
+
+	// KubeVirtDriver is the driver of the VFs prepared for KubeVirt.
+	KubeVirtDriver = "vfio-pci"
+
+	// TCOffloadQdiscClsact and TCOffloadQdiscIngress are the qdiscs TC filters are attached to on
+	// the representor of a VF with TCOffload.
+	TCOffloadQdiscClsact  = "clsact"
+	TCOffloadQdiscIngress = "ingress"
+
+	// SysctlIfName is the component of the names of Sysctls replaced by the interface of the VF in
+	// the pod, e.g. net.ipv4.conf.IFNAME.rp_filter.
+	SysctlIfName = "IFNAME"
 )
 
 // Decoder implements a decoder for objects in this API group.
@@ -46,6 +63,221 @@ type VfConfig struct {
 	IfName                string `json:"ifName,omitempty"`
 	NetAttachDefName      string `json:"netAttachDefName,omitempty"`
 	NetAttachDefNamespace string `json:"netAttachDefNamespace,omitempty"`
+	// CNIConfig is a CNI config (single plugin or plugin list) used instead of the config of
+	// NetAttachDefName, for clusters without the NetworkAttachmentDefinition CRD.
+	CNIConfig *runtime.RawExtension `json:"cniConfig,omitempty"`
+	// ChainedNetAttachDefs are NetworkAttachmentDefinitions whose plugins are chained, in order,
+	// after the plugins of NetAttachDefName on the same VF, e.g. a route-override or tuning plugin.
+	ChainedNetAttachDefs []NetAttachDefReference `json:"chainedNetAttachDefs,omitempty"`
+	// IPAM overrides the addressing of the netconf for this claim, so per-claim static
+	// addresses do not need one NetworkAttachmentDefinition per pod.
+	IPAM *IPAMOverride `json:"ipam,omitempty"`
+	// CNITimeout bounds each CNI operation (ADD, DEL, CHECK) on the VF, overriding the driver default.
+	CNITimeout *metav1.Duration `json:"cniTimeout,omitempty"`
+	// AddHugepagesMount mounts the hugepages of the host in the container when the VF is bound to
+	// a DPDK driver. The pod still has to request hugepages resources.
+	AddHugepagesMount bool `json:"addHugepagesMount,omitempty"`
+	// VFIODevicePermissions sets the mode and owner of the /dev/vfio devices of the container,
+	// overriding the driver defaults, so non-root containers can open the VFIO group.
+	VFIODevicePermissions *DevicePermissions `json:"vfioDevicePermissions,omitempty"`
+	// VhostUserSocketDir mounts a directory private to the pod in the container, for the
+	// vhost-user sockets shared with a virtio-user datapath of the host.
+	VhostUserSocketDir *VhostUserSocketDir `json:"vhostUserSocketDir,omitempty"`
+	// Bond bonds the VFs of the request, allocated on different PFs, into a single interface of the
+	// pod named IfName, for NIC redundancy. Only used in STANDALONE mode.
+	Bond *BondConfig `json:"bond,omitempty"`
+	// KubeVirt prepares the VFs for the hostDevices of a KubeVirt VM: they are bound to vfio-pci
+	// and exposed the way virt-launcher expects them. NetAttachDefName becomes optional, without it
+	// the VFs are not attached to the pod networks.
+	KubeVirt *KubeVirtConfig `json:"kubeVirt,omitempty"`
+	// TCOffload enables the TC hardware offload on the representors of switchdev VFs and adds the
+	// qdisc TC filters are attached to, so the flows offloaded by OVS or TC are programmed in the
+	// eswitch.
+	TCOffload *TCOffloadConfig `json:"tcOffload,omitempty"`
+	// OVSBridge plugs the representors of switchdev VFs into a bridge of the Open vSwitch of the
+	// node, in place of an ovs-cni plugin chained after the CNI of the VF.
+	OVSBridge *OVSBridgeConfig `json:"ovsBridge,omitempty"`
+	// InfiniBand assigns the GUIDs of InfiniBand VFs, e.g. the GUIDs registered in the partitions
+	// of the subnet manager. The VFs get their original GUIDs back on unprepare.
+	InfiniBand *InfiniBandConfig `json:"infiniBand,omitempty"`
+	// Env are environment variables added to the containers of the devices, keyed by name. Names
+	// and values are Go templates rendered with the device and the claim like --env-templates.
+	Env map[string]string `json:"env,omitempty"`
+	// NumQueues sets the number of combined channels of the network interface of the VFs, like
+	// ethtool -L combined, for workloads needing a deterministic count of RSS queues. The VFs get
+	// their original count back on unprepare. Only used with kernel drivers.
+	NumQueues *int32 `json:"numQueues,omitempty"`
+	// RingSize sets the sizes of the RX and TX rings of the network interface of the VFs, like
+	// ethtool -G, e.g. larger rings for packet capture workloads. The VFs get their original sizes
+	// back on unprepare. Only used with kernel drivers.
+	RingSize *RingSizeConfig `json:"ringSize,omitempty"`
+	// Offloads turns offload features of the network interface of the VFs on or off, like
+	// ethtool -K, e.g. LRO off for NFV workloads that cannot run privileged to change it. The VFs
+	// get their original features back on unprepare. Only used with kernel drivers.
+	Offloads *OffloadConfig `json:"offloads,omitempty"`
+	// Sysctls are network sysctls set in the network namespace of the pod once the VFs are
+	// attached, keyed by name, e.g. net.ipv4.conf.IFNAME.rp_filter, in place of a tuning CNI
+	// plugin chained after the CNI of the VF. The IFNAME component of the names is replaced by the
+	// interface of the VF in the pod.
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+	// Routes are static routes added through the interface of the VFs in the network namespace of
+	// the pod once they are attached, for multi-homed pods steering traffic over the VFs whatever
+	// their IPAM. Only used with kernel drivers.
+	Routes []RouteConfig `json:"routes,omitempty"`
+	// Neighbors are permanent neighbor entries, e.g. static ARP entries, added to the interface of
+	// the VFs in the network namespace of the pod once they are attached, for provider networks
+	// suppressing ARP. Only used with kernel drivers.
+	Neighbors []NeighborConfig `json:"neighbors,omitempty"`
+}
+
+// NeighborConfig is a permanent neighbor entry of the interface of a VF in the pod.
+type NeighborConfig struct {
+	// IP is the IPv4 or IPv6 address of the neighbor.
+	IP string `json:"ip"`
+	// MAC is the hardware address of the neighbor.
+	MAC string `json:"mac"`
+}
+
+// RouteConfig is a static route through the interface of a VF in the pod.
+type RouteConfig struct {
+	// Dst is the destination of the route in CIDR notation, e.g. 10.10.0.0/16.
+	Dst string `json:"dst"`
+	// GW is the gateway of the route, the destination is reachable on the link without gateway.
+	GW string `json:"gw,omitempty"`
+	// Metric is the metric of the route, the default of the kernel when unset.
+	Metric int32 `json:"metric,omitempty"`
+}
+
+// SysctlPaths returns the Sysctls keyed by their path under /proc/sys for a VF attached as ifName,
+// e.g. net/ipv4/conf/net1/rp_filter. The names are split on dots before IFNAME is replaced, so
+// interfaces with a dot in their name, e.g. VLANs, are kept whole.
+func (c *VfConfig) SysctlPaths(ifName string) map[string]string {
+	paths := make(map[string]string, len(c.Sysctls))
+	for name, value := range c.Sysctls {
+		components := strings.Split(name, ".")
+		for i, component := range components {
+			if component == SysctlIfName {
+				components[i] = ifName
+			}
+		}
+		paths[path.Join(components...)] = value
+	}
+	return paths
+}
+
+// OffloadConfig are the offloads of the network interface of a VF to turn on or off. Offloads
+// without value are left as they are.
+type OffloadConfig struct {
+	// TSO is the TCP segmentation offload, the tx-tcp-segmentation and tx-tcp6-segmentation
+	// features.
+	TSO *bool `json:"tso,omitempty"`
+	// GRO is the generic receive offload, the rx-gro feature.
+	GRO *bool `json:"gro,omitempty"`
+	// LRO is the large receive offload, the rx-lro feature.
+	LRO *bool `json:"lro,omitempty"`
+}
+
+// Features returns the ethtool features of the offloads with a value, keyed by name.
+func (o *OffloadConfig) Features() map[string]bool {
+	features := map[string]bool{}
+	if o.TSO != nil {
+		features["tx-tcp-segmentation"] = *o.TSO
+		features["tx-tcp6-segmentation"] = *o.TSO
+	}
+	if o.GRO != nil {
+		features["rx-gro"] = *o.GRO
+	}
+	if o.LRO != nil {
+		features["rx-lro"] = *o.LRO
+	}
+	return features
+}
+
+// RingSizeConfig are the sizes of the rings of the network interface of a VF, in descriptors. A
+// ring without size keeps its current size.
+type RingSizeConfig struct {
+	RX int32 `json:"rx,omitempty"`
+	TX int32 `json:"tx,omitempty"`
+}
+
+// OVSBridgeConfig is the bridge of Open vSwitch the representor of a switchdev VF is plugged
+// into.
+type OVSBridgeConfig struct {
+	// Bridge is the name of the bridge, which must exist.
+	Bridge string `json:"bridge"`
+}
+
+// InfiniBandConfig are the GUIDs of an InfiniBand VF, in the 8-byte colon-separated format, e.g.
+// 02:00:00:00:00:00:00:01. Either defaults to the other.
+type InfiniBandConfig struct {
+	NodeGUID string `json:"nodeGUID,omitempty"`
+	PortGUID string `json:"portGUID,omitempty"`
+}
+
+// TCOffloadConfig is the TC hardware offload configured on the representor of a switchdev VF.
+type TCOffloadConfig struct {
+	// Qdisc is the qdisc added to the representor, clsact or ingress, defaults to clsact.
+	Qdisc string `json:"qdisc,omitempty"`
+}
+
+// KubeVirtConfig is the KubeVirt prepare mode of the VFs of a request.
+type KubeVirtConfig struct {
+	// ResourceName is the resource name of the hostDevices of the VM, e.g. intel.com/sriov. The
+	// PCI addresses of the VFs are set in the PCI_RESOURCE_<ResourceName> environment variable
+	// read by virt-launcher. Defaults to the resourceName attribute of the VFs.
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+// BondConfig is the kernel bond created in the pod over the VFs of a request by the bond CNI
+// plugin.
+type BondConfig struct {
+	// Mode is the bonding mode, defaults to active-backup.
+	Mode string `json:"mode,omitempty"`
+	// Miimon is the link monitoring interval in milliseconds, defaults to 100.
+	Miimon *int32 `json:"miimon,omitempty"`
+}
+
+// VhostUserSocketDir is a directory created on the host for each pod and mounted in its
+// containers, holding the vhost-user sockets of the pod.
+type VhostUserSocketDir struct {
+	// ContainerPath is where the directory is mounted in the container, defaults to
+	// /var/run/vhost-user.
+	ContainerPath string `json:"containerPath,omitempty"`
+	// Permissions are the mode and owner of the directory, so a non-root container can create its
+	// sockets there. Defaults to 0775 and root ownership.
+	Permissions *DevicePermissions `json:"permissions,omitempty"`
+}
+
+// DevicePermissions are the mode and owner of device files created in a container. Unset fields
+// keep the values of the runtime: the mode of the host device and root ownership.
+type DevicePermissions struct {
+	// FileMode are the permission bits of the device files, e.g. 0660.
+	FileMode *int32  `json:"fileMode,omitempty"`
+	UID      *uint32 `json:"uid,omitempty"`
+	GID      *uint32 `json:"gid,omitempty"`
+}
+
+// NetAttachDefReference references a NetworkAttachmentDefinition.
+type NetAttachDefReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the primary NetworkAttachmentDefinition.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// IPAMOverride holds addressing merged into the IPAM of the netconf before CNI ADD.
+type IPAMOverride struct {
+	// Addresses are static addresses in CIDR notation, setting them switches the IPAM to static.
+	Addresses []string `json:"addresses,omitempty"`
+	// Gateway is the gateway of the addresses of the same IP family.
+	Gateway string `json:"gateway,omitempty"`
+	// Routes are added to the routes of the IPAM.
+	Routes []IPAMRoute `json:"routes,omitempty"`
+}
+
+// IPAMRoute is a route installed by the IPAM plugin.
+type IPAMRoute struct {
+	Dst string `json:"dst"`
+	GW  string `json:"gw,omitempty"`
 }
 
 // DefaultGpuConfig provides the default GPU configuration.
@@ -61,22 +293,109 @@ func DefaultVfConfig() *VfConfig {
 	}
 }
 
-// Override overrides a VfConfig config with another VfConfig config.
+// Override overrides a VfConfig config with the fields set in another VfConfig config, e.g. a
+// config of the DeviceClass with a config of the claim. Unset fields keep their value, so a
+// boolean can be enabled but not disabled by the other config.
 func (c *VfConfig) Override(other *VfConfig) {
 	if other.Driver != "" {
 		c.Driver = other.Driver
 	}
+	if other.AddVhostMount {
+		c.AddVhostMount = true
+	}
+	if other.AddHugepagesMount {
+		c.AddHugepagesMount = true
+	}
+	if other.NetAttachDefNamespace != "" {
+		c.NetAttachDefNamespace = other.NetAttachDefNamespace
+	}
 	if other.IfName != "" {
 		c.IfName = other.IfName
 	}
 	if other.NetAttachDefName != "" {
 		c.NetAttachDefName = other.NetAttachDefName
 	}
+	if other.CNIConfig != nil {
+		c.CNIConfig = other.CNIConfig.DeepCopy()
+	}
+	if len(other.ChainedNetAttachDefs) > 0 {
+		c.ChainedNetAttachDefs = append([]NetAttachDefReference(nil), other.ChainedNetAttachDefs...)
+	}
+	if other.IPAM != nil {
+		c.IPAM = other.IPAM.DeepCopy()
+	}
+	if other.CNITimeout != nil {
+		c.CNITimeout = other.CNITimeout.DeepCopy()
+	}
+	if other.VFIODevicePermissions != nil {
+		c.VFIODevicePermissions = other.VFIODevicePermissions.DeepCopy()
+	}
+	if other.VhostUserSocketDir != nil {
+		c.VhostUserSocketDir = other.VhostUserSocketDir.DeepCopy()
+	}
+	if other.Bond != nil {
+		c.Bond = other.Bond.DeepCopy()
+	}
+	if other.KubeVirt != nil {
+		c.KubeVirt = other.KubeVirt.DeepCopy()
+	}
+	if other.TCOffload != nil {
+		c.TCOffload = other.TCOffload.DeepCopy()
+	}
+	if other.OVSBridge != nil {
+		c.OVSBridge = other.OVSBridge.DeepCopy()
+	}
+	if other.InfiniBand != nil {
+		c.InfiniBand = other.InfiniBand.DeepCopy()
+	}
+	if other.NumQueues != nil {
+		c.NumQueues = ptr.To(*other.NumQueues)
+	}
+	if other.RingSize != nil {
+		c.RingSize = other.RingSize.DeepCopy()
+	}
+	if other.Offloads != nil {
+		c.Offloads = other.Offloads.DeepCopy()
+	}
+	if len(other.Routes) > 0 {
+		c.Routes = append([]RouteConfig(nil), other.Routes...)
+	}
+	if len(other.Neighbors) > 0 {
+		c.Neighbors = append([]NeighborConfig(nil), other.Neighbors...)
+	}
+	// variables are merged, so a claim adds to the variables of its DeviceClass
+	if len(other.Env) > 0 {
+		env := make(map[string]string, len(c.Env)+len(other.Env))
+		maps.Copy(env, c.Env)
+		maps.Copy(env, other.Env)
+		c.Env = env
+	}
+	// like the variables, a claim adds to the sysctls of its DeviceClass
+	if len(other.Sysctls) > 0 {
+		sysctls := make(map[string]string, len(c.Sysctls)+len(other.Sysctls))
+		maps.Copy(sysctls, c.Sysctls)
+		maps.Copy(sysctls, other.Sysctls)
+		c.Sysctls = sysctls
+	}
 }
 
 // Normalize updates a VfConfig config with implied default values.
-// IMPLEMENT IF NEEDED
 func (c *VfConfig) Normalize() {
+	// KubeVirt passes the VFs through to the VM with VFIO
+	if c.KubeVirt != nil && c.Driver == "" {
+		c.Driver = KubeVirtDriver
+	}
+	if c.TCOffload != nil && c.TCOffload.Qdisc == "" {
+		c.TCOffload.Qdisc = TCOffloadQdiscClsact
+	}
+	if c.InfiniBand != nil {
+		if c.InfiniBand.NodeGUID == "" {
+			c.InfiniBand.NodeGUID = c.InfiniBand.PortGUID
+		}
+		if c.InfiniBand.PortGUID == "" {
+			c.InfiniBand.PortGUID = c.InfiniBand.NodeGUID
+		}
+	}
 }
 
 //nolint:gochecknoinits // Required for Kubernetes scheme registration
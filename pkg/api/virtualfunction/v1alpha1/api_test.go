@@ -17,9 +17,14 @@
 package v1alpha1
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 )
@@ -73,6 +78,42 @@ var _ = Describe("VfConfig", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 
+			It("should validate an IPAM override", func() {
+				config := &VfConfig{
+					Driver:           "vfio-pci",
+					NetAttachDefName: "test-network",
+					IPAM: &IPAMOverride{
+						Addresses: []string{"10.0.0.5/24"},
+						Gateway:   "10.0.0.1",
+						Routes:    []IPAMRoute{{Dst: "192.168.0.0/16", GW: "10.0.0.254"}},
+					},
+				}
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for an invalid IPAM override", func() {
+				for _, ipam := range []*IPAMOverride{
+					{Addresses: []string{"10.0.0.5"}},
+					{Gateway: "10.0.0.1"},
+					{Addresses: []string{"10.0.0.5/24"}, Gateway: "gateway"},
+					{Routes: []IPAMRoute{{Dst: "192.168.0.0"}}},
+					{Routes: []IPAMRoute{{Dst: "192.168.0.0/16", GW: "gw"}}},
+				} {
+					config := &VfConfig{Driver: "vfio-pci", NetAttachDefName: "test-network", IPAM: ipam}
+					err := config.Validate()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(HavePrefix("ipam: Invalid value: "))
+				}
+			})
+
+			It("should validate config with an inline CNI config instead of a net attach def", func() {
+				config := &VfConfig{
+					Driver:    "vfio-pci",
+					CNIConfig: &runtime.RawExtension{Raw: []byte(`{"type":"sriov"}`)},
+				}
+				Expect(config.Validate()).To(Succeed())
+			})
+
 			It("should validate config with minimal required fields", func() {
 				config := &VfConfig{
 					Driver:           "vfio-pci",
@@ -91,7 +132,7 @@ var _ = Describe("VfConfig", func() {
 				}
 				err := config.Validate()
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("no driver set"))
+				Expect(err.Error()).To(Equal("driver: Required value: no driver set"))
 			})
 
 			It("should return error when NetAttachDefName is empty", func() {
@@ -101,7 +142,7 @@ var _ = Describe("VfConfig", func() {
 				}
 				err := config.Validate()
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("no net attach def name set"))
+				Expect(err.Error()).To(Equal("netAttachDefName: Required value: no net attach def name set"))
 			})
 
 			It("should return error when both Driver and NetAttachDefName are empty", func() {
@@ -111,7 +152,270 @@ var _ = Describe("VfConfig", func() {
 				}
 				err := config.Validate()
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("no driver set"))
+				Expect(err.Error()).To(Equal("[driver: Required value: no driver set, netAttachDefName: Required value: no net attach def name set]"))
+			})
+
+			It("should return error when both NetAttachDefName and CNIConfig are set", func() {
+				config := &VfConfig{
+					Driver:           "vfio-pci",
+					NetAttachDefName: "test-network",
+					CNIConfig:        &runtime.RawExtension{Raw: []byte(`{"type":"sriov"}`)},
+				}
+				err := config.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("cniConfig: Forbidden: netAttachDefName and cniConfig are mutually exclusive"))
+			})
+
+			It("should return error when a chained net attach def has no name", func() {
+				config := &VfConfig{
+					Driver:               "vfio-pci",
+					NetAttachDefName:     "test-network",
+					ChainedNetAttachDefs: []NetAttachDefReference{{Name: "routes"}, {Namespace: "default"}},
+				}
+				err := config.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("chainedNetAttachDefs[1].name: Required value: no name set for chained net attach def"))
+			})
+
+			It("should return error when CNITimeout is not positive", func() {
+				config := &VfConfig{
+					Driver:           "vfio-pci",
+					NetAttachDefName: "test-network",
+					CNITimeout:       &metav1.Duration{Duration: 0},
+				}
+				err := config.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal(`cniTimeout: Invalid value: "0s": cni timeout must be positive`))
+			})
+
+			It("should return error when the VFIO device mode is not a permission mode", func() {
+				config := &VfConfig{
+					Driver:                "vfio-pci",
+					NetAttachDefName:      "test-network",
+					VFIODevicePermissions: &DevicePermissions{FileMode: ptr.To(int32(0o4777))},
+				}
+				err := config.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal(`vfioDevicePermissions: Invalid value: {"fileMode":2559}: file mode 04777 is not within 0 and 0777`))
+			})
+
+			It("should return error when the vhost-user socket dir is not mounted at an absolute path", func() {
+				config := &VfConfig{
+					Driver:             "vfio-pci",
+					NetAttachDefName:   "test-network",
+					VhostUserSocketDir: &VhostUserSocketDir{ContainerPath: "run/vhost"},
+				}
+				err := config.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(HaveSuffix(`container path "run/vhost" is not a clean absolute path`))
+				Expect(err.Error()).To(HavePrefix("vhostUserSocketDir: Invalid value: "))
+			})
+
+			It("should return error for an unknown bonding mode", func() {
+				config := &VfConfig{
+					Driver:           "netdevice",
+					NetAttachDefName: "test-network",
+					Bond:             &BondConfig{Mode: "round-robin"},
+				}
+				err := config.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(`bond: Invalid value: {"mode":"round-robin"}: unsupported mode "round-robin"`))
+			})
+
+			It("should return error for a negative miimon", func() {
+				config := &VfConfig{
+					Driver:           "netdevice",
+					NetAttachDefName: "test-network",
+					Bond:             &BondConfig{Mode: "active-backup", Miimon: ptr.To(int32(-1))},
+				}
+				Expect(config.Validate()).To(MatchError(`bond: Invalid value: {"mode":"active-backup","miimon":-1}: miimon must not be negative`))
+			})
+
+			It("should accept KubeVirt VFs without network", func() {
+				config := &VfConfig{Driver: "vfio-pci", KubeVirt: &KubeVirtConfig{ResourceName: "intel.com/sriov"}}
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for KubeVirt VFs not bound to vfio-pci", func() {
+				config := &VfConfig{Driver: "netdevice", KubeVirt: &KubeVirtConfig{}}
+				Expect(config.Validate()).To(MatchError(`driver: Invalid value: "netdevice": kubeVirt requires the vfio-pci driver`))
+			})
+
+			It("should accept TC offload with a clsact or ingress qdisc", func() {
+				config := &VfConfig{Driver: "netdevice", NetAttachDefName: "test-network", TCOffload: &TCOffloadConfig{}}
+				Expect(config.Validate()).To(Succeed())
+				config.TCOffload.Qdisc = TCOffloadQdiscIngress
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for TC offload with another qdisc", func() {
+				config := &VfConfig{Driver: "netdevice", NetAttachDefName: "test-network", TCOffload: &TCOffloadConfig{Qdisc: "htb"}}
+				Expect(config.Validate()).To(MatchError(`tcOffload.qdisc: Invalid value: "htb": unsupported qdisc "htb", expected "clsact" or "ingress"`))
+			})
+
+			It("should return error for an OVS bridge without name", func() {
+				config := &VfConfig{Driver: "netdevice", NetAttachDefName: "test-network", OVSBridge: &OVSBridgeConfig{}}
+				Expect(config.Validate()).To(MatchError("ovsBridge.bridge: Required value: ovsBridge requires a bridge"))
+				config.OVSBridge.Bridge = "br-ex"
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for InfiniBand config without valid GUIDs", func() {
+				config := &VfConfig{Driver: "netdevice", NetAttachDefName: "test-network", InfiniBand: &InfiniBandConfig{}}
+				Expect(config.Validate()).To(MatchError("infiniBand: Invalid value: {}: nodeGUID or portGUID is required"))
+				config.InfiniBand.NodeGUID = "02:00:00:00:00:01"
+				Expect(config.Validate()).To(MatchError(ContainSubstring(`invalid nodeGUID "02:00:00:00:00:01", expected 8 colon-separated bytes`)))
+				config.InfiniBand.NodeGUID = "02:00:00:00:00:00:00:01"
+				config.InfiniBand.PortGUID = "port"
+				Expect(config.Validate()).To(MatchError(ContainSubstring(`invalid portGUID "port"`)))
+				config.InfiniBand.PortGUID = ""
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for an env variable without name", func() {
+				config := &VfConfig{Driver: "netdevice", NetAttachDefName: "test-network", Env: map[string]string{"": "1"}}
+				Expect(config.Validate()).To(MatchError("env[]: Required value: env has a variable without name"))
+				config.Env = map[string]string{"APP_VF_{{ .EnvDeviceName }}": "{{ .PCIAddress }}"}
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for an interface name with a userspace driver", func() {
+				config := &VfConfig{Driver: "vfio-pci", NetAttachDefName: "test-network", IfName: "net1"}
+				Expect(config.Validate()).To(MatchError("ifName: Forbidden: ifName and the userspace driver vfio-pci are mutually exclusive, the VF has no network interface"))
+				config.Driver = "iavf"
+				Expect(config.Validate()).To(Succeed())
+			})
+
+			It("should return error for invalid VF settings of an inline sriov CNI config", func() {
+				config := &VfConfig{Driver: "iavf", CNIConfig: &runtime.RawExtension{Raw: []byte(
+					`{"type":"sriov","mac":"02:00:00:00:01","vlan":5000,"vlanQoS":1.5,"min_tx_rate":200,"max_tx_rate":100}`)}}
+				Expect(config.Validate()).To(MatchError(`[` +
+					`cniConfig.mac: Invalid value: "02:00:00:00:01": must be a MAC address of 6 colon-separated bytes, ` +
+					`cniConfig.vlan: Invalid value: 5000: must be within 0 and 4094, ` +
+					`cniConfig.vlanQoS: Invalid value: 1.5: must be an integer, ` +
+					`cniConfig.min_tx_rate: Invalid value: 200: must not exceed max_tx_rate 100]`))
+
+				config.CNIConfig.Raw = []byte(`{"plugins":[{"type":"sriov","mac":"02:00:00:00:00:01","vlan":100,"vlanQoS":3,"min_tx_rate":100},{"type":"tuning","vlan":"any"}]}`)
+				Expect(config.Validate()).To(Succeed())
+
+				config.CNIConfig.Raw = []byte(`{"plugins":[{"type":"tuning"},{"type":"sriov","max_tx_rate":-1}]}`)
+				Expect(config.Validate()).To(MatchError("cniConfig.plugins[1].max_tx_rate: Invalid value: -1: must be within 0 and 2147483647"))
+
+				config.CNIConfig.Raw = []byte(`{"type":`)
+				Expect(config.Validate()).To(MatchError(ContainSubstring("cniConfig: Invalid value: ")))
+			})
+
+			It("should return error for numQueues not positive or with a userspace driver", func() {
+				config := &VfConfig{Driver: "iavf", NetAttachDefName: "test-network", NumQueues: ptr.To(int32(4))}
+				Expect(config.Validate()).To(Succeed())
+				config.NumQueues = ptr.To(int32(0))
+				Expect(config.Validate()).To(MatchError("numQueues: Invalid value: 0: must be positive"))
+				config.NumQueues = ptr.To(int32(4))
+				config.Driver = "vfio-pci"
+				Expect(config.Validate()).To(MatchError("numQueues: Forbidden: numQueues and the userspace driver vfio-pci are mutually exclusive, the VF has no network interface"))
+			})
+
+			It("should return error for negative ring sizes or ring sizes with a userspace driver", func() {
+				config := &VfConfig{Driver: "iavf", NetAttachDefName: "test-network", RingSize: &RingSizeConfig{RX: 4096}}
+				Expect(config.Validate()).To(Succeed())
+				config.RingSize.TX = -1
+				Expect(config.Validate()).To(MatchError("ringSize.tx: Invalid value: -1: must not be negative"))
+				config.RingSize.TX = 0
+				config.Driver = "igb_uio"
+				Expect(config.Validate()).To(MatchError("ringSize: Forbidden: ringSize and the userspace driver igb_uio are mutually exclusive, the VF has no network interface"))
+			})
+
+			It("should return error for sysctls outside of the network namespace", func() {
+				config := &VfConfig{Driver: "iavf", NetAttachDefName: "test-network", Sysctls: map[string]string{
+					"net.ipv4.conf.IFNAME.rp_filter": "2",
+					"net.ipv6.conf.all.forwarding":   "1",
+				}}
+				Expect(config.Validate()).To(Succeed())
+
+				config.Sysctls = map[string]string{"kernel.shmmax": "1", "net.ipv4..rp_filter": "2", "net.ipv4/../../kernel": "1"}
+				err := config.Validate()
+				Expect(err).To(MatchError(ContainSubstring("sysctls[kernel.shmmax]: Invalid value: \"kernel.shmmax\": must be a network sysctl, starting with net.")))
+				Expect(err).To(MatchError(ContainSubstring("sysctls[net.ipv4..rp_filter]: Invalid value: \"net.ipv4..rp_filter\": must be dot separated names")))
+				Expect(err).To(MatchError(ContainSubstring("sysctls[net.ipv4/../../kernel]: Invalid value")))
+
+				config.Sysctls = map[string]string{"net.ipv4.conf.IFNAME.rp_filter": "2"}
+				config.Driver = "vfio-pci"
+				Expect(config.Validate()).To(MatchError("sysctls[net.ipv4.conf.IFNAME.rp_filter]: Forbidden: IFNAME and the userspace driver vfio-pci are mutually exclusive, the VF has no network interface"))
+			})
+
+			It("should return the paths of the sysctls for the interface of the VF", func() {
+				config := &VfConfig{Sysctls: map[string]string{
+					"net.ipv4.conf.IFNAME.arp_notify": "1",
+					"net.ipv4.ip_forward":             "0",
+				}}
+				Expect(config.SysctlPaths("net1")).To(Equal(map[string]string{
+					"net/ipv4/conf/net1/arp_notify": "1",
+					"net/ipv4/ip_forward":           "0",
+				}))
+				Expect(config.SysctlPaths("net1.100")).To(HaveKey("net/ipv4/conf/net1.100/arp_notify"))
+			})
+
+			It("should return error for invalid routes or routes with a userspace driver", func() {
+				config := &VfConfig{Driver: "iavf", NetAttachDefName: "test-network", Routes: []RouteConfig{
+					{Dst: "10.10.0.0/16", GW: "192.168.100.1", Metric: 100},
+					{Dst: "fd00:10::/64"},
+				}}
+				Expect(config.Validate()).To(Succeed())
+
+				config.Routes = []RouteConfig{
+					{Dst: "10.10.0.0"},
+					{Dst: "fd00:10::/64", GW: "192.168.100.1"},
+					{Dst: "10.10.0.0/16", GW: "gateway", Metric: -1},
+				}
+				err := config.Validate()
+				Expect(err).To(MatchError(ContainSubstring(`routes[0].dst: Invalid value: "10.10.0.0": must be in CIDR notation`)))
+				Expect(err).To(MatchError(ContainSubstring(`routes[1].gw: Invalid value: "192.168.100.1": must be of the IP family of dst`)))
+				Expect(err).To(MatchError(ContainSubstring(`routes[2].gw: Invalid value: "gateway": must be an IP address`)))
+				Expect(err).To(MatchError(ContainSubstring(`routes[2].metric: Invalid value: -1: must not be negative`)))
+
+				config.Routes = []RouteConfig{{Dst: "10.10.0.0/16"}}
+				config.Driver = "vfio-pci"
+				Expect(config.Validate()).To(MatchError("routes: Forbidden: routes and the userspace driver vfio-pci are mutually exclusive, the VF has no network interface"))
+			})
+
+			It("should return error for invalid neighbors or neighbors with a userspace driver", func() {
+				config := &VfConfig{Driver: "iavf", NetAttachDefName: "test-network", Neighbors: []NeighborConfig{
+					{IP: "192.168.100.1", MAC: "0a:1b:2c:3d:4e:5f"},
+					{IP: "fd00::1", MAC: "0a:1b:2c:3d:4e:5f"},
+				}}
+				Expect(config.Validate()).To(Succeed())
+
+				config.Neighbors = append(config.Neighbors,
+					NeighborConfig{IP: "192.168.100.1", MAC: "0a:1b:2c:3d:4e:60"},
+					NeighborConfig{IP: "gateway", MAC: "0a:1b:2c"})
+				err := config.Validate()
+				Expect(err).To(MatchError(ContainSubstring(`neighbors[2].ip: Duplicate value: "192.168.100.1"`)))
+				Expect(err).To(MatchError(ContainSubstring(`neighbors[3].ip: Invalid value: "gateway": must be an IP address`)))
+				Expect(err).To(MatchError(ContainSubstring(`neighbors[3].mac: Invalid value: "0a:1b:2c": must be a MAC address`)))
+
+				config.Neighbors = config.Neighbors[:1]
+				config.Driver = "igb_uio"
+				Expect(config.Validate()).To(MatchError("neighbors: Forbidden: neighbors and the userspace driver igb_uio are mutually exclusive, the VF has no network interface"))
+			})
+
+			It("should return error for offloads with a userspace driver", func() {
+				config := &VfConfig{Driver: "iavf", NetAttachDefName: "test-network", Offloads: &OffloadConfig{LRO: ptr.To(false)}}
+				Expect(config.Validate()).To(Succeed())
+				config.Driver = "vfio-pci"
+				Expect(config.Validate()).To(MatchError("offloads: Forbidden: offloads and the userspace driver vfio-pci are mutually exclusive, the VF has no network interface"))
+			})
+
+			It("should return an error for each invalid field", func() {
+				config := &VfConfig{
+					Driver:     "netdevice",
+					KubeVirt:   &KubeVirtConfig{},
+					Bond:       &BondConfig{},
+					CNITimeout: &metav1.Duration{Duration: -time.Second},
+				}
+				Expect(config.Validate()).To(MatchError("[" +
+					`cniTimeout: Invalid value: "-1s": cni timeout must be positive, ` +
+					`driver: Invalid value: "netdevice": kubeVirt requires the vfio-pci driver, ` +
+					"bond: Forbidden: kubeVirt and bond are mutually exclusive]"))
 			})
 
 			It("should return error for default config without modifications", func() {
@@ -122,6 +426,21 @@ var _ = Describe("VfConfig", func() {
 		})
 	})
 
+	Describe("ValidateFields", func() {
+		It("should not require a driver or a network", func() {
+			Expect(DefaultVfConfig().ValidateFields(nil, ValidationOptions{})).To(BeEmpty())
+		})
+
+		It("should only accept the allowed drivers", func() {
+			opts := ValidationOptions{AllowedDrivers: []string{"default", "vfio-pci"}}
+			Expect((&VfConfig{Driver: "vfio-pci"}).ValidateFields(nil, opts)).To(BeEmpty())
+			Expect((&VfConfig{}).ValidateFields(nil, opts)).To(BeEmpty())
+
+			errs := (&VfConfig{Driver: "igb_uio"}).ValidateFields(field.NewPath("parameters"), opts)
+			Expect(errs.ToAggregate()).To(MatchError(`parameters.driver: Unsupported value: "igb_uio": supported values: "default", "vfio-pci"`))
+		})
+	})
+
 	Describe("Override", func() {
 		Context("Override All Fields", func() {
 			It("should override all fields when other has all fields set", func() {
@@ -345,12 +664,21 @@ var _ = Describe("VfConfig", func() {
 
 				base.Override(other)
 
-				// AddVhostMount should remain unchanged as it's not in Override logic
+				// an unset boolean keeps the value of the base config
 				Expect(base.AddVhostMount).To(BeTrue())
 				Expect(base.Driver).To(Equal("netdevice"))
 			})
 
-			It("should not affect NetAttachDefNamespace field", func() {
+			It("should enable the mounts set in other", func() {
+				base := &VfConfig{Driver: "vfio-pci"}
+				base.Override(&VfConfig{AddVhostMount: true, AddHugepagesMount: true})
+
+				Expect(base.AddVhostMount).To(BeTrue())
+				Expect(base.AddHugepagesMount).To(BeTrue())
+				Expect(base.Driver).To(Equal("vfio-pci"))
+			})
+
+			It("should override NetAttachDefNamespace only when other has it set", func() {
 				base := &VfConfig{
 					Driver:                "vfio-pci",
 					NetAttachDefName:      "net1",
@@ -364,13 +692,263 @@ var _ = Describe("VfConfig", func() {
 
 				base.Override(other)
 
-				// NetAttachDefNamespace should remain unchanged
-				Expect(base.NetAttachDefNamespace).To(Equal("default"))
+				Expect(base.NetAttachDefNamespace).To(Equal("other-namespace"))
 				Expect(base.Driver).To(Equal("netdevice"))
+
+				base.Override(&VfConfig{})
+				Expect(base.NetAttachDefNamespace).To(Equal("other-namespace"))
+			})
+
+			It("should override IPAM only when other has it set", func() {
+				base := &VfConfig{IPAM: &IPAMOverride{Addresses: []string{"10.0.0.5/24"}}}
+
+				base.Override(&VfConfig{})
+				Expect(base.IPAM.Addresses).To(Equal([]string{"10.0.0.5/24"}))
+
+				other := &VfConfig{IPAM: &IPAMOverride{Addresses: []string{"10.0.0.6/24"}}}
+				base.Override(other)
+				Expect(base.IPAM.Addresses).To(Equal([]string{"10.0.0.6/24"}))
+				other.IPAM.Addresses[0] = "10.0.0.7/24"
+				Expect(base.IPAM.Addresses).To(Equal([]string{"10.0.0.6/24"}))
+			})
+
+			It("should override CNIConfig only when other has it set", func() {
+				base := &VfConfig{CNIConfig: &runtime.RawExtension{Raw: []byte(`{"type":"sriov"}`)}}
+
+				base.Override(&VfConfig{})
+				Expect(string(base.CNIConfig.Raw)).To(Equal(`{"type":"sriov"}`))
+
+				base.Override(&VfConfig{CNIConfig: &runtime.RawExtension{Raw: []byte(`{"type":"host-device"}`)}})
+				Expect(string(base.CNIConfig.Raw)).To(Equal(`{"type":"host-device"}`))
+			})
+
+			It("should override ChainedNetAttachDefs only when other has some", func() {
+				base := &VfConfig{ChainedNetAttachDefs: []NetAttachDefReference{{Name: "routes"}}}
+
+				base.Override(&VfConfig{})
+				Expect(base.ChainedNetAttachDefs).To(Equal([]NetAttachDefReference{{Name: "routes"}}))
+
+				base.Override(&VfConfig{ChainedNetAttachDefs: []NetAttachDefReference{{Name: "tuning", Namespace: "ns"}}})
+				Expect(base.ChainedNetAttachDefs).To(Equal([]NetAttachDefReference{{Name: "tuning", Namespace: "ns"}}))
+			})
+
+			It("should override CNITimeout only when other has it set", func() {
+				base := &VfConfig{CNITimeout: &metav1.Duration{Duration: time.Second}}
+
+				base.Override(&VfConfig{})
+				Expect(base.CNITimeout.Duration).To(Equal(time.Second))
+
+				other := &VfConfig{CNITimeout: &metav1.Duration{Duration: time.Minute}}
+				base.Override(other)
+				Expect(base.CNITimeout.Duration).To(Equal(time.Minute))
+
+				// the override is a copy
+				other.CNITimeout.Duration = time.Hour
+				Expect(base.CNITimeout.Duration).To(Equal(time.Minute))
+			})
+
+			It("should override VFIODevicePermissions only when other has them set", func() {
+				base := &VfConfig{VFIODevicePermissions: &DevicePermissions{FileMode: ptr.To(int32(0o660))}}
+
+				base.Override(&VfConfig{})
+				Expect(*base.VFIODevicePermissions.FileMode).To(Equal(int32(0o660)))
+
+				other := &VfConfig{VFIODevicePermissions: &DevicePermissions{GID: ptr.To(uint32(1000))}}
+				base.Override(other)
+				Expect(base.VFIODevicePermissions.FileMode).To(BeNil())
+				Expect(*base.VFIODevicePermissions.GID).To(Equal(uint32(1000)))
+
+				// the override is a copy
+				*other.VFIODevicePermissions.GID = 2000
+				Expect(*base.VFIODevicePermissions.GID).To(Equal(uint32(1000)))
+			})
+
+			It("should override VhostUserSocketDir only when other has it set", func() {
+				base := &VfConfig{VhostUserSocketDir: &VhostUserSocketDir{ContainerPath: "/run/vhost"}}
+
+				base.Override(&VfConfig{})
+				Expect(base.VhostUserSocketDir.ContainerPath).To(Equal("/run/vhost"))
+
+				other := &VfConfig{VhostUserSocketDir: &VhostUserSocketDir{Permissions: &DevicePermissions{UID: ptr.To(uint32(1000))}}}
+				base.Override(other)
+				Expect(base.VhostUserSocketDir.ContainerPath).To(BeEmpty())
+				Expect(*base.VhostUserSocketDir.Permissions.UID).To(Equal(uint32(1000)))
+
+				// the override is a copy
+				*other.VhostUserSocketDir.Permissions.UID = 2000
+				Expect(*base.VhostUserSocketDir.Permissions.UID).To(Equal(uint32(1000)))
+			})
+
+			It("should override KubeVirt only when other has it set", func() {
+				base := &VfConfig{KubeVirt: &KubeVirtConfig{ResourceName: "intel.com/sriov"}}
+
+				base.Override(&VfConfig{})
+				Expect(base.KubeVirt.ResourceName).To(Equal("intel.com/sriov"))
+
+				base.Override(&VfConfig{KubeVirt: &KubeVirtConfig{}})
+				Expect(base.KubeVirt.ResourceName).To(BeEmpty())
+			})
+
+			It("should override TCOffload only when other has it set", func() {
+				base := &VfConfig{TCOffload: &TCOffloadConfig{Qdisc: TCOffloadQdiscIngress}}
+
+				base.Override(&VfConfig{})
+				Expect(base.TCOffload.Qdisc).To(Equal(TCOffloadQdiscIngress))
+
+				other := &VfConfig{TCOffload: &TCOffloadConfig{}}
+				base.Override(other)
+				Expect(base.TCOffload.Qdisc).To(BeEmpty())
+				Expect(base.TCOffload).NotTo(BeIdenticalTo(other.TCOffload))
+			})
+
+			It("should override OVSBridge only when other has it set", func() {
+				base := &VfConfig{OVSBridge: &OVSBridgeConfig{Bridge: "br-ex"}}
+
+				base.Override(&VfConfig{})
+				Expect(base.OVSBridge.Bridge).To(Equal("br-ex"))
+
+				base.Override(&VfConfig{OVSBridge: &OVSBridgeConfig{Bridge: "br-int"}})
+				Expect(base.OVSBridge.Bridge).To(Equal("br-int"))
+			})
+
+			It("should override InfiniBand only when other has it set", func() {
+				base := &VfConfig{InfiniBand: &InfiniBandConfig{NodeGUID: "02:00:00:00:00:00:00:01"}}
+
+				base.Override(&VfConfig{})
+				Expect(base.InfiniBand.NodeGUID).To(Equal("02:00:00:00:00:00:00:01"))
+
+				other := &VfConfig{InfiniBand: &InfiniBandConfig{PortGUID: "02:00:00:00:00:00:00:02"}}
+				base.Override(other)
+				Expect(*base.InfiniBand).To(Equal(InfiniBandConfig{PortGUID: "02:00:00:00:00:00:00:02"}))
+				Expect(base.InfiniBand).NotTo(BeIdenticalTo(other.InfiniBand))
+			})
+
+			It("should override NumQueues only when other has it set", func() {
+				base := &VfConfig{NumQueues: ptr.To(int32(4))}
+
+				base.Override(&VfConfig{})
+				Expect(*base.NumQueues).To(Equal(int32(4)))
+
+				other := &VfConfig{NumQueues: ptr.To(int32(2))}
+				base.Override(other)
+				Expect(*base.NumQueues).To(Equal(int32(2)))
+				Expect(base.NumQueues).NotTo(BeIdenticalTo(other.NumQueues))
+			})
+
+			It("should override RingSize only when other has it set", func() {
+				base := &VfConfig{RingSize: &RingSizeConfig{RX: 4096, TX: 4096}}
+
+				base.Override(&VfConfig{})
+				Expect(*base.RingSize).To(Equal(RingSizeConfig{RX: 4096, TX: 4096}))
+
+				other := &VfConfig{RingSize: &RingSizeConfig{RX: 8192}}
+				base.Override(other)
+				Expect(*base.RingSize).To(Equal(RingSizeConfig{RX: 8192}))
+				Expect(base.RingSize).NotTo(BeIdenticalTo(other.RingSize))
+			})
+
+			It("should override Offloads only when other has it set", func() {
+				base := &VfConfig{Offloads: &OffloadConfig{GRO: ptr.To(false)}}
+
+				base.Override(&VfConfig{})
+				Expect(*base.Offloads.GRO).To(BeFalse())
+
+				other := &VfConfig{Offloads: &OffloadConfig{TSO: ptr.To(true), LRO: ptr.To(false)}}
+				base.Override(other)
+				Expect(base.Offloads.GRO).To(BeNil())
+				Expect(base.Offloads.Features()).To(Equal(map[string]bool{
+					"tx-tcp-segmentation":  true,
+					"tx-tcp6-segmentation": true,
+					"rx-lro":               false,
+				}))
+				Expect(base.Offloads.LRO).NotTo(BeIdenticalTo(other.Offloads.LRO))
+			})
+
+			It("should merge Env with the variables of other", func() {
+				baseEnv := map[string]string{"APP_MODE": "slow", "APP_QUEUES": "2"}
+				base := &VfConfig{Env: baseEnv}
+
+				base.Override(&VfConfig{})
+				Expect(base.Env).To(Equal(map[string]string{"APP_MODE": "slow", "APP_QUEUES": "2"}))
+
+				base.Override(&VfConfig{Env: map[string]string{"APP_MODE": "fast", "APP_DEBUG": "1"}})
+				Expect(base.Env).To(Equal(map[string]string{"APP_MODE": "fast", "APP_QUEUES": "2", "APP_DEBUG": "1"}))
+				Expect(baseEnv).To(HaveKeyWithValue("APP_MODE", "slow"))
+			})
+
+			It("should override Routes only when other has them set", func() {
+				base := &VfConfig{Routes: []RouteConfig{{Dst: "10.10.0.0/16"}}}
+
+				base.Override(&VfConfig{})
+				Expect(base.Routes).To(Equal([]RouteConfig{{Dst: "10.10.0.0/16"}}))
+
+				other := &VfConfig{Routes: []RouteConfig{{Dst: "10.20.0.0/16", GW: "192.168.100.1"}}}
+				base.Override(other)
+				Expect(base.Routes).To(Equal([]RouteConfig{{Dst: "10.20.0.0/16", GW: "192.168.100.1"}}))
+				other.Routes[0].GW = "192.168.100.2"
+				Expect(base.Routes[0].GW).To(Equal("192.168.100.1"))
+			})
+
+			It("should override Neighbors only when other has them set", func() {
+				base := &VfConfig{Neighbors: []NeighborConfig{{IP: "192.168.100.1", MAC: "0a:1b:2c:3d:4e:5f"}}}
+
+				base.Override(&VfConfig{})
+				Expect(base.Neighbors).To(HaveLen(1))
+
+				other := &VfConfig{Neighbors: []NeighborConfig{{IP: "192.168.100.2", MAC: "0a:1b:2c:3d:4e:60"}}}
+				base.Override(other)
+				Expect(base.Neighbors).To(Equal([]NeighborConfig{{IP: "192.168.100.2", MAC: "0a:1b:2c:3d:4e:60"}}))
+				other.Neighbors[0].MAC = "0a:1b:2c:3d:4e:61"
+				Expect(base.Neighbors[0].MAC).To(Equal("0a:1b:2c:3d:4e:60"))
+			})
+
+			It("should merge Sysctls with the sysctls of other", func() {
+				base := &VfConfig{Sysctls: map[string]string{"net.ipv4.conf.IFNAME.rp_filter": "1"}}
+
+				base.Override(&VfConfig{Sysctls: map[string]string{
+					"net.ipv4.conf.IFNAME.rp_filter":  "2",
+					"net.ipv4.conf.IFNAME.arp_notify": "1",
+				}})
+				Expect(base.Sysctls).To(Equal(map[string]string{
+					"net.ipv4.conf.IFNAME.rp_filter":  "2",
+					"net.ipv4.conf.IFNAME.arp_notify": "1",
+				}))
+			})
+
+			It("should override Bond only when other has it set", func() {
+				base := &VfConfig{Bond: &BondConfig{Mode: "802.3ad"}}
+
+				base.Override(&VfConfig{})
+				Expect(base.Bond.Mode).To(Equal("802.3ad"))
+
+				other := &VfConfig{Bond: &BondConfig{Miimon: ptr.To(int32(200))}}
+				base.Override(other)
+				Expect(base.Bond.Mode).To(BeEmpty())
+				Expect(*base.Bond.Miimon).To(Equal(int32(200)))
+
+				// the override is a copy
+				*other.Bond.Miimon = 300
+				Expect(*base.Bond.Miimon).To(Equal(int32(200)))
 			})
 		})
 	})
 
+	Describe("Decoder", func() {
+		It("should decode an inline CNI config object", func() {
+			data := []byte(`{
+				"apiVersion": "` + GroupName + "/" + Version + `",
+				"kind": "VfConfig",
+				"cniConfig": {"cniVersion": "1.0.0", "type": "sriov", "ipam": {"type": "host-local"}}
+			}`)
+			obj, _, err := Decoder.Decode(data, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			config, ok := obj.(*VfConfig)
+			Expect(ok).To(BeTrue())
+			Expect(config.CNIConfig).NotTo(BeNil())
+			Expect(string(config.CNIConfig.Raw)).To(ContainSubstring(`"host-local"`))
+		})
+	})
+
 	Describe("Normalize", func() {
 		It("should not panic when called", func() {
 			config := &VfConfig{
@@ -397,5 +975,35 @@ var _ = Describe("VfConfig", func() {
 			config := DefaultVfConfig()
 			Expect(func() { config.Normalize() }).NotTo(Panic())
 		})
+
+		It("should bind KubeVirt VFs to vfio-pci unless a driver is set", func() {
+			config := &VfConfig{KubeVirt: &KubeVirtConfig{}}
+			config.Normalize()
+			Expect(config.Driver).To(Equal(KubeVirtDriver))
+
+			config = &VfConfig{Driver: "igb_uio", KubeVirt: &KubeVirtConfig{}}
+			config.Normalize()
+			Expect(config.Driver).To(Equal("igb_uio"))
+		})
+
+		It("should default the TC offload qdisc to clsact", func() {
+			config := &VfConfig{TCOffload: &TCOffloadConfig{}}
+			config.Normalize()
+			Expect(config.TCOffload.Qdisc).To(Equal(TCOffloadQdiscClsact))
+
+			config = &VfConfig{TCOffload: &TCOffloadConfig{Qdisc: TCOffloadQdiscIngress}}
+			config.Normalize()
+			Expect(config.TCOffload.Qdisc).To(Equal(TCOffloadQdiscIngress))
+		})
+
+		It("should default each InfiniBand GUID to the other", func() {
+			config := &VfConfig{InfiniBand: &InfiniBandConfig{NodeGUID: "02:00:00:00:00:00:00:01"}}
+			config.Normalize()
+			Expect(config.InfiniBand.PortGUID).To(Equal("02:00:00:00:00:00:00:01"))
+
+			config = &VfConfig{InfiniBand: &InfiniBandConfig{PortGUID: "02:00:00:00:00:00:00:02"}}
+			config.Normalize()
+			Expect(config.InfiniBand.NodeGUID).To(Equal("02:00:00:00:00:00:00:02"))
+		})
 	})
 })
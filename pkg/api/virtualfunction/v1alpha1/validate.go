@@ -1,15 +1,375 @@
 package v1alpha1
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
 
-// Validate ensures that GpuConfig has a valid set of values.
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// userspaceDrivers are the drivers VFs are bound to when they are used from userspace, e.g. with
+// DPDK, the VFs have no network interface then.
+var userspaceDrivers = []string{"vfio-pci", "uio_pci_generic", "igb_uio"}
+
+// ValidationOptions are the settings of the node a VfConfig is validated against.
+type ValidationOptions struct {
+	// AllowedDrivers are the drivers the VFs may be bound to, any driver is accepted when nil.
+	AllowedDrivers []string
+}
+
+// Validate ensures that VfConfig has a valid set of values, including a driver and a network.
 func (c *VfConfig) Validate() error {
+	allErrs := field.ErrorList{}
 	if c.Driver == "" {
-		return fmt.Errorf("no driver set")
+		allErrs = append(allErrs, field.Required(field.NewPath("driver"), "no driver set"))
 	}
-	if c.NetAttachDefName == "" {
-		return fmt.Errorf("no net attach def name set")
+	if c.NetAttachDefName == "" && c.CNIConfig == nil && c.KubeVirt == nil {
+		allErrs = append(allErrs, field.Required(field.NewPath("netAttachDefName"), "no net attach def name set"))
 	}
+	allErrs = append(allErrs, c.ValidateFields(nil, ValidationOptions{})...)
+	return allErrs.ToAggregate()
+}
 
+// ValidateFields checks the values of the fields set in VfConfig and returns an error for each
+// invalid field, with its path under path. Unlike Validate, the driver and the network are
+// optional, as in the claims the VfConfig is prepared for.
+func (c *VfConfig) ValidateFields(path *field.Path, opts ValidationOptions) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if c.Driver != "" && opts.AllowedDrivers != nil && !slices.Contains(opts.AllowedDrivers, c.Driver) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("driver"), c.Driver, opts.AllowedDrivers))
+	}
+	if c.IfName != "" && slices.Contains(userspaceDrivers, c.Driver) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("ifName"),
+			fmt.Sprintf("ifName and the userspace driver %s are mutually exclusive, the VF has no network interface", c.Driver)))
+	}
+	if c.NetAttachDefName != "" && c.CNIConfig != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("cniConfig"), "netAttachDefName and cniConfig are mutually exclusive"))
+	}
+	if c.CNIConfig != nil {
+		allErrs = append(allErrs, validateCNIConfig(path.Child("cniConfig"), c.CNIConfig.Raw)...)
+	}
+	for i, ref := range c.ChainedNetAttachDefs {
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("chainedNetAttachDefs").Index(i).Child("name"), "no name set for chained net attach def"))
+		}
+	}
+	if c.IPAM != nil {
+		if err := c.IPAM.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("ipam"), c.IPAM, err.Error()))
+		}
+	}
+	if c.CNITimeout != nil && c.CNITimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("cniTimeout"), c.CNITimeout.Duration.String(), "cni timeout must be positive"))
+	}
+	if c.VFIODevicePermissions != nil {
+		if err := c.VFIODevicePermissions.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("vfioDevicePermissions"), c.VFIODevicePermissions, err.Error()))
+		}
+	}
+	if c.VhostUserSocketDir != nil {
+		if err := c.VhostUserSocketDir.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("vhostUserSocketDir"), c.VhostUserSocketDir, err.Error()))
+		}
+	}
+	if c.Bond != nil {
+		if err := c.Bond.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("bond"), c.Bond, err.Error()))
+		}
+	}
+	if c.KubeVirt != nil {
+		if c.Driver != KubeVirtDriver {
+			allErrs = append(allErrs, field.Invalid(path.Child("driver"), c.Driver, fmt.Sprintf("kubeVirt requires the %s driver", KubeVirtDriver)))
+		}
+		if c.Bond != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("bond"), "kubeVirt and bond are mutually exclusive"))
+		}
+	}
+	if c.TCOffload != nil {
+		if err := c.TCOffload.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("tcOffload", "qdisc"), c.TCOffload.Qdisc, err.Error()))
+		}
+	}
+	if c.OVSBridge != nil && c.OVSBridge.Bridge == "" {
+		allErrs = append(allErrs, field.Required(path.Child("ovsBridge", "bridge"), "ovsBridge requires a bridge"))
+	}
+	if c.InfiniBand != nil {
+		if err := c.InfiniBand.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("infiniBand"), c.InfiniBand, err.Error()))
+		}
+	}
+	if c.NumQueues != nil {
+		if *c.NumQueues < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child("numQueues"), *c.NumQueues, "must be positive"))
+		}
+		if slices.Contains(userspaceDrivers, c.Driver) {
+			allErrs = append(allErrs, field.Forbidden(path.Child("numQueues"),
+				fmt.Sprintf("numQueues and the userspace driver %s are mutually exclusive, the VF has no network interface", c.Driver)))
+		}
+	}
+	if c.RingSize != nil {
+		if c.RingSize.RX < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("ringSize", "rx"), c.RingSize.RX, "must not be negative"))
+		}
+		if c.RingSize.TX < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("ringSize", "tx"), c.RingSize.TX, "must not be negative"))
+		}
+		if slices.Contains(userspaceDrivers, c.Driver) {
+			allErrs = append(allErrs, field.Forbidden(path.Child("ringSize"),
+				fmt.Sprintf("ringSize and the userspace driver %s are mutually exclusive, the VF has no network interface", c.Driver)))
+		}
+	}
+	if c.Offloads != nil && slices.Contains(userspaceDrivers, c.Driver) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("offloads"),
+			fmt.Sprintf("offloads and the userspace driver %s are mutually exclusive, the VF has no network interface", c.Driver)))
+	}
+	if _, ok := c.Env[""]; ok {
+		allErrs = append(allErrs, field.Required(path.Child("env").Key(""), "env has a variable without name"))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Sysctls)) {
+		allErrs = append(allErrs, validateSysctl(path.Child("sysctls").Key(name), name, c.Driver)...)
+	}
+	for i, route := range c.Routes {
+		allErrs = append(allErrs, validateRoute(path.Child("routes").Index(i), route)...)
+	}
+	if len(c.Routes) > 0 && slices.Contains(userspaceDrivers, c.Driver) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("routes"),
+			fmt.Sprintf("routes and the userspace driver %s are mutually exclusive, the VF has no network interface", c.Driver)))
+	}
+	neighborIPs := map[string]bool{}
+	for i, neighbor := range c.Neighbors {
+		neighborPath := path.Child("neighbors").Index(i)
+		if ip := net.ParseIP(neighbor.IP); ip == nil {
+			allErrs = append(allErrs, field.Invalid(neighborPath.Child("ip"), neighbor.IP, "must be an IP address"))
+		} else if neighborIPs[ip.String()] {
+			allErrs = append(allErrs, field.Duplicate(neighborPath.Child("ip"), neighbor.IP))
+		} else {
+			neighborIPs[ip.String()] = true
+		}
+		if _, err := net.ParseMAC(neighbor.MAC); err != nil {
+			allErrs = append(allErrs, field.Invalid(neighborPath.Child("mac"), neighbor.MAC, "must be a MAC address"))
+		}
+	}
+	if len(c.Neighbors) > 0 && slices.Contains(userspaceDrivers, c.Driver) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("neighbors"),
+			fmt.Sprintf("neighbors and the userspace driver %s are mutually exclusive, the VF has no network interface", c.Driver)))
+	}
+
+	return allErrs
+}
+
+// validateSysctl checks that the name of a sysctl is a network sysctl, the only ones scoped to the
+// network namespace of the pod, and that IFNAME is only used when the VF has a network interface.
+func validateSysctl(path *field.Path, name, driver string) field.ErrorList {
+	components := strings.Split(name, ".")
+	if len(components) < 2 || components[0] != "net" {
+		return field.ErrorList{field.Invalid(path, name, "must be a network sysctl, starting with net.")}
+	}
+	for _, component := range components {
+		if component == "" || component == ".." || strings.Contains(component, "/") {
+			return field.ErrorList{field.Invalid(path, name, "must be dot separated names")}
+		}
+	}
+	if slices.Contains(components, SysctlIfName) && slices.Contains(userspaceDrivers, driver) {
+		return field.ErrorList{field.Forbidden(path,
+			fmt.Sprintf("%s and the userspace driver %s are mutually exclusive, the VF has no network interface", SysctlIfName, driver))}
+	}
+	return nil
+}
+
+// validateRoute checks that a route has a destination in CIDR notation and a gateway of the same
+// IP family.
+func validateRoute(path *field.Path, route RouteConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+	dstIP, _, err := net.ParseCIDR(route.Dst)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("dst"), route.Dst, "must be in CIDR notation"))
+	}
+	if route.GW != "" {
+		gw := net.ParseIP(route.GW)
+		switch {
+		case gw == nil:
+			allErrs = append(allErrs, field.Invalid(path.Child("gw"), route.GW, "must be an IP address"))
+		case dstIP != nil && (gw.To4() == nil) != (dstIP.To4() == nil):
+			allErrs = append(allErrs, field.Invalid(path.Child("gw"), route.GW, "must be of the IP family of dst"))
+		}
+	}
+	if route.Metric < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("metric"), route.Metric, "must not be negative"))
+	}
+	return allErrs
+}
+
+// validateCNIConfig checks that an inline CNI config is a netconf or a plugin list, and the VF
+// settings of its sriov plugins, which the sriov CNI would only reject when the device is attached.
+func validateCNIConfig(path *field.Path, rawConfig []byte) field.ErrorList {
+	var netConf map[string]interface{}
+	if err := json.Unmarshal(rawConfig, &netConf); err != nil {
+		return field.ErrorList{field.Invalid(path, string(rawConfig), fmt.Sprintf("invalid netconf: %v", err))}
+	}
+	rawPlugins, isList := netConf["plugins"]
+	if !isList {
+		return validateSriovPlugin(path, netConf)
+	}
+	plugins, ok := rawPlugins.([]interface{})
+	if !ok {
+		return field.ErrorList{field.Invalid(path.Child("plugins"), rawPlugins, "plugins must be a list")}
+	}
+	allErrs := field.ErrorList{}
+	for i, rawPlugin := range plugins {
+		plugin, ok := rawPlugin.(map[string]interface{})
+		if !ok {
+			allErrs = append(allErrs, field.Invalid(path.Child("plugins").Index(i), rawPlugin, "plugin must be an object"))
+			continue
+		}
+		allErrs = append(allErrs, validateSriovPlugin(path.Child("plugins").Index(i), plugin)...)
+	}
+	return allErrs
+}
+
+// validateSriovPlugin checks the MAC address, VLAN and rates of a plugin of the sriov CNI, other
+// plugins are left to their CNI.
+func validateSriovPlugin(path *field.Path, plugin map[string]interface{}) field.ErrorList {
+	if pluginType, _ := plugin["type"].(string); pluginType != "sriov" {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	if value, ok := plugin["mac"]; ok {
+		// an empty MAC address keeps the one of the VF
+		mac, isString := value.(string)
+		if hw, err := net.ParseMAC(mac); !isString || (mac != "" && (err != nil || len(hw) != 6)) {
+			allErrs = append(allErrs, field.Invalid(path.Child("mac"), value, "must be a MAC address of 6 colon-separated bytes"))
+		}
+	}
+	if _, err := netConfInt(path.Child("vlan"), plugin["vlan"], 0, 4094); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	if _, err := netConfInt(path.Child("vlanQoS"), plugin["vlanQoS"], 0, 7); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	minRate, minErr := netConfInt(path.Child("min_tx_rate"), plugin["min_tx_rate"], 0, math.MaxInt32)
+	if minErr != nil {
+		allErrs = append(allErrs, minErr)
+	}
+	maxRate, maxErr := netConfInt(path.Child("max_tx_rate"), plugin["max_tx_rate"], 0, math.MaxInt32)
+	if maxErr != nil {
+		allErrs = append(allErrs, maxErr)
+	}
+	// a max_tx_rate of 0 does not limit the rate
+	if minErr == nil && maxErr == nil && maxRate > 0 && minRate > maxRate {
+		allErrs = append(allErrs, field.Invalid(path.Child("min_tx_rate"), minRate, fmt.Sprintf("must not exceed max_tx_rate %d", maxRate)))
+	}
+	return allErrs
+}
+
+// netConfInt returns the integer of a netconf field within minValue and maxValue, or 0 when the
+// field is not set. JSON numbers are decoded as float64.
+func netConfInt(path *field.Path, value interface{}, minValue, maxValue int64) (int64, *field.Error) {
+	if value == nil {
+		return 0, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, field.Invalid(path, value, "must be an integer")
+	}
+	if number < float64(minValue) || number > float64(maxValue) {
+		return 0, field.Invalid(path, int64(number), fmt.Sprintf("must be within %d and %d", minValue, maxValue))
+	}
+	return int64(number), nil
+}
+
+// Validate ensures that the IPAM override holds valid addresses and routes.
+func (o *IPAMOverride) Validate() error {
+	for _, address := range o.Addresses {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return fmt.Errorf("invalid address %q: %w", address, err)
+		}
+	}
+	if o.Gateway != "" {
+		if len(o.Addresses) == 0 {
+			return fmt.Errorf("gateway set without addresses")
+		}
+		if net.ParseIP(o.Gateway) == nil {
+			return fmt.Errorf("invalid gateway %q", o.Gateway)
+		}
+	}
+	for _, route := range o.Routes {
+		if _, _, err := net.ParseCIDR(route.Dst); err != nil {
+			return fmt.Errorf("invalid route destination %q: %w", route.Dst, err)
+		}
+		if route.GW != "" && net.ParseIP(route.GW) == nil {
+			return fmt.Errorf("invalid route gateway %q", route.GW)
+		}
+	}
+	return nil
+}
+
+// Validate ensures that the device permissions only hold permission bits.
+func (p *DevicePermissions) Validate() error {
+	if p.FileMode != nil && (*p.FileMode < 0 || *p.FileMode > 0o777) {
+		return fmt.Errorf("file mode %#o is not within 0 and 0777", *p.FileMode)
+	}
+	return nil
+}
+
+// Validate ensures that the vhost-user socket dir is mounted at a clean absolute path.
+func (d *VhostUserSocketDir) Validate() error {
+	if d.ContainerPath != "" && (!filepath.IsAbs(d.ContainerPath) || filepath.Clean(d.ContainerPath) != d.ContainerPath) {
+		return fmt.Errorf("container path %q is not a clean absolute path", d.ContainerPath)
+	}
+	if d.Permissions != nil {
+		if err := d.Permissions.Validate(); err != nil {
+			return fmt.Errorf("invalid permissions: %w", err)
+		}
+	}
+	return nil
+}
+
+// bondModes are the bonding modes of the kernel.
+var bondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+
+// Validate ensures that the bond uses a bonding mode of the kernel and a valid link monitoring
+// interval.
+func (b *BondConfig) Validate() error {
+	if b.Mode != "" && !slices.Contains(bondModes, b.Mode) {
+		return fmt.Errorf("unsupported mode %q, expected one of %v", b.Mode, bondModes)
+	}
+	if b.Miimon != nil && *b.Miimon < 0 {
+		return fmt.Errorf("miimon must not be negative")
+	}
+	return nil
+}
+
+// Validate ensures that the TC offload adds a qdisc TC filters can be attached to.
+func (t *TCOffloadConfig) Validate() error {
+	if t.Qdisc != "" && t.Qdisc != TCOffloadQdiscClsact && t.Qdisc != TCOffloadQdiscIngress {
+		return fmt.Errorf("unsupported qdisc %q, expected %q or %q", t.Qdisc, TCOffloadQdiscClsact, TCOffloadQdiscIngress)
+	}
+	return nil
+}
+
+// Validate ensures that the InfiniBand config sets valid GUIDs.
+func (i *InfiniBandConfig) Validate() error {
+	if i.NodeGUID == "" && i.PortGUID == "" {
+		return fmt.Errorf("nodeGUID or portGUID is required")
+	}
+	if err := validateGUID("nodeGUID", i.NodeGUID); err != nil {
+		return err
+	}
+	return validateGUID("portGUID", i.PortGUID)
+}
+
+func validateGUID(name, guid string) error {
+	if guid == "" {
+		return nil
+	}
+	if hw, err := net.ParseMAC(guid); err != nil || len(hw) != 8 {
+		return fmt.Errorf("invalid %s %q, expected 8 colon-separated bytes", name, guid)
+	}
 	return nil
 }
@@ -21,13 +21,348 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BondConfig) DeepCopyInto(out *BondConfig) {
+	*out = *in
+	if in.Miimon != nil {
+		in, out := &in.Miimon, &out.Miimon
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BondConfig.
+func (in *BondConfig) DeepCopy() *BondConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BondConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevicePermissions) DeepCopyInto(out *DevicePermissions) {
+	*out = *in
+	if in.FileMode != nil {
+		in, out := &in.FileMode, &out.FileMode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UID != nil {
+		in, out := &in.UID, &out.UID
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.GID != nil {
+		in, out := &in.GID, &out.GID
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevicePermissions.
+func (in *DevicePermissions) DeepCopy() *DevicePermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(DevicePermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAMOverride) DeepCopyInto(out *IPAMOverride) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]IPAMRoute, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAMOverride.
+func (in *IPAMOverride) DeepCopy() *IPAMOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAMOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAMRoute) DeepCopyInto(out *IPAMRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAMRoute.
+func (in *IPAMRoute) DeepCopy() *IPAMRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAMRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfiniBandConfig) DeepCopyInto(out *InfiniBandConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfiniBandConfig.
+func (in *InfiniBandConfig) DeepCopy() *InfiniBandConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(InfiniBandConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeVirtConfig) DeepCopyInto(out *KubeVirtConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeVirtConfig.
+func (in *KubeVirtConfig) DeepCopy() *KubeVirtConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeVirtConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeighborConfig) DeepCopyInto(out *NeighborConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeighborConfig.
+func (in *NeighborConfig) DeepCopy() *NeighborConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NeighborConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetAttachDefReference) DeepCopyInto(out *NetAttachDefReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetAttachDefReference.
+func (in *NetAttachDefReference) DeepCopy() *NetAttachDefReference {
+	if in == nil {
+		return nil
+	}
+	out := new(NetAttachDefReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OVSBridgeConfig) DeepCopyInto(out *OVSBridgeConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OVSBridgeConfig.
+func (in *OVSBridgeConfig) DeepCopy() *OVSBridgeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OVSBridgeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OffloadConfig) DeepCopyInto(out *OffloadConfig) {
+	*out = *in
+	if in.TSO != nil {
+		in, out := &in.TSO, &out.TSO
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GRO != nil {
+		in, out := &in.GRO, &out.GRO
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LRO != nil {
+		in, out := &in.LRO, &out.LRO
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OffloadConfig.
+func (in *OffloadConfig) DeepCopy() *OffloadConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OffloadConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RingSizeConfig) DeepCopyInto(out *RingSizeConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RingSizeConfig.
+func (in *RingSizeConfig) DeepCopy() *RingSizeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RingSizeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteConfig) DeepCopyInto(out *RouteConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteConfig.
+func (in *RouteConfig) DeepCopy() *RouteConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCOffloadConfig) DeepCopyInto(out *TCOffloadConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCOffloadConfig.
+func (in *TCOffloadConfig) DeepCopy() *TCOffloadConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TCOffloadConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VfConfig) DeepCopyInto(out *VfConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	if in.CNIConfig != nil {
+		in, out := &in.CNIConfig, &out.CNIConfig
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChainedNetAttachDefs != nil {
+		in, out := &in.ChainedNetAttachDefs, &out.ChainedNetAttachDefs
+		*out = make([]NetAttachDefReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPAM != nil {
+		in, out := &in.IPAM, &out.IPAM
+		*out = new(IPAMOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CNITimeout != nil {
+		in, out := &in.CNITimeout, &out.CNITimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.VFIODevicePermissions != nil {
+		in, out := &in.VFIODevicePermissions, &out.VFIODevicePermissions
+		*out = new(DevicePermissions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VhostUserSocketDir != nil {
+		in, out := &in.VhostUserSocketDir, &out.VhostUserSocketDir
+		*out = new(VhostUserSocketDir)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bond != nil {
+		in, out := &in.Bond, &out.Bond
+		*out = new(BondConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeVirt != nil {
+		in, out := &in.KubeVirt, &out.KubeVirt
+		*out = new(KubeVirtConfig)
+		**out = **in
+	}
+	if in.TCOffload != nil {
+		in, out := &in.TCOffload, &out.TCOffload
+		*out = new(TCOffloadConfig)
+		**out = **in
+	}
+	if in.OVSBridge != nil {
+		in, out := &in.OVSBridge, &out.OVSBridge
+		*out = new(OVSBridgeConfig)
+		**out = **in
+	}
+	if in.InfiniBand != nil {
+		in, out := &in.InfiniBand, &out.InfiniBand
+		*out = new(InfiniBandConfig)
+		**out = **in
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NumQueues != nil {
+		in, out := &in.NumQueues, &out.NumQueues
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RingSize != nil {
+		in, out := &in.RingSize, &out.RingSize
+		*out = new(RingSizeConfig)
+		**out = **in
+	}
+	if in.Offloads != nil {
+		in, out := &in.Offloads, &out.Offloads
+		*out = new(OffloadConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]RouteConfig, len(*in))
+		copy(*out, *in)
+	}
+	if in.Neighbors != nil {
+		in, out := &in.Neighbors, &out.Neighbors
+		*out = make([]NeighborConfig, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VfConfig.
@@ -47,3 +382,23 @@ func (in *VfConfig) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VhostUserSocketDir) DeepCopyInto(out *VhostUserSocketDir) {
+	*out = *in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = new(DevicePermissions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VhostUserSocketDir.
+func (in *VhostUserSocketDir) DeepCopy() *VhostUserSocketDir {
+	if in == nil {
+		return nil
+	}
+	out := new(VhostUserSocketDir)
+	in.DeepCopyInto(out)
+	return out
+}
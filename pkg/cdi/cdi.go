@@ -1,8 +1,11 @@
 package cdi
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
@@ -14,15 +17,24 @@ import (
 )
 
 const (
-	cdiVendor = consts.DriverName
-	cdiClass  = "vf"
-	cdiKind   = cdiVendor + "/" + cdiClass
+	cdiClass = "vf"
 
 	cdiCommonDeviceName = "dra-driver-sriov"
 )
 
+// cdiVendor returns the CDI vendor of the devices, the name of the driver.
+func cdiVendor() string {
+	return consts.DriverName
+}
+
+// cdiKind returns the CDI kind of the devices.
+func cdiKind() string {
+	return cdiVendor() + "/" + cdiClass
+}
+
 type Handler struct {
 	cache *cdiapi.Cache
+	root  string
 }
 
 func NewHandler(cdiRootPath string) (*Handler, error) {
@@ -34,15 +46,29 @@ func NewHandler(cdiRootPath string) (*Handler, error) {
 	}
 	handler := &Handler{
 		cache: cache,
+		root:  cdiRootPath,
 	}
 
 	return handler, nil
 }
 
+// CheckSpecDir checks that spec files can be written to the CDI root, e.g. that it was not
+// remounted read-only. It is the cdi probe of the healthcheck.
+func (cdi *Handler) CheckSpecDir(_ context.Context) error {
+	file, err := os.CreateTemp(cdi.root, ".healthcheck-")
+	if err != nil {
+		return fmt.Errorf("CDI root %s is not writable: %w", cdi.root, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(file.Name())
+}
+
 // NOT used right now
 func (cdi *Handler) CreateCommonSpecFile() error {
 	spec := &cdispec.Spec{
-		Kind: cdiKind,
+		Kind: cdiKind(),
 		Devices: []cdispec.Device{
 			{
 				Name: cdiCommonDeviceName,
@@ -72,16 +98,21 @@ func (cdi *Handler) CreateCommonSpecFile() error {
 
 func (cdi *Handler) CreateClaimSpecFile(preparedDevices types.PreparedDevices) error {
 	claimUID := string(preparedDevices[0].ClaimNamespacedName.UID)
-	specName := cdiapi.GenerateTransientSpecName(cdiVendor, cdiClass, claimUID)
+	specName := cdiapi.GenerateTransientSpecName(cdiVendor(), cdiClass, claimUID)
 
 	spec := &cdispec.Spec{
-		Kind:    cdiKind,
+		Kind:    cdiKind(),
 		Devices: []cdispec.Device{},
 	}
 
 	for _, device := range preparedDevices {
+		annotations, err := claimDeviceAnnotations(device)
+		if err != nil {
+			return err
+		}
 		cdiDevice := cdispec.Device{
 			Name:           fmt.Sprintf("%s-%s", claimUID, device.Device.DeviceName),
+			Annotations:    annotations,
 			ContainerEdits: *device.ContainerEdits.ContainerEdits,
 		}
 
@@ -96,9 +127,80 @@ func (cdi *Handler) CreateClaimSpecFile(preparedDevices types.PreparedDevices) e
 	return cdi.cache.WriteSpec(spec, specName)
 }
 
+// claimDeviceAnnotations returns the annotations of the CDI device of a prepared device. They only
+// describe the device in the spec, the container metadata is not changed.
+func claimDeviceAnnotations(device *types.PreparedDevice) (map[string]string, error) {
+	annotations := map[string]string{
+		consts.CDIAnnotationClaimName:      device.ClaimNamespacedName.Name,
+		consts.CDIAnnotationClaimNamespace: device.ClaimNamespacedName.Namespace,
+		consts.CDIAnnotationClaimUID:       string(device.ClaimNamespacedName.UID),
+		consts.CDIAnnotationDeviceName:     device.Device.DeviceName,
+		consts.CDIAnnotationPciAddress:     device.PciAddress,
+	}
+	if device.ResourceName != "" {
+		annotations[consts.CDIAnnotationResourceName] = device.ResourceName
+	}
+	recoveryData := RecoveryData{
+		Version:        RecoveryDataVersion,
+		ClaimName:      device.ClaimNamespacedName.Name,
+		ClaimNamespace: device.ClaimNamespacedName.Namespace,
+		ClaimUID:       string(device.ClaimNamespacedName.UID),
+		DeviceName:     device.Device.DeviceName,
+		PoolName:       device.Device.PoolName,
+		RequestNames:   device.Device.RequestNames,
+		PciAddress:     device.PciAddress,
+	}
+	if device.Config != nil {
+		recoveryData.NetAttachDefName = device.Config.NetAttachDefName
+		recoveryData.NetAttachDefNamespace = device.Config.NetAttachDefNamespace
+	}
+	data, err := json.Marshal(recoveryData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recovery data of device %s: %w", device.Device.DeviceName, err)
+	}
+	annotations[consts.CDIAnnotationRecovery] = string(data)
+	return annotations, nil
+}
+
+// RecoveryDataVersion is the version of the RecoveryData written in the CDI specs of the claims.
+// It is bumped when a change of the data is not backward compatible.
+const RecoveryDataVersion = 1
+
+// RecoveryData is the data of a prepared device recorded in the CDI device of its claim, to rebuild
+// the prepared claims when the checkpoint is lost.
+type RecoveryData struct {
+	Version               int      `json:"version"`
+	ClaimName             string   `json:"claimName"`
+	ClaimNamespace        string   `json:"claimNamespace"`
+	ClaimUID              string   `json:"claimUID"`
+	DeviceName            string   `json:"deviceName"`
+	PoolName              string   `json:"poolName,omitempty"`
+	RequestNames          []string `json:"requestNames,omitempty"`
+	PciAddress            string   `json:"pciAddress"`
+	NetAttachDefName      string   `json:"netAttachDefName,omitempty"`
+	NetAttachDefNamespace string   `json:"netAttachDefNamespace,omitempty"`
+}
+
+// DeviceRecoveryData returns the recovery data of a CDI device of a claim, or nil when the device
+// has none, e.g. when written by an older version of the driver.
+func DeviceRecoveryData(device cdispec.Device) (*RecoveryData, error) {
+	value, ok := device.Annotations[consts.CDIAnnotationRecovery]
+	if !ok {
+		return nil, nil
+	}
+	recoveryData := &RecoveryData{}
+	if err := json.Unmarshal([]byte(value), recoveryData); err != nil {
+		return nil, fmt.Errorf("failed to decode recovery data of CDI device %s: %w", device.Name, err)
+	}
+	if recoveryData.Version != RecoveryDataVersion {
+		return nil, fmt.Errorf("unsupported recovery data version %d of CDI device %s", recoveryData.Version, device.Name)
+	}
+	return recoveryData, nil
+}
+
 func (cdi *Handler) CreateGlobalPodSpecFile(podUID string, pciAddresses []string) error {
 	envs := []string{fmt.Sprintf("SRIOVNETWORK_PCI_ADDRESSES=%s", strings.Join(pciAddresses, ","))}
-	specName := cdiapi.GenerateTransientSpecName(cdiVendor, cdiClass, podUID)
+	specName := cdiapi.GenerateTransientSpecName(cdiVendor(), cdiClass, podUID)
 
 	cdiDevice := cdispec.Device{
 		Name: podUID,
@@ -108,7 +210,7 @@ func (cdi *Handler) CreateGlobalPodSpecFile(podUID string, pciAddresses []string
 	}
 
 	spec := &cdispec.Spec{
-		Kind:    cdiKind,
+		Kind:    cdiKind(),
 		Devices: []cdispec.Device{cdiDevice},
 	}
 
@@ -122,14 +224,33 @@ func (cdi *Handler) CreateGlobalPodSpecFile(podUID string, pciAddresses []string
 }
 
 func (cdi *Handler) DeleteSpecFile(uid string) error {
-	specName := cdiapi.GenerateTransientSpecName(cdiVendor, cdiClass, uid)
+	specName := cdiapi.GenerateTransientSpecName(cdiVendor(), cdiClass, uid)
 	return cdi.cache.RemoveSpec(specName)
 }
 
 func (cdi *Handler) GetClaimDevices(claimUID string, device string) string {
-	return cdiparser.QualifiedName(cdiVendor, cdiClass, fmt.Sprintf("%s-%s", claimUID, device))
+	return cdiparser.QualifiedName(cdiVendor(), cdiClass, fmt.Sprintf("%s-%s", claimUID, device))
 }
 
 func (cdi *Handler) GetPodSpecName(podUID string) string {
-	return cdiparser.QualifiedName(cdiVendor, cdiClass, podUID)
+	return cdiparser.QualifiedName(cdiVendor(), cdiClass, podUID)
+}
+
+// ListTransientSpecs returns the devices of all transient specs written by the driver,
+// keyed by the claim or pod UID the spec was generated for.
+func (cdi *Handler) ListTransientSpecs() map[string][]cdispec.Device {
+	prefix := cdiapi.GenerateSpecName(cdiVendor(), cdiClass) + "_"
+	specs := make(map[string][]cdispec.Device)
+	for _, spec := range cdi.cache.GetVendorSpecs(cdiVendor()) {
+		if spec.GetClass() != cdiClass {
+			continue
+		}
+		name := filepath.Base(spec.GetPath())
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		specs[strings.TrimPrefix(name, prefix)] = spec.Devices
+	}
+	return specs
 }
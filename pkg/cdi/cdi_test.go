@@ -1,6 +1,8 @@
 package cdi_test
 
 import (
+	"context"
+	"maps"
 	"os"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -11,7 +13,9 @@ import (
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	draTypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -133,6 +137,71 @@ var _ = Describe("CDI Handler", func() {
 			// We can't easily verify the contents, but no error indicates success
 		})
 
+		It("should annotate the devices with the claim metadata", func() {
+			preparedDevices[0].ClaimNamespacedName.Name = "test-claim"
+			preparedDevices[0].ClaimNamespacedName.Namespace = "test-ns"
+			preparedDevices[0].PciAddress = pciAddress1
+			preparedDevices[0].ResourceName = "intel.com/sriov-net"
+			Expect(handler.CreateClaimSpecFile(preparedDevices)).To(Succeed())
+
+			// a new cache loads the spec written by the handler
+			cache, err := cdiapi.NewCache(cdiapi.WithSpecDirs(tempDir), cdiapi.WithAutoRefresh(false))
+			Expect(err).NotTo(HaveOccurred())
+			device := cache.GetDevice(handler.GetClaimDevices(claimUID, deviceName))
+			Expect(device).NotTo(BeNil())
+			Expect(device.Annotations).To(HaveKey(consts.CDIAnnotationRecovery))
+			annotations := maps.Clone(device.Annotations)
+			delete(annotations, consts.CDIAnnotationRecovery)
+			Expect(annotations).To(Equal(map[string]string{
+				consts.CDIAnnotationClaimName:      "test-claim",
+				consts.CDIAnnotationClaimNamespace: "test-ns",
+				consts.CDIAnnotationClaimUID:       claimUID,
+				consts.CDIAnnotationDeviceName:     deviceName,
+				consts.CDIAnnotationPciAddress:     pciAddress1,
+				consts.CDIAnnotationResourceName:   "intel.com/sriov-net",
+			}))
+		})
+
+		It("should record the recovery data of the devices", func() {
+			preparedDevices[0].ClaimNamespacedName.Name = "test-claim"
+			preparedDevices[0].ClaimNamespacedName.Namespace = "test-ns"
+			preparedDevices[0].PciAddress = pciAddress1
+			preparedDevices[0].Device.RequestNames = []string{"vf"}
+			preparedDevices[0].Config = &configapi.VfConfig{NetAttachDefName: "sriov-net", NetAttachDefNamespace: "net-ns"}
+			Expect(handler.CreateClaimSpecFile(preparedDevices)).To(Succeed())
+
+			cache, err := cdiapi.NewCache(cdiapi.WithSpecDirs(tempDir), cdiapi.WithAutoRefresh(false))
+			Expect(err).NotTo(HaveOccurred())
+			device := cache.GetDevice(handler.GetClaimDevices(claimUID, deviceName))
+			Expect(device).NotTo(BeNil())
+			recoveryData, err := cdi.DeviceRecoveryData(*device.Device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recoveryData).To(Equal(&cdi.RecoveryData{
+				Version:               cdi.RecoveryDataVersion,
+				ClaimName:             "test-claim",
+				ClaimNamespace:        "test-ns",
+				ClaimUID:              claimUID,
+				DeviceName:            deviceName,
+				RequestNames:          []string{"vf"},
+				PciAddress:            pciAddress1,
+				NetAttachDefName:      "sriov-net",
+				NetAttachDefNamespace: "net-ns",
+			}))
+		})
+
+		It("should reject recovery data of an unsupported version", func() {
+			device := cdispec.Device{
+				Name:        "test-device",
+				Annotations: map[string]string{consts.CDIAnnotationRecovery: `{"version":2}`},
+			}
+			_, err := cdi.DeviceRecoveryData(device)
+			Expect(err).To(MatchError(ContainSubstring("unsupported recovery data version 2")))
+
+			recoveryData, err := cdi.DeviceRecoveryData(cdispec.Device{Name: "test-device"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recoveryData).To(BeNil())
+		})
+
 		It("should handle multiple devices in claim", func() {
 			// Add another device to the claim
 			preparedDevices = append(preparedDevices, &draTypes.PreparedDevice{
@@ -267,6 +336,23 @@ var _ = Describe("CDI Handler", func() {
 		})
 	})
 
+	Context("CheckSpecDir", func() {
+		It("should succeed and leave no file behind when the CDI root is writable", func() {
+			Expect(handler.CheckSpecDir(context.Background())).To(Succeed())
+
+			entries, err := os.ReadDir(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+
+		It("should fail when the CDI root is gone", func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+
+			err := handler.CheckSpecDir(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("is not writable")))
+		})
+	})
+
 	Context("GetPodSpecName", func() {
 		It("should return correct qualified pod spec name", func() {
 			result := handler.GetPodSpecName(podUID)
@@ -22,14 +22,15 @@ package cni
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
 	"github.com/containernetworking/cni/libcni"
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	netattdefclientutils "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/utils"
-	resourcev1 "k8s.io/api/resource/v1"
 	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
@@ -40,12 +41,23 @@ import (
 type Runtime struct {
 	CNIConfig  libcni.CNI
 	DriverName string
+	// Timeout bounds each CNI operation, unless overridden by the VfConfig of the device.
+	// Zero disables the timeout.
+	Timeout time.Duration
+	// DHCPSocketPath is the socket of the CNI DHCP daemon handed to dhcp IPAM plugins whose
+	// netconf does not set daemonSocketPath. Empty leaves the plugin default.
+	DHCPSocketPath string
+	// InterfaceReader reads the attached interfaces from the pod network namespace to complete
+	// the network data of minimal CNI results. Nil reports the CNI result only.
+	InterfaceReader InterfaceReader
 }
 
 // New creates and returns a new CNI Runtime instance.
 func New(
 	driverName string,
 	cniPath []string,
+	timeout time.Duration,
+	dhcpSocketPath string,
 ) *Runtime {
 	exec := &RawExec{
 		Stderr: os.Stderr,
@@ -53,8 +65,12 @@ func New(
 	}
 
 	rntm := &Runtime{
-		CNIConfig:  libcni.NewCNIConfig(cniPath, exec),
-		DriverName: driverName,
+		CNIConfig:      libcni.NewCNIConfig(cniPath, exec),
+		DriverName:     driverName,
+		Timeout:        timeout,
+		DHCPSocketPath: dhcpSocketPath,
+
+		InterfaceReader: netlinkInterfaceReader{},
 	}
 
 	return rntm
@@ -64,66 +80,82 @@ func New(
 // It processes the ResourceClaim's device allocation status, extracts CNI configuration for each device,
 // and invokes the CNI ADD operation for each relevant device. The results of the CNI operations are used
 // to update the ResourceClaim's status with allocated device information.
-// If a request fails, an error is returned together with the previous successful device status up to date.
-// If the status of a device is already set, CNI ADD will be skipped and the existing status will be preserved.
-func (rntm *Runtime) AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*resourcev1.NetworkDeviceData, map[string]interface{}, error) {
-	rt := &libcni.RuntimeConf{
-		ContainerID: pod.Id,
-		NetNS:       podNetworkNamespace,
-		IfName:      deviceConfig.IfName,
-		Args: [][2]string{
-			{"IgnoreUnknown", "true"},
-			{"K8S_POD_NAMESPACE", pod.Namespace},
-			{"K8S_POD_NAME", pod.Name},
-			{"K8S_POD_INFRA_CONTAINER_ID", pod.Id},
-			{"K8S_POD_UID", pod.Uid},
-		},
-	}
+// The returned AttachResult also carries the netconf passed to the plugin and the raw CNI result,
+// so callers can persist them for a later DetachNetwork.
+func (rntm *Runtime) AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*AttachResult, error) {
+	rt := runtimeConf(pod, podNetworkNamespace, deviceConfig)
 	rawNetConf, err := netattdefclientutils.GetCNIConfigFromSpec(deviceConfig.NetAttachDefConfig, rntm.DriverName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
+		return nil, fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
 	}
 
-	pluginConf, err := libcni.NetworkPluginConfFromBytes(rawNetConf)
+	confList, err := networkConfList(rawNetConf)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to NetworkPluginConfFromBytes: %v", err)
+		return nil, err
+	}
+	dhcpSocketPath, err := rntm.withDHCPSocket(confList)
+	if err != nil {
+		return nil, err
+	}
+	if dhcpSocketPath != "" {
+		if err := checkDHCPDaemon(dhcpSocketPath); err != nil {
+			return nil, err
+		}
 	}
 	klog.FromContext(ctx).V(3).Info("Runtime.AttachNetwork", "deviceConfig", deviceConfig)
 
-	cniResult, err := rntm.CNIConfig.AddNetwork(ctx, pluginConf, rt)
+	opCtx, cancel := rntm.operationContext(ctx, deviceConfig)
+	defer cancel()
+	cniResult, err := rntm.CNIConfig.AddNetworkList(opCtx, confList, rt)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to AddNetwork: %v", err)
+		return nil, fmt.Errorf("failed to AddNetworkList: %w", operationError(opCtx, err))
 	}
 	if cniResult == nil {
-		return nil, nil, fmt.Errorf("cni result is nil")
+		return nil, fmt.Errorf("cni result is nil")
 	}
 
 	klog.FromContext(ctx).V(3).Info("Runtime.AttachedNetwork", "cniResult", cniResult)
 	// Convert to NetworkDeviceData (minimal info)
-	netData, err := cniResultToNetworkData(cniResult)
+	netData, err := cniResultToNetworkData(cniResult, deviceConfig.IfName)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if rntm.InterfaceReader != nil {
+		// the interface is attached, reading it is best effort
+		podInterface, err := rntm.InterfaceReader.ReadInterface(podNetworkNamespace, netData.InterfaceName)
+		if err != nil {
+			klog.FromContext(ctx).V(2).Info("Failed to read the attached interface, reporting the CNI result only",
+				"interface", netData.InterfaceName, "netns", podNetworkNamespace, "error", err.Error())
+		} else {
+			completeNetworkData(netData, podInterface)
+		}
+	}
+	result := &AttachResult{
+		NetworkDeviceData: netData,
+		NetConf:           string(rawNetConf),
 	}
 
 	// Convert full CNI 1.0.0 result to a generic map to avoid information loss
 	cni100Result, err := cni100.NewResultFromResult(cniResult)
 	if err != nil {
-		return netData, nil, fmt.Errorf("failed to convert CNI result to 1.0.0: %v", err)
+		return result, fmt.Errorf("failed to convert CNI result to 1.0.0: %v", err)
 	}
 	raw, err := json.Marshal(cni100Result)
 	if err != nil {
-		return netData, nil, fmt.Errorf("failed to marshal CNI result: %v", err)
+		return result, fmt.Errorf("failed to marshal CNI result: %v", err)
 	}
-	var resultMap map[string]interface{}
-	if err := json.Unmarshal(raw, &resultMap); err != nil {
-		return netData, nil, fmt.Errorf("failed to unmarshal CNI result into map: %v", err)
+	result.RawCNIResult = string(raw)
+	if err := json.Unmarshal(raw, &result.CNIResult); err != nil {
+		return result, fmt.Errorf("failed to unmarshal CNI result into map: %v", err)
 	}
 
-	return netData, resultMap, nil
+	return result, nil
 }
 
 // DetachNetworks detaches all network interfaces associated with a given pod.
 // It is typically called during pod teardown to clean up network resources.
+// The netconf and result recorded at attach time are preferred, so DEL still works when the
+// NetworkAttachmentDefinition changed or the CNI cache was lost since the attachment.
 func (rntm *Runtime) DetachNetwork(
 	ctx context.Context,
 	pod *api.PodSandbox,
@@ -131,7 +163,146 @@ func (rntm *Runtime) DetachNetwork(
 	deviceConfig *types.PreparedDevice,
 ) error {
 	klog.FromContext(ctx).Info("Runtime.DetachNetwork", "deviceConfig", deviceConfig)
-	rt := &libcni.RuntimeConf{
+	rt := runtimeConf(pod, podNetworkNamespace, deviceConfig)
+	confList, err := rntm.attachedConfList(deviceConfig)
+	if err != nil {
+		return err
+	}
+
+	// without a cached ADD result libcni would call DEL without prevResult, hand it over to
+	// every plugin of the list from the recorded one
+	if deviceConfig.CNIResult != "" {
+		cachedResult, err := rntm.CNIConfig.GetNetworkListCachedResult(confList, rt)
+		if err != nil || cachedResult == nil {
+			klog.FromContext(ctx).V(2).Info("No cached CNI result, using the recorded one", "deviceName", deviceConfig.Device.DeviceName)
+			for i, plugin := range confList.Plugins {
+				confList.Plugins[i], err = libcni.InjectConf(plugin, map[string]interface{}{"prevResult": json.RawMessage(deviceConfig.CNIResult)})
+				if err != nil {
+					return fmt.Errorf("failed to inject prevResult: %v", err)
+				}
+			}
+		}
+	}
+
+	klog.FromContext(ctx).V(3).Info("Runtime.DetachNetwork", "deviceConfig", deviceConfig)
+	opCtx, cancel := rntm.operationContext(ctx, deviceConfig)
+	defer cancel()
+	err = rntm.CNIConfig.DelNetworkList(opCtx, confList, rt)
+	if err != nil {
+		return fmt.Errorf("failed to DelNetworkList: %v", operationError(opCtx, err))
+	}
+
+	return nil
+}
+
+// CheckNetwork runs CNI CHECK for an already attached device. libcni compares
+// the plugin's view of the attachment against the cached ADD result, so a
+// non-nil error means the interface inside the pod no longer matches what was
+// configured (e.g. it was deleted or renamed).
+func (rntm *Runtime) CheckNetwork(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	podNetworkNamespace string,
+	deviceConfig *types.PreparedDevice,
+) error {
+	rt := runtimeConf(pod, podNetworkNamespace, deviceConfig)
+	confList, err := rntm.attachedConfList(deviceConfig)
+	if err != nil {
+		return err
+	}
+	klog.FromContext(ctx).V(3).Info("Runtime.CheckNetwork", "deviceConfig", deviceConfig)
+	opCtx, cancel := rntm.operationContext(ctx, deviceConfig)
+	defer cancel()
+	if err := rntm.CNIConfig.CheckNetworkList(opCtx, confList, rt); err != nil {
+		return fmt.Errorf("failed to CheckNetworkList: %v", operationError(opCtx, err))
+	}
+
+	return nil
+}
+
+// attachedConfList returns the network configuration list of an attached device, using the
+// netconf recorded at attach time when available.
+func (rntm *Runtime) attachedConfList(deviceConfig *types.PreparedDevice) (*libcni.NetworkConfigList, error) {
+	rawNetConf := []byte(deviceConfig.CNINetConf)
+	if len(rawNetConf) == 0 {
+		var err error
+		rawNetConf, err = netattdefclientutils.GetCNIConfigFromSpec(deviceConfig.NetAttachDefConfig, rntm.DriverName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
+		}
+	}
+	confList, err := networkConfList(rawNetConf)
+	if err != nil {
+		return nil, err
+	}
+	// DEL releases the DHCP lease through the daemon, so it needs the socket as well
+	if _, err := rntm.withDHCPSocket(confList); err != nil {
+		return nil, err
+	}
+	return confList, nil
+}
+
+// networkConfList parses a netconf, either a plugin list (conflist) chaining several plugins
+// or a single plugin configuration, which is wrapped in a list of one plugin. Both share the
+// network name, so the CNI cache of attachments made with a single plugin is still found.
+func networkConfList(rawNetConf []byte) (*libcni.NetworkConfigList, error) {
+	rawConf := map[string]interface{}{}
+	if err := json.Unmarshal(rawNetConf, &rawConf); err != nil {
+		return nil, fmt.Errorf("failed to parse netconf: %v", err)
+	}
+	if _, isList := rawConf["plugins"]; !isList {
+		rawList := map[string]interface{}{
+			"name":    rawConf["name"],
+			"plugins": []interface{}{rawConf},
+		}
+		if cniVersion, ok := rawConf["cniVersion"]; ok {
+			rawList["cniVersion"] = cniVersion
+		}
+		var err error
+		rawNetConf, err = json.Marshal(rawList)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap netconf in a list: %v", err)
+		}
+	}
+
+	confList, err := libcni.NetworkConfFromBytes(rawNetConf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to NetworkConfFromBytes: %v", err)
+	}
+	if len(confList.Plugins) == 0 {
+		return nil, fmt.Errorf("netconf %q has no plugins", confList.Name)
+	}
+	return confList, nil
+}
+
+// errOperationTimeout is the cause of the cancellation of a CNI operation exceeding its timeout.
+var errOperationTimeout = errors.New("CNI operation timed out")
+
+// operationContext returns the context a CNI operation on a device runs with, bounded by the
+// timeout of the device config or else the runtime one. The plugin is killed once it expires.
+func (rntm *Runtime) operationContext(ctx context.Context, deviceConfig *types.PreparedDevice) (context.Context, context.CancelFunc) {
+	timeout := rntm.Timeout
+	if deviceConfig.Config != nil && deviceConfig.Config.CNITimeout != nil {
+		timeout = deviceConfig.Config.CNITimeout.Duration
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", errOperationTimeout, timeout))
+}
+
+// operationError reports the timeout instead of the plugin being killed when the operation
+// context expired.
+func operationError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", context.Cause(ctx), err)
+	}
+	return err
+}
+
+// runtimeConf returns the CNI runtime configuration of a device of a pod.
+func runtimeConf(pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) *libcni.RuntimeConf {
+	return &libcni.RuntimeConf{
 		ContainerID: pod.Id,
 		NetNS:       podNetworkNamespace,
 		IfName:      deviceConfig.IfName,
@@ -143,20 +314,4 @@ func (rntm *Runtime) DetachNetwork(
 			{"K8S_POD_UID", pod.Uid},
 		},
 	}
-	rawNetConf, err := netattdefclientutils.GetCNIConfigFromSpec(deviceConfig.NetAttachDefConfig, rntm.DriverName)
-	if err != nil {
-		return fmt.Errorf("failed to GetCNIConfigFromSpec: %v", err)
-	}
-
-	pluginConf, err := libcni.NetworkPluginConfFromBytes(rawNetConf)
-	if err != nil {
-		return fmt.Errorf("failed to NetworkPluginConfFromBytes: %v", err)
-	}
-	klog.FromContext(ctx).V(3).Info("Runtime.DetachNetwork", "deviceConfig", deviceConfig)
-	err = rntm.CNIConfig.DelNetwork(ctx, pluginConf, rt)
-	if err != nil {
-		return fmt.Errorf("failed to DelNetwork: %v", err)
-	}
-
-	return nil
 }
@@ -3,12 +3,21 @@ package cni_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"net"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
+	"github.com/containernetworking/cni/libcni"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
@@ -25,7 +34,7 @@ var _ = Describe("CNI", func() {
 		ctx = context.Background()
 
 		// Create runtime
-		runtime = cni.New("test-driver", []string{"/opt/cni/bin"})
+		runtime = cni.New("test-driver", []string{"/opt/cni/bin"}, 0, "")
 
 		pod = &api.PodSandbox{
 			Id:        "test-container-id",
@@ -42,7 +51,7 @@ var _ = Describe("CNI", func() {
 			driverName := "test-driver"
 			cniPath := []string{"/opt/cni/bin"}
 
-			runtime := cni.New(driverName, cniPath)
+			runtime := cni.New(driverName, cniPath, 0, "")
 
 			Expect(runtime).NotTo(BeNil())
 			Expect(runtime.DriverName).To(Equal(driverName))
@@ -50,7 +59,7 @@ var _ = Describe("CNI", func() {
 		})
 
 		It("should handle empty CNI path", func() {
-			runtime := cni.New("test-driver", []string{})
+			runtime := cni.New("test-driver", []string{}, 0, "")
 
 			Expect(runtime).NotTo(BeNil())
 			Expect(runtime.DriverName).To(Equal("test-driver"))
@@ -58,7 +67,7 @@ var _ = Describe("CNI", func() {
 
 		It("should handle multiple CNI paths", func() {
 			paths := []string{"/opt/cni/bin", "/usr/local/bin"}
-			runtime := cni.New("test-driver", paths)
+			runtime := cni.New("test-driver", paths, 0, "")
 
 			Expect(runtime).NotTo(BeNil())
 			Expect(runtime.DriverName).To(Equal("test-driver"))
@@ -72,7 +81,7 @@ var _ = Describe("CNI", func() {
 				NetAttachDefConfig: `invalid json`,
 			}
 
-			_, _, err := runtime.AttachNetwork(ctx, pod, netNS, invalidConfig)
+			_, err := runtime.AttachNetwork(ctx, pod, netNS, invalidConfig)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to GetCNIConfigFromSpec"))
@@ -84,10 +93,90 @@ var _ = Describe("CNI", func() {
 				NetAttachDefConfig: `{}`,
 			}
 
-			_, _, err := runtime.AttachNetwork(ctx, pod, netNS, emptyConfig)
+			_, err := runtime.AttachNetwork(ctx, pod, netNS, emptyConfig)
 
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("runs every plugin of a plugin list", func() {
+			fake := &fakeCNI{addResult: &cni100.Result{
+				CNIVersion: "1.0.0",
+				Interfaces: []*cni100.Interface{{Name: "net1", Sandbox: netNS}},
+			}}
+			runtime.CNIConfig = fake
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","plugins":[{"type":"sriov","deviceID":"0000:00:00.1"},{"type":"tuning","mtu":9000}]}`,
+			}
+
+			result, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.addedConf.Name).To(Equal("test-driver"))
+			Expect(fake.addedConf.CNIVersion).To(Equal("1.0.0"))
+			Expect(fake.addedConf.Plugins).To(HaveLen(2))
+			Expect(fake.addedConf.Plugins[0].Network.Type).To(Equal("sriov"))
+			Expect(fake.addedConf.Plugins[1].Network.Type).To(Equal("tuning"))
+			Expect(result.NetConf).To(ContainSubstring(`"plugins"`))
+			Expect(result.RawCNIResult).To(ContainSubstring(`"net1"`))
+		})
+
+		It("wraps a single plugin configuration in a list", func() {
+			fake := &fakeCNI{addResult: &cni100.Result{CNIVersion: "1.0.0"}}
+			runtime.CNIConfig = fake
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"net1","type":"sriov"}`,
+			}
+
+			_, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fake.addedConf.Name).To(Equal("net1"))
+			Expect(fake.addedConf.Plugins).To(HaveLen(1))
+			Expect(fake.addedConf.Plugins[0].Network.Type).To(Equal("sriov"))
+		})
+
+		It("completes a minimal CNI result with the interface of the pod network namespace", func() {
+			fake := &fakeCNI{addResult: &cni100.Result{
+				CNIVersion: "1.0.0",
+				Interfaces: []*cni100.Interface{{Name: "net1", Sandbox: netNS}},
+			}}
+			runtime.CNIConfig = fake
+			reader := &fakeInterfaceReader{podInterface: &cni.PodInterface{
+				HardwareAddress: "aa:bb:cc:dd:ee:ff",
+				IPs:             []string{"10.1.2.3/24", "fd00::3/64"},
+			}}
+			runtime.InterfaceReader = reader
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"net1","type":"sriov"}`,
+			}
+
+			result, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reader.netnsPath).To(Equal(netNS))
+			Expect(reader.ifName).To(Equal("net1"))
+			Expect(result.NetworkDeviceData.InterfaceName).To(Equal("net1"))
+			Expect(result.NetworkDeviceData.HardwareAddress).To(Equal("aa:bb:cc:dd:ee:ff"))
+			Expect(result.NetworkDeviceData.IPs).To(Equal([]string{"10.1.2.3/24", "fd00::3/64"}))
+		})
+
+		It("reports the CNI result when the interface cannot be read", func() {
+			fake := &fakeCNI{addResult: &cni100.Result{
+				CNIVersion: "1.0.0",
+				Interfaces: []*cni100.Interface{{Name: "net1", Mac: "aa:bb:cc:dd:ee:ff", Sandbox: netNS}},
+			}}
+			runtime.CNIConfig = fake
+			runtime.InterfaceReader = &fakeInterfaceReader{err: errors.New("no such network namespace")}
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"net1","type":"sriov"}`,
+			}
+
+			result, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.NetworkDeviceData.HardwareAddress).To(Equal("aa:bb:cc:dd:ee:ff"))
+			Expect(result.NetworkDeviceData.IPs).To(BeEmpty())
+		})
 	})
 
 	Context("DetachNetwork", func() {
@@ -115,6 +204,227 @@ var _ = Describe("CNI", func() {
 		})
 	})
 
+	Context("DetachNetwork with a recorded attachment", func() {
+		var fake *fakeCNI
+
+		BeforeEach(func() {
+			fake = &fakeCNI{}
+			runtime.CNIConfig = fake
+		})
+
+		It("uses the recorded netconf instead of the NetworkAttachmentDefinition", func() {
+			device := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `invalid json`,
+				CNINetConf:         `{"cniVersion":"1.0.0","name":"net1","type":"sriov","deviceID":"0000:00:00.1"}`,
+			}
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(fake.deletedConf).NotTo(BeNil())
+			Expect(fake.deletedConf.Plugins).To(HaveLen(1))
+			Expect(fake.deletedConf.Plugins[0].Network.Type).To(Equal("sriov"))
+			Expect(string(fake.deletedConf.Plugins[0].Bytes)).To(ContainSubstring(`"deviceID":"0000:00:00.1"`))
+			Expect(string(fake.deletedConf.Plugins[0].Bytes)).NotTo(ContainSubstring("prevResult"))
+		})
+
+		It("injects the recorded result when the CNI cache is missing", func() {
+			fake.cachedResultErr = errors.New("no cache")
+			device := &types.PreparedDevice{
+				IfName:     "net1",
+				CNINetConf: `{"cniVersion":"1.0.0","name":"net1","type":"sriov"}`,
+				CNIResult:  `{"cniVersion":"1.0.0","interfaces":[{"name":"net1"}]}`,
+			}
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(string(fake.deletedConf.Plugins[0].Bytes)).To(ContainSubstring(`"prevResult"`))
+			Expect(string(fake.deletedConf.Plugins[0].Bytes)).To(ContainSubstring(`"interfaces"`))
+		})
+
+		It("detaches every plugin of a recorded plugin list", func() {
+			fake.cachedResultErr = errors.New("no cache")
+			device := &types.PreparedDevice{
+				IfName:     "net1",
+				CNINetConf: `{"cniVersion":"1.0.0","name":"net1","plugins":[{"type":"sriov"},{"type":"tuning"},{"type":"sbr"}]}`,
+				CNIResult:  `{"cniVersion":"1.0.0","interfaces":[{"name":"net1"}]}`,
+			}
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(fake.deletedConf.Name).To(Equal("net1"))
+			Expect(fake.deletedConf.Plugins).To(HaveLen(3))
+			for _, plugin := range fake.deletedConf.Plugins {
+				Expect(string(plugin.Bytes)).To(ContainSubstring(`"prevResult"`))
+			}
+			Expect(fake.deletedConf.Plugins[1].Network.Type).To(Equal("tuning"))
+		})
+
+		It("keeps the cached result when present", func() {
+			fake.cachedResult = &cni100.Result{CNIVersion: "1.0.0"}
+			device := &types.PreparedDevice{
+				IfName:     "net1",
+				CNINetConf: `{"cniVersion":"1.0.0","name":"net1","type":"sriov"}`,
+				CNIResult:  `{"cniVersion":"1.0.0","interfaces":[{"name":"net1"}]}`,
+			}
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(string(fake.deletedConf.Plugins[0].Bytes)).NotTo(ContainSubstring("prevResult"))
+		})
+	})
+
+	Context("DHCP IPAM", func() {
+		var (
+			fake       *fakeCNI
+			socketPath string
+			device     *types.PreparedDevice
+		)
+
+		BeforeEach(func() {
+			fake = &fakeCNI{addResult: &cni100.Result{CNIVersion: "1.0.0"}}
+			runtime.CNIConfig = fake
+			// unix socket paths are limited in length, keep it short
+			dir, err := os.MkdirTemp("", "dhcp")
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(os.RemoveAll, dir)
+			socketPath = filepath.Join(dir, "dhcp.sock")
+			runtime.DHCPSocketPath = socketPath
+			device = &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `{"cniVersion":"1.0.0","name":"net1","type":"sriov","ipam":{"type":"dhcp"}}`,
+			}
+		})
+
+		It("hands the daemon socket to the dhcp IPAM", func() {
+			listener, err := net.Listen("unix", socketPath)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(listener.Close)
+
+			_, err = runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fake.addedConf.Plugins[0].Bytes)).To(ContainSubstring(`"daemonSocketPath":"` + socketPath + `"`))
+		})
+
+		It("keeps the socket path set in the netconf", func() {
+			listener, err := net.Listen("unix", socketPath)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(listener.Close)
+			runtime.DHCPSocketPath = "/run/other/dhcp.sock"
+			device.NetAttachDefConfig = `{"cniVersion":"1.0.0","name":"net1","type":"sriov","ipam":{"type":"dhcp","daemonSocketPath":"` + socketPath + `"}}`
+
+			_, err = runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fake.addedConf.Plugins[0].Bytes)).NotTo(ContainSubstring("/run/other"))
+		})
+
+		It("fails the attachment when the daemon is not running", func() {
+			_, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("DHCP daemon is not reachable"))
+			Expect(fake.addedConf).To(BeNil())
+		})
+
+		It("hands the daemon socket to the dhcp IPAM on detach", func() {
+			device.CNINetConf = `{"cniVersion":"1.0.0","name":"net1","type":"sriov","ipam":{"type":"dhcp"}}`
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			Expect(string(fake.deletedConf.Plugins[0].Bytes)).To(ContainSubstring(`"daemonSocketPath":"` + socketPath + `"`))
+		})
+	})
+
+	Context("Status", func() {
+		var binDir string
+
+		// writePlugin installs a fake sriov plugin answering STATUS with the given script body
+		writePlugin := func(body string) {
+			script := "#!/bin/sh\nif [ \"$CNI_COMMAND\" = STATUS ]; then\n" + body + "\nfi\n"
+			Expect(os.WriteFile(filepath.Join(binDir, "sriov"), []byte(script), 0o755)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			binDir = GinkgoT().TempDir()
+			runtime = cni.New("test-driver", []string{binDir}, 5*time.Second, "")
+		})
+
+		It("succeeds when the plugin reports it is ready", func() {
+			writePlugin("exit 0")
+			Expect(runtime.Status(ctx)).To(Succeed())
+		})
+
+		It("fails when the plugin binary is missing", func() {
+			err := runtime.Status(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("CNI plugin sriov is not ready"))
+		})
+
+		It("fails when the plugin reports it is not available", func() {
+			writePlugin(`echo '{"cniVersion":"1.1.0","code":50,"msg":"switchdev mode unavailable"}'; exit 1`)
+			err := runtime.Status(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("switchdev mode unavailable"))
+		})
+
+		It("succeeds when the plugin predates the STATUS verb", func() {
+			writePlugin(`echo '{"cniVersion":"1.0.0","code":4,"msg":"unknown CNI_COMMAND: STATUS"}'; exit 1`)
+			Expect(runtime.Status(ctx)).To(Succeed())
+
+			writePlugin(`echo '{"cniVersion":"1.0.0","code":1,"msg":"plugin version does not allow STATUS"}'; exit 1`)
+			Expect(runtime.Status(ctx)).To(Succeed())
+		})
+	})
+
+	Context("Timeouts", func() {
+		var (
+			fake   *fakeCNI
+			device *types.PreparedDevice
+		)
+
+		BeforeEach(func() {
+			fake = &fakeCNI{hang: true}
+			runtime.CNIConfig = fake
+			device = &types.PreparedDevice{
+				IfName:     "net1",
+				CNINetConf: `{"cniVersion":"1.0.0","name":"net1","type":"sriov"}`,
+			}
+		})
+
+		It("aborts an operation exceeding the runtime timeout", func() {
+			runtime.Timeout = 10 * time.Millisecond
+
+			err := runtime.DetachNetwork(ctx, pod, netNS, device)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("timed out after 10ms"))
+		})
+
+		It("prefers the timeout of the device config", func() {
+			runtime.Timeout = time.Hour
+			device.Config = &configapi.VfConfig{CNITimeout: &metav1.Duration{Duration: 10 * time.Millisecond}}
+
+			err := runtime.DetachNetwork(ctx, pod, netNS, device)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("timed out after 10ms"))
+		})
+
+		It("does not bound operations when no timeout is set", func() {
+			fake.hang = false
+
+			Expect(runtime.DetachNetwork(ctx, pod, netNS, device)).To(Succeed())
+			_, hasDeadline := fake.deleteCtx.Deadline()
+			Expect(hasDeadline).To(BeFalse())
+		})
+	})
+
+	Context("CheckNetwork", func() {
+		It("should handle invalid CNI configuration parsing", func() {
+			invalidConfig := &types.PreparedDevice{
+				IfName:             "net1",
+				NetAttachDefConfig: `invalid json`,
+			}
+
+			err := runtime.CheckNetwork(ctx, pod, netNS, invalidConfig)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to GetCNIConfigFromSpec"))
+		})
+	})
+
 	Context("RawExec", func() {
 		var rawExec *cni.RawExec
 
@@ -184,9 +494,55 @@ var _ = Describe("CNI", func() {
 			}
 
 			for _, device := range devices {
-				_, _, err := runtime.AttachNetwork(ctx, pod, netNS, device)
+				_, err := runtime.AttachNetwork(ctx, pod, netNS, device)
 				Expect(err).To(HaveOccurred()) // Expected to fail due to invalid config
 			}
 		})
 	})
 })
+
+// fakeInterfaceReader returns a fixed interface and records the interface it was asked for.
+type fakeInterfaceReader struct {
+	podInterface *cni.PodInterface
+	err          error
+	netnsPath    string
+	ifName       string
+}
+
+func (f *fakeInterfaceReader) ReadInterface(netnsPath, ifName string) (*cni.PodInterface, error) {
+	f.netnsPath, f.ifName = netnsPath, ifName
+	return f.podInterface, f.err
+}
+
+// fakeCNI records the configuration passed to AddNetworkList and DelNetworkList. Other libcni
+// calls are not expected.
+type fakeCNI struct {
+	libcni.CNI
+	addResult       cnitypes.Result
+	addedConf       *libcni.NetworkConfigList
+	cachedResult    cnitypes.Result
+	cachedResultErr error
+	deletedConf     *libcni.NetworkConfigList
+	deleteCtx       context.Context
+	// hang makes DelNetwork block until its context is done, like a stuck plugin
+	hang bool
+}
+
+func (f *fakeCNI) AddNetworkList(_ context.Context, list *libcni.NetworkConfigList, _ *libcni.RuntimeConf) (cnitypes.Result, error) {
+	f.addedConf = list
+	return f.addResult, nil
+}
+
+func (f *fakeCNI) GetNetworkListCachedResult(_ *libcni.NetworkConfigList, _ *libcni.RuntimeConf) (cnitypes.Result, error) {
+	return f.cachedResult, f.cachedResultErr
+}
+
+func (f *fakeCNI) DelNetworkList(ctx context.Context, list *libcni.NetworkConfigList, _ *libcni.RuntimeConf) error {
+	f.deletedConf = list
+	f.deleteCtx = ctx
+	if f.hang {
+		<-ctx.Done()
+		return errors.New("signal: killed")
+	}
+	return nil
+}
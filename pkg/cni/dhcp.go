@@ -0,0 +1,82 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/containernetworking/cni/libcni"
+)
+
+const (
+	// dhcpIPAMType is the type of the IPAM plugin leasing addresses through the CNI DHCP daemon.
+	dhcpIPAMType = "dhcp"
+	// DefaultDHCPSocketPath is the socket the CNI DHCP daemon listens on by default.
+	DefaultDHCPSocketPath = "/run/cni/dhcp.sock"
+	// dhcpDialTimeout bounds the liveness check of the DHCP daemon socket.
+	dhcpDialTimeout = time.Second
+)
+
+// withDHCPSocket points the dhcp IPAM of the plugins of a list at the configured daemon
+// socket, unless the netconf sets daemonSocketPath itself. It returns the socket path used
+// by the first dhcp IPAM of the list, or an empty string if the list does not use dhcp IPAM.
+func (rntm *Runtime) withDHCPSocket(confList *libcni.NetworkConfigList) (string, error) {
+	socketPath := ""
+	for i, plugin := range confList.Plugins {
+		if plugin.Network.IPAM.Type != dhcpIPAMType {
+			continue
+		}
+		rawPlugin := map[string]interface{}{}
+		if err := json.Unmarshal(plugin.Bytes, &rawPlugin); err != nil {
+			return "", fmt.Errorf("failed to parse plugin config: %v", err)
+		}
+		ipam, ok := rawPlugin["ipam"].(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("invalid ipam in plugin config")
+		}
+		path, _ := ipam["daemonSocketPath"].(string)
+		if path == "" && rntm.DHCPSocketPath != "" {
+			path = rntm.DHCPSocketPath
+			ipam["daemonSocketPath"] = path
+			injected, err := libcni.InjectConf(plugin, map[string]interface{}{"ipam": ipam})
+			if err != nil {
+				return "", fmt.Errorf("failed to inject dhcp daemon socket path: %v", err)
+			}
+			confList.Plugins[i] = injected
+		}
+		if path == "" {
+			path = DefaultDHCPSocketPath
+		}
+		if socketPath == "" {
+			socketPath = path
+		}
+	}
+	return socketPath, nil
+}
+
+// checkDHCPDaemon verifies that the DHCP daemon accepts connections on its socket, so an
+// attachment fails right away with a clear error instead of inside the dhcp IPAM plugin.
+func checkDHCPDaemon(socketPath string) error {
+	conn, err := net.DialTimeout("unix", socketPath, dhcpDialTimeout)
+	if err != nil {
+		return fmt.Errorf("DHCP daemon is not reachable on %s, is the CNI dhcp daemon running?: %v", socketPath, err)
+	}
+	return conn.Close()
+}
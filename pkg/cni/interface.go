@@ -27,10 +27,23 @@ import (
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
+// AttachResult is the outcome of a CNI ADD for a device.
+type AttachResult struct {
+	// NetworkDeviceData is the network data reported in the ResourceClaim status.
+	NetworkDeviceData *resourcev1.NetworkDeviceData
+	// CNIResult is the full CNI 1.0.0 result as a generic map.
+	CNIResult map[string]interface{}
+	// NetConf is the network configuration passed to the CNI plugin.
+	NetConf string
+	// RawCNIResult is the CNI 1.0.0 result as JSON.
+	RawCNIResult string
+}
+
 // Interface abstracts the CNI runtime to enable mocking in unit tests.
 type Interface interface {
-	AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*resourcev1.NetworkDeviceData, map[string]interface{}, error)
+	AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*AttachResult, error)
 	DetachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error
+	CheckNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error
 }
 
 // Ensure Runtime implements Interface.
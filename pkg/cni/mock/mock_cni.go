@@ -14,9 +14,9 @@ import (
 	reflect "reflect"
 
 	api "github.com/containerd/nri/pkg/api"
+	cni "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
 	types "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 	gomock "go.uber.org/mock/gomock"
-	v1 "k8s.io/api/resource/v1"
 )
 
 // MockInterface is a mock of Interface interface.
@@ -44,13 +44,12 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 }
 
 // AttachNetwork mocks base method.
-func (m *MockInterface) AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*v1.NetworkDeviceData, map[string]any, error) {
+func (m *MockInterface) AttachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) (*cni.AttachResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "AttachNetwork", ctx, pod, podNetworkNamespace, deviceConfig)
-	ret0, _ := ret[0].(*v1.NetworkDeviceData)
-	ret1, _ := ret[1].(map[string]any)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret0, _ := ret[0].(*cni.AttachResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // AttachNetwork indicates an expected call of AttachNetwork.
@@ -59,6 +58,20 @@ func (mr *MockInterfaceMockRecorder) AttachNetwork(ctx, pod, podNetworkNamespace
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachNetwork", reflect.TypeOf((*MockInterface)(nil).AttachNetwork), ctx, pod, podNetworkNamespace, deviceConfig)
 }
 
+// CheckNetwork mocks base method.
+func (m *MockInterface) CheckNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckNetwork", ctx, pod, podNetworkNamespace, deviceConfig)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckNetwork indicates an expected call of CheckNetwork.
+func (mr *MockInterfaceMockRecorder) CheckNetwork(ctx, pod, podNetworkNamespace, deviceConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckNetwork", reflect.TypeOf((*MockInterface)(nil).CheckNetwork), ctx, pod, podNetworkNamespace, deviceConfig)
+}
+
 // DetachNetwork mocks base method.
 func (m *MockInterface) DetachNetwork(ctx context.Context, pod *api.PodSandbox, podNetworkNamespace string, deviceConfig *types.PreparedDevice) error {
 	m.ctrl.T.Helper()
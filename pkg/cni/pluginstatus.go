@@ -0,0 +1,73 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/containernetworking/cni/libcni"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	// sriovPluginType is the type of the CNI plugin attaching the VFs.
+	sriovPluginType = "sriov"
+	// statusCNIVersion is the first CNI version defining the STATUS verb.
+	statusCNIVersion = "1.1.0"
+)
+
+// Status runs the CNI STATUS verb against the sriov plugin, failing when the plugin binary is
+// missing or the plugin reports that it cannot attach devices, e.g. because a prerequisite is
+// unavailable. Plugins implementing a CNI version older than 1.1.0 cannot report their status,
+// finding their binary is all that is checked for them.
+func (rntm *Runtime) Status(ctx context.Context) error {
+	rawList, err := json.Marshal(map[string]interface{}{
+		"cniVersion": statusCNIVersion,
+		"name":       rntm.DriverName,
+		"plugins":    []interface{}{map[string]interface{}{"type": sriovPluginType}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal status config: %v", err)
+	}
+	confList, err := libcni.NetworkConfFromBytes(rawList)
+	if err != nil {
+		return fmt.Errorf("failed to parse status config: %v", err)
+	}
+
+	opCtx, cancel := rntm.operationContext(ctx, &types.PreparedDevice{})
+	defer cancel()
+	err = rntm.CNIConfig.GetStatusNetworkList(opCtx, confList)
+	if err != nil && !statusUnsupported(err) {
+		return fmt.Errorf("CNI plugin %s is not ready: %w", sriovPluginType, operationError(opCtx, err))
+	}
+	return nil
+}
+
+// statusUnsupported reports whether a STATUS error comes from a plugin predating the verb.
+func statusUnsupported(err error) bool {
+	var cniErr *cnitypes.Error
+	if !errors.As(err, &cniErr) {
+		return false
+	}
+	// plugins built with an older skel reject the verb as an unknown command
+	return cniErr.Code == cnitypes.ErrIncompatibleCNIVersion || cniErr.Code == cnitypes.ErrInvalidEnvironmentVariables
+}
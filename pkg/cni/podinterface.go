@@ -0,0 +1,104 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	resourcev1 "k8s.io/api/resource/v1"
+)
+
+// PodInterface is the state of an interface in the network namespace of a pod.
+type PodInterface struct {
+	HardwareAddress string
+	// IPs are the global addresses of the interface, in CIDR notation.
+	IPs []string
+}
+
+// InterfaceReader reads the interfaces of the network namespace of a pod.
+type InterfaceReader interface {
+	ReadInterface(netnsPath, ifName string) (*PodInterface, error)
+}
+
+// netlinkInterfaceReader reads the interfaces of a pod with netlink.
+type netlinkInterfaceReader struct{}
+
+// ReadInterface returns the hardware address and the global addresses of an interface of the
+// network namespace of a pod.
+func (netlinkInterfaceReader) ReadInterface(netnsPath, ifName string) (*PodInterface, error) {
+	ns, err := netns.GetFromPath(netnsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open network namespace %q: %w", netnsPath, err)
+	}
+	defer ns.Close()
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create netlink handle in network namespace %q: %w", netnsPath, err)
+	}
+	defer handle.Close()
+
+	link, err := handle.LinkByName(ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface %s: %w", ifName, err)
+	}
+	addrs, err := handle.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses of interface %s: %w", ifName, err)
+	}
+
+	podInterface := &PodInterface{HardwareAddress: link.Attrs().HardwareAddr.String()}
+	for _, addr := range addrs {
+		// link-local addresses are assigned to every interface and are of no use to consumers
+		if addr.Scope != int(netlink.SCOPE_UNIVERSE) {
+			continue
+		}
+		podInterface.IPs = append(podInterface.IPs, addr.IPNet.String())
+	}
+	return podInterface, nil
+}
+
+// completeNetworkData fills what the CNI result did not report with the interface read from the
+// pod network namespace: the hardware address when missing, and the addresses not reported,
+// e.g. assigned by a chained plugin or by SLAAC.
+func completeNetworkData(networkData *resourcev1.NetworkDeviceData, podInterface *PodInterface) {
+	if networkData.HardwareAddress == "" {
+		networkData.HardwareAddress = podInterface.HardwareAddress
+	}
+	for _, address := range podInterface.IPs {
+		if !containsIP(networkData.IPs, address) {
+			networkData.IPs = append(networkData.IPs, address)
+		}
+	}
+}
+
+// containsIP reports whether a list of addresses in CIDR notation contains the IP of an address,
+// whatever its prefix.
+func containsIP(addresses []string, address string) bool {
+	ip, _, err := net.ParseCIDR(address)
+	if err != nil {
+		return false
+	}
+	for _, other := range addresses {
+		if otherIP, _, err := net.ParseCIDR(other); err == nil && otherIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+)
+
+// retryableMessages are fragments of the errors of CNI plugins failing on conditions expected to
+// clear up on their own, such as an IP lease still held by a sandbox being torn down or a netlink
+// operation racing with another one on the same PF.
+var retryableMessages = []string{
+	"try again",
+	"resource temporarily unavailable",
+	"device or resource busy",
+	"already allocated",
+	"address already in use",
+}
+
+// IsRetryable reports whether a failed CNI ADD is worth retrying: the plugin asked for it with the
+// "try again later" error code, or failed with a transient IPAM or netlink error. Timeouts are not
+// retryable, the operation already used its whole budget.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, errOperationTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var cniErr *cnitypes.Error
+	if errors.As(err, &cniErr) && cniErr.Code == cnitypes.ErrTryAgainLater {
+		return true
+	}
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range retryableMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
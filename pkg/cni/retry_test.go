@@ -0,0 +1,31 @@
+package cni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+)
+
+var _ = Describe("IsRetryable", func() {
+	DescribeTable("classifies CNI ADD errors",
+		func(err error, retryable bool) {
+			Expect(IsRetryable(err)).To(Equal(retryable))
+		},
+		Entry("no error", nil, false),
+		Entry("try again later code", fmt.Errorf("failed to AddNetworkList: %w", &cnitypes.Error{Code: cnitypes.ErrTryAgainLater, Msg: "busy"}), true),
+		Entry("other plugin error code", &cnitypes.Error{Code: cnitypes.ErrInvalidNetworkConfig, Msg: "bad config"}, false),
+		Entry("EAGAIN", fmt.Errorf("netlink: %w", syscall.EAGAIN), true),
+		Entry("EBUSY message from the plugin", &cnitypes.Error{Code: 999, Msg: "failed to set vf 3 mac: device or resource busy"}, true),
+		Entry("IP still allocated", errors.New("failed to allocate for range 0: requested IP address 10.0.0.5 is already allocated to container abc"), true),
+		Entry("timed out", fmt.Errorf("%w after %s: signal: killed, resource temporarily unavailable", errOperationTimeout, time.Second), false),
+		Entry("cancelled", fmt.Errorf("failed to AddNetworkList: %w", context.Canceled), false),
+		Entry("unrelated error", errors.New("failed to find plugin \"sriov\" in path"), false),
+	)
+})
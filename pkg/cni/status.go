@@ -24,24 +24,42 @@ import (
 	resourcev1 "k8s.io/api/resource/v1"
 )
 
-func cniResultToNetworkData(result cnitypes.Result) (*resourcev1.NetworkDeviceData, error) {
-	networkData := &resourcev1.NetworkDeviceData{}
+// cniResultToNetworkData converts a CNI result to the NetworkDeviceData reported in the claim status.
+// The interface name is always set: it is the sandbox interface reported by the plugin for the
+// requested ifName, or the first sandbox interface if the plugin renamed it, and falls back to the
+// requested ifName when the plugin does not report interfaces at all.
+func cniResultToNetworkData(result cnitypes.Result, ifName string) (*resourcev1.NetworkDeviceData, error) {
+	networkData := &resourcev1.NetworkDeviceData{InterfaceName: ifName}
 
 	cniResult, err := cni100.NewResultFromResult(result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to NewResultFromResult result (%v): %v", result, err)
 	}
 
-	for _, ip := range cniResult.IPs {
-		networkData.IPs = append(networkData.IPs, ip.Address.String())
+	ifIndex := -1
+	for idx, ifs := range cniResult.Interfaces {
+		// Only pod interfaces can have sandbox information
+		if ifs.Sandbox == "" {
+			continue
+		}
+		if ifIndex == -1 || ifs.Name == ifName {
+			ifIndex = idx
+		}
+		if ifs.Name == ifName {
+			break
+		}
+	}
+	if ifIndex != -1 {
+		networkData.InterfaceName = cniResult.Interfaces[ifIndex].Name
+		networkData.HardwareAddress = cniResult.Interfaces[ifIndex].Mac
 	}
 
-	for _, ifs := range cniResult.Interfaces {
-		// Only pod interfaces can have sandbox information
-		if ifs.Sandbox != "" {
-			networkData.InterfaceName = ifs.Name
-			networkData.HardwareAddress = ifs.Mac
+	for _, ip := range cniResult.IPs {
+		// Skip addresses explicitly assigned to another interface
+		if ip.Interface != nil && ifIndex != -1 && *ip.Interface != ifIndex {
+			continue
 		}
+		networkData.IPs = append(networkData.IPs, ip.Address.String())
 	}
 
 	return networkData, nil
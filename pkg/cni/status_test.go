@@ -23,7 +23,7 @@ var _ = Describe("CNI Status Conversion", func() {
 				},
 			}
 
-			nd, err := cniResultToNetworkData(res)
+			nd, err := cniResultToNetworkData(res, "eth0")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(nd).To(Equal(&resourcev1.NetworkDeviceData{
 				InterfaceName:   "eth0",
@@ -31,6 +31,89 @@ var _ = Describe("CNI Status Conversion", func() {
 				IPs:             []string{"10.1.2.0/24"},
 			}))
 		})
+
+		It("falls back to the requested interface name when the plugin reports no interfaces", func() {
+			res := &cni100.Result{
+				CNIVersion: "1.0.0",
+				IPs: []*cni100.IPConfig{
+					{Address: mustParseCIDR("10.1.2.3/24")},
+				},
+			}
+
+			nd, err := cniResultToNetworkData(res, "net1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nd.InterfaceName).To(Equal("net1"))
+			Expect(nd.IPs).To(Equal([]string{"10.1.2.0/24"}))
+		})
+
+		It("reports the final interface name when the plugin renamed it", func() {
+			res := &cni100.Result{
+				CNIVersion: "1.0.0",
+				Interfaces: []*cni100.Interface{
+					{Name: "ens1f0v2", Mac: "aa:bb:cc:dd:ee:00"},
+					{Name: "sriov0", Mac: "aa:bb:cc:dd:ee:ff", Sandbox: "/proc/1/ns/net"},
+				},
+			}
+
+			nd, err := cniResultToNetworkData(res, "net1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nd.InterfaceName).To(Equal("sriov0"))
+			Expect(nd.HardwareAddress).To(Equal("aa:bb:cc:dd:ee:ff"))
+		})
+
+		It("selects the requested interface and its addresses among several sandbox interfaces", func() {
+			first, second := 0, 1
+			res := &cni100.Result{
+				CNIVersion: "1.0.0",
+				Interfaces: []*cni100.Interface{
+					{Name: "net0", Mac: "aa:bb:cc:dd:ee:00", Sandbox: "/proc/1/ns/net"},
+					{Name: "net1", Mac: "aa:bb:cc:dd:ee:01", Sandbox: "/proc/1/ns/net"},
+				},
+				IPs: []*cni100.IPConfig{
+					{Interface: &first, Address: mustParseCIDR("10.1.0.3/24")},
+					{Interface: &second, Address: mustParseCIDR("10.1.1.3/24")},
+				},
+			}
+
+			nd, err := cniResultToNetworkData(res, "net1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nd).To(Equal(&resourcev1.NetworkDeviceData{
+				InterfaceName:   "net1",
+				HardwareAddress: "aa:bb:cc:dd:ee:01",
+				IPs:             []string{"10.1.1.0/24"},
+			}))
+		})
+	})
+
+	Context("completeNetworkData", func() {
+		It("fills the missing hardware address and addresses", func() {
+			nd := &resourcev1.NetworkDeviceData{InterfaceName: "net1"}
+
+			completeNetworkData(nd, &PodInterface{
+				HardwareAddress: "aa:bb:cc:dd:ee:ff",
+				IPs:             []string{"10.1.2.3/24", "fd00::3/64"},
+			})
+			Expect(nd).To(Equal(&resourcev1.NetworkDeviceData{
+				InterfaceName:   "net1",
+				HardwareAddress: "aa:bb:cc:dd:ee:ff",
+				IPs:             []string{"10.1.2.3/24", "fd00::3/64"},
+			}))
+		})
+
+		It("keeps what the CNI result reported", func() {
+			nd := &resourcev1.NetworkDeviceData{
+				InterfaceName:   "net1",
+				HardwareAddress: "11:22:33:44:55:66",
+				IPs:             []string{"10.1.2.3/32"},
+			}
+
+			completeNetworkData(nd, &PodInterface{
+				HardwareAddress: "aa:bb:cc:dd:ee:ff",
+				IPs:             []string{"10.1.2.3/24", "fd00::3/64"},
+			})
+			Expect(nd.HardwareAddress).To(Equal("11:22:33:44:55:66"))
+			Expect(nd.IPs).To(Equal([]string{"10.1.2.3/32", "fd00::3/64"}))
+		})
 	})
 })
 
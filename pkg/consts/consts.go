@@ -25,27 +25,20 @@ import (
 )
 
 const (
+	// DefaultDriverName is the name the driver registers with unless configured otherwise
+	DefaultDriverName          = "sriovnetwork.k8snetworkplumbingwg.io"
 	GroupName                  = "sriovnetwork.k8snetworkplumbingwg.io"
-	DriverName                 = "sriovnetwork.k8snetworkplumbingwg.io"
 	DriverPluginCheckpointFile = "checkpoint.json"
+	KubeletDRAStateFile        = "dra_manager_state"
 	MultusAttributePrefix      = "k8s.cni.cncf.io"
 
-	AttributePciAddress         = DriverName + "/pciAddress"
-	AttributePFName             = DriverName + "/PFName"
-	AttributeEswitchMode        = DriverName + "/EswitchMode"
-	AttributeVendorID           = DriverName + "/vendor"
-	AttributeDeviceID           = DriverName + "/deviceID"
-	AttributePFDeviceID         = DriverName + "/pfDeviceID"
-	AttributeVFID               = DriverName + "/vfID"
-	AttributeResourceName       = DriverName + "/resourceName"
-	AttributeLinkType           = DriverName + "/linkType"
-	AttributeRDMACapable        = DriverName + "/rdmaCapable"
 	AttributeMultusDeviceID     = MultusAttributePrefix + "/deviceID"
 	AttributeMultusResourceName = MultusAttributePrefix + "/resourceName"
 	// Use upstream Kubernetes standard attribute prefix for pciAddress
 	AttributeStandardPciAddress = deviceattribute.StandardDeviceAttributePrefix + "pciBusID"
-	// AttributePfPciAddress is for the PCI address of the Physical Function (PF).
-	AttributePfPciAddress = DriverName + "/pfPciAddress"
+	// Standard network attributes of the DRA networking KEPs, published for the VFs having a netdev
+	AttributeStandardInterfaceName = deviceattribute.StandardDeviceAttributePrefix + "interfaceName"
+	AttributeStandardMAC           = deviceattribute.StandardDeviceAttributePrefix + "mac"
 
 	// this is the most-common nonstandard prefix, supported by dranet and dracpu
 	DraNetCompatPrefix = "dra.net"
@@ -54,22 +47,187 @@ const (
 	// Network device constants
 	NetClass  = 0x02 // Network controller class
 	SysBusPci = "/sys/bus/pci/devices"
+	// SysDevicesSystemNode holds the NUMA nodes of the host
+	SysDevicesSystemNode = "/sys/devices/system/node"
+	// SysModuleVFIONoIOMMU is the vfio module parameter enabling the unsafe no-IOMMU mode
+	SysModuleVFIONoIOMMU = "/sys/module/vfio/parameters/enable_unsafe_noiommu_mode"
+	// HugepagesDir is the hugetlbfs mount of the host used by DPDK applications
+	HugepagesDir = "/dev/hugepages"
+	// DefaultVhostUserSocketRoot is the host directory holding the vhost-user socket directories
+	// of the pods unless configured otherwise
+	DefaultVhostUserSocketRoot = "/var/run/dra-driver-sriov/vhost-user"
+	// DefaultVhostUserSocketContainerPath is where the vhost-user socket directory of a pod is
+	// mounted in its containers unless the VfConfig sets it
+	DefaultVhostUserSocketContainerPath = "/var/run/vhost-user"
+
+	// VendorMellanox is the PCI vendor ID of NVIDIA/Mellanox NICs
+	VendorMellanox = "15b3"
 
 	// Link type constants
 	LinkTypeEthernet   = "ethernet"
 	LinkTypeInfiniband = "infiniband"
 	LinkTypeUnknown    = "unknown"
 
+	// Eswitch modes of a PF, see devlink dev eswitch
+	EswitchModeLegacy    = "legacy"
+	EswitchModeSwitchdev = "switchdev"
+
 	// RDMA device constants
 	SysClassInfiniband = "/sys/class/infiniband"
+	// RDMACMDevice is the RDMA connection manager device used by librdmacm, created by rdma_ucm
+	RDMACMDevice = "/dev/infiniband/rdma_cm"
+
+	// DefaultCNIBinDir is the directory searched for CNI plugin binaries unless configured otherwise
+	DefaultCNIBinDir = "/opt/cni/bin"
+
+	// DefaultVFDriver is the VfConfig driver binding a VF back to its default kernel driver
+	DefaultVFDriver = "default"
+
+	// DebugPreparedClaimsPath is the metrics server path listing the prepared claims tracked on the node
+	DebugPreparedClaimsPath = "/debug/prepared-claims"
+	// DebugStatePath is the debug socket path dumping the state of the driver
+	DebugStatePath = "/debug/state"
+	// DefaultDebugSocketPath is the unix socket serving the debug API read by the state subcommand
+	DefaultDebugSocketPath = "/var/run/dra-driver-sriov/debug.sock"
 )
 
+// DriverName is the name the driver registers with and the CDI vendor of its devices. The
+// attributes, annotations and labels below are prefixed with it and are recomputed by SetDriverName.
+var DriverName = DefaultDriverName
+
+// Attributes published by the driver
+var (
+	AttributePciAddress        resourceapi.QualifiedName
+	AttributePFName            resourceapi.QualifiedName
+	AttributeEswitchMode       resourceapi.QualifiedName
+	AttributeVendorID          resourceapi.QualifiedName
+	AttributeDeviceID          resourceapi.QualifiedName
+	AttributePFDeviceID        resourceapi.QualifiedName
+	AttributePFTotalVFs        resourceapi.QualifiedName
+	AttributePFNumVFs          resourceapi.QualifiedName
+	AttributePFFirmwareVersion resourceapi.QualifiedName
+	AttributePFDriverVersion   resourceapi.QualifiedName
+	AttributePFPartNumber      resourceapi.QualifiedName
+	AttributePFBoardID         resourceapi.QualifiedName
+	AttributePFNICID           resourceapi.QualifiedName
+	AttributeVFID              resourceapi.QualifiedName
+	AttributeResourceName      resourceapi.QualifiedName
+	AttributeLinkType          resourceapi.QualifiedName
+	AttributeDriver            resourceapi.QualifiedName
+	AttributeRDMACapable       resourceapi.QualifiedName
+	AttributeSwitchdevCapable  resourceapi.QualifiedName
+	AttributeVDPACapable       resourceapi.QualifiedName
+	AttributeVFIONoIOMMU       resourceapi.QualifiedName
+	AttributeIOMMUGroup        resourceapi.QualifiedName
+	AttributeUUID              resourceapi.QualifiedName
+	AttributeNodeGUID          resourceapi.QualifiedName
+	AttributePortGUID          resourceapi.QualifiedName
+	// AttributePfPciAddress is for the PCI address of the Physical Function (PF).
+	AttributePfPciAddress resourceapi.QualifiedName
+)
+
+// Annotations of the CDI devices of the claims, so runtime tooling and NRI plugins can map
+// the CDI devices of a container to the claims and VFs it was allocated
+var (
+	CDIAnnotationClaimName      string
+	CDIAnnotationClaimNamespace string
+	CDIAnnotationClaimUID       string
+	CDIAnnotationDeviceName     string
+	CDIAnnotationPciAddress     string
+	CDIAnnotationResourceName   string
+	// CDIAnnotationRecovery holds the versioned data rebuilding the prepared device when the
+	// checkpoint is lost, see cdi.RecoveryData.
+	CDIAnnotationRecovery string
+)
+
+// Labels of the node summarizing the devices discovered on it, see --node-labels. The VF count
+// of each vendor is labeled NodeLabelVendorVFsPrefix followed by the PCI vendor ID.
+var (
+	NodeLabelSriov           string
+	NodeLabelVFs             string
+	NodeLabelVendorVFsPrefix string
+	NodeLabelRDMA            string
+	NodeLabelSwitchdev       string
+)
+
+// NodeAnnotationExtendedResources lists the extended resources the driver set in the capacity of
+// the node, see --extended-resources, so the ones no longer published are removed after restarts.
+var NodeAnnotationExtendedResources string
+
+// v2 names of the attributes renamed from the v1 schema
+var (
+	AttributeV2PFName      resourceapi.QualifiedName
+	AttributeV2EswitchMode resourceapi.QualifiedName
+	AttributeV2VendorID    resourceapi.QualifiedName
+)
+
+//nolint:gochecknoinits // Derives the names prefixed with the default driver name
+func init() {
+	SetDriverName(DefaultDriverName)
+}
+
+// SetDriverName sets the name of the driver and recomputes the attributes, annotations and labels
+// prefixed with it. It must be called before the driver publishes or prepares any device.
+func SetDriverName(name string) {
+	DriverName = name
+
+	AttributePciAddress = resourceapi.QualifiedName(name + "/pciAddress")
+	AttributePFName = resourceapi.QualifiedName(name + "/PFName")
+	AttributeEswitchMode = resourceapi.QualifiedName(name + "/EswitchMode")
+	AttributeVendorID = resourceapi.QualifiedName(name + "/vendor")
+	AttributeDeviceID = resourceapi.QualifiedName(name + "/deviceID")
+	AttributePFDeviceID = resourceapi.QualifiedName(name + "/pfDeviceID")
+	AttributePFTotalVFs = resourceapi.QualifiedName(name + "/pfTotalVFs")
+	AttributePFNumVFs = resourceapi.QualifiedName(name + "/pfNumVFs")
+	AttributePFFirmwareVersion = resourceapi.QualifiedName(name + "/pfFirmwareVersion")
+	AttributePFDriverVersion = resourceapi.QualifiedName(name + "/pfDriverVersion")
+	AttributePFPartNumber = resourceapi.QualifiedName(name + "/pfPartNumber")
+	AttributePFBoardID = resourceapi.QualifiedName(name + "/pfBoardID")
+	AttributePFNICID = resourceapi.QualifiedName(name + "/pfNICID")
+	AttributeVFID = resourceapi.QualifiedName(name + "/vfID")
+	AttributeResourceName = resourceapi.QualifiedName(name + "/resourceName")
+	AttributeLinkType = resourceapi.QualifiedName(name + "/linkType")
+	AttributeDriver = resourceapi.QualifiedName(name + "/driver")
+	AttributeRDMACapable = resourceapi.QualifiedName(name + "/rdmaCapable")
+	AttributeSwitchdevCapable = resourceapi.QualifiedName(name + "/switchdevCapable")
+	AttributeVDPACapable = resourceapi.QualifiedName(name + "/vdpaCapable")
+	AttributeVFIONoIOMMU = resourceapi.QualifiedName(name + "/vfioNoIOMMU")
+	AttributeIOMMUGroup = resourceapi.QualifiedName(name + "/iommuGroup")
+	AttributeUUID = resourceapi.QualifiedName(name + "/uuid")
+	AttributeNodeGUID = resourceapi.QualifiedName(name + "/nodeGUID")
+	AttributePortGUID = resourceapi.QualifiedName(name + "/portGUID")
+	AttributePfPciAddress = resourceapi.QualifiedName(name + "/pfPciAddress")
+	AttributeV2PFName = resourceapi.QualifiedName(name + "/pfName")
+	AttributeV2EswitchMode = resourceapi.QualifiedName(name + "/eswitchMode")
+	AttributeV2VendorID = resourceapi.QualifiedName(name + "/vendorID")
+
+	CDIAnnotationClaimName = name + "/claim-name"
+	CDIAnnotationClaimNamespace = name + "/claim-namespace"
+	CDIAnnotationClaimUID = name + "/claim-uid"
+	CDIAnnotationDeviceName = name + "/device-name"
+	CDIAnnotationPciAddress = name + "/pci-address"
+	CDIAnnotationResourceName = name + "/resource-name"
+	CDIAnnotationRecovery = name + "/recovery"
+
+	NodeLabelSriov = name + "/sriov"
+	NodeLabelVFs = name + "/vfs"
+	NodeLabelVendorVFsPrefix = name + "/vendor-"
+	NodeLabelRDMA = name + "/rdma"
+	NodeLabelSwitchdev = name + "/switchdev"
+
+	NodeAnnotationExtendedResources = name + "/extended-resources"
+}
+
 // Kubernetes standard attributes
 var (
 	// AttributePCIeRoot identifies the PCIe root complex of the device
 	AttributePCIeRoot resourceapi.QualifiedName = deviceattribute.StandardDeviceAttributePCIeRoot
 )
 
+// DefaultAllowedVFDrivers are the drivers a VfConfig may bind VFs to unless configured otherwise,
+// on top of the default kernel driver of the VF
+var DefaultAllowedVFDrivers = []string{"vfio-pci"}
+
 type ConfigurationMode string
 
 const (
@@ -77,6 +235,104 @@ const (
 	ConfigurationModeMultus     ConfigurationMode = "MULTUS"
 )
 
+// DriverMode selects which parts of the driver are active on the node.
+type DriverMode string
+
+const (
+	// DriverModeFull runs discovery, publishing and device preparation.
+	DriverModeFull DriverMode = "full"
+	// DriverModeInventory only discovers and publishes devices; prepare requests are refused.
+	DriverModeInventory DriverMode = "inventory"
+)
+
+// DefaultExtendedResourcePrefix is the prefix of the extended resources published for resource
+// names without one, the default resource prefix of the SR-IOV network device plugin.
+const DefaultExtendedResourcePrefix = "intel.com"
+
+// AttributeSchema selects the naming scheme of the attributes published in ResourceSlices.
+type AttributeSchema string
+
+const (
+	// AttributeSchemaV1 publishes the original attribute names only.
+	AttributeSchemaV1 AttributeSchema = "v1"
+	// AttributeSchemaV2 publishes the consistent lowerCamelCase attribute names only.
+	AttributeSchemaV2 AttributeSchema = "v2"
+	// AttributeSchemaDual publishes both naming schemes, for the transition between v1 and v2.
+	AttributeSchemaDual AttributeSchema = "v1+v2"
+)
+
+// DeviceNamingScheme selects how the devices published in ResourceSlices are named.
+type DeviceNamingScheme string
+
+const (
+	// DeviceNamingSchemePCIAddress names devices after their PCI address, e.g. 0000-08-00-2.
+	DeviceNamingSchemePCIAddress DeviceNamingScheme = "pci-address-dashes"
+	// DeviceNamingSchemePFNameVFID names devices after the netdev of their PF and their VF index,
+	// e.g. ens1f0-vf2.
+	DeviceNamingSchemePFNameVFID DeviceNamingScheme = "pfname-vfid"
+	// DeviceNamingSchemeStableUUID names devices with a UUID derived from the MAC address of their
+	// PF and their VF index.
+	DeviceNamingSchemeStableUUID DeviceNamingScheme = "stable-uuid"
+	// DeviceNamingSchemeSerialUUID names devices after their uuid attribute, derived from the VPD
+	// serial number of their NIC, the PCI function of their PF and their VF index.
+	DeviceNamingSchemeSerialUUID DeviceNamingScheme = "serial-uuid"
+)
+
+// SriovOperatorCoexistence selects how the driver behaves on nodes where sriov-network-operator
+// also manages PFs, as described by the SriovNetworkNodeState of the node.
+type SriovOperatorCoexistence string
+
+const (
+	// SriovOperatorCoexistenceIgnore does not look for the operator.
+	SriovOperatorCoexistenceIgnore SriovOperatorCoexistence = "ignore"
+	// SriovOperatorCoexistenceValidate publishes the VFs of the PFs managed by the operator, reports
+	// where the node differs from the operator spec and refuses to rebind their VFs against it.
+	SriovOperatorCoexistenceValidate SriovOperatorCoexistence = "validate"
+	// SriovOperatorCoexistenceDefer leaves the PFs managed by the operator to it, their VFs are not
+	// published.
+	SriovOperatorCoexistenceDefer SriovOperatorCoexistence = "defer"
+)
+
+// NUMAAlignment selects how the NRI plugin handles containers whose CPUs are not on the NUMA
+// node of their VFs.
+type NUMAAlignment string
+
+const (
+	// NUMAAlignmentNone does not inspect the containers.
+	NUMAAlignmentNone NUMAAlignment = "none"
+	// NUMAAlignmentWarn logs and emits a warning event for misaligned containers.
+	NUMAAlignmentWarn NUMAAlignment = "warn"
+	// NUMAAlignmentPin restricts the cpuset of misaligned containers to the NUMA nodes of their VFs.
+	NUMAAlignmentPin NUMAAlignment = "pin"
+)
+
+// HealthcheckProbe names a check run by the gRPC healthcheck service, see --healthcheck-probes.
+type HealthcheckProbe string
+
+const (
+	// HealthcheckProbeKubeletRegistration calls the registration and DRA sockets of the kubelet
+	// plugin. It is the only probe of the liveness service.
+	HealthcheckProbeKubeletRegistration HealthcheckProbe = "kubelet-registration"
+	// HealthcheckProbeDiscovery checks that the VFs discovered at startup still exist, e.g. after
+	// a change of sriov_numvfs.
+	HealthcheckProbeDiscovery HealthcheckProbe = "discovery"
+	// HealthcheckProbeCDI checks that CDI specs can be written to the CDI root.
+	HealthcheckProbeCDI HealthcheckProbe = "cdi"
+	// HealthcheckProbeNRI checks that the NRI plugin is connected to the runtime.
+	HealthcheckProbeNRI HealthcheckProbe = "nri"
+	// HealthcheckProbeCNIBinary runs the CNI STATUS verb against the sriov plugin.
+	HealthcheckProbeCNIBinary HealthcheckProbe = "cni-binary"
+)
+
+// HealthcheckProbes are all the probes of the healthcheck service, run by default.
+var HealthcheckProbes = []HealthcheckProbe{
+	HealthcheckProbeKubeletRegistration,
+	HealthcheckProbeDiscovery,
+	HealthcheckProbeCDI,
+	HealthcheckProbeNRI,
+	HealthcheckProbeCNIBinary,
+}
+
 var Backoff = wait.Backoff{
 	Duration: 100 * time.Millisecond, // Initial delay
 	Factor:   2.0,                    // Exponential factor
@@ -38,26 +38,61 @@ var _ = Describe("Consts", func() {
 				"vendor":       consts.DriverName + "/vendor",
 				"deviceID":     consts.DriverName + "/deviceID",
 				"pfDeviceID":   consts.DriverName + "/pfDeviceID",
+				"pfTotalVFs":   consts.DriverName + "/pfTotalVFs",
+				"pfNumVFs":     consts.DriverName + "/pfNumVFs",
+				"pfFwVersion":  consts.DriverName + "/pfFirmwareVersion",
+				"pfDrvVersion": consts.DriverName + "/pfDriverVersion",
+				"pfPartNumber": consts.DriverName + "/pfPartNumber",
+				"pfBoardID":    consts.DriverName + "/pfBoardID",
+				"pfNICID":      consts.DriverName + "/pfNICID",
 				"vfID":         consts.DriverName + "/vfID",
 				"resourceName": consts.DriverName + "/resourceName",
 				"pfPciAddress": consts.DriverName + "/pfPciAddress",
 			}
 
-			Expect(consts.AttributePciAddress).To(Equal(expectedAttributes["pciAddress"]))
-			Expect(consts.AttributePFName).To(Equal(expectedAttributes["PFName"]))
-			Expect(consts.AttributeEswitchMode).To(Equal(expectedAttributes["EswitchMode"]))
-			Expect(consts.AttributeVendorID).To(Equal(expectedAttributes["vendor"]))
-			Expect(consts.AttributeDeviceID).To(Equal(expectedAttributes["deviceID"]))
-			Expect(consts.AttributePFDeviceID).To(Equal(expectedAttributes["pfDeviceID"]))
-			Expect(consts.AttributeVFID).To(Equal(expectedAttributes["vfID"]))
-			Expect(consts.AttributeResourceName).To(Equal(expectedAttributes["resourceName"]))
-			Expect(consts.AttributePfPciAddress).To(Equal(expectedAttributes["pfPciAddress"]))
+			Expect(string(consts.AttributePciAddress)).To(Equal(expectedAttributes["pciAddress"]))
+			Expect(string(consts.AttributePFName)).To(Equal(expectedAttributes["PFName"]))
+			Expect(string(consts.AttributeEswitchMode)).To(Equal(expectedAttributes["EswitchMode"]))
+			Expect(string(consts.AttributeVendorID)).To(Equal(expectedAttributes["vendor"]))
+			Expect(string(consts.AttributeDeviceID)).To(Equal(expectedAttributes["deviceID"]))
+			Expect(string(consts.AttributePFDeviceID)).To(Equal(expectedAttributes["pfDeviceID"]))
+			Expect(string(consts.AttributePFTotalVFs)).To(Equal(expectedAttributes["pfTotalVFs"]))
+			Expect(string(consts.AttributePFNumVFs)).To(Equal(expectedAttributes["pfNumVFs"]))
+			Expect(string(consts.AttributePFFirmwareVersion)).To(Equal(expectedAttributes["pfFwVersion"]))
+			Expect(string(consts.AttributePFDriverVersion)).To(Equal(expectedAttributes["pfDrvVersion"]))
+			Expect(string(consts.AttributePFPartNumber)).To(Equal(expectedAttributes["pfPartNumber"]))
+			Expect(string(consts.AttributePFBoardID)).To(Equal(expectedAttributes["pfBoardID"]))
+			Expect(string(consts.AttributePFNICID)).To(Equal(expectedAttributes["pfNICID"]))
+			Expect(string(consts.AttributeVFID)).To(Equal(expectedAttributes["vfID"]))
+			Expect(string(consts.AttributeResourceName)).To(Equal(expectedAttributes["resourceName"]))
+			Expect(string(consts.AttributePfPciAddress)).To(Equal(expectedAttributes["pfPciAddress"]))
 		})
 
 		It("should have correct attributes with standard prefix", func() {
 			// These attributes should use the upstream standard prefix
 			Expect(string(consts.AttributePCIeRoot)).To(Equal(deviceattribute.StandardDeviceAttributePrefix + "pcieRoot"))
 			Expect(consts.AttributeStandardPciAddress).To(Equal(deviceattribute.StandardDeviceAttributePrefix + "pciBusID"))
+			Expect(consts.AttributeStandardInterfaceName).To(Equal(deviceattribute.StandardDeviceAttributePrefix + "interfaceName"))
+			Expect(consts.AttributeStandardMAC).To(Equal(deviceattribute.StandardDeviceAttributePrefix + "mac"))
+		})
+
+		It("should have capability attributes", func() {
+			Expect(string(consts.AttributeRDMACapable)).To(Equal(consts.DriverName + "/rdmaCapable"))
+			Expect(string(consts.AttributeSwitchdevCapable)).To(Equal(consts.DriverName + "/switchdevCapable"))
+			Expect(string(consts.AttributeVDPACapable)).To(Equal(consts.DriverName + "/vdpaCapable"))
+		})
+
+		It("should have topology attributes", func() {
+			Expect(string(consts.AttributeIOMMUGroup)).To(Equal(consts.DriverName + "/iommuGroup"))
+		})
+
+		It("should have identity attributes", func() {
+			Expect(string(consts.AttributeUUID)).To(Equal(consts.DriverName + "/uuid"))
+		})
+
+		It("should have InfiniBand attributes", func() {
+			Expect(string(consts.AttributeNodeGUID)).To(Equal(consts.DriverName + "/nodeGUID"))
+			Expect(string(consts.AttributePortGUID)).To(Equal(consts.DriverName + "/portGUID"))
 		})
 
 		It("should have compatibility attributes", func() {
@@ -72,6 +107,29 @@ var _ = Describe("Consts", func() {
 		})
 	})
 
+	Context("SetDriverName", func() {
+		AfterEach(func() {
+			consts.SetDriverName(consts.DefaultDriverName)
+		})
+
+		It("should prefix the attributes, CDI annotations and node labels with the configured name", func() {
+			consts.SetDriverName("sriov.example.com")
+
+			Expect(consts.DriverName).To(Equal("sriov.example.com"))
+			Expect(string(consts.AttributePciAddress)).To(Equal("sriov.example.com/pciAddress"))
+			Expect(string(consts.AttributeVFIONoIOMMU)).To(Equal("sriov.example.com/vfioNoIOMMU"))
+			Expect(string(consts.AttributeV2PFName)).To(Equal("sriov.example.com/pfName"))
+			Expect(consts.CDIAnnotationClaimUID).To(Equal("sriov.example.com/claim-uid"))
+			Expect(consts.NodeLabelVendorVFsPrefix).To(Equal("sriov.example.com/vendor-"))
+		})
+
+		It("should not change the API group", func() {
+			consts.SetDriverName("sriov.example.com")
+
+			Expect(consts.GroupName).To(Equal("sriovnetwork.k8snetworkplumbingwg.io"))
+		})
+	})
+
 	Context("Backoff configuration", func() {
 		It("should have valid backoff configuration", func() {
 			backoff := consts.Backoff
@@ -40,6 +40,7 @@ import (
 	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
 const (
@@ -129,6 +130,32 @@ func (r *SriovResourcePolicyReconciler) getPolicyDeviceMap(
 	}
 
 	allocatableDevices := r.deviceStateManager.GetAllocatableDevices()
+	for deviceName, match := range r.matchPolicyDevices(policies, allDeviceAttrs, allocatableDevices) {
+		policyDevices[deviceName] = match.attributes
+	}
+
+	r.log.Info("Policy devices resolved",
+		"matchingDevices", len(policyDevices),
+		"totalDevices", len(allocatableDevices))
+	r.log.V(2).Info("Policy devices details", "policyDevices", policyDevices)
+
+	return policyDevices
+}
+
+// policyMatch is a device matched by a policy, with the attributes the policy sets on it.
+type policyMatch struct {
+	policy     string
+	attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+}
+
+// matchPolicyDevices matches the devices against the configs of the policies, in the order of
+// the policy names. A device matched by several configs gets the attributes of the first one.
+func (r *SriovResourcePolicyReconciler) matchPolicyDevices(
+	policies []*sriovdrav1alpha1.SriovResourcePolicy,
+	allDeviceAttrs []sriovdrav1alpha1.DeviceAttributes,
+	allocatableDevices drasriovtypes.AllocatableDevices,
+) map[string]policyMatch {
+	matches := make(map[string]policyMatch)
 
 	sort.Slice(policies, func(i, j int) bool {
 		return policies[i].Name < policies[j].Name
@@ -141,9 +168,18 @@ func (r *SriovResourcePolicyReconciler) getPolicyDeviceMap(
 
 		for _, config := range policy.Spec.Configs {
 			resolvedAttrs := r.resolveDeviceAttributes(config.DeviceAttributesSelector, allDeviceAttrs)
+			// static attributes of the config override the selected DeviceAttributes
+			if len(config.Attributes) > 0 {
+				if resolvedAttrs == nil {
+					resolvedAttrs = make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, len(config.Attributes))
+				}
+				for k, v := range config.Attributes {
+					resolvedAttrs[k] = v
+				}
+			}
 
 			for deviceName, device := range allocatableDevices {
-				if _, exists := policyDevices[deviceName]; exists {
+				if _, exists := matches[deviceName]; exists {
 					continue
 				}
 
@@ -152,7 +188,7 @@ func (r *SriovResourcePolicyReconciler) getPolicyDeviceMap(
 					for k, v := range resolvedAttrs {
 						attrs[k] = v
 					}
-					policyDevices[deviceName] = attrs
+					matches[deviceName] = policyMatch{policy: policy.Name, attributes: attrs}
 					r.log.V(2).Info("Device matches config filter",
 						"deviceName", deviceName,
 						"policyName", policy.Name,
@@ -163,12 +199,7 @@ func (r *SriovResourcePolicyReconciler) getPolicyDeviceMap(
 		}
 	}
 
-	r.log.Info("Policy devices resolved",
-		"matchingDevices", len(policyDevices),
-		"totalDevices", len(allocatableDevices))
-	r.log.V(2).Info("Policy devices details", "policyDevices", policyDevices)
-
-	return policyDevices
+	return matches
 }
 
 // resolveDeviceAttributes finds all DeviceAttributes objects matching the
@@ -283,6 +314,16 @@ func (r *SriovResourcePolicyReconciler) deviceMatchesFilter(device resourceapi.D
 		}
 	}
 
+	if len(filter.PfDevices) > 0 {
+		pfDeviceAttr, exists := device.Attributes[consts.AttributePFDeviceID]
+		if !exists || pfDeviceAttr.StringValue == nil {
+			return false
+		}
+		if !stringSliceContains(filter.PfDevices, *pfDeviceAttr.StringValue) {
+			return false
+		}
+	}
+
 	if len(filter.PciAddresses) > 0 {
 		pciAttr, exists := device.Attributes[consts.AttributePciAddress]
 		if !exists || pciAttr.StringValue == nil {
@@ -313,9 +354,35 @@ func (r *SriovResourcePolicyReconciler) deviceMatchesFilter(device resourceapi.D
 		}
 	}
 
-	// TODO: Implement driver checking if needed
+	// the driver discovered, so devices do not leave a policy while a claim rebinds them
 	if len(filter.Drivers) > 0 {
-		r.log.V(3).Info("Driver filtering not yet implemented", "deviceName", device.Name)
+		driverAttr, exists := device.Attributes[consts.AttributeDriver]
+		if !exists || driverAttr.StringValue == nil {
+			return false
+		}
+		if !stringSliceContains(filter.Drivers, *driverAttr.StringValue) {
+			return false
+		}
+	}
+
+	if len(filter.EswitchModes) > 0 {
+		eswitchModeAttr, exists := device.Attributes[consts.AttributeEswitchMode]
+		if !exists || eswitchModeAttr.StringValue == nil {
+			return false
+		}
+		if !stringSliceContains(filter.EswitchModes, *eswitchModeAttr.StringValue) {
+			return false
+		}
+	}
+
+	if len(filter.LinkTypes) > 0 {
+		linkTypeAttr, exists := device.Attributes[consts.AttributeLinkType]
+		if !exists || linkTypeAttr.StringValue == nil {
+			return false
+		}
+		if !stringSliceContains(filter.LinkTypes, *linkTypeAttr.StringValue) {
+			return false
+		}
 	}
 
 	return true
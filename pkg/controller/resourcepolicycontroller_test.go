@@ -96,19 +96,27 @@ var _ = Describe("deviceMatchesFilter", func() {
 		r := &SriovResourcePolicyReconciler{}
 		vendor := "8086"
 		dev := "154c"
+		pfDev := "1593"
 		pf := "eth0"
 		pci := "0000:00:00.1"
 		pcieRoot := "pci0000:00"
 		pfPci := "0000:01:00.0"
+		driver := "iavf"
+		eswitchMode := "switchdev"
+		linkType := "infiniband"
 		d := resourceapi.Device{
 			Name: "devA",
 			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 				sriovconsts.AttributeVendorID:     {StringValue: &vendor},
 				sriovconsts.AttributeDeviceID:     {StringValue: &dev},
+				sriovconsts.AttributePFDeviceID:   {StringValue: &pfDev},
 				sriovconsts.AttributePFName:       {StringValue: &pf},
 				sriovconsts.AttributePciAddress:   {StringValue: &pci},
 				sriovconsts.AttributePCIeRoot:     {StringValue: &pcieRoot},
 				sriovconsts.AttributePfPciAddress: {StringValue: &pfPci},
+				sriovconsts.AttributeDriver:       {StringValue: &driver},
+				sriovconsts.AttributeEswitchMode:  {StringValue: &eswitchMode},
+				sriovconsts.AttributeLinkType:     {StringValue: &linkType},
 			},
 		}
 
@@ -117,18 +125,28 @@ var _ = Describe("deviceMatchesFilter", func() {
 		f := sriovdrav1alpha1.ResourceFilter{
 			Vendors:        []string{"8086"},
 			Devices:        []string{"154c"},
+			PfDevices:      []string{"1592", "1593"},
 			PciAddresses:   []string{"0000:00:00.1"},
 			PfNames:        []string{"eth0"},
 			PfPciAddresses: []string{"0000:01:00.0"},
+			Drivers:        []string{"iavf", "vfio-pci"},
+			EswitchModes:   []string{"switchdev"},
+			LinkTypes:      []string{"infiniband"},
 		}
 		Expect(r.deviceMatchesFilter(d, f)).To(BeTrue())
 
 		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Vendors: []string{"1234"}})).To(BeFalse())
 		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Devices: []string{"9999"}})).To(BeFalse())
+		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfDevices: []string{"1592"}})).To(BeFalse())
 		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PciAddresses: []string{"0000:00:00.2"}})).To(BeFalse())
 		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfNames: []string{"eth9"}})).To(BeFalse())
 		// Test with a different parent PCI address
 		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{PfPciAddresses: []string{"0000:00:ff.f"}})).To(BeFalse())
+		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{Drivers: []string{"vfio-pci"}})).To(BeFalse())
+		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{EswitchModes: []string{"legacy"}})).To(BeFalse())
+		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{LinkTypes: []string{"ethernet"}})).To(BeFalse())
+		delete(d.Attributes, sriovconsts.AttributeEswitchMode)
+		Expect(r.deviceMatchesFilter(d, sriovdrav1alpha1.ResourceFilter{EswitchModes: []string{"switchdev"}})).To(BeFalse())
 	})
 })
 
@@ -211,4 +229,51 @@ var _ = Describe("getPolicyDeviceMap", func() {
 		Expect(m["devA"]).To(HaveKey(resourceapi.QualifiedName("sriovnetwork.k8snetworkplumbingwg.io/resourceName")))
 		Expect(*m["devA"][resourceapi.QualifiedName("sriovnetwork.k8snetworkplumbingwg.io/resourceName")].StringValue).To(Equal("my-resource"))
 	})
+
+	It("stamps static config attributes onto matched devices, overriding selected DeviceAttributes", func() {
+		vendor := "8086"
+		alloc := drasriovtypes.AllocatableDevices{
+			"devA": resourceapi.Device{
+				Name: "devA",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					sriovconsts.AttributeVendorID: {StringValue: &vendor},
+				},
+			},
+		}
+		r := &SriovResourcePolicyReconciler{deviceStateManager: &localFakeState{alloc: alloc}}
+
+		silver, gold, resName := "silver", "gold", "my-resource"
+		deviceAttrs := []sriovdrav1alpha1.DeviceAttributes{{
+			ObjectMeta: metav1.ObjectMeta{Name: "da1", Labels: map[string]string{"pool": "test"}},
+			Spec: sriovdrav1alpha1.DeviceAttributesSpec{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					"tier": {StringValue: &silver},
+					"sriovnetwork.k8snetworkplumbingwg.io/resourceName": {StringValue: &resName},
+				},
+			},
+		}}
+		policies := []*sriovdrav1alpha1.SriovResourcePolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "p1"},
+			Spec: sriovdrav1alpha1.SriovResourcePolicySpec{
+				Configs: []sriovdrav1alpha1.Config{{
+					DeviceAttributesSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "test"}},
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"tier": {StringValue: &gold},
+					},
+					ResourceFilters: []sriovdrav1alpha1.ResourceFilter{{Vendors: []string{"8086"}}},
+				}},
+			},
+		}}
+
+		m := r.getPolicyDeviceMap(policies, deviceAttrs)
+		Expect(m).To(HaveLen(1))
+		Expect(*m["devA"][resourceapi.QualifiedName("tier")].StringValue).To(Equal("gold"))
+		Expect(*m["devA"][resourceapi.QualifiedName("sriovnetwork.k8snetworkplumbingwg.io/resourceName")].StringValue).To(Equal("my-resource"))
+
+		// static attributes also apply without a DeviceAttributesSelector
+		policies[0].Spec.Configs[0].DeviceAttributesSelector = nil
+		m = r.getPolicyDeviceMap(policies, deviceAttrs)
+		Expect(m["devA"]).To(HaveLen(1))
+		Expect(*m["devA"][resourceapi.QualifiedName("tier")].StringValue).To(Equal("gold"))
+	})
 })
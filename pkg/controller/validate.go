@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+
+	sriovdrav1alpha1 "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/sriovdra/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// DeviceAssignment is a device matched by a SriovResourcePolicy, as reported by ValidatePolicies.
+type DeviceAssignment struct {
+	Device     string `json:"device"`
+	PciAddress string `json:"pciAddress,omitempty"`
+	Policy     string `json:"policy"`
+	// ResourceName is the k8s.cni.cncf.io/resourceName attribute set by the policy, or the
+	// resourceName attribute of the driver.
+	ResourceName string                                                    `json:"resourceName,omitempty"`
+	Attributes   map[resourceapi.QualifiedName]resourceapi.DeviceAttribute `json:"attributes,omitempty"`
+}
+
+// PolicyValidation is the result of matching SriovResourcePolicies against the devices of a node.
+type PolicyValidation struct {
+	// Devices are the devices matched by the policies, which would be advertised, sorted by name.
+	Devices []DeviceAssignment `json:"devices"`
+	// UnmatchedDevices are the names of the devices matched by no policy.
+	UnmatchedDevices []string `json:"unmatchedDevices"`
+	// SkippedPolicies are the policies whose nodeSelector does not match the labels of the node.
+	SkippedPolicies []string `json:"skippedPolicies,omitempty"`
+}
+
+// LoadPolicies reads the SriovResourcePolicies and DeviceAttributes of YAML or JSON documents,
+// e.g. the manifests applied to the cluster. Lists of them are accepted too.
+func LoadPolicies(r io.Reader) ([]sriovdrav1alpha1.SriovResourcePolicy, []sriovdrav1alpha1.DeviceAttributes, error) {
+	var (
+		policies    []sriovdrav1alpha1.SriovResourcePolicy
+		deviceAttrs []sriovdrav1alpha1.DeviceAttributes
+	)
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		data, err := yaml.ToJSON(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse document: %w", err)
+		}
+		// empty documents, e.g. after a trailing separator
+		if string(data) == "null" {
+			continue
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := json.Unmarshal(data, &typeMeta); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse document: %w", err)
+		}
+		switch typeMeta.Kind {
+		case "SriovResourcePolicy":
+			policy := sriovdrav1alpha1.SriovResourcePolicy{}
+			if err := json.Unmarshal(data, &policy); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse SriovResourcePolicy: %w", err)
+			}
+			policies = append(policies, policy)
+		case "SriovResourcePolicyList":
+			list := sriovdrav1alpha1.SriovResourcePolicyList{}
+			if err := json.Unmarshal(data, &list); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse SriovResourcePolicyList: %w", err)
+			}
+			policies = append(policies, list.Items...)
+		case "DeviceAttributes":
+			deviceAttr := sriovdrav1alpha1.DeviceAttributes{}
+			if err := json.Unmarshal(data, &deviceAttr); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse DeviceAttributes: %w", err)
+			}
+			deviceAttrs = append(deviceAttrs, deviceAttr)
+		case "DeviceAttributesList":
+			list := sriovdrav1alpha1.DeviceAttributesList{}
+			if err := json.Unmarshal(data, &list); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse DeviceAttributesList: %w", err)
+			}
+			deviceAttrs = append(deviceAttrs, list.Items...)
+		default:
+			return nil, nil, fmt.Errorf("unsupported kind %q, expected SriovResourcePolicy or DeviceAttributes", typeMeta.Kind)
+		}
+	}
+	if len(policies) == 0 {
+		return nil, nil, fmt.Errorf("no SriovResourcePolicy found")
+	}
+	return policies, deviceAttrs, nil
+}
+
+// ValidatePolicies runs the matching of the reconciler of the node on the given devices, e.g.
+// the ones discovered on the host or a simulated inventory, and reports which devices would be
+// advertised with which resource names. The nodeSelectors of the policies are checked against
+// nodeLabels, unless it is nil.
+func ValidatePolicies(
+	devices drasriovtypes.AllocatableDevices,
+	policies []sriovdrav1alpha1.SriovResourcePolicy,
+	deviceAttrs []sriovdrav1alpha1.DeviceAttributes,
+	nodeLabels map[string]string,
+) *PolicyValidation {
+	r := &SriovResourcePolicyReconciler{log: klog.Background().WithName("SriovResourcePolicy")}
+	result := &PolicyValidation{Devices: []DeviceAssignment{}, UnmatchedDevices: []string{}}
+
+	var matchingPolicies []*sriovdrav1alpha1.SriovResourcePolicy
+	for i := range policies {
+		policy := &policies[i]
+		if nodeLabels != nil && !r.matchesNodeSelector(nodeLabels, policy.Spec.NodeSelector) {
+			result.SkippedPolicies = append(result.SkippedPolicies, policy.Name)
+			continue
+		}
+		matchingPolicies = append(matchingPolicies, policy)
+	}
+
+	matches := r.matchPolicyDevices(matchingPolicies, deviceAttrs, devices)
+	for _, name := range slices.Sorted(maps.Keys(devices)) {
+		match, ok := matches[name]
+		if !ok {
+			result.UnmatchedDevices = append(result.UnmatchedDevices, name)
+			continue
+		}
+		assignment := DeviceAssignment{
+			Device:     name,
+			Policy:     match.policy,
+			Attributes: match.attributes,
+		}
+		if pciAddress := devices[name].Attributes[consts.AttributePciAddress].StringValue; pciAddress != nil {
+			assignment.PciAddress = *pciAddress
+		}
+		for _, key := range []resourceapi.QualifiedName{consts.AttributeMultusResourceName, consts.AttributeResourceName} {
+			if resourceName := match.attributes[key].StringValue; resourceName != nil {
+				assignment.ResourceName = *resourceName
+				break
+			}
+		}
+		result.Devices = append(result.Devices, assignment)
+	}
+	slices.Sort(result.SkippedPolicies)
+	return result
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	sriovconsts "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("ValidatePolicies", func() {
+	const manifests = `
+apiVersion: sriovnetwork.k8snetworkplumbingwg.io/v1alpha1
+kind: DeviceAttributes
+metadata:
+  name: eth0-attrs
+  labels:
+    pool: eth0-resource
+spec:
+  attributes:
+    k8s.cni.cncf.io/resourceName:
+      string: eth0_resource
+---
+apiVersion: sriovnetwork.k8snetworkplumbingwg.io/v1alpha1
+kind: SriovResourcePolicy
+metadata:
+  name: eth0-policy
+spec:
+  configs:
+  - deviceAttributesSelector:
+      matchLabels:
+        pool: eth0-resource
+    resourceFilters:
+    - pfNames: ["eth0"]
+---
+apiVersion: sriovnetwork.k8snetworkplumbingwg.io/v1alpha1
+kind: SriovResourcePolicy
+metadata:
+  name: worker-2-policy
+spec:
+  nodeSelector:
+    nodeSelectorTerms:
+    - matchExpressions:
+      - key: kubernetes.io/hostname
+        operator: In
+        values: ["worker-2"]
+  configs:
+  - {}
+---
+`
+
+	newDevice := func(pfName, pciAddress string) resourceapi.Device {
+		return resourceapi.Device{Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			sriovconsts.AttributePFName:     {StringValue: ptr.To(pfName)},
+			sriovconsts.AttributePciAddress: {StringValue: ptr.To(pciAddress)},
+		}}
+	}
+	devices := drasriovtypes.AllocatableDevices{
+		"0000-01-00-2": newDevice("eth0", "0000:01:00.2"),
+		"0000-02-00-2": newDevice("eth1", "0000:02:00.2"),
+	}
+
+	It("loads the policies and device attributes of the manifests", func() {
+		policies, deviceAttrs, err := LoadPolicies(strings.NewReader(manifests))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(policies).To(HaveLen(2))
+		Expect(deviceAttrs).To(HaveLen(1))
+		Expect(deviceAttrs[0].Labels).To(HaveKeyWithValue("pool", "eth0-resource"))
+	})
+
+	It("rejects other kinds and manifests without policy", func() {
+		_, _, err := LoadPolicies(strings.NewReader("apiVersion: v1\nkind: ConfigMap\n"))
+		Expect(err).To(MatchError(ContainSubstring(`unsupported kind "ConfigMap"`)))
+		_, _, err = LoadPolicies(strings.NewReader("---\n"))
+		Expect(err).To(MatchError("no SriovResourcePolicy found"))
+	})
+
+	It("reports the devices matched by the policies of the node with their resource names", func() {
+		policies, deviceAttrs, err := LoadPolicies(strings.NewReader(manifests))
+		Expect(err).ToNot(HaveOccurred())
+
+		result := ValidatePolicies(devices, policies, deviceAttrs, map[string]string{"kubernetes.io/hostname": "worker-1"})
+		Expect(result.Devices).To(HaveLen(1))
+		Expect(result.Devices[0].Device).To(Equal("0000-01-00-2"))
+		Expect(result.Devices[0].PciAddress).To(Equal("0000:01:00.2"))
+		Expect(result.Devices[0].Policy).To(Equal("eth0-policy"))
+		Expect(result.Devices[0].ResourceName).To(Equal("eth0_resource"))
+		Expect(result.UnmatchedDevices).To(Equal([]string{"0000-02-00-2"}))
+		Expect(result.SkippedPolicies).To(Equal([]string{"worker-2-policy"}))
+	})
+
+	It("ignores the node selectors without node labels", func() {
+		policies, deviceAttrs, err := LoadPolicies(strings.NewReader(manifests))
+		Expect(err).ToNot(HaveOccurred())
+
+		result := ValidatePolicies(devices, policies, deviceAttrs, nil)
+		Expect(result.Devices).To(HaveLen(2))
+		Expect(result.Devices[1].Device).To(Equal("0000-02-00-2"))
+		Expect(result.Devices[1].Policy).To(Equal("worker-2-policy"))
+		Expect(result.Devices[1].ResourceName).To(BeEmpty())
+		Expect(result.UnmatchedDevices).To(BeEmpty())
+		Expect(result.SkippedPolicies).To(BeEmpty())
+	})
+})
@@ -0,0 +1,70 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/klog/v2"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// isAdminAccess reports whether a device was allocated with admin access, which the scheduler
+// grants on devices already allocated to other claims.
+func isAdminAccess(result *resourceapi.DeviceRequestAllocationResult) bool {
+	return result.AdminAccess != nil && *result.AdminAccess
+}
+
+// applyAdminAccessOnDevice prepares a device of an admin access claim. The device may be in use by
+// another workload, so it is left untouched: it is not rebound nor attached to a network and the
+// VfConfig of the claim is ignored. The containers only get environment variables describing it.
+func (s *Manager) applyAdminAccessOnDevice(ctx context.Context, claim *resourceapi.ResourceClaim, result *resourceapi.DeviceRequestAllocationResult) (*drasriovtypes.PreparedDevice, error) {
+	logger := klog.FromContext(ctx).WithName("applyAdminAccessOnDevice")
+	deviceInfo, exist := s.GetAllocatableDeviceByName(result.Device)
+	if !exist {
+		return nil, fmt.Errorf("device %s not found in allocatable devices", result.Device)
+	}
+	pciAddress := *deviceInfo.Attributes[consts.AttributePciAddress].StringValue
+	devicePrefix := strings.ReplaceAll(result.Device, "-", "_")
+
+	envs := []string{
+		fmt.Sprintf("SRIOVNETWORK_VF_DEVICE_%s=%s", devicePrefix, pciAddress),
+		fmt.Sprintf("SRIOVNETWORK_%s_ADMIN_ACCESS=true", devicePrefix),
+	}
+	if driver, ok := s.currentDriver(result.Device); ok {
+		envs = append(envs, fmt.Sprintf("SRIOVNETWORK_%s_DRIVER=%s", devicePrefix, driver))
+	}
+	envs = append(envs, numaEnvs(ctx, deviceInfo, pciAddress, result.Device)...)
+	logger.V(2).Info("Prepared device for admin access", "device", pciAddress, "claim", claim.UID)
+
+	return &drasriovtypes.PreparedDevice{
+		ClaimNamespacedName: kubeletplugin.NamespacedObject{
+			NamespacedName: k8stypes.NamespacedName{
+				Name:      claim.Name,
+				Namespace: claim.Namespace,
+			},
+			UID: claim.UID,
+		},
+		Device: drapbv1.Device{
+			RequestNames: []string{result.Request},
+			PoolName:     result.Pool,
+			DeviceName:   result.Device,
+			CDIDeviceIDs: s.claimCDIDeviceIDs(claim, result.Device),
+		},
+		ContainerEdits: &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{Env: envs}},
+		PciAddress:     pciAddress,
+		PodUID:         claimPodUID(claim),
+		Config:         configapi.DefaultVfConfig(),
+		ResourceName:   attributeString(deviceInfo.Attributes[consts.AttributeResourceName]),
+		AdminAccess:    true,
+	}, nil
+}
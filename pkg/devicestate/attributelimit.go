@@ -0,0 +1,73 @@
+package devicestate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// optionalAttributes returns the discovered attributes dropped, in order, from a device over the
+// limit of the API server once the duplicates of the dual schema are gone. They describe the
+// device rather than being used to select it.
+func optionalAttributes() []resourceapi.QualifiedName {
+	return []resourceapi.QualifiedName{
+		consts.AttributePFFirmwareVersion,
+		consts.AttributePFDriverVersion,
+		consts.AttributePFBoardID,
+		consts.AttributePFPartNumber,
+		consts.AttributePFNICID,
+		consts.AttributePFTotalVFs,
+		consts.AttributePFNumVFs,
+		consts.AttributeUUID,
+		consts.AttributeStandardMAC,
+		consts.AttributeIOMMUGroup,
+	}
+}
+
+// limitDeviceAttributes returns the device with the attributes dropped to fit its attributes and
+// capacities in the limit of the API server, which would otherwise reject the whole
+// ResourceSlice. The v1 names of the attributes published under both schemas go first, then the
+// optional attributes, and the attributes set by policies are never dropped. It returns an error
+// when the device still does not fit, so it can be left out of the slice instead.
+func limitDeviceAttributes(logger klog.Logger, name string, device resourceapi.Device, policyKeys map[resourceapi.QualifiedName]bool) (resourceapi.Device, error) {
+	overLimit := func() bool {
+		return len(device.Attributes)+len(device.Capacity) > resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice
+	}
+	if !overLimit() {
+		return device, nil
+	}
+
+	// the attributes may be the ones of the allocatable devices
+	device.Attributes = maps.Clone(device.Attributes)
+	dropped := []resourceapi.QualifiedName{}
+	drop := func(attribute resourceapi.QualifiedName) {
+		if _, exists := device.Attributes[attribute]; !exists || policyKeys[attribute] || !overLimit() {
+			return
+		}
+		delete(device.Attributes, attribute)
+		dropped = append(dropped, attribute)
+	}
+	// sorted so the same attributes are dropped on every publish
+	for _, v1Name := range slices.Sorted(maps.Keys(attributeRenamesV2)) {
+		if _, exists := device.Attributes[attributeRenamesV2[v1Name]]; exists {
+			drop(v1Name)
+		}
+	}
+	for _, attribute := range optionalAttributes() {
+		drop(attribute)
+	}
+	if len(dropped) > 0 {
+		logger.V(2).Info("Dropped attributes over the limit of the API server", "device", name, "attributes", dropped)
+	}
+
+	if overLimit() {
+		return device, fmt.Errorf("device %s has %d attributes and capacities, more than the %d allowed",
+			name, len(device.Attributes)+len(device.Capacity), resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice)
+	}
+	return device, nil
+}
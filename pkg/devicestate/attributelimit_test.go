@@ -0,0 +1,101 @@
+package devicestate
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+var _ = Describe("Attribute limit", func() {
+	// fullyPopulatedAttributes returns the attributes of a Mellanox InfiniBand VF with every
+	// discovered attribute, along with the ones set by a policy.
+	fullyPopulatedAttributes := func() map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+		attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+		for _, name := range []resourceapi.QualifiedName{
+			consts.AttributeVendorID, consts.AttributeDeviceID, consts.AttributePFDeviceID,
+			consts.AttributePciAddress, consts.AttributeMultusDeviceID, consts.AttributePFName,
+			consts.AttributeEswitchMode, consts.AttributePCIeRoot, consts.AttributePfPciAddress,
+			consts.AttributeStandardPciAddress, consts.AttributeLinkType, consts.AttributePFFirmwareVersion,
+			consts.AttributePFDriverVersion, consts.AttributePFPartNumber, consts.AttributePFBoardID,
+			consts.AttributePFNICID, consts.AttributeUUID, consts.AttributeNodeGUID, consts.AttributePortGUID,
+			consts.AttributeDriver, consts.AttributeStandardInterfaceName, consts.AttributeStandardMAC,
+			consts.AttributeResourceName, consts.AttributeMultusResourceName,
+		} {
+			attributes[name] = resourceapi.DeviceAttribute{StringValue: ptr.To("value")}
+		}
+		for _, name := range []resourceapi.QualifiedName{
+			consts.AttributeVFID, consts.AttributeNUMANode, consts.AttributePFTotalVFs,
+			consts.AttributePFNumVFs, consts.AttributeIOMMUGroup,
+		} {
+			attributes[name] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(1))}
+		}
+		for _, name := range []resourceapi.QualifiedName{
+			consts.AttributeRDMACapable, consts.AttributeSwitchdevCapable, consts.AttributeVDPACapable,
+			consts.AttributeVFIONoIOMMU,
+		} {
+			attributes[name] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+		}
+		return attributes
+	}
+
+	It("fits a fully populated device under the dual schema in the limit of the API server", func() {
+		attributes := fullyPopulatedAttributes()
+		discovered := len(attributes)
+		s := &Manager{
+			allocatable: map[string]resourceapi.Device{"devA": {Attributes: attributes}},
+			policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+				"devA": {consts.AttributeResourceName: true, consts.AttributeMultusResourceName: true},
+			},
+			attributeSchema: consts.AttributeSchemaDual,
+		}
+
+		advertised := s.GetAdvertisedDevices()
+		Expect(advertised).To(HaveKey("devA"))
+		Expect(len(advertised["devA"].Attributes)).To(BeNumerically("<=", resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice))
+		// the v2 names and the attributes of the policy are kept
+		Expect(advertised["devA"].Attributes).To(HaveKey(consts.AttributeV2PFName))
+		Expect(advertised["devA"].Attributes).To(HaveKey(consts.AttributeResourceName))
+		Expect(advertised["devA"].Attributes).To(HaveKey(resourceapi.QualifiedName(consts.AttributeMultusResourceName)))
+		Expect(advertised["devA"].Attributes).To(HaveKey(consts.AttributeNodeGUID))
+		// the discovered device is left untouched
+		Expect(s.allocatable["devA"].Attributes).To(HaveLen(discovered))
+	})
+
+	It("leaves out a device over the limit and advertises the others", func() {
+		attributes := fullyPopulatedAttributes()
+		policyKeys := map[resourceapi.QualifiedName]bool{}
+		for i := range resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice {
+			name := resourceapi.QualifiedName(fmt.Sprintf("example.com/attribute%d", i))
+			attributes[name] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(i))}
+			policyKeys[name] = true
+		}
+		s := &Manager{
+			allocatable: map[string]resourceapi.Device{
+				"devA": {Attributes: attributes},
+				"devB": {Attributes: fullyPopulatedAttributes()},
+			},
+			policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+				"devA": policyKeys,
+				"devB": {},
+			},
+		}
+
+		advertised := s.GetAdvertisedDevices()
+		Expect(advertised).ToNot(HaveKey("devA"))
+		Expect(advertised).To(HaveKey("devB"))
+	})
+
+	It("keeps devices under the limit as they are", func() {
+		device := resourceapi.Device{Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			consts.AttributePFFirmwareVersion: {StringValue: ptr.To("1.0")},
+		}}
+		limited, err := limitDeviceAttributes(GinkgoLogr, "devA", device, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limited).To(Equal(device))
+	})
+})
@@ -0,0 +1,54 @@
+package devicestate
+
+import (
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// attributeRenamesV2 maps the v1 name of every attribute renamed by the v2 schema to its v2 name.
+// Attributes not listed here have the same name in both schemas.
+var attributeRenamesV2 = map[resourceapi.QualifiedName]resourceapi.QualifiedName{
+	consts.AttributePFName:      consts.AttributeV2PFName,
+	consts.AttributeEswitchMode: consts.AttributeV2EswitchMode,
+	consts.AttributeVendorID:    consts.AttributeV2VendorID,
+}
+
+// ValidateAttributeSchema checks that the requested attribute schema is supported.
+func ValidateAttributeSchema(schema string) error {
+	switch consts.AttributeSchema(schema) {
+	case consts.AttributeSchemaV1, consts.AttributeSchemaV2, consts.AttributeSchemaDual:
+		return nil
+	default:
+		return fmt.Errorf("unsupported attribute schema %q, expected %q, %q or %q",
+			schema, consts.AttributeSchemaV1, consts.AttributeSchemaV2, consts.AttributeSchemaDual)
+	}
+}
+
+// applyAttributeSchema returns the attributes to publish for a device under the given schema.
+// Devices are discovered and matched with the v1 names, so the renaming only happens here,
+// when building the published view. The input map is never modified.
+func applyAttributeSchema(attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, schema consts.AttributeSchema) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	if schema == "" || schema == consts.AttributeSchemaV1 {
+		return attributes
+	}
+
+	published := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, len(attributes)+len(attributeRenamesV2))
+	for name, value := range attributes {
+		v2Name, renamed := attributeRenamesV2[name]
+		if !renamed {
+			published[name] = value
+			continue
+		}
+		// an attribute explicitly set under the v2 name (e.g. by a policy) wins
+		if _, exists := attributes[v2Name]; !exists {
+			published[v2Name] = value
+		}
+		if schema == consts.AttributeSchemaDual {
+			published[name] = value
+		}
+	}
+	return published
+}
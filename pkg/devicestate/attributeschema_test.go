@@ -0,0 +1,73 @@
+package devicestate
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+var _ = Describe("Attribute schema", func() {
+	var attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+
+	BeforeEach(func() {
+		attributes = map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			consts.AttributePciAddress:  {StringValue: ptr.To("0000:08:00.2")},
+			consts.AttributePFName:      {StringValue: ptr.To("eth0")},
+			consts.AttributeEswitchMode: {StringValue: ptr.To("legacy")},
+			consts.AttributeVendorID:    {StringValue: ptr.To("15b3")},
+		}
+	})
+
+	Context("ValidateAttributeSchema", func() {
+		It("accepts the supported schemas", func() {
+			Expect(ValidateAttributeSchema("v1")).To(Succeed())
+			Expect(ValidateAttributeSchema("v2")).To(Succeed())
+			Expect(ValidateAttributeSchema("v1+v2")).To(Succeed())
+		})
+
+		It("rejects unknown schemas", func() {
+			err := ValidateAttributeSchema("v3")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported attribute schema"))
+		})
+	})
+
+	Context("applyAttributeSchema", func() {
+		It("keeps the v1 names with the v1 schema", func() {
+			published := applyAttributeSchema(attributes, consts.AttributeSchemaV1)
+			Expect(published).To(Equal(attributes))
+		})
+
+		It("renames attributes with the v2 schema", func() {
+			published := applyAttributeSchema(attributes, consts.AttributeSchemaV2)
+			Expect(published).To(HaveLen(4))
+			Expect(published).To(HaveKey(resourceapi.QualifiedName(consts.AttributePciAddress)))
+			Expect(published).ToNot(HaveKey(resourceapi.QualifiedName(consts.AttributePFName)))
+			Expect(*published[consts.AttributeV2PFName].StringValue).To(Equal("eth0"))
+			Expect(*published[consts.AttributeV2EswitchMode].StringValue).To(Equal("legacy"))
+			Expect(*published[consts.AttributeV2VendorID].StringValue).To(Equal("15b3"))
+		})
+
+		It("publishes both names with the dual schema", func() {
+			published := applyAttributeSchema(attributes, consts.AttributeSchemaDual)
+			Expect(published).To(HaveLen(7))
+			Expect(*published[consts.AttributePFName].StringValue).To(Equal("eth0"))
+			Expect(*published[consts.AttributeV2PFName].StringValue).To(Equal("eth0"))
+		})
+
+		It("does not override an attribute already set under its v2 name", func() {
+			attributes[consts.AttributeV2PFName] = resourceapi.DeviceAttribute{StringValue: ptr.To("custom")}
+			published := applyAttributeSchema(attributes, consts.AttributeSchemaDual)
+			Expect(*published[consts.AttributeV2PFName].StringValue).To(Equal("custom"))
+		})
+
+		It("does not modify the discovered attributes", func() {
+			_ = applyAttributeSchema(attributes, consts.AttributeSchemaV2)
+			Expect(attributes).To(HaveLen(4))
+			Expect(attributes).To(HaveKey(resourceapi.QualifiedName(consts.AttributePFName)))
+		})
+	})
+})
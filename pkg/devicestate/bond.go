@@ -0,0 +1,75 @@
+package devicestate
+
+import (
+	"fmt"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// minBondDevices is the number of VFs a bond needs to provide redundancy.
+const minBondDevices = 2
+
+// bondDevices bonds the prepared devices of each request whose VfConfig has a bond into a single
+// interface of the pod. The VFs of a bond must be on different PFs, so that the bond survives
+// the loss of a NIC port. The bond takes the IPAM of the netconf of the VFs and is attached once
+// they are, from the netconf kept on the first VF of the bond.
+func (s *Manager) bondDevices(ifNameIndex *int, preparedDevices drasriovtypes.PreparedDevices) error {
+	var requests []string
+	bonds := map[string]drasriovtypes.PreparedDevices{}
+	for _, device := range preparedDevices {
+		if device.Config == nil || device.Config.Bond == nil || len(device.Device.RequestNames) == 0 {
+			continue
+		}
+		request := device.Device.RequestNames[0]
+		if _, ok := bonds[request]; !ok {
+			requests = append(requests, request)
+		}
+		bonds[request] = append(bonds[request], device)
+	}
+
+	for _, request := range requests {
+		members := bonds[request]
+		if !s.isStandaloneMode() {
+			return fmt.Errorf("bond of request %s requires the %s configuration mode", request, consts.ConfigurationModeStandalone)
+		}
+		if len(members) < minBondDevices {
+			return fmt.Errorf("bond of request %s needs at least %d devices, got %d", request, minBondDevices, len(members))
+		}
+
+		devicesByPF := map[string]string{}
+		links := make([]string, 0, len(members))
+		for _, member := range members {
+			// the allocatable devices are updated by the policy controller in the meantime
+			device, exists := s.GetAllocatableDeviceByName(member.Device.DeviceName)
+			if !exists {
+				return fmt.Errorf("device %s of the bond of request %s is not allocatable", member.Device.DeviceName, request)
+			}
+			pf := attributeString(device.Attributes[consts.AttributePfPciAddress])
+			if other, ok := devicesByPF[pf]; ok {
+				return fmt.Errorf("devices %s and %s of the bond of request %s are on the same PF %s", other, member.Device.DeviceName, request, pf)
+			}
+			devicesByPF[pf] = member.Device.DeviceName
+			links = append(links, member.IfName)
+		}
+
+		bondIfName := members[0].Config.IfName
+		if bondIfName == "" {
+			bondIfName = fmt.Sprintf("%s%d", s.defaultInterfacePrefix, *ifNameIndex)
+			*ifNameIndex++
+		}
+		bondNetConf, err := drasriovtypes.BondNetConf(members[0].NetAttachDefConfig, members[0].Config.Bond, links)
+		if err != nil {
+			return fmt.Errorf("error creating the bond config of request %s: %w", request, err)
+		}
+		for _, member := range members {
+			member.NetAttachDefConfig, err = drasriovtypes.RemoveIPAMFromNetConf(member.NetAttachDefConfig)
+			if err != nil {
+				return fmt.Errorf("error removing the IPAM of device %s: %w", member.Device.DeviceName, err)
+			}
+			member.BondIfName = bondIfName
+		}
+		members[0].BondNetConf = bondNetConf
+	}
+	return nil
+}
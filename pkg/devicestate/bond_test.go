@@ -0,0 +1,128 @@
+package devicestate
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("bondDevices", func() {
+	const netConf = `{"cniVersion":"1.0.0","name":"sriov-net","type":"sriov","ipam":{"type":"whereabouts"}}`
+
+	var (
+		manager     *Manager
+		ifNameIndex int
+	)
+
+	newDevice := func(name, request, ifName string, config *configapi.VfConfig) *drasriovtypes.PreparedDevice {
+		return &drasriovtypes.PreparedDevice{
+			Device:             drapbv1.Device{DeviceName: name, RequestNames: []string{request}},
+			Config:             config,
+			IfName:             ifName,
+			NetAttachDefConfig: netConf,
+		}
+	}
+
+	BeforeEach(func() {
+		ifNameIndex = 3
+		manager = &Manager{
+			defaultInterfacePrefix: "net",
+			allocatable: drasriovtypes.AllocatableDevices{
+				"vf-a0": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{consts.AttributePfPciAddress: {StringValue: ptr.To("0000:01:00.0")}}},
+				"vf-a1": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{consts.AttributePfPciAddress: {StringValue: ptr.To("0000:01:00.0")}}},
+				"vf-b0": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{consts.AttributePfPciAddress: {StringValue: ptr.To("0000:01:00.1")}}},
+			},
+		}
+	})
+
+	It("bonds the devices of a request on different PFs", func() {
+		config := &configapi.VfConfig{IfName: "bond0", Bond: &configapi.BondConfig{}}
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-a0", "ha", "net1", config),
+			newDevice("vf-b0", "ha", "net2", config),
+		}
+
+		Expect(manager.bondDevices(&ifNameIndex, devices)).To(Succeed())
+		Expect(ifNameIndex).To(Equal(3))
+		for _, device := range devices {
+			Expect(device.BondIfName).To(Equal("bond0"))
+			Expect(device.NetAttachDefConfig).NotTo(ContainSubstring("ipam"))
+		}
+		Expect(devices[1].BondNetConf).To(BeEmpty())
+
+		var bondConfig map[string]interface{}
+		Expect(json.Unmarshal([]byte(devices[0].BondNetConf), &bondConfig)).To(Succeed())
+		Expect(bondConfig).To(HaveKeyWithValue("type", "bond"))
+		Expect(bondConfig).To(HaveKey("ipam"))
+		Expect(bondConfig["links"]).To(Equal([]interface{}{
+			map[string]interface{}{"name": "net1"},
+			map[string]interface{}{"name": "net2"},
+		}))
+	})
+
+	It("names the bond after the default prefix without an interface name", func() {
+		config := &configapi.VfConfig{Bond: &configapi.BondConfig{}}
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-a0", "ha", "net1", config),
+			newDevice("vf-b0", "ha", "net2", config),
+		}
+
+		Expect(manager.bondDevices(&ifNameIndex, devices)).To(Succeed())
+		Expect(devices[0].BondIfName).To(Equal("net3"))
+		Expect(ifNameIndex).To(Equal(4))
+	})
+
+	It("leaves the devices of requests without bond alone", func() {
+		devices := drasriovtypes.PreparedDevices{newDevice("vf-a0", "plain", "net1", &configapi.VfConfig{})}
+
+		Expect(manager.bondDevices(&ifNameIndex, devices)).To(Succeed())
+		Expect(devices[0].BondIfName).To(BeEmpty())
+		Expect(devices[0].NetAttachDefConfig).To(Equal(netConf))
+	})
+
+	It("refuses devices on the same PF", func() {
+		config := &configapi.VfConfig{Bond: &configapi.BondConfig{}}
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-a0", "ha", "net1", config),
+			newDevice("vf-a1", "ha", "net2", config),
+		}
+
+		Expect(manager.bondDevices(&ifNameIndex, devices)).To(MatchError(ContainSubstring("are on the same PF 0000:01:00.0")))
+	})
+
+	It("refuses devices that are no longer allocatable", func() {
+		config := &configapi.VfConfig{Bond: &configapi.BondConfig{}}
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-a0", "ha", "net1", config),
+			newDevice("vf-gone", "ha", "net2", config),
+		}
+
+		err := manager.bondDevices(&ifNameIndex, devices)
+		Expect(err).To(MatchError(ContainSubstring("device vf-gone of the bond of request ha is not allocatable")))
+	})
+
+	It("refuses a single device", func() {
+		devices := drasriovtypes.PreparedDevices{newDevice("vf-a0", "ha", "net1", &configapi.VfConfig{Bond: &configapi.BondConfig{}})}
+
+		Expect(manager.bondDevices(&ifNameIndex, devices)).To(MatchError(ContainSubstring("needs at least 2 devices, got 1")))
+	})
+
+	It("refuses bonds in multus mode", func() {
+		manager.configurationMode = string(consts.ConfigurationModeMultus)
+		config := &configapi.VfConfig{Bond: &configapi.BondConfig{}}
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-a0", "ha", "", config),
+			newDevice("vf-b0", "ha", "", config),
+		}
+
+		Expect(manager.bondDevices(&ifNameIndex, devices)).To(MatchError(ContainSubstring("configuration mode")))
+	})
+})
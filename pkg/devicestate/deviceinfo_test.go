@@ -78,6 +78,8 @@ var _ = Describe("DeviceInfo compatibility", Serial, func() {
 	BeforeEach(func() {
 		mockCtrl = gomock.NewController(GinkgoT())
 		mockHost = mock_host.NewMockInterface(mockCtrl)
+		// the original VF settings are covered by dedicated specs
+		mockHost.EXPECT().GetVFSettings(gomock.Any()).Return(nil, nil).AnyTimes()
 		origHelpers = host.GetHelpers()
 		host.Helpers = mockHost
 	})
@@ -285,6 +287,7 @@ var _ = Describe("DeviceInfo compatibility", Serial, func() {
 		}
 
 		mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("ixgbevf", nil)
+		mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 		mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("/dev/vfio/1", "/dev/vfio/1", nil)
 		mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{})
 		mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.1", "ixgbevf").Return(nil)
@@ -365,6 +368,7 @@ var _ = Describe("DeviceInfo compatibility", Serial, func() {
 		}
 
 		mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("", nil)
+		mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 
 		claim := &resourceapi.ResourceClaim{
 			ObjectMeta: metav1.ObjectMeta{
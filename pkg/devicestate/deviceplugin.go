@@ -0,0 +1,132 @@
+package devicestate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// devicePluginCheckpointInterval is how often the checkpoint of the kubelet device manager is read
+// for the devices allocated by the SR-IOV device plugin.
+var devicePluginCheckpointInterval = 10 * time.Second
+
+// devicePluginCheckpoint is the part of the checkpoint of the kubelet device manager listing the
+// devices allocated to the containers of the pods.
+type devicePluginCheckpoint struct {
+	Data struct {
+		PodDeviceEntries []struct {
+			ResourceName string
+			// DeviceIDs are the device IDs by NUMA node since Kubernetes 1.20, a list of device IDs
+			// before
+			DeviceIDs json.RawMessage
+		}
+	}
+}
+
+// readDevicePluginAllocations returns the device IDs allocated by device plugins according to the
+// checkpoint of the kubelet device manager, the PCI addresses of the VFs for the SR-IOV device
+// plugin. A missing checkpoint allocates nothing.
+func readDevicePluginAllocations(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path) /* #nosec G304 */
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device plugin checkpoint %s: %w", path, err)
+	}
+	checkpoint := &devicePluginCheckpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse device plugin checkpoint %s: %w", path, err)
+	}
+
+	allocated := map[string]bool{}
+	for _, entry := range checkpoint.Data.PodDeviceEntries {
+		var deviceIDs []string
+		var deviceIDsByNUMANode map[string][]string
+		if err := json.Unmarshal(entry.DeviceIDs, &deviceIDsByNUMANode); err == nil {
+			for _, ids := range deviceIDsByNUMANode {
+				deviceIDs = append(deviceIDs, ids...)
+			}
+		} else if err := json.Unmarshal(entry.DeviceIDs, &deviceIDs); err != nil {
+			return nil, fmt.Errorf("invalid device IDs of resource %s in device plugin checkpoint %s", entry.ResourceName, path)
+		}
+		for _, id := range deviceIDs {
+			allocated[strings.ToLower(id)] = true
+		}
+	}
+	return allocated, nil
+}
+
+// allocatedByDevicePlugin reports whether a device is allocated to a pod by the SR-IOV device
+// plugin, see --device-plugin-checkpoint.
+func (s *Manager) allocatedByDevicePlugin(deviceName string) bool {
+	s.devicePluginMu.Lock()
+	defer s.devicePluginMu.Unlock()
+	return s.devicePluginAllocated[deviceName]
+}
+
+// syncDevicePluginAllocations reads the devices allocated by the SR-IOV device plugin from the
+// checkpoint of the kubelet device manager and reports whether they changed.
+func (s *Manager) syncDevicePluginAllocations(ctx context.Context) (bool, error) {
+	logger := klog.FromContext(ctx).WithName("syncDevicePluginAllocations")
+
+	allocatedIDs, err := readDevicePluginAllocations(s.devicePluginCheckpoint)
+	if err != nil {
+		return false, err
+	}
+	allocated := map[string]bool{}
+	for name, device := range s.GetAllocatableDevices() {
+		if allocatedIDs[strings.ToLower(attributeString(device.Attributes[consts.AttributePciAddress]))] {
+			allocated[name] = true
+		}
+	}
+
+	s.devicePluginMu.Lock()
+	defer s.devicePluginMu.Unlock()
+	if maps.Equal(allocated, s.devicePluginAllocated) {
+		return false, nil
+	}
+	for name := range allocated {
+		if !s.devicePluginAllocated[name] {
+			logger.Info("Withdrawing device allocated by the SR-IOV device plugin", "device", name)
+		}
+	}
+	for name := range s.devicePluginAllocated {
+		if !allocated[name] {
+			logger.Info("Publishing device released by the SR-IOV device plugin", "device", name)
+		}
+	}
+	s.devicePluginAllocated = allocated
+	return true, nil
+}
+
+// WatchDevicePluginCheckpoint keeps the devices allocated by the SR-IOV device plugin out of the
+// ResourceSlice until the context is done, so both can share the VFs of a node during a migration
+// to DRA. It returns at once when --device-plugin-checkpoint is not set.
+func (s *Manager) WatchDevicePluginCheckpoint(ctx context.Context) {
+	if s.devicePluginCheckpoint == "" {
+		return
+	}
+	logger := klog.FromContext(ctx).WithName("WatchDevicePluginCheckpoint")
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		changed, err := s.syncDevicePluginAllocations(ctx)
+		if err != nil {
+			logger.Error(err, "Failed to sync the devices allocated by the SR-IOV device plugin")
+			return
+		}
+		if changed {
+			s.republish(ctx)
+		}
+	}, devicePluginCheckpointInterval)
+}
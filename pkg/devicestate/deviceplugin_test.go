@@ -0,0 +1,124 @@
+package devicestate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Manager", Serial, func() {
+	Context("devices allocated by the SR-IOV device plugin", func() {
+		const checkpoint = `{"Data":{"PodDeviceEntries":[
+			{"PodUID":"pod-1","ContainerName":"app","ResourceName":"intel.com/sriov","DeviceIDs":{"0":["0000:01:00.1"]},"AllocResp":""},
+			{"PodUID":"pod-2","ContainerName":"app","ResourceName":"nvidia.com/gpu","DeviceIDs":{"-1":["GPU-1"]},"AllocResp":""}
+		],"RegisteredDevices":{"intel.com/sriov":["0000:01:00.1","0000:01:00.2"]}},"Checksum":1}`
+
+		var (
+			m              *Manager
+			checkpointPath string
+			republished    atomic.Int32
+		)
+
+		BeforeEach(func() {
+			checkpointPath = filepath.Join(GinkgoT().TempDir(), "kubelet_internal_checkpoint")
+			republished.Store(0)
+			m = &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name:       "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")}},
+					},
+					"device2": {
+						Name:       "device2",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.2")}},
+					},
+				},
+				policyAttrKeys:         map[string]map[resourceapi.QualifiedName]bool{"device1": {}, "device2": {}},
+				devicePluginCheckpoint: checkpointPath,
+				republishCallback: func(context.Context) error {
+					republished.Add(1)
+					return nil
+				},
+			}
+		})
+
+		It("reads the allocated devices of both checkpoint formats", func() {
+			Expect(os.WriteFile(checkpointPath, []byte(checkpoint), 0o600)).To(Succeed())
+			allocated, err := readDevicePluginAllocations(checkpointPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allocated).To(Equal(map[string]bool{"0000:01:00.1": true, "gpu-1": true}))
+
+			// checkpoints of kubelets older than 1.20 list the device IDs
+			Expect(os.WriteFile(checkpointPath, []byte(`{"Data":{"PodDeviceEntries":[{"ResourceName":"intel.com/sriov","DeviceIDs":["0000:01:00.2"]}]}}`), 0o600)).To(Succeed())
+			allocated, err = readDevicePluginAllocations(checkpointPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allocated).To(Equal(map[string]bool{"0000:01:00.2": true}))
+		})
+
+		It("allocates nothing without a checkpoint", func() {
+			allocated, err := readDevicePluginAllocations(checkpointPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allocated).To(BeEmpty())
+		})
+
+		It("fails on a corrupt checkpoint", func() {
+			Expect(os.WriteFile(checkpointPath, []byte(`{"Data":`), 0o600)).To(Succeed())
+			_, err := readDevicePluginAllocations(checkpointPath)
+			Expect(err).To(MatchError(ContainSubstring("failed to parse device plugin checkpoint")))
+		})
+
+		It("withdraws the allocated devices and refuses to prepare them", func() {
+			Expect(os.WriteFile(checkpointPath, []byte(checkpoint), 0o600)).To(Succeed())
+			changed, err := m.syncDevicePluginAllocations(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).To(BeTrue())
+
+			advertised := m.GetAdvertisedDevices()
+			Expect(advertised).To(HaveLen(1))
+			Expect(advertised).To(HaveKey("device2"))
+
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "default", UID: "claim-uid"}}
+			_, err = m.applyConfigOnDevice(context.Background(), new(int), claim, nil, &resourceapi.DeviceRequestAllocationResult{Device: "device1"})
+			Expect(err).To(MatchError("device device1 is allocated by the SR-IOV device plugin"))
+
+			changed, err = m.syncDevicePluginAllocations(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).To(BeFalse())
+		})
+
+		It("publishes the devices again once the device plugin releases them", func() {
+			origInterval := devicePluginCheckpointInterval
+			devicePluginCheckpointInterval = 10 * time.Millisecond
+			DeferCleanup(func() { devicePluginCheckpointInterval = origInterval })
+
+			Expect(os.WriteFile(checkpointPath, []byte(checkpoint), 0o600)).To(Succeed())
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			go m.WatchDevicePluginCheckpoint(ctx)
+
+			Eventually(m.GetAdvertisedDevices).Should(HaveLen(1))
+			Expect(republished.Load()).To(Equal(int32(1)))
+
+			Expect(os.WriteFile(checkpointPath, []byte(`{"Data":{"PodDeviceEntries":[]}}`), 0o600)).To(Succeed())
+			Eventually(m.GetAdvertisedDevices).Should(HaveLen(2))
+			Eventually(republished.Load).Should(Equal(int32(2)))
+		})
+
+		It("does not watch without a checkpoint", func() {
+			m.devicePluginCheckpoint = ""
+			m.WatchDevicePluginCheckpoint(context.Background())
+			Expect(m.GetAdvertisedDevices()).To(HaveLen(2))
+		})
+	})
+})
@@ -24,6 +24,21 @@ type PFInfo struct {
 	PCIeRoot    string
 	LinkType    string
 	NumaNode    string
+	// TotalVFs and NumVFs are nil when the VF counts of the PF could not be read
+	TotalVFs *int64
+	NumVFs   *int64
+	// FirmwareVersion and DriverVersion are reported by ethtool, empty when unknown
+	FirmwareVersion string
+	DriverVersion   string
+	// PartNumber is read from the VPD, BoardID is the PSID of NVIDIA/Mellanox NICs, empty when unknown
+	PartNumber string
+	BoardID    string
+	// NICID identifies the physical NIC of the PF, shared by the PFs of a multi-port NIC
+	NICID string
+	// SerialNumber is the serial number of the VPD of the PF, empty when unknown
+	SerialNumber string
+	// SwitchdevCapable reports that the eswitch of the PF supports the switchdev mode
+	SwitchdevCapable bool
 }
 
 func DiscoverSriovDevices() (types.AllocatableDevices, error) {
@@ -97,6 +112,41 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 			linkType = consts.LinkTypeUnknown // Default to unknown if we can't determine it
 		}
 
+		// Get the VF counts, so partially provisioned PFs can be told apart
+		var totalVFsPtr, numVFsPtr *int64
+		totalVFs, numVFs, err := host.GetHelpers().GetSriovVFCounts(device.Address)
+		if err != nil {
+			logger.V(2).Info("Failed to get VF counts", "address", device.Address, "error", err)
+		} else {
+			totalVFsPtr, numVFsPtr = ptr.To(int64(totalVFs)), ptr.To(int64(numVFs))
+		}
+
+		// Get the firmware and driver versions, for workloads depending on NIC features
+		var firmwareVersion, driverVersion string
+		driverInfo, err := host.GetHelpers().GetDriverInfo(pfNetName)
+		if err != nil {
+			logger.V(2).Info("Failed to get driver info", "address", device.Address, "interface", pfNetName, "error", err)
+		} else {
+			firmwareVersion, driverVersion = driverInfo.FirmwareVersion, driverInfo.Version
+		}
+
+		// Get the part number from the VPD, any vendor may provide it
+		var partNumber string
+		vpd, err := host.GetHelpers().GetVPD(device.Address)
+		if err != nil {
+			logger.V(2).Info("Failed to get VPD", "address", device.Address, "error", err)
+		} else {
+			partNumber = vpd[host.VPDKeywordPartNumber]
+		}
+		nicID := nicID(device.Address, vpd)
+
+		var boardID string
+		if device.Vendor.ID == consts.VendorMellanox {
+			boardID = mellanoxBoardID(device.Address, firmwareVersion)
+		}
+
+		switchdevCapable := host.GetHelpers().IsSwitchdevCapable(device.Address)
+
 		logger.Info("Found SR-IOV PF device",
 			"address", device.Address,
 			"interface", pfNetName,
@@ -105,7 +155,15 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 			"eswitchMode", eswitchMode,
 			"numaNode", numaNode,
 			"pcieRoot", pcieRoot,
-			"linkType", linkType)
+			"linkType", linkType,
+			"totalVFs", totalVFs,
+			"numVFs", numVFs,
+			"firmwareVersion", firmwareVersion,
+			"driverVersion", driverVersion,
+			"partNumber", partNumber,
+			"boardID", boardID,
+			"nicID", nicID,
+			"switchdevCapable", switchdevCapable)
 
 		pfList = append(pfList, PFInfo{
 			PciAddress:  device.Address,
@@ -117,11 +175,32 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 			PCIeRoot:    pcieRoot,
 			LinkType:    linkType,
 			NumaNode:    numaNode,
+			TotalVFs:    totalVFsPtr,
+			NumVFs:      numVFsPtr,
+
+			FirmwareVersion: firmwareVersion,
+			DriverVersion:   driverVersion,
+			PartNumber:      partNumber,
+			BoardID:         boardID,
+			NICID:           nicID,
+			SerialNumber:    vpd[host.VPDKeywordSerialNumber],
+
+			SwitchdevCapable: switchdevCapable,
 		})
 	}
 
 	logger.Info("Processing SR-IOV PF devices", "pfCount", len(pfList))
 
+	// VFs vDPA devices can be created on, listed once for all PFs
+	vdpaCapable := map[string]bool{}
+	vdpaDevices, err := host.GetHelpers().GetVDPAManagementDevices()
+	if err != nil {
+		logger.V(2).Info("Failed to get vDPA management devices, no VF is reported vDPA capable", "error", err)
+	}
+	for _, pciAddress := range vdpaDevices {
+		vdpaCapable[pciAddress] = true
+	}
+
 	for _, pfInfo := range pfList {
 		logger.V(1).Info("Getting VF list for PF", "pf", pfInfo.NetName, "address", pfInfo.Address)
 
@@ -145,8 +224,7 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 		numaNodeIntPtr := ptr.To(numaNodeInt)
 
 		for _, vfInfo := range vfList {
-			deviceName := strings.ReplaceAll(vfInfo.PciAddress, ":", "-")
-			deviceName = strings.ReplaceAll(deviceName, ".", "-")
+			deviceName := DeviceNameFromPciAddress(vfInfo.PciAddress)
 
 			// Check RDMA capability for this VF
 			rdmaCapable := host.GetHelpers().VerifyRDMACapability(vfInfo.PciAddress)
@@ -204,12 +282,89 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 				consts.AttributeRDMACapable: {
 					BoolValue: ptr.To(rdmaCapable),
 				},
+				consts.AttributeSwitchdevCapable: {
+					BoolValue: ptr.To(pfInfo.SwitchdevCapable),
+				},
+				consts.AttributeVDPACapable: {
+					BoolValue: ptr.To(vdpaCapable[vfInfo.PciAddress]),
+				},
 				// compatibility attributes
 				consts.AttributeNUMANode: {
 					IntValue: numaNodeIntPtr,
 				},
 			}
 
+			if pfInfo.TotalVFs != nil {
+				attributes[consts.AttributePFTotalVFs] = resourceapi.DeviceAttribute{IntValue: pfInfo.TotalVFs}
+				attributes[consts.AttributePFNumVFs] = resourceapi.DeviceAttribute{IntValue: pfInfo.NumVFs}
+			}
+
+			if pfInfo.FirmwareVersion != "" {
+				attributes[consts.AttributePFFirmwareVersion] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfInfo.FirmwareVersion)}
+			}
+			if pfInfo.DriverVersion != "" {
+				attributes[consts.AttributePFDriverVersion] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfInfo.DriverVersion)}
+			}
+
+			if pfInfo.PartNumber != "" {
+				attributes[consts.AttributePFPartNumber] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfInfo.PartNumber)}
+			}
+			if pfInfo.BoardID != "" {
+				attributes[consts.AttributePFBoardID] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfInfo.BoardID)}
+			}
+			if pfInfo.NICID != "" {
+				attributes[consts.AttributePFNICID] = resourceapi.DeviceAttribute{StringValue: ptr.To(pfInfo.NICID)}
+			}
+
+			// Identifier of the VF surviving PCI address changes, only for NICs with a serial number
+			if pfInfo.SerialNumber != "" {
+				attributes[consts.AttributeUUID] = resourceapi.DeviceAttribute{
+					StringValue: ptr.To(stableDeviceUUID(pfInfo.SerialNumber, pfInfo.PciAddress, vfInfo.VFID)),
+				}
+			}
+
+			// IOMMU group of the VF, VFs sharing a group cannot be isolated from each other with VFIO
+			iommuGroup, err := host.GetHelpers().GetIOMMUGroup(vfInfo.PciAddress)
+			if err != nil {
+				logger.V(2).Info("Failed to get VF IOMMU group", "vfAddress", vfInfo.PciAddress, "error", err)
+			} else if iommuGroup >= 0 {
+				attributes[consts.AttributeIOMMUGroup] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(iommuGroup))}
+			}
+
+			// GUIDs of InfiniBand VFs, as set by the administrator, for the partitions of the subnet
+			if pfInfo.LinkType == consts.LinkTypeInfiniband {
+				nodeGUID, portGUID, err := host.GetHelpers().GetVFGUIDs(vfInfo.PciAddress)
+				if err != nil {
+					logger.V(2).Info("Failed to get VF GUIDs", "vfAddress", vfInfo.PciAddress, "error", err)
+				} else {
+					attributes[consts.AttributeNodeGUID] = resourceapi.DeviceAttribute{StringValue: ptr.To(nodeGUID)}
+					attributes[consts.AttributePortGUID] = resourceapi.DeviceAttribute{StringValue: ptr.To(portGUID)}
+				}
+			}
+
+			// Driver the VF is bound to, empty when unbound, kept in sync after prepares by the Manager
+			vfDriver, err := host.GetHelpers().GetDriverByBusAndDevice(vfInfo.PciAddress)
+			if err != nil {
+				logger.Error(err, "Failed to get VF driver", "vfAddress", vfInfo.PciAddress)
+			} else {
+				attributes[consts.AttributeDriver] = resourceapi.DeviceAttribute{StringValue: ptr.To(vfDriver)}
+			}
+
+			// Standard network attributes, only for VFs bound to a network driver
+			if vfNetName := host.GetHelpers().TryGetInterfaceName(vfInfo.PciAddress); vfNetName != "" {
+				attributes[consts.AttributeStandardInterfaceName] = resourceapi.DeviceAttribute{
+					StringValue: ptr.To(vfNetName),
+				}
+				mac, err := host.GetHelpers().GetInterfaceMACAddress(vfInfo.PciAddress, vfNetName)
+				if err != nil {
+					logger.Error(err, "Failed to get VF MAC address", "vfAddress", vfInfo.PciAddress, "interface", vfNetName)
+				} else {
+					attributes[consts.AttributeStandardMAC] = resourceapi.DeviceAttribute{
+						StringValue: ptr.To(mac),
+					}
+				}
+			}
+
 			resourceList[deviceName] = resourceapi.Device{
 				Name:       deviceName,
 				Attributes: attributes,
@@ -220,3 +375,37 @@ func DiscoverSriovDevices() (types.AllocatableDevices, error) {
 	logger.Info("SR-IOV device discovery completed", "totalDevices", len(resourceList))
 	return resourceList, nil
 }
+
+// nicID returns the identifier of the physical NIC of a PF: the serial number of its VPD, or else
+// the PCI slot of the PF (domain:bus:device), shared by the functions of a multi-port NIC.
+func nicID(pciAddress string, vpd map[string]string) string {
+	if serialNumber := vpd[host.VPDKeywordSerialNumber]; serialNumber != "" {
+		return serialNumber
+	}
+	slot, _, found := strings.Cut(pciAddress, ".")
+	if !found {
+		return ""
+	}
+	return slot
+}
+
+// mellanoxBoardID returns the PSID of an NVIDIA/Mellanox PF, from its RDMA device or else from
+// the firmware version, which mlx5 reports as e.g. "28.43.1014 (MT_0000000838)".
+func mellanoxBoardID(pciAddress, firmwareVersion string) string {
+	boardID, err := host.GetHelpers().GetBoardID(pciAddress)
+	if err == nil && boardID != "" {
+		return boardID
+	}
+	start, end := strings.LastIndex(firmwareVersion, "("), strings.LastIndex(firmwareVersion, ")")
+	if start == -1 || end < start {
+		return ""
+	}
+	return strings.TrimSpace(firmwareVersion[start+1 : end])
+}
+
+// DeviceNameFromPciAddress returns the name of the device published for a VF,
+// e.g. 0000-08-00-2 for 0000:08:00.2.
+func DeviceNameFromPciAddress(pciAddress string) string {
+	deviceName := strings.ReplaceAll(pciAddress, ":", "-")
+	return strings.ReplaceAll(deviceName, ".", "-")
+}
@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/utils/ptr"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
@@ -77,9 +78,21 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("pci0000:00", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(42, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("eth0v0")
+			mockHost.EXPECT().GetInterfaceMACAddress("0000:01:00.1", "eth0v0").Return("aa:bb:cc:dd:ee:01", nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.2").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.2").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.2").Return("vfio-pci", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.2").Return("")
 
 			devices, err := DiscoverSriovDevices()
 			Expect(err).NotTo(HaveOccurred())
@@ -91,6 +104,10 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			Expect(dev1.Attributes[consts.AttributeVendorID].StringValue).To(Equal(ptr.To("8086")))
 			Expect(dev1.Attributes[consts.AttributeDeviceID].StringValue).To(Equal(ptr.To("154c")))
 			Expect(dev1.Attributes[consts.AttributePFDeviceID].StringValue).To(Equal(ptr.To("1572")))
+			Expect(dev1.Attributes[consts.AttributePFTotalVFs].IntValue).To(Equal(ptr.To(int64(8))))
+			Expect(dev1.Attributes[consts.AttributePFNumVFs].IntValue).To(Equal(ptr.To(int64(2))))
+			Expect(dev1.Attributes[consts.AttributePFFirmwareVersion].StringValue).To(Equal(ptr.To("9.20 0x8000d95e 1.3429.0")))
+			Expect(dev1.Attributes[consts.AttributePFDriverVersion].StringValue).To(Equal(ptr.To("2.24.6")))
 			Expect(dev1.Attributes[consts.AttributePciAddress].StringValue).To(Equal(ptr.To("0000:01:00.1")))
 			Expect(dev1.Attributes[consts.AttributePFName].StringValue).To(Equal(ptr.To("eth0")))
 			Expect(dev1.Attributes[consts.AttributeEswitchMode].StringValue).To(Equal(ptr.To("legacy")))
@@ -99,6 +116,7 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			Expect(dev1.Attributes[consts.AttributePfPciAddress].StringValue).To(Equal(ptr.To("0000:01:00.0")))
 			Expect(dev1.Attributes[consts.AttributeStandardPciAddress].StringValue).To(Equal(ptr.To("0000:01:00.1")))
 			Expect(dev1.Attributes[consts.AttributeLinkType].StringValue).To(Equal(ptr.To(consts.LinkTypeEthernet)))
+			Expect(dev1.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributeNodeGUID)))
 			// Compatibility attributes
 			Expect(dev1.Attributes[consts.AttributeNUMANode].IntValue).To(Equal(ptr.To(int64(0))))
 
@@ -107,6 +125,16 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			Expect(dev2.Name).To(Equal("0000-01-00-2"))
 			Expect(dev2.Attributes[consts.AttributeVFID].IntValue).To(Equal(ptr.To(int64(1))))
 			Expect(dev2.Attributes[consts.AttributeStandardPciAddress].StringValue).To(Equal(ptr.To("0000:01:00.2")))
+			// The IOMMU group is only published for VFs having one
+			Expect(dev1.Attributes[consts.AttributeIOMMUGroup].IntValue).To(Equal(ptr.To(int64(42))))
+			Expect(dev2.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributeIOMMUGroup)))
+			Expect(dev1.Attributes[consts.AttributeDriver].StringValue).To(Equal(ptr.To("iavf")))
+			Expect(dev2.Attributes[consts.AttributeDriver].StringValue).To(Equal(ptr.To("vfio-pci")))
+			// Standard network attributes are only published for VFs having a netdev
+			Expect(dev1.Attributes[consts.AttributeStandardInterfaceName].StringValue).To(Equal(ptr.To("eth0v0")))
+			Expect(dev1.Attributes[consts.AttributeStandardMAC].StringValue).To(Equal(ptr.To("aa:bb:cc:dd:ee:01")))
+			Expect(dev2.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributeStandardInterfaceName)))
+			Expect(dev2.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributeStandardMAC)))
 		})
 
 		It("should discover multiple PFs with VFs", func() {
@@ -143,6 +171,10 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("pci0000:00", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
 
 			// Second PF
 			mockHost.EXPECT().IsSriovVF("0000:02:00.0").Return(false)
@@ -151,11 +183,24 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:02:00.0").Return("1", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:02:00.0").Return("pci0000:00", nil)
 			mockHost.EXPECT().GetLinkType("0000:02:00.0").Return(consts.LinkTypeInfiniband, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:02:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth1").Return(&host.DriverInfo{Driver: "mlx5_core", Version: "24.10-1.1.4", FirmwareVersion: "22.41.1000 (MT_0000000359)"}, nil)
+			mockHost.EXPECT().GetVPD("0000:02:00.0").Return(map[string]string{"PN": "MCX623106AN-CDAT", "SN": "MT2142X12345"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:02:00.0").Return(true)
+			mockHost.EXPECT().GetBoardID("0000:02:00.0").Return("MT_0000000359", nil)
 
+			mockHost.EXPECT().GetVDPAManagementDevices().Return([]string{"0000:02:00.1"}, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList1, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
 			mockHost.EXPECT().GetVFList("0000:02:00.0").Return(vfList2, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:02:00.1").Return(false)
+			mockHost.EXPECT().GetVFGUIDs("0000:02:00.1").Return("02:00:00:00:00:00:00:01", "02:00:00:00:00:00:00:02", nil)
+			mockHost.EXPECT().GetIOMMUGroup("0000:02:00.1").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:02:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:02:00.1").Return("")
 
 			devices, err := DiscoverSriovDevices()
 			Expect(err).NotTo(HaveOccurred())
@@ -167,6 +212,14 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			Expect(dev1.Attributes[consts.AttributePFName].StringValue).To(Equal(ptr.To("eth0")))
 			Expect(dev1.Attributes[consts.AttributeEswitchMode].StringValue).To(Equal(ptr.To("legacy")))
 			Expect(dev1.Attributes[consts.AttributePCIeRoot].StringValue).To(Equal(ptr.To("pci0000:00")))
+			// the board ID is only looked up for NVIDIA/Mellanox NICs
+			Expect(dev1.Attributes[consts.AttributePFPartNumber].StringValue).To(Equal(ptr.To("X710DA2")))
+			Expect(dev1.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributePFBoardID)))
+			// without a VPD serial number the NIC is identified by the PCI slot of the PF
+			Expect(dev1.Attributes[consts.AttributePFNICID].StringValue).To(Equal(ptr.To("0000:01:00")))
+			Expect(dev1.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributeUUID)))
+			Expect(dev1.Attributes[consts.AttributeSwitchdevCapable].BoolValue).To(Equal(ptr.To(false)))
+			Expect(dev1.Attributes[consts.AttributeVDPACapable].BoolValue).To(Equal(ptr.To(false)))
 			Expect(dev1.Attributes[consts.AttributeStandardPciAddress].StringValue).To(Equal(ptr.To("0000:01:00.1")))
 			Expect(dev1.Attributes[consts.AttributeLinkType].StringValue).To(Equal(ptr.To(consts.LinkTypeEthernet)))
 			// Compatibility attributes
@@ -176,10 +229,18 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			dev2 := devices["0000-02-00-1"]
 			Expect(dev2.Attributes[consts.AttributeVendorID].StringValue).To(Equal(ptr.To("15b3")))
 			Expect(dev2.Attributes[consts.AttributePFName].StringValue).To(Equal(ptr.To("eth1")))
+			Expect(dev2.Attributes[consts.AttributePFPartNumber].StringValue).To(Equal(ptr.To("MCX623106AN-CDAT")))
+			Expect(dev2.Attributes[consts.AttributePFBoardID].StringValue).To(Equal(ptr.To("MT_0000000359")))
+			Expect(dev2.Attributes[consts.AttributePFNICID].StringValue).To(Equal(ptr.To("MT2142X12345")))
+			Expect(dev2.Attributes[consts.AttributeUUID].StringValue).To(Equal(ptr.To(stableDeviceUUID("MT2142X12345", "0000:02:00.0", 0))))
+			Expect(dev2.Attributes[consts.AttributeSwitchdevCapable].BoolValue).To(Equal(ptr.To(true)))
+			Expect(dev2.Attributes[consts.AttributeVDPACapable].BoolValue).To(Equal(ptr.To(true)))
 			Expect(dev2.Attributes[consts.AttributeEswitchMode].StringValue).To(Equal(ptr.To("switchdev")))
 			Expect(dev2.Attributes[consts.AttributePCIeRoot].StringValue).To(Equal(ptr.To("pci0000:00")))
 			Expect(dev2.Attributes[consts.AttributeStandardPciAddress].StringValue).To(Equal(ptr.To("0000:02:00.1")))
 			Expect(dev2.Attributes[consts.AttributeLinkType].StringValue).To(Equal(ptr.To(consts.LinkTypeInfiniband)))
+			Expect(dev2.Attributes[consts.AttributeNodeGUID].StringValue).To(Equal(ptr.To("02:00:00:00:00:00:00:01")))
+			Expect(dev2.Attributes[consts.AttributePortGUID].StringValue).To(Equal(ptr.To("02:00:00:00:00:00:00:02")))
 			// Compatibility attributes
 			Expect(dev2.Attributes[consts.AttributeNUMANode].IntValue).To(Equal(ptr.To(int64(1))))
 		})
@@ -207,8 +268,16 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
 
 			devices, err := DiscoverSriovDevices()
 			Expect(err).NotTo(HaveOccurred())
@@ -242,8 +311,16 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("pci0000:00", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return("", fmt.Errorf("lookup failed"))
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
 
 			devices, err := DiscoverSriovDevices()
 			Expect(err).NotTo(HaveOccurred())
@@ -258,6 +335,52 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			Expect(dev.Attributes[consts.AttributeStandardPciAddress].StringValue).To(Equal(ptr.To("0000:01:00.1")))
 		})
 
+		It("should not publish PF attributes that cannot be read", func() {
+			pciInfo := &pci.Info{
+				Devices: []*pci.Device{
+					{
+						Address: "0000:01:00.0",
+						Class:   &pcidb.Class{ID: "02"},
+						Vendor:  &pcidb.Vendor{ID: "8086"},
+						Product: &pcidb.Product{ID: "1572"},
+					},
+				},
+			}
+
+			vfList := []host.VFInfo{
+				{PciAddress: "0000:01:00.1", VFID: 0, DeviceID: "154c"},
+			}
+
+			mockHost.EXPECT().PCI().Return(pciInfo, nil)
+			mockHost.EXPECT().IsSriovVF("0000:01:00.0").Return(false)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.0").Return("eth0")
+			mockHost.EXPECT().GetNicSriovMode("0000:01:00.0").Return("legacy")
+			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
+			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("pci0000:00", nil)
+			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(0, 0, fmt.Errorf("lookup failed"))
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(nil, fmt.Errorf("operation not supported"))
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(nil, fmt.Errorf("no such file or directory"))
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
+			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
+			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
+
+			devices, err := DiscoverSriovDevices()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveLen(1))
+
+			dev := devices["0000-01-00-1"]
+			Expect(dev.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributePFTotalVFs)))
+			Expect(dev.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributePFNumVFs)))
+			Expect(dev.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributePFFirmwareVersion)))
+			Expect(dev.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributePFDriverVersion)))
+			Expect(dev.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributePFPartNumber)))
+		})
+
 		Context("RDMA Capability", func() {
 			var (
 				pciInfo *pci.Info
@@ -291,6 +414,12 @@ var _ = Describe("DiscoverSriovDevices", func() {
 				mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("1", nil)
 				mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("pci0000:00", nil)
 				mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeInfiniband, nil)
+				mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+				mockHost.EXPECT().GetDriverInfo("ib0").Return(&host.DriverInfo{Driver: "mlx5_core", Version: "24.10-1.1.4", FirmwareVersion: "28.43.1014 (MT_0000000838)"}, nil)
+				mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "MCX653106A-HDAT"}, nil)
+				mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+				// mlx5_ib not loaded, the PSID is taken from the firmware version
+				mockHost.EXPECT().GetBoardID("0000:01:00.0").Return("", fmt.Errorf("no RDMA device"))
 			})
 
 			It("should discover RDMA-capable VFs with RDMA attributes", func() {
@@ -307,13 +436,23 @@ var _ = Describe("DiscoverSriovDevices", func() {
 						DeviceID:   "1018",
 					},
 				}
+				mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 				mockHost.EXPECT().GetVFList("0000:01:00.0").Return(localVfList, nil)
 
 				// First VF is RDMA-capable
 				mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(true)
+				mockHost.EXPECT().GetVFGUIDs("0000:01:00.1").Return("02:00:00:00:00:00:00:01", "02:00:00:00:00:00:00:01", nil)
+				mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+				mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+				mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
 
 				// Second VF is not RDMA-capable
 				mockHost.EXPECT().VerifyRDMACapability("0000:01:00.2").Return(false)
+				// the GUIDs are not published when the PF driver does not report them
+				mockHost.EXPECT().GetVFGUIDs("0000:01:00.2").Return("", "", fmt.Errorf("no sriov directory"))
+				mockHost.EXPECT().GetIOMMUGroup("0000:01:00.2").Return(-1, nil)
+				mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.2").Return("iavf", nil)
+				mockHost.EXPECT().TryGetInterfaceName("0000:01:00.2").Return("")
 
 				devices, err := DiscoverSriovDevices()
 				Expect(err).NotTo(HaveOccurred())
@@ -327,6 +466,7 @@ var _ = Describe("DiscoverSriovDevices", func() {
 				Expect(dev1.Attributes[consts.AttributePFDeviceID].StringValue).To(Equal(ptr.To("1017")))
 				Expect(dev1.Attributes[consts.AttributePciAddress].StringValue).To(Equal(ptr.To("0000:01:00.1")))
 				Expect(dev1.Attributes[consts.AttributePFName].StringValue).To(Equal(ptr.To("ib0")))
+				Expect(dev1.Attributes[consts.AttributePFBoardID].StringValue).To(Equal(ptr.To("MT_0000000838")))
 				Expect(dev1.Attributes[consts.AttributeEswitchMode].StringValue).To(Equal(ptr.To("switchdev")))
 				Expect(dev1.Attributes[consts.AttributeVFID].IntValue).To(Equal(ptr.To(int64(0))))
 				Expect(dev1.Attributes[consts.AttributePCIeRoot].StringValue).To(Equal(ptr.To("pci0000:00")))
@@ -334,6 +474,7 @@ var _ = Describe("DiscoverSriovDevices", func() {
 				Expect(dev1.Attributes[consts.AttributeStandardPciAddress].StringValue).To(Equal(ptr.To("0000:01:00.1")))
 				// RDMA-specific attributes
 				Expect(dev1.Attributes[consts.AttributeRDMACapable].BoolValue).To(Equal(ptr.To(true)))
+				Expect(dev1.Attributes[consts.AttributeNodeGUID].StringValue).To(Equal(ptr.To("02:00:00:00:00:00:00:01")))
 				// Compatibility attributes
 				Expect(dev1.Attributes[consts.AttributeNUMANode].IntValue).To(Equal(ptr.To(int64(1))))
 
@@ -342,12 +483,18 @@ var _ = Describe("DiscoverSriovDevices", func() {
 				Expect(dev2.Name).To(Equal("0000-01-00-2"))
 				Expect(dev2.Attributes[consts.AttributeVFID].IntValue).To(Equal(ptr.To(int64(1))))
 				Expect(dev2.Attributes[consts.AttributeRDMACapable].BoolValue).To(Equal(ptr.To(false)))
+				Expect(dev2.Attributes).NotTo(HaveKey(resourceapi.QualifiedName(consts.AttributeNodeGUID)))
 			})
 
 			It("should handle RDMA capability check errors gracefully", func() {
+				mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 				mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
 				// RDMA capability check fails (returns false)
 				mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+				mockHost.EXPECT().GetVFGUIDs("0000:01:00.1").Return("02:00:00:00:00:00:00:01", "02:00:00:00:00:00:00:01", nil)
+				mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+				mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+				mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
 
 				devices, err := DiscoverSriovDevices()
 				Expect(err).NotTo(HaveOccurred())
@@ -382,6 +529,7 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().PCI().Return(pciInfo, nil)
 			// No other calls expected since devices are not network class
 
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			devices, err := DiscoverSriovDevices()
 			// When all devices are filtered, function returns successfully with empty list
 			Expect(err).NotTo(HaveOccurred())
@@ -419,8 +567,16 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:01:00.1").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:01:00.1").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.1").Return("")
 
 			// Second device (VF) - should be skipped
 			mockHost.EXPECT().IsSriovVF("0000:01:00.1").Return(true)
@@ -446,6 +602,7 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().IsSriovVF("0000:01:00.0").Return(false)
 			mockHost.EXPECT().TryGetInterfaceName("0000:01:00.0").Return("") // No interface name
 
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			devices, err := DiscoverSriovDevices()
 			// Device is skipped, returns successfully with empty list
 			Expect(err).NotTo(HaveOccurred())
@@ -467,6 +624,7 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().PCI().Return(pciInfo, nil)
 			// No other calls since parsing fails
 
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			devices, err := DiscoverSriovDevices()
 			// Device parsing fails, returns successfully with empty list
 			Expect(err).NotTo(HaveOccurred())
@@ -516,6 +674,11 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(nil, fmt.Errorf("failed to get VF list"))
 
 			devices, err := DiscoverSriovDevices()
@@ -549,8 +712,16 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return(vfList, nil)
 			mockHost.EXPECT().VerifyRDMACapability("0000:af:10.7").Return(false)
+			mockHost.EXPECT().GetIOMMUGroup("0000:af:10.7").Return(-1, nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:af:10.7").Return("iavf", nil)
+			mockHost.EXPECT().TryGetInterfaceName("0000:af:10.7").Return("")
 
 			devices, err := DiscoverSriovDevices()
 			Expect(err).NotTo(HaveOccurred())
@@ -581,6 +752,11 @@ var _ = Describe("DiscoverSriovDevices", func() {
 			mockHost.EXPECT().GetNumaNode("0000:01:00.0").Return("0", nil)
 			mockHost.EXPECT().GetPCIeRoot("0000:01:00.0").Return("", nil)
 			mockHost.EXPECT().GetLinkType("0000:01:00.0").Return(consts.LinkTypeEthernet, nil)
+			mockHost.EXPECT().GetSriovVFCounts("0000:01:00.0").Return(8, 2, nil)
+			mockHost.EXPECT().GetDriverInfo("eth0").Return(&host.DriverInfo{Driver: "i40e", Version: "2.24.6", FirmwareVersion: "9.20 0x8000d95e 1.3429.0"}, nil)
+			mockHost.EXPECT().GetVPD("0000:01:00.0").Return(map[string]string{"PN": "X710DA2"}, nil)
+			mockHost.EXPECT().IsSwitchdevCapable("0000:01:00.0").Return(false)
+			mockHost.EXPECT().GetVDPAManagementDevices().Return(nil, nil)
 			mockHost.EXPECT().GetVFList("0000:01:00.0").Return([]host.VFInfo{}, nil) // Empty list
 
 			devices, err := DiscoverSriovDevices()
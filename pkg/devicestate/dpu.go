@@ -0,0 +1,80 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/dpu"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// dpuVF identifies a device to the DPU agent.
+func (s *Manager) dpuVF(device *drasriovtypes.PreparedDevice) dpu.VF {
+	allocatable, _ := s.GetAllocatableDeviceByName(device.Device.DeviceName)
+	attributes := allocatable.Attributes
+	vf := dpu.VF{
+		NICID:        attributeString(attributes[consts.AttributePFNICID]),
+		PFPciAddress: attributeString(attributes[consts.AttributePfPciAddress]),
+		PFName:       attributeString(attributes[consts.AttributePFName]),
+		PciAddress:   device.PciAddress,
+	}
+	if vfID := attributes[consts.AttributeVFID].IntValue; vfID != nil {
+		vf.VFID = *vfID
+	}
+	return vf
+}
+
+// configureDPU delegates the programming of the representors of the prepared devices to the
+// agent of the DPU in the split-driver mode, see --dpu-agent-endpoint. Devices whose
+// representor is programmed record it, so unprepareDevices releases them, including after a
+// failure of a later device.
+func (s *Manager) configureDPU(ctx context.Context, claimName, claimNamespace string, preparedDevices drasriovtypes.PreparedDevices) error {
+	if s.dpuAgent == nil {
+		return nil
+	}
+	logger := klog.FromContext(ctx).WithName("configureDPU")
+
+	for _, device := range preparedDevices {
+		if device.AdminAccess {
+			continue
+		}
+		resp, err := s.dpuAgent.ConfigureVF(ctx, &dpu.ConfigureVFRequest{
+			VF:             s.dpuVF(device),
+			DeviceName:     device.Device.DeviceName,
+			ClaimUID:       string(device.ClaimNamespacedName.UID),
+			ClaimNamespace: claimNamespace,
+			ClaimName:      claimName,
+			PodUID:         device.PodUID,
+			NetworkConfig:  device.NetAttachDefConfig,
+		})
+		if err != nil {
+			return fmt.Errorf("DPU agent failed to configure device %s: %w", device.Device.DeviceName, err)
+		}
+		device.DPURepresentor = resp.Representor
+		logger.V(2).Info("DPU agent configured device", "device", device.Device.DeviceName, "representor", resp.Representor)
+	}
+	return nil
+}
+
+// releaseDPU asks the agent of the DPU to undo the programming of the representor of a device.
+func (s *Manager) releaseDPU(ctx context.Context, device *drasriovtypes.PreparedDevice) error {
+	if device.DPURepresentor == "" {
+		return nil
+	}
+	if s.dpuAgent == nil {
+		return fmt.Errorf("representor %s of device %s was programmed by a DPU agent but --dpu-agent-endpoint is not set", device.DPURepresentor, device.Device.DeviceName)
+	}
+	_, err := s.dpuAgent.ReleaseVF(ctx, &dpu.ReleaseVFRequest{
+		VF:          s.dpuVF(device),
+		DeviceName:  device.Device.DeviceName,
+		ClaimUID:    string(device.ClaimNamespacedName.UID),
+		Representor: device.DPURepresentor,
+	})
+	if err != nil {
+		return fmt.Errorf("DPU agent failed to release representor %s of device %s: %w", device.DPURepresentor, device.Device.DeviceName, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/dpu"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// fakeDPUAgent records the requests of the driver and fails the devices of failDevices.
+type fakeDPUAgent struct {
+	configured  []*dpu.ConfigureVFRequest
+	released    []*dpu.ReleaseVFRequest
+	failDevices map[string]bool
+}
+
+func (a *fakeDPUAgent) ConfigureVF(_ context.Context, req *dpu.ConfigureVFRequest) (*dpu.ConfigureVFResponse, error) {
+	if a.failDevices[req.DeviceName] {
+		return nil, errors.New("eswitch busy")
+	}
+	a.configured = append(a.configured, req)
+	return &dpu.ConfigureVFResponse{Representor: req.VF.PFName + "_" + req.DeviceName}, nil
+}
+
+func (a *fakeDPUAgent) ReleaseVF(_ context.Context, req *dpu.ReleaseVFRequest) (*dpu.ReleaseVFResponse, error) {
+	if a.failDevices[req.DeviceName] {
+		return nil, errors.New("eswitch busy")
+	}
+	a.released = append(a.released, req)
+	return &dpu.ReleaseVFResponse{}, nil
+}
+
+var _ = Describe("DPU split-driver mode", func() {
+	var (
+		agent   *fakeDPUAgent
+		manager *Manager
+	)
+
+	newDevice := func(name, pciAddress string) *drasriovtypes.PreparedDevice {
+		return &drasriovtypes.PreparedDevice{
+			Device:              drapbv1.Device{DeviceName: name},
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{NamespacedName: k8stypes.NamespacedName{Name: "claim", Namespace: "default"}, UID: "claim-uid"},
+			Config:              &configapi.VfConfig{},
+			PciAddress:          pciAddress,
+			PodUID:              "pod-uid",
+			NetAttachDefConfig:  `{"type":"sriov","vlan":100}`,
+		}
+	}
+
+	BeforeEach(func() {
+		agent = &fakeDPUAgent{failDevices: map[string]bool{}}
+		manager = &Manager{
+			dpuAgent: agent,
+			allocatable: drasriovtypes.AllocatableDevices{
+				"vf-1": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributePFNICID:      {StringValue: ptr.To("MT2334X00001")},
+					consts.AttributePfPciAddress: {StringValue: ptr.To("0000:03:00.0")},
+					consts.AttributePFName:       {StringValue: ptr.To("p0")},
+					consts.AttributeVFID:         {IntValue: ptr.To(int64(1))},
+				}},
+				"vf-2": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributePfPciAddress: {StringValue: ptr.To("0000:03:00.0")},
+					consts.AttributePFName:       {StringValue: ptr.To("p0")},
+					consts.AttributeVFID:         {IntValue: ptr.To(int64(2))},
+				}},
+			},
+		}
+	})
+
+	It("delegates the representors of the prepared devices to the agent", func() {
+		devices := drasriovtypes.PreparedDevices{newDevice("vf-1", "0000:03:00.3"), newDevice("vf-2", "0000:03:00.4")}
+
+		Expect(manager.configureDPU(context.Background(), "claim", "default", devices)).To(Succeed())
+		Expect(agent.configured).To(HaveLen(2))
+		Expect(*agent.configured[0]).To(Equal(dpu.ConfigureVFRequest{
+			VF:             dpu.VF{NICID: "MT2334X00001", PFPciAddress: "0000:03:00.0", PFName: "p0", VFID: 1, PciAddress: "0000:03:00.3"},
+			DeviceName:     "vf-1",
+			ClaimUID:       "claim-uid",
+			ClaimNamespace: "default",
+			ClaimName:      "claim",
+			PodUID:         "pod-uid",
+			NetworkConfig:  `{"type":"sriov","vlan":100}`,
+		}))
+		Expect(devices[0].DPURepresentor).To(Equal("p0_vf-1"))
+		Expect(devices[1].DPURepresentor).To(Equal("p0_vf-2"))
+
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+		Expect(agent.released).To(HaveLen(2))
+		Expect(agent.released[1].Representor).To(Equal("p0_vf-2"))
+		Expect(agent.released[1].VF.VFID).To(Equal(int64(2)))
+	})
+
+	It("keeps the representors already programmed when a device fails", func() {
+		agent.failDevices["vf-2"] = true
+		devices := drasriovtypes.PreparedDevices{newDevice("vf-1", "0000:03:00.3"), newDevice("vf-2", "0000:03:00.4")}
+
+		Expect(manager.configureDPU(context.Background(), "claim", "default", devices)).To(MatchError(ContainSubstring("DPU agent failed to configure device vf-2")))
+		Expect(devices[0].DPURepresentor).To(Equal("p0_vf-1"))
+		Expect(devices[1].DPURepresentor).To(BeEmpty())
+
+		// the rollback only releases what was programmed
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+		Expect(agent.released).To(HaveLen(1))
+	})
+
+	It("fails the unprepare when the agent cannot release a representor", func() {
+		device := newDevice("vf-1", "0000:03:00.3")
+		device.DPURepresentor = "p0_vf-1"
+		agent.failDevices["vf-1"] = true
+
+		Expect(manager.unprepareDevices(drasriovtypes.PreparedDevices{device})).To(MatchError(ContainSubstring("DPU agent failed to release representor p0_vf-1")))
+	})
+
+	It("does nothing without agent", func() {
+		manager.dpuAgent = nil
+		devices := drasriovtypes.PreparedDevices{newDevice("vf-1", "0000:03:00.3")}
+
+		Expect(manager.configureDPU(context.Background(), "claim", "default", devices)).To(Succeed())
+		Expect(devices[0].DPURepresentor).To(BeEmpty())
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+	})
+})
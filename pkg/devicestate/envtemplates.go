@@ -0,0 +1,178 @@
+package devicestate
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	resourceapi "k8s.io/api/resource/v1"
+)
+
+// reservedEnvPrefix is the prefix of the environment variables set by the driver, templates may
+// not override them.
+const reservedEnvPrefix = "SRIOVNETWORK_"
+
+// EnvTemplate is an environment variable added to the containers of every prepared device, on
+// top of the SRIOVNETWORK_* ones. Its name and value are Go templates rendered with the
+// EnvTemplateData of the device, see --env-templates.
+type EnvTemplate struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	name  *template.Template
+	value *template.Template
+}
+
+// EnvTemplateData is the device and claim data the environment variable templates are rendered
+// with.
+type EnvTemplateData struct {
+	// DeviceName is the name of the device in the ResourceSlice, EnvDeviceName is the same name
+	// usable in an environment variable name, as in SRIOVNETWORK_VF_DEVICE_<EnvDeviceName>.
+	DeviceName    string
+	EnvDeviceName string
+	PCIAddress    string
+	PFName        string
+	ResourceName  string
+	// Driver is the driver requested by the VfConfig, empty for the default kernel driver.
+	Driver                string
+	IfName                string
+	NetAttachDefName      string
+	NetAttachDefNamespace string
+	ClaimName             string
+	ClaimNamespace        string
+	ClaimUID              string
+	PodUID                string // empty for a claim shared by several pods
+	Request               string
+	// Attributes are the attributes published for the device, keyed by qualified name.
+	Attributes map[string]string
+}
+
+var envTemplateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"replace": func(old, replacement, s string) string {
+		return strings.ReplaceAll(s, old, replacement)
+	},
+	"envName": envName,
+}
+
+// ParseEnvTemplates parses the JSON list of environment variable templates of --env-templates,
+// e.g. [{"name": "PCIDEVICE_{{ envName .ResourceName }}", "value": "{{ .PCIAddress }}"}].
+func ParseEnvTemplates(raw string) ([]*EnvTemplate, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var envTemplates []*EnvTemplate
+	if err := json.Unmarshal([]byte(raw), &envTemplates); err != nil {
+		return nil, fmt.Errorf("invalid env-templates, expected a JSON list of name and value templates: %w", err)
+	}
+	for i, envTemplate := range envTemplates {
+		if envTemplate.Name == "" {
+			return nil, fmt.Errorf("invalid env-templates: template %d has no name", i)
+		}
+		var err error
+		if envTemplate.name, err = newEnvTemplate(envTemplate.Name); err != nil {
+			return nil, fmt.Errorf("invalid env-templates: name of template %d: %w", i, err)
+		}
+		if envTemplate.value, err = newEnvTemplate(envTemplate.Value); err != nil {
+			return nil, fmt.Errorf("invalid env-templates: value of template %d: %w", i, err)
+		}
+	}
+	return envTemplates, nil
+}
+
+// configEnvTemplates parses the environment variables of the env of a VfConfig, sorted by name so
+// the containers get them in a stable order.
+func configEnvTemplates(env map[string]string) ([]*EnvTemplate, error) {
+	envTemplates := make([]*EnvTemplate, 0, len(env))
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if name == "" {
+			return nil, fmt.Errorf("invalid env: variable without name")
+		}
+		envTemplate := &EnvTemplate{Name: name, Value: env[name]}
+		var err error
+		if envTemplate.name, err = newEnvTemplate(envTemplate.Name); err != nil {
+			return nil, fmt.Errorf("invalid env: name %q: %w", name, err)
+		}
+		if envTemplate.value, err = newEnvTemplate(envTemplate.Value); err != nil {
+			return nil, fmt.Errorf("invalid env: value of %q: %w", name, err)
+		}
+		envTemplates = append(envTemplates, envTemplate)
+	}
+	return envTemplates, nil
+}
+
+func newEnvTemplate(text string) (*template.Template, error) {
+	// attributes a device does not have render as empty strings
+	return template.New("env").Funcs(envTemplateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// renderEnvTemplates returns the environment variables of the templates for a device. A template
+// whose name renders empty is skipped, so templates can only apply to some devices.
+func renderEnvTemplates(envTemplates []*EnvTemplate, data *EnvTemplateData) ([]string, error) {
+	envs := make([]string, 0, len(envTemplates))
+	for _, envTemplate := range envTemplates {
+		var name, value strings.Builder
+		if err := envTemplate.name.Execute(&name, data); err != nil {
+			return nil, fmt.Errorf("failed to render env template name %q: %w", envTemplate.Name, err)
+		}
+		if name.Len() == 0 {
+			continue
+		}
+		if strings.ContainsAny(name.String(), "= \t\n") {
+			return nil, fmt.Errorf("env template name %q rendered to invalid name %q", envTemplate.Name, name.String())
+		}
+		if strings.HasPrefix(name.String(), reservedEnvPrefix) {
+			return nil, fmt.Errorf("env template name %q rendered to %q, the %s prefix is reserved for the driver",
+				envTemplate.Name, name.String(), reservedEnvPrefix)
+		}
+		if err := envTemplate.value.Execute(&value, data); err != nil {
+			return nil, fmt.Errorf("failed to render env template value %q: %w", envTemplate.Value, err)
+		}
+		envs = append(envs, name.String()+"="+value.String())
+	}
+	return envs, nil
+}
+
+// envName converts a string to an environment variable name, e.g. the resource name
+// "intel.com/sriov-net" to "INTEL_COM_SRIOV_NET" as the SR-IOV network device plugin does.
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// attributeString returns the value of a device attribute as a string.
+func attributeString(attribute resourceapi.DeviceAttribute) string {
+	switch {
+	case attribute.StringValue != nil:
+		return *attribute.StringValue
+	case attribute.IntValue != nil:
+		return strconv.FormatInt(*attribute.IntValue, 10)
+	case attribute.BoolValue != nil:
+		return strconv.FormatBool(*attribute.BoolValue)
+	case attribute.VersionValue != nil:
+		return *attribute.VersionValue
+	}
+	return ""
+}
+
+// envTemplateAttributes returns the attributes of a device keyed by qualified name.
+func envTemplateAttributes(attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) map[string]string {
+	values := make(map[string]string, len(attributes))
+	for name, attribute := range attributes {
+		values[string(name)] = attributeString(attribute)
+	}
+	return values
+}
@@ -0,0 +1,110 @@
+package devicestate
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Env templates", func() {
+	data := &EnvTemplateData{
+		DeviceName:    "0000-01-00-1",
+		EnvDeviceName: "0000_01_00_1",
+		PCIAddress:    "0000:01:00.1",
+		ResourceName:  "intel.com/sriov-net",
+		Attributes:    map[string]string{"dra.net/numaNode": "1"},
+	}
+
+	Context("ParseEnvTemplates", func() {
+		It("returns no templates when unset", func() {
+			envTemplates, err := ParseEnvTemplates("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envTemplates).To(BeEmpty())
+		})
+
+		It("rejects invalid lists and templates", func() {
+			_, err := ParseEnvTemplates(`{"name": "FOO"}`)
+			Expect(err).To(MatchError(ContainSubstring("expected a JSON list")))
+			_, err = ParseEnvTemplates(`[{"value": "{{ .PCIAddress }}"}]`)
+			Expect(err).To(MatchError(ContainSubstring("has no name")))
+			_, err = ParseEnvTemplates(`[{"name": "FOO", "value": "{{ .PCIAddress "}]`)
+			Expect(err).To(MatchError(ContainSubstring("value of template 0")))
+			_, err = ParseEnvTemplates(`[{"name": "{{ unknown .PCIAddress }}"}]`)
+			Expect(err).To(MatchError(ContainSubstring("name of template 0")))
+		})
+	})
+
+	Context("renderEnvTemplates", func() {
+		It("renders the names and values with the device data", func() {
+			envTemplates, err := ParseEnvTemplates(`[
+				{"name": "PCIDEVICE_{{ envName .ResourceName }}", "value": "{{ .PCIAddress }}"},
+				{"name": "VF_{{ .EnvDeviceName }}_NUMA", "value": "{{ index .Attributes \"dra.net/numaNode\" }}"},
+				{"name": "VF_{{ .EnvDeviceName }}_VENDOR", "value": "{{ index .Attributes \"unknown\" }}"},
+				{"name": "RESOURCE", "value": "{{ .ResourceName | replace \"/\" \"-\" | upper }}"}
+			]`)
+			Expect(err).NotTo(HaveOccurred())
+
+			envs, err := renderEnvTemplates(envTemplates, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(Equal([]string{
+				"PCIDEVICE_INTEL_COM_SRIOV_NET=0000:01:00.1",
+				"VF_0000_01_00_1_NUMA=1",
+				"VF_0000_01_00_1_VENDOR=",
+				"RESOURCE=INTEL.COM-SRIOV-NET",
+			}))
+		})
+
+		It("skips the templates whose name renders empty", func() {
+			envTemplates, err := ParseEnvTemplates(`[{"name": "{{ if .Driver }}DPDK_{{ .EnvDeviceName }}{{ end }}", "value": "1"}]`)
+			Expect(err).NotTo(HaveOccurred())
+
+			envs, err := renderEnvTemplates(envTemplates, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(BeEmpty())
+		})
+
+		It("rejects invalid and reserved names", func() {
+			envTemplates, err := ParseEnvTemplates(`[{"name": "VF {{ .PCIAddress }}", "value": "1"}]`)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = renderEnvTemplates(envTemplates, data)
+			Expect(err).To(MatchError(ContainSubstring("invalid name")))
+
+			envTemplates, err = ParseEnvTemplates(`[{"name": "SRIOVNETWORK_{{ .EnvDeviceName }}", "value": "1"}]`)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = renderEnvTemplates(envTemplates, data)
+			Expect(err).To(MatchError(ContainSubstring("reserved for the driver")))
+		})
+	})
+
+	Context("configEnvTemplates", func() {
+		It("renders the env of a VfConfig sorted by name", func() {
+			envTemplates, err := configEnvTemplates(map[string]string{
+				"APP_VF_{{ .EnvDeviceName }}": "{{ .PCIAddress }}",
+				"APP_MODE":                    "fast",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			envs, err := renderEnvTemplates(envTemplates, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs).To(Equal([]string{"APP_MODE=fast", "APP_VF_0000_01_00_1=0000:01:00.1"}))
+		})
+
+		It("rejects invalid templates", func() {
+			_, err := configEnvTemplates(map[string]string{"APP": "{{ .PCIAddress "})
+			Expect(err).To(MatchError(ContainSubstring(`invalid env: value of "APP"`)))
+			_, err = configEnvTemplates(map[string]string{"": "1"})
+			Expect(err).To(MatchError(ContainSubstring("variable without name")))
+		})
+	})
+
+	Context("attributeString", func() {
+		It("formats every attribute type", func() {
+			Expect(attributeString(resourceapi.DeviceAttribute{StringValue: ptr.To("eth0")})).To(Equal("eth0"))
+			Expect(attributeString(resourceapi.DeviceAttribute{IntValue: ptr.To(int64(3))})).To(Equal("3"))
+			Expect(attributeString(resourceapi.DeviceAttribute{BoolValue: ptr.To(true)})).To(Equal("true"))
+			Expect(attributeString(resourceapi.DeviceAttribute{VersionValue: ptr.To("1.2.3")})).To(Equal("1.2.3"))
+			Expect(attributeString(resourceapi.DeviceAttribute{})).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package devicestate
+
+import (
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// excludeDevices removes the devices reserved by other agents of the node, see --excluded-devices.
+// An entry is the PCI address of a VF or of a PF, excluding all its VFs, or the netdev name of a PF.
+func excludeDevices(allocatable drasriovtypes.AllocatableDevices, excluded []string) {
+	if len(excluded) == 0 {
+		return
+	}
+	logger := klog.LoggerWithName(klog.Background(), "excludeDevices")
+
+	entries := make(map[string]bool, len(excluded))
+	for _, entry := range excluded {
+		entries[strings.ToLower(entry)] = true
+	}
+	for name, device := range allocatable {
+		if entry, ok := excludedBy(device.Attributes, entries); ok {
+			logger.Info("Excluding device, see --excluded-devices", "device", name, "entry", entry)
+			delete(allocatable, name)
+		}
+	}
+}
+
+// excludedBy returns the entry of the exclusion list matching a device, if any.
+func excludedBy(attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, entries map[string]bool) (string, bool) {
+	for _, attribute := range []resourceapi.QualifiedName{consts.AttributePciAddress, consts.AttributePfPciAddress, consts.AttributePFName} {
+		if value, ok := attributes[attribute]; ok && value.StringValue != nil {
+			if entry := strings.ToLower(*value.StringValue); entries[entry] {
+				return entry, true
+			}
+		}
+	}
+	return "", false
+}
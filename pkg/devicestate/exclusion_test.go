@@ -0,0 +1,52 @@
+package devicestate
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("excludeDevices", func() {
+	var allocatable drasriovtypes.AllocatableDevices
+
+	newVF := func(name, pciAddress, pfPciAddress, pfName string) resourceapi.Device {
+		return resourceapi.Device{
+			Name: name,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributePciAddress:   {StringValue: ptr.To(pciAddress)},
+				consts.AttributePfPciAddress: {StringValue: ptr.To(pfPciAddress)},
+				consts.AttributePFName:       {StringValue: ptr.To(pfName)},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		allocatable = drasriovtypes.AllocatableDevices{
+			"vf0": newVF("vf0", "0000:01:00.1", "0000:01:00.0", "eth0"),
+			"vf1": newVF("vf1", "0000:01:00.2", "0000:01:00.0", "eth0"),
+			"vf2": newVF("vf2", "0000:02:00.1", "0000:02:00.0", "eth1"),
+			"vf3": newVF("vf3", "0000:03:00.1", "0000:03:00.0", "eth2"),
+		}
+	})
+
+	It("excludes VFs by PCI address, PF PCI address and PF name", func() {
+		excludeDevices(allocatable, []string{"0000:01:00.2", "0000:02:00.0", "eth2"})
+		Expect(allocatable).To(HaveLen(1))
+		Expect(allocatable).To(HaveKey("vf0"))
+	})
+
+	It("matches PCI addresses regardless of case", func() {
+		allocatable["vf4"] = newVF("vf4", "0000:0a:00.1", "0000:0a:00.0", "eth3")
+		excludeDevices(allocatable, []string{"0000:0A:00.0"})
+		Expect(allocatable).NotTo(HaveKey("vf4"))
+	})
+
+	It("keeps all devices without exclusion list", func() {
+		excludeDevices(allocatable, nil)
+		Expect(allocatable).To(HaveLen(4))
+	})
+})
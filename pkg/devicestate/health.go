@@ -0,0 +1,40 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// discoveredPciAddresses returns the sorted PCI addresses of the discovered devices.
+func discoveredPciAddresses(allocatable drasriovtypes.AllocatableDevices) []string {
+	pciAddresses := make([]string, 0, len(allocatable))
+	for _, device := range allocatable {
+		if attribute, ok := device.Attributes[consts.AttributePciAddress]; ok && attribute.StringValue != nil {
+			pciAddresses = append(pciAddresses, *attribute.StringValue)
+		}
+	}
+	slices.Sort(pciAddresses)
+	return pciAddresses
+}
+
+// CheckDiscoveredDevices checks that the VFs discovered at startup still exist, they are only
+// discovered once, so VFs removed by a change of sriov_numvfs stay published until the driver
+// restarts. It is the discovery probe of the healthcheck.
+func (s *Manager) CheckDiscoveredDevices(_ context.Context) error {
+	var missing []string
+	for _, pciAddress := range s.discoveredPciAddresses {
+		if !host.GetHelpers().IsSriovVF(pciAddress) {
+			missing = append(missing, pciAddress)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%d discovered devices no longer exist: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
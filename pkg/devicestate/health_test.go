@@ -0,0 +1,58 @@
+package devicestate
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Discovery healthcheck", func() {
+	var (
+		ctrl     *gomock.Controller
+		mockHost *hostmock.MockInterface
+		manager  *Manager
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() { host.Helpers = originalHelpers })
+
+		manager = &Manager{discoveredPciAddresses: discoveredPciAddresses(drasriovtypes.AllocatableDevices{
+			"vf-2": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributePciAddress: {StringValue: ptr.To("0000:03:00.3")},
+			}},
+			"vf-1": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributePciAddress: {StringValue: ptr.To("0000:03:00.2")},
+			}},
+		})}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("passes while the discovered VFs exist", func() {
+		Expect(manager.discoveredPciAddresses).To(Equal([]string{"0000:03:00.2", "0000:03:00.3"}))
+		mockHost.EXPECT().IsSriovVF(gomock.Any()).Return(true).Times(2)
+		Expect(manager.CheckDiscoveredDevices(context.Background())).To(Succeed())
+	})
+
+	It("fails when discovered VFs were removed", func() {
+		mockHost.EXPECT().IsSriovVF("0000:03:00.2").Return(false)
+		mockHost.EXPECT().IsSriovVF("0000:03:00.3").Return(true)
+		Expect(manager.CheckDiscoveredDevices(context.Background())).To(MatchError("1 discovered devices no longer exist: 0000:03:00.2"))
+	})
+})
@@ -0,0 +1,78 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+)
+
+// validateInfiniBandConfig checks that a VfConfig assigning GUIDs targets an InfiniBand VF.
+func validateInfiniBandConfig(config *configapi.VfConfig, device resourceapi.Device) error {
+	if config.InfiniBand == nil {
+		return nil
+	}
+	if linkType := attributeString(device.Attributes[consts.AttributeLinkType]); linkType != consts.LinkTypeInfiniband {
+		return fmt.Errorf("infiniBand requires a VF of an InfiniBand PF, device %s has link type %q", device.Name, linkType)
+	}
+	return config.InfiniBand.Validate()
+}
+
+// validateInfiniBandRequests checks that the requests whose VfConfig assigns GUIDs are allocated
+// a single device, two VFs with the same GUIDs would conflict in the subnet.
+func validateInfiniBandRequests(claim *resourceapi.ResourceClaim, resultsConfig map[string]*configapi.VfConfig) error {
+	devices := map[string]int{}
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver != consts.DriverName {
+			continue
+		}
+		if config, ok := resultsConfig[result.Request]; ok && config.InfiniBand != nil {
+			devices[result.Request]++
+			if devices[result.Request] > 1 {
+				return fmt.Errorf("request %s assigns infiniBand GUIDs to more than one device", result.Request)
+			}
+		}
+	}
+	return nil
+}
+
+// setInfiniBandGUIDs assigns the GUIDs of a VfConfig to an InfiniBand VF and returns the GUIDs
+// it had, to be restored with restoreInfiniBandGUIDs. It returns empty GUIDs when the VfConfig
+// assigns none.
+func setInfiniBandGUIDs(ctx context.Context, pciAddress string, config *configapi.VfConfig) (string, string, error) {
+	if config.InfiniBand == nil {
+		return "", "", nil
+	}
+	nodeGUID, portGUID, err := host.GetHelpers().GetVFGUIDs(pciAddress)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get GUIDs of device %s: %w", pciAddress, err)
+	}
+	if err := host.GetHelpers().SetVFGUIDs(pciAddress, config.InfiniBand.NodeGUID, config.InfiniBand.PortGUID); err != nil {
+		// the node GUID may already be set
+		if restoreErr := restoreInfiniBandGUIDs(ctx, pciAddress, nodeGUID, portGUID); restoreErr != nil {
+			return "", "", fmt.Errorf("failed to set GUIDs of device %s: %w; additionally failed to restore them: %v", pciAddress, err, restoreErr)
+		}
+		return "", "", fmt.Errorf("failed to set GUIDs of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("setInfiniBandGUIDs").V(2).Info("Set GUIDs of device", "device", pciAddress,
+		"nodeGUID", config.InfiniBand.NodeGUID, "portGUID", config.InfiniBand.PortGUID)
+	return nodeGUID, portGUID, nil
+}
+
+// restoreInfiniBandGUIDs sets back the GUIDs an InfiniBand VF had before setInfiniBandGUIDs.
+func restoreInfiniBandGUIDs(ctx context.Context, pciAddress, nodeGUID, portGUID string) error {
+	if nodeGUID == "" {
+		return nil
+	}
+	if err := host.GetHelpers().SetVFGUIDs(pciAddress, nodeGUID, portGUID); err != nil {
+		return fmt.Errorf("failed to restore GUIDs of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("restoreInfiniBandGUIDs").V(2).Info("Restored GUIDs of device", "device", pciAddress,
+		"nodeGUID", nodeGUID, "portGUID", portGUID)
+	return nil
+}
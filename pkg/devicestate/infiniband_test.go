@@ -0,0 +1,112 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("InfiniBand GUIDs", func() {
+	const (
+		originalGUID = "00:00:00:00:00:00:00:00"
+		nodeGUID     = "02:00:00:00:00:00:00:01"
+		portGUID     = "02:00:00:00:00:00:00:02"
+	)
+
+	var (
+		ctrl     *gomock.Controller
+		mockHost *hostmock.MockInterface
+		config   *configapi.VfConfig
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() { host.Helpers = originalHelpers })
+		config = &configapi.VfConfig{InfiniBand: &configapi.InfiniBandConfig{NodeGUID: nodeGUID, PortGUID: portGUID}}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("only accepts VFs of InfiniBand PFs", func() {
+		device := resourceapi.Device{Name: "vf-1", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			consts.AttributeLinkType: {StringValue: ptr.To(consts.LinkTypeEthernet)},
+		}}
+		Expect(validateInfiniBandConfig(config, device)).To(MatchError(ContainSubstring(`device vf-1 has link type "ethernet"`)))
+
+		device.Attributes[consts.AttributeLinkType] = resourceapi.DeviceAttribute{StringValue: ptr.To(consts.LinkTypeInfiniband)}
+		Expect(validateInfiniBandConfig(config, device)).To(Succeed())
+		Expect(validateInfiniBandConfig(&configapi.VfConfig{}, resourceapi.Device{})).To(Succeed())
+	})
+
+	It("rejects requests assigning the same GUIDs to several devices", func() {
+		claim := &resourceapi.ResourceClaim{Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{Devices: resourceapi.DeviceAllocationResult{
+				Results: []resourceapi.DeviceRequestAllocationResult{
+					{Request: "ib", Driver: consts.DriverName, Device: "vf-1"},
+					{Request: "eth", Driver: consts.DriverName, Device: "vf-2"},
+					{Request: "eth", Driver: consts.DriverName, Device: "vf-3"},
+				},
+			}},
+		}}
+		resultsConfig := map[string]*configapi.VfConfig{"ib": config, "eth": {}}
+		Expect(validateInfiniBandRequests(claim, resultsConfig)).To(Succeed())
+
+		claim.Status.Allocation.Devices.Results[1].Request = "ib"
+		Expect(validateInfiniBandRequests(claim, resultsConfig)).To(MatchError("request ib assigns infiniBand GUIDs to more than one device"))
+	})
+
+	It("sets the GUIDs of the VF and restores them on unprepare", func() {
+		mockHost.EXPECT().GetVFGUIDs("0000:03:00.2").Return(originalGUID, originalGUID, nil)
+		mockHost.EXPECT().SetVFGUIDs("0000:03:00.2", nodeGUID, portGUID).Return(nil)
+
+		node, port, err := setInfiniBandGUIDs(context.Background(), "0000:03:00.2", config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node).To(Equal(originalGUID))
+		Expect(port).To(Equal(originalGUID))
+
+		device := &drasriovtypes.PreparedDevice{
+			Device:           drapbv1.Device{DeviceName: "vf-1"},
+			Config:           config,
+			PciAddress:       "0000:03:00.2",
+			OriginalNodeGUID: node,
+			OriginalPortGUID: port,
+		}
+		mockHost.EXPECT().SetVFGUIDs("0000:03:00.2", originalGUID, originalGUID).Return(nil)
+		Expect((&Manager{}).unprepareDevices(drasriovtypes.PreparedDevices{device})).To(Succeed())
+	})
+
+	It("restores the GUIDs when they cannot all be set", func() {
+		mockHost.EXPECT().GetVFGUIDs("0000:03:00.2").Return(originalGUID, originalGUID, nil)
+		mockHost.EXPECT().SetVFGUIDs("0000:03:00.2", nodeGUID, portGUID).Return(errors.New("failed to set port GUID"))
+		mockHost.EXPECT().SetVFGUIDs("0000:03:00.2", originalGUID, originalGUID).Return(nil)
+
+		_, _, err := setInfiniBandGUIDs(context.Background(), "0000:03:00.2", config)
+		Expect(err).To(MatchError(ContainSubstring("failed to set GUIDs of device 0000:03:00.2")))
+	})
+
+	It("does nothing without InfiniBand config", func() {
+		node, port, err := setInfiniBandGUIDs(context.Background(), "0000:03:00.2", &configapi.VfConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node).To(BeEmpty())
+		Expect(port).To(BeEmpty())
+		Expect(restoreInfiniBandGUIDs(context.Background(), "0000:03:00.2", "", "")).To(Succeed())
+	})
+})
@@ -0,0 +1,67 @@
+package devicestate
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// kubeVirtPCIResourcePrefix prefixes the environment variables holding the PCI addresses of the
+// host devices of each resource, read by virt-launcher.
+const kubeVirtPCIResourcePrefix = "PCI_RESOURCE"
+
+// kubeVirtEnvName returns the environment variable virt-launcher reads the PCI addresses of the
+// host devices of a resource from, e.g. PCI_RESOURCE_INTEL_COM_SRIOV for intel.com/sriov.
+func kubeVirtEnvName(resourceName string) string {
+	name := strings.ToUpper(resourceName)
+	name = strings.ReplaceAll(name, "/", "_")
+	name = strings.ReplaceAll(name, ".", "_")
+	return kubeVirtPCIResourcePrefix + "_" + name
+}
+
+// kubeVirtDevices exposes the PCI addresses of the prepared devices in KubeVirt mode to
+// virt-launcher. The devices of a resource share a single variable listing all their addresses,
+// so it is set with the same value on each of them and the containers get the complete list
+// whatever the order the runtime applies the CDI devices in.
+func (s *Manager) kubeVirtDevices(preparedDevices drasriovtypes.PreparedDevices) {
+	logger := klog.LoggerWithName(klog.Background(), "kubeVirtDevices")
+
+	var envNames []string
+	devicesByEnv := map[string]drasriovtypes.PreparedDevices{}
+	for _, device := range preparedDevices {
+		if device.Config == nil || device.Config.KubeVirt == nil {
+			continue
+		}
+		resourceName := device.Config.KubeVirt.ResourceName
+		if resourceName == "" {
+			allocatable, _ := s.GetAllocatableDeviceByName(device.Device.DeviceName)
+			resourceName = attributeString(allocatable.Attributes[consts.AttributeResourceName])
+		}
+		// KubeVirt DRA host devices find the PCI address in the ResourceSlice instead
+		if resourceName == "" {
+			logger.V(2).Info("No resource name for KubeVirt device, skipping its PCI_RESOURCE variable", "device", device.Device.DeviceName)
+			continue
+		}
+		envName := kubeVirtEnvName(resourceName)
+		if _, ok := devicesByEnv[envName]; !ok {
+			envNames = append(envNames, envName)
+		}
+		devicesByEnv[envName] = append(devicesByEnv[envName], device)
+	}
+
+	for _, envName := range envNames {
+		devices := devicesByEnv[envName]
+		pciAddresses := make([]string, 0, len(devices))
+		for _, device := range devices {
+			pciAddresses = append(pciAddresses, device.PciAddress)
+		}
+		env := fmt.Sprintf("%s=%s", envName, strings.Join(pciAddresses, ","))
+		for _, device := range devices {
+			device.ContainerEdits.Env = append(device.ContainerEdits.Env, env)
+		}
+	}
+}
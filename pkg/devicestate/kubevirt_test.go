@@ -0,0 +1,67 @@
+package devicestate
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("KubeVirt", func() {
+	newDevice := func(name, pciAddress string, config *configapi.VfConfig) *drasriovtypes.PreparedDevice {
+		return &drasriovtypes.PreparedDevice{
+			Device:         drapbv1.Device{DeviceName: name},
+			Config:         config,
+			PciAddress:     pciAddress,
+			ContainerEdits: &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{}},
+		}
+	}
+
+	It("names the variables after the resource names the way virt-launcher does", func() {
+		Expect(kubeVirtEnvName("intel.com/sriov")).To(Equal("PCI_RESOURCE_INTEL_COM_SRIOV"))
+		Expect(kubeVirtEnvName("nvidia.com/cx7_vf")).To(Equal("PCI_RESOURCE_NVIDIA_COM_CX7_VF"))
+	})
+
+	Context("kubeVirtDevices", func() {
+		var manager *Manager
+
+		BeforeEach(func() {
+			manager = &Manager{allocatable: drasriovtypes.AllocatableDevices{
+				"vf-1": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{consts.AttributeResourceName: {StringValue: ptr.To("nvidia.com/cx7")}}},
+				"vf-2": {},
+				"vf-3": {},
+				"vf-4": {},
+			}}
+		})
+
+		It("lists the PCI addresses of the devices of each resource on all of them", func() {
+			sriov := &configapi.VfConfig{Driver: "vfio-pci", KubeVirt: &configapi.KubeVirtConfig{ResourceName: "intel.com/sriov"}}
+			devices := drasriovtypes.PreparedDevices{
+				newDevice("vf-1", "0000:01:00.1", &configapi.VfConfig{Driver: "vfio-pci", KubeVirt: &configapi.KubeVirtConfig{}}),
+				newDevice("vf-2", "0000:02:00.1", sriov),
+				newDevice("vf-3", "0000:02:00.2", sriov),
+				newDevice("vf-4", "0000:03:00.1", &configapi.VfConfig{}),
+			}
+
+			manager.kubeVirtDevices(devices)
+			Expect(devices[0].ContainerEdits.Env).To(Equal([]string{"PCI_RESOURCE_NVIDIA_COM_CX7=0000:01:00.1"}))
+			Expect(devices[1].ContainerEdits.Env).To(Equal([]string{"PCI_RESOURCE_INTEL_COM_SRIOV=0000:02:00.1,0000:02:00.2"}))
+			Expect(devices[2].ContainerEdits.Env).To(Equal([]string{"PCI_RESOURCE_INTEL_COM_SRIOV=0000:02:00.1,0000:02:00.2"}))
+			Expect(devices[3].ContainerEdits.Env).To(BeEmpty())
+		})
+
+		It("skips the variable of devices without resource name", func() {
+			devices := drasriovtypes.PreparedDevices{newDevice("vf-2", "0000:02:00.1", &configapi.VfConfig{Driver: "vfio-pci", KubeVirt: &configapi.KubeVirtConfig{}})}
+
+			manager.kubeVirtDevices(devices)
+			Expect(devices[0].ContainerEdits.Env).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package devicestate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// deviceNameNamespace is the namespace of the UUIDs of the stable-uuid naming scheme. It must never
+// change, or devices would be renamed on upgrade.
+var deviceNameNamespace = uuid.NewSHA1(uuid.NameSpaceDNS, []byte(consts.GroupName))
+
+// ValidateDeviceNamingScheme checks that the requested device naming scheme is supported.
+func ValidateDeviceNamingScheme(scheme string) error {
+	switch consts.DeviceNamingScheme(scheme) {
+	case consts.DeviceNamingSchemePCIAddress, consts.DeviceNamingSchemePFNameVFID, consts.DeviceNamingSchemeStableUUID,
+		consts.DeviceNamingSchemeSerialUUID:
+		return nil
+	default:
+		return fmt.Errorf("unsupported device naming scheme %q, expected %q, %q, %q or %q", scheme,
+			consts.DeviceNamingSchemePCIAddress, consts.DeviceNamingSchemePFNameVFID, consts.DeviceNamingSchemeStableUUID,
+			consts.DeviceNamingSchemeSerialUUID)
+	}
+}
+
+// renameDevices returns the discovered devices, named after their PCI address, under the names of
+// the given scheme, see --device-naming-scheme. A device missing what the scheme needs keeps its
+// PCI address name. Two devices getting the same name is an error, as they could not both be
+// published.
+func renameDevices(allocatable drasriovtypes.AllocatableDevices, scheme consts.DeviceNamingScheme) (drasriovtypes.AllocatableDevices, error) {
+	if scheme == "" || scheme == consts.DeviceNamingSchemePCIAddress {
+		return allocatable, nil
+	}
+	logger := klog.LoggerWithName(klog.Background(), "renameDevices")
+
+	// PFs share the MAC address lookup between their VFs
+	pfMACs := map[string]string{}
+	renamed := make(drasriovtypes.AllocatableDevices, len(allocatable))
+	// original name of each renamed device, to report conflicts
+	renamedFrom := make(map[string]string, len(allocatable))
+	for name, device := range allocatable {
+		newName, err := deviceName(device.Attributes, scheme, pfMACs)
+		if err != nil {
+			logger.Error(err, "Keeping the PCI address name of the device", "device", name, "scheme", scheme)
+			newName = name
+		}
+		if other, exists := renamedFrom[newName]; exists {
+			return nil, fmt.Errorf("devices %s and %s are both named %s with the %s naming scheme", other, name, newName, scheme)
+		}
+		renamedFrom[newName] = name
+		device.Name = newName
+		renamed[newName] = device
+	}
+	return renamed, nil
+}
+
+// deviceName returns the name of a device under a naming scheme other than the PCI address one.
+func deviceName(attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, scheme consts.DeviceNamingScheme, pfMACs map[string]string) (string, error) {
+	if scheme == consts.DeviceNamingSchemeSerialUUID {
+		deviceUUID := attributes[consts.AttributeUUID].StringValue
+		if deviceUUID == nil {
+			return "", fmt.Errorf("device has no uuid, its NIC has no VPD serial number")
+		}
+		return *deviceUUID, nil
+	}
+
+	pfName := attributes[consts.AttributePFName].StringValue
+	vfID := attributes[consts.AttributeVFID].IntValue
+	if pfName == nil || *pfName == "" || vfID == nil {
+		return "", fmt.Errorf("device has no PF name or VF index")
+	}
+
+	switch scheme {
+	case consts.DeviceNamingSchemePFNameVFID:
+		return fmt.Sprintf("%s-vf%d", dnsLabel(*pfName), *vfID), nil
+	case consts.DeviceNamingSchemeStableUUID:
+		pfPciAddress := attributes[consts.AttributePfPciAddress].StringValue
+		if pfPciAddress == nil {
+			return "", fmt.Errorf("device has no PF PCI address")
+		}
+		mac, ok := pfMACs[*pfPciAddress]
+		if !ok {
+			var err error
+			mac, err = host.GetHelpers().GetInterfaceMACAddress(*pfPciAddress, *pfName)
+			if err != nil {
+				return "", fmt.Errorf("failed to get MAC address of PF %s: %w", *pfName, err)
+			}
+			mac = strings.ToLower(mac)
+			pfMACs[*pfPciAddress] = mac
+		}
+		return uuid.NewSHA1(deviceNameNamespace, fmt.Appendf(nil, "%s/%d", mac, *vfID)).String(), nil
+	default:
+		return "", fmt.Errorf("unsupported device naming scheme %q", scheme)
+	}
+}
+
+// stableDeviceUUID returns the uuid attribute of a VF, derived from the VPD serial number of its
+// NIC, the PCI function of its PF, which tells apart the ports of a multi-port NIC, and its VF
+// index. Unlike the PCI address, none of them change when the bus numbering does.
+func stableDeviceUUID(serialNumber, pfPciAddress string, vfID int) string {
+	_, pfFunction, _ := strings.Cut(pfPciAddress, ".")
+	return uuid.NewSHA1(deviceNameNamespace, fmt.Appendf(nil, "%s/%s/%d", serialNumber, pfFunction, vfID)).String()
+}
+
+// dnsLabel turns a netdev name into a valid part of a DNS label, as device names must be.
+func dnsLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	return strings.Trim(label, "-")
+}
@@ -0,0 +1,150 @@
+package devicestate
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("renameDevices", Serial, func() {
+	var (
+		mockHost    *hostmock.MockInterface
+		allocatable drasriovtypes.AllocatableDevices
+	)
+
+	newVF := func(pciAddress, pfPciAddress, pfName string, vfID int64) resourceapi.Device {
+		name := DeviceNameFromPciAddress(pciAddress)
+		return resourceapi.Device{
+			Name: name,
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributePciAddress:   {StringValue: ptr.To(pciAddress)},
+				consts.AttributePfPciAddress: {StringValue: ptr.To(pfPciAddress)},
+				consts.AttributePFName:       {StringValue: ptr.To(pfName)},
+				consts.AttributeVFID:         {IntValue: ptr.To(vfID)},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() {
+			host.Helpers = originalHelpers
+		})
+
+		allocatable = drasriovtypes.AllocatableDevices{}
+		for _, vf := range []resourceapi.Device{
+			newVF("0000:01:00.2", "0000:01:00.0", "ens1f0", 0),
+			newVF("0000:01:00.3", "0000:01:00.0", "ens1f0", 1),
+			newVF("0000:01:01.2", "0000:01:00.1", "ens1f1", 0),
+		} {
+			allocatable[vf.Name] = vf
+		}
+	})
+
+	It("keeps the PCI address names by default", func() {
+		renamed, err := renameDevices(allocatable, consts.DeviceNamingSchemePCIAddress)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(HaveKey("0000-01-00-2"))
+		Expect(renamed).To(HaveLen(3))
+	})
+
+	It("names devices after their PF and VF index", func() {
+		renamed, err := renameDevices(allocatable, consts.DeviceNamingSchemePFNameVFID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(HaveLen(3))
+		Expect(renamed).To(HaveKey("ens1f0-vf0"))
+		Expect(renamed).To(HaveKey("ens1f0-vf1"))
+		Expect(renamed).To(HaveKey("ens1f1-vf0"))
+		Expect(renamed["ens1f0-vf1"].Name).To(Equal("ens1f0-vf1"))
+		Expect(*renamed["ens1f0-vf1"].Attributes[consts.AttributePciAddress].StringValue).To(Equal("0000:01:00.3"))
+	})
+
+	It("turns PF names into DNS labels", func() {
+		allocatable = drasriovtypes.AllocatableDevices{
+			"0000-02-00-2": newVF("0000:02:00.2", "0000:02:00.0", "Eth_P0", 4),
+		}
+		renamed, err := renameDevices(allocatable, consts.DeviceNamingSchemePFNameVFID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(HaveKey("eth-p0-vf4"))
+	})
+
+	It("names devices with UUIDs stable across PCI addresses", func() {
+		mockHost.EXPECT().GetInterfaceMACAddress("0000:01:00.0", "ens1f0").Return("AA:BB:CC:00:00:01", nil).Times(1)
+		mockHost.EXPECT().GetInterfaceMACAddress("0000:01:00.1", "ens1f1").Return("aa:bb:cc:00:00:02", nil).Times(1)
+
+		renamed, err := renameDevices(allocatable, consts.DeviceNamingSchemeStableUUID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(HaveLen(3))
+		for name, device := range renamed {
+			Expect(name).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`))
+			Expect(device.Name).To(Equal(name))
+		}
+
+		// the same PF and VF index enumerated at another PCI address keep their name
+		mockHost.EXPECT().GetInterfaceMACAddress("0000:81:00.0", "ens1f0").Return("aa:bb:cc:00:00:01", nil)
+		reenumerated, err := renameDevices(drasriovtypes.AllocatableDevices{
+			"0000-81-00-3": newVF("0000:81:00.3", "0000:81:00.0", "ens1f0", 1),
+		}, consts.DeviceNamingSchemeStableUUID)
+		Expect(err).ToNot(HaveOccurred())
+		for name := range reenumerated {
+			Expect(renamed).To(HaveKey(name))
+			Expect(*renamed[name].Attributes[consts.AttributePciAddress].StringValue).To(Equal("0000:01:00.3"))
+		}
+	})
+
+	It("keeps the PCI address name of devices whose PF MAC address is unknown", func() {
+		mockHost.EXPECT().GetInterfaceMACAddress(gomock.Any(), gomock.Any()).Return("", fmt.Errorf("no such device")).AnyTimes()
+
+		renamed, err := renameDevices(allocatable, consts.DeviceNamingSchemeStableUUID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(HaveKey("0000-01-00-2"))
+	})
+
+	It("names devices after their uuid attribute", func() {
+		vf := allocatable["0000-01-00-2"]
+		vf.Attributes[consts.AttributeUUID] = resourceapi.DeviceAttribute{StringValue: ptr.To(stableDeviceUUID("MT2142X12345", "0000:01:00.0", 0))}
+
+		renamed, err := renameDevices(allocatable, consts.DeviceNamingSchemeSerialUUID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renamed).To(HaveLen(3))
+		Expect(renamed).To(HaveKey(stableDeviceUUID("MT2142X12345", "0000:01:00.0", 0)))
+		// devices of NICs without serial number keep their PCI address name
+		Expect(renamed).To(HaveKey("0000-01-00-3"))
+	})
+
+	It("derives stable UUIDs from the serial number, PF function and VF index", func() {
+		deviceUUID := stableDeviceUUID("MT2142X12345", "0000:01:00.1", 3)
+		Expect(deviceUUID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`))
+		// the same port of the NIC enumerated on another bus keeps its UUID
+		Expect(stableDeviceUUID("MT2142X12345", "0000:81:00.1", 3)).To(Equal(deviceUUID))
+		Expect(stableDeviceUUID("MT2142X12345", "0000:01:00.0", 3)).NotTo(Equal(deviceUUID))
+		Expect(stableDeviceUUID("MT2142X12345", "0000:01:00.1", 2)).NotTo(Equal(deviceUUID))
+		Expect(stableDeviceUUID("MT2142X54321", "0000:01:00.1", 3)).NotTo(Equal(deviceUUID))
+	})
+
+	It("fails when two devices get the same name", func() {
+		allocatable["0000-02-00-2"] = newVF("0000:02:00.2", "0000:02:00.0", "ens1f0", 0)
+
+		_, err := renameDevices(allocatable, consts.DeviceNamingSchemePFNameVFID)
+		Expect(err).To(MatchError(ContainSubstring("both named ens1f0-vf0")))
+	})
+
+	It("validates the naming scheme", func() {
+		Expect(ValidateDeviceNamingScheme("pfname-vfid")).To(Succeed())
+		Expect(ValidateDeviceNamingScheme("serial-uuid")).To(Succeed())
+		Expect(ValidateDeviceNamingScheme("by-serial")).To(MatchError(ContainSubstring("unsupported device naming scheme")))
+	})
+})
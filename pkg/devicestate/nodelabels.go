@@ -0,0 +1,101 @@
+package devicestate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// nodeLabelTimeout bounds the update of the labels of the node at startup.
+const nodeLabelTimeout = 30 * time.Second
+
+// nodeLabels returns the labels summarizing the discovered devices, for the schedulers and
+// autoscalers that cannot read ResourceSlices: whether the node has VFs, their number overall and
+// by PCI vendor ID, and whether some are RDMA or switchdev capable. A node without VFs gets none.
+func nodeLabels(allocatable drasriovtypes.AllocatableDevices) map[string]string {
+	labels := map[string]string{}
+	if len(allocatable) == 0 {
+		return labels
+	}
+	vendorVFs := map[string]int{}
+	for _, device := range allocatable {
+		if vendor := strings.ToLower(attributeString(device.Attributes[consts.AttributeVendorID])); vendor != "" {
+			vendorVFs[vendor]++
+		}
+		if rdma := device.Attributes[consts.AttributeRDMACapable].BoolValue; rdma != nil && *rdma {
+			labels[consts.NodeLabelRDMA] = "true"
+		}
+		if switchdev := device.Attributes[consts.AttributeSwitchdevCapable].BoolValue; switchdev != nil && *switchdev {
+			labels[consts.NodeLabelSwitchdev] = "true"
+		}
+	}
+	labels[consts.NodeLabelSriov] = "true"
+	labels[consts.NodeLabelVFs] = strconv.Itoa(len(allocatable))
+	for vendor, vfs := range vendorVFs {
+		labels[consts.NodeLabelVendorVFsPrefix+vendor] = strconv.Itoa(vfs)
+	}
+	return labels
+}
+
+// isNodeLabel reports whether a label of the node is one of the labels set by nodeLabels.
+func isNodeLabel(key string) bool {
+	switch key {
+	case consts.NodeLabelSriov, consts.NodeLabelVFs, consts.NodeLabelRDMA, consts.NodeLabelSwitchdev:
+		return true
+	}
+	return strings.HasPrefix(key, consts.NodeLabelVendorVFsPrefix)
+}
+
+// labelNode sets the labels summarizing the discovered devices on the node, see --node-labels,
+// and removes the ones a previous run set that no longer apply, e.g. the count of a vendor whose
+// NICs were removed.
+func labelNode(ctx context.Context, config *drasriovtypes.Config, allocatable drasriovtypes.AllocatableDevices) error {
+	logger := klog.FromContext(ctx).WithName("labelNode")
+	if config.K8sClient.Interface == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, nodeLabelTimeout)
+	defer cancel()
+
+	node, err := config.K8sClient.CoreV1().Nodes().Get(ctx, config.Flags.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get node %s: %w", config.Flags.NodeName, err)
+	}
+
+	// a null value removes the label in a merge patch
+	changes := map[string]*string{}
+	labels := nodeLabels(allocatable)
+	for key, value := range labels {
+		if current, ok := node.Labels[key]; !ok || current != value {
+			changes[key] = &value
+		}
+	}
+	for key := range node.Labels {
+		if _, ok := labels[key]; !ok && isNodeLabel(key) {
+			changes[key] = nil
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"labels": changes}})
+	if err != nil {
+		return fmt.Errorf("failed to build node labels patch: %w", err)
+	}
+	if _, err := config.K8sClient.CoreV1().Nodes().Patch(ctx, node.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to label node %s: %w", node.Name, err)
+	}
+	logger.Info("Updated node labels", "node", node.Name, "labels", labels)
+	return nil
+}
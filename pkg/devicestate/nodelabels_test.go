@@ -0,0 +1,107 @@
+package devicestate
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("node labels", func() {
+	newVF := func(vendor string, rdma, switchdev bool) resourceapi.Device {
+		return resourceapi.Device{
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributeVendorID:         {StringValue: ptr.To(vendor)},
+				consts.AttributeRDMACapable:      {BoolValue: ptr.To(rdma)},
+				consts.AttributeSwitchdevCapable: {BoolValue: ptr.To(switchdev)},
+			},
+		}
+	}
+
+	var allocatable drasriovtypes.AllocatableDevices
+
+	BeforeEach(func() {
+		allocatable = drasriovtypes.AllocatableDevices{
+			"vf-1": newVF("15b3", true, false),
+			"vf-2": newVF("15b3", false, false),
+			"vf-3": newVF("8086", false, false),
+		}
+	})
+
+	Context("nodeLabels", func() {
+		It("summarizes the discovered devices", func() {
+			Expect(nodeLabels(allocatable)).To(Equal(map[string]string{
+				consts.DriverName + "/sriov":       "true",
+				consts.DriverName + "/vfs":         "3",
+				consts.DriverName + "/vendor-15b3": "2",
+				consts.DriverName + "/vendor-8086": "1",
+				consts.DriverName + "/rdma":        "true",
+			}))
+		})
+
+		It("labels nothing without devices", func() {
+			Expect(nodeLabels(drasriovtypes.AllocatableDevices{})).To(BeEmpty())
+		})
+	})
+
+	Context("labelNode", func() {
+		var (
+			clientset *k8sfake.Clientset
+			config    *drasriovtypes.Config
+		)
+
+		BeforeEach(func() {
+			clientset = k8sfake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name: "node-1",
+				Labels: map[string]string{
+					"kubernetes.io/hostname":           "node-1",
+					consts.DriverName + "/vfs":         "8",
+					consts.DriverName + "/vendor-14e4": "8",
+					consts.DriverName + "/switchdev":   "true",
+				},
+			}})
+			config = &drasriovtypes.Config{
+				Flags:     &drasriovtypes.Flags{NodeName: "node-1"},
+				K8sClient: flags.ClientSets{Interface: clientset},
+			}
+		})
+
+		It("sets the labels and removes the stale ones", func() {
+			Expect(labelNode(context.Background(), config, allocatable)).To(Succeed())
+
+			node, err := clientset.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(node.Labels).To(Equal(map[string]string{
+				"kubernetes.io/hostname":           "node-1",
+				consts.DriverName + "/sriov":       "true",
+				consts.DriverName + "/vfs":         "3",
+				consts.DriverName + "/vendor-15b3": "2",
+				consts.DriverName + "/vendor-8086": "1",
+				consts.DriverName + "/rdma":        "true",
+			}))
+		})
+
+		It("does not patch a node already labeled", func() {
+			Expect(labelNode(context.Background(), config, allocatable)).To(Succeed())
+			clientset.ClearActions()
+
+			Expect(labelNode(context.Background(), config, allocatable)).To(Succeed())
+			Expect(clientset.Actions()).To(HaveLen(1))
+			Expect(clientset.Actions()[0].GetVerb()).To(Equal("get"))
+		})
+
+		It("fails on a missing node", func() {
+			config.Flags.NodeName = "node-2"
+			Expect(labelNode(context.Background(), config, allocatable)).To(MatchError(ContainSubstring("failed to get node node-2")))
+		})
+	})
+})
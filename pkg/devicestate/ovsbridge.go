@@ -0,0 +1,76 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// validateOVSBridgeConfig checks that the driver can plug the representors of the VFs of a
+// VfConfig into a bridge of Open vSwitch.
+func (s *Manager) validateOVSBridgeConfig(config *configapi.VfConfig) error {
+	if config.OVSBridge == nil {
+		return nil
+	}
+	if s.dpuAgent != nil {
+		return fmt.Errorf("ovsBridge is not supported in the DPU split-driver mode, the representors are programmed by the DPU agent")
+	}
+	if s.ovsBridges == nil {
+		return fmt.Errorf("ovsBridge requires the OVSDB endpoint of the node, see --ovsdb-endpoint")
+	}
+	return nil
+}
+
+// configureOVSBridge plugs the representors of the prepared devices whose VfConfig has an
+// ovsBridge into the bridge. The ports are tagged with the claim and the device in their
+// external_ids. Devices whose representor is plugged record the port, so unprepareDevices
+// deletes them, including after a failure of a later device.
+func (s *Manager) configureOVSBridge(ctx context.Context, claimName, claimNamespace string, preparedDevices drasriovtypes.PreparedDevices) error {
+	logger := klog.FromContext(ctx).WithName("configureOVSBridge")
+
+	for _, device := range preparedDevices {
+		if device.AdminAccess || device.Config == nil || device.Config.OVSBridge == nil {
+			continue
+		}
+		representor := device.TCOffloadRepresentor
+		if representor == "" {
+			var err error
+			if representor, err = host.GetHelpers().GetVFRepresentor(device.PciAddress); err != nil {
+				return fmt.Errorf("failed to get representor of device %s: %w", device.Device.DeviceName, err)
+			}
+		}
+		bridge := device.Config.OVSBridge.Bridge
+		externalIDs := map[string]string{
+			consts.DriverName + "/claim":   claimNamespace + "/" + claimName,
+			consts.DriverName + "/device":  device.Device.DeviceName,
+			consts.DriverName + "/pod-uid": device.PodUID,
+		}
+		if err := s.ovsBridges.AddPort(ctx, bridge, representor, externalIDs); err != nil {
+			return fmt.Errorf("failed to plug representor of device %s into OVS bridge %s: %w", device.Device.DeviceName, bridge, err)
+		}
+		device.OVSBridge = bridge
+		device.OVSPort = representor
+		logger.V(2).Info("Plugged representor into OVS bridge", "device", device.Device.DeviceName, "bridge", bridge, "port", representor)
+	}
+	return nil
+}
+
+// releaseOVSBridge deletes the port configureOVSBridge added for the representor of a device.
+func (s *Manager) releaseOVSBridge(ctx context.Context, device *drasriovtypes.PreparedDevice) error {
+	if device.OVSPort == "" {
+		return nil
+	}
+	if s.ovsBridges == nil {
+		return fmt.Errorf("port %s of device %s was added to OVS bridge %s but --ovsdb-endpoint is not set", device.OVSPort, device.Device.DeviceName, device.OVSBridge)
+	}
+	if err := s.ovsBridges.DeletePort(ctx, device.OVSBridge, device.OVSPort); err != nil {
+		return fmt.Errorf("failed to delete port %s of device %s from OVS bridge %s: %w", device.OVSPort, device.Device.DeviceName, device.OVSBridge, err)
+	}
+	return nil
+}
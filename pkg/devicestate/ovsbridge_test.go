@@ -0,0 +1,143 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// fakeOVSBridges records the ports of the bridges and fails the ports of failPorts.
+type fakeOVSBridges struct {
+	ports       map[string]string
+	externalIDs map[string]map[string]string
+	failPorts   map[string]bool
+}
+
+func (b *fakeOVSBridges) AddPort(_ context.Context, bridge, port string, externalIDs map[string]string) error {
+	if b.failPorts[port] {
+		return errors.New("bridge " + bridge + " not found")
+	}
+	b.ports[port] = bridge
+	b.externalIDs[port] = externalIDs
+	return nil
+}
+
+func (b *fakeOVSBridges) DeletePort(_ context.Context, _, port string) error {
+	if b.failPorts[port] {
+		return errors.New("connection refused")
+	}
+	delete(b.ports, port)
+	return nil
+}
+
+var _ = Describe("OVS bridge", func() {
+	var (
+		ctrl     *gomock.Controller
+		mockHost *hostmock.MockInterface
+		bridges  *fakeOVSBridges
+		manager  *Manager
+	)
+
+	newDevice := func(name, pciAddress string, ovsBridge *configapi.OVSBridgeConfig) *drasriovtypes.PreparedDevice {
+		return &drasriovtypes.PreparedDevice{
+			Device:     drapbv1.Device{DeviceName: name},
+			Config:     &configapi.VfConfig{OVSBridge: ovsBridge},
+			PciAddress: pciAddress,
+			PodUID:     "pod-uid",
+		}
+	}
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() { host.Helpers = originalHelpers })
+		bridges = &fakeOVSBridges{ports: map[string]string{}, externalIDs: map[string]map[string]string{}, failPorts: map[string]bool{}}
+		manager = &Manager{ovsBridges: bridges}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("plugs the representors into the bridge and deletes their ports on unprepare", func() {
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-1", "0000:03:00.2", &configapi.OVSBridgeConfig{Bridge: "br-ex"}),
+			newDevice("vf-2", "0000:03:00.3", nil),
+		}
+		mockHost.EXPECT().GetVFRepresentor("0000:03:00.2").Return("pf0vf1", nil)
+
+		Expect(manager.configureOVSBridge(context.Background(), "claim", "default", devices)).To(Succeed())
+		Expect(bridges.ports).To(Equal(map[string]string{"pf0vf1": "br-ex"}))
+		Expect(bridges.externalIDs["pf0vf1"]).To(Equal(map[string]string{
+			"sriovnetwork.k8snetworkplumbingwg.io/claim":   "default/claim",
+			"sriovnetwork.k8snetworkplumbingwg.io/device":  "vf-1",
+			"sriovnetwork.k8snetworkplumbingwg.io/pod-uid": "pod-uid",
+		}))
+		Expect(devices[0].OVSBridge).To(Equal("br-ex"))
+		Expect(devices[0].OVSPort).To(Equal("pf0vf1"))
+		Expect(devices[1].OVSPort).To(BeEmpty())
+
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+		Expect(bridges.ports).To(BeEmpty())
+	})
+
+	It("reuses the representor found for the TC offload", func() {
+		device := newDevice("vf-1", "0000:03:00.2", &configapi.OVSBridgeConfig{Bridge: "br-ex"})
+		device.TCOffloadRepresentor = "pf0vf1"
+
+		Expect(manager.configureOVSBridge(context.Background(), "claim", "default", drasriovtypes.PreparedDevices{device})).To(Succeed())
+		Expect(bridges.ports).To(HaveKeyWithValue("pf0vf1", "br-ex"))
+	})
+
+	It("keeps the ports already added when a device fails", func() {
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-1", "0000:03:00.2", &configapi.OVSBridgeConfig{Bridge: "br-ex"}),
+			newDevice("vf-2", "0000:03:00.3", &configapi.OVSBridgeConfig{Bridge: "br-ex"}),
+		}
+		mockHost.EXPECT().GetVFRepresentor("0000:03:00.2").Return("pf0vf1", nil)
+		mockHost.EXPECT().GetVFRepresentor("0000:03:00.3").Return("pf0vf2", nil)
+		bridges.failPorts["pf0vf2"] = true
+
+		Expect(manager.configureOVSBridge(context.Background(), "claim", "default", devices)).To(MatchError(ContainSubstring("failed to plug representor of device vf-2 into OVS bridge br-ex")))
+		Expect(devices[1].OVSPort).To(BeEmpty())
+
+		// the rollback only deletes what was added
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+		Expect(bridges.ports).To(BeEmpty())
+	})
+
+	It("fails the unprepare when the port cannot be deleted", func() {
+		device := newDevice("vf-1", "0000:03:00.2", &configapi.OVSBridgeConfig{Bridge: "br-ex"})
+		device.OVSBridge, device.OVSPort = "br-ex", "pf0vf1"
+		bridges.failPorts["pf0vf1"] = true
+
+		Expect(manager.unprepareDevices(drasriovtypes.PreparedDevices{device})).To(MatchError(ContainSubstring("failed to delete port pf0vf1 of device vf-1 from OVS bridge br-ex")))
+
+		manager.ovsBridges = nil
+		Expect(manager.unprepareDevices(drasriovtypes.PreparedDevices{device})).To(MatchError(ContainSubstring("--ovsdb-endpoint is not set")))
+	})
+
+	It("requires the OVSDB endpoint and rejects the DPU split-driver mode", func() {
+		config := &configapi.VfConfig{OVSBridge: &configapi.OVSBridgeConfig{Bridge: "br-ex"}}
+		Expect(manager.validateOVSBridgeConfig(config)).To(Succeed())
+
+		manager.dpuAgent = &fakeDPUAgent{}
+		Expect(manager.validateOVSBridgeConfig(config)).To(MatchError(ContainSubstring("not supported in the DPU split-driver mode")))
+
+		manager.dpuAgent = nil
+		manager.ovsBridges = nil
+		Expect(manager.validateOVSBridgeConfig(config)).To(MatchError(ContainSubstring("requires the OVSDB endpoint")))
+	})
+})
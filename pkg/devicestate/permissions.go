@@ -0,0 +1,71 @@
+package devicestate
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"k8s.io/utils/ptr"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// VFIODevicePermissionsFromFlags returns the default permissions of the /dev/vfio devices of the
+// containers, set by --vfio-device-mode, --vfio-device-uid and --vfio-device-gid.
+func VFIODevicePermissionsFromFlags(flags *drasriovtypes.Flags) (configapi.DevicePermissions, error) {
+	var permissions configapi.DevicePermissions
+	if flags.VFIODeviceMode != "" {
+		mode, err := strconv.ParseUint(flags.VFIODeviceMode, 8, 32)
+		if err != nil || mode > 0o777 {
+			return permissions, fmt.Errorf("invalid vfio-device-mode %q, expected octal permission bits such as 0660", flags.VFIODeviceMode)
+		}
+		permissions.FileMode = ptr.To(int32(mode))
+	}
+	if flags.VFIODeviceUID >= 0 {
+		if flags.VFIODeviceUID > math.MaxUint32 {
+			return permissions, fmt.Errorf("invalid vfio-device-uid %d", flags.VFIODeviceUID)
+		}
+		permissions.UID = ptr.To(uint32(flags.VFIODeviceUID))
+	}
+	if flags.VFIODeviceGID >= 0 {
+		if flags.VFIODeviceGID > math.MaxUint32 {
+			return permissions, fmt.Errorf("invalid vfio-device-gid %d", flags.VFIODeviceGID)
+		}
+		permissions.GID = ptr.To(uint32(flags.VFIODeviceGID))
+	}
+	return permissions, nil
+}
+
+// vfioDevicePermissions returns the permissions of the /dev/vfio devices of a VfConfig, the
+// fields it sets override the driver defaults.
+func (s *Manager) vfioDevicePermissions(config *configapi.VfConfig) (configapi.DevicePermissions, error) {
+	permissions := s.defaultVFIOPermissions
+	if config.VFIODevicePermissions == nil {
+		return permissions, nil
+	}
+	if err := config.VFIODevicePermissions.Validate(); err != nil {
+		return permissions, fmt.Errorf("invalid vfio device permissions: %w", err)
+	}
+	if config.VFIODevicePermissions.FileMode != nil {
+		permissions.FileMode = config.VFIODevicePermissions.FileMode
+	}
+	if config.VFIODevicePermissions.UID != nil {
+		permissions.UID = config.VFIODevicePermissions.UID
+	}
+	if config.VFIODevicePermissions.GID != nil {
+		permissions.GID = config.VFIODevicePermissions.GID
+	}
+	return permissions, nil
+}
+
+// applyDevicePermissions sets the mode and owner of a device node of the CDI spec.
+func applyDevicePermissions(deviceNode *cdispec.DeviceNode, permissions configapi.DevicePermissions) {
+	if permissions.FileMode != nil {
+		deviceNode.FileMode = ptr.To(os.FileMode(*permissions.FileMode))
+	}
+	deviceNode.UID = permissions.UID
+	deviceNode.GID = permissions.GID
+}
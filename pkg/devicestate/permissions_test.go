@@ -0,0 +1,70 @@
+package devicestate
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("VFIO device permissions", func() {
+	Context("VFIODevicePermissionsFromFlags", func() {
+		It("leaves the runtime defaults when the flags are unset", func() {
+			permissions, err := VFIODevicePermissionsFromFlags(&drasriovtypes.Flags{VFIODeviceUID: -1, VFIODeviceGID: -1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(permissions).To(Equal(configapi.DevicePermissions{}))
+		})
+
+		It("parses the octal mode and the owner", func() {
+			permissions, err := VFIODevicePermissionsFromFlags(&drasriovtypes.Flags{VFIODeviceMode: "0660", VFIODeviceUID: -1, VFIODeviceGID: 1000})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(permissions).To(Equal(configapi.DevicePermissions{FileMode: ptr.To(int32(0o660)), GID: ptr.To(uint32(1000))}))
+		})
+
+		It("rejects modes that are not permission bits", func() {
+			_, err := VFIODevicePermissionsFromFlags(&drasriovtypes.Flags{VFIODeviceMode: "0888", VFIODeviceUID: -1, VFIODeviceGID: -1})
+			Expect(err).To(MatchError(ContainSubstring("invalid vfio-device-mode")))
+			_, err = VFIODevicePermissionsFromFlags(&drasriovtypes.Flags{VFIODeviceMode: "4777", VFIODeviceUID: -1, VFIODeviceGID: -1})
+			Expect(err).To(MatchError(ContainSubstring("invalid vfio-device-mode")))
+		})
+	})
+
+	Context("vfioDevicePermissions", func() {
+		It("overrides the driver defaults with the fields set by the VfConfig", func() {
+			m := &Manager{defaultVFIOPermissions: configapi.DevicePermissions{FileMode: ptr.To(int32(0o660)), GID: ptr.To(uint32(1000))}}
+
+			permissions, err := m.vfioDevicePermissions(&configapi.VfConfig{
+				VFIODevicePermissions: &configapi.DevicePermissions{UID: ptr.To(uint32(1001)), GID: ptr.To(uint32(1001))},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(permissions).To(Equal(configapi.DevicePermissions{
+				FileMode: ptr.To(int32(0o660)),
+				UID:      ptr.To(uint32(1001)),
+				GID:      ptr.To(uint32(1001)),
+			}))
+		})
+
+		It("rejects invalid modes of the VfConfig", func() {
+			m := &Manager{}
+			_, err := m.vfioDevicePermissions(&configapi.VfConfig{
+				VFIODevicePermissions: &configapi.DevicePermissions{FileMode: ptr.To(int32(0o1777))},
+			})
+			Expect(err).To(MatchError(ContainSubstring("invalid vfio device permissions")))
+		})
+	})
+
+	Context("applyDevicePermissions", func() {
+		It("sets the mode and owner of the device node", func() {
+			deviceNode := &cdispec.DeviceNode{Path: "/dev/vfio/1"}
+			applyDevicePermissions(deviceNode, configapi.DevicePermissions{FileMode: ptr.To(int32(0o666)), UID: ptr.To(uint32(1000))})
+			Expect(*deviceNode.FileMode).To(Equal(os.FileMode(0o666)))
+			Expect(*deviceNode.UID).To(Equal(uint32(1000)))
+			Expect(deviceNode.GID).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package devicestate
+
+import (
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// excludePrimaryPFs removes the VFs of the PFs carrying the primary networking of the node, i.e.
+// its default route or node IPs, so the management NIC is not handed to workloads. VFs carrying
+// it themselves, e.g. in a VM, are removed as well.
+func excludePrimaryPFs(allocatable drasriovtypes.AllocatableDevices, nodeIPs []string) error {
+	logger := klog.LoggerWithName(klog.Background(), "excludePrimaryPFs")
+
+	interfaces, err := host.GetHelpers().GetPrimaryInterfaces(nodeIPs)
+	if err != nil {
+		return fmt.Errorf("failed to get the primary interfaces of the node: %w", err)
+	}
+	primary := make(map[string]bool, len(interfaces))
+	for _, name := range interfaces {
+		primary[name] = true
+	}
+
+	for name, device := range allocatable {
+		if netdev, ok := primaryNetdev(device.Attributes, primary); ok {
+			logger.Info("Excluding device of the primary network of the node, see --exclude-primary-pfs", "device", name, "interface", netdev)
+			delete(allocatable, name)
+		}
+	}
+	return nil
+}
+
+// primaryNetdev returns the netdev of a device or of its PF which is a primary interface.
+func primaryNetdev(attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, primary map[string]bool) (string, bool) {
+	for _, attribute := range []resourceapi.QualifiedName{consts.AttributePFName, consts.AttributeStandardInterfaceName} {
+		if value, ok := attributes[attribute]; ok && value.StringValue != nil && primary[*value.StringValue] {
+			return *value.StringValue, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,79 @@
+package devicestate
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("excludePrimaryPFs", Serial, func() {
+	var (
+		mockHost    *hostmock.MockInterface
+		allocatable drasriovtypes.AllocatableDevices
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() {
+			host.Helpers = originalHelpers
+		})
+
+		allocatable = drasriovtypes.AllocatableDevices{
+			"eth0-vf0": {
+				Name: "eth0-vf0",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributePFName: {StringValue: ptr.To("eth0")},
+				},
+			},
+			"eth1-vf0": {
+				Name: "eth1-vf0",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributePFName:                {StringValue: ptr.To("eth1")},
+					consts.AttributeStandardInterfaceName: {StringValue: ptr.To("eth1v0")},
+				},
+			},
+			"eth1-vf1": {
+				Name: "eth1-vf1",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributePFName: {StringValue: ptr.To("eth1")},
+				},
+			},
+		}
+	})
+
+	It("excludes the VFs of the primary PFs", func() {
+		mockHost.EXPECT().GetPrimaryInterfaces([]string{"10.0.0.1"}).Return([]string{"bond0", "eth0"}, nil)
+
+		Expect(excludePrimaryPFs(allocatable, []string{"10.0.0.1"})).To(Succeed())
+		Expect(allocatable).To(HaveLen(2))
+		Expect(allocatable).NotTo(HaveKey("eth0-vf0"))
+	})
+
+	It("excludes the VFs carrying the primary network themselves", func() {
+		mockHost.EXPECT().GetPrimaryInterfaces(nil).Return([]string{"eth1v0"}, nil)
+
+		Expect(excludePrimaryPFs(allocatable, nil)).To(Succeed())
+		Expect(allocatable).To(HaveLen(2))
+		Expect(allocatable).NotTo(HaveKey("eth1-vf0"))
+	})
+
+	It("fails when the primary interfaces cannot be listed", func() {
+		mockHost.EXPECT().GetPrimaryInterfaces(nil).Return(nil, fmt.Errorf("netlink error"))
+
+		Expect(excludePrimaryPFs(allocatable, nil)).To(MatchError(ContainSubstring("netlink error")))
+		Expect(allocatable).To(HaveLen(3))
+	})
+})
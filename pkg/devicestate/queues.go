@@ -0,0 +1,120 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+)
+
+// setNumQueues sets the combined channels of the network interface of a VF bound to a kernel
+// driver to the NumQueues of a VfConfig and returns the count it had, to be restored with
+// restoreNumQueues. It returns 0 when the VfConfig sets no count.
+func setNumQueues(ctx context.Context, pciAddress string, config *configapi.VfConfig) (int, error) {
+	if config.NumQueues == nil {
+		return 0, nil
+	}
+	original, err := host.GetHelpers().GetCombinedChannels(pciAddress)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queues of device %s: %w", pciAddress, err)
+	}
+	if err := host.GetHelpers().SetCombinedChannels(pciAddress, int(*config.NumQueues)); err != nil {
+		return 0, fmt.Errorf("failed to set queues of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("setNumQueues").V(2).Info("Set queues of device", "device", pciAddress,
+		"numQueues", *config.NumQueues, "originalNumQueues", original)
+	return original, nil
+}
+
+// restoreNumQueues sets back the combined channels a VF had before setNumQueues.
+func restoreNumQueues(ctx context.Context, pciAddress string, numQueues int) error {
+	if numQueues == 0 {
+		return nil
+	}
+	if err := host.GetHelpers().SetCombinedChannels(pciAddress, numQueues); err != nil {
+		return fmt.Errorf("failed to restore queues of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("restoreNumQueues").V(2).Info("Restored queues of device", "device", pciAddress, "numQueues", numQueues)
+	return nil
+}
+
+// setRingSizes sets the sizes of the rings of the network interface of a VF bound to a kernel
+// driver to the RingSize of a VfConfig and returns the sizes it had, to be restored with
+// restoreRingSizes. It returns nil when the VfConfig sets no size.
+func setRingSizes(ctx context.Context, pciAddress string, config *configapi.VfConfig) (*host.RingSizes, error) {
+	if config.RingSize == nil || (config.RingSize.RX == 0 && config.RingSize.TX == 0) {
+		return nil, nil
+	}
+	original, err := host.GetHelpers().GetRingSizes(pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ring sizes of device %s: %w", pciAddress, err)
+	}
+	sizes := &host.RingSizes{RX: int(config.RingSize.RX), TX: int(config.RingSize.TX)}
+	if err := host.GetHelpers().SetRingSizes(pciAddress, sizes); err != nil {
+		return nil, fmt.Errorf("failed to set ring sizes of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("setRingSizes").V(2).Info("Set ring sizes of device", "device", pciAddress,
+		"rx", sizes.RX, "tx", sizes.TX, "originalRX", original.RX, "originalTX", original.TX)
+	return original, nil
+}
+
+// restoreRingSizes sets back the ring sizes a VF had before setRingSizes.
+func restoreRingSizes(ctx context.Context, pciAddress string, sizes *host.RingSizes) error {
+	if sizes == nil {
+		return nil
+	}
+	if err := host.GetHelpers().SetRingSizes(pciAddress, sizes); err != nil {
+		return fmt.Errorf("failed to restore ring sizes of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("restoreRingSizes").V(2).Info("Restored ring sizes of device", "device", pciAddress,
+		"rx", sizes.RX, "tx", sizes.TX)
+	return nil
+}
+
+// setOffloads turns the features of the Offloads of a VfConfig on or off on the network interface
+// of a VF bound to a kernel driver and returns the states they had, to be restored with
+// restoreOffloads. It returns nil when the VfConfig sets no offload.
+func setOffloads(ctx context.Context, pciAddress string, config *configapi.VfConfig) (map[string]bool, error) {
+	if config.Offloads == nil {
+		return nil, nil
+	}
+	features := config.Offloads.Features()
+	if len(features) == 0 {
+		return nil, nil
+	}
+	current, err := host.GetHelpers().GetFeatures(pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get features of device %s: %w", pciAddress, err)
+	}
+	original := make(map[string]bool, len(features))
+	for feature := range features {
+		if active, ok := current[feature]; ok {
+			original[feature] = active
+		}
+	}
+	if err := host.GetHelpers().SetFeatures(pciAddress, features); err != nil {
+		// some features may already be set
+		if restoreErr := restoreOffloads(ctx, pciAddress, original); restoreErr != nil {
+			return nil, fmt.Errorf("failed to set features of device %s: %w; additionally %v", pciAddress, err, restoreErr)
+		}
+		return nil, fmt.Errorf("failed to set features of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("setOffloads").V(2).Info("Set features of device", "device", pciAddress,
+		"features", features, "originalFeatures", original)
+	return original, nil
+}
+
+// restoreOffloads sets back the features a VF had before setOffloads.
+func restoreOffloads(ctx context.Context, pciAddress string, features map[string]bool) error {
+	if len(features) == 0 {
+		return nil
+	}
+	if err := host.GetHelpers().SetFeatures(pciAddress, features); err != nil {
+		return fmt.Errorf("failed to restore features of device %s: %w", pciAddress, err)
+	}
+	klog.FromContext(ctx).WithName("restoreOffloads").V(2).Info("Restored features of device", "device", pciAddress, "features", features)
+	return nil
+}
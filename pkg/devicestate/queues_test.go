@@ -0,0 +1,132 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Queues", func() {
+	var (
+		ctrl     *gomock.Controller
+		mockHost *hostmock.MockInterface
+		config   *configapi.VfConfig
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() { host.Helpers = originalHelpers })
+		config = &configapi.VfConfig{NumQueues: ptr.To(int32(2))}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("sets the queues of the VF and restores them on unprepare", func() {
+		mockHost.EXPECT().GetCombinedChannels("0000:03:00.2").Return(8, nil)
+		mockHost.EXPECT().SetCombinedChannels("0000:03:00.2", 2).Return(nil)
+
+		original, err := setNumQueues(context.Background(), "0000:03:00.2", config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(original).To(Equal(8))
+
+		device := &drasriovtypes.PreparedDevice{
+			Device:            drapbv1.Device{DeviceName: "vf-1"},
+			Config:            config,
+			PciAddress:        "0000:03:00.2",
+			OriginalNumQueues: original,
+		}
+		mockHost.EXPECT().SetCombinedChannels("0000:03:00.2", 8).Return(nil)
+		Expect((&Manager{}).unprepareDevices(drasriovtypes.PreparedDevices{device})).To(Succeed())
+	})
+
+	It("fails when the queues cannot be set", func() {
+		mockHost.EXPECT().GetCombinedChannels("0000:03:00.2").Return(8, nil)
+		mockHost.EXPECT().SetCombinedChannels("0000:03:00.2", 2).Return(errors.New("interface eth0v1 supports 1 to 1 combined channels, 2 requested"))
+
+		_, err := setNumQueues(context.Background(), "0000:03:00.2", config)
+		Expect(err).To(MatchError(ContainSubstring("failed to set queues of device 0000:03:00.2")))
+	})
+
+	It("sets the ring sizes of the VF and restores them on unprepare", func() {
+		config = &configapi.VfConfig{RingSize: &configapi.RingSizeConfig{RX: 4096}}
+		mockHost.EXPECT().GetRingSizes("0000:03:00.2").Return(&host.RingSizes{RX: 1024, TX: 1024}, nil)
+		mockHost.EXPECT().SetRingSizes("0000:03:00.2", &host.RingSizes{RX: 4096}).Return(nil)
+
+		original, err := setRingSizes(context.Background(), "0000:03:00.2", config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(original).To(Equal(&host.RingSizes{RX: 1024, TX: 1024}))
+
+		device := &drasriovtypes.PreparedDevice{
+			Device:            drapbv1.Device{DeviceName: "vf-1"},
+			Config:            config,
+			PciAddress:        "0000:03:00.2",
+			OriginalRingSizes: original,
+		}
+		mockHost.EXPECT().SetRingSizes("0000:03:00.2", &host.RingSizes{RX: 1024, TX: 1024}).Return(nil)
+		Expect((&Manager{}).unprepareDevices(drasriovtypes.PreparedDevices{device})).To(Succeed())
+	})
+
+	It("sets the offloads of the VF and restores them on unprepare", func() {
+		config = &configapi.VfConfig{Offloads: &configapi.OffloadConfig{LRO: ptr.To(false), GRO: ptr.To(true)}}
+		mockHost.EXPECT().GetFeatures("0000:03:00.2").Return(map[string]bool{"rx-gro": true, "rx-lro": true, "tx-tcp-segmentation": true}, nil)
+		mockHost.EXPECT().SetFeatures("0000:03:00.2", map[string]bool{"rx-gro": true, "rx-lro": false}).Return(nil)
+
+		original, err := setOffloads(context.Background(), "0000:03:00.2", config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(original).To(Equal(map[string]bool{"rx-gro": true, "rx-lro": true}))
+
+		device := &drasriovtypes.PreparedDevice{
+			Device:           drapbv1.Device{DeviceName: "vf-1"},
+			Config:           config,
+			PciAddress:       "0000:03:00.2",
+			OriginalFeatures: original,
+		}
+		mockHost.EXPECT().SetFeatures("0000:03:00.2", map[string]bool{"rx-gro": true, "rx-lro": true}).Return(nil)
+		Expect((&Manager{}).unprepareDevices(drasriovtypes.PreparedDevices{device})).To(Succeed())
+	})
+
+	It("restores the offloads already set when the others cannot be set", func() {
+		config = &configapi.VfConfig{Offloads: &configapi.OffloadConfig{TSO: ptr.To(false)}}
+		current := map[string]bool{"tx-tcp-segmentation": true, "tx-tcp6-segmentation": true}
+		mockHost.EXPECT().GetFeatures("0000:03:00.2").Return(current, nil)
+		mockHost.EXPECT().SetFeatures("0000:03:00.2", map[string]bool{"tx-tcp-segmentation": false, "tx-tcp6-segmentation": false}).
+			Return(errors.New("failed to set feature tx-tcp6-segmentation of interface eth0v1: operation not supported"))
+		mockHost.EXPECT().SetFeatures("0000:03:00.2", current).Return(nil)
+
+		_, err := setOffloads(context.Background(), "0000:03:00.2", config)
+		Expect(err).To(MatchError(ContainSubstring("failed to set features of device 0000:03:00.2")))
+	})
+
+	It("does nothing without numQueues, ring sizes or offloads", func() {
+		original, err := setNumQueues(context.Background(), "0000:03:00.2", &configapi.VfConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(original).To(BeZero())
+		Expect(restoreNumQueues(context.Background(), "0000:03:00.2", 0)).To(Succeed())
+
+		sizes, err := setRingSizes(context.Background(), "0000:03:00.2", &configapi.VfConfig{RingSize: &configapi.RingSizeConfig{}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sizes).To(BeNil())
+		Expect(restoreRingSizes(context.Background(), "0000:03:00.2", nil)).To(Succeed())
+
+		features, err := setOffloads(context.Background(), "0000:03:00.2", &configapi.VfConfig{Offloads: &configapi.OffloadConfig{}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(features).To(BeNil())
+		Expect(restoreOffloads(context.Background(), "0000:03:00.2", nil)).To(Succeed())
+	})
+})
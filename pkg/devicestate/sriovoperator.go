@@ -0,0 +1,224 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	// sriovOperatorLookupTimeout bounds the lookup of the SriovNetworkNodeState at startup.
+	sriovOperatorLookupTimeout = 30 * time.Second
+	// sriovOperatorDeviceTypeVFIO is the deviceType of the VF groups the operator binds to vfio-pci.
+	sriovOperatorDeviceTypeVFIO = "vfio-pci"
+	// eventReasonSriovOperatorConflict is the reason of the node events reporting conflicts with
+	// the operator.
+	eventReasonSriovOperatorConflict = "SriovOperatorConflict"
+)
+
+// sriovNetworkNodeStateListGVK is the list of the node states of sriov-network-operator, read as
+// unstructured objects so the driver does not depend on the operator API.
+var sriovNetworkNodeStateListGVK = schema.GroupVersionKind{Group: "sriovnetwork.openshift.io", Version: "v1", Kind: "SriovNetworkNodeStateList"}
+
+// sriovOperatorInterface is a PF of the spec of a SriovNetworkNodeState.
+type sriovOperatorInterface struct {
+	NumVFs int64
+	// DeviceTypes are the deviceType of each VF index of the VF groups of the PF
+	DeviceTypes map[int64]string
+}
+
+// ValidateSriovOperatorCoexistence checks that the requested coexistence mode is supported.
+func ValidateSriovOperatorCoexistence(mode string) error {
+	switch consts.SriovOperatorCoexistence(mode) {
+	case "", consts.SriovOperatorCoexistenceIgnore, consts.SriovOperatorCoexistenceValidate, consts.SriovOperatorCoexistenceDefer:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sriov-network-operator coexistence mode %q, expected %q, %q or %q", mode,
+			consts.SriovOperatorCoexistenceIgnore, consts.SriovOperatorCoexistenceValidate, consts.SriovOperatorCoexistenceDefer)
+	}
+}
+
+// getSriovOperatorInterfaces returns the PFs of the SriovNetworkNodeState of the node by PCI
+// address, nil when sriov-network-operator is not installed or does not manage the node.
+func getSriovOperatorInterfaces(ctx context.Context, config *drasriovtypes.Config) (map[string]sriovOperatorInterface, error) {
+	if config.K8sClient.Client == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, sriovOperatorLookupTimeout)
+	defer cancel()
+
+	nodeStates := &unstructured.UnstructuredList{}
+	nodeStates.SetGroupVersionKind(sriovNetworkNodeStateListGVK)
+	if err := config.K8sClient.List(ctx, nodeStates); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list SriovNetworkNodeStates: %w", err)
+	}
+	for _, nodeState := range nodeStates.Items {
+		if nodeState.GetName() == config.Flags.NodeName {
+			return parseSriovOperatorInterfaces(nodeState.Object)
+		}
+	}
+	return nil, nil
+}
+
+// parseSriovOperatorInterfaces returns the PFs of the spec of a SriovNetworkNodeState.
+func parseSriovOperatorInterfaces(nodeState map[string]interface{}) (map[string]sriovOperatorInterface, error) {
+	rawInterfaces, _, err := unstructured.NestedSlice(nodeState, "spec", "interfaces")
+	if err != nil {
+		return nil, fmt.Errorf("invalid interfaces in SriovNetworkNodeState: %w", err)
+	}
+	interfaces := make(map[string]sriovOperatorInterface, len(rawInterfaces))
+	for _, rawInterface := range rawInterfaces {
+		iface, ok := rawInterface.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid interface in SriovNetworkNodeState")
+		}
+		pciAddress, _, _ := unstructured.NestedString(iface, "pciAddress")
+		if pciAddress == "" {
+			continue
+		}
+		numVFs, _, _ := unstructured.NestedInt64(iface, "numVfs")
+		vfGroups, _, _ := unstructured.NestedSlice(iface, "vfGroups")
+		deviceTypes := map[int64]string{}
+		for _, rawGroup := range vfGroups {
+			group, ok := rawGroup.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			deviceType, _, _ := unstructured.NestedString(group, "deviceType")
+			vfRange, _, _ := unstructured.NestedString(group, "vfRange")
+			first, last, err := parseVFRange(vfRange)
+			if err != nil {
+				return nil, fmt.Errorf("invalid VF group of PF %s in SriovNetworkNodeState: %w", pciAddress, err)
+			}
+			for vfID := first; vfID <= last; vfID++ {
+				deviceTypes[vfID] = deviceType
+			}
+		}
+		interfaces[strings.ToLower(pciAddress)] = sriovOperatorInterface{NumVFs: numVFs, DeviceTypes: deviceTypes}
+	}
+	return interfaces, nil
+}
+
+// parseVFRange parses the VF range of a VF group of the operator, a VF index or a range of them,
+// e.g. 0-7.
+func parseVFRange(vfRange string) (int64, int64, error) {
+	firstRaw, lastRaw, isRange := strings.Cut(vfRange, "-")
+	first, err := strconv.ParseInt(strings.TrimSpace(firstRaw), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid VF range %q", vfRange)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	last, err := strconv.ParseInt(strings.TrimSpace(lastRaw), 10, 64)
+	if err != nil || last < first {
+		return 0, 0, fmt.Errorf("invalid VF range %q", vfRange)
+	}
+	return first, last, nil
+}
+
+// applySriovOperatorCoexistence handles the devices whose PF is also managed by
+// sriov-network-operator, see --sriov-operator-coexistence. In defer mode they are removed. In
+// validate mode they are kept and the differences between the node and the operator spec are
+// returned as conflicts, along with the deviceType of the operator for each of the kept devices.
+func applySriovOperatorCoexistence(allocatable drasriovtypes.AllocatableDevices, mode consts.SriovOperatorCoexistence, interfaces map[string]sriovOperatorInterface) (map[string]string, []string) {
+	logger := klog.LoggerWithName(klog.Background(), "applySriovOperatorCoexistence")
+
+	deviceTypes := map[string]string{}
+	var conflicts []string
+	checkedPFs := map[string]bool{}
+	for name, device := range allocatable {
+		pfPciAddress := strings.ToLower(attributeString(device.Attributes[consts.AttributePfPciAddress]))
+		iface, managed := interfaces[pfPciAddress]
+		if !managed {
+			continue
+		}
+		if mode == consts.SriovOperatorCoexistenceDefer {
+			logger.Info("Leaving device to sriov-network-operator, see --sriov-operator-coexistence", "device", name, "pf", pfPciAddress)
+			delete(allocatable, name)
+			continue
+		}
+
+		if numVFs := device.Attributes[consts.AttributePFNumVFs].IntValue; numVFs != nil && !checkedPFs[pfPciAddress] {
+			checkedPFs[pfPciAddress] = true
+			if *numVFs != iface.NumVFs {
+				conflicts = append(conflicts, fmt.Sprintf("PF %s has %d VFs, sriov-network-operator configures %d", pfPciAddress, *numVFs, iface.NumVFs))
+			}
+		}
+		vfID := device.Attributes[consts.AttributeVFID].IntValue
+		if vfID == nil {
+			continue
+		}
+		deviceType, ok := iface.DeviceTypes[*vfID]
+		if !ok {
+			continue
+		}
+		deviceTypes[name] = deviceType
+		driver := attributeString(device.Attributes[consts.AttributeDriver])
+		if driver != "" && (deviceType == sriovOperatorDeviceTypeVFIO) != (driver == sriovOperatorDeviceTypeVFIO) {
+			conflicts = append(conflicts, fmt.Sprintf("VF %s is bound to %s, sriov-network-operator configures it as a %s device", name, driver, deviceType))
+		}
+	}
+	slices.Sort(conflicts)
+	return deviceTypes, conflicts
+}
+
+// validateSriovOperatorDriver refuses to bind a VF managed by sriov-network-operator to a driver
+// the operator would bind it away from.
+func (s *Manager) validateSriovOperatorDriver(deviceName, driver string) error {
+	deviceType, managed := s.sriovOperatorDeviceTypes[deviceName]
+	if !managed || driver == "" {
+		return nil
+	}
+	if deviceType == sriovOperatorDeviceTypeVFIO {
+		if driver == sriovOperatorDeviceTypeVFIO {
+			return nil
+		}
+	} else if driver == consts.DefaultVFDriver || !host.GetHelpers().IsDpdkDriver(driver) {
+		return nil
+	}
+	return fmt.Errorf("VF %s is managed by sriov-network-operator as a %s device, refusing to bind it to %s", deviceName, deviceType, driver)
+}
+
+// reportSriovOperatorConflicts logs the conflicts with sriov-network-operator, exports their
+// number and reports them in a warning event of the node.
+func reportSriovOperatorConflicts(config *drasriovtypes.Config, conflicts []string) {
+	logger := klog.LoggerWithName(klog.Background(), "reportSriovOperatorConflicts")
+	metrics.SriovOperatorConflicts.Set(float64(len(conflicts)))
+	if len(conflicts) == 0 {
+		return
+	}
+	for _, conflict := range conflicts {
+		logger.Info("Conflict with sriov-network-operator", "conflict", conflict)
+	}
+	if config.K8sClient.Interface == nil {
+		return
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: config.K8sClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: consts.DriverName, Host: config.Flags.NodeName})
+	node := &corev1.ObjectReference{Kind: "Node", Name: config.Flags.NodeName, UID: k8stypes.UID(config.Flags.NodeName)}
+	recorder.Eventf(node, corev1.EventTypeWarning, eventReasonSriovOperatorConflict,
+		"%d conflicts with sriov-network-operator: %s", len(conflicts), strings.Join(conflicts, "; "))
+}
@@ -0,0 +1,182 @@
+package devicestate
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("sriov-network-operator coexistence", func() {
+	newNodeState := func(name string) *unstructured.Unstructured {
+		nodeState := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"interfaces": []interface{}{
+					map[string]interface{}{
+						"pciAddress": "0000:01:00.0",
+						"numVfs":     int64(4),
+						"vfGroups": []interface{}{
+							map[string]interface{}{"deviceType": "netdevice", "vfRange": "0-1"},
+							map[string]interface{}{"deviceType": "vfio-pci", "vfRange": "2-3"},
+						},
+					},
+				},
+			},
+		}}
+		nodeState.SetGroupVersionKind(schema.GroupVersionKind{Group: "sriovnetwork.openshift.io", Version: "v1", Kind: "SriovNetworkNodeState"})
+		nodeState.SetNamespace("sriov-network-operator")
+		nodeState.SetName(name)
+		return nodeState
+	}
+
+	newVF := func(pciAddress, pfPciAddress string, vfID, pfNumVFs int64, driver string) resourceapi.Device {
+		return resourceapi.Device{
+			Name: DeviceNameFromPciAddress(pciAddress),
+			Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				consts.AttributePciAddress:   {StringValue: ptr.To(pciAddress)},
+				consts.AttributePfPciAddress: {StringValue: ptr.To(pfPciAddress)},
+				consts.AttributeVFID:         {IntValue: ptr.To(vfID)},
+				consts.AttributePFNumVFs:     {IntValue: ptr.To(pfNumVFs)},
+				consts.AttributeDriver:       {StringValue: ptr.To(driver)},
+			},
+		}
+	}
+
+	Context("getSriovOperatorInterfaces", func() {
+		newConfig := func(objects ...runtime.Object) *drasriovtypes.Config {
+			scheme := runtime.NewScheme()
+			scheme.AddKnownTypeWithName(schema.GroupVersionKind{Group: "sriovnetwork.openshift.io", Version: "v1", Kind: "SriovNetworkNodeState"}, &unstructured.Unstructured{})
+			scheme.AddKnownTypeWithName(sriovNetworkNodeStateListGVK, &unstructured.UnstructuredList{})
+			return &drasriovtypes.Config{
+				Flags:     &drasriovtypes.Flags{NodeName: "node-1"},
+				K8sClient: flags.ClientSets{Client: crfake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()},
+			}
+		}
+
+		It("reads the PFs of the node state of the node", func() {
+			interfaces, err := getSriovOperatorInterfaces(context.Background(), newConfig(newNodeState("node-1"), newNodeState("node-2")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(HaveLen(1))
+			Expect(interfaces["0000:01:00.0"].NumVFs).To(Equal(int64(4)))
+			Expect(interfaces["0000:01:00.0"].DeviceTypes).To(Equal(map[int64]string{0: "netdevice", 1: "netdevice", 2: "vfio-pci", 3: "vfio-pci"}))
+		})
+
+		It("finds nothing when the operator does not manage the node", func() {
+			interfaces, err := getSriovOperatorInterfaces(context.Background(), newConfig(newNodeState("node-2")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(BeNil())
+		})
+
+		It("finds nothing without a kubernetes client", func() {
+			interfaces, err := getSriovOperatorInterfaces(context.Background(), &drasriovtypes.Config{Flags: &drasriovtypes.Flags{}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(BeNil())
+		})
+	})
+
+	Context("parseVFRange", func() {
+		It("parses single VFs and ranges", func() {
+			first, last, err := parseVFRange("3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]int64{first, last}).To(Equal([]int64{3, 3}))
+
+			first, last, err = parseVFRange("0-7")
+			Expect(err).ToNot(HaveOccurred())
+			Expect([]int64{first, last}).To(Equal([]int64{0, 7}))
+
+			_, _, err = parseVFRange("7-0")
+			Expect(err).To(HaveOccurred())
+			_, _, err = parseVFRange("")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("applySriovOperatorCoexistence", func() {
+		var (
+			allocatable drasriovtypes.AllocatableDevices
+			interfaces  map[string]sriovOperatorInterface
+		)
+
+		BeforeEach(func() {
+			allocatable = drasriovtypes.AllocatableDevices{}
+			for _, vf := range []resourceapi.Device{
+				newVF("0000:01:00.2", "0000:01:00.0", 0, 4, "iavf"),
+				newVF("0000:01:00.3", "0000:01:00.0", 2, 4, "iavf"),
+				newVF("0000:02:00.2", "0000:02:00.0", 0, 2, "iavf"),
+			} {
+				allocatable[vf.Name] = vf
+			}
+			interfaces = map[string]sriovOperatorInterface{
+				"0000:01:00.0": {NumVFs: 8, DeviceTypes: map[int64]string{0: "netdevice", 2: "vfio-pci"}},
+			}
+		})
+
+		It("leaves the PFs of the operator to it in defer mode", func() {
+			deviceTypes, conflicts := applySriovOperatorCoexistence(allocatable, consts.SriovOperatorCoexistenceDefer, interfaces)
+			Expect(deviceTypes).To(BeEmpty())
+			Expect(conflicts).To(BeEmpty())
+			Expect(allocatable).To(HaveLen(1))
+			Expect(allocatable).To(HaveKey("0000-02-00-2"))
+		})
+
+		It("reports the differences with the operator spec in validate mode", func() {
+			deviceTypes, conflicts := applySriovOperatorCoexistence(allocatable, consts.SriovOperatorCoexistenceValidate, interfaces)
+			Expect(allocatable).To(HaveLen(3))
+			Expect(deviceTypes).To(Equal(map[string]string{"0000-01-00-2": "netdevice", "0000-01-00-3": "vfio-pci"}))
+			Expect(conflicts).To(Equal([]string{
+				"PF 0000:01:00.0 has 4 VFs, sriov-network-operator configures 8",
+				"VF 0000-01-00-3 is bound to iavf, sriov-network-operator configures it as a vfio-pci device",
+			}))
+		})
+	})
+
+	Context("validateSriovOperatorDriver", func() {
+		var manager *Manager
+
+		BeforeEach(func() {
+			ctrl := gomock.NewController(GinkgoT())
+			_ = host.GetHelpers()
+			mockHost := hostmock.NewMockInterface(ctrl)
+			originalHelpers := host.Helpers
+			host.Helpers = mockHost
+			DeferCleanup(func() {
+				host.Helpers = originalHelpers
+			})
+			mockHost.EXPECT().IsDpdkDriver(gomock.Any()).DoAndReturn(func(driver string) bool {
+				return driver == "vfio-pci"
+			}).AnyTimes()
+
+			manager = &Manager{sriovOperatorDeviceTypes: map[string]string{"netdev-vf": "netdevice", "vfio-vf": "vfio-pci"}}
+		})
+
+		It("accepts the drivers of the operator deviceType", func() {
+			Expect(manager.validateSriovOperatorDriver("netdev-vf", "")).To(Succeed())
+			Expect(manager.validateSriovOperatorDriver("netdev-vf", consts.DefaultVFDriver)).To(Succeed())
+			Expect(manager.validateSriovOperatorDriver("vfio-vf", "vfio-pci")).To(Succeed())
+			Expect(manager.validateSriovOperatorDriver("other-vf", "vfio-pci")).To(Succeed())
+		})
+
+		It("refuses to rebind VFs against the operator", func() {
+			Expect(manager.validateSriovOperatorDriver("netdev-vf", "vfio-pci")).To(MatchError(ContainSubstring("managed by sriov-network-operator as a netdevice device")))
+			Expect(manager.validateSriovOperatorDriver("vfio-vf", consts.DefaultVFDriver)).To(MatchError(ContainSubstring("refusing to bind it to default")))
+		})
+	})
+
+	It("validates the coexistence mode", func() {
+		Expect(ValidateSriovOperatorCoexistence("defer")).To(Succeed())
+		Expect(ValidateSriovOperatorCoexistence("fight")).To(MatchError(ContainSubstring("unsupported sriov-network-operator coexistence mode")))
+	})
+})
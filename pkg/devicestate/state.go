@@ -5,27 +5,37 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/klog/v2"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	netattdefv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
 	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/dpu"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/ovs"
 	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -35,13 +45,66 @@ type Manager struct {
 	cdi                    *cdi.Handler
 	deviceInfoStore        DeviceInfoStore
 	defaultInterfacePrefix string
-	allocatable            drasriovtypes.AllocatableDevices
+	// allocatableMu guards allocatable and policyAttrKeys, updated by the policy controller while
+	// prepares read them and the devices are published from other goroutines. The attributes of
+	// the devices are replaced, never modified, so the devices read can be used without the lock.
+	allocatableMu sync.RWMutex
+	allocatable   drasriovtypes.AllocatableDevices
+	// discoveredPciAddresses are the PCI addresses of the allocatable devices, read by the
+	// healthcheck without racing with the policy updates of allocatable.
+	discoveredPciAddresses []string
 	republishCallback      func(context.Context) error
+	// netAttachDefReader serves net attach defs from an informer cache, when set, so prepares
+	// do not GET them from the API server.
+	netAttachDefReader client.Reader
 	// policyAttrKeys tracks attribute keys set by policy per device, so they
 	// can be cleared without touching discovery attributes. Presence of a
 	// device key also indicates that the device is advertised (policy-matched).
 	policyAttrKeys    map[string]map[resourceapi.QualifiedName]bool
 	configurationMode string
+	attributeSchema   consts.AttributeSchema
+	// defaultNetAttachDefNamespace is where net attach defs are looked up when the VfConfig does
+	// not set a namespace, the claim namespace is used if it is empty.
+	defaultNetAttachDefNamespace string
+	// vfioNoIOMMU reports that vfio runs in the unsafe no-IOMMU mode, see --enable-vfio-noiommu.
+	vfioNoIOMMU bool
+	// allowedDrivers are the drivers a VfConfig may bind devices to besides their default kernel
+	// driver, consts.DefaultAllowedVFDrivers when nil, see --allowed-vf-drivers.
+	allowedDrivers map[string]bool
+	// defaultVFIOPermissions are the mode and owner of the /dev/vfio devices of the containers when
+	// the VfConfig does not set them, see --vfio-device-mode.
+	defaultVFIOPermissions configapi.DevicePermissions
+	// envTemplates are the environment variables added to the containers of every device, see
+	// --env-templates.
+	envTemplates []*EnvTemplate
+	// resetOnUnprepare resets devices when their claim is unprepared, see --reset-vf-on-unprepare.
+	resetOnUnprepare bool
+	// vhostUserSocketRoot holds the vhost-user socket directories of the pods, see
+	// --vhost-user-socket-root.
+	vhostUserSocketRoot string
+	// sriovOperatorDeviceTypes is the deviceType sriov-network-operator configures for the devices
+	// of the PFs it also manages, see --sriov-operator-coexistence.
+	sriovOperatorDeviceTypes map[string]string
+	// devicePluginCheckpoint is the checkpoint of the kubelet device manager listing the devices
+	// allocated by the SR-IOV device plugin, see --device-plugin-checkpoint. devicePluginAllocated
+	// are these devices, not advertised.
+	devicePluginCheckpoint string
+	devicePluginAllocated  map[string]bool
+	devicePluginMu         sync.Mutex
+	// dpuAgent programs the representors of the VFs on the DPU in the split-driver mode, nil
+	// otherwise, see --dpu-agent-endpoint.
+	dpuAgent dpu.Agent
+	// ovsBridges plugs the representors of the VFs into the bridges of Open vSwitch, nil when no
+	// OVSDB endpoint is configured, see --ovsdb-endpoint.
+	ovsBridges ovs.Bridges
+	// unhealthy tracks the devices whose driver unbind timed out, with the reason, they are not
+	// advertised until they recover.
+	unhealthy   map[string]error
+	unhealthyMu sync.Mutex
+	// drivers tracks the driver each device is currently bound to, published in place of the
+	// driver discovered, as prepares and unprepares rebind devices.
+	drivers   map[string]string
+	driversMu sync.Mutex
 }
 
 // NewManager creates a new device-state manager and initializes allocatable SR-IOV devices.
@@ -65,26 +128,166 @@ func NewManager(config *drasriovtypes.Config, cdi *cdi.Handler, deviceInfoStore
 	if err != nil {
 		return nil, fmt.Errorf("error enumerating all possible devices: %v", err)
 	}
+	excludeDevices(allocatable, config.Flags.ExcludedDevices)
+	allocatable, err = renameDevices(allocatable, consts.DeviceNamingScheme(config.Flags.DeviceNamingScheme))
+	if err != nil {
+		return nil, err
+	}
+
+	// PFs also managed by sriov-network-operator are left to it or checked against its spec
+	var sriovOperatorDeviceTypes map[string]string
+	coexistence := consts.SriovOperatorCoexistence(config.Flags.SriovOperatorCoexistence)
+	if coexistence != "" && coexistence != consts.SriovOperatorCoexistenceIgnore {
+		interfaces, err := getSriovOperatorInterfaces(context.Background(), config)
+		// publishing the PFs of the operator in defer mode would fight it, checking them is best effort
+		if err != nil && coexistence == consts.SriovOperatorCoexistenceDefer {
+			return nil, err
+		}
+		if err != nil {
+			klog.Background().Error(err, "Skipping the sriov-network-operator coexistence checks")
+		}
+		var conflicts []string
+		sriovOperatorDeviceTypes, conflicts = applySriovOperatorCoexistence(allocatable, coexistence, interfaces)
+		reportSriovOperatorConflicts(config, conflicts)
+	}
+
+	if config.Flags.ExcludePrimaryPFs {
+		if err := excludePrimaryPFs(allocatable, config.Flags.NodeIPs); err != nil {
+			return nil, err
+		}
+	}
+
+	// labels are a convenience for the consumers that cannot read ResourceSlices, not fatal
+	if config.Flags.NodeLabels {
+		if err := labelNode(context.Background(), config, allocatable); err != nil {
+			klog.Background().Error(err, "Failed to label the node with the discovered devices")
+		}
+	}
 
 	if deviceInfoStore == nil {
 		deviceInfoStore = NewDeviceInfoStore()
 	}
 
+	// DPDK applications must know that the devices come without IOMMU protection
+	if config.Flags.EnableVFIONoIOMMU {
+		for name, device := range allocatable {
+			device.Attributes[consts.AttributeVFIONoIOMMU] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+			allocatable[name] = device
+		}
+	}
+
+	vfioPermissions, err := VFIODevicePermissionsFromFlags(config.Flags)
+	if err != nil {
+		return nil, err
+	}
+
+	envTemplates, err := ParseEnvTemplates(config.Flags.EnvTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowedDrivers map[string]bool
+	if config.Flags.AllowedVFDrivers != nil {
+		allowedDrivers = make(map[string]bool, len(config.Flags.AllowedVFDrivers))
+		for _, driver := range config.Flags.AllowedVFDrivers {
+			allowedDrivers[driver] = true
+		}
+	}
+
+	var dpuAgent dpu.Agent
+	if config.Flags.DPUAgentEndpoint != "" {
+		dpuAgent, err = dpu.NewClient(config.Flags.DPUAgentEndpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ovsBridges ovs.Bridges
+	if config.Flags.OVSDBEndpoint != "" {
+		ovsBridges, err = ovs.NewClient(config.Flags.OVSDBEndpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	state := &Manager{
 		k8sClient:              config.K8sClient,
 		defaultInterfacePrefix: config.Flags.DefaultInterfacePrefix,
 		cdi:                    cdi,
 		deviceInfoStore:        deviceInfoStore,
 		allocatable:            allocatable,
+		discoveredPciAddresses: discoveredPciAddresses(allocatable),
 		configurationMode:      configurationMode,
+		attributeSchema:        consts.AttributeSchema(config.Flags.AttributeSchema),
+
+		defaultNetAttachDefNamespace: config.Flags.DefaultNetAttachDefNamespace,
+		vfioNoIOMMU:                  config.Flags.EnableVFIONoIOMMU,
+		defaultVFIOPermissions:       vfioPermissions,
+		allowedDrivers:               allowedDrivers,
+		envTemplates:                 envTemplates,
+		resetOnUnprepare:             config.Flags.ResetVFOnUnprepare,
+		vhostUserSocketRoot:          config.Flags.VhostUserSocketRoot,
+		drivers:                      discoveredDrivers(allocatable),
+		sriovOperatorDeviceTypes:     sriovOperatorDeviceTypes,
+		devicePluginCheckpoint:       config.Flags.DevicePluginCheckpoint,
+		dpuAgent:                     dpuAgent,
+		ovsBridges:                   ovsBridges,
+	}
+
+	// devices allocated by the SR-IOV device plugin must not be published a first time
+	if state.devicePluginCheckpoint != "" {
+		if _, err := state.syncDevicePluginAllocations(context.Background()); err != nil {
+			return nil, err
+		}
 	}
 
 	return state, nil
 }
 
-// GetAllocatableDevices returns the allocatable devices
+// validateConfig checks the VfConfig of a request against the drivers a device may be bound to.
+// The errors hold the paths of the invalid fields, they are reported in the prepare result.
+func (s *Manager) validateConfig(request, pciAddress string, config *configapi.VfConfig) error {
+	allowedDrivers, err := s.allowedDriversOf(pciAddress, config.Driver)
+	if err != nil {
+		return err
+	}
+	if errs := config.ValidateFields(nil, configapi.ValidationOptions{AllowedDrivers: allowedDrivers}); len(errs) > 0 {
+		return fmt.Errorf("invalid config of request %s: %w", request, errs.ToAggregate())
+	}
+	return nil
+}
+
+// allowedDriversOf returns the drivers a VfConfig may bind a device to. Claim configs are
+// user-controlled, so only the allowed drivers and the default kernel driver of the device, which
+// it is bound to unless a userspace driver took it over, are accepted. The current driver of the
+// device is only looked up when driver is not otherwise allowed.
+func (s *Manager) allowedDriversOf(pciAddress, driver string) ([]string, error) {
+	allowedDrivers := []string{consts.DefaultVFDriver}
+	if s.allowedDrivers == nil {
+		allowedDrivers = append(allowedDrivers, consts.DefaultAllowedVFDrivers...)
+	} else {
+		for _, allowedDriver := range slices.Sorted(maps.Keys(s.allowedDrivers)) {
+			allowedDrivers = append(allowedDrivers, allowedDriver)
+		}
+	}
+	if driver == "" || slices.Contains(allowedDrivers, driver) {
+		return allowedDrivers, nil
+	}
+	currentDriver, err := host.GetHelpers().GetDriverByBusAndDevice(pciAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current driver of device %s: %w", pciAddress, err)
+	}
+	if currentDriver == driver && !host.GetHelpers().IsDpdkDriver(currentDriver) {
+		allowedDrivers = append(allowedDrivers, currentDriver)
+	}
+	return allowedDrivers, nil
+}
+
+// GetAllocatableDevices returns a snapshot of the allocatable devices
 func (s *Manager) GetAllocatableDevices() drasriovtypes.AllocatableDevices {
-	return s.allocatable
+	s.allocatableMu.RLock()
+	defer s.allocatableMu.RUnlock()
+	return maps.Clone(s.allocatable)
 }
 
 // normalizeConfigurationMode validates the configured mode and applies defaulting.
@@ -103,6 +306,8 @@ func normalizeConfigurationMode(mode string) (string, error) {
 
 // GetAllocatableDeviceByName returns a discovered allocatable device and whether it exists.
 func (s *Manager) GetAllocatableDeviceByName(deviceName string) (resourceapi.Device, bool) {
+	s.allocatableMu.RLock()
+	defer s.allocatableMu.RUnlock()
 	device, exists := s.allocatable[deviceName]
 	return device, exists
 }
@@ -150,7 +355,10 @@ func (s *Manager) PrepareDevicesForClaim(ctx context.Context, ifNameIndex *int,
 		return nil, errors.Join(rollbackErrs...)
 	}
 
-	if err = s.cdi.CreateClaimSpecFile(preparedDevices); err != nil {
+	cdiTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationPrepare, metrics.PhaseCDI))
+	err = s.cdi.CreateClaimSpecFile(preparedDevices)
+	cdiTimer.ObserveDuration()
+	if err != nil {
 		rollbackErrs := []error{fmt.Errorf("unable to create CDI spec file for claim: %v", err)}
 		if cleanupErr := s.cleanDeviceInfoFilesForPreparedDevicesIfNeeded(ctx, preparedDevices); cleanupErr != nil {
 			rollbackErrs = append(rollbackErrs, fmt.Errorf("cleanup after CDI spec failure failed: %w", cleanupErr))
@@ -161,6 +369,7 @@ func (s *Manager) PrepareDevicesForClaim(ctx context.Context, ifNameIndex *int,
 		return nil, errors.Join(rollbackErrs...)
 	}
 
+	s.syncDrivers(ctx, preparedDevices)
 	return preparedDevices, nil
 }
 
@@ -169,6 +378,9 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 	resultsConfig map[string]*configapi.VfConfig) (drasriovtypes.PreparedDevices, error) {
 	logger := klog.FromContext(ctx).WithName("prepareDevices")
 	preparedDevices := drasriovtypes.PreparedDevices{}
+	if err := validateInfiniBandRequests(claim, resultsConfig); err != nil {
+		return nil, err
+	}
 	for _, result := range claim.Status.Allocation.Devices.Results {
 		if result.Driver != consts.DriverName {
 			continue
@@ -182,7 +394,13 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 		// make changes if needed
 		config.Normalize()
 
-		preparedDevice, err := s.applyConfigOnDevice(ctx, ifNameIndex, claim, config, &result)
+		var preparedDevice *drasriovtypes.PreparedDevice
+		var err error
+		if isAdminAccess(&result) {
+			preparedDevice, err = s.applyAdminAccessOnDevice(ctx, claim, &result)
+		} else {
+			preparedDevice, err = s.applyConfigOnDevice(ctx, ifNameIndex, claim, config, &result)
+		}
 		if err != nil {
 			logger.Error(err, "error applying config on device", "config", config, "result", result)
 			if rollbackErr := s.unprepareDevices(preparedDevices); rollbackErr != nil {
@@ -191,7 +409,7 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 			return nil, fmt.Errorf("error applying config on device: %v", err)
 		}
 
-		rawConfig, err := json.Marshal(config)
+		rawConfig, err := statusData(config, preparedDevice)
 		if err != nil {
 			logger.Error(err, "error marshaling config", "config", config)
 			rawConfig = []byte("{}")
@@ -206,6 +424,40 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 		preparedDevices = append(preparedDevices, preparedDevice)
 	}
 
+	if err := s.bondDevices(ifNameIndex, preparedDevices); err != nil {
+		logger.Error(err, "error bonding devices")
+		if rollbackErr := s.unprepareDevices(preparedDevices); rollbackErr != nil {
+			return nil, fmt.Errorf("error bonding devices: %v; rollback failed: %v", err, rollbackErr)
+		}
+		return nil, fmt.Errorf("error bonding devices: %v", err)
+	}
+
+	if err := s.configureTCOffload(ctx, preparedDevices); err != nil {
+		logger.Error(err, "error configuring TC offload")
+		if rollbackErr := s.unprepareDevices(preparedDevices); rollbackErr != nil {
+			return nil, fmt.Errorf("error configuring TC offload: %v; rollback failed: %v", err, rollbackErr)
+		}
+		return nil, fmt.Errorf("error configuring TC offload: %v", err)
+	}
+
+	if err := s.configureOVSBridge(ctx, claim.Name, claim.Namespace, preparedDevices); err != nil {
+		logger.Error(err, "error plugging representors into OVS bridges")
+		if rollbackErr := s.unprepareDevices(preparedDevices); rollbackErr != nil {
+			return nil, fmt.Errorf("error plugging representors into OVS bridges: %v; rollback failed: %v", err, rollbackErr)
+		}
+		return nil, fmt.Errorf("error plugging representors into OVS bridges: %v", err)
+	}
+
+	if err := s.configureDPU(ctx, claim.Name, claim.Namespace, preparedDevices); err != nil {
+		logger.Error(err, "error configuring devices on the DPU")
+		if rollbackErr := s.unprepareDevices(preparedDevices); rollbackErr != nil {
+			return nil, fmt.Errorf("error configuring devices on the DPU: %v; rollback failed: %v", err, rollbackErr)
+		}
+		return nil, fmt.Errorf("error configuring devices on the DPU: %v", err)
+	}
+
+	s.kubeVirtDevices(preparedDevices)
+
 	logger.V(3).Info("Prepared devices", "preparedDevices", preparedDevices)
 	return preparedDevices, nil
 }
@@ -213,7 +465,7 @@ func (s *Manager) prepareDevices(ctx context.Context, ifNameIndex *int,
 func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, claim *resourceapi.ResourceClaim, config *configapi.VfConfig, result *resourceapi.DeviceRequestAllocationResult) (*drasriovtypes.PreparedDevice, error) {
 	logger := klog.FromContext(ctx).WithName("applyConfigOnDevice")
 	logger.V(3).Info("Applying config on device", "config", config, "result", result)
-	deviceInfo, exist := s.allocatable[result.Device]
+	deviceInfo, exist := s.GetAllocatableDeviceByName(result.Device)
 	if !exist {
 		return nil, fmt.Errorf("device %s not found in allocatable devices", result.Device)
 	}
@@ -232,37 +484,151 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 	var netAttachDefRawConfig string
 	var err error
 	pciAddress := *deviceInfo.Attributes[consts.AttributePciAddress].StringValue
+	if err := s.checkHealthy(result.Device); err != nil {
+		return nil, fmt.Errorf("device %s is unhealthy: %w", result.Device, err)
+	}
+	if s.allocatedByDevicePlugin(result.Device) {
+		return nil, fmt.Errorf("device %s is allocated by the SR-IOV device plugin", result.Device)
+	}
+	if err := s.validateConfig(result.Request, pciAddress, config); err != nil {
+		return nil, err
+	}
+	if err := s.validateSriovOperatorDriver(result.Device, config.Driver); err != nil {
+		return nil, err
+	}
+	if err := s.validateTCOffloadConfig(config); err != nil {
+		return nil, err
+	}
+	if err := s.validateOVSBridgeConfig(config); err != nil {
+		return nil, err
+	}
+	if err := validateInfiniBandConfig(config, deviceInfo); err != nil {
+		return nil, err
+	}
+	// VFs passed through to a KubeVirt VM are only attached to a network when one is configured
+	attachNetwork := config.KubeVirt == nil || config.NetAttachDefName != "" || config.CNIConfig != nil
 	// if in standalone mode, we get the net attach def raw config and add the deviceID (PCI address) to it
-	if s.isStandaloneMode() {
+	if s.isStandaloneMode() && attachNetwork {
 		netAttachDefNamespace := claim.GetNamespace()
+		if s.defaultNetAttachDefNamespace != "" {
+			netAttachDefNamespace = s.defaultNetAttachDefNamespace
+		}
 		if config.NetAttachDefNamespace != "" {
 			netAttachDefNamespace = config.NetAttachDefNamespace
 		}
-		netAttachDefRawConfig, err = s.getNetAttachDefRawConfig(ctx, netAttachDefNamespace, config.NetAttachDefName)
+		if config.CNIConfig != nil {
+			// an inline config replaces the net attach def, clusters without the NAD CRD can still attach networks
+			netAttachDefRawConfig = string(config.CNIConfig.Raw)
+			if err := drasriovtypes.ValidateNetConf(netAttachDefRawConfig); err != nil {
+				return nil, fmt.Errorf("invalid inline cni config: %w", err)
+			}
+		} else {
+			netAttachDefRawConfig, err = s.getNetAttachDefRawConfig(ctx, netAttachDefNamespace, config.NetAttachDefName)
+			if err != nil {
+				return nil, fmt.Errorf("error getting net attach def raw config: %w", err)
+			}
+		}
+		// stack the plugins of the chained net attach defs after the primary ones
+		chainedRawConfigs := make([]string, 0, len(config.ChainedNetAttachDefs))
+		for _, ref := range config.ChainedNetAttachDefs {
+			namespace := netAttachDefNamespace
+			if ref.Namespace != "" {
+				namespace = ref.Namespace
+			}
+			chainedRawConfig, err := s.getNetAttachDefRawConfig(ctx, namespace, ref.Name)
+			if err != nil {
+				return nil, fmt.Errorf("error getting chained net attach def raw config: %w", err)
+			}
+			chainedRawConfigs = append(chainedRawConfigs, chainedRawConfig)
+		}
+		netAttachDefRawConfig, err = drasriovtypes.ChainNetConfs(netAttachDefRawConfig, chainedRawConfigs...)
+		if err != nil {
+			return nil, fmt.Errorf("error chaining net attach def configs: %w", err)
+		}
+		netAttachDefRawConfig, err = drasriovtypes.MergeIPAMIntoNetConf(netAttachDefRawConfig, config.IPAM)
 		if err != nil {
-			return nil, fmt.Errorf("error getting net attach def raw config: %w", err)
+			return nil, fmt.Errorf("error merging ipam overrides into net attach def config: %w", err)
 		}
 		// add to sriov-cni compatible netconf the deviceID (PCI address)
 		netAttachDefRawConfig, err = drasriovtypes.AddDeviceIDToNetConf(netAttachDefRawConfig, pciAddress)
 		if err != nil {
 			return nil, fmt.Errorf("error converting net attach def config to sriov-cni format: %w", err)
 		}
+		if attributeString(deviceInfo.Attributes[consts.AttributeEswitchMode]) == consts.EswitchModeSwitchdev {
+			netAttachDefRawConfig, err = drasriovtypes.AddSwitchdevOffloadToNetConf(netAttachDefRawConfig, pciAddress)
+			if err != nil {
+				return nil, fmt.Errorf("error adding switchdev offload to net attach def config: %w", err)
+			}
+		}
+	}
+	// keep the administrative settings of the VF, which its consumer may change, e.g. the MAC
+	// address and VLAN set by sriov-cni
+	originalVFSettings, err := host.GetHelpers().GetVFSettings(pciAddress)
+	if err != nil {
+		logger.V(2).Info("Failed to get VF settings, they will not be restored on unprepare", "device", pciAddress, "error", err.Error())
+		originalVFSettings = nil
+	}
+	// the GUIDs are set before the driver is bound, which reads them when it probes the VF
+	originalNodeGUID, originalPortGUID, err := setInfiniBandGUIDs(ctx, pciAddress, config)
+	if err != nil {
+		return nil, err
 	}
 	// Bind device to driver if specified in config
+	bindTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationPrepare, metrics.PhaseDriverBind))
 	originalDriver, err := host.GetHelpers().BindDeviceDriver(pciAddress, config)
+	bindTimer.ObserveDuration()
+	if err != nil {
+		s.handleDriverError(ctx, result.Device, pciAddress, err)
+		err = fmt.Errorf("error binding device %s to driver: %w", pciAddress, err)
+		if restoreErr := restoreInfiniBandGUIDs(ctx, pciAddress, originalNodeGUID, originalPortGUID); restoreErr != nil {
+			return nil, fmt.Errorf("%w; additionally %v", err, restoreErr)
+		}
+		return nil, err
+	}
+	// the driver the VF ends up bound to, the one it already had when the config sets none, is
+	// reported in the claim
+	boundDriver, err := host.GetHelpers().GetDriverByBusAndDevice(pciAddress)
 	if err != nil {
-		return nil, fmt.Errorf("error binding device %s to driver: %w", pciAddress, err)
+		logger.V(2).Info("Failed to get the driver of device, it is not reported in the claim", "device", pciAddress, "error", err.Error())
+		boundDriver = ""
 	}
+	var originalNumQueues int
+	var originalRingSizes *host.RingSizes
+	var originalFeatures map[string]bool
 	restoreDriverOnError := func(cause error) error {
+		if restoreErr := restoreOffloads(ctx, pciAddress, originalFeatures); restoreErr != nil {
+			cause = fmt.Errorf("%w; additionally %v", cause, restoreErr)
+		}
+		if restoreErr := restoreRingSizes(ctx, pciAddress, originalRingSizes); restoreErr != nil {
+			cause = fmt.Errorf("%w; additionally %v", cause, restoreErr)
+		}
+		if restoreErr := restoreNumQueues(ctx, pciAddress, originalNumQueues); restoreErr != nil {
+			cause = fmt.Errorf("%w; additionally %v", cause, restoreErr)
+		}
+		if restoreErr := restoreInfiniBandGUIDs(ctx, pciAddress, originalNodeGUID, originalPortGUID); restoreErr != nil {
+			cause = fmt.Errorf("%w; additionally %v", cause, restoreErr)
+		}
 		if config.Driver == "" {
 			return cause
 		}
 		if restoreErr := host.GetHelpers().RestoreDeviceDriver(pciAddress, originalDriver); restoreErr != nil {
+			s.handleDriverError(ctx, result.Device, pciAddress, restoreErr)
 			return fmt.Errorf("%w; additionally failed to restore original driver for device %s: %v", cause, pciAddress, restoreErr)
 		}
 		return cause
 	}
 
+	// the queues, rings and offloads are set once the kernel driver of the VF created its network interface
+	if originalNumQueues, err = setNumQueues(ctx, pciAddress, config); err != nil {
+		return nil, restoreDriverOnError(err)
+	}
+	if originalRingSizes, err = setRingSizes(ctx, pciAddress, config); err != nil {
+		return nil, restoreDriverOnError(err)
+	}
+	if originalFeatures, err = setOffloads(ctx, pciAddress, config); err != nil {
+		return nil, restoreDriverOnError(err)
+	}
+
 	// Ensure that the kernel module are loaded if the user request vhost mounts
 	if config.AddVhostMount {
 		if err := host.GetHelpers().EnsureVhostModulesLoaded(); err != nil {
@@ -281,30 +647,57 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 
 	// If device is bound to vfio-pci, add VFIO device nodes
 	if config.Driver == "vfio-pci" {
+		vfioPermissions, err := s.vfioDevicePermissions(config)
+		if err != nil {
+			return nil, restoreDriverOnError(err)
+		}
 		devFileHost, devFileContainer, err := host.GetHelpers().GetVFIODeviceFile(pciAddress)
 		if err != nil {
 			return nil, restoreDriverOnError(fmt.Errorf("error getting VFIO device file for device %s: %w", pciAddress, err))
 		}
+		// in no-IOMMU mode DPDK opens the groups as /dev/vfio/noiommu-<group>, keep the host name
+		if s.vfioNoIOMMU {
+			devFileContainer = devFileHost
+		}
 
 		// Add VFIO device node
-		deviceNodes = append(deviceNodes, &cdispec.DeviceNode{
+		groupDeviceNode := &cdispec.DeviceNode{
 			Path:     devFileContainer,
 			HostPath: devFileHost,
 			Type:     "c", // character device
-		})
+		}
 
 		// Also add /dev/vfio/vfio (VFIO container device) if it exists
 		vfioContainerPath := "/dev/vfio/vfio"
-		deviceNodes = append(deviceNodes, &cdispec.DeviceNode{
+		containerDeviceNode := &cdispec.DeviceNode{
 			Path:     vfioContainerPath,
 			HostPath: vfioContainerPath,
 			Type:     "c", // character device
-		})
+		}
+		// both devices have to be opened by the application, e.g. when it does not run as root
+		applyDevicePermissions(groupDeviceNode, vfioPermissions)
+		applyDevicePermissions(containerDeviceNode, vfioPermissions)
+		deviceNodes = append(deviceNodes, groupDeviceNode, containerDeviceNode)
 
 		envs = append(envs, fmt.Sprintf("SRIOVNETWORK_%s_VFIO_DEVICE=%s", strings.ReplaceAll(result.Device, "-", "_"), devFileContainer))
 		logger.V(2).Info("Added VFIO device nodes for device", "device", pciAddress, "hostPath", devFileHost, "containerPath", devFileContainer)
 	}
 
+	// If device is bound to a UIO driver, add its /dev/uioX device node
+	if config.Driver == "uio_pci_generic" || config.Driver == "igb_uio" {
+		devFile, err := host.GetHelpers().GetUIODeviceFile(pciAddress)
+		if err != nil {
+			return nil, restoreDriverOnError(fmt.Errorf("error getting UIO device file for device %s: %w", pciAddress, err))
+		}
+		deviceNodes = append(deviceNodes, &cdispec.DeviceNode{
+			Path:     devFile,
+			HostPath: devFile,
+			Type:     "c", // character device
+		})
+		envs = append(envs, fmt.Sprintf("SRIOVNETWORK_%s_UIO_DEVICE=%s", strings.ReplaceAll(result.Device, "-", "_"), devFile))
+		logger.V(2).Info("Added UIO device node for device", "device", pciAddress, "devFile", devFile)
+	}
+
 	// if addVhostMount is true, we add a volume mount for the vhost device
 	if config.AddVhostMount {
 		deviceNodes = append(deviceNodes, &cdispec.DeviceNode{
@@ -319,27 +712,92 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 		})
 	}
 
+	// mount the hugepages used by DPDK, only DPDK drivers need them
+	var mounts []*cdispec.Mount
+	if config.AddHugepagesMount {
+		if host.GetHelpers().IsDpdkDriver(config.Driver) {
+			mounts = append(mounts, &cdispec.Mount{
+				HostPath:      consts.HugepagesDir,
+				ContainerPath: consts.HugepagesDir,
+				Type:          "bind",
+				Options:       []string{"rbind", "rw"},
+			})
+			envs = append(envs, fmt.Sprintf("SRIOVNETWORK_%s_HUGEPAGES_DIR=%s", strings.ReplaceAll(result.Device, "-", "_"), consts.HugepagesDir))
+		} else {
+			logger.V(2).Info("Hugepages mount requested without a DPDK driver, skipping it", "device", pciAddress, "driver", config.Driver)
+		}
+	}
+
+	// mount a directory private to the pod for the vhost-user sockets shared with the host datapath
+	var vhostUserSocketDir string
+	if config.VhostUserSocketDir != nil {
+		// the pods sharing a claim share its directory
+		owner := claimPodUID(claim)
+		if owner == "" {
+			owner = string(claim.UID)
+		}
+		vhostUserSocketDir = s.vhostUserSocketDir(owner)
+		mount, err := createVhostUserSocketDir(vhostUserSocketDir, config.VhostUserSocketDir)
+		if err != nil {
+			return nil, restoreDriverOnError(err)
+		}
+		mounts = append(mounts, mount)
+		envs = append(envs, fmt.Sprintf("SRIOVNETWORK_%s_VHOST_USER_SOCKET_DIR=%s", strings.ReplaceAll(result.Device, "-", "_"), mount.ContainerPath))
+	}
+
 	// Add RDMA character devices if applicable
-	rdmaDeviceNodes, rdmaEnvs, err := s.handleRDMADevice(ctx, deviceInfo, pciAddress, result.Device)
+	rdmaDevice, rdmaDeviceNodes, rdmaEnvs, err := s.handleRDMADevice(ctx, deviceInfo, pciAddress, result.Device)
 	if err != nil {
 		return nil, restoreDriverOnError(fmt.Errorf("error handling RDMA device: %w", err))
 	}
 	deviceNodes = append(deviceNodes, rdmaDeviceNodes...)
 	envs = append(envs, rdmaEnvs...)
-
-	edits := &cdispec.ContainerEdits{
-		Env:         envs,
-		DeviceNodes: deviceNodes,
-	}
+	envs = append(envs, numaEnvs(ctx, deviceInfo, pciAddress, result.Device)...)
 
 	ifName := config.IfName
+	// the IfName of a bond is the one of the bond, its VFs get the default names
+	if config.Bond != nil {
+		ifName = ""
+	}
 	// if the device name is not set, we use the default interface prefix
 	// and the interface index, we also bump the index.
-	if s.isStandaloneMode() && ifName == "" {
+	if s.isStandaloneMode() && attachNetwork && ifName == "" {
 		ifName = fmt.Sprintf("%s%d", s.defaultInterfacePrefix, *ifNameIndex)
 		*ifNameIndex++
 	}
 
+	configTemplates, err := configEnvTemplates(config.Env)
+	if err != nil {
+		return nil, restoreDriverOnError(err)
+	}
+	templateEnvs, err := renderEnvTemplates(slices.Concat(s.envTemplates, configTemplates), &EnvTemplateData{
+		DeviceName:            result.Device,
+		EnvDeviceName:         strings.ReplaceAll(result.Device, "-", "_"),
+		PCIAddress:            pciAddress,
+		PFName:                attributeString(deviceInfo.Attributes[consts.AttributePFName]),
+		ResourceName:          attributeString(deviceInfo.Attributes[consts.AttributeResourceName]),
+		Driver:                config.Driver,
+		IfName:                ifName,
+		NetAttachDefName:      config.NetAttachDefName,
+		NetAttachDefNamespace: config.NetAttachDefNamespace,
+		ClaimName:             claim.Name,
+		ClaimNamespace:        claim.Namespace,
+		ClaimUID:              string(claim.UID),
+		PodUID:                claimPodUID(claim),
+		Request:               result.Request,
+		Attributes:            envTemplateAttributes(deviceInfo.Attributes),
+	})
+	if err != nil {
+		return nil, restoreDriverOnError(err)
+	}
+	envs = append(envs, templateEnvs...)
+
+	edits := &cdispec.ContainerEdits{
+		Env:         envs,
+		DeviceNodes: deviceNodes,
+		Mounts:      mounts,
+	}
+
 	preparedDevice := &drasriovtypes.PreparedDevice{
 		ClaimNamespacedName: kubeletplugin.NamespacedObject{
 			NamespacedName: k8stypes.NamespacedName{
@@ -352,7 +810,7 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 			RequestNames: []string{result.Request},
 			PoolName:     result.Pool,
 			DeviceName:   result.Device,
-			CDIDeviceIDs: []string{s.cdi.GetClaimDevices(string(claim.UID), result.Device), s.cdi.GetPodSpecName(string(claim.Status.ReservedFor[0].UID))},
+			CDIDeviceIDs: s.claimCDIDeviceIDs(claim, result.Device),
 		},
 		ContainerEdits:     &cdiapi.ContainerEdits{ContainerEdits: edits},
 		NetAttachDefConfig: netAttachDefRawConfig,
@@ -360,21 +818,87 @@ func (s *Manager) applyConfigOnDevice(ctx context.Context, ifNameIndex *int, cla
 		PciAddress:         pciAddress,
 		MultusDeviceID:     multusDeviceID,
 		MultusResourceName: multusResourceName,
-		PodUID:             string(claim.Status.ReservedFor[0].UID),
+		PodUID:             claimPodUID(claim),
 		Config:             config,
 		OriginalDriver:     originalDriver,
+		OriginalVFSettings: originalVFSettings,
+		OriginalNodeGUID:   originalNodeGUID,
+		OriginalPortGUID:   originalPortGUID,
+		OriginalNumQueues:  originalNumQueues,
+		OriginalRingSizes:  originalRingSizes,
+		OriginalFeatures:   originalFeatures,
+		ResourceName:       attributeString(deviceInfo.Attributes[consts.AttributeResourceName]),
+		PFName:             attributeString(deviceInfo.Attributes[consts.AttributePFName]),
+		VFID:               deviceInfo.Attributes[consts.AttributeVFID].IntValue,
+		BoundDriver:        boundDriver,
+		RDMADevice:         rdmaDevice,
+		VhostUserSocketDir: vhostUserSocketDir,
 	}
 
 	return preparedDevice, nil
 }
 
-// handleRDMADevice handles RDMA device configuration and returns device nodes, environment variables, or an error
-func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.Device, pciAddress, deviceName string) ([]*cdispec.DeviceNode, []string, error) {
+// claimPodUID returns the UID of the pod a claim is reserved for, or an empty string for a claim
+// shared by several pods, whose devices belong to none of them.
+func claimPodUID(claim *resourceapi.ResourceClaim) string {
+	if len(claim.Status.ReservedFor) != 1 {
+		return ""
+	}
+	return string(claim.Status.ReservedFor[0].UID)
+}
+
+// claimCDIDeviceIDs returns the CDI devices injected for a device of a claim: the device, and the
+// pod level spec of the pod the claim is reserved for.
+func (s *Manager) claimCDIDeviceIDs(claim *resourceapi.ResourceClaim, deviceName string) []string {
+	cdiDeviceIDs := []string{s.cdi.GetClaimDevices(string(claim.UID), deviceName)}
+	if podUID := claimPodUID(claim); podUID != "" {
+		cdiDeviceIDs = append(cdiDeviceIDs, s.cdi.GetPodSpecName(podUID))
+	}
+	return cdiDeviceIDs
+}
+
+// statusData returns the Data of the status of a prepared device in its claim, the applied config
+// along with the details of the VF of the device, see PreparedDevice.StatusData.
+func statusData(config *configapi.VfConfig, preparedDevice *drasriovtypes.PreparedDevice) ([]byte, error) {
+	rawConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(rawConfig, &data); err != nil {
+		return nil, err
+	}
+	maps.Copy(data, preparedDevice.StatusData())
+	return json.Marshal(data)
+}
+
+// numaEnvs returns the environment variables describing the NUMA node of a device and its local
+// CPUs, so DPDK applications can pin their threads without reading sysfs. Devices without NUMA
+// affinity get none, and failing to read the CPUs only drops the CPU list.
+func numaEnvs(ctx context.Context, deviceInfo resourceapi.Device, pciAddress, deviceName string) []string {
+	numaNodeAttr, ok := deviceInfo.Attributes[consts.AttributeNUMANode]
+	if !ok || numaNodeAttr.IntValue == nil || *numaNodeAttr.IntValue < 0 {
+		return nil
+	}
+	numaNode := strconv.FormatInt(*numaNodeAttr.IntValue, 10)
+	devicePrefix := strings.ReplaceAll(deviceName, "-", "_")
+	envs := []string{fmt.Sprintf("SRIOVNETWORK_%s_NUMA_NODE=%s", devicePrefix, numaNode)}
+
+	cpuList, err := host.GetHelpers().GetNumaNodeCPUs(numaNode)
+	if err != nil {
+		klog.FromContext(ctx).V(2).Info("Failed to get the CPUs of the NUMA node of the device", "device", pciAddress, "numaNode", numaNode, "error", err.Error())
+		return envs
+	}
+	return append(envs, fmt.Sprintf("SRIOVNETWORK_%s_NUMA_CPUS=%s", devicePrefix, cpuList))
+}
+
+// handleRDMADevice handles RDMA device configuration and returns the RDMA device, device nodes, environment variables, or an error
+func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.Device, pciAddress, deviceName string) (string, []*cdispec.DeviceNode, []string, error) {
 	logger := klog.FromContext(ctx).WithName("handleRDMADevice")
 
 	// Check if device is RDMA capable
 	if rdmaCapableAttr, ok := deviceInfo.Attributes[consts.AttributeRDMACapable]; !ok || rdmaCapableAttr.BoolValue == nil || !*rdmaCapableAttr.BoolValue {
-		return nil, nil, nil
+		return "", nil, nil, nil
 	}
 
 	var deviceNodes []*cdispec.DeviceNode
@@ -384,11 +908,11 @@ func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.D
 
 	if len(rdmaDevices) == 0 {
 		logger.V(2).Info("No RDMA devices found for PCI address", "device", pciAddress)
-		return nil, nil, fmt.Errorf("no RDMA devices found for PCI address %s", pciAddress)
+		return "", nil, nil, fmt.Errorf("no RDMA devices found for PCI address %s", pciAddress)
 	}
 
 	if len(rdmaDevices) > 1 {
-		return nil, nil, fmt.Errorf("expected exactly one RDMA device for PCI address %s, but found %d: %v", pciAddress, len(rdmaDevices), rdmaDevices)
+		return "", nil, nil, fmt.Errorf("expected exactly one RDMA device for PCI address %s, but found %d: %v", pciAddress, len(rdmaDevices), rdmaDevices)
 	}
 
 	rdmaDevice := rdmaDevices[0]
@@ -400,13 +924,24 @@ func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.D
 	if err != nil {
 		logger.Error(err, "Failed to get RDMA character devices",
 			"device", pciAddress, "rdmaDevice", rdmaDevice)
-		return nil, nil, err
+		return "", nil, nil, err
 	}
 
 	if len(charDevices) == 0 {
 		logger.V(2).Info("No RDMA character devices found",
 			"device", pciAddress, "rdmaDevice", rdmaDevice)
-		return nil, nil, fmt.Errorf("no RDMA character devices found for RDMA device %s (PCI: %s)", rdmaDevice, pciAddress)
+		return "", nil, nil, fmt.Errorf("no RDMA character devices found for RDMA device %s (PCI: %s)", rdmaDevice, pciAddress)
+	}
+
+	// most RDMA applications connect through librdmacm, which needs the connection manager device
+	// on top of uverbs. It is shared by all the RDMA devices and only exists once rdma_ucm is loaded.
+	if !slices.Contains(charDevices, consts.RDMACMDevice) {
+		if err := host.GetHelpers().EnsureRDMACMModuleLoaded(); err != nil {
+			logger.Error(err, "RDMA connection manager is not available, applications using librdmacm will fail",
+				"device", pciAddress, "rdmaDevice", rdmaDevice)
+		} else {
+			charDevices = append(charDevices, consts.RDMACMDevice)
+		}
 	}
 
 	// Use RDMA device name in env var key to support multiple RDMA devices
@@ -441,16 +976,24 @@ func (s *Manager) handleRDMADevice(ctx context.Context, deviceInfo resourceapi.D
 	envs = append(envs, fmt.Sprintf("SRIOVNETWORK_%s_RDMA_DEVICE=%s",
 		devicePrefix, rdmaDevice))
 
-	return deviceNodes, envs, nil
+	return rdmaDevice, deviceNodes, envs, nil
 }
 
 func (s *Manager) getNetAttachDefRawConfig(ctx context.Context, namespace string, netAttachDefName string) (string, error) {
 	// Get the net attach def information
 	netAttachDef := &netattdefv1.NetworkAttachmentDefinition{}
-	err := s.k8sClient.Get(ctx, client.ObjectKey{
+	key := client.ObjectKey{
 		Name:      netAttachDefName,
 		Namespace: namespace,
-	}, netAttachDef)
+	}
+	var err error
+	if s.netAttachDefReader != nil {
+		err = s.netAttachDefReader.Get(ctx, key, netAttachDef)
+	}
+	// a net attach def created right before the pod may not have reached the cache yet
+	if s.netAttachDefReader == nil || apierrors.IsNotFound(err) {
+		err = s.k8sClient.Get(ctx, key, netAttachDef)
+	}
 	if err != nil {
 		return "", fmt.Errorf("error getting net attach def for net attach def %s/%s: %w", namespace, netAttachDefName, err)
 	}
@@ -468,18 +1011,34 @@ func (s *Manager) Unprepare(claimUID string, preparedDevices drasriovtypes.Prepa
 	if err := s.unprepareDevices(preparedDevices); err != nil {
 		errs = append(errs, fmt.Errorf("unprepare failed: %v", err))
 	}
+	s.syncDrivers(context.Background(), preparedDevices)
 
+	cdiTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationUnprepare, metrics.PhaseCDI))
 	err := s.cdi.DeleteSpecFile(claimUID)
 	if err != nil {
 		errs = append(errs, fmt.Errorf("unable to delete CDI spec file for PodUID: %v", err))
 	}
 
-	if len(preparedDevices) > 0 && preparedDevices[0] != nil {
+	// the devices of a shared claim belong to no pod
+	if len(preparedDevices) > 0 && preparedDevices[0] != nil && preparedDevices[0].PodUID != "" {
 		err = s.cdi.DeleteSpecFile(preparedDevices[0].PodUID)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("unable to delete CDI spec file for PodUID: %v", err))
 		}
 	}
+	cdiTimer.ObserveDuration()
+
+	// like the pod CDI spec, the vhost-user socket dir of the pod goes with its claims
+	removed := map[string]bool{}
+	for _, preparedDevice := range preparedDevices {
+		if preparedDevice == nil || preparedDevice.VhostUserSocketDir == "" || removed[preparedDevice.VhostUserSocketDir] {
+			continue
+		}
+		removed[preparedDevice.VhostUserSocketDir] = true
+		if err := removeVhostUserSocketDir(preparedDevice.VhostUserSocketDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
 
 	if len(errs) > 0 {
 		return errors.Join(errs...)
@@ -489,7 +1048,11 @@ func (s *Manager) Unprepare(claimUID string, preparedDevices drasriovtypes.Prepa
 
 // unprepareDevices reverts the driver configuration for the prepared devices
 func (s *Manager) unprepareDevices(preparedDevices drasriovtypes.PreparedDevices) error {
-	logger := klog.FromContext(context.Background()).WithName("unprepareDevices")
+	ctx := context.Background()
+	logger := klog.FromContext(ctx).WithName("unprepareDevices")
+	// a device failing to restore, e.g. with its unbind timing out, must not keep the others
+	// bound to the drivers of the claim
+	var errs []error
 	for _, preparedDevice := range preparedDevices {
 		if preparedDevice == nil {
 			logger.V(2).Info("Skipping nil prepared device entry during unprepare")
@@ -499,29 +1062,107 @@ func (s *Manager) unprepareDevices(preparedDevices drasriovtypes.PreparedDevices
 			logger.V(2).Info("Skipping prepared device with nil config during unprepare", "device", preparedDevice.PciAddress)
 			continue
 		}
+		// admin access leaves the device to the workload it is allocated to
+		if preparedDevice.AdminAccess {
+			continue
+		}
+		// reset while still bound to the driver of the claim, a crashed DPDK application may have
+		// left the VF half configured; a failed reset must not keep the device from being released
+		if s.resetOnUnprepare && s.checkHealthy(preparedDevice.Device.DeviceName) == nil {
+			if err := host.GetHelpers().ResetDevice(preparedDevice.PciAddress); err != nil {
+				logger.Error(err, "Failed to reset device", "device", preparedDevice.PciAddress)
+			}
+		}
+		// the representor is released before the VF changes hands
+		if err := s.releaseOVSBridge(ctx, preparedDevice); err != nil {
+			logger.Error(err, "Failed to delete OVS port of device", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if err := releaseTCOffload(ctx, preparedDevice); err != nil {
+			logger.Error(err, "Failed to release TC offload of device", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if err := s.releaseDPU(ctx, preparedDevice); err != nil {
+			logger.Error(err, "Failed to release device on the DPU", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if err := restoreOffloads(ctx, preparedDevice.PciAddress, preparedDevice.OriginalFeatures); err != nil {
+			logger.Error(err, "Failed to restore original features of device", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if err := restoreRingSizes(ctx, preparedDevice.PciAddress, preparedDevice.OriginalRingSizes); err != nil {
+			logger.Error(err, "Failed to restore original ring sizes of device", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if err := restoreNumQueues(ctx, preparedDevice.PciAddress, preparedDevice.OriginalNumQueues); err != nil {
+			logger.Error(err, "Failed to restore original queues of device", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if err := restoreInfiniBandGUIDs(ctx, preparedDevice.PciAddress, preparedDevice.OriginalNodeGUID, preparedDevice.OriginalPortGUID); err != nil {
+			logger.Error(err, "Failed to restore original GUIDs of device", "device", preparedDevice.PciAddress)
+			errs = append(errs, err)
+		}
+		if preparedDevice.OriginalVFSettings != nil {
+			if err := host.GetHelpers().SetVFSettings(preparedDevice.PciAddress, preparedDevice.OriginalVFSettings); err != nil {
+				logger.Error(err, "Failed to restore original settings of device", "device", preparedDevice.PciAddress)
+				errs = append(errs, fmt.Errorf("failed to restore original settings of device %s: %w", preparedDevice.PciAddress, err))
+			}
+		}
 		// Restore original driver if a driver change was made
 		if preparedDevice.Config.Driver != "" {
-			if err := host.GetHelpers().RestoreDeviceDriver(preparedDevice.PciAddress, preparedDevice.OriginalDriver); err != nil {
+			restoreTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationUnprepare, metrics.PhaseDriverBind))
+			err := host.GetHelpers().RestoreDeviceDriver(preparedDevice.PciAddress, preparedDevice.OriginalDriver)
+			restoreTimer.ObserveDuration()
+			if err != nil {
 				logger.Error(err, "Failed to restore original driver for device", "device", preparedDevice.PciAddress, "originalDriver", preparedDevice.OriginalDriver)
-				return fmt.Errorf("failed to restore original driver for device %s: %w", preparedDevice.PciAddress, err)
+				s.handleDriverError(ctx, preparedDevice.Device.DeviceName, preparedDevice.PciAddress, err)
+				errs = append(errs, fmt.Errorf("failed to restore original driver for device %s: %w", preparedDevice.PciAddress, err))
+				continue
 			}
 			logger.V(2).Info("Successfully restored original driver for device", "device", preparedDevice.PciAddress, "originalDriver", preparedDevice.OriginalDriver)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-// GetAdvertisedDevices returns only devices that are matched by a policy.
+// GetAdvertisedDevices returns only healthy devices that are matched by a policy and not
+// allocated by the SR-IOV device plugin, with their attributes named according to the configured
+// attribute schema and limited to the number of attributes the API server accepts.
 func (s *Manager) GetAdvertisedDevices() drasriovtypes.AllocatableDevices {
+	logger := klog.Background().WithName("GetAdvertisedDevices")
+	s.allocatableMu.RLock()
+	defer s.allocatableMu.RUnlock()
 	result := make(drasriovtypes.AllocatableDevices, len(s.policyAttrKeys))
 	for name := range s.policyAttrKeys {
+		if s.checkHealthy(name) != nil || s.allocatedByDevicePlugin(name) {
+			continue
+		}
 		if device, exists := s.allocatable[name]; exists {
+			device.Attributes = applyAttributeSchema(s.withCurrentDriver(name, device.Attributes), s.attributeSchema)
+			device, err := limitDeviceAttributes(logger, name, device, s.policyAttrKeys[name])
+			if err != nil {
+				// a single device over the limit would prevent publishing the whole slice
+				logger.Error(err, "Not advertising device")
+				continue
+			}
 			result[name] = device
 		}
 	}
 	return result
 }
 
+// GetPolicyAttributeKeys returns the names of the devices matched by a policy with the sorted names
+// of the attributes set on them by the policies, i.e. the resource filter applied on the node.
+func (s *Manager) GetPolicyAttributeKeys() map[string][]resourceapi.QualifiedName {
+	s.allocatableMu.RLock()
+	defer s.allocatableMu.RUnlock()
+	result := make(map[string][]resourceapi.QualifiedName, len(s.policyAttrKeys))
+	for name, keys := range s.policyAttrKeys {
+		result[name] = slices.Sorted(maps.Keys(keys))
+	}
+	return result
+}
+
 // UpdatePolicyDevices updates the set of advertised devices and their policy-applied attributes.
 // Keys in policyDevices are device names matched by policies (these will be advertised).
 // Values are additional attributes from resolved DeviceAttributes objects.
@@ -530,6 +1171,31 @@ func (s *Manager) UpdatePolicyDevices(ctx context.Context, policyDevices map[str
 	logger := klog.FromContext(ctx).WithName("UpdatePolicyDevices")
 	logger.V(2).Info("Updating policy devices", "policyDeviceCount", len(policyDevices))
 
+	// the devices are published once the lock is released, from a snapshot
+	changesMade, totalDevices, advertisedDevices := s.applyPolicyDevices(logger, policyDevices)
+	if !changesMade {
+		logger.V(2).Info("No changes to policy devices")
+		return nil
+	}
+
+	logger.Info("Policy devices updated", "totalDevices", totalDevices, "advertisedDevices", advertisedDevices)
+	if s.republishCallback != nil {
+		if err := s.republishCallback(ctx); err != nil {
+			logger.Error(err, "Failed to republish resources after policy update")
+			return fmt.Errorf("failed to republish resources: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyPolicyDevices applies the policy attributes of UpdatePolicyDevices under the lock of the
+// allocatable devices, and reports whether the advertised devices changed with the number of
+// devices and advertised devices.
+func (s *Manager) applyPolicyDevices(logger klog.Logger, policyDevices map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) (bool, int, int) {
+	s.allocatableMu.Lock()
+	defer s.allocatableMu.Unlock()
+
 	changesMade := false
 
 	// Clear policy attributes from devices no longer in the policy set
@@ -563,6 +1229,8 @@ func (s *Manager) UpdatePolicyDevices(ctx context.Context, policyDevices map[str
 			continue
 		}
 
+		// the attributes are copied, the devices returned before may still be in use
+		device.Attributes = maps.Clone(device.Attributes)
 		if device.Attributes == nil {
 			device.Attributes = make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute)
 		}
@@ -595,24 +1263,11 @@ func (s *Manager) UpdatePolicyDevices(ctx context.Context, policyDevices map[str
 		}
 		s.policyAttrKeys[deviceName] = newKeys
 	}
-
-	if !changesMade {
-		logger.V(2).Info("No changes to policy devices")
-		return nil
-	}
-
-	logger.Info("Policy devices updated", "totalDevices", len(s.allocatable), "advertisedDevices", len(s.policyAttrKeys))
-	if s.republishCallback != nil {
-		if err := s.republishCallback(ctx); err != nil {
-			logger.Error(err, "Failed to republish resources after policy update")
-			return fmt.Errorf("failed to republish resources: %w", err)
-		}
-	}
-
-	return nil
+	return changesMade, len(s.allocatable), len(s.policyAttrKeys)
 }
 
-// clearPolicyAttributes removes all policy-set attributes from a device.
+// clearPolicyAttributes removes all policy-set attributes from a device, the lock of the
+// allocatable devices must be held.
 func (s *Manager) clearPolicyAttributes(deviceName string) bool {
 	oldKeys, ok := s.policyAttrKeys[deviceName]
 	if !ok || len(oldKeys) == 0 {
@@ -626,6 +1281,7 @@ func (s *Manager) clearPolicyAttributes(deviceName string) bool {
 		return false
 	}
 
+	device.Attributes = maps.Clone(device.Attributes)
 	for key := range oldKeys {
 		delete(device.Attributes, key)
 	}
@@ -638,6 +1294,12 @@ func deviceAttributeEqual(a, b resourceapi.DeviceAttribute) bool {
 	return reflect.DeepEqual(a, b)
 }
 
+// SetNetAttachDefReader sets the cache net attach defs are read from before falling back to
+// the API server.
+func (s *Manager) SetNetAttachDefReader(reader client.Reader) {
+	s.netAttachDefReader = reader
+}
+
 // SetRepublishCallback sets the callback function to trigger resource republishing
 func (s *Manager) SetRepublishCallback(callback func(context.Context) error) {
 	s.republishCallback = callback
@@ -2,11 +2,15 @@ package devicestate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"go.uber.org/mock/gomock"
 
 	resourceapi "k8s.io/api/resource/v1"
@@ -26,6 +30,7 @@ import (
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
 	mock_host "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
 	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -56,6 +61,8 @@ var _ = Describe("Manager", Serial, func() {
 	BeforeEach(func() {
 		mockCtrl = gomock.NewController(GinkgoT())
 		mockHost = mock_host.NewMockInterface(mockCtrl)
+		// the original VF settings are covered by dedicated specs
+		mockHost.EXPECT().GetVFSettings(gomock.Any()).Return(nil, nil).AnyTimes()
 		// Save original helpers and replace with mock
 		_ = host.GetHelpers()
 		origHelpers = host.Helpers
@@ -156,6 +163,33 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(config).To(Equal(`{"cniVersion":"0.3.1","type":"sriov"}`))
 		})
 
+		It("should read the network attachment definition from the cache when set", func() {
+			newNetAttachDef := func(config string) *netattdefv1.NetworkAttachmentDefinition {
+				return &netattdefv1.NetworkAttachmentDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-net", Namespace: "test-ns"},
+					Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: config},
+				}
+			}
+			m := newTestManagerWithK8sClient(newNetAttachDef(`{"type":"live"}`))
+			m.SetNetAttachDefReader(newTestManagerWithK8sClient(newNetAttachDef(`{"type":"cached"}`)).k8sClient.Client)
+
+			config, err := m.getNetAttachDefRawConfig(context.Background(), "test-ns", "test-net")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(`{"type":"cached"}`))
+		})
+
+		It("should fall back to the API server when the cache misses the network attachment definition", func() {
+			m := newTestManagerWithK8sClient(&netattdefv1.NetworkAttachmentDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-net", Namespace: "test-ns"},
+				Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: `{"type":"live"}`},
+			})
+			m.SetNetAttachDefReader(newTestManagerWithK8sClient().k8sClient.Client)
+
+			config, err := m.getNetAttachDefRawConfig(context.Background(), "test-ns", "test-net")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(`{"type":"live"}`))
+		})
+
 		It("should return error when network attachment definition does not exist", func() {
 			m := newTestManagerWithK8sClient()
 
@@ -261,6 +295,36 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("removes the vhost-user socket dir of the pod", func() {
+			cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			socketDir := filepath.Join(GinkgoT().TempDir(), "pod-uid-123")
+			Expect(os.MkdirAll(socketDir, 0o775)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(socketDir, "vhost-user.sock"), nil, 0o600)).To(Succeed())
+
+			preparedDevices := drasriovtypes.PreparedDevices{
+				&drasriovtypes.PreparedDevice{
+					PciAddress:         "0000:01:00.1",
+					PodUID:             "pod-uid-123",
+					Config:             &configapi.VfConfig{},
+					VhostUserSocketDir: socketDir,
+				},
+				&drasriovtypes.PreparedDevice{
+					PciAddress:         "0000:01:00.2",
+					PodUID:             "pod-uid-123",
+					Config:             &configapi.VfConfig{},
+					VhostUserSocketDir: socketDir,
+				},
+			}
+
+			m := &Manager{
+				cdi: cdiHandler,
+			}
+
+			Expect(m.Unprepare("claim-uid-123", preparedDevices)).To(Succeed())
+			Expect(socketDir).NotTo(BeAnExistingFile())
+		})
+
 		It("should not panic when preparedDevices is empty", func() {
 			cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
 			Expect(err).NotTo(HaveOccurred())
@@ -476,6 +540,7 @@ var _ = Describe("Manager", Serial, func() {
 			}
 
 			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("/dev/vfio/1", "/dev/vfio/1", nil)
 			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{})
 			mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.1", "ixgbevf").Return(fmt.Errorf("restore failed"))
@@ -514,11 +579,17 @@ var _ = Describe("Manager", Serial, func() {
 				},
 			}
 
+			binds := phaseSampleCount(metrics.OperationPrepare, metrics.PhaseDriverBind)
+			restores := phaseSampleCount(metrics.OperationUnprepare, metrics.PhaseDriverBind)
+
 			ifNameIndex := 0
 			_, err = m.PrepareDevicesForClaim(context.Background(), &ifNameIndex, claim)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unable to create device-info files for claim"))
 			Expect(err.Error()).To(ContainSubstring("rollback failed"))
+			// the bind and the failed restore are both observed
+			Expect(phaseSampleCount(metrics.OperationPrepare, metrics.PhaseDriverBind)).To(Equal(binds + 1))
+			Expect(phaseSampleCount(metrics.OperationUnprepare, metrics.PhaseDriverBind)).To(Equal(restores + 1))
 		})
 
 		It("should include cleanup failure details when post-sync cleanup fails", func() {
@@ -545,6 +616,7 @@ var _ = Describe("Manager", Serial, func() {
 			}
 
 			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{})
 
 			claim := &resourceapi.ResourceClaim{
@@ -634,6 +706,7 @@ var _ = Describe("Manager", Serial, func() {
 			}
 
 			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 
 			ifNameIndex := 0
 			prepared, err := m.PrepareDevicesForClaim(context.Background(), &ifNameIndex, claim)
@@ -729,6 +802,7 @@ var _ = Describe("Manager", Serial, func() {
 			}
 
 			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", gomock.Any()).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 
 			ifNameIndex := 0
 			prepared, err := m.prepareDevices(context.Background(), &ifNameIndex, claim, resultsConfig)
@@ -807,6 +881,12 @@ var _ = Describe("Manager", Serial, func() {
 						consts.AttributePciAddress: {
 							StringValue: ptr.To("0000:01:00.1"),
 						},
+						consts.AttributePFName: {
+							StringValue: ptr.To("eth0"),
+						},
+						consts.AttributeVFID: {
+							IntValue: ptr.To(int64(0)),
+						},
 					},
 				},
 			}
@@ -845,6 +925,7 @@ var _ = Describe("Manager", Serial, func() {
 			}
 
 			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", vfConfig).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
 
 			ifNameIndex := 0
 			devices, err := m.prepareDevices(context.Background(), &ifNameIndex, claim, resultsConfig)
@@ -862,6 +943,83 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(claim.Status.Devices[0].Device).To(Equal("device1"))
 			Expect(claim.Status.Devices[0].Pool).To(Equal("pool1"))
 			Expect(claim.Status.Devices[0].Driver).To(Equal(consts.DriverName))
+			// the applied config along with the wiring of the VF
+			Expect(string(claim.Status.Devices[0].Data.Raw)).To(MatchJSON(
+				`{"netAttachDefName":"test-net","pfName":"eth0","vfID":0,"boundDriver":"iavf"}`))
+		})
+
+		It("should leave the devices of admin access claims untouched", func() {
+			cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+
+			m := newTestManagerWithK8sClient()
+			m.cdi = cdiHandler
+			m.defaultInterfacePrefix = "net"
+			m.drivers = map[string]string{"device1": "vfio-pci"}
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {
+							StringValue: ptr.To("0000:01:00.1"),
+						},
+					},
+				},
+			}
+
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "admin-claim",
+					Namespace: "admin-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{
+						Devices: resourceapi.DeviceAllocationResult{
+							Results: []resourceapi.DeviceRequestAllocationResult{
+								{
+									Driver:      consts.DriverName,
+									Device:      "device1",
+									Request:     "req1",
+									Pool:        "pool1",
+									AdminAccess: ptr.To(true),
+								},
+							},
+						},
+					},
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+
+			// the VfConfig is ignored: no net attach def lookup and no driver bind
+			resultsConfig := map[string]*configapi.VfConfig{
+				"req1": {Driver: "vfio-pci", NetAttachDefName: "missing-net"},
+			}
+
+			ifNameIndex := 0
+			devices, err := m.prepareDevices(context.Background(), &ifNameIndex, claim, resultsConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveLen(1))
+			Expect(ifNameIndex).To(Equal(0))
+
+			Expect(devices[0].AdminAccess).To(BeTrue())
+			Expect(devices[0].AttachesNetwork()).To(BeFalse())
+			Expect(devices[0].PciAddress).To(Equal("0000:01:00.1"))
+			Expect(devices[0].IfName).To(BeEmpty())
+			Expect(devices[0].NetAttachDefConfig).To(BeEmpty())
+			Expect(devices[0].Config.Driver).To(BeEmpty())
+			Expect(devices[0].ContainerEdits.DeviceNodes).To(BeEmpty())
+			Expect(devices[0].ContainerEdits.Env).To(ConsistOf(
+				"SRIOVNETWORK_VF_DEVICE_device1=0000:01:00.1",
+				"SRIOVNETWORK_device1_ADMIN_ACCESS=true",
+				"SRIOVNETWORK_device1_DRIVER=vfio-pci",
+			))
+
+			// unprepare neither resets nor rebinds the device
+			m.resetOnUnprepare = true
+			Expect(m.unprepareDevices(devices)).To(Succeed())
 		})
 	})
 
@@ -892,6 +1050,74 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(err.Error()).To(ContainSubstring("device nonexistent not found"))
 		})
 
+		It("should reject drivers that are not allowed", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
+
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+			mockHost.EXPECT().IsDpdkDriver("iavf").Return(false).AnyTimes()
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, &configapi.VfConfig{Driver: "pci-stub"}, result)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`invalid config of request req1: driver: Unsupported value: "pci-stub"`))
+		})
+
+		It("should reject invalid configs with the paths of the invalid fields", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeStandalone),
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
+			config := &configapi.VfConfig{
+				Driver:    "vfio-pci",
+				IfName:    "net1",
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","type":"sriov","vlan":4095}`)},
+			}
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).To(MatchError("invalid config of request req1: [" +
+				"ifName: Forbidden: ifName and the userspace driver vfio-pci are mutually exclusive, the VF has no network interface, " +
+				"cniConfig.vlan: Invalid value: 4095: must be within 0 and 4094]"))
+		})
+
+		It("should allow the default kernel driver of the device", func() {
+			m := &Manager{allowedDrivers: map[string]bool{"vfio-pci": true}}
+
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil).Times(2)
+			mockHost.EXPECT().IsDpdkDriver("iavf").Return(false)
+
+			Expect(m.validateConfig("req1", "0000:01:00.1", &configapi.VfConfig{Driver: "vfio-pci"})).To(Succeed())
+			Expect(m.validateConfig("req1", "0000:01:00.1", &configapi.VfConfig{Driver: consts.DefaultVFDriver})).To(Succeed())
+			Expect(m.validateConfig("req1", "0000:01:00.1", &configapi.VfConfig{Driver: "iavf"})).To(Succeed())
+			Expect(m.validateConfig("req1", "0000:01:00.1", &configapi.VfConfig{Driver: "uio_pci_generic"})).To(MatchError(
+				`invalid config of request req1: driver: Unsupported value: "uio_pci_generic": supported values: "default", "vfio-pci"`))
+		})
+
 		It("should use custom namespace from config", func() {
 			netAttachDef := &netattdefv1.NetworkAttachmentDefinition{
 				ObjectMeta: metav1.ObjectMeta{
@@ -941,6 +1167,7 @@ var _ = Describe("Manager", Serial, func() {
 			}
 
 			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
 
 			ifNameIndex := 0
 			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
@@ -950,120 +1177,931 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(preparedDevice.IfName).To(Equal("net0"))
 		})
 
-		It("restores the original driver when VFIO file lookup fails", func() {
-			m := &Manager{
-				allocatable: drasriovtypes.AllocatableDevices{
-					"device1": {
-						Name: "device1",
-						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+		It("chains the plugins of additional net attach defs after the primary ones", func() {
+			newNetAttachDef := func(name, namespace, config string) *netattdefv1.NetworkAttachmentDefinition {
+				return &netattdefv1.NetworkAttachmentDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: config},
+				}
+			}
+			m := newTestManagerWithK8sClient(
+				newNetAttachDef("test-net", "test-ns", `{"cniVersion":"1.0.0","name":"test-net","type":"sriov"}`),
+				newNetAttachDef("routes", "test-ns", `{"cniVersion":"1.0.0","name":"routes","type":"route-override","addroutes":[{"dst":"10.0.0.0/8"}]}`),
+				newNetAttachDef("tuning", "shared-ns", `{"cniVersion":"1.0.0","name":"tuning","plugins":[{"type":"tuning","mtu":9000},{"type":"sbr"}]}`),
+			)
+			m.defaultInterfacePrefix = "net"
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {
+							StringValue: ptr.To("0000:01:00.1"),
 						},
 					},
 				},
-				configurationMode: string(consts.ConfigurationModeMultus),
 			}
+
 			config := &configapi.VfConfig{
-				Driver: "vfio-pci",
+				NetAttachDefName: "test-net",
+				ChainedNetAttachDefs: []configapi.NetAttachDefReference{
+					{Name: "routes"},
+					{Name: "tuning", Namespace: "shared-ns"},
+				},
 			}
 			claim := &resourceapi.ResourceClaim{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "test-claim",
-					Namespace: "test-ns",
-					UID:       "claim-uid",
-				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
 				Status: resourceapi.ResourceClaimStatus{
-					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
-						{UID: "pod-uid"},
-					},
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}},
 				},
 			}
-			result := &resourceapi.DeviceRequestAllocationResult{
-				Device:  "device1",
-				Request: "req1",
-				Pool:    "pool1",
-			}
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
 
-			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
-			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("", "", fmt.Errorf("vfio lookup failed"))
-			mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.1", "ixgbevf").Return(nil)
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
 
 			ifNameIndex := 0
-			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("error getting VFIO device file"))
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+
+			var netConf struct {
+				Name    string                   `json:"name"`
+				Plugins []map[string]interface{} `json:"plugins"`
+			}
+			Expect(json.Unmarshal([]byte(preparedDevice.NetAttachDefConfig), &netConf)).To(Succeed())
+			Expect(netConf.Name).To(Equal("test-net"))
+			Expect(netConf.Plugins).To(HaveLen(4))
+			Expect(netConf.Plugins[0]).To(HaveKeyWithValue("type", "sriov"))
+			Expect(netConf.Plugins[0]).To(HaveKeyWithValue("deviceID", "0000:01:00.1"))
+			Expect(netConf.Plugins[1]).To(HaveKeyWithValue("type", "route-override"))
+			Expect(netConf.Plugins[2]).To(HaveKeyWithValue("type", "tuning"))
+			Expect(netConf.Plugins[3]).To(HaveKeyWithValue("type", "sbr"))
 		})
-	})
 
-	Context("UpdatePolicyDevices", func() {
-		It("advertises devices present in the map and applies attributes", func() {
-			s := &Manager{
-				allocatable: map[string]resourceapi.Device{
-					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
-					"devB": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
+		It("adds the deviceID to the offloading plugins of VFs of switchdev PFs", func() {
+			m := newTestManagerWithK8sClient(
+				&netattdefv1.NetworkAttachmentDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-net", Namespace: "test-ns"},
+					Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: `{"cniVersion":"1.0.0","name":"test-net","plugins":[{"type":"sriov"},{"type":"ovs","bridge":"br-ex"}]}`},
+				},
+			)
+			m.defaultInterfacePrefix = "net"
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress:  {StringValue: ptr.To("0000:01:00.1")},
+						consts.AttributeEswitchMode: {StringValue: ptr.To("switchdev")},
+					},
 				},
 			}
-
-			resName := "vendor.com/resA"
-			policyDevices := map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-				"devA": {
-					consts.AttributeResourceName: {StringValue: &resName},
+			config := &configapi.VfConfig{NetAttachDefName: "test-net"}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}},
 				},
 			}
-			err := s.UpdatePolicyDevices(context.Background(), policyDevices)
-			Expect(err).ToNot(HaveOccurred())
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
 
-			Expect(s.policyAttrKeys).To(HaveKey("devA"))
-			Expect(s.policyAttrKeys).ToNot(HaveKey("devB"))
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
 
-			val := s.allocatable["devA"].Attributes[consts.AttributeResourceName].StringValue
-			Expect(val).ToNot(BeNil())
-			Expect(*val).To(Equal("vendor.com/resA"))
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+
+			var netConf struct {
+				Plugins []map[string]interface{} `json:"plugins"`
+			}
+			Expect(json.Unmarshal([]byte(preparedDevice.NetAttachDefConfig), &netConf)).To(Succeed())
+			Expect(netConf.Plugins[0]).To(HaveKeyWithValue("deviceID", "0000:01:00.1"))
+			Expect(netConf.Plugins[1]).To(HaveKeyWithValue("deviceID", "0000:01:00.1"))
 		})
 
-		It("clears policy attributes when device is removed from map", func() {
-			resName := "vendor.com/resA"
-			s := &Manager{
-				allocatable: map[string]resourceapi.Device{
-					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-						consts.AttributeResourceName: {StringValue: &resName},
-						consts.AttributeVendorID:     {StringValue: ptr.To("8086")},
-					}},
+		It("looks up the net attach def in the default namespace unless the config sets one", func() {
+			m := newTestManagerWithK8sClient(
+				&netattdefv1.NetworkAttachmentDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-net", Namespace: "nads"},
+					Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: `{"cniVersion":"1.0.0","name":"central","type":"sriov"}`},
 				},
-				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
-					"devA": {consts.AttributeResourceName: true},
+				&netattdefv1.NetworkAttachmentDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-net", Namespace: "other-ns"},
+					Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: `{"cniVersion":"1.0.0","name":"other","type":"sriov"}`},
+				},
+			)
+			m.defaultNetAttachDefNamespace = "nads"
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+					},
+				},
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}},
 				},
 			}
 
-			// Remove devA from policy
-			err := s.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{})
-			Expect(err).ToNot(HaveOccurred())
+			for namespace, name := range map[string]string{"": "central", "other-ns": "other"} {
+				config := &configapi.VfConfig{NetAttachDefName: "test-net", NetAttachDefNamespace: namespace}
+				mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+				mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
 
-			Expect(s.policyAttrKeys).To(BeEmpty())
-			// Policy attribute (resourceName) should be cleared
-			_, exists := s.allocatable["devA"].Attributes[consts.AttributeResourceName]
-			Expect(exists).To(BeFalse())
-			// Discovery attribute (vendorID) should still exist
-			_, exists = s.allocatable["devA"].Attributes[consts.AttributeVendorID]
-			Expect(exists).To(BeTrue())
+				ifNameIndex := 0
+				preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, &resourceapi.DeviceRequestAllocationResult{Device: "device1"})
+				Expect(err).NotTo(HaveOccurred())
+
+				var netConf map[string]interface{}
+				Expect(json.Unmarshal([]byte(preparedDevice.NetAttachDefConfig), &netConf)).To(Succeed())
+				Expect(netConf).To(HaveKeyWithValue("name", name))
+			}
 		})
 
-		It("GetAdvertisedDevices returns only advertised devices", func() {
-			s := &Manager{
-				allocatable: map[string]resourceapi.Device{
-					"devA": {},
-					"devB": {},
+		It("uses an inline CNI config instead of a net attach def", func() {
+			// no net attach def exists, the inline config must be used as is
+			m := newTestManagerWithK8sClient()
+			m.defaultInterfacePrefix = "net"
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+					},
 				},
-				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
-					"devA": {},
+			}
+			config := &configapi.VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","name":"inline","type":"sriov"}`)},
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}},
 				},
 			}
 
-			advertised := s.GetAdvertisedDevices()
-			Expect(advertised).To(HaveLen(1))
-			Expect(advertised).To(HaveKey("devA"))
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, &resourceapi.DeviceRequestAllocationResult{Device: "device1"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var netConf map[string]interface{}
+			Expect(json.Unmarshal([]byte(preparedDevice.NetAttachDefConfig), &netConf)).To(Succeed())
+			Expect(netConf).To(HaveKeyWithValue("name", "inline"))
+			Expect(netConf).To(HaveKeyWithValue("type", "sriov"))
+			Expect(netConf).To(HaveKeyWithValue("deviceID", "0000:01:00.1"))
 		})
 
-		It("should trigger republish callback when changes are made", func() {
+		It("merges the IPAM override into the net attach def config", func() {
+			m := newTestManagerWithK8sClient()
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+					},
+				},
+			}
+			config := &configapi.VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0","name":"inline","type":"sriov","ipam":{"type":"host-local"}}`)},
+				IPAM:      &configapi.IPAMOverride{Addresses: []string{"10.0.0.5/24"}, Gateway: "10.0.0.1"},
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}},
+				},
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, &resourceapi.DeviceRequestAllocationResult{Device: "device1"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var netConf map[string]interface{}
+			Expect(json.Unmarshal([]byte(preparedDevice.NetAttachDefConfig), &netConf)).To(Succeed())
+			Expect(netConf).To(HaveKeyWithValue("deviceID", "0000:01:00.1"))
+			Expect(netConf["ipam"]).To(Equal(map[string]interface{}{
+				"type":      "static",
+				"addresses": []interface{}{map[string]interface{}{"address": "10.0.0.5/24", "gateway": "10.0.0.1"}},
+			}))
+		})
+
+		It("rejects an invalid inline CNI config", func() {
+			m := newTestManagerWithK8sClient()
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+					},
+				},
+			}
+			config := &configapi.VfConfig{
+				CNIConfig: &runtime.RawExtension{Raw: []byte(`{"cniVersion":"1.0.0"}`)},
+			}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns"}}
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, &resourceapi.DeviceRequestAllocationResult{Device: "device1"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid inline cni config"))
+		})
+
+		It("fails when a chained net attach def does not exist", func() {
+			m := newTestManagerWithK8sClient(&netattdefv1.NetworkAttachmentDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-net", Namespace: "test-ns"},
+				Spec:       netattdefv1.NetworkAttachmentDefinitionSpec{Config: `{"cniVersion":"1.0.0","type":"sriov"}`},
+			})
+			m.allocatable = drasriovtypes.AllocatableDevices{
+				"device1": resourceapi.Device{
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+					},
+				},
+			}
+			config := &configapi.VfConfig{
+				NetAttachDefName:     "test-net",
+				ChainedNetAttachDefs: []configapi.NetAttachDefReference{{Name: "missing"}},
+			}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns"}}
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, &resourceapi.DeviceRequestAllocationResult{Device: "device1"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error getting chained net attach def raw config"))
+		})
+
+		It("restores the original driver when VFIO file lookup fails", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			config := &configapi.VfConfig{
+				Driver: "vfio-pci",
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("", "", fmt.Errorf("vfio lookup failed"))
+			mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.1", "ixgbevf").Return(nil)
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error getting VFIO device file"))
+		})
+
+		It("keeps the no-IOMMU group name in the container in vfio no-IOMMU mode", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+				vfioNoIOMMU:       true,
+			}
+			config := &configapi.VfConfig{
+				Driver: "vfio-pci",
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("/dev/vfio/noiommu-1", "/dev/vfio/1", nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.DeviceNodes[0].Path).To(Equal("/dev/vfio/noiommu-1"))
+			Expect(preparedDevice.ContainerEdits.DeviceNodes[0].HostPath).To(Equal("/dev/vfio/noiommu-1"))
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElement(HaveSuffix("_VFIO_DEVICE=/dev/vfio/noiommu-1")))
+		})
+
+		It("sets the permissions of the VFIO device nodes", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode:      string(consts.ConfigurationModeMultus),
+				defaultVFIOPermissions: configapi.DevicePermissions{FileMode: ptr.To(int32(0o660))},
+			}
+			config := &configapi.VfConfig{
+				Driver:                "vfio-pci",
+				VFIODevicePermissions: &configapi.DevicePermissions{GID: ptr.To(uint32(1000))},
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("/dev/vfio/1", "/dev/vfio/1", nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.DeviceNodes).To(HaveLen(2))
+			for _, deviceNode := range preparedDevice.ContainerEdits.DeviceNodes {
+				Expect(*deviceNode.FileMode).To(Equal(os.FileMode(0o660)))
+				Expect(deviceNode.UID).To(BeNil())
+				Expect(*deviceNode.GID).To(Equal(uint32(1000)))
+			}
+		})
+
+		It("passes VFs through to KubeVirt without attaching them to a network", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode:      string(consts.ConfigurationModeStandalone),
+				defaultInterfacePrefix: "net",
+			}
+			config := &configapi.VfConfig{KubeVirt: &configapi.KubeVirtConfig{ResourceName: "intel.com/sriov"}}
+			config.Normalize()
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("/dev/vfio/12", "/dev/vfio/12", nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Driver).To(Equal("vfio-pci"))
+			Expect(preparedDevice.NetAttachDefConfig).To(BeEmpty())
+			Expect(preparedDevice.IfName).To(BeEmpty())
+			Expect(ifNameIndex).To(Equal(0))
+			Expect(preparedDevice.ContainerEdits.DeviceNodes[0].Path).To(Equal("/dev/vfio/12"))
+			Expect(preparedDevice.ContainerEdits.DeviceNodes[0].HostPath).To(Equal("/dev/vfio/12"))
+		})
+
+		It("refuses KubeVirt VFs bound to another driver than vfio-pci", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+				allowedDrivers:    map[string]bool{"vfio-pci": true, "uio_pci_generic": true},
+			}
+			config := &configapi.VfConfig{Driver: "uio_pci_generic", KubeVirt: &configapi.KubeVirtConfig{}}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"}}
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).To(MatchError(ContainSubstring("kubeVirt requires the vfio-pci driver")))
+		})
+
+		It("adds the uio device node of devices bound to a uio driver", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+				allowedDrivers:    map[string]bool{"vfio-pci": true, "uio_pci_generic": true},
+			}
+			config := &configapi.VfConfig{
+				Driver: "uio_pci_generic",
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetUIODeviceFile("0000:01:00.1").Return("/dev/uio3", nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.DeviceNodes).To(HaveLen(1))
+			Expect(preparedDevice.ContainerEdits.DeviceNodes[0].Path).To(Equal("/dev/uio3"))
+			Expect(preparedDevice.ContainerEdits.DeviceNodes[0].HostPath).To(Equal("/dev/uio3"))
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElement("SRIOVNETWORK_device1_UIO_DEVICE=/dev/uio3"))
+		})
+
+		It("mounts the hugepages of devices bound to a DPDK driver", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			config := &configapi.VfConfig{
+				Driver:            "vfio-pci",
+				AddHugepagesMount: true,
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetVFIODeviceFile("0000:01:00.1").Return("/dev/vfio/1", "/dev/vfio/1", nil)
+			mockHost.EXPECT().IsDpdkDriver("vfio-pci").Return(true)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.Mounts).To(HaveLen(1))
+			Expect(preparedDevice.ContainerEdits.Mounts[0].HostPath).To(Equal("/dev/hugepages"))
+			Expect(preparedDevice.ContainerEdits.Mounts[0].ContainerPath).To(Equal("/dev/hugepages"))
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElement("SRIOVNETWORK_device1_HUGEPAGES_DIR=/dev/hugepages"))
+		})
+
+		It("does not mount the hugepages of devices using a kernel driver", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			config := &configapi.VfConfig{
+				AddHugepagesMount: true,
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().IsDpdkDriver("").Return(false)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.Mounts).To(BeEmpty())
+			Expect(preparedDevice.ContainerEdits.Env).NotTo(ContainElement(HavePrefix("SRIOVNETWORK_device1_HUGEPAGES_DIR")))
+		})
+
+		It("mounts a vhost-user socket dir private to the pod", func() {
+			root := GinkgoT().TempDir()
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode:   string(consts.ConfigurationModeMultus),
+				vhostUserSocketRoot: root,
+			}
+			config := &configapi.VfConfig{
+				VhostUserSocketDir: &configapi.VhostUserSocketDir{
+					Permissions: &configapi.DevicePermissions{FileMode: ptr.To(int32(0o770)), GID: ptr.To(uint32(os.Getgid()))},
+				},
+			}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			hostPath := filepath.Join(root, "pod-uid")
+			Expect(preparedDevice.VhostUserSocketDir).To(Equal(hostPath))
+			Expect(preparedDevice.ContainerEdits.Mounts).To(HaveLen(1))
+			Expect(preparedDevice.ContainerEdits.Mounts[0].HostPath).To(Equal(hostPath))
+			Expect(preparedDevice.ContainerEdits.Mounts[0].ContainerPath).To(Equal(consts.DefaultVhostUserSocketContainerPath))
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElement("SRIOVNETWORK_device1_VHOST_USER_SOCKET_DIR=/var/run/vhost-user"))
+
+			info, err := os.Stat(hostPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o770)))
+		})
+
+		It("keeps the devices of a shared claim out of the pods", func() {
+			root := GinkgoT().TempDir()
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode:   string(consts.ConfigurationModeMultus),
+				vhostUserSocketRoot: root,
+			}
+			config := &configapi.VfConfig{VhostUserSocketDir: &configapi.VhostUserSocketDir{}}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}, {UID: "other-pod-uid"}},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.PodUID).To(BeEmpty())
+			// no pod level spec, which belongs to a single pod
+			Expect(preparedDevice.Device.CDIDeviceIDs).To(HaveLen(1))
+			Expect(preparedDevice.VhostUserSocketDir).To(Equal(filepath.Join(root, "claim-uid")))
+		})
+
+		It("adds the environment variables of the env templates", func() {
+			envTemplates, err := ParseEnvTemplates(`[{"name": "PCIDEVICE_{{ envName .ResourceName }}", "value": "{{ .PCIAddress }}"}]`)
+			Expect(err).NotTo(HaveOccurred())
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress:   {StringValue: ptr.To("0000:01:00.1")},
+							consts.AttributeResourceName: {StringValue: ptr.To("intel.com/sriov-net")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+				envTemplates:      envTemplates,
+			}
+			config := &configapi.VfConfig{}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElement("PCIDEVICE_INTEL_COM_SRIOV_NET=0000:01:00.1"))
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElement("SRIOVNETWORK_VF_DEVICE_device1=0000:01:00.1"))
+		})
+
+		It("adds the environment variables of the env of the config", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			config := &configapi.VfConfig{Env: map[string]string{
+				"APP_QUEUES_{{ .EnvDeviceName }}": "4",
+				"APP_CLAIM":                       "{{ .ClaimNamespace }}/{{ .ClaimName }}",
+			}}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-claim",
+					Namespace: "test-ns",
+					UID:       "claim-uid",
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+						{UID: "pod-uid"},
+					},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{
+				Device:  "device1",
+				Request: "req1",
+				Pool:    "pool1",
+			}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+
+			ifNameIndex := 0
+			preparedDevice, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preparedDevice.ContainerEdits.Env).To(ContainElements("APP_CLAIM=test-ns/test-claim", "APP_QUEUES_device1=4"))
+		})
+
+		It("fails when the env of the config uses a reserved name", func() {
+			m := &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			config := &configapi.VfConfig{Env: map[string]string{"SRIOVNETWORK_VF_DEVICE_{{ .DeviceName }}": "0000:02:00.1"}}
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+				Status: resourceapi.ResourceClaimStatus{
+					ReservedFor: []resourceapi.ResourceClaimConsumerReference{{UID: "pod-uid"}},
+				},
+			}
+			result := &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
+
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("ixgbevf", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return(config.Driver, nil)
+			mockHost.EXPECT().GetRDMADevicesForPCI("0000:01:00.1").Return([]string{}).AnyTimes()
+			mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.1", "ixgbevf").Return(nil).AnyTimes()
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).To(MatchError(ContainSubstring("reserved for the driver")))
+		})
+	})
+
+	Context("UpdatePolicyDevices", func() {
+		It("advertises devices present in the map and applies attributes", func() {
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
+					"devB": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
+				},
+			}
+
+			resName := "vendor.com/resA"
+			policyDevices := map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"devA": {
+					consts.AttributeResourceName: {StringValue: &resName},
+				},
+			}
+			err := s.UpdatePolicyDevices(context.Background(), policyDevices)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(s.policyAttrKeys).To(HaveKey("devA"))
+			Expect(s.policyAttrKeys).ToNot(HaveKey("devB"))
+			Expect(s.GetPolicyAttributeKeys()).To(Equal(map[string][]resourceapi.QualifiedName{
+				"devA": {consts.AttributeResourceName},
+			}))
+
+			val := s.allocatable["devA"].Attributes[consts.AttributeResourceName].StringValue
+			Expect(val).ToNot(BeNil())
+			Expect(*val).To(Equal("vendor.com/resA"))
+		})
+
+		It("clears policy attributes when device is removed from map", func() {
+			resName := "vendor.com/resA"
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributeResourceName: {StringValue: &resName},
+						consts.AttributeVendorID:     {StringValue: ptr.To("8086")},
+					}},
+				},
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+					"devA": {consts.AttributeResourceName: true},
+				},
+			}
+
+			// Remove devA from policy
+			err := s.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(s.policyAttrKeys).To(BeEmpty())
+			// Policy attribute (resourceName) should be cleared
+			_, exists := s.allocatable["devA"].Attributes[consts.AttributeResourceName]
+			Expect(exists).To(BeFalse())
+			// Discovery attribute (vendorID) should still exist
+			_, exists = s.allocatable["devA"].Attributes[consts.AttributeVendorID]
+			Expect(exists).To(BeTrue())
+		})
+
+		It("GetAdvertisedDevices returns only advertised devices", func() {
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {},
+					"devB": {},
+				},
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+					"devA": {},
+				},
+			}
+
+			advertised := s.GetAdvertisedDevices()
+			Expect(advertised).To(HaveLen(1))
+			Expect(advertised).To(HaveKey("devA"))
+		})
+
+		It("GetAdvertisedDevices publishes attributes with the configured schema", func() {
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePFName: {StringValue: ptr.To("eth0")},
+					}},
+				},
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+					"devA": {},
+				},
+				attributeSchema: consts.AttributeSchemaV2,
+			}
+
+			advertised := s.GetAdvertisedDevices()
+			Expect(advertised["devA"].Attributes).To(HaveKey(resourceapi.QualifiedName(consts.AttributeV2PFName)))
+			Expect(advertised["devA"].Attributes).ToNot(HaveKey(resourceapi.QualifiedName(consts.AttributePFName)))
+			// the discovered device keeps the v1 names used internally
+			Expect(s.allocatable["devA"].Attributes).To(HaveKey(resourceapi.QualifiedName(consts.AttributePFName)))
+		})
+
+		It("should trigger republish callback when changes are made", func() {
 			callbackCalled := false
 			callback := func(ctx context.Context) error {
 				callbackCalled = true
@@ -1120,6 +2158,77 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(callbackCalled).To(BeFalse())
 		})
 
+		It("republishes without holding the lock of the allocatable devices", func() {
+			var republished drasriovtypes.AllocatableDevices
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {},
+				},
+			}
+			s.republishCallback = func(ctx context.Context) error {
+				republished = s.GetAdvertisedDevices()
+				return nil
+			}
+
+			Expect(s.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"devA": {consts.AttributeResourceName: {StringValue: ptr.To("vendor.com/resA")}},
+			})).To(Succeed())
+			Expect(republished).To(HaveKey("devA"))
+		})
+
+		It("does not modify the attributes of the devices read before an update", func() {
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
+				},
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+					"devA": {},
+				},
+			}
+			before := s.GetAdvertisedDevices()
+
+			Expect(s.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"devA": {consts.AttributeResourceName: {StringValue: ptr.To("vendor.com/resA")}},
+			})).To(Succeed())
+			Expect(before["devA"].Attributes).To(BeEmpty())
+			Expect(s.GetAdvertisedDevices()["devA"].Attributes).To(HaveKey(consts.AttributeResourceName))
+		})
+
+		It("serves the advertised devices while policies are updated", func() {
+			s := &Manager{
+				allocatable: map[string]resourceapi.Device{
+					"devA": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
+					"devB": {Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}},
+				},
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				for i := range 100 {
+					policyDevices := map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"devA": {consts.AttributeResourceName: {StringValue: ptr.To(fmt.Sprintf("vendor.com/res%d", i))}},
+					}
+					if i%2 == 0 {
+						policyDevices["devB"] = map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+					}
+					Expect(s.UpdatePolicyDevices(context.Background(), policyDevices)).To(Succeed())
+				}
+			}()
+			for {
+				select {
+				case <-done:
+					Expect(s.GetAdvertisedDevices()).To(HaveKey("devA"))
+					return
+				default:
+					for _, device := range s.GetAdvertisedDevices() {
+						_ = len(device.Attributes)
+					}
+					_ = s.GetPolicyAttributeKeys()
+				}
+			}
+		})
+
 		It("should return error when republish callback fails", func() {
 			callback := func(ctx context.Context) error {
 				return fmt.Errorf("republish failed")
@@ -1152,7 +2261,7 @@ var _ = Describe("Manager", Serial, func() {
 				},
 			}
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), nonRdmaDevice, "0000:08:00.1", "device-1")
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), nonRdmaDevice, "0000:08:00.1", "device-1")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(deviceNodes).To(BeEmpty())
 			Expect(envs).To(BeEmpty())
@@ -1170,6 +2279,8 @@ var _ = Describe("Manager", Serial, func() {
 		BeforeEach(func() {
 			mockCtrl = gomock.NewController(GinkgoT())
 			mockHost = mock_host.NewMockInterface(mockCtrl)
+			// the original VF settings are covered by dedicated specs
+			mockHost.EXPECT().GetVFSettings(gomock.Any()).Return(nil, nil).AnyTimes()
 			_ = host.GetHelpers()
 			origHelpers = host.Helpers
 			host.Helpers = mockHost
@@ -1201,9 +2312,10 @@ var _ = Describe("Manager", Serial, func() {
 				"/dev/infiniband/rdma_cm",
 			}, nil)
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
+			rdmaDevice, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
 
 			Expect(err).ToNot(HaveOccurred())
+			Expect(rdmaDevice).To(Equal("mlx5_0"))
 			Expect(deviceNodes).To(HaveLen(4))
 			Expect(deviceNodes[0].Path).To(Equal("/dev/infiniband/uverbs0"))
 			Expect(deviceNodes[0].HostPath).To(Equal("/dev/infiniband/uverbs0"))
@@ -1220,6 +2332,49 @@ var _ = Describe("Manager", Serial, func() {
 			Expect(envs).To(ContainElement("SRIOVNETWORK_device_1_RDMA_DEVICE=mlx5_0"))
 		})
 
+		It("should add the RDMA connection manager device when it is missing", func() {
+			pciAddress := "0000:08:00.1"
+			rdmaDeviceName := "mlx5_0"
+
+			deviceInfo := resourceapi.Device{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributeRDMACapable: {BoolValue: ptr.To(true)},
+				},
+			}
+
+			mockHost.EXPECT().GetRDMADevicesForPCI(pciAddress).Return([]string{rdmaDeviceName})
+			mockHost.EXPECT().GetRDMACharDevices(rdmaDeviceName).Return([]string{"/dev/infiniband/uverbs0"}, nil)
+			mockHost.EXPECT().EnsureRDMACMModuleLoaded().Return(nil)
+
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, "device-1")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deviceNodes).To(HaveLen(2))
+			Expect(deviceNodes[1].Path).To(Equal("/dev/infiniband/rdma_cm"))
+			Expect(envs).To(ContainElement("SRIOVNETWORK_device_1_RDMA_CM=/dev/infiniband/rdma_cm"))
+		})
+
+		It("should prepare the device without the RDMA connection manager when rdma_ucm cannot be loaded", func() {
+			pciAddress := "0000:08:00.1"
+			rdmaDeviceName := "mlx5_0"
+
+			deviceInfo := resourceapi.Device{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributeRDMACapable: {BoolValue: ptr.To(true)},
+				},
+			}
+
+			mockHost.EXPECT().GetRDMADevicesForPCI(pciAddress).Return([]string{rdmaDeviceName})
+			mockHost.EXPECT().GetRDMACharDevices(rdmaDeviceName).Return([]string{"/dev/infiniband/uverbs0"}, nil)
+			mockHost.EXPECT().EnsureRDMACMModuleLoaded().Return(fmt.Errorf("module rdma_ucm not found"))
+
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, "device-1")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deviceNodes).To(HaveLen(1))
+			Expect(envs).NotTo(ContainElement(HavePrefix("SRIOVNETWORK_device_1_RDMA_CM")))
+		})
+
 		It("should return error when multiple RDMA devices found", func() {
 			pciAddress := "0000:08:00.1"
 			deviceName := "device-1"
@@ -1232,7 +2387,7 @@ var _ = Describe("Manager", Serial, func() {
 
 			mockHost.EXPECT().GetRDMADevicesForPCI(pciAddress).Return([]string{"mlx5_0", "mlx5_1"})
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("expected exactly one RDMA device"))
@@ -1250,7 +2405,7 @@ var _ = Describe("Manager", Serial, func() {
 				},
 			}
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(deviceNodes).To(BeEmpty())
@@ -1269,7 +2424,7 @@ var _ = Describe("Manager", Serial, func() {
 
 			mockHost.EXPECT().GetRDMADevicesForPCI(pciAddress).Return([]string{})
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("no RDMA devices found"))
@@ -1291,7 +2446,7 @@ var _ = Describe("Manager", Serial, func() {
 			mockHost.EXPECT().GetRDMADevicesForPCI(pciAddress).Return([]string{rdmaDeviceName})
 			mockHost.EXPECT().GetRDMACharDevices(rdmaDeviceName).Return(nil, fmt.Errorf("failed to get char devices"))
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to get char devices"))
@@ -1313,7 +2468,7 @@ var _ = Describe("Manager", Serial, func() {
 			mockHost.EXPECT().GetRDMADevicesForPCI(pciAddress).Return([]string{rdmaDeviceName})
 			mockHost.EXPECT().GetRDMACharDevices(rdmaDeviceName).Return([]string{}, nil)
 
-			deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
+			_, deviceNodes, envs, err := manager.handleRDMADevice(context.Background(), deviceInfo, pciAddress, deviceName)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("no RDMA character devices found"))
@@ -1322,6 +2477,65 @@ var _ = Describe("Manager", Serial, func() {
 		})
 	})
 
+	Context("numaEnvs", func() {
+		var (
+			mockCtrl    *gomock.Controller
+			mockHost    *mock_host.MockInterface
+			origHelpers host.Interface
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockHost = mock_host.NewMockInterface(mockCtrl)
+			_ = host.GetHelpers()
+			origHelpers = host.Helpers
+			host.Helpers = mockHost
+		})
+
+		AfterEach(func() {
+			host.Helpers = origHelpers
+			mockCtrl.Finish()
+		})
+
+		It("should describe the NUMA node of the device and its CPUs", func() {
+			deviceInfo := resourceapi.Device{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributeNUMANode: {IntValue: ptr.To(int64(1))},
+				},
+			}
+			mockHost.EXPECT().GetNumaNodeCPUs("1").Return("8-15,24-31", nil)
+
+			Expect(numaEnvs(context.Background(), deviceInfo, "0000:08:00.1", "device-1")).To(Equal([]string{
+				"SRIOVNETWORK_device_1_NUMA_NODE=1",
+				"SRIOVNETWORK_device_1_NUMA_CPUS=8-15,24-31",
+			}))
+		})
+
+		It("should skip devices without NUMA affinity", func() {
+			deviceInfo := resourceapi.Device{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributeNUMANode: {IntValue: ptr.To(int64(-1))},
+				},
+			}
+
+			Expect(numaEnvs(context.Background(), deviceInfo, "0000:08:00.1", "device-1")).To(BeEmpty())
+			Expect(numaEnvs(context.Background(), resourceapi.Device{}, "0000:08:00.1", "device-1")).To(BeEmpty())
+		})
+
+		It("should keep the NUMA node when its CPUs cannot be read", func() {
+			deviceInfo := resourceapi.Device{
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					consts.AttributeNUMANode: {IntValue: ptr.To(int64(0))},
+				},
+			}
+			mockHost.EXPECT().GetNumaNodeCPUs("0").Return("", fmt.Errorf("no such file"))
+
+			Expect(numaEnvs(context.Background(), deviceInfo, "0000:08:00.1", "device-1")).To(Equal([]string{
+				"SRIOVNETWORK_device_1_NUMA_NODE=0",
+			}))
+		})
+	})
+
 	Context("MULTUS/STANDALONE behavior", func() {
 		It("skips ifName generation and NetAttachDef fetch in MULTUS", func() {
 			tmp, err := os.MkdirTemp("", "cdi-root")
@@ -1354,6 +2568,7 @@ var _ = Describe("Manager", Serial, func() {
 			ifIndex := 0
 			res := &resourceapi.DeviceRequestAllocationResult{Device: "devA", Pool: "pool1", Request: "req1"}
 			mockHost.EXPECT().BindDeviceDriver("0000:00:00.1", cfg).Return("", nil)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:00:00.1").Return(cfg.Driver, nil)
 
 			pd, err := s.applyConfigOnDevice(context.Background(), &ifIndex, claim, cfg, res)
 			Expect(err).ToNot(HaveOccurred())
@@ -1368,3 +2583,10 @@ var _ = Describe("Manager", Serial, func() {
 })
 
 func strPtr(s string) *string { return &s }
+
+// phaseSampleCount returns the number of durations observed for a phase of the claim operations.
+func phaseSampleCount(operation, phase string) uint64 {
+	metric := &dto.Metric{}
+	Expect(metrics.PhaseDuration.WithLabelValues(operation, phase).(prometheus.Histogram).Write(metric)).To(Succeed())
+	return metric.GetHistogram().GetSampleCount()
+}
@@ -11,17 +11,20 @@ import (
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 )
 
-// GetOpaqueDeviceConfigs returns an ordered list of the configs contained in possibleConfigs for this driver.
+// getMapOfOpaqueDeviceConfigForDevice merges the configs of possibleConfigs for this driver into
+// one VfConfig per request.
 //
 // Configs can either come from the resource claim itself or from the device
 // class associated with the request. Configs coming directly from the resource
-// claim take precedence over configs coming from the device class. Moreover,
-// configs found later in the list of configs attached to its source take
-// precedence over configs found earlier in the list for that source.
+// claim take precedence over configs coming from the device class, wherever
+// they are in the list. Moreover, configs found later in the list of configs
+// attached to its source take precedence over configs found earlier in the
+// list for that source.
 //
-// All of the configs relevant to the driver from the list of possibleConfigs
-// will be returned in order of precedence (from lowest to highest). If no
-// configs are found, nil is returned.
+// The configs are merged with VfConfig.Override, from the lowest precedence to
+// the highest: a field set by a config overrides the same field of the configs
+// before it, the fields it does not set are kept. Requests without any config
+// are not in the returned map.
 func getMapOfOpaqueDeviceConfigForDevice(
 	decoder runtime.Decoder,
 	possibleConfigs []resourceapi.DeviceAllocationConfiguration,
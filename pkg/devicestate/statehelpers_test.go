@@ -185,6 +185,69 @@ var _ = Describe("getMapOfOpaqueDeviceConfigForDevice", func() {
 			Expect(result["request1"].IfName).To(Equal("eth0"))
 		})
 
+		It("should merge the fields of class and claim configs whatever their order", func() {
+			classConfig := &configapi.VfConfig{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "sriovnetwork.k8snetworkplumbingwg.io/v1alpha1",
+					Kind:       "VfConfig",
+				},
+				Driver:                "vfio-pci",
+				NetAttachDefName:      "class-net",
+				NetAttachDefNamespace: "class-ns",
+				AddHugepagesMount:     true,
+			}
+			claimConfig := &configapi.VfConfig{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "sriovnetwork.k8snetworkplumbingwg.io/v1alpha1",
+					Kind:       "VfConfig",
+				},
+				NetAttachDefName: "claim-net",
+				AddVhostMount:    true,
+			}
+			classEncoded, err := runtime.Encode(configapi.Decoder.(runtime.Encoder), classConfig)
+			Expect(err).NotTo(HaveOccurred())
+			claimEncoded, err := runtime.Encode(configapi.Decoder.(runtime.Encoder), claimConfig)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the claim config is listed first, it still takes precedence
+			configs := []resourceapi.DeviceAllocationConfiguration{
+				{
+					Source:   resourceapi.AllocationConfigSourceClaim,
+					Requests: []string{"request1"},
+					DeviceConfiguration: resourceapi.DeviceConfiguration{
+						Opaque: &resourceapi.OpaqueDeviceConfiguration{
+							Driver:     consts.DriverName,
+							Parameters: runtime.RawExtension{Raw: claimEncoded},
+						},
+					},
+				},
+				{
+					Source:   resourceapi.AllocationConfigSourceClass,
+					Requests: []string{"request1", "request2"},
+					DeviceConfiguration: resourceapi.DeviceConfiguration{
+						Opaque: &resourceapi.OpaqueDeviceConfiguration{
+							Driver:     consts.DriverName,
+							Parameters: runtime.RawExtension{Raw: classEncoded},
+						},
+					},
+				},
+			}
+
+			result, err := getMapOfOpaqueDeviceConfigForDevice(decoder, configs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(HaveLen(2))
+
+			Expect(result["request1"].Driver).To(Equal("vfio-pci"))
+			Expect(result["request1"].NetAttachDefName).To(Equal("claim-net"))
+			Expect(result["request1"].NetAttachDefNamespace).To(Equal("class-ns"))
+			Expect(result["request1"].AddHugepagesMount).To(BeTrue())
+			Expect(result["request1"].AddVhostMount).To(BeTrue())
+
+			Expect(result["request2"].NetAttachDefName).To(Equal("class-net"))
+			Expect(result["request2"].NetAttachDefNamespace).To(Equal("class-ns"))
+			Expect(result["request2"].AddVhostMount).To(BeFalse())
+		})
+
 		It("should apply later config over earlier config within same source", func() {
 			config1 := &configapi.VfConfig{
 				TypeMeta: metav1.TypeMeta{
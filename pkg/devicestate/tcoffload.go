@@ -0,0 +1,59 @@
+package devicestate
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// validateTCOffloadConfig checks that the representors a VfConfig enables the TC offload on are
+// reachable from the node, they only exist on the DPU in the split-driver mode.
+func (s *Manager) validateTCOffloadConfig(config *configapi.VfConfig) error {
+	if config.TCOffload == nil {
+		return nil
+	}
+	if s.dpuAgent != nil {
+		return fmt.Errorf("tcOffload is not supported in the DPU split-driver mode, the representors are programmed by the DPU agent")
+	}
+	return config.TCOffload.Validate()
+}
+
+// configureTCOffload enables the TC hardware offload on the representors of the prepared devices
+// whose VfConfig has a tcOffload. Devices whose representor is configured record it, so
+// unprepareDevices releases them, including after a failure of a later device.
+func (s *Manager) configureTCOffload(ctx context.Context, preparedDevices drasriovtypes.PreparedDevices) error {
+	logger := klog.FromContext(ctx).WithName("configureTCOffload")
+
+	for _, device := range preparedDevices {
+		if device.AdminAccess || device.Config == nil || device.Config.TCOffload == nil {
+			continue
+		}
+		representor, err := host.GetHelpers().GetVFRepresentor(device.PciAddress)
+		if err != nil {
+			return fmt.Errorf("failed to get representor of device %s: %w", device.Device.DeviceName, err)
+		}
+		if err := host.GetHelpers().EnableTCOffload(representor, device.Config.TCOffload.Qdisc); err != nil {
+			return fmt.Errorf("failed to enable TC offload of device %s: %w", device.Device.DeviceName, err)
+		}
+		device.TCOffloadRepresentor = representor
+		logger.V(2).Info("Enabled TC offload", "device", device.Device.DeviceName, "representor", representor, "qdisc", device.Config.TCOffload.Qdisc)
+	}
+	return nil
+}
+
+// releaseTCOffload deletes the qdisc configureTCOffload added to the representor of a device.
+func releaseTCOffload(ctx context.Context, device *drasriovtypes.PreparedDevice) error {
+	if device.TCOffloadRepresentor == "" || device.Config.TCOffload == nil {
+		return nil
+	}
+	if err := host.GetHelpers().DisableTCOffload(device.TCOffloadRepresentor, device.Config.TCOffload.Qdisc); err != nil {
+		return fmt.Errorf("failed to release TC offload of device %s: %w", device.Device.DeviceName, err)
+	}
+	klog.FromContext(ctx).WithName("releaseTCOffload").V(2).Info("Released TC offload", "device", device.Device.DeviceName, "representor", device.TCOffloadRepresentor)
+	return nil
+}
@@ -0,0 +1,97 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("TC offload", func() {
+	var (
+		ctrl     *gomock.Controller
+		mockHost *hostmock.MockInterface
+		manager  *Manager
+	)
+
+	newDevice := func(name, pciAddress string, tcOffload *configapi.TCOffloadConfig) *drasriovtypes.PreparedDevice {
+		return &drasriovtypes.PreparedDevice{
+			Device:     drapbv1.Device{DeviceName: name},
+			Config:     &configapi.VfConfig{TCOffload: tcOffload},
+			PciAddress: pciAddress,
+		}
+	}
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		_ = host.GetHelpers()
+		mockHost = hostmock.NewMockInterface(ctrl)
+		originalHelpers := host.Helpers
+		host.Helpers = mockHost
+		DeferCleanup(func() { host.Helpers = originalHelpers })
+		manager = &Manager{}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("enables the TC offload on the representors and releases it on unprepare", func() {
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-1", "0000:03:00.2", &configapi.TCOffloadConfig{Qdisc: configapi.TCOffloadQdiscClsact}),
+			newDevice("vf-2", "0000:03:00.3", nil),
+		}
+		mockHost.EXPECT().GetVFRepresentor("0000:03:00.2").Return("pf0vf1", nil)
+		mockHost.EXPECT().EnableTCOffload("pf0vf1", "clsact").Return(nil)
+
+		Expect(manager.configureTCOffload(context.Background(), devices)).To(Succeed())
+		Expect(devices[0].TCOffloadRepresentor).To(Equal("pf0vf1"))
+		Expect(devices[1].TCOffloadRepresentor).To(BeEmpty())
+
+		mockHost.EXPECT().DisableTCOffload("pf0vf1", "clsact").Return(nil)
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+	})
+
+	It("keeps the representors already configured when a device fails", func() {
+		devices := drasriovtypes.PreparedDevices{
+			newDevice("vf-1", "0000:03:00.2", &configapi.TCOffloadConfig{Qdisc: configapi.TCOffloadQdiscIngress}),
+			newDevice("vf-2", "0000:03:00.3", &configapi.TCOffloadConfig{Qdisc: configapi.TCOffloadQdiscIngress}),
+		}
+		mockHost.EXPECT().GetVFRepresentor("0000:03:00.2").Return("pf0vf1", nil)
+		mockHost.EXPECT().EnableTCOffload("pf0vf1", "ingress").Return(nil)
+		mockHost.EXPECT().GetVFRepresentor("0000:03:00.3").Return("", errors.New("PF p0 of device 0000:03:00.3 is not in switchdev mode"))
+
+		Expect(manager.configureTCOffload(context.Background(), devices)).To(MatchError(ContainSubstring("failed to get representor of device vf-2")))
+		Expect(devices[1].TCOffloadRepresentor).To(BeEmpty())
+
+		// the rollback only releases what was configured
+		mockHost.EXPECT().DisableTCOffload("pf0vf1", "ingress").Return(nil)
+		Expect(manager.unprepareDevices(devices)).To(Succeed())
+	})
+
+	It("fails the unprepare when the qdisc cannot be deleted", func() {
+		device := newDevice("vf-1", "0000:03:00.2", &configapi.TCOffloadConfig{Qdisc: configapi.TCOffloadQdiscClsact})
+		device.TCOffloadRepresentor = "pf0vf1"
+		mockHost.EXPECT().DisableTCOffload("pf0vf1", "clsact").Return(errors.New("device busy"))
+
+		Expect(manager.unprepareDevices(drasriovtypes.PreparedDevices{device})).To(MatchError(ContainSubstring("failed to release TC offload of device vf-1")))
+	})
+
+	It("rejects the TC offload in the DPU split-driver mode", func() {
+		manager.dpuAgent = &fakeDPUAgent{}
+		config := &configapi.VfConfig{TCOffload: &configapi.TCOffloadConfig{Qdisc: configapi.TCOffloadQdiscClsact}}
+
+		Expect(manager.validateTCOffloadConfig(config)).To(MatchError(ContainSubstring("not supported in the DPU split-driver mode")))
+		manager.dpuAgent = nil
+		Expect(manager.validateTCOffloadConfig(config)).To(Succeed())
+		Expect(manager.validateTCOffloadConfig(&configapi.VfConfig{TCOffload: &configapi.TCOffloadConfig{Qdisc: "htb"}})).To(MatchError(ContainSubstring("unsupported qdisc")))
+	})
+})
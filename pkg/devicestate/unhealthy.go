@@ -0,0 +1,90 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+)
+
+// unhealthyRecoveryInterval is how often a device whose driver unbind timed out is checked for
+// the unbind to have completed.
+var unhealthyRecoveryInterval = 10 * time.Second
+
+// checkHealthy returns the reason a device was marked unhealthy, nil if it is healthy.
+func (s *Manager) checkHealthy(deviceName string) error {
+	s.unhealthyMu.Lock()
+	defer s.unhealthyMu.Unlock()
+	return s.unhealthy[deviceName]
+}
+
+// handleDriverError marks a device unhealthy when a driver change failed because the unbind of
+// its driver timed out, see host.ErrSysfsTimeout.
+func (s *Manager) handleDriverError(ctx context.Context, deviceName, pciAddress string, err error) {
+	if errors.Is(err, host.ErrSysfsTimeout) {
+		s.markUnhealthy(ctx, deviceName, pciAddress, err)
+	}
+}
+
+// markUnhealthy withdraws a device from the ResourceSlice, since its driver is stuck, and watches
+// for it to recover.
+func (s *Manager) markUnhealthy(ctx context.Context, deviceName, pciAddress string, cause error) {
+	logger := klog.FromContext(ctx).WithName("markUnhealthy")
+
+	s.unhealthyMu.Lock()
+	_, alreadyUnhealthy := s.unhealthy[deviceName]
+	if s.unhealthy == nil {
+		s.unhealthy = make(map[string]error)
+	}
+	s.unhealthy[deviceName] = cause
+	s.unhealthyMu.Unlock()
+	if alreadyUnhealthy {
+		return
+	}
+
+	logger.Error(cause, "Device marked unhealthy, withdrawing it until its driver recovers", "deviceName", deviceName, "pciAddress", pciAddress)
+	ctx = context.WithoutCancel(ctx)
+	s.republish(ctx)
+	go s.recoverUnhealthyDevice(ctx, deviceName, pciAddress, unhealthyRecoveryInterval)
+}
+
+// recoverUnhealthyDevice waits for the stuck unbind of a device to complete, then forces the
+// device back to its default driver and publishes it again.
+func (s *Manager) recoverUnhealthyDevice(ctx context.Context, deviceName, pciAddress string, interval time.Duration) {
+	logger := klog.FromContext(ctx).WithName("recoverUnhealthyDevice")
+
+	err := wait.PollUntilContextCancel(ctx, interval, false, func(ctx context.Context) (bool, error) {
+		driver, err := host.GetHelpers().GetDriverByBusAndDevice(pciAddress)
+		if err != nil || driver != "" {
+			return false, nil
+		}
+		if err := host.GetHelpers().BindDefaultDriver(pciAddress); err != nil {
+			logger.Error(err, "Failed to bind recovered device to its default driver", "deviceName", deviceName, "pciAddress", pciAddress)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return
+	}
+
+	s.unhealthyMu.Lock()
+	delete(s.unhealthy, deviceName)
+	s.unhealthyMu.Unlock()
+	logger.Info("Device recovered, publishing it again", "deviceName", deviceName, "pciAddress", pciAddress)
+	s.republish(ctx)
+}
+
+// republish publishes the advertised devices again, if a callback is set.
+func (s *Manager) republish(ctx context.Context) {
+	if s.republishCallback == nil {
+		return
+	}
+	if err := s.republishCallback(ctx); err != nil {
+		klog.FromContext(ctx).Error(err, "Failed to republish resources after a device change")
+	}
+}
@@ -0,0 +1,118 @@
+package devicestate
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Manager", Serial, func() {
+	Context("unhealthy devices", func() {
+		var (
+			mockHost     *hostmock.MockInterface
+			m            *Manager
+			republished  atomic.Int32
+			origInterval time.Duration
+			timeoutErr   error
+			claim        *resourceapi.ResourceClaim
+			result       *resourceapi.DeviceRequestAllocationResult
+		)
+
+		BeforeEach(func() {
+			ctrl := gomock.NewController(GinkgoT())
+			_ = host.GetHelpers()
+			mockHost = hostmock.NewMockInterface(ctrl)
+			mockHost.EXPECT().GetVFSettings(gomock.Any()).Return(nil, nil).AnyTimes()
+			originalHelpers := host.Helpers
+			host.Helpers = mockHost
+			origInterval = unhealthyRecoveryInterval
+			DeferCleanup(func() {
+				host.Helpers = originalHelpers
+				unhealthyRecoveryInterval = origInterval
+			})
+
+			republished.Store(0)
+			m = &Manager{
+				allocatable: drasriovtypes.AllocatableDevices{
+					"device1": {
+						Name: "device1",
+						Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						},
+					},
+				},
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{
+					"device1": {},
+				},
+				configurationMode: string(consts.ConfigurationModeMultus),
+			}
+			m.SetRepublishCallback(func(context.Context) error {
+				republished.Add(1)
+				return nil
+			})
+			timeoutErr = &host.SysfsWriteError{Path: "/sys/bus/pci/drivers/iavf/unbind", Reason: host.ErrSysfsTimeout, Err: errors.New("no completion after 30s")}
+			claim = &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-claim", Namespace: "test-ns", UID: "claim-uid"},
+			}
+			result = &resourceapi.DeviceRequestAllocationResult{Device: "device1", Request: "req1", Pool: "pool1"}
+		})
+
+		It("withdraws a device whose unbind timed out and refuses to prepare it", func() {
+			// keep the recovery from polling the mocks during the test
+			unhealthyRecoveryInterval = time.Hour
+			config := &configapi.VfConfig{Driver: "vfio-pci"}
+			mockHost.EXPECT().BindDeviceDriver("0000:01:00.1", config).Return("", timeoutErr)
+
+			ifNameIndex := 0
+			_, err := m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(errors.Is(err, host.ErrSysfsTimeout)).To(BeTrue())
+			Expect(m.GetAdvertisedDevices()).To(BeEmpty())
+			Expect(republished.Load()).To(Equal(int32(1)))
+
+			_, err = m.applyConfigOnDevice(context.Background(), &ifNameIndex, claim, config, result)
+			Expect(err).To(MatchError(ContainSubstring("device device1 is unhealthy")))
+		})
+
+		It("restores the other devices when one of them times out", func() {
+			unhealthyRecoveryInterval = time.Hour
+			mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.1", "iavf").Return(timeoutErr)
+			mockHost.EXPECT().RestoreDeviceDriver("0000:01:00.2", "iavf").Return(nil)
+
+			err := m.unprepareDevices(drasriovtypes.PreparedDevices{
+				{Device: drapbv1.Device{DeviceName: "device1"}, PciAddress: "0000:01:00.1", OriginalDriver: "iavf", Config: &configapi.VfConfig{Driver: "vfio-pci"}},
+				{Device: drapbv1.Device{DeviceName: "device2"}, PciAddress: "0000:01:00.2", OriginalDriver: "iavf", Config: &configapi.VfConfig{Driver: "vfio-pci"}},
+			})
+			Expect(errors.Is(err, host.ErrSysfsTimeout)).To(BeTrue())
+			Expect(m.checkHealthy("device1")).To(HaveOccurred())
+			Expect(m.checkHealthy("device2")).To(Succeed())
+		})
+
+		It("publishes the device again once the unbind completed", func() {
+			unhealthyRecoveryInterval = 10 * time.Millisecond
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil).Times(1)
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("", nil).Times(1)
+			mockHost.EXPECT().BindDefaultDriver("0000:01:00.1").Return(nil).Times(1)
+
+			m.markUnhealthy(context.Background(), "device1", "0000:01:00.1", timeoutErr)
+			Expect(m.GetAdvertisedDevices()).To(BeEmpty())
+
+			Eventually(m.GetAdvertisedDevices).Should(HaveKey("device1"))
+			Eventually(republished.Load).Should(Equal(int32(2)))
+		})
+	})
+})
@@ -1,6 +1,8 @@
 package devicestate
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
@@ -33,5 +35,58 @@ var _ = Describe("Manager", func() {
 			err := s.unprepareDevices(devices)
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("resets devices before restoring their driver when enabled", func() {
+			ctrl := gomock.NewController(GinkgoT())
+			defer ctrl.Finish()
+
+			_ = host.GetHelpers()
+			mockHost := hostmock.NewMockInterface(ctrl)
+			originalHelpers := host.Helpers
+			defer func() { host.Helpers = originalHelpers }()
+			host.Helpers = mockHost
+
+			gomock.InOrder(
+				mockHost.EXPECT().ResetDevice("0000:00:00.1").Return(nil),
+				mockHost.EXPECT().RestoreDeviceDriver("0000:00:00.1", "ixgbe").Return(nil),
+			)
+			// a failed reset does not keep the device from being released
+			mockHost.EXPECT().ResetDevice("0000:00:00.2").Return(errors.New("reset failed"))
+
+			s := &Manager{resetOnUnprepare: true}
+			devices := drasriovtypes.PreparedDevices{
+				&drasriovtypes.PreparedDevice{PciAddress: "0000:00:00.1", OriginalDriver: "ixgbe", Config: &configapi.VfConfig{Driver: "vfio-pci"}},
+				&drasriovtypes.PreparedDevice{PciAddress: "0000:00:00.2", Config: &configapi.VfConfig{}},
+			}
+			Expect(s.unprepareDevices(devices)).To(Succeed())
+		})
+
+		It("restores the original settings of the VFs", func() {
+			ctrl := gomock.NewController(GinkgoT())
+			defer ctrl.Finish()
+
+			_ = host.GetHelpers()
+			mockHost := hostmock.NewMockInterface(ctrl)
+			originalHelpers := host.Helpers
+			defer func() { host.Helpers = originalHelpers }()
+			host.Helpers = mockHost
+
+			settings := &host.VFSettings{MAC: "02:00:00:00:00:01", VLAN: 100, SpoofChk: true}
+			gomock.InOrder(
+				mockHost.EXPECT().SetVFSettings("0000:00:00.1", settings).Return(nil),
+				mockHost.EXPECT().RestoreDeviceDriver("0000:00:00.1", "ixgbe").Return(nil),
+			)
+			mockHost.EXPECT().SetVFSettings("0000:00:00.2", settings).Return(errors.New("PF is gone"))
+
+			s := &Manager{}
+			devices := drasriovtypes.PreparedDevices{
+				&drasriovtypes.PreparedDevice{PciAddress: "0000:00:00.1", OriginalDriver: "ixgbe", OriginalVFSettings: settings, Config: &configapi.VfConfig{Driver: "vfio-pci"}},
+				&drasriovtypes.PreparedDevice{PciAddress: "0000:00:00.2", OriginalVFSettings: settings, Config: &configapi.VfConfig{}},
+				// settings that could not be read are not restored
+				&drasriovtypes.PreparedDevice{PciAddress: "0000:00:00.3", Config: &configapi.VfConfig{}},
+			}
+			err := s.unprepareDevices(devices)
+			Expect(err).To(MatchError(ContainSubstring("failed to restore original settings of device 0000:00:00.2")))
+		})
 	})
 })
@@ -0,0 +1,85 @@
+package devicestate
+
+import (
+	"context"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// discoveredDrivers returns the drivers the devices were bound to when discovered, keyed by
+// device name.
+func discoveredDrivers(allocatable drasriovtypes.AllocatableDevices) map[string]string {
+	drivers := make(map[string]string, len(allocatable))
+	for name, device := range allocatable {
+		if attribute, ok := device.Attributes[consts.AttributeDriver]; ok && attribute.StringValue != nil {
+			drivers[name] = *attribute.StringValue
+		}
+	}
+	return drivers
+}
+
+// currentDriver returns the driver a device is currently bound to, if known.
+func (s *Manager) currentDriver(deviceName string) (string, bool) {
+	s.driversMu.Lock()
+	defer s.driversMu.Unlock()
+	driver, ok := s.drivers[deviceName]
+	return driver, ok
+}
+
+// setCurrentDriver records the driver a device is bound to and reports whether it changed.
+func (s *Manager) setCurrentDriver(deviceName, driver string) bool {
+	s.driversMu.Lock()
+	defer s.driversMu.Unlock()
+	if current, ok := s.drivers[deviceName]; ok && current == driver {
+		return false
+	}
+	if s.drivers == nil {
+		s.drivers = make(map[string]string)
+	}
+	s.drivers[deviceName] = driver
+	return true
+}
+
+// withCurrentDriver returns the attributes of a device with its driver attribute set to the
+// driver it is currently bound to. The attributes are copied, not modified.
+func (s *Manager) withCurrentDriver(deviceName string, attributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	driver, ok := s.currentDriver(deviceName)
+	if !ok {
+		return attributes
+	}
+	published := make(map[resourceapi.QualifiedName]resourceapi.DeviceAttribute, len(attributes)+1)
+	for name, value := range attributes {
+		published[name] = value
+	}
+	published[consts.AttributeDriver] = resourceapi.DeviceAttribute{StringValue: &driver}
+	return published
+}
+
+// syncDrivers refreshes the driver of the devices whose VfConfig rebinds them, after a prepare
+// or an unprepare, and publishes the devices again when one of them changed.
+func (s *Manager) syncDrivers(ctx context.Context, preparedDevices drasriovtypes.PreparedDevices) {
+	logger := klog.FromContext(ctx).WithName("syncDrivers")
+	changed := false
+	for _, preparedDevice := range preparedDevices {
+		if preparedDevice == nil || preparedDevice.Config == nil || preparedDevice.Config.Driver == "" {
+			continue
+		}
+		driver, err := host.GetHelpers().GetDriverByBusAndDevice(preparedDevice.PciAddress)
+		if err != nil {
+			logger.Error(err, "Failed to get current driver of device", "device", preparedDevice.PciAddress)
+			continue
+		}
+		if s.setCurrentDriver(preparedDevice.Device.DeviceName, driver) {
+			logger.V(2).Info("Device driver changed", "deviceName", preparedDevice.Device.DeviceName, "driver", driver)
+			changed = true
+		}
+	}
+	if changed {
+		s.republish(ctx)
+	}
+}
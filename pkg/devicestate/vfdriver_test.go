@@ -0,0 +1,111 @@
+package devicestate
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourceapi "k8s.io/api/resource/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Manager", Serial, func() {
+	Context("device drivers", func() {
+		var (
+			mockHost    *hostmock.MockInterface
+			m           *Manager
+			republished int
+			devices     drasriovtypes.PreparedDevices
+		)
+
+		BeforeEach(func() {
+			ctrl := gomock.NewController(GinkgoT())
+			_ = host.GetHelpers()
+			mockHost = hostmock.NewMockInterface(ctrl)
+			originalHelpers := host.Helpers
+			host.Helpers = mockHost
+			DeferCleanup(func() {
+				host.Helpers = originalHelpers
+			})
+
+			allocatable := drasriovtypes.AllocatableDevices{
+				"device1": {
+					Name: "device1",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						consts.AttributePciAddress: {StringValue: ptr.To("0000:01:00.1")},
+						consts.AttributeDriver:     {StringValue: ptr.To("iavf")},
+					},
+				},
+			}
+			republished = 0
+			m = &Manager{
+				allocatable:    allocatable,
+				policyAttrKeys: map[string]map[resourceapi.QualifiedName]bool{"device1": {}},
+				drivers:        discoveredDrivers(allocatable),
+			}
+			m.SetRepublishCallback(func(context.Context) error {
+				republished++
+				return nil
+			})
+			devices = drasriovtypes.PreparedDevices{
+				{Device: drapbv1.Device{DeviceName: "device1"}, PciAddress: "0000:01:00.1", Config: &configapi.VfConfig{Driver: "vfio-pci"}},
+			}
+		})
+
+		It("publishes the driver a device was rebound to", func() {
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("vfio-pci", nil)
+
+			m.syncDrivers(context.Background(), devices)
+			Expect(republished).To(Equal(1))
+			Expect(m.GetAdvertisedDevices()["device1"].Attributes[consts.AttributeDriver].StringValue).To(Equal(ptr.To("vfio-pci")))
+			// the discovered attributes are left untouched
+			Expect(m.allocatable["device1"].Attributes[consts.AttributeDriver].StringValue).To(Equal(ptr.To("iavf")))
+		})
+
+		It("publishes the rebound driver while policies are updated", func() {
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("vfio-pci", nil)
+			// both publish from another goroutine, reading the devices as PublishResources does
+			m.SetRepublishCallback(func(context.Context) error {
+				_ = m.GetAdvertisedDevices()
+				return nil
+			})
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				m.syncDrivers(context.Background(), devices)
+			}()
+			Expect(m.UpdatePolicyDevices(context.Background(), map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				"device1": {consts.AttributeResourceName: {StringValue: ptr.To("vendor.com/resA")}},
+			})).To(Succeed())
+			Eventually(done).Should(BeClosed())
+
+			attributes := m.GetAdvertisedDevices()["device1"].Attributes
+			Expect(attributes[consts.AttributeDriver].StringValue).To(Equal(ptr.To("vfio-pci")))
+			Expect(attributes[consts.AttributeResourceName].StringValue).To(Equal(ptr.To("vendor.com/resA")))
+		})
+
+		It("does not publish again when the driver did not change", func() {
+			mockHost.EXPECT().GetDriverByBusAndDevice("0000:01:00.1").Return("iavf", nil)
+
+			m.syncDrivers(context.Background(), devices)
+			Expect(republished).To(BeZero())
+		})
+
+		It("ignores devices their VfConfig does not rebind", func() {
+			devices[0].Config = &configapi.VfConfig{}
+
+			m.syncDrivers(context.Background(), devices)
+			Expect(republished).To(BeZero())
+		})
+	})
+})
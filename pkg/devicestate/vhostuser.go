@@ -0,0 +1,78 @@
+package devicestate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// defaultVhostUserSocketDirMode lets the group of the directory create sockets in it.
+const defaultVhostUserSocketDirMode = 0o775
+
+// vhostUserSocketDir returns the host directory holding the vhost-user sockets of a pod, see
+// --vhost-user-socket-root.
+func (s *Manager) vhostUserSocketDir(podUID string) string {
+	root := s.vhostUserSocketRoot
+	if root == "" {
+		root = consts.DefaultVhostUserSocketRoot
+	}
+	return filepath.Join(root, podUID)
+}
+
+// createVhostUserSocketDir creates the vhost-user socket directory of a pod on the host with the
+// mode and owner of the VfConfig and returns its mount in the container. The devices of a pod
+// share the directory, creating it again only applies the permissions.
+func createVhostUserSocketDir(hostPath string, config *configapi.VhostUserSocketDir) (*cdispec.Mount, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vhost-user socket dir: %w", err)
+	}
+	mode := os.FileMode(defaultVhostUserSocketDirMode)
+	uid, gid := -1, -1
+	if config.Permissions != nil {
+		if config.Permissions.FileMode != nil {
+			mode = os.FileMode(*config.Permissions.FileMode)
+		}
+		if config.Permissions.UID != nil {
+			uid = int(*config.Permissions.UID)
+		}
+		if config.Permissions.GID != nil {
+			gid = int(*config.Permissions.GID)
+		}
+	}
+
+	if err := os.MkdirAll(hostPath, mode); err != nil {
+		return nil, fmt.Errorf("failed to create vhost-user socket dir %s: %w", hostPath, err)
+	}
+	// the mode passed to MkdirAll is masked by the umask of the driver
+	if err := os.Chmod(hostPath, mode); err != nil {
+		return nil, fmt.Errorf("failed to set the mode of vhost-user socket dir %s: %w", hostPath, err)
+	}
+	if err := os.Chown(hostPath, uid, gid); err != nil {
+		return nil, fmt.Errorf("failed to set the owner of vhost-user socket dir %s: %w", hostPath, err)
+	}
+
+	containerPath := config.ContainerPath
+	if containerPath == "" {
+		containerPath = consts.DefaultVhostUserSocketContainerPath
+	}
+	return &cdispec.Mount{
+		HostPath:      hostPath,
+		ContainerPath: containerPath,
+		Type:          "bind",
+		Options:       []string{"rbind", "rw"},
+	}, nil
+}
+
+// removeVhostUserSocketDir removes the vhost-user socket directory of a pod and the sockets
+// left in it.
+func removeVhostUserSocketDir(hostPath string) error {
+	if err := os.RemoveAll(hostPath); err != nil {
+		return fmt.Errorf("failed to remove vhost-user socket dir %s: %w", hostPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dpu is the gRPC API between the node driver and the agent running on the ARM cores of a
+// DPU, e.g. a BlueField, in the split-driver mode. The eswitch of the NIC and the representors of
+// the VFs only exist on the DPU side, so the driver handles VFIO and CDI on the host and
+// delegates their programming to the agent. Messages are JSON encoded, so agents can be written
+// without generated code.
+package dpu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// ServiceName is the gRPC service implemented by the agent.
+	ServiceName = "dra.sriov.dpu.v1alpha1.Agent"
+	// DefaultTimeout bounds each call to the agent.
+	DefaultTimeout = 30 * time.Second
+
+	configureVFMethod = "/" + ServiceName + "/ConfigureVF"
+	releaseVFMethod   = "/" + ServiceName + "/ReleaseVF"
+	codecName         = "json"
+)
+
+// VF identifies a VF of the host. The agent does not see the PCI topology of the host, it finds
+// the representor of the VF from the NIC, the PF and the index of the VF.
+type VF struct {
+	// NICID identifies the NIC of the PF, see the pfNICID attribute.
+	NICID        string `json:"nicID,omitempty"`
+	PFPciAddress string `json:"pfPciAddress"`
+	PFName       string `json:"pfName,omitempty"`
+	VFID         int64  `json:"vfID"`
+	PciAddress   string `json:"pciAddress"`
+}
+
+// ConfigureVFRequest asks the agent to program the representor of a VF and the eswitch for the
+// pod the VF is prepared for.
+type ConfigureVFRequest struct {
+	VF             VF     `json:"vf"`
+	DeviceName     string `json:"deviceName"`
+	ClaimUID       string `json:"claimUID"`
+	ClaimNamespace string `json:"claimNamespace"`
+	ClaimName      string `json:"claimName"`
+	PodUID         string `json:"podUID"`
+	// NetworkConfig is the CNI config of the VF, empty when it is not attached to a network, from
+	// which the agent takes e.g. the VLAN to program on the representor.
+	NetworkConfig string `json:"networkConfig,omitempty"`
+}
+
+// ConfigureVFResponse reports the representor programmed for a VF.
+type ConfigureVFResponse struct {
+	Representor string `json:"representor"`
+}
+
+// ReleaseVFRequest asks the agent to undo the programming of the representor of a VF. It must
+// succeed when the VF is not programmed, the driver retries it after failures and restarts.
+type ReleaseVFRequest struct {
+	VF          VF     `json:"vf"`
+	DeviceName  string `json:"deviceName"`
+	ClaimUID    string `json:"claimUID"`
+	Representor string `json:"representor"`
+}
+
+// ReleaseVFResponse is the empty response to ReleaseVFRequest.
+type ReleaseVFResponse struct{}
+
+// Agent programs the representors of the VFs on the DPU, implemented by the agent and by Client.
+type Agent interface {
+	ConfigureVF(ctx context.Context, req *ConfigureVFRequest) (*ConfigureVFResponse, error)
+	ReleaseVF(ctx context.Context, req *ReleaseVFRequest) (*ReleaseVFResponse, error)
+}
+
+// jsonCodec encodes the messages of the service in JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return codecName }
+
+//nolint:gochecknoinits // Registers the codec the agent decodes the requests of the driver with
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// serviceDesc describes the service for RegisterAgentServer.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*Agent)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "ConfigureVF", Handler: configureVFHandler},
+		{MethodName: "ReleaseVF", Handler: releaseVFHandler},
+	},
+}
+
+// RegisterAgentServer registers the agent implementation on a gRPC server of the DPU.
+func RegisterAgentServer(server *grpc.Server, agent Agent) {
+	server.RegisterService(&serviceDesc, agent)
+}
+
+func configureVFHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := &ConfigureVFRequest{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Agent).ConfigureVF(ctx, req.(*ConfigureVFRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: configureVFMethod}, handler)
+}
+
+func releaseVFHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := &ReleaseVFRequest{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Agent).ReleaseVF(ctx, req.(*ReleaseVFRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: releaseVFMethod}, handler)
+}
+
+// Client calls the agent of the DPU of the node.
+type Client struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+var _ Agent = &Client{}
+
+// NewClient creates a client of the agent listening at endpoint, a gRPC target such as
+// 192.168.100.2:50051 or unix:///var/run/dpu-agent.sock. It connects on the first call.
+func NewClient(endpoint string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codecName)),
+	}, opts...)
+	conn, err := grpc.NewClient(endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of DPU agent %s: %w", endpoint, err)
+	}
+	return &Client{conn: conn, timeout: DefaultTimeout}, nil
+}
+
+// ConfigureVF asks the agent to program the representor of a VF.
+func (c *Client) ConfigureVF(ctx context.Context, req *ConfigureVFRequest) (*ConfigureVFResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp := &ConfigureVFResponse{}
+	if err := c.conn.Invoke(ctx, configureVFMethod, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReleaseVF asks the agent to undo the programming of the representor of a VF.
+func (c *Client) ReleaseVF(ctx context.Context, req *ReleaseVFRequest) (*ReleaseVFResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp := &ReleaseVFResponse{}
+	if err := c.conn.Invoke(ctx, releaseVFMethod, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Close closes the connection to the agent.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,80 @@
+package dpu_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/dpu"
+)
+
+// fakeAgent programs representors named after the PF and the VF index.
+type fakeAgent struct {
+	configured map[string]string
+}
+
+func (a *fakeAgent) ConfigureVF(_ context.Context, req *dpu.ConfigureVFRequest) (*dpu.ConfigureVFResponse, error) {
+	if req.VF.NICID == "" {
+		return nil, status.Error(codes.InvalidArgument, "unknown NIC")
+	}
+	representor := req.VF.PFName + "vf" + strconv.FormatInt(req.VF.VFID, 10)
+	a.configured[req.DeviceName] = req.NetworkConfig
+	return &dpu.ConfigureVFResponse{Representor: representor}, nil
+}
+
+func (a *fakeAgent) ReleaseVF(_ context.Context, req *dpu.ReleaseVFRequest) (*dpu.ReleaseVFResponse, error) {
+	delete(a.configured, req.DeviceName)
+	return &dpu.ReleaseVFResponse{}, nil
+}
+
+var _ = Describe("Agent", func() {
+	var (
+		agent  *fakeAgent
+		client *dpu.Client
+	)
+
+	BeforeEach(func() {
+		agent = &fakeAgent{configured: map[string]string{}}
+		listener := bufconn.Listen(1024 * 1024)
+		server := grpc.NewServer()
+		dpu.RegisterAgentServer(server, agent)
+		go func() { _ = server.Serve(listener) }()
+		DeferCleanup(server.Stop)
+
+		var err error
+		client, err = dpu.NewClient("passthrough:///dpu-agent", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(client.Close)
+	})
+
+	It("configures and releases the representors of the VFs", func() {
+		vf := dpu.VF{NICID: "MT2334X00001", PFPciAddress: "0000:03:00.0", PFName: "pf0", VFID: 2, PciAddress: "0000:03:00.4"}
+		resp, err := client.ConfigureVF(context.Background(), &dpu.ConfigureVFRequest{
+			VF:            vf,
+			DeviceName:    "0000-03-00-4",
+			ClaimUID:      "claim-uid",
+			NetworkConfig: `{"vlan":100}`,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Representor).To(Equal("pf0vf2"))
+		Expect(agent.configured).To(HaveKeyWithValue("0000-03-00-4", `{"vlan":100}`))
+
+		_, err = client.ReleaseVF(context.Background(), &dpu.ReleaseVFRequest{VF: vf, DeviceName: "0000-03-00-4", Representor: resp.Representor})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(agent.configured).To(BeEmpty())
+	})
+
+	It("returns the errors of the agent", func() {
+		_, err := client.ConfigureVF(context.Background(), &dpu.ConfigureVFRequest{DeviceName: "0000-03-00-4"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
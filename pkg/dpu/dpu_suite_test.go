@@ -0,0 +1,13 @@
+package dpu_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDPU(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DPU Suite")
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+)
+
+// collectOrphanCDISpecs removes the CDI specs of claims and pods the pod manager does not know,
+// left behind when the driver crashed or the node rebooted between writing them and
+// unpreparing. It must run before the plugin serves prepares, whose specs are written before
+// the claims are checkpointed, or while they are serialized, see collectOrphanCDISpecsSerialized. Claims the kubelet still needs are prepared again, with new specs.
+func collectOrphanCDISpecs(ctx context.Context, cdiHandler *cdi.Handler, podManager *podmanager.PodManager) error {
+	logger := klog.FromContext(ctx).WithName("collectOrphanCDISpecs")
+
+	known := make(map[string]bool)
+	for _, podUID := range podManager.GetPodUIDs() {
+		known[string(podUID)] = true
+		claims, _ := podManager.GetClaimsByPodUID(podUID)
+		for claimUID := range claims {
+			known[string(claimUID)] = true
+		}
+	}
+
+	var errs []error
+	for uid := range cdiHandler.ListTransientSpecs() {
+		if known[uid] {
+			continue
+		}
+		logger.Info("Removing orphan CDI spec", "uid", k8stypes.UID(uid))
+		if err := cdiHandler.DeleteSpecFile(uid); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove CDI spec of %s: %w", uid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
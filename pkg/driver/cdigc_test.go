@@ -0,0 +1,74 @@
+package driver
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("collectOrphanCDISpecs", func() {
+	var (
+		pm      *podmanager.PodManager
+		cdiRoot string
+	)
+
+	// like on startup, a new handler loads the specs written before it was created
+	newHandler := func() *cdi.Handler {
+		handler, err := cdi.NewHandler(cdiRoot)
+		Expect(err).ToNot(HaveOccurred())
+		return handler
+	}
+
+	claimDevices := func(claimUID string) types.PreparedDevices {
+		return types.PreparedDevices{{
+			Device:              drapbv1.Device{DeviceName: "device1"},
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: k8stypes.UID(claimUID)},
+			ContainerEdits:      &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{Env: []string{"TEST_ENV=test_value"}}},
+			PciAddress:          "0000:01:00.1",
+		}}
+	}
+
+	BeforeEach(func() {
+		var err error
+		pm, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+		cdiRoot = GinkgoT().TempDir()
+		cdiHandler := newHandler()
+
+		Expect(pm.Set("live-pod", "live-claim", claimDevices("live-claim"))).To(Succeed())
+		for _, claimUID := range []string{"live-claim", "gone-claim"} {
+			Expect(cdiHandler.CreateClaimSpecFile(claimDevices(claimUID))).To(Succeed())
+		}
+		for _, podUID := range []string{"live-pod", "gone-pod"} {
+			Expect(cdiHandler.CreateGlobalPodSpecFile(podUID, []string{"0000:01:00.1"})).To(Succeed())
+		}
+	})
+
+	It("removes the specs of the claims and pods missing from the checkpoint", func() {
+		Expect(collectOrphanCDISpecs(context.Background(), newHandler(), pm)).To(Succeed())
+
+		specs := newHandler().ListTransientSpecs()
+		Expect(specs).To(HaveLen(2))
+		Expect(specs).To(HaveKey("live-claim"))
+		Expect(specs).To(HaveKey("live-pod"))
+	})
+
+	It("keeps every spec of a shared claim", func() {
+		Expect(pm.SetShared([]k8stypes.UID{"gone-pod", "live-pod"}, "gone-claim", claimDevices("gone-claim"))).To(Succeed())
+
+		Expect(collectOrphanCDISpecs(context.Background(), newHandler(), pm)).To(Succeed())
+
+		Expect(newHandler().ListTransientSpecs()).To(HaveLen(4))
+	})
+})
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// claimLocks serializes the operations on the same claim, i.e. its prepare and unprepare served to
+// the kubelet and its release by the node tasks. The zero value is ready to use.
+type claimLocks struct {
+	mu    sync.Mutex
+	locks map[k8stypes.UID]*claimLock
+}
+
+type claimLock struct {
+	sync.Mutex
+	// refs counts the holders and waiters, the lock is dropped once there are none
+	refs int
+}
+
+// lock waits for the lock of a claim and returns the function releasing it.
+func (l *claimLocks) lock(claimUID k8stypes.UID) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[k8stypes.UID]*claimLock)
+	}
+	cl, ok := l.locks[claimUID]
+	if !ok {
+		cl = &claimLock{}
+		l.locks[claimUID] = cl
+	}
+	cl.refs++
+	l.mu.Unlock()
+
+	cl.Lock()
+	return func() {
+		cl.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		cl.refs--
+		if cl.refs == 0 {
+			delete(l.locks, claimUID)
+		}
+	}
+}
+
+// lockClaimForRelease serializes the release of a claim by the node tasks with its prepare and
+// unprepare served to the kubelet. During rolling updates it also takes the lock the kubelet
+// plugin helpers of both instances hold while serving the kubelet, and reloads the prepared
+// claims they checkpointed.
+func (d *Driver) lockClaimForRelease(ctx context.Context, claimUID k8stypes.UID) (func(), error) {
+	var grpcLock *os.File
+	if d.config != nil && d.config.RollingUpdateEnabled() {
+		var err error
+		grpcLock, err = lockFile(ctx, path.Join(d.config.DriverPluginPath(), grpcLockFile), 100*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.reloadPreparedClaims(); err != nil {
+			grpcLock.Close()
+			return nil, err
+		}
+	}
+	unlock := d.claimLocks.lock(claimUID)
+	return func() {
+		unlock()
+		if grpcLock != nil {
+			grpcLock.Close()
+		}
+	}, nil
+}
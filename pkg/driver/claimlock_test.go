@@ -0,0 +1,30 @@
+package driver
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("claimLocks", func() {
+	It("serializes the operations on the same claim only", func() {
+		var locks claimLocks
+		unlock := locks.lock("claim-1")
+
+		// another claim is not blocked
+		locks.lock("claim-2")()
+
+		locked := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			locks.lock("claim-1")()
+			close(locked)
+		}()
+		Consistently(locked).ShouldNot(BeClosed())
+
+		unlock()
+		Eventually(locked).Should(BeClosed())
+		locks.mu.Lock()
+		defer locks.mu.Unlock()
+		Expect(locks.locks).To(BeEmpty())
+	})
+})
@@ -0,0 +1,136 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/klog/v2"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// DebugState is the state of the driver served on the debug socket and printed by the state
+// subcommand, for field debugging.
+type DebugState struct {
+	// Devices are the devices discovered on the node, with their attributes.
+	Devices types.AllocatableDevices `json:"devices"`
+	// ResourceFilter holds the devices matched by the SriovResourcePolicies of the node, with the
+	// names of the attributes the policies set on them.
+	ResourceFilter map[string][]resourceapi.QualifiedName `json:"resourceFilter"`
+	// AdvertisedDevices are the names of the devices published in the ResourceSlices.
+	AdvertisedDevices []string `json:"advertisedDevices"`
+	// PreparedClaims are the claims prepared on the node.
+	PreparedClaims []podmanager.PreparedClaim `json:"preparedClaims"`
+	// CDISpecs are the devices of the transient CDI specs, keyed by the claim or pod UID.
+	CDISpecs map[string][]cdispec.Device `json:"cdiSpecs"`
+}
+
+// debugState returns the current state of the driver.
+func (d *Driver) debugState() *DebugState {
+	return &DebugState{
+		Devices:           d.deviceStateManager.GetAllocatableDevices(),
+		ResourceFilter:    d.deviceStateManager.GetPolicyAttributeKeys(),
+		AdvertisedDevices: slices.Sorted(maps.Keys(d.deviceStateManager.GetAdvertisedDevices())),
+		PreparedClaims:    d.podManager.List(podmanager.ListFilter{}),
+		CDISpecs:          d.cdi.ListTransientSpecs(),
+	}
+}
+
+// debugHandler serves the state of the driver as JSON.
+func (d *Driver) debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(consts.DebugStatePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(d.debugState()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+// ServeDebugSocket serves the debug API on a unix socket, only accessible to root, until the
+// context is done. A socket left by a previous run is replaced.
+func (d *Driver) ServeDebugSocket(ctx context.Context, socketPath string) error {
+	logger := klog.FromContext(ctx).WithName("ServeDebugSocket")
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0750); err != nil {
+		return fmt.Errorf("failed to create debug socket directory: %w", err)
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale debug socket: %w", err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on debug socket %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict debug socket %s: %w", socketPath, err)
+	}
+
+	server := &http.Server{Handler: d.debugHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		// the listener removes the socket when closed
+		if err := server.Close(); err != nil {
+			logger.Error(err, "Failed to stop debug server")
+		}
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err, "Debug server failed", "socket", socketPath)
+		}
+	}()
+	logger.Info("Serving debug API", "socket", socketPath)
+	return nil
+}
+
+// GetDebugState reads the state of the driver serving the debug API on a unix socket and returns
+// it as indented JSON.
+func GetDebugState(ctx context.Context, socketPath string) ([]byte, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	// the host is ignored by the dialer
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+consts.DebugStatePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query debug socket %s, is the driver running with --debug-socket?: %w", socketPath, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read driver state: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get driver state: %s: %s", resp.Status, body)
+	}
+	return body, nil
+}
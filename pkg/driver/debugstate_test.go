@@ -0,0 +1,95 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Debug socket", func() {
+	var (
+		d          *Driver
+		socketPath string
+	)
+
+	BeforeEach(func() {
+		pm, err := podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+		cdiRoot := GinkgoT().TempDir()
+		cdiHandler, err := cdi.NewHandler(cdiRoot)
+		Expect(err).ToNot(HaveOccurred())
+		devices := types.PreparedDevices{{
+			Device:              drapbv1.Device{DeviceName: "device1"},
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: k8stypes.UID("claim-uid")},
+			ContainerEdits:      &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{Env: []string{"TEST_ENV=test_value"}}},
+			PciAddress:          "0000:01:00.1",
+		}}
+		Expect(pm.Set("pod-uid", "claim-uid", devices)).To(Succeed())
+		Expect(cdiHandler.CreateClaimSpecFile(devices)).To(Succeed())
+		// a new handler loads the spec without waiting for the refresh of the cache
+		cdiHandler, err = cdi.NewHandler(cdiRoot)
+		Expect(err).ToNot(HaveOccurred())
+		d = &Driver{deviceStateManager: &devicestate.Manager{}, podManager: pm, cdi: cdiHandler}
+
+		// unix socket paths are limited to 108 bytes, shorter than some test temp dirs
+		dir, err := os.MkdirTemp("", "debug")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		socketPath = filepath.Join(dir, "run", "debug.sock")
+	})
+
+	It("serves the state of the driver to root only", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(d.ServeDebugSocket(ctx, socketPath)).To(Succeed())
+		info, err := os.Stat(socketPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		data, err := GetDebugState(ctx, socketPath)
+		Expect(err).ToNot(HaveOccurred())
+		state := &DebugState{}
+		Expect(json.Unmarshal(data, state)).To(Succeed())
+		Expect(state.PreparedClaims).To(HaveLen(1))
+		Expect(state.PreparedClaims[0].PodUID).To(Equal(k8stypes.UID("pod-uid")))
+		Expect(state.PreparedClaims[0].Devices[0].PciAddress).To(Equal("0000:01:00.1"))
+		Expect(state.CDISpecs).To(HaveKey("claim-uid"))
+		Expect(state.AdvertisedDevices).To(BeEmpty())
+
+		cancel()
+		Eventually(func() error {
+			_, err := os.Stat(socketPath)
+			return err
+		}).Should(MatchError(os.ErrNotExist))
+	})
+
+	It("replaces a stale socket", func() {
+		Expect(os.MkdirAll(filepath.Dir(socketPath), 0750)).To(Succeed())
+		Expect(os.WriteFile(socketPath, nil, 0600)).To(Succeed())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(d.ServeDebugSocket(ctx, socketPath)).To(Succeed())
+		_, err := GetDebugState(ctx, socketPath)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("fails when the driver is not serving the debug API", func() {
+		_, err := GetDebugState(context.Background(), socketPath)
+		Expect(err).To(MatchError(ContainSubstring("failed to query debug socket")))
+	})
+})
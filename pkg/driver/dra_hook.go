@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/prometheus/client_golang/prometheus"
 	resourceapi "k8s.io/api/resource/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,6 +18,8 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	sriovdratype "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
 func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceapi.ResourceClaim) (map[k8stypes.UID]kubeletplugin.PrepareResult, error) {
@@ -25,62 +30,124 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 	logger := klog.FromContext(ctx).WithName("PrepareResourceClaims")
 	logger.V(3).Info("claims", "claims", claims)
 
+	// in inventory mode devices are only published, never handed out
+	if d.config != nil && d.config.IsInventoryMode() {
+		for _, claim := range claims {
+			result[claim.UID] = kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("driver is running in %s mode, preparing devices is disabled", consts.DriverModeInventory),
+			}
+		}
+		logger.Info("Refused to prepare claims in inventory mode", "claims", len(claims))
+		return result, nil
+	}
+
+	// a draining driver hands new claims over to the next instance
+	if !d.inflight.Begin() {
+		for _, claim := range claims {
+			result[claim.UID] = kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("driver is shutting down, not preparing new claims"),
+			}
+		}
+		logger.Info("Refused to prepare claims while shutting down", "claims", len(claims))
+		return result, nil
+	}
+	defer d.inflight.End()
+
+	if err := d.reloadPreparedClaims(); err != nil {
+		return result, err
+	}
+
 	// we share this between all the claims so we can enumerate network interfaces
 	ifNameIndex := 0
 	// let's prepare the claims
 	for _, claim := range claims {
 		logger.V(1).Info("Preparing claim", "claim", claim.UID)
 		logger.V(3).Info("Claim", "claim", claim)
+		timer := prometheus.NewTimer(metrics.ClaimDuration.WithLabelValues(metrics.OperationPrepare))
 		result[claim.UID] = d.prepareResourceClaim(ctx, &ifNameIndex, claim)
+		timer.ObserveDuration()
 		logger.V(1).Info("Prepared claim", "claim", claim.UID, "result", result[claim.UID])
 		if result[claim.UID].Err != nil {
 			logger.Error(result[claim.UID].Err, "failed to prepare resource claim", "claim", claim)
 		}
 	}
 
+	// the pod level spec belongs to the pod of the claims reserved for a single pod, the devices of
+	// claims shared by several pods do not reference it
 	var podUID k8stypes.UID
+	shared := false
 	for _, claim := range claims {
-		if claim != nil && len(claim.Status.ReservedFor) > 0 {
-			podUID = claim.Status.ReservedFor[0].UID
-			break
+		if claim == nil || len(claim.Status.ReservedFor) == 0 {
+			continue
+		}
+		if len(claim.Status.ReservedFor) > 1 {
+			shared = true
+			continue
 		}
+		podUID = claim.Status.ReservedFor[0].UID
+		break
 	}
-	if podUID == "" {
+	if podUID == "" && !shared {
 		return result, fmt.Errorf("no pod info found for prepared claims")
 	}
 
+	if podUID != "" {
+		if err := d.createGlobalPodSpec(ctx, podUID); err != nil {
+			if cleanupErr := d.rollbackPreparedClaims(ctx, claims); cleanupErr != nil {
+				return result, errors.Join(err, fmt.Errorf("cleanup failed after global spec error: %w", cleanupErr))
+			}
+			return result, err
+		}
+	}
+
+	if err := d.updateExtendedResources(ctx); err != nil {
+		logger.Error(err, "Failed to update the extended resources of the node")
+	}
+
+	logger.V(3).Info("Prepared claims", "result", result)
+	return result, nil
+}
+
+// createGlobalPodSpec creates the spec file holding the pod level environment variables of the
+// devices prepared for a pod.
+func (d *Driver) createGlobalPodSpec(ctx context.Context, podUID k8stypes.UID) error {
+	logger := klog.FromContext(ctx).WithName("createGlobalPodSpec")
 	preparedDevices, exists := d.podManager.GetDevicesByPodUID(podUID)
-	if !exists && len(claims) > 0 {
+	if !exists {
 		logger.Error(fmt.Errorf("no prepared devices found for pod %s", podUID), "Error preparing devices for claim")
-		return result, fmt.Errorf("no prepared devices found for pod %s", podUID)
+		return fmt.Errorf("no prepared devices found for pod %s", podUID)
 	}
-	// create a global spec file for the pod level environment variables
 	pciAddresses := []string{}
 	for _, preparedDevice := range preparedDevices {
 		device, exist := d.deviceStateManager.GetAllocatableDeviceByName(preparedDevice.Device.DeviceName)
 		if !exist {
-			baseErr := fmt.Errorf("device not found for device name %s", preparedDevice.Device.DeviceName)
-			logger.Error(baseErr, "Error preparing devices for claim")
-			if cleanupErr := d.rollbackPreparedClaims(ctx, claims); cleanupErr != nil {
-				return result, errors.Join(baseErr, fmt.Errorf("cleanup failed after prepare error: %w", cleanupErr))
-			}
-			return result, baseErr
+			err := fmt.Errorf("device not found for device name %s", preparedDevice.Device.DeviceName)
+			logger.Error(err, "Error preparing devices for claim")
+			return err
 		}
 		pciAddresses = append(pciAddresses, *device.Attributes[consts.AttributePciAddress].StringValue)
 	}
 
+	cdiTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationPrepare, metrics.PhaseCDI))
 	err := d.cdi.CreateGlobalPodSpecFile(string(podUID), pciAddresses)
+	cdiTimer.ObserveDuration()
 	if err != nil {
 		logger.Error(err, "Error creating global spec file for pod", "pod", podUID)
-		baseErr := fmt.Errorf("error creating global spec file for pod: %w", err)
-		if cleanupErr := d.rollbackPreparedClaims(ctx, claims); cleanupErr != nil {
-			return result, errors.Join(baseErr, fmt.Errorf("cleanup failed after global spec error: %w", cleanupErr))
-		}
-		return result, baseErr
+		return fmt.Errorf("error creating global spec file for pod: %w", err)
 	}
+	return nil
+}
 
-	logger.V(3).Info("Prepared claims", "result", result)
-	return result, nil
+// reloadPreparedClaims reads the prepared claims back from the checkpoint during rolling updates, since
+// the kubelet may have sent the previous calls to the other driver instance.
+func (d *Driver) reloadPreparedClaims() error {
+	if d.config == nil || !d.config.RollingUpdateEnabled() {
+		return nil
+	}
+	if err := d.podManager.Reload(); err != nil {
+		return fmt.Errorf("failed to load prepared claims: %w", err)
+	}
+	return nil
 }
 
 // rollbackPreparedClaims rolls back successful claim preparations that were stored in pod manager state.
@@ -115,7 +182,7 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 		return kubeletplugin.PrepareResult{
 			Err: fmt.Errorf("no pod info found for claim %s/%s/%s", claim.Namespace, claim.Name, claim.UID),
 		}
-	} else if len(claim.Status.ReservedFor) > 1 {
+	} else if len(claim.Status.ReservedFor) > 1 && (d.config == nil || !d.config.AllowsSharedClaims()) {
 		logger.Error(fmt.Errorf("multiple pods found for claim %s/%s/%s not supported", claim.Namespace, claim.Name, claim.UID), "Error preparing devices for claim")
 		return kubeletplugin.PrepareResult{
 			Err: fmt.Errorf("multiple pods found for claim %s/%s/%s not supported", claim.Namespace, claim.Name, claim.UID),
@@ -127,11 +194,38 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 		return kubeletplugin.PrepareResult{Err: fmt.Errorf("claim not yet allocated")}
 	}
 
+	// the node tasks may be releasing the claim of a deleted pod
+	unlock := d.claimLocks.lock(claim.UID)
+	defer unlock()
+
 	// get the pod UID
 	podUID := claim.Status.ReservedFor[0].UID
 
 	// check if the pod claim is already prepared and return the prepared devices
 	preparedDevices, isAlreadyPrepared := d.podManager.Get(podUID, claim.UID)
+	if len(claim.Status.ReservedFor) > 1 {
+		// a shared claim is prepared once, the pods reserving it since are added as its consumers,
+		// including when it was first prepared for a single pod
+		sharedDevices, found := d.podManager.GetByClaim(kubeletplugin.NamespacedObject{UID: claim.UID})
+		if found && len(sharedDevices) > 0 {
+			// a VF attached to the network of the first pod would no longer be detached from it
+			for _, sharedDevice := range sharedDevices {
+				if sharedDevice.AttachesNetwork() && sharedDevice.CNISandboxID != "" {
+					err := fmt.Errorf("claim %s/%s/%s is attached to the network of pod %s and cannot be shared", claim.Namespace, claim.Name, claim.UID, sharedDevice.PodUID)
+					logger.Error(err, "Error preparing devices for claim")
+					return kubeletplugin.PrepareResult{Err: err}
+				}
+			}
+			sharedDevices = shareDevices(sharedDevices)
+			if err := d.podManager.SetShared(reservedForUIDs(claim), claim.UID, sharedDevices); err != nil {
+				logger.Error(err, "Error refreshing the pods of shared claim", "claim", claim.UID)
+				return kubeletplugin.PrepareResult{
+					Err: fmt.Errorf("error refreshing the pods of shared claim %s: %w", claim.UID, err),
+				}
+			}
+			preparedDevices, isAlreadyPrepared = sharedDevices, true
+		}
+	}
 	if isAlreadyPrepared {
 		var prepared []kubeletplugin.Device
 		for _, preparedDevice := range preparedDevices {
@@ -164,7 +258,19 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 		})
 	}
 
-	err = d.podManager.Set(podUID, claim.UID, preparedDevices)
+	// a claim shared by several pods is prepared once and referenced by each pod
+	if len(claim.Status.ReservedFor) > 1 {
+		for _, preparedDevice := range preparedDevices {
+			preparedDevice.Shared = true
+		}
+		checkpointTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationPrepare, metrics.PhaseCheckpoint))
+		err = d.podManager.SetShared(reservedForUIDs(claim), claim.UID, preparedDevices)
+		checkpointTimer.ObserveDuration()
+	} else {
+		checkpointTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationPrepare, metrics.PhaseCheckpoint))
+		err = d.podManager.Set(podUID, claim.UID, preparedDevices)
+		checkpointTimer.ObserveDuration()
+	}
 	if err != nil {
 		logger.Error(err, "Error setting prepared devices for pod into pod manager", "pod", podUID)
 		if cleanupErr := d.deviceStateManager.Unprepare(string(claim.UID), preparedDevices); cleanupErr != nil {
@@ -214,14 +320,55 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, ifNameIndex *int, cla
 	return kubeletplugin.PrepareResult{Devices: prepared}
 }
 
+// reservedForUIDs returns the UIDs of the pods reserving a claim.
+func reservedForUIDs(claim *resourceapi.ResourceClaim) []k8stypes.UID {
+	podUIDs := make([]k8stypes.UID, 0, len(claim.Status.ReservedFor))
+	for _, reservedFor := range claim.Status.ReservedFor {
+		podUIDs = append(podUIDs, reservedFor.UID)
+	}
+	return podUIDs
+}
+
+// shareDevices returns copies of the prepared devices of a claim marked as shared. The devices of a
+// claim first prepared for a single pod keep its PodUID, so its pod level spec is removed along
+// with the claim, but the spec is no longer injected into the pods sharing the claim.
+func shareDevices(preparedDevices sriovdratype.PreparedDevices) sriovdratype.PreparedDevices {
+	sharedDevices := make(sriovdratype.PreparedDevices, 0, len(preparedDevices))
+	for _, preparedDevice := range preparedDevices {
+		sharedDevice := *preparedDevice
+		sharedDevice.Shared = true
+		if sharedDevice.PodUID != "" {
+			podSpecSuffix := "=" + sharedDevice.PodUID
+			sharedDevice.Device.CDIDeviceIDs = slices.DeleteFunc(slices.Clone(sharedDevice.Device.CDIDeviceIDs), func(id string) bool {
+				return strings.HasSuffix(id, podSpecSuffix)
+			})
+		}
+		sharedDevices = append(sharedDevices, &sharedDevice)
+	}
+	return sharedDevices
+}
+
 func (d *Driver) UnprepareResourceClaims(ctx context.Context, claims []kubeletplugin.NamespacedObject) (map[k8stypes.UID]error, error) {
 	logger := klog.FromContext(ctx).WithName("UnprepareResourceClaims")
 	logger.V(1).Info("UnprepareResourceClaims is called", "number of claims", len(claims))
 	logger.V(3).Info("claims", "claims", claims)
 	result := make(map[k8stypes.UID]error)
 
+	d.inflight.Track()
+	defer d.inflight.End()
+
+	if err := d.reloadPreparedClaims(); err != nil {
+		return result, err
+	}
+
 	for _, claim := range claims {
+		timer := prometheus.NewTimer(metrics.ClaimDuration.WithLabelValues(metrics.OperationUnprepare))
 		result[claim.UID] = d.unprepareResourceClaim(ctx, claim)
+		timer.ObserveDuration()
+	}
+
+	if err := d.updateExtendedResources(ctx); err != nil {
+		logger.Error(err, "Failed to update the extended resources of the node")
 	}
 
 	logger.V(3).Info("Unprepared claims", "result", result)
@@ -233,6 +380,9 @@ func (d *Driver) unprepareResourceClaim(ctx context.Context, claim kubeletplugin
 	logger.V(1).Info("Unpreparing resource claim", "claim", claim.UID)
 	logger.V(3).Info("claim", "claim", claim)
 
+	unlock := d.claimLocks.lock(claim.UID)
+	defer unlock()
+
 	preparedDevices, found := d.podManager.GetByClaim(claim)
 	if !found {
 		return nil
@@ -243,7 +393,9 @@ func (d *Driver) unprepareResourceClaim(ctx context.Context, claim kubeletplugin
 	}
 
 	// delete the claim from the pod manager
+	checkpointTimer := prometheus.NewTimer(metrics.PhaseDuration.WithLabelValues(metrics.OperationUnprepare, metrics.PhaseCheckpoint))
 	err := d.podManager.DeleteClaim(claim)
+	checkpointTimer.ObserveDuration()
 	if err != nil {
 		logger.Error(err, "Error deleting claim from pod manager", "claim", claim.UID)
 		return fmt.Errorf("error deleting claim %s from pod manager: %w", claim.UID, err)
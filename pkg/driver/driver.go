@@ -22,9 +22,13 @@ import (
 	"maps"
 	"os"
 	"path"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	resourceapi "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	coreclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
@@ -33,6 +37,7 @@ import (
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/inflight"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	sriovdratype "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
@@ -46,29 +51,65 @@ type Driver struct {
 	cancelCtx          func(error)
 	config             *sriovdratype.Config
 	cdi                *cdi.Handler
+	staleCollector     *stalePodCollector
+	// nodeTasksLock is held while this instance runs the node tasks, see startNodeTasks.
+	nodeTasksMu   sync.Mutex
+	nodeTasksLock *os.File
+	// nodeTasksStarted is closed once this instance runs the node tasks.
+	nodeTasksStarted chan struct{}
+	// inflight tracks the prepares and unprepares in progress, see Drain.
+	inflight inflight.Tracker
+	// claimLocks serializes the prepare, unprepare and release by the node tasks of each claim
+	claimLocks claimLocks
+	// extendedResourcesMu serializes the updates of the extended resources of the node
+	extendedResourcesMu sync.Mutex
 }
 
-// Start creates a new DRA driver and starts the kubelet plugin. It waits for the plugin to be registered
-// with the kubelet before starting the healthcheck service and publishing the available resources
-func Start(ctx context.Context, config *sriovdratype.Config, deviceStateManager *devicestate.Manager, podManager *podmanager.PodManager, cdi *cdi.Handler) (*Driver, error) {
-	driver := &Driver{
+// New creates a DRA driver handling prepare and unprepare requests, without registering it with the kubelet.
+// The returned driver implements kubeletplugin.DRAPlugin and can be called directly, e.g. from tests.
+func New(config *sriovdratype.Config, deviceStateManager *devicestate.Manager, podManager *podmanager.PodManager, cdi *cdi.Handler) *Driver {
+	return &Driver{
 		client:             config.K8sClient.Interface,
 		cancelCtx:          config.CancelMainCtx,
 		config:             config,
 		deviceStateManager: deviceStateManager,
 		podManager:         podManager,
 		cdi:                cdi,
+		nodeTasksStarted:   make(chan struct{}),
+	}
+}
+
+// Start creates a new DRA driver and starts the kubelet plugin. It waits for the plugin to be registered
+// with the kubelet before starting the healthcheck service and publishing the available resources
+func Start(ctx context.Context, config *sriovdratype.Config, deviceStateManager *devicestate.Manager, podManager *podmanager.PodManager, cdi *cdi.Handler) (*Driver, error) {
+	driver := New(config, deviceStateManager, podManager, cdi)
+
+	// during rolling updates the previous instance keeps the lock until it stops
+	lock, err := tryLockFile(path.Join(config.DriverPluginPath(), nodeTasksLockFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the node tasks: %w", err)
+	}
+	driver.nodeTasksLock = lock
+	if lock != nil {
+		// drop the specs left behind by crashes, before new prepares write theirs
+		if err := collectOrphanCDISpecs(ctx, cdi, podManager); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to remove orphan CDI specs")
+		}
 	}
 
-	helper, err := kubeletplugin.Start(
-		ctx,
-		driver,
+	opts := []kubeletplugin.Option{
 		kubeletplugin.KubeClient(config.K8sClient.Interface),
 		kubeletplugin.NodeName(config.Flags.NodeName),
 		kubeletplugin.DriverName(consts.DriverName),
 		kubeletplugin.RegistrarDirectoryPath(config.Flags.KubeletRegistrarDirectoryPath),
 		kubeletplugin.PluginDataDirectoryPath(config.DriverPluginPath()),
-	)
+	}
+	if config.RollingUpdateEnabled() {
+		// the helper serializes the calls of both instances with a lock file in the plugin data directory
+		opts = append(opts, kubeletplugin.RollingUpdate(k8stypes.UID(config.Flags.PodUID)))
+	}
+
+	helper, err := kubeletplugin.Start(ctx, driver, opts...)
 	if err != nil {
 		klog.FromContext(ctx).Error(err, "Failed to start DRA kubelet plugin")
 		return nil, err
@@ -84,14 +125,44 @@ func Start(ctx context.Context, config *sriovdratype.Config, deviceStateManager
 	if err != nil {
 		return nil, fmt.Errorf("start healthcheck: %w", err)
 	}
+	driver.SetHealthcheckProbe(consts.HealthcheckProbeDiscovery, deviceStateManager.CheckDiscoveredDevices)
+	if !config.IsInventoryMode() {
+		driver.SetHealthcheckProbe(consts.HealthcheckProbeCDI, cdi.CheckSpecDir)
+	}
 
 	// Publish resources
 	if err = driver.PublishResources(ctx); err != nil {
 		return nil, fmt.Errorf("failed to publish resources: %w", err)
 	}
+
+	if driver.nodeTasksLock == nil {
+		go driver.takeOverNodeTasks(ctx)
+		return driver, nil
+	}
+	if err = driver.startNodeTasks(ctx); err != nil {
+		return nil, err
+	}
 	return driver, nil
 }
 
+// ReleaseStalePod restores the devices of a pod right away when it no longer exists on the node,
+// e.g. after its orphaned network attachments were removed on startup. Pods that still exist are
+// left to the kubelet, which recreates their sandbox with the prepared devices.
+func (d *Driver) ReleaseStalePod(ctx context.Context, podUID k8stypes.UID) error {
+	if d.staleCollector == nil {
+		return nil
+	}
+	return d.staleCollector.collectPodIfGone(ctx, podUID)
+}
+
+// SetHealthcheckProbe sets the check of a probe of the healthcheck served by another component,
+// e.g. the status of the CNI plugins. It only runs when the probe is enabled.
+func (d *Driver) SetHealthcheckProbe(probe consts.HealthcheckProbe, check func(ctx context.Context) error) {
+	if d.healthcheck != nil {
+		d.healthcheck.setCheck(probe, check)
+	}
+}
+
 // waitForRegistration waits for the plugin to be registered with the kubelet
 func waitForRegistration(ctx context.Context, helper *kubeletplugin.Helper) error {
 	logger := klog.FromContext(ctx)
@@ -119,20 +190,35 @@ func waitForRegistration(ctx context.Context, helper *kubeletplugin.Helper) erro
 	}
 }
 
+// Drain stops accepting prepare requests and waits for the prepares and unprepares in progress to
+// complete, or for ctx to be done. Unprepares are still served until Shutdown, so claims can be
+// released while the driver drains.
+func (d *Driver) Drain(ctx context.Context) error {
+	return d.inflight.Drain(ctx)
+}
+
 // Shutdown shuts down the driver
 func (d *Driver) Shutdown(logger klog.Logger) error {
 	if d.healthcheck != nil {
 		d.healthcheck.Stop(logger)
 	}
 	d.helper.Stop()
+	// hand the node tasks over to the next instance, the NRI plugin must be stopped already
+	d.nodeTasksMu.Lock()
+	if d.nodeTasksLock != nil {
+		d.nodeTasksLock.Close()
+		d.nodeTasksLock = nil
+	}
+	d.nodeTasksMu.Unlock()
 
 	// remove the socket files
 	// TODO: this is not needed after https://github.com/kubernetes/kubernetes/pull/133934 is merged
-	err := os.Remove(path.Join(d.config.Flags.KubeletRegistrarDirectoryPath, consts.DriverName+"-reg.sock"))
+	// with rolling updates the sockets are suffixed with the pod UID, the new instance cannot remove ours
+	err := os.Remove(registrarSocketPath(d.config))
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error removing socket file: %w", err)
 	}
-	err = os.Remove(path.Join(d.config.DriverPluginPath(), "dra.sock"))
+	err = os.Remove(draSocketPath(d.config))
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error removing socket file: %w", err)
 	}
@@ -140,8 +226,27 @@ func (d *Driver) Shutdown(logger klog.Logger) error {
 	return nil
 }
 
-// PublishResources publishes policy-matched devices to the DRA resource slice.
-// Only devices matched by a SriovResourcePolicy are advertised.
+// socketUIDSuffix returns the suffix the kubelet plugin helper adds to the names of its sockets,
+// the pod UID with rolling updates, so the sockets of both instances can coexist.
+func socketUIDSuffix(config *sriovdratype.Config) string {
+	if !config.RollingUpdateEnabled() {
+		return ""
+	}
+	return "-" + config.Flags.PodUID
+}
+
+// registrarSocketPath returns the path of the registration socket served to the kubelet.
+func registrarSocketPath(config *sriovdratype.Config) string {
+	return path.Join(config.Flags.KubeletRegistrarDirectoryPath, consts.DriverName+socketUIDSuffix(config)+"-reg.sock")
+}
+
+// draSocketPath returns the path of the DRA socket served to the kubelet.
+func draSocketPath(config *sriovdratype.Config) string {
+	return path.Join(config.DriverPluginPath(), "dra"+socketUIDSuffix(config)+".sock")
+}
+
+// PublishResources publishes policy-matched devices to the DRA resource slices of the node pool,
+// split by sliceDevices. Only devices matched by a SriovResourcePolicy are advertised.
 func (d *Driver) PublishResources(ctx context.Context) error {
 	advertised := d.deviceStateManager.GetAdvertisedDevices()
 	devices := make([]resourceapi.Device, 0, len(advertised))
@@ -151,11 +256,7 @@ func (d *Driver) PublishResources(ctx context.Context) error {
 	resources := resourceslice.DriverResources{
 		Pools: map[string]resourceslice.Pool{
 			d.config.Flags.NodeName: {
-				Slices: []resourceslice.Slice{
-					{
-						Devices: devices,
-					},
-				},
+				Slices: sliceDevices(devices, resourceapi.ResourceSliceMaxDevices),
 			},
 		},
 	}
@@ -163,5 +264,29 @@ func (d *Driver) PublishResources(ctx context.Context) error {
 	if err := d.helper.PublishResources(ctx, resources); err != nil {
 		return err
 	}
+	// extended resources are a migration aid, the devices are published anyway
+	if err := d.updateExtendedResources(ctx); err != nil {
+		klog.FromContext(ctx).Error(err, "Failed to update the extended resources of the node")
+	}
 	return nil
 }
+
+// sliceDevices splits the devices of the node into slices of up to maxDevices devices, the limit of
+// the API server being resourceapi.ResourceSliceMaxDevices, sorted by
+// name so the same devices always land in the same slice, e.g. the VFs of a PF with the default
+// naming scheme. A node with several hundred VFs gets several slices of its pool, and a change of
+// the advertised devices only rewrites the slices from the first one that changed.
+func sliceDevices(devices []resourceapi.Device, maxDevices int) []resourceslice.Slice {
+	slices.SortFunc(devices, func(a, b resourceapi.Device) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	// a node without devices keeps publishing an empty slice, as before the split
+	if len(devices) == 0 {
+		return []resourceslice.Slice{{Devices: devices}}
+	}
+	var result []resourceslice.Slice
+	for chunk := range slices.Chunk(devices, maxDevices) {
+		result = append(result, resourceslice.Slice{Devices: chunk})
+	}
+	return result
+}
@@ -11,7 +11,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
 
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
@@ -42,6 +44,30 @@ var _ = Describe("Driver", func() {
 			Expect(err.Error()).To(ContainSubstring("no prepared devices found for pod"))
 		})
 
+		It("refuses to prepare claims in inventory mode", func() {
+			cfg := &types.Config{Flags: &types.Flags{Mode: string(consts.DriverModeInventory)}}
+			d := &Driver{config: cfg}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc1", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: k8stypes.UID("pod-uid")}}
+
+			result, err := d.PrepareResourceClaims(context.Background(), []*resourceapi.ResourceClaim{claim})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(HaveKey(k8stypes.UID("rc-uid")))
+			Expect(result[k8stypes.UID("rc-uid")].Err).To(HaveOccurred())
+			Expect(result[k8stypes.UID("rc-uid")].Err.Error()).To(ContainSubstring("inventory mode"))
+		})
+
+		It("refuses to prepare claims once draining", func() {
+			d := &Driver{config: &types.Config{Flags: &types.Flags{}}}
+			Expect(d.Drain(context.Background())).To(Succeed())
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc1", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: k8stypes.UID("pod-uid")}}
+
+			result, err := d.PrepareResourceClaims(context.Background(), []*resourceapi.ResourceClaim{claim})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result[k8stypes.UID("rc-uid")].Err).To(MatchError(ContainSubstring("shutting down")))
+		})
+
 		It("returns error instead of panicking when no claim contains pod info", func() {
 			flags := &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}
 			cfg := &types.Config{Flags: flags}
@@ -81,6 +107,112 @@ var _ = Describe("Driver", func() {
 			Expect(res.Err.Error()).To(ContainSubstring("multiple pods"))
 		})
 
+		It("accepts multiple pods in ReservedFor when shared claims are allowed", func() {
+			d := &Driver{config: &types.Config{Flags: &types.Flags{AllowSharedClaims: true}}}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: "a"}, {UID: "b"}}
+			res := d.prepareResourceClaim(context.Background(), new(int), claim)
+			Expect(res.Err).To(HaveOccurred())
+			Expect(res.Err.Error()).To(ContainSubstring("claim not yet allocated"))
+		})
+
+		It("adds the pods reserving an already prepared shared claim", func() {
+			flags := &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir(), AllowSharedClaims: true}
+			cfg := &types.Config{Flags: flags}
+			pm, err := podmanager.NewPodManager(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			devices := types.PreparedDevices{{PciAddress: "0000:01:00.3", Shared: true}}
+			devices[0].Device.DeviceName = "vf-3"
+			Expect(pm.SetShared([]k8stypes.UID{"a", "b"}, "rc-uid", devices)).To(Succeed())
+
+			d := &Driver{config: cfg, podManager: pm}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: "b"}, {UID: "c"}}
+			claim.Status.Allocation = &resourceapi.AllocationResult{}
+
+			result, err := d.PrepareResourceClaims(context.Background(), []*resourceapi.ResourceClaim{claim})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result[claim.UID].Err).ToNot(HaveOccurred())
+			Expect(result[claim.UID].Devices).To(HaveLen(1))
+			Expect(result[claim.UID].Devices[0].DeviceName).To(Equal("vf-3"))
+			Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("b"), k8stypes.UID("c")))
+			Expect(pm.ClaimRefCount(claim.UID)).To(Equal(2))
+		})
+
+		It("shares a claim prepared for a single pod once more pods reserve it", func() {
+			flags := &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir(), AllowSharedClaims: true}
+			cfg := &types.Config{Flags: flags}
+			pm, err := podmanager.NewPodManager(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			devices := types.PreparedDevices{{PciAddress: "0000:01:00.3", PodUID: "a"}}
+			devices[0].Device.DeviceName = "vf-3"
+			devices[0].Device.CDIDeviceIDs = []string{"k8s.io/net=rc-uid-vf-3", "k8s.io/net=a"}
+			Expect(pm.Set("a", "rc-uid", devices)).To(Succeed())
+
+			d := &Driver{config: cfg, podManager: pm}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: "a"}, {UID: "b"}, {UID: "c"}}
+			claim.Status.Allocation = &resourceapi.AllocationResult{}
+
+			result, err := d.PrepareResourceClaims(context.Background(), []*resourceapi.ResourceClaim{claim})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result[claim.UID].Err).ToNot(HaveOccurred())
+			Expect(result[claim.UID].Devices).To(HaveLen(1))
+			Expect(result[claim.UID].Devices[0].CDIDeviceIDs).To(Equal([]string{"k8s.io/net=rc-uid-vf-3"}))
+			Expect(pm.ClaimRefCount(claim.UID)).To(Equal(3))
+			shared, found := pm.Get("c", claim.UID)
+			Expect(found).To(BeTrue())
+			Expect(shared[0].Shared).To(BeTrue())
+			// the pod level spec of the first pod is removed with the claim
+			Expect(shared[0].PodUID).To(Equal("a"))
+
+			// the first pod is no longer the last reference
+			last, err := pm.ReleaseClaim("a", claim.UID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(last).To(BeFalse())
+		})
+
+		It("refuses to share a claim attached to the network of its first pod", func() {
+			flags := &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir(), AllowSharedClaims: true}
+			cfg := &types.Config{Flags: flags}
+			pm, err := podmanager.NewPodManager(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			devices := types.PreparedDevices{{PciAddress: "0000:01:00.3", PodUID: "a", CNISandboxID: "sandbox-a"}}
+			devices[0].Device.DeviceName = "vf-3"
+			Expect(pm.Set("a", "rc-uid", devices)).To(Succeed())
+
+			d := &Driver{config: cfg, podManager: pm}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: "a"}, {UID: "b"}}
+			claim.Status.Allocation = &resourceapi.AllocationResult{}
+
+			res := d.prepareResourceClaim(context.Background(), new(int), claim)
+			Expect(res.Err).To(MatchError(ContainSubstring("cannot be shared")))
+			Expect(pm.ClaimRefCount(claim.UID)).To(Equal(1))
+		})
+
+		It("does not prepare a shared claim again when the order of its pods changes", func() {
+			flags := &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir(), AllowSharedClaims: true}
+			cfg := &types.Config{Flags: flags}
+			pm, err := podmanager.NewPodManager(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			devices := types.PreparedDevices{{PciAddress: "0000:01:00.3", Shared: true}}
+			devices[0].Device.DeviceName = "vf-3"
+			Expect(pm.SetShared([]k8stypes.UID{"a", "b"}, "rc-uid", devices)).To(Succeed())
+
+			// a prepare of the devices would fail without a device state manager
+			d := &Driver{config: cfg, podManager: pm}
+			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
+			claim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{{UID: "c"}, {UID: "b"}, {UID: "a"}}
+			claim.Status.Allocation = &resourceapi.AllocationResult{}
+
+			result, err := d.PrepareResourceClaims(context.Background(), []*resourceapi.ResourceClaim{claim})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result[claim.UID].Err).ToNot(HaveOccurred())
+			Expect(result[claim.UID].Devices).To(HaveLen(1))
+			Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("a"), k8stypes.UID("b"), k8stypes.UID("c")))
+		})
+
 		It("errors when Allocation is nil", func() {
 			d := &Driver{}
 			claim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rc", UID: k8stypes.UID("rc-uid")}}
@@ -105,4 +237,47 @@ var _ = Describe("Driver", func() {
 			Expect(called).To(BeFalse())
 		})
 	})
+
+	Context("sliceDevices", func() {
+		newDevices := func(names ...string) []resourceapi.Device {
+			devices := make([]resourceapi.Device, 0, len(names))
+			for _, name := range names {
+				devices = append(devices, resourceapi.Device{Name: name})
+			}
+			return devices
+		}
+		names := func(slice resourceslice.Slice) []string {
+			var result []string
+			for _, device := range slice.Devices {
+				result = append(result, device.Name)
+			}
+			return result
+		}
+
+		It("splits the devices into sorted slices of the maximum size", func() {
+			slices := sliceDevices(newDevices("vf-4", "vf-1", "vf-5", "vf-3", "vf-2"), 2)
+			Expect(slices).To(HaveLen(3))
+			Expect(names(slices[0])).To(Equal([]string{"vf-1", "vf-2"}))
+			Expect(names(slices[1])).To(Equal([]string{"vf-3", "vf-4"}))
+			Expect(names(slices[2])).To(Equal([]string{"vf-5"}))
+		})
+
+		It("keeps the devices in a single slice below the limit of the API server", func() {
+			devices := make([]string, 0, resourceapi.ResourceSliceMaxDevices+1)
+			for i := range resourceapi.ResourceSliceMaxDevices + 1 {
+				devices = append(devices, fmt.Sprintf("vf-%03d", i))
+			}
+			Expect(sliceDevices(newDevices(devices[:resourceapi.ResourceSliceMaxDevices]...), resourceapi.ResourceSliceMaxDevices)).To(HaveLen(1))
+
+			slices := sliceDevices(newDevices(devices...), resourceapi.ResourceSliceMaxDevices)
+			Expect(slices).To(HaveLen(2))
+			Expect(names(slices[1])).To(Equal([]string{fmt.Sprintf("vf-%03d", resourceapi.ResourceSliceMaxDevices)}))
+		})
+
+		It("publishes an empty slice without devices", func() {
+			slices := sliceDevices(nil, resourceapi.ResourceSliceMaxDevices)
+			Expect(slices).To(HaveLen(1))
+			Expect(slices[0].Devices).To(BeEmpty())
+		})
+	})
 })
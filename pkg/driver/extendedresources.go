@@ -0,0 +1,176 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	coreclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	sriovdratype "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// ValidateExtendedResourcePrefix checks that the prefix of the extended resources is a valid
+// domain outside of kubernetes.io, which is reserved for the resources of Kubernetes.
+func ValidateExtendedResourcePrefix(prefix string) error {
+	if errs := validation.IsDNS1123Subdomain(prefix); len(errs) > 0 {
+		return fmt.Errorf("invalid extended resource prefix %q: %s", prefix, strings.Join(errs, ", "))
+	}
+	if reservedDomain(prefix) {
+		return fmt.Errorf("invalid extended resource prefix %q: kubernetes.io is reserved", prefix)
+	}
+	return nil
+}
+
+func reservedDomain(domain string) bool {
+	return domain == "kubernetes.io" || strings.HasSuffix(domain, ".kubernetes.io")
+}
+
+// extendedResourceName returns the extended resource of a resource name, prefixed with prefix
+// unless it already has one, e.g. intel.com/eth0_resource for eth0_resource.
+func extendedResourceName(prefix, resourceName string) (corev1.ResourceName, error) {
+	name := resourceName
+	if !strings.Contains(name, "/") {
+		name = prefix + "/" + name
+	}
+	if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid extended resource %q: %s", name, strings.Join(errs, ", "))
+	}
+	domain, _, _ := strings.Cut(name, "/")
+	if reservedDomain(domain) {
+		return "", fmt.Errorf("invalid extended resource %q: kubernetes.io is reserved", name)
+	}
+	return corev1.ResourceName(name), nil
+}
+
+// extendedResources counts the advertised devices of each resource name set by the policies that
+// are not prepared for a claim. Devices without resource name, or whose resource name is not a
+// valid extended resource, are not counted.
+func extendedResources(logger klog.Logger, advertised sriovdratype.AllocatableDevices, prepared map[string]bool, prefix string) map[corev1.ResourceName]int64 {
+	resources := map[corev1.ResourceName]int64{}
+	for name, device := range advertised {
+		resourceName := device.Attributes[consts.AttributeMultusResourceName].StringValue
+		if resourceName == nil || *resourceName == "" {
+			continue
+		}
+		extendedResource, err := extendedResourceName(prefix, *resourceName)
+		if err != nil {
+			logger.V(2).Info("Not publishing the resource name of the device as extended resource", "device", name, "error", err)
+			continue
+		}
+		if _, ok := resources[extendedResource]; !ok {
+			resources[extendedResource] = 0
+		}
+		if !prepared[name] {
+			resources[extendedResource]++
+		}
+	}
+	return resources
+}
+
+// preparedDeviceNames returns the names of the devices prepared for claims.
+func preparedDeviceNames(podManager *podmanager.PodManager) map[string]bool {
+	prepared := map[string]bool{}
+	if podManager == nil {
+		return prepared
+	}
+	for _, claim := range podManager.List(podmanager.ListFilter{}) {
+		for _, device := range claim.Devices {
+			prepared[device.Device.DeviceName] = true
+		}
+	}
+	return prepared
+}
+
+// updateExtendedResources publishes the extended resources of the advertised devices that are not
+// prepared for claims, see --extended-resources. It runs after the devices are published and after
+// claims are prepared or unprepared.
+func (d *Driver) updateExtendedResources(ctx context.Context) error {
+	if d.config == nil || d.config.Flags == nil || !d.config.Flags.ExtendedResources || d.client == nil {
+		return nil
+	}
+	logger := klog.FromContext(ctx).WithName("updateExtendedResources")
+	d.extendedResourcesMu.Lock()
+	defer d.extendedResourcesMu.Unlock()
+
+	resources := extendedResources(logger, d.deviceStateManager.GetAdvertisedDevices(), preparedDeviceNames(d.podManager), d.config.Flags.ExtendedResourcePrefix)
+	return setNodeExtendedResources(klog.NewContext(ctx, logger), d.client, d.config.Flags.NodeName, resources)
+}
+
+// setNodeExtendedResources sets the extended resources in the capacity of the node and removes the
+// ones the driver set before that are no longer published, as listed by the
+// NodeAnnotationExtendedResources annotation. The kubelet keeps the extended resources it does not
+// manage and derives their allocatable from the capacity.
+func setNodeExtendedResources(ctx context.Context, client coreclientset.Interface, nodeName string, resources map[corev1.ResourceName]int64) error {
+	logger := klog.FromContext(ctx)
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+
+	// a null value removes the resource in a merge patch
+	changes := map[corev1.ResourceName]*string{}
+	for name, count := range resources {
+		value := strconv.FormatInt(count, 10)
+		if current, ok := node.Status.Capacity[name]; !ok || current.Value() != count {
+			changes[name] = &value
+		}
+	}
+	var previous []string
+	if annotation := node.Annotations[consts.NodeAnnotationExtendedResources]; annotation != "" {
+		previous = strings.Split(annotation, ",")
+	}
+	for _, name := range previous {
+		if _, ok := resources[corev1.ResourceName(name)]; !ok {
+			if _, set := node.Status.Capacity[corev1.ResourceName(name)]; set {
+				changes[corev1.ResourceName(name)] = nil
+			}
+		}
+	}
+
+	// the annotation is updated first, so a failure never leaves resources the driver forgets
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, string(name))
+	}
+	slices.Sort(names)
+	for _, name := range previous {
+		if _, ok := changes[corev1.ResourceName(name)]; ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if annotation := strings.Join(names, ","); annotation != node.Annotations[consts.NodeAnnotationExtendedResources] {
+		patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{
+			"annotations": map[string]string{consts.NodeAnnotationExtendedResources: annotation},
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to build node annotation patch: %w", err)
+		}
+		if _, err := client.CoreV1().Nodes().Patch(ctx, node.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to annotate node %s: %w", node.Name, err)
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"status": map[string]interface{}{"capacity": changes}})
+	if err != nil {
+		return fmt.Errorf("failed to build node capacity patch: %w", err)
+	}
+	if _, err := client.CoreV1().Nodes().Patch(ctx, node.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+		return fmt.Errorf("failed to update the capacity of node %s: %w", node.Name, err)
+	}
+	logger.Info("Updated node extended resources", "node", node.Name, "resources", resources)
+	return nil
+}
@@ -0,0 +1,135 @@
+package driver
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("Extended resources", func() {
+	newDevice := func(name, resourceName string) resourceapi.Device {
+		device := resourceapi.Device{Name: name, Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}}
+		if resourceName != "" {
+			device.Attributes[consts.AttributeMultusResourceName] = resourceapi.DeviceAttribute{StringValue: ptr.To(resourceName)}
+		}
+		return device
+	}
+
+	Context("extendedResourceName", func() {
+		It("prefixes the resource names without prefix", func() {
+			Expect(extendedResourceName("intel.com", "eth0_resource")).To(Equal(corev1.ResourceName("intel.com/eth0_resource")))
+			Expect(extendedResourceName("intel.com", "nvidia.com/mlnx_sriov")).To(Equal(corev1.ResourceName("nvidia.com/mlnx_sriov")))
+		})
+
+		It("rejects invalid and reserved names", func() {
+			_, err := extendedResourceName("intel.com", "eth0 resource")
+			Expect(err).To(MatchError(ContainSubstring("invalid extended resource")))
+			_, err = extendedResourceName("intel.com", "kubernetes.io/sriov")
+			Expect(err).To(MatchError(ContainSubstring("kubernetes.io is reserved")))
+		})
+
+		It("validates the prefix", func() {
+			Expect(ValidateExtendedResourcePrefix("intel.com")).To(Succeed())
+			Expect(ValidateExtendedResourcePrefix("Intel_com")).To(MatchError(ContainSubstring("invalid extended resource prefix")))
+			Expect(ValidateExtendedResourcePrefix("sriov.kubernetes.io")).To(MatchError(ContainSubstring("kubernetes.io is reserved")))
+		})
+	})
+
+	It("counts the devices of each resource name not prepared for claims", func() {
+		advertised := types.AllocatableDevices{
+			"vf-1": newDevice("vf-1", "eth0_resource"),
+			"vf-2": newDevice("vf-2", "eth0_resource"),
+			"vf-3": newDevice("vf-3", "eth1_resource"),
+			"vf-4": newDevice("vf-4", ""),
+		}
+
+		pm, err := podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pm.Set("pod", "claim", types.PreparedDevices{
+			{Device: drapbv1.Device{DeviceName: "vf-2"}},
+			{Device: drapbv1.Device{DeviceName: "vf-3"}},
+		})).To(Succeed())
+
+		resources := extendedResources(klog.Background(), advertised, preparedDeviceNames(pm), "intel.com")
+		Expect(resources).To(Equal(map[corev1.ResourceName]int64{
+			"intel.com/eth0_resource": 1,
+			// a resource name whose devices are all prepared stays published
+			"intel.com/eth1_resource": 0,
+		}))
+	})
+
+	Context("setNodeExtendedResources", func() {
+		var clientset *k8sfake.Clientset
+
+		BeforeEach(func() {
+			clientset = k8sfake.NewSimpleClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "node-1",
+					Annotations: map[string]string{consts.NodeAnnotationExtendedResources: "intel.com/old_resource"},
+				},
+				Status: corev1.NodeStatus{Capacity: corev1.ResourceList{
+					corev1.ResourceCPU:       resource.MustParse("8"),
+					"intel.com/old_resource": resource.MustParse("4"),
+					"nvidia.com/gpu":         resource.MustParse("2"),
+				}},
+			})
+		})
+
+		getNode := func() *corev1.Node {
+			node, err := clientset.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return node
+		}
+
+		It("sets the extended resources and removes the ones no longer published", func() {
+			Expect(setNodeExtendedResources(context.Background(), clientset, "node-1", map[corev1.ResourceName]int64{
+				"intel.com/eth0_resource": 3,
+			})).To(Succeed())
+
+			node := getNode()
+			Expect(node.Status.Capacity).To(HaveKeyWithValue(corev1.ResourceName("intel.com/eth0_resource"), resource.MustParse("3")))
+			Expect(node.Status.Capacity).ToNot(HaveKey(corev1.ResourceName("intel.com/old_resource")))
+			// the resources of others are left alone
+			Expect(node.Status.Capacity).To(HaveKey(corev1.ResourceCPU))
+			Expect(node.Status.Capacity).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+			Expect(node.Annotations).To(HaveKeyWithValue(consts.NodeAnnotationExtendedResources, "intel.com/eth0_resource,intel.com/old_resource"))
+
+			// the removed resource is forgotten once it is gone from the capacity
+			Expect(setNodeExtendedResources(context.Background(), clientset, "node-1", map[corev1.ResourceName]int64{
+				"intel.com/eth0_resource": 2,
+			})).To(Succeed())
+			node = getNode()
+			Expect(node.Status.Capacity).To(HaveKeyWithValue(corev1.ResourceName("intel.com/eth0_resource"), resource.MustParse("2")))
+			Expect(node.Annotations).To(HaveKeyWithValue(consts.NodeAnnotationExtendedResources, "intel.com/eth0_resource"))
+		})
+
+		It("does not patch a node already up to date", func() {
+			resources := map[corev1.ResourceName]int64{"intel.com/eth0_resource": 3}
+			Expect(setNodeExtendedResources(context.Background(), clientset, "node-1", resources)).To(Succeed())
+			Expect(setNodeExtendedResources(context.Background(), clientset, "node-1", resources)).To(Succeed())
+			clientset.ClearActions()
+
+			Expect(setNodeExtendedResources(context.Background(), clientset, "node-1", resources)).To(Succeed())
+			Expect(clientset.Actions()).To(HaveLen(1))
+			Expect(clientset.Actions()[0].GetVerb()).To(Equal("get"))
+		})
+
+		It("fails on a missing node", func() {
+			Expect(setNodeExtendedResources(context.Background(), clientset, "node-2", nil)).To(MatchError(ContainSubstring("failed to get node node-2")))
+		})
+	})
+})
@@ -21,7 +21,7 @@ import (
 	"fmt"
 	"net"
 	"net/url"
-	"path"
+	"slices"
 	"strconv"
 	"sync"
 
@@ -34,9 +34,9 @@ import (
 	drapb "k8s.io/kubelet/pkg/apis/dra/v1beta1"
 	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
 
-	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
-
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
 type Healthcheck struct {
@@ -47,6 +47,22 @@ type Healthcheck struct {
 
 	regClient registerapi.RegistrationClient
 	draClient drapb.DRAPluginClient
+
+	// probes are the checks enabled with --healthcheck-probes. The checks of the probes served by
+	// other components, e.g. the NRI plugin, are set once they start, probes without a check pass.
+	probes   []consts.HealthcheckProbe
+	checksMu sync.Mutex
+	checks   map[consts.HealthcheckProbe]func(ctx context.Context) error
+}
+
+// ValidateHealthcheckProbes checks that the probes of --healthcheck-probes are supported.
+func ValidateHealthcheckProbes(probes []string) error {
+	for _, probe := range probes {
+		if !slices.Contains(consts.HealthcheckProbes, consts.HealthcheckProbe(probe)) {
+			return fmt.Errorf("unsupported healthcheck probe %q, expected one of %v", probe, consts.HealthcheckProbes)
+		}
+	}
+	return nil
 }
 
 func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck, error) {
@@ -65,9 +81,7 @@ func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck,
 
 	regSockPath := (&url.URL{
 		Scheme: "unix",
-		// TODO: this needs to adapt when seamless upgrades
-		// are enabled and the filename includes a uid.
-		Path: path.Join(config.Flags.KubeletRegistrarDirectoryPath, consts.DriverName+"-reg.sock"),
+		Path:   registrarSocketPath(config),
 	}).String()
 	log.Info("connecting to registration socket", "path", regSockPath)
 	regConn, err := grpc.NewClient(
@@ -80,7 +94,7 @@ func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck,
 
 	draSockPath := (&url.URL{
 		Scheme: "unix",
-		Path:   path.Join(config.DriverPluginPath(), "dra.sock"),
+		Path:   draSocketPath(config),
 	}).String()
 	log.Info("connecting to DRA socket", "path", draSockPath)
 	draConn, err := grpc.NewClient(
@@ -92,11 +106,11 @@ func startHealthcheck(ctx context.Context, config *types.Config) (*Healthcheck,
 	}
 
 	server := grpc.NewServer()
-	healthcheck := &Healthcheck{
-		server:    server,
-		regClient: registerapi.NewRegistrationClient(regConn),
-		draClient: drapb.NewDRAPluginClient(draConn),
-	}
+	healthcheck := newHealthcheck(config.Flags.HealthcheckProbes)
+	healthcheck.server = server
+	healthcheck.regClient = registerapi.NewRegistrationClient(regConn)
+	healthcheck.draClient = drapb.NewDRAPluginClient(draConn)
+	healthcheck.setCheck(consts.HealthcheckProbeKubeletRegistration, healthcheck.checkKubeletPlugin)
 	grpc_health_v1.RegisterHealthServer(server, healthcheck)
 
 	healthcheck.wg.Add(1)
@@ -119,33 +133,96 @@ func (h *Healthcheck) Stop(logger klog.Logger) {
 	h.wg.Wait()
 }
 
-// Check implements [grpc_health_v1.HealthServer].
+func newHealthcheck(probes []string) *Healthcheck {
+	h := &Healthcheck{checks: map[consts.HealthcheckProbe]func(ctx context.Context) error{}}
+	for _, probe := range probes {
+		h.probes = append(h.probes, consts.HealthcheckProbe(probe))
+	}
+	return h
+}
+
+// Check implements [grpc_health_v1.HealthServer]. The liveness service only runs the
+// kubelet-registration probe, so a missing CNI plugin or a lost runtime connection does not
+// restart the driver, the readiness and default services run all the probes and every probe is
+// also served on its own under its name.
 func (h *Healthcheck) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
 	log := klog.FromContext(ctx)
 
-	knownServices := map[string]struct{}{"": {}, "liveness": {}}
-	if _, known := knownServices[req.GetService()]; !known {
-		return nil, status.Error(codes.NotFound, "unknown service")
+	probes, err := h.serviceProbes(req.GetService())
+	if err != nil {
+		return nil, err
 	}
 
 	status := &grpc_health_v1.HealthCheckResponse{
-		Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		Status: grpc_health_v1.HealthCheckResponse_SERVING,
+	}
+	// all the probes run, so their metrics are up to date
+	for _, probe := range probes {
+		if err := h.runProbe(ctx, probe); err != nil {
+			log.Error(err, "failed healthcheck probe", "probe", probe, "service", req.GetService())
+			status.Status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	return status, nil
+}
+
+// serviceProbes returns the enabled probes a service of the healthcheck runs.
+func (h *Healthcheck) serviceProbes(service string) ([]consts.HealthcheckProbe, error) {
+	switch service {
+	case "", "readiness":
+		return h.probes, nil
+	case "liveness":
+		if slices.Contains(h.probes, consts.HealthcheckProbeKubeletRegistration) {
+			return []consts.HealthcheckProbe{consts.HealthcheckProbeKubeletRegistration}, nil
+		}
+		return nil, nil
+	}
+	if probe := consts.HealthcheckProbe(service); slices.Contains(h.probes, probe) {
+		return []consts.HealthcheckProbe{probe}, nil
 	}
+	return nil, status.Error(codes.NotFound, "unknown service")
+}
+
+// runProbe runs the check of a probe and records its result.
+func (h *Healthcheck) runProbe(ctx context.Context, probe consts.HealthcheckProbe) error {
+	var err error
+	if check := h.getCheck(probe); check != nil {
+		err = check(ctx)
+	}
+	if err != nil {
+		metrics.HealthcheckProbeStatus.WithLabelValues(string(probe)).Set(0)
+		return err
+	}
+	metrics.HealthcheckProbeStatus.WithLabelValues(string(probe)).Set(1)
+	return nil
+}
+
+// checkKubeletPlugin calls the registration and DRA sockets of the kubelet plugin.
+func (h *Healthcheck) checkKubeletPlugin(ctx context.Context) error {
+	log := klog.FromContext(ctx)
 
 	info, err := h.regClient.GetInfo(ctx, &registerapi.InfoRequest{})
 	if err != nil {
-		log.Error(err, "failed to call GetInfo")
-		return status, nil
+		return fmt.Errorf("failed to call GetInfo: %w", err)
 	}
 	log.V(5).Info("Successfully invoked GetInfo", "info", info)
 
 	_, err = h.draClient.NodePrepareResources(ctx, &drapb.NodePrepareResourcesRequest{})
 	if err != nil {
-		log.Error(err, "failed to call NodePrepareResources")
-		return status, nil
+		return fmt.Errorf("failed to call NodePrepareResources: %w", err)
 	}
 	log.V(5).Info("Successfully invoked NodePrepareResources")
+	return nil
+}
 
-	status.Status = grpc_health_v1.HealthCheckResponse_SERVING
-	return status, nil
+func (h *Healthcheck) setCheck(probe consts.HealthcheckProbe, check func(ctx context.Context) error) {
+	h.checksMu.Lock()
+	defer h.checksMu.Unlock()
+	h.checks[probe] = check
+}
+
+func (h *Healthcheck) getCheck(probe consts.HealthcheckProbe) func(ctx context.Context) error {
+	h.checksMu.Lock()
+	defer h.checksMu.Unlock()
+	return h.checks[probe]
 }
@@ -0,0 +1,165 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	drapb "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+type fakeRegistrationServer struct {
+	registerapi.UnimplementedRegistrationServer
+}
+
+func (fakeRegistrationServer) GetInfo(context.Context, *registerapi.InfoRequest) (*registerapi.PluginInfo, error) {
+	return &registerapi.PluginInfo{Name: consts.DriverName}, nil
+}
+
+type fakeDRAPluginServer struct {
+	drapb.UnimplementedDRAPluginServer
+}
+
+func (*fakeDRAPluginServer) NodePrepareResources(context.Context, *drapb.NodePrepareResourcesRequest) (*drapb.NodePrepareResourcesResponse, error) {
+	return &drapb.NodePrepareResourcesResponse{}, nil
+}
+
+// serveFakeKubeletPlugin serves the registration or DRA service of the kubelet plugin on a socket.
+func serveFakeKubeletPlugin(socketPath string, register func(*grpc.Server)) {
+	Expect(os.MkdirAll(filepath.Dir(socketPath), 0750)).To(Succeed())
+	lis, err := net.Listen("unix", socketPath)
+	Expect(err).NotTo(HaveOccurred())
+	server := grpc.NewServer()
+	register(server)
+	go func() { _ = server.Serve(lis) }()
+	DeferCleanup(server.Stop)
+}
+
+var _ = Describe("Healthcheck", func() {
+	var (
+		healthcheck *Healthcheck
+		cniErr      error
+		calls       map[consts.HealthcheckProbe]int
+	)
+
+	check := func(probe consts.HealthcheckProbe, err *error) func(ctx context.Context) error {
+		return func(context.Context) error {
+			calls[probe]++
+			return *err
+		}
+	}
+
+	checkService := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		resp, err := healthcheck.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		Expect(err).NotTo(HaveOccurred())
+		return resp.GetStatus()
+	}
+
+	BeforeEach(func() {
+		cniErr = nil
+		calls = map[consts.HealthcheckProbe]int{}
+		var noErr error
+		healthcheck = newHealthcheck([]string{"kubelet-registration", "cdi", "cni-binary"})
+		healthcheck.setCheck(consts.HealthcheckProbeKubeletRegistration, check(consts.HealthcheckProbeKubeletRegistration, &noErr))
+		healthcheck.setCheck(consts.HealthcheckProbeCDI, check(consts.HealthcheckProbeCDI, &noErr))
+		healthcheck.setCheck(consts.HealthcheckProbeCNIBinary, check(consts.HealthcheckProbeCNIBinary, &cniErr))
+		healthcheck.setCheck(consts.HealthcheckProbeNRI, check(consts.HealthcheckProbeNRI, &noErr))
+	})
+
+	It("runs all the enabled probes for the readiness and default services", func() {
+		Expect(checkService("readiness")).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+		Expect(checkService("")).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+		Expect(calls).To(Equal(map[consts.HealthcheckProbe]int{
+			consts.HealthcheckProbeKubeletRegistration: 2,
+			consts.HealthcheckProbeCDI:                 2,
+			consts.HealthcheckProbeCNIBinary:           2,
+		}))
+	})
+
+	It("only fails the readiness when a readiness probe fails", func() {
+		cniErr = errors.New("sriov plugin not found")
+
+		Expect(checkService("readiness")).To(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+		// the probes after the failed one still run
+		Expect(calls[consts.HealthcheckProbeCDI]).To(Equal(1))
+		Expect(checkService("liveness")).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+		Expect(calls[consts.HealthcheckProbeCNIBinary]).To(Equal(1))
+	})
+
+	It("serves every enabled probe under its name and records its result", func() {
+		Expect(checkService("cni-binary")).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+		Expect(testutil.ToFloat64(metrics.HealthcheckProbeStatus.WithLabelValues("cni-binary"))).To(Equal(1.0))
+
+		cniErr = errors.New("sriov plugin not found")
+		Expect(checkService("cni-binary")).To(Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING))
+		Expect(testutil.ToFloat64(metrics.HealthcheckProbeStatus.WithLabelValues("cni-binary"))).To(Equal(0.0))
+		Expect(calls).To(HaveLen(1))
+	})
+
+	It("does not serve unknown or disabled probes", func() {
+		for _, service := range []string{"nri", "unknown"} {
+			_, err := healthcheck.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+			Expect(status.Code(err)).To(Equal(codes.NotFound))
+		}
+	})
+
+	It("passes the probes whose check is not set", func() {
+		healthcheck = newHealthcheck([]string{"discovery", "nri"})
+		Expect(checkService("readiness")).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+		Expect(checkService("liveness")).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+	})
+
+	It("validates the probes", func() {
+		Expect(ValidateHealthcheckProbes([]string{"discovery", "cdi", "nri", "cni-binary", "kubelet-registration"})).To(Succeed())
+		Expect(ValidateHealthcheckProbes(nil)).To(Succeed())
+		Expect(ValidateHealthcheckProbes([]string{"cdi", "dhcp"})).To(MatchError(ContainSubstring(`unsupported healthcheck probe "dhcp"`)))
+	})
+})
+
+var _ = Describe("startHealthcheck", func() {
+	It("checks the sockets suffixed with the pod UID with rolling updates", func() {
+		// unix socket paths are limited to 108 characters
+		dir, err := os.MkdirTemp("", "health")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		config := &types.Config{Flags: &types.Flags{
+			HealthcheckPort:               0,
+			HealthcheckProbes:             []string{string(consts.HealthcheckProbeKubeletRegistration)},
+			KubeletRegistrarDirectoryPath: filepath.Join(dir, "registry"),
+			KubeletPluginsDirectoryPath:   filepath.Join(dir, "plugins"),
+			PodUID:                        "pod-uid",
+		}}
+		Expect(registrarSocketPath(config)).To(Equal(filepath.Join(dir, "registry", consts.DriverName+"-pod-uid-reg.sock")))
+		Expect(draSocketPath(config)).To(Equal(filepath.Join(dir, "plugins", consts.DriverName, "dra-pod-uid.sock")))
+
+		serveFakeKubeletPlugin(registrarSocketPath(config), func(server *grpc.Server) {
+			registerapi.RegisterRegistrationServer(server, fakeRegistrationServer{})
+		})
+		serveFakeKubeletPlugin(draSocketPath(config), func(server *grpc.Server) {
+			drapb.RegisterDRAPluginServer(server, &fakeDRAPluginServer{})
+		})
+
+		healthcheck, err := startHealthcheck(context.Background(), config)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(healthcheck.Stop, klog.Background())
+
+		resp, err := healthcheck.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "liveness"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.GetStatus()).To(Equal(grpc_health_v1.HealthCheckResponse_SERVING))
+	})
+})
@@ -0,0 +1,139 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nodeTasksLockFile is the lock file in the plugin data directory held by the driver instance
+	// running the node tasks, see startNodeTasks.
+	nodeTasksLockFile = "node-tasks.lock"
+	// grpcLockFile is the lock file in the plugin data directory the kubelet plugin helper holds
+	// while serving a prepare or unprepare with rolling updates.
+	grpcLockFile = "serialize.lock"
+	// lockRetryInterval is how often a lock held by the other driver instance is retried.
+	lockRetryInterval = time.Second
+)
+
+// tryLockFile takes an exclusive lock on a file, creating it if needed. It returns nil when
+// another process holds the lock. The lock is released by closing the file, or when the process
+// exits.
+func tryLockFile(lockPath string) (*os.File, error) {
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", lockPath, err)
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+	return file, nil
+}
+
+// lockFile waits for the exclusive lock on a file until the context is done.
+func lockFile(ctx context.Context, lockPath string, interval time.Duration) (*os.File, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		file, err := tryLockFile(lockPath)
+		if err != nil || file != nil {
+			return file, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// startNodeTasks starts the tasks that act on the whole node rather than on the claims the
+// kubelet sends, i.e. the release of the claims of deleted pods. Only one driver instance runs
+// them: during rolling updates the new instance serves the kubelet right away, but waits for the
+// previous one to stop before taking them over, see WaitForNodeTasks.
+func (d *Driver) startNodeTasks(ctx context.Context) error {
+	if !d.config.IsInventoryMode() {
+		collector := newStalePodCollector(d.podManager, d.deviceStateManager.Unprepare, d.lockClaimForRelease)
+		d.staleCollector = collector
+
+		// Deleted pods are released on the next pass instead of after two, and the pods whose
+		// sandbox is removed are looked up in the cache of the watcher
+		if err := startPodDeletionWatcher(ctx, d.client, d.config.Flags.NodeName, collector); err != nil {
+			return fmt.Errorf("failed to start pod deletion watcher: %w", err)
+		}
+
+		// Periodically release claims of pods that disappeared without being unprepared
+		if interval := d.config.Flags.StalePodGCInterval; interval > 0 {
+			go collector.run(ctx, interval)
+		}
+	}
+	close(d.nodeTasksStarted)
+	return nil
+}
+
+// takeOverNodeTasks waits for the previous driver instance to stop, then runs the node tasks.
+func (d *Driver) takeOverNodeTasks(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("takeOverNodeTasks")
+	logger.Info("Another driver instance runs the node tasks, waiting for it to stop")
+
+	lock, err := lockFile(ctx, path.Join(d.config.DriverPluginPath(), nodeTasksLockFile), lockRetryInterval)
+	if err != nil {
+		logger.Error(err, "Stopped waiting for the node tasks")
+		return
+	}
+	d.nodeTasksMu.Lock()
+	d.nodeTasksLock = lock
+	d.nodeTasksMu.Unlock()
+	logger.Info("Taking over the node tasks")
+
+	if err := d.collectOrphanCDISpecsSerialized(ctx); err != nil {
+		logger.Error(err, "Failed to remove orphan CDI specs")
+	}
+	if err := d.startNodeTasks(ctx); err != nil {
+		logger.Error(err, "Failed to start the node tasks")
+		if d.cancelCtx != nil {
+			d.cancelCtx(err)
+		}
+	}
+}
+
+// collectOrphanCDISpecsSerialized removes the orphan CDI specs while the kubelet is served, by
+// holding the lock of the kubelet plugin helper so no prepare of either instance writes its spec
+// before checkpointing the claim in the meantime.
+func (d *Driver) collectOrphanCDISpecsSerialized(ctx context.Context) error {
+	if d.config.RollingUpdateEnabled() {
+		lock, err := lockFile(ctx, path.Join(d.config.DriverPluginPath(), grpcLockFile), 100*time.Millisecond)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+	// the previous instance prepared and unprepared claims since we loaded the checkpoint
+	if err := d.podManager.Reload(); err != nil {
+		return fmt.Errorf("failed to load prepared claims: %w", err)
+	}
+	return collectOrphanCDISpecs(ctx, d.cdi, d.podManager)
+}
+
+// WaitForNodeTasks waits until this driver instance runs the node tasks, i.e. right away unless
+// another instance still runs them, or until the context is done. The NRI plugin must only be
+// started after it returns, so a single instance attaches and releases the networks of the pods.
+func (d *Driver) WaitForNodeTasks(ctx context.Context) error {
+	select {
+	case <-d.nodeTasksStarted:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
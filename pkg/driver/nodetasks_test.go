@@ -0,0 +1,89 @@
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("node tasks", func() {
+	var config *types.Config
+
+	newDriver := func() *Driver {
+		pm, err := podmanager.NewPodManager(config)
+		Expect(err).ToNot(HaveOccurred())
+		cdiHandler, err := cdi.NewHandler(GinkgoT().TempDir())
+		Expect(err).ToNot(HaveOccurred())
+		return New(config, nil, pm, cdiHandler)
+	}
+
+	BeforeEach(func() {
+		config = &types.Config{Flags: &types.Flags{
+			KubeletPluginsDirectoryPath: GinkgoT().TempDir(),
+			Mode:                        string(consts.DriverModeInventory),
+			PodUID:                      "pod-uid",
+		}}
+		Expect(os.MkdirAll(config.DriverPluginPath(), 0750)).To(Succeed())
+	})
+
+	It("only lets one process hold a lock file", func() {
+		lockPath := filepath.Join(config.DriverPluginPath(), nodeTasksLockFile)
+		lock, err := tryLockFile(lockPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lock).ToNot(BeNil())
+
+		// flock locks are per open file, so a second open behaves like another instance
+		other, err := tryLockFile(lockPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(other).To(BeNil())
+
+		Expect(lock.Close()).To(Succeed())
+		other, err = tryLockFile(lockPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(other).ToNot(BeNil())
+		Expect(other.Close()).To(Succeed())
+	})
+
+	It("hands the node tasks over once the previous instance stops", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		previous := newDriver()
+		lock, err := tryLockFile(filepath.Join(config.DriverPluginPath(), nodeTasksLockFile))
+		Expect(err).ToNot(HaveOccurred())
+		previous.nodeTasksLock = lock
+		Expect(previous.startNodeTasks(ctx)).To(Succeed())
+		Expect(previous.WaitForNodeTasks(ctx)).To(Succeed())
+
+		next := newDriver()
+		go next.takeOverNodeTasks(ctx)
+		Consistently(next.nodeTasksStarted).ShouldNot(BeClosed())
+
+		Expect(previous.nodeTasksLock.Close()).To(Succeed())
+		Eventually(next.nodeTasksStarted, "5s").Should(BeClosed())
+		Expect(next.WaitForNodeTasks(ctx)).To(Succeed())
+		Expect(next.nodeTasksLock.Close()).To(Succeed())
+	})
+
+	It("stops waiting for the node tasks when the context is done", func() {
+		lock, err := tryLockFile(filepath.Join(config.DriverPluginPath(), nodeTasksLockFile))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lock).ToNot(BeNil())
+		defer lock.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		next := newDriver()
+		next.takeOverNodeTasks(ctx)
+		Expect(next.nodeTasksLock).To(BeNil())
+		Expect(next.WaitForNodeTasks(ctx)).To(MatchError(context.Canceled))
+	})
+})
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	sriovdratype "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// stalePodCollector removes pod manager entries for pods that no longer exist on the node.
+// A pod is only collected after it was found missing in two consecutive passes, so pods
+// that are being torn down by the kubelet get a chance to unprepare their claims first.
+type stalePodCollector struct {
+	podManager *podmanager.PodManager
+	// unprepare reverts the device changes and removes the CDI specs of a claim.
+	unprepare func(claimUID string, preparedDevices sriovdratype.PreparedDevices) error
+	// lockClaim serializes the release of a claim with its unprepare by the kubelet, see
+	// Driver.lockClaimForRelease.
+	lockClaim func(ctx context.Context, claimUID k8stypes.UID) (func(), error)
+	// missing tracks pods that were not found on the node in the previous pass, or were
+	// reported deleted since.
+	missingMu sync.Mutex
+	missing   map[k8stypes.UID]bool
+	// pods caches the pods of the node indexed by UID, see startPodDeletionWatcher. It must be
+	// set before collecting.
+	pods cache.Indexer
+}
+
+func newStalePodCollector(podManager *podmanager.PodManager,
+	unprepare func(string, sriovdratype.PreparedDevices) error,
+	lockClaim func(context.Context, k8stypes.UID) (func(), error)) *stalePodCollector {
+	return &stalePodCollector{
+		podManager: podManager,
+		unprepare:  unprepare,
+		lockClaim:  lockClaim,
+		missing:    make(map[k8stypes.UID]bool),
+	}
+}
+
+// markMissing records a pod as missing, e.g. when it is deleted, so the next pass collects it if
+// it is still not found on the node, without waiting for a second pass.
+func (c *stalePodCollector) markMissing(podUID k8stypes.UID) {
+	c.missingMu.Lock()
+	defer c.missingMu.Unlock()
+	c.missing[podUID] = true
+}
+
+// run periodically collects stale pods until the context is done.
+func (c *stalePodCollector) run(ctx context.Context, interval time.Duration) {
+	logger := klog.FromContext(ctx).WithName("stalePodCollector")
+	logger.Info("Starting stale pod garbage collection", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.collect(ctx); err != nil {
+				logger.Error(err, "Stale pod garbage collection failed")
+			}
+		}
+	}
+}
+
+// collect runs a single garbage collection pass.
+func (c *stalePodCollector) collect(ctx context.Context) error {
+	logger := klog.FromContext(ctx).WithName("stalePodCollector")
+
+	// the pods marked missing during this pass are kept for the next one
+	c.missingMu.Lock()
+	previouslyMissing := c.missing
+	c.missing = make(map[k8stypes.UID]bool)
+	c.missingMu.Unlock()
+
+	trackedPods := c.podManager.GetPodUIDs()
+	if len(trackedPods) == 0 {
+		return nil
+	}
+
+	var errs []error
+	missing := make(map[k8stypes.UID]bool)
+	for _, podUID := range trackedPods {
+		// the pods are looked up in the cache of the watcher rather than listed on every pass
+		exists, err := c.podExists(podUID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if !previouslyMissing[podUID] {
+			logger.V(2).Info("Pod not found on node, will collect on next pass if still missing", "pod", podUID)
+			missing[podUID] = true
+			continue
+		}
+
+		logger.Info("Collecting prepared claims of stale pod", "pod", podUID)
+		if err := c.collectPod(ctx, podUID); err != nil {
+			// keep the pod as a candidate so the next pass retries immediately
+			missing[podUID] = true
+			errs = append(errs, err)
+		}
+	}
+	c.missingMu.Lock()
+	for podUID := range missing {
+		c.missing[podUID] = true
+	}
+	c.missingMu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// collectPodIfGone collects a pod immediately if it is no longer in the pod cache. Its claims
+// are only released if still prepared once the unprepare by the kubelet, running in parallel,
+// completed.
+func (c *stalePodCollector) collectPodIfGone(ctx context.Context, podUID k8stypes.UID) error {
+	exists, err := c.podExists(podUID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	klog.FromContext(ctx).WithName("stalePodCollector").Info("Collecting prepared claims of stale pod", "pod", podUID)
+	return c.collectPod(ctx, podUID)
+}
+
+// podExists reports whether a pod of the node is in the pod cache.
+func (c *stalePodCollector) podExists(podUID k8stypes.UID) (bool, error) {
+	if c.pods == nil {
+		return false, fmt.Errorf("pod cache not started")
+	}
+	pods, err := c.pods.ByIndex(podUIDIndex, string(podUID))
+	if err != nil {
+		return false, fmt.Errorf("failed to look up pod %s: %w", podUID, err)
+	}
+	return len(pods) > 0, nil
+}
+
+// collectPod unprepares all claims of a stale pod and removes it from the pod manager.
+func (c *stalePodCollector) collectPod(ctx context.Context, podUID k8stypes.UID) error {
+	claims, found := c.podManager.GetClaimsByPodUID(podUID)
+	if !found {
+		return nil
+	}
+	var errs []error
+	for claimUID := range claims {
+		if err := c.collectClaim(ctx, podUID, claimUID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// collectClaim unprepares a claim of a stale pod, unless the kubelet unprepared it meanwhile, and
+// drops the reference of the pod to it. A pod is removed from the pod manager with its last claim.
+func (c *stalePodCollector) collectClaim(ctx context.Context, podUID, claimUID k8stypes.UID) error {
+	unlock, err := c.lockClaim(ctx, claimUID)
+	if err != nil {
+		return fmt.Errorf("failed to lock claim %s of stale pod %s: %w", claimUID, podUID, err)
+	}
+	defer unlock()
+
+	preparedDevices, found := c.podManager.Get(podUID, claimUID)
+	if !found {
+		return nil
+	}
+	// a claim still referenced by other pods only loses this pod's reference
+	last, err := c.podManager.ReleaseClaim(podUID, claimUID)
+	if err != nil {
+		return fmt.Errorf("failed to release claim %s of stale pod %s: %w", claimUID, podUID, err)
+	}
+	if !last {
+		return nil
+	}
+	if err := c.unprepare(string(claimUID), preparedDevices); err != nil {
+		return fmt.Errorf("failed to unprepare claim %s of stale pod %s: %w", claimUID, podUID, err)
+	}
+	if err := c.podManager.DeleteClaim(kubeletplugin.NamespacedObject{UID: claimUID}); err != nil {
+		return fmt.Errorf("failed to delete claim %s of stale pod %s: %w", claimUID, podUID, err)
+	}
+	return nil
+}
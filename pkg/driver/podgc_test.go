@@ -0,0 +1,182 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// noClaimLock is the claim lock of collectors of tests without concurrent unprepares.
+func noClaimLock(context.Context, k8stypes.UID) (func(), error) {
+	return func() {}, nil
+}
+
+var _ = Describe("stalePodCollector", func() {
+	var (
+		pm          *podmanager.PodManager
+		unprepared  []string
+		unprepareFn func(string, types.PreparedDevices) error
+	)
+
+	newPod := func(uid, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: uid, Namespace: "default", UID: k8stypes.UID(uid)},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+
+	// watchPods serves the pods of the client to the collector from the cache of the watcher.
+	watchPods := func(client *k8sfake.Clientset, c *stalePodCollector) {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		Expect(startPodDeletionWatcher(ctx, client, "node1", c)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		pm, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pm.Set("live-pod", "live-claim", types.PreparedDevices{{PciAddress: "0000:01:00.1"}})).To(Succeed())
+		Expect(pm.Set("gone-pod", "gone-claim", types.PreparedDevices{{PciAddress: "0000:01:00.2"}})).To(Succeed())
+
+		unprepared = nil
+		unprepareFn = func(claimUID string, _ types.PreparedDevices) error {
+			unprepared = append(unprepared, claimUID)
+			return nil
+		}
+	})
+
+	It("collects pods only after they are missing in two consecutive passes", func() {
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		watchPods(client, c)
+		// the pods are not listed from the API server on each pass
+		client.ClearActions()
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(BeEmpty())
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod"), k8stypes.UID("gone-pod")))
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(ConsistOf("gone-claim"))
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod")))
+		Expect(client.Actions()).To(BeEmpty())
+	})
+
+	It("does not collect a pod that reappears", func() {
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		watchPods(client, c)
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		_, err := client.CoreV1().Pods("default").Create(context.Background(), newPod("gone-pod", "node1"), metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func() (bool, error) { return c.podExists("gone-pod") }).Should(BeTrue())
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(BeEmpty())
+		Expect(pm.GetPodUIDs()).To(HaveLen(2))
+	})
+
+	It("only drops the reference of a stale pod to a shared claim", func() {
+		Expect(pm.SetShared([]k8stypes.UID{"gone-pod", "live-pod"}, "shared-claim", types.PreparedDevices{{PciAddress: "0000:01:00.3"}})).To(Succeed())
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		watchPods(client, c)
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(ConsistOf("gone-claim"))
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod")))
+		Expect(pm.ClaimRefCount("shared-claim")).To(Equal(1))
+	})
+
+	It("unprepares a shared claim once its last pod is gone", func() {
+		Expect(pm.SetShared([]k8stypes.UID{"gone-pod", "other-gone-pod"}, "shared-claim", types.PreparedDevices{{PciAddress: "0000:01:00.3"}})).To(Succeed())
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		watchPods(client, c)
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(ConsistOf("gone-claim", "shared-claim"))
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod")))
+		Expect(pm.ClaimRefCount("shared-claim")).To(BeZero())
+	})
+
+	It("keeps the pod when unprepare fails", func() {
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, func(string, types.PreparedDevices) error {
+			return fmt.Errorf("restore failed")
+		}, noClaimLock)
+		watchPods(client, c)
+
+		Expect(c.collect(context.Background())).To(Succeed())
+		err := c.collect(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("restore failed"))
+		Expect(pm.GetPodUIDs()).To(ContainElement(k8stypes.UID("gone-pod")))
+	})
+
+	It("does not release a claim the kubelet unprepared while waiting for its lock", func() {
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, func(_ context.Context, claimUID k8stypes.UID) (func(), error) {
+			// the unprepare served to the kubelet held the lock
+			Expect(pm.DeleteClaim(kubeletplugin.NamespacedObject{UID: claimUID})).To(Succeed())
+			return func() {}, nil
+		})
+		watchPods(client, c)
+
+		c.markMissing("gone-pod")
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(BeEmpty())
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod")))
+	})
+
+	It("collects a pod marked missing on the next pass", func() {
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		watchPods(client, c)
+
+		c.markMissing("gone-pod")
+		Expect(c.collect(context.Background())).To(Succeed())
+		Expect(unprepared).To(ConsistOf("gone-claim"))
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod")))
+	})
+
+	It("fails without the pod cache", func() {
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		Expect(c.collect(context.Background())).To(MatchError(ContainSubstring("pod cache not started")))
+		Expect(unprepared).To(BeEmpty())
+	})
+
+	It("immediately collects a pod that is no longer in the pod cache", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		client := k8sfake.NewSimpleClientset(newPod("live-pod", "node1"))
+		c := newStalePodCollector(pm, unprepareFn, noClaimLock)
+		Expect(c.collectPodIfGone(ctx, "gone-pod")).To(MatchError(ContainSubstring("pod cache not started")))
+		Expect(startPodDeletionWatcher(ctx, client, "node1", c)).To(Succeed())
+		// the pods are not listed from the API server anymore
+		client.ClearActions()
+
+		Expect(c.collectPodIfGone(context.Background(), "live-pod")).To(Succeed())
+		Expect(unprepared).To(BeEmpty())
+
+		Expect(c.collectPodIfGone(context.Background(), "gone-pod")).To(Succeed())
+		Expect(unprepared).To(ConsistOf("gone-claim"))
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("live-pod")))
+		Expect(client.Actions()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	coreclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// podUIDIndex indexes the pods of the pod informer by UID.
+const podUIDIndex = "uid"
+
+// startPodDeletionWatcher watches the pods scheduled on the node and marks a pod missing in the
+// collector as soon as it is deleted, so its prepared claims are released on the next stale pod
+// collection pass instead of after two. The informer also serves the pods the collector looks
+// up, see stalePodCollector.podExists.
+func startPodDeletionWatcher(ctx context.Context, client coreclientset.Interface, nodeName string, collector *stalePodCollector) error {
+	logger := klog.FromContext(ctx).WithName("podDeletionWatcher")
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}))
+	informer := factory.Core().V1().Pods().Informer()
+	err := informer.AddIndexers(cache.Indexers{podUIDIndex: func(obj interface{}) ([]string, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return nil, nil
+		}
+		return []string{string(pod.UID)}, nil
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to add pod UID index: %w", err)
+	}
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if _, found := collector.podManager.GetClaimsByPodUID(pod.UID); !found {
+				return
+			}
+			// the kubelet may still be unpreparing the claims, the collector only releases them
+			// if the pod is still missing on its next pass
+			logger.Info("Pod deleted, releasing its prepared claims on the next collection pass", "pod", klog.KObj(pod), "podUID", pod.UID)
+			collector.markMissing(pod.UID)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add pod event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync pod informer")
+	}
+	collector.pods = informer.GetIndexer()
+	logger.Info("Started pod deletion watcher")
+	return nil
+}
@@ -0,0 +1,71 @@
+package driver
+
+import (
+	"context"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("podDeletionWatcher", func() {
+	var (
+		pm         *podmanager.PodManager
+		mu         sync.Mutex
+		unprepared []string
+		collector  *stalePodCollector
+		client     *k8sfake.Clientset
+	)
+
+	BeforeEach(func() {
+		var err error
+		pm, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pm.Set("pod-1", "claim-1", types.PreparedDevices{{PciAddress: "0000:01:00.1"}})).To(Succeed())
+		Expect(pm.Set("pod-2", "claim-2", types.PreparedDevices{{PciAddress: "0000:01:00.2"}})).To(Succeed())
+
+		client = k8sfake.NewSimpleClientset(
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default", UID: "pod-1"}, Spec: corev1.PodSpec{NodeName: "node1"}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default", UID: "pod-2"}, Spec: corev1.PodSpec{NodeName: "node1"}},
+		)
+		mu.Lock()
+		unprepared = nil
+		mu.Unlock()
+		collector = newStalePodCollector(pm, func(claimUID string, _ types.PreparedDevices) error {
+			mu.Lock()
+			defer mu.Unlock()
+			unprepared = append(unprepared, claimUID)
+			return nil
+		}, noClaimLock)
+	})
+
+	It("releases the claims of a deleted pod on the next collection pass", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(startPodDeletionWatcher(ctx, client, "node1", collector)).To(Succeed())
+
+		Expect(client.CoreV1().Pods("default").Delete(ctx, "pod-1", metav1.DeleteOptions{})).To(Succeed())
+
+		// the kubelet gets a chance to unprepare the claims first
+		Eventually(func() bool {
+			collector.missingMu.Lock()
+			defer collector.missingMu.Unlock()
+			return collector.missing["pod-1"]
+		}).Should(BeTrue())
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("pod-1"), k8stypes.UID("pod-2")))
+
+		Expect(collector.collect(ctx)).To(Succeed())
+		Expect(pm.GetPodUIDs()).To(ConsistOf(k8stypes.UID("pod-2")))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(unprepared).To(ConsistOf("claim-1"))
+	})
+})
@@ -252,4 +252,24 @@ users:
 			_ = clientSets.Client
 		})
 	})
+
+	Context("LoggingConfig", func() {
+		It("should add the log file flags and accept --log-format", func() {
+			names := map[string]cli.Flag{}
+			for _, flag := range flags.NewLoggingConfig().Flags() {
+				for _, name := range flag.Names() {
+					names[name] = flag
+				}
+			}
+
+			Expect(names).To(HaveKey("logging-format"))
+			Expect(names["log-format"]).To(BeIdenticalTo(names["logging-format"]))
+			Expect(names["log-format"].(*cli.GenericFlag).EnvVars).To(ContainElements("LOGGING_FORMAT", "LOG_FORMAT"))
+
+			Expect(names).To(HaveKey("log-file"))
+			Expect(names["log-file-max-size"].(*cli.IntFlag).Value).To(Equal(100))
+			Expect(names["log-file-max-backups"].(*cli.IntFlag).Value).To(Equal(5))
+			Expect(names["alsologtostderr"].(*cli.BoolFlag).EnvVars).To(ContainElement("ALSOLOGTOSTDERR"))
+		})
+	})
 })
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotatingFile is a log file rotated once it would grow past maxSize bytes, keeping up to
+// maxBackups rotated files named after it with the suffixes .1, the most recent, to .<maxBackups>.
+// A maxSize of zero disables the rotation.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log file directory: %w", err)
+	}
+	f := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer. Entries are never split across files.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the rotated files, dropping the oldest one, and starts a new file.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove log file: %w", err)
+		}
+		return f.open()
+	}
+	for i := f.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return f.open()
+}
@@ -0,0 +1,69 @@
+package flags
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rotatingFile", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "logs", "driver.log")
+	})
+
+	readFile := func(name string) string {
+		content, err := os.ReadFile(name)
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	It("rotates the file before it grows past its maximum size", func() {
+		file, err := openRotatingFile(path, 10, 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, entry := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+			_, err := file.Write([]byte(entry))
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(readFile(path)).To(Equal("fourth\n"))
+		Expect(readFile(path + ".1")).To(Equal("third\n"))
+		// the oldest file is dropped
+		Expect(readFile(path + ".2")).To(Equal("second\n"))
+		Expect(path + ".3").NotTo(BeAnExistingFile())
+	})
+
+	It("appends to an existing file", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("before\n"), 0o644)).To(Succeed())
+
+		file, err := openRotatingFile(path, 10, 1)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = file.Write([]byte("after\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(readFile(path)).To(Equal("after\n"))
+		Expect(readFile(path + ".1")).To(Equal("before\n"))
+	})
+
+	It("truncates the file without backups and never rotates without maximum size", func() {
+		file, err := openRotatingFile(path, 10, 0)
+		Expect(err).NotTo(HaveOccurred())
+		for _, entry := range []string{"first\n", "second\n"} {
+			_, err := file.Write([]byte(entry))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(readFile(path)).To(Equal("second\n"))
+		Expect(path + ".1").NotTo(BeAnExistingFile())
+
+		file, err = openRotatingFile(path, 0, 1)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = file.Write([]byte("a very long entry\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readFile(path)).To(Equal("second\na very long entry\n"))
+	})
+})
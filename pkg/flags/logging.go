@@ -17,6 +17,9 @@
 package flags
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -32,6 +35,13 @@ import (
 type LoggingConfig struct {
 	featureGate featuregate.MutableVersionedFeatureGate
 	config      *logsapi.LoggingConfiguration
+
+	// logFile receives the logs instead of stderr when set, rotated once it reaches
+	// logFileMaxSize MiB, see rotatingFile.
+	logFile           string
+	logFileMaxSize    int
+	logFileMaxBackups int
+	alsoLogToStderr   bool
 }
 
 func NewLoggingConfig() *LoggingConfig {
@@ -49,7 +59,24 @@ func NewLoggingConfig() *LoggingConfig {
 // Apply should be called in a cli.App.Before directly after parsing command
 // line flags and before running any code which emits log entries.
 func (l *LoggingConfig) Apply() error {
-	return logsapi.ValidateAndApply(l.config, l.featureGate)
+	if l.logFile == "" {
+		return logsapi.ValidateAndApply(l.config, l.featureGate)
+	}
+	if l.logFileMaxSize < 0 || l.logFileMaxBackups < 0 {
+		return fmt.Errorf("log-file-max-size and log-file-max-backups must not be negative")
+	}
+	file, err := openRotatingFile(l.logFile, int64(l.logFileMaxSize)*1024*1024, l.logFileMaxBackups)
+	if err != nil {
+		return err
+	}
+	var output io.Writer = file
+	if l.alsoLogToStderr {
+		output = io.MultiWriter(file, os.Stderr)
+	}
+	return logsapi.ValidateAndApplyWithOptions(l.config, &logsapi.LoggingOptions{
+		ErrorStream: output,
+		InfoStream:  output,
+	}, l.featureGate)
 }
 
 // Flags returns the flags for the configuration.
@@ -70,12 +97,49 @@ func (l *LoggingConfig) Flags() []cli.Flag {
 
 	var flags []cli.Flag
 	fs.VisitAll(func(flag *pflag.Flag) {
-		flags = append(flags, pflagToCLI(flag, "Logging:"))
+		cliFlag := pflagToCLI(flag, "Logging:")
+		// --log-format is the name used by the other flags of the driver and the chart
+		if flag.Name == "logging-format" {
+			cliFlag.Aliases = []string{"log-format"}
+			cliFlag.EnvVars = append(cliFlag.EnvVars, "LOG_FORMAT")
+		}
+		flags = append(flags, cliFlag)
 	})
-	return flags
+	return append(flags,
+		&cli.StringFlag{
+			Category:    "Logging:",
+			Name:        "log-file",
+			Usage:       "Write the logs, in the format of --logging-format, to this file instead of stderr.",
+			Destination: &l.logFile,
+			EnvVars:     []string{"LOG_FILE"},
+		},
+		&cli.IntFlag{
+			Category:    "Logging:",
+			Name:        "log-file-max-size",
+			Usage:       "Size in MiB at which the --log-file is rotated. Zero disables the rotation.",
+			Value:       100,
+			Destination: &l.logFileMaxSize,
+			EnvVars:     []string{"LOG_FILE_MAX_SIZE"},
+		},
+		&cli.IntFlag{
+			Category:    "Logging:",
+			Name:        "log-file-max-backups",
+			Usage:       "Number of rotated --log-file files kept, with the suffixes .1 (the most recent) to .<max-backups>.",
+			Value:       5,
+			Destination: &l.logFileMaxBackups,
+			EnvVars:     []string{"LOG_FILE_MAX_BACKUPS"},
+		},
+		&cli.BoolFlag{
+			Category:    "Logging:",
+			Name:        "alsologtostderr",
+			Usage:       "Also write the logs to stderr when --log-file is set.",
+			Destination: &l.alsoLogToStderr,
+			EnvVars:     []string{"ALSOLOGTOSTDERR"},
+		},
+	)
 }
 
-func pflagToCLI(flag *pflag.Flag, category string) cli.Flag {
+func pflagToCLI(flag *pflag.Flag, category string) *cli.GenericFlag {
 	return &cli.GenericFlag{
 		Name:        flag.Name,
 		Category:    category,
@@ -0,0 +1,47 @@
+package host
+
+import (
+	"fmt"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+)
+
+// pciBusName is the devlink and vDPA bus name of PCI devices
+const pciBusName = "pci"
+
+// IsSwitchdevCapable reports whether the eswitch of a PF can be switched to switchdev mode, i.e.
+// its driver reports an eswitch mode through devlink.
+func (h *Host) IsSwitchdevCapable(pfPciAddress string) bool {
+	device, err := h.netlinkProvider.DevLinkGetDeviceByName(pciBusName, pfPciAddress)
+	if err != nil {
+		h.log.V(2).Info("IsSwitchdevCapable(): failed to get devlink device", "device", pfPciAddress, "error", err.Error())
+		return false
+	}
+	return device.Attrs.Eswitch.Mode != ""
+}
+
+// GetNicSriovMode returns the eswitch mode of a PF, legacy or switchdev, as reported by devlink.
+// PFs whose driver does not report an eswitch mode are in legacy mode.
+func (h *Host) GetNicSriovMode(pfPciAddress string) string {
+	device, err := h.netlinkProvider.DevLinkGetDeviceByName(pciBusName, pfPciAddress)
+	if err != nil || device.Attrs.Eswitch.Mode == "" {
+		return consts.EswitchModeLegacy
+	}
+	return device.Attrs.Eswitch.Mode
+}
+
+// GetVDPAManagementDevices returns the PCI addresses of the devices vDPA devices can be created
+// on, e.g. the VFs of NICs whose vDPA driver is loaded.
+func (h *Host) GetVDPAManagementDevices() ([]string, error) {
+	mgmtDevs, err := h.netlinkProvider.VDPAGetMGMTDevList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vDPA management devices: %w", err)
+	}
+	var pciAddresses []string
+	for _, mgmtDev := range mgmtDevs {
+		if mgmtDev.BusName == pciBusName {
+			pciAddresses = append(pciAddresses, mgmtDev.DevName)
+		}
+	}
+	return pciAddresses, nil
+}
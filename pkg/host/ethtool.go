@@ -0,0 +1,237 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ethtoolStringSetStats is ETH_SS_STATS, the string set naming the statistics of a NIC.
+	ethtoolStringSetStats = 1
+	// ethtoolStringSetFeatures is ETH_SS_FEATURES, the string set naming the features of a NIC.
+	ethtoolStringSetFeatures = 4
+	// ethtoolStringLen is ETH_GSTRING_LEN, the length of the name of a statistic.
+	ethtoolStringLen = 32
+)
+
+// DriverInfo is the driver information of a network interface reported by ethtool -i.
+type DriverInfo struct {
+	Driver          string
+	Version         string
+	FirmwareVersion string
+}
+
+// ethtoolDrvinfo runs the ethtool GDRVINFO command on an interface in a network namespace, the
+// one of the driver when the path is empty, replaced in tests.
+var ethtoolDrvinfo = func(netnsPath, ifName string) (*unix.EthtoolDrvinfo, error) {
+	fd, err := ethtoolSocketAt(netnsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer unix.Close(fd)
+	return unix.IoctlGetEthtoolDrvinfo(fd, ifName)
+}
+
+// GetDriverInfo returns the driver name, driver version and firmware version of a network
+// interface, as reported by ethtool GDRVINFO
+func (h *Host) GetDriverInfo(ifName string) (*DriverInfo, error) {
+	drvinfo, err := ethtoolDrvinfo("", ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver info of interface %s: %w", ifName, err)
+	}
+	return &DriverInfo{
+		Driver:          unix.ByteSliceToString(drvinfo.Driver[:]),
+		Version:         unix.ByteSliceToString(drvinfo.Version[:]),
+		FirmwareVersion: unix.ByteSliceToString(drvinfo.Fw_version[:]),
+	}, nil
+}
+
+// ethtoolIfreq is the ifreq of the SIOCETHTOOL ioctl, pointing to the ethtool command.
+type ethtoolIfreq struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [unsafe.Sizeof(unix.Ifreq{}) - unix.IFNAMSIZ - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ethtoolSsetInfo is struct ethtool_sset_info requesting the size of a single string set.
+type ethtoolSsetInfo struct {
+	cmd      uint32
+	reserved uint32
+	mask     uint64
+	data     uint32
+}
+
+// ethtoolIoctl runs an ethtool command on an interface.
+func ethtoolIoctl(fd int, ifName string, data unsafe.Pointer) error {
+	_, err := ethtoolIoctlReturn(fd, ifName, data)
+	return err
+}
+
+// ethtoolIoctlReturn runs an ethtool command on an interface and returns the non-negative value
+// returned by the commands reporting a partial success.
+func ethtoolIoctlReturn(fd int, ifName string, data unsafe.Pointer) (uintptr, error) {
+	ifr := ethtoolIfreq{data: data}
+	copy(ifr.name[:unix.IFNAMSIZ-1], ifName)
+	ret, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return ret, nil
+}
+
+// ethtoolSocketAt opens the socket ethtool commands are run on in a network namespace, the one of
+// the driver when the path is empty. Interfaces are only visible from their network namespace.
+func ethtoolSocketAt(netnsPath string) (int, error) {
+	if netnsPath == "" {
+		return unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	}
+	fd := -1
+	err := doInNetns(netnsPath, func() error {
+		// the socket stays in the network namespace it was created in
+		var err error
+		fd, err = unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open ethtool socket: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if fd >= 0 {
+			unix.Close(fd)
+		}
+		return -1, err
+	}
+	return fd, nil
+}
+
+// ethtoolStrings returns the strings of a string set of an interface, e.g. the names of its
+// statistics or of its features, empty when the interface does not report the set.
+func ethtoolStrings(fd int, ifName string, stringSet uint32) ([]string, error) {
+	ssetInfo := ethtoolSsetInfo{cmd: unix.ETHTOOL_GSSET_INFO, mask: 1 << stringSet}
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&ssetInfo)); err != nil {
+		return nil, err
+	}
+	count := int(ssetInfo.data)
+	if ssetInfo.mask == 0 || count == 0 {
+		return nil, nil
+	}
+
+	gstrings := make([]byte, 12+count*ethtoolStringLen)
+	binary.NativeEndian.PutUint32(gstrings[0:], unix.ETHTOOL_GSTRINGS)
+	binary.NativeEndian.PutUint32(gstrings[4:], stringSet)
+	binary.NativeEndian.PutUint32(gstrings[8:], uint32(count)) // #nosec G115 -- count comes from a uint32
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&gstrings[0])); err != nil {
+		return nil, err
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = unix.ByteSliceToString(gstrings[12+i*ethtoolStringLen : 12+(i+1)*ethtoolStringLen])
+	}
+	return names, nil
+}
+
+// ethtoolSetFeature runs the ethtool SFEATURES command turning a feature of an interface on or
+// off, e.g. hw-tc-offload, replaced in tests.
+var ethtoolSetFeature = func(ifName, feature string, enable bool) error {
+	fd, err := ethtoolSocketAt("")
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	names, err := ethtoolStrings(fd, ifName, ethtoolStringSetFeatures)
+	if err != nil {
+		return err
+	}
+	index := slices.Index(names, feature)
+	if index < 0 {
+		return fmt.Errorf("feature %s not reported by interface %s", feature, ifName)
+	}
+
+	// struct ethtool_sfeatures, followed by one {valid, requested} block per 32 features
+	blocks := (len(names) + 31) / 32
+	sfeatures := make([]byte, 8+blocks*8)
+	binary.NativeEndian.PutUint32(sfeatures[0:], unix.ETHTOOL_SFEATURES)
+	binary.NativeEndian.PutUint32(sfeatures[4:], uint32(blocks)) // #nosec G115 -- blocks comes from a uint32
+	block := 8 + (index/32)*8
+	bit := uint32(1) << (index % 32)
+	binary.NativeEndian.PutUint32(sfeatures[block:], bit)
+	if enable {
+		binary.NativeEndian.PutUint32(sfeatures[block+4:], bit)
+	}
+	ret, err := ethtoolIoctlReturn(fd, ifName, unsafe.Pointer(&sfeatures[0]))
+	if err != nil {
+		return err
+	}
+	// a positive return reports features the driver could not change
+	if ret&unix.ETHTOOL_F_UNSUPPORTED != 0 {
+		return fmt.Errorf("feature %s of interface %s cannot be changed", feature, ifName)
+	}
+	return nil
+}
+
+// ethtoolStats runs the ethtool GSTRINGS and GSTATS commands on an interface in a network
+// namespace, returning the names and the values of its statistics, replaced in tests.
+var ethtoolStats = func(netnsPath, ifName string) ([]string, []uint64, error) {
+	fd, err := ethtoolSocketAt(netnsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unix.Close(fd)
+
+	names, err := ethtoolStrings(fd, ifName, ethtoolStringSetStats)
+	if err != nil || len(names) == 0 {
+		return nil, nil, err
+	}
+	count := len(names)
+
+	gstats := make([]byte, 8+count*8)
+	binary.NativeEndian.PutUint32(gstats[0:], unix.ETHTOOL_GSTATS)
+	binary.NativeEndian.PutUint32(gstats[4:], uint32(count)) // #nosec G115 -- count comes from a uint32
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&gstats[0])); err != nil {
+		return nil, nil, err
+	}
+	values := make([]uint64, count)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint64(gstats[8+i*8:])
+	}
+	return names, values, nil
+}
+
+// GetEthtoolStats returns the NIC statistics of a network interface in a network namespace, e.g.
+// the one of the pod a VF is attached to, as reported by ethtool -S. The statistics depend on the
+// driver, interfaces without statistics return an empty map.
+func (h *Host) GetEthtoolStats(netnsPath, ifName string) (map[string]uint64, error) {
+	names, values, err := ethtoolStats(netnsPath, ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ethtool statistics of interface %s in network namespace %s: %w", ifName, netnsPath, err)
+	}
+	if len(names) != len(values) {
+		return nil, fmt.Errorf("interface %s reported %d ethtool statistics names for %d values", ifName, len(names), len(values))
+	}
+	stats := make(map[string]uint64, len(names))
+	for i, name := range names {
+		stats[name] = values[i]
+	}
+	return stats, nil
+}
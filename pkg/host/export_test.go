@@ -0,0 +1,77 @@
+package host
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetFinitModule replaces the finit_module syscall and returns a function restoring it.
+func SetFinitModule(fn func(fd int, params string, flags int) error) func() {
+	orig := finitModule
+	finitModule = fn
+	return func() { finitModule = orig }
+}
+
+// SetSysfsWriteFile replaces the sysfs writes and returns a function restoring them.
+func SetSysfsWriteFile(fn func(name string, data []byte, perm os.FileMode) error) func() {
+	orig := sysfsWriteFile
+	sysfsWriteFile = fn
+	return func() { sysfsWriteFile = orig }
+}
+
+// SetEthtoolDrvinfo replaces the ethtool GDRVINFO command and returns a function restoring it.
+func SetEthtoolDrvinfo(fn func(netnsPath, ifName string) (*unix.EthtoolDrvinfo, error)) func() {
+	orig := ethtoolDrvinfo
+	ethtoolDrvinfo = fn
+	return func() { ethtoolDrvinfo = orig }
+}
+
+// SetEthtoolStats replaces the ethtool GSTRINGS and GSTATS commands and returns a function
+// restoring them.
+func SetEthtoolStats(fn func(netnsPath, ifName string) ([]string, []uint64, error)) func() {
+	orig := ethtoolStats
+	ethtoolStats = fn
+	return func() { ethtoolStats = orig }
+}
+
+// SetEthtoolSetFeature replaces the ethtool SFEATURES command and returns a function restoring it.
+func SetEthtoolSetFeature(fn func(ifName, feature string, enable bool) error) func() {
+	orig := ethtoolSetFeature
+	ethtoolSetFeature = fn
+	return func() { ethtoolSetFeature = orig }
+}
+
+// SetEthtoolChannels replaces the ethtool GCHANNELS and SCHANNELS commands and returns a function
+// restoring them.
+func SetEthtoolChannels(get func(ifName string) (uint32, uint32, error), set func(ifName string, combined uint32) error) func() {
+	origGet, origSet := ethtoolGetChannels, ethtoolSetCombinedChannels
+	ethtoolGetChannels, ethtoolSetCombinedChannels = get, set
+	return func() { ethtoolGetChannels, ethtoolSetCombinedChannels = origGet, origSet }
+}
+
+// SetEthtoolRingSizes replaces the ethtool GRINGPARAM and SRINGPARAM commands and returns a
+// function restoring them.
+func SetEthtoolRingSizes(get func(ifName string) (RingSizes, RingSizes, error), set func(ifName string, sizes RingSizes) error) func() {
+	origGet, origSet := ethtoolGetRingSizes, ethtoolSetRingSizes
+	ethtoolGetRingSizes, ethtoolSetRingSizes = get, set
+	return func() { ethtoolGetRingSizes, ethtoolSetRingSizes = origGet, origSet }
+}
+
+// SetEthtoolGetFeatures replaces the ethtool GFEATURES command and returns a function restoring it.
+func SetEthtoolGetFeatures(fn func(ifName string) (map[string]bool, error)) func() {
+	orig := ethtoolGetFeatures
+	ethtoolGetFeatures = fn
+	return func() { ethtoolGetFeatures = orig }
+}
+
+// SetWriteNetnsSysctls replaces the sysctl writes in network namespaces and returns a function
+// restoring them.
+func SetWriteNetnsSysctls(fn func(netnsPath string, sysctls map[string]string) error) func() {
+	orig := writeNetnsSysctls
+	writeNetnsSysctls = fn
+	return func() { writeNetnsSysctls = orig }
+}
+
+// DoInNetns runs a function in a network namespace, see doInNetns.
+var DoInNetns = doInNetns
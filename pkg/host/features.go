@@ -0,0 +1,84 @@
+package host
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ethtoolGetFeatures runs the ethtool GSTRINGS and GFEATURES commands on an interface, returning
+// whether each of its features is active, keyed by name, replaced in tests.
+var ethtoolGetFeatures = func(ifName string) (map[string]bool, error) {
+	fd, err := ethtoolSocketAt("")
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	names, err := ethtoolStrings(fd, ifName, ethtoolStringSetFeatures)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+
+	// struct ethtool_gfeatures, followed by one {available, requested, active, never_changed}
+	// block per 32 features
+	blocks := (len(names) + 31) / 32
+	gfeatures := make([]byte, 8+blocks*16)
+	binary.NativeEndian.PutUint32(gfeatures[0:], unix.ETHTOOL_GFEATURES)
+	binary.NativeEndian.PutUint32(gfeatures[4:], uint32(blocks)) // #nosec G115 -- blocks comes from a uint32
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&gfeatures[0])); err != nil {
+		return nil, err
+	}
+	features := make(map[string]bool, len(names))
+	for i, name := range names {
+		active := binary.NativeEndian.Uint32(gfeatures[8+(i/32)*16+8:])
+		features[name] = active&(uint32(1)<<(i%32)) != 0
+	}
+	return features, nil
+}
+
+// GetFeatures returns whether the features of the network interface of a device, e.g. rx-lro,
+// are on, as reported by ethtool -k.
+func (h *Host) GetFeatures(pciAddress string) (map[string]bool, error) {
+	ifName := h.TryGetInterfaceName(pciAddress)
+	if ifName == "" {
+		return nil, fmt.Errorf("no network interface found for device %s", pciAddress)
+	}
+	features, err := ethtoolGetFeatures(ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get features of interface %s: %w", ifName, err)
+	}
+	return features, nil
+}
+
+// SetFeatures turns the features of the network interface of a device on or off, like ethtool
+// -K. Features already in the requested state are not changed.
+func (h *Host) SetFeatures(pciAddress string, features map[string]bool) error {
+	ifName := h.TryGetInterfaceName(pciAddress)
+	if ifName == "" {
+		return fmt.Errorf("no network interface found for device %s", pciAddress)
+	}
+	current, err := ethtoolGetFeatures(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get features of interface %s: %w", ifName, err)
+	}
+	for _, feature := range slices.Sorted(maps.Keys(features)) {
+		enable := features[feature]
+		active, ok := current[feature]
+		if !ok {
+			return fmt.Errorf("feature %s not reported by interface %s", feature, ifName)
+		}
+		if active == enable {
+			continue
+		}
+		h.log.V(2).Info("SetFeatures(): set feature", "device", pciAddress, "interface", ifName, "feature", feature, "enable", enable)
+		if err := ethtoolSetFeature(ifName, feature, enable); err != nil {
+			return fmt.Errorf("failed to set feature %s of interface %s: %w", feature, ifName, err)
+		}
+	}
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -82,18 +81,34 @@ type Interface interface {
 	IsSriovVF(pciAddress string) bool
 	IsSriovPF(pciAddress string) bool
 	GetVFList(pfPciAddress string) ([]VFInfo, error)
+	GetSriovVFCounts(pfPciAddress string) (totalVFs, numVFs int, err error)
 
 	// PCI device discovery functionality
 	PCI() (*ghw.PCIInfo, error)
 
 	// Network interface functions
 	TryGetInterfaceName(pciAddr string) string
+	GetInterfaceMACAddress(pciAddr, ifName string) (string, error)
+	GetDriverInfo(ifName string) (*DriverInfo, error)
+	GetEthtoolStats(netnsPath, ifName string) (map[string]uint64, error)
+	GetVPD(pciAddress string) (map[string]string, error)
+	GetBoardID(pciAddress string) (string, error)
+	IsSwitchdevCapable(pfPciAddress string) bool
+	GetVDPAManagementDevices() ([]string, error)
+	GetPrimaryInterfaces(nodeIPs []string) ([]string, error)
 	GetNicSriovMode(pciAddr string) string
 	GetLinkType(pciAddr string) (string, error)
+	GetLinkStatistics(netnsPath, ifName string) (*LinkStatistics, error)
+	GetNetnsInterface(netnsPath, ifName string) (*NetnsInterface, error)
+	SetNetnsSysctls(netnsPath string, sysctls map[string]string) error
+	AddNetnsRoutes(netnsPath, ifName string, routes []Route) error
+	AddNetnsNeighbors(netnsPath, ifName string, neighbors []Neighbor) error
 
 	// Topology functions
 	GetNumaNode(pciAddress string) (string, error)
+	GetNumaNodeCPUs(numaNode string) (string, error)
 	GetPCIeRoot(pciAddress string) (string, error)
+	GetIOMMUGroup(pciAddress string) (int, error)
 
 	// Driver binding operations
 	BindDeviceDriver(pciAddress string, config *configapi.VfConfig) (string, error)
@@ -104,36 +119,73 @@ type Interface interface {
 	BindDriverByBusAndDevice(device, driver string) error
 	UnbindDriverByBusAndDevice(device string) error
 	BindDefaultDriver(pciAddress string) error
+	ResetDevice(pciAddress string) error
 
 	// Driver utility functions
 	IsDpdkDriver(driver string) bool
 
-	// VFIO device functions
+	// VF administrative settings functions
+	GetVFSettings(pciAddress string) (*VFSettings, error)
+	SetVFSettings(pciAddress string, settings *VFSettings) error
+
+	// VF queue functions
+	GetCombinedChannels(pciAddress string) (int, error)
+	SetCombinedChannels(pciAddress string, count int) error
+	GetRingSizes(pciAddress string) (*RingSizes, error)
+	SetRingSizes(pciAddress string, sizes *RingSizes) error
+	GetFeatures(pciAddress string) (map[string]bool, error)
+	SetFeatures(pciAddress string, features map[string]bool) error
+
+	// InfiniBand VF functions
+	GetVFGUIDs(pciAddress string) (nodeGUID, portGUID string, err error)
+	SetVFGUIDs(pciAddress, nodeGUID, portGUID string) error
+
+	// Switchdev representor functions
+	GetVFRepresentor(pciAddress string) (string, error)
+	EnableTCOffload(representor, qdisc string) error
+	DisableTCOffload(representor, qdisc string) error
+
+	// VFIO and UIO device functions
 	GetVFIODeviceFile(pciAddress string) (devFileHost, devFileContainer string, err error)
+	GetUIODeviceFile(pciAddress string) (string, error)
 
 	// Kernel module management functions
 	IsKernelModuleLoaded(moduleName string) bool
 	LoadKernelModule(moduleName string) error
 	EnsureDpdkModuleLoaded(driver string) error
 	EnsureVhostModulesLoaded() error
+	EnsureRDMACMModuleLoaded() error
+	EnableVFIONoIOMMU() error
 
 	// RDMA device functions
 	GetRDMADevicesForPCI(pciAddr string) []string
 	VerifyRDMACapability(pciAddr string) bool
 	GetRDMACharDevices(rdmaDeviceName string) ([]string, error)
+	GetRDMANetnsMode() (string, error)
+	MoveRDMADeviceToNetns(rdmaDeviceName, netnsPath string) error
+	MoveRDMADeviceFromNetns(rdmaDeviceName, netnsPath string) error
 }
 
 // Host provides unified host system functionality for SR-IOV, PCI operations, and driver management
 type Host struct {
-	log          klog.Logger
-	rdmaProvider RdmaProvider
+	log             klog.Logger
+	rdmaProvider    RdmaProvider
+	netlinkProvider NetlinkProvider
+	config          Config
 }
 
-// NewHost creates a new Host instance
+// NewHost creates a new Host instance with the default configuration
 func NewHost() Interface {
+	return NewHostWithConfig(DefaultConfig())
+}
+
+// NewHostWithConfig creates a new Host instance with the given configuration
+func NewHostWithConfig(config Config) Interface {
 	return &Host{
-		log:          klog.FromContext(context.Background()).WithName("Host"),
-		rdmaProvider: newRdmaProvider(),
+		log:             klog.FromContext(context.Background()).WithName("Host"),
+		rdmaProvider:    newRdmaProvider(),
+		netlinkProvider: newNetlinkProvider(),
+		config:          config,
 	}
 }
 
@@ -143,7 +195,21 @@ var (
 	helpersOnce sync.Once
 )
 
-// initHelpers initializes the global Helpers instance
+// InitHelpers initializes the global Helpers instance with the given configuration. It fails when
+// the instance was already initialized, e.g. by an earlier GetHelpers.
+func InitHelpers(config Config) error {
+	initialized := false
+	helpersOnce.Do(func() {
+		Helpers = NewHostWithConfig(config)
+		initialized = true
+	})
+	if !initialized {
+		return fmt.Errorf("host helpers already initialized")
+	}
+	return nil
+}
+
+// initHelpers initializes the global Helpers instance with the default configuration
 func initHelpers() {
 	helpersOnce.Do(func() {
 		Helpers = NewHost()
@@ -162,6 +228,12 @@ func (h *Host) SetRdmaProvider(provider RdmaProvider) {
 	h.rdmaProvider = provider
 }
 
+// SetNetlinkProvider sets the netlink provider for a Host instance
+// This is primarily used for injecting mock providers in unit tests
+func (h *Host) SetNetlinkProvider(provider NetlinkProvider) {
+	h.netlinkProvider = provider
+}
+
 // SR-IOV Detection Functions
 
 // IsSriovVF checks if a PCI device is an SR-IOV Virtual Function
@@ -184,6 +256,26 @@ func (h *Host) IsSriovPF(pciAddress string) bool {
 	return false
 }
 
+// GetSriovVFCounts returns the number of VFs a PF supports (sriov_totalvfs) and the number of
+// VFs currently created on it (sriov_numvfs)
+func (h *Host) GetSriovVFCounts(pfPciAddress string) (totalVFs, numVFs int, err error) {
+	if totalVFs, err = readSysfsInt(buildSysBusPciPath(pfPciAddress, "sriov_totalvfs")); err != nil {
+		return 0, 0, fmt.Errorf("failed to read sriov_totalvfs of PF %s: %w", pfPciAddress, err)
+	}
+	if numVFs, err = readSysfsInt(buildSysBusPciPath(pfPciAddress, "sriov_numvfs")); err != nil {
+		return 0, 0, fmt.Errorf("failed to read sriov_numvfs of PF %s: %w", pfPciAddress, err)
+	}
+	return totalVFs, numVFs, nil
+}
+
+func readSysfsInt(path string) (int, error) {
+	data, err := os.ReadFile(path) /* #nosec G304 */
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
 // GetVFList returns list of VFs for a given PF with their VF IDs and device IDs
 func (h *Host) GetVFList(pfPciAddress string) ([]VFInfo, error) {
 	var vfList []VFInfo
@@ -263,12 +355,14 @@ func (h *Host) TryGetInterfaceName(pciAddr string) string {
 	return fInfos[0].Name()
 }
 
-// GetNicSriovMode returns the interface mode (simplified implementation)
-// This is a simplified version that returns "legacy" mode as fallback
-func (h *Host) GetNicSriovMode(_ string) string {
-	// For simplicity, always return legacy mode
-	// A full implementation would use netlink to query the eswitch mode
-	return "legacy"
+// GetInterfaceMACAddress returns the MAC address of a network interface of a PCI device
+func (h *Host) GetInterfaceMACAddress(pciAddr, ifName string) (string, error) {
+	addressPath := buildSysBusPciPath(pciAddr, filepath.Join("net", ifName, "address"))
+	address, err := os.ReadFile(addressPath) /* #nosec G304 */
+	if err != nil {
+		return "", fmt.Errorf("failed to read MAC address of interface %s: %w", ifName, err)
+	}
+	return strings.TrimSpace(string(address)), nil
 }
 
 // GetLinkType returns the link type for a given network interface
@@ -324,6 +418,17 @@ func (h *Host) GetNumaNode(pciAddress string) (string, error) {
 	return strings.TrimSpace(string(content)), nil
 }
 
+// GetNumaNodeCPUs returns the list of CPUs of a NUMA node, in the kernel cpulist format (e.g. "0-7,16-23").
+func (h *Host) GetNumaNodeCPUs(numaNode string) (string, error) {
+	cpuListPath := buildSysPath(filepath.Join(consts.SysDevicesSystemNode, "node"+numaNode, "cpulist"))
+	content, err := os.ReadFile(cpuListPath) /* #nosec G304 */
+	if err != nil {
+		return "", fmt.Errorf("failed to read cpulist of NUMA node %s: %v", numaNode, err)
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
 // GetPCIeRoot returns the PCIe Root Complex for a given PCI device using the upstream Kubernetes implementation.
 // The PCIe Root Complex is returned in the format "pci<domain>:<bus>" (e.g., "pci0000:00").
 // This is used to identify devices that share the same PCIe Root Complex for resource alignment.
@@ -341,6 +446,24 @@ func (h *Host) GetPCIeRoot(pciAddress string) (string, error) {
 	return "", fmt.Errorf("PCIe root attribute for %s has no string value", pciAddress)
 }
 
+// GetIOMMUGroup returns the IOMMU group of a PCI device, read from its iommu_group link.
+// It returns -1 when the device has no IOMMU group, e.g. when the IOMMU is disabled.
+func (h *Host) GetIOMMUGroup(pciAddress string) (int, error) {
+	link, err := os.Readlink(buildSysBusPciPath(pciAddress, "iommu_group"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return -1, nil
+		}
+		return -1, fmt.Errorf("failed to read iommu_group of %s: %v", pciAddress, err)
+	}
+
+	group, err := strconv.Atoi(filepath.Base(link))
+	if err != nil {
+		return -1, fmt.Errorf("invalid iommu_group %q of %s: %v", link, pciAddress, err)
+	}
+	return group, nil
+}
+
 // High-level Driver Management Functions
 
 // BindDeviceDriver binds a device to the specified driver based on config.Driver:
@@ -359,7 +482,7 @@ func (h *Host) BindDeviceDriver(pciAddress string, config *configapi.VfConfig) (
 		return "", fmt.Errorf("failed to get current driver for device %s: %w", pciAddress, err)
 	}
 
-	if config.Driver == "default" {
+	if config.Driver == consts.DefaultVFDriver {
 		h.log.V(2).Info("BindDeviceDriver(): binding device to default driver", "device", pciAddress)
 		if err := h.BindDefaultDriver(pciAddress); err != nil {
 			return "", fmt.Errorf("failed to bind device %s to default driver: %w", pciAddress, err)
@@ -413,6 +536,26 @@ func (h *Host) BindDefaultDriver(pciAddress string) error {
 	return nil
 }
 
+// ResetDevice issues a function-level reset of a device through its sysfs reset attribute, so
+// the next consumer does not inherit the state left by the previous one. Devices without a reset
+// method are left alone.
+func (h *Host) ResetDevice(pciAddress string) error {
+	resetPath := buildSysBusPciPath(pciAddress, "reset")
+	if _, err := os.Stat(resetPath); err != nil {
+		if os.IsNotExist(err) {
+			h.log.V(2).Info("ResetDevice(): device doesn't support reset, skip", "device", pciAddress)
+			return nil
+		}
+		return err
+	}
+	h.log.V(2).Info("ResetDevice(): reset device", "device", pciAddress)
+	if err := writeSysfsWithRetry(resetPath, []byte("1"), 0, h.config.SysfsWriteBackoff); err != nil {
+		h.log.Error(err, "ResetDevice(): failed to reset device", "device", pciAddress)
+		return fmt.Errorf("failed to reset device %s: %w", pciAddress, err)
+	}
+	return nil
+}
+
 // Low-level Driver Operations
 
 // BindDriverByBusAndDevice binds device to the provided driver
@@ -484,10 +627,10 @@ func (h *Host) GetDriverByBusAndDevice(device string) (string, error) {
 func (h *Host) bindDriver(device, driver string) error {
 	h.log.V(2).Info("bindDriver(): bind to driver", "device", device, "driver", driver)
 	bindPath := buildSysBusPciDriverPath(driver, "bind")
-	err := os.WriteFile(bindPath, []byte(device), os.ModeAppend)
+	err := writeSysfsWithRetry(bindPath, []byte(device), 0, h.config.SysfsWriteBackoff)
 	if err != nil {
 		h.log.Error(err, "bindDriver(): failed to bind driver", "device", device, "driver", driver)
-		return err
+		return fmt.Errorf("failed to bind device %s to driver %s: %w", device, driver, err)
 	}
 	return nil
 }
@@ -496,10 +639,10 @@ func (h *Host) bindDriver(device, driver string) error {
 func (h *Host) unbindDriver(device, driver string) error {
 	h.log.V(2).Info("unbindDriver(): unbind from driver", "device", device, "driver", driver)
 	unbindPath := buildSysBusPciDriverPath(driver, "unbind")
-	err := os.WriteFile(unbindPath, []byte(device), os.ModeAppend)
+	err := writeSysfsWithRetry(unbindPath, []byte(device), h.config.UnbindTimeout, h.config.SysfsWriteBackoff)
 	if err != nil {
 		h.log.Error(err, "unbindDriver(): failed to unbind driver", "device", device, "driver", driver)
-		return err
+		return fmt.Errorf("failed to unbind device %s from driver %s: %w", device, driver, err)
 	}
 	return nil
 }
@@ -508,10 +651,10 @@ func (h *Host) unbindDriver(device, driver string) error {
 func (h *Host) probeDriver(device string) error {
 	h.log.V(2).Info("probeDriver(): drivers probe", "device", device)
 	probePath := buildSysPath("/sys/bus/pci/drivers_probe")
-	err := os.WriteFile(probePath, []byte(device), os.ModeAppend)
+	err := writeSysfsWithRetry(probePath, []byte(device), 0, h.config.SysfsWriteBackoff)
 	if err != nil {
 		h.log.Error(err, "probeDriver(): failed to trigger driver probe", "device", device)
-		return err
+		return fmt.Errorf("failed to probe drivers for device %s: %w", device, err)
 	}
 	return nil
 }
@@ -536,7 +679,7 @@ func (h *Host) setDriverOverride(device, override string) error {
 		h.log.V(2).Info("setDriverOverride(): reset driver override for device", "device", device)
 		overrideData = []byte("\x00")
 	}
-	err := os.WriteFile(driverOverridePath, overrideData, os.ModeAppend)
+	err := writeSysfs(driverOverridePath, overrideData)
 	if err != nil {
 		h.log.Error(err, "setDriverOverride(): fail to write driver_override for device",
 			"device", device, "driver", override)
@@ -627,6 +770,28 @@ func (h *Host) GetVFIODeviceFile(pciAddress string) (devFileHost, devFileContain
 	return devFileHost, devFileContainer, err
 }
 
+// UIO Device Functions
+
+// GetUIODeviceFile returns the UIO device file of a PCI device bound to a UIO driver
+// (uio_pci_generic or igb_uio), e.g. /dev/uio0
+func (h *Host) GetUIODeviceFile(pciAddress string) (string, error) {
+	h.log.V(2).Info("GetUIODeviceFile(): getting UIO device file", "device", pciAddress)
+
+	uioDir := buildSysBusPciPath(pciAddress, "uio")
+	entries, err := os.ReadDir(uioDir)
+	if err != nil {
+		return "", fmt.Errorf("GetUIODeviceFile(): unable to find uio directory of device %s: %v", pciAddress, err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "uio") {
+			devFile := filepath.Join("/dev", entry.Name())
+			h.log.V(2).Info("GetUIODeviceFile(): resolved UIO device file", "device", pciAddress, "devFile", devFile)
+			return devFile, nil
+		}
+	}
+	return "", fmt.Errorf("GetUIODeviceFile(): no uio device found for device %s", pciAddress)
+}
+
 // Kernel Module Management Functions
 
 // IsKernelModuleLoaded checks if a kernel module is currently loaded
@@ -651,23 +816,48 @@ func (h *Host) IsKernelModuleLoaded(moduleName string) bool {
 	return false
 }
 
-// LoadKernelModule loads a kernel module using modprobe with chroot to access host filesystem
+// LoadKernelModule loads a kernel module of the host and the modules it depends on, without
+// relying on the host userspace tools
 func (h *Host) LoadKernelModule(moduleName string) error {
 	h.log.V(2).Info("LoadKernelModule(): loading kernel module", "module", moduleName)
 
-	cmd := exec.Command("chroot", "/proc/1/root", "modprobe", moduleName)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		h.log.Error(err, "LoadKernelModule(): failed to load kernel module",
-			"module", moduleName, "output", string(output))
-		return fmt.Errorf("failed to load kernel module %s: %w (output: %s)",
-			moduleName, err, string(output))
+	if err := loadKernelModule(moduleName); err != nil {
+		h.log.Error(err, "LoadKernelModule(): failed to load kernel module", "module", moduleName)
+		return err
 	}
 
 	h.log.V(2).Info("LoadKernelModule(): successfully loaded kernel module", "module", moduleName)
 	return nil
 }
 
+// EnableVFIONoIOMMU enables the unsafe no-IOMMU mode of vfio, loading the module with
+// enable_unsafe_noiommu_mode=1 or setting the parameter when the module is already loaded or
+// built into the kernel. The mode allows vfio-pci on hosts without an IOMMU, such as VMs used in CI.
+func (h *Host) EnableVFIONoIOMMU() error {
+	paramPath := buildSysPath(consts.SysModuleVFIONoIOMMU)
+	if _, err := os.Stat(paramPath); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to access vfio no-IOMMU parameter: %w", err)
+		}
+		h.log.Info("EnableVFIONoIOMMU(): loading vfio in no-IOMMU mode")
+		if err := loadKernelModule("vfio", "enable_unsafe_noiommu_mode=1"); err != nil {
+			return err
+		}
+	} else if err := writeSysfs(paramPath, []byte("1")); err != nil {
+		return fmt.Errorf("failed to enable vfio no-IOMMU mode: %w", err)
+	}
+
+	value, err := os.ReadFile(paramPath)
+	if err != nil {
+		return fmt.Errorf("failed to read vfio no-IOMMU parameter: %w", err)
+	}
+	if enabled := strings.TrimSpace(string(value)); enabled != "Y" && enabled != "1" {
+		return fmt.Errorf("vfio no-IOMMU mode is not enabled, kernel reports %q (is CONFIG_VFIO_NOIOMMU set?)", strings.TrimSpace(string(value)))
+	}
+	h.log.Info("EnableVFIONoIOMMU(): vfio no-IOMMU mode enabled")
+	return nil
+}
+
 // EnsureDpdkModuleLoaded ensures that the kernel module for a DPDK driver is loaded
 func (h *Host) EnsureDpdkModuleLoaded(driver string) error {
 	if !h.IsDpdkDriver(driver) {
@@ -680,6 +870,11 @@ func (h *Host) EnsureDpdkModuleLoaded(driver string) error {
 	switch driver {
 	case "vfio-pci":
 		modulesNames = []string{"vfio", "vfio_pci"}
+	case "uio_pci_generic":
+		modulesNames = []string{"uio", "uio_pci_generic"}
+	case "igb_uio":
+		// out-of-tree module from dpdk-kmods, it must be installed on the host
+		modulesNames = []string{"uio", "igb_uio"}
 	default:
 		return fmt.Errorf("unknown DPDK driver: %s", driver)
 	}
@@ -775,6 +970,25 @@ func (h *Host) EnsureVhostModulesLoaded() error {
 	return nil
 }
 
+// EnsureRDMACMModuleLoaded loads rdma_ucm, which creates the RDMA connection manager device
+// (/dev/infiniband/rdma_cm) used by librdmacm.
+func (h *Host) EnsureRDMACMModuleLoaded() error {
+	const moduleName = "rdma_ucm"
+	if h.IsKernelModuleLoaded(moduleName) {
+		h.log.V(2).Info("EnsureRDMACMModuleLoaded(): kernel module already loaded", "module", moduleName)
+		return nil
+	}
+
+	h.log.Info("EnsureRDMACMModuleLoaded(): loading kernel module for the RDMA connection manager", "module", moduleName)
+	if err := h.LoadKernelModule(moduleName); err != nil {
+		return fmt.Errorf("failed to load module %s: %w", moduleName, err)
+	}
+	if !h.IsKernelModuleLoaded(moduleName) {
+		return fmt.Errorf("module %s was not loaded after LoadKernelModule call", moduleName)
+	}
+	return nil
+}
+
 // RDMA Device Functions
 
 // GetRDMADevicesForPCI returns the RDMA device names associated with a PCI address
@@ -1,12 +1,20 @@
 package host_test
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
@@ -26,6 +34,11 @@ var _ = Describe("Host", func() {
 		fs = &host.FakeFilesystem{}
 	})
 
+	It("should not reconfigure the helpers once initialized", func() {
+		Expect(host.GetHelpers()).NotTo(BeNil())
+		Expect(host.InitHelpers(host.DefaultConfig())).To(MatchError(ContainSubstring("already initialized")))
+	})
+
 	AfterEach(func() {
 		if tearDown != nil {
 			tearDown()
@@ -136,6 +149,351 @@ var _ = Describe("Host", func() {
 		})
 	})
 
+	Describe("GetDriverInfo", func() {
+		It("should return the driver and firmware versions reported by ethtool", func() {
+			restore := host.SetEthtoolDrvinfo(func(netnsPath, ifName string) (*unix.EthtoolDrvinfo, error) {
+				Expect(netnsPath).To(BeEmpty())
+				Expect(ifName).To(Equal("eth0"))
+				drvinfo := &unix.EthtoolDrvinfo{}
+				copy(drvinfo.Driver[:], "mlx5_core")
+				copy(drvinfo.Version[:], "24.10-1.1.4")
+				copy(drvinfo.Fw_version[:], "28.43.1014 (MT_0000000838)")
+				return drvinfo, nil
+			})
+			defer restore()
+
+			driverInfo, err := h.GetDriverInfo("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(driverInfo).To(Equal(&host.DriverInfo{
+				Driver:          "mlx5_core",
+				Version:         "24.10-1.1.4",
+				FirmwareVersion: "28.43.1014 (MT_0000000838)",
+			}))
+		})
+
+		It("should fail when ethtool fails", func() {
+			restore := host.SetEthtoolDrvinfo(func(string, string) (*unix.EthtoolDrvinfo, error) {
+				return nil, unix.EOPNOTSUPP
+			})
+			defer restore()
+
+			_, err := h.GetDriverInfo("eth0")
+			Expect(err).To(MatchError(unix.EOPNOTSUPP))
+		})
+	})
+
+	Describe("GetEthtoolStats", func() {
+		It("should return the statistics reported by ethtool by name", func() {
+			restore := host.SetEthtoolStats(func(netnsPath, ifName string) ([]string, []uint64, error) {
+				Expect(netnsPath).To(Equal("/proc/123/ns/net"))
+				Expect(ifName).To(Equal("net1"))
+				return []string{"rx_packets", "tx_packets", "rx_out_of_buffer"}, []uint64{10, 20, 3}, nil
+			})
+			defer restore()
+
+			stats, err := h.GetEthtoolStats("/proc/123/ns/net", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stats).To(Equal(map[string]uint64{"rx_packets": 10, "tx_packets": 20, "rx_out_of_buffer": 3}))
+		})
+
+		It("should return no statistics for interfaces without any", func() {
+			restore := host.SetEthtoolStats(func(string, string) ([]string, []uint64, error) {
+				return nil, nil, nil
+			})
+			defer restore()
+
+			stats, err := h.GetEthtoolStats("", "eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stats).To(BeEmpty())
+		})
+
+		It("should fail when ethtool fails", func() {
+			restore := host.SetEthtoolStats(func(string, string) ([]string, []uint64, error) {
+				return nil, nil, unix.ENODEV
+			})
+			defer restore()
+
+			_, err := h.GetEthtoolStats("/proc/123/ns/net", "net1")
+			Expect(err).To(MatchError(unix.ENODEV))
+		})
+
+		It("should fail when names and values do not match", func() {
+			restore := host.SetEthtoolStats(func(string, string) ([]string, []uint64, error) {
+				return []string{"rx_packets"}, []uint64{10, 20}, nil
+			})
+			defer restore()
+
+			_, err := h.GetEthtoolStats("/proc/123/ns/net", "net1")
+			Expect(err).To(MatchError(ContainSubstring("1 ethtool statistics names for 2 values")))
+		})
+	})
+
+	Describe("Queue Functions", func() {
+		BeforeEach(func() {
+			fs.Dirs = []string{"sys/bus/pci/devices/0000:01:00.1/net/eth0v0"}
+			tearDown = fs.Use()
+		})
+
+		It("should return the combined channels of the interface of the device", func() {
+			restore := host.SetEthtoolChannels(func(ifName string) (uint32, uint32, error) {
+				Expect(ifName).To(Equal("eth0v0"))
+				return 4, 8, nil
+			}, nil)
+			defer restore()
+
+			channels, err := h.GetCombinedChannels("0000:01:00.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channels).To(Equal(4))
+		})
+
+		It("should set the combined channels within the maximum of the interface", func() {
+			var set []uint32
+			restore := host.SetEthtoolChannels(func(string) (uint32, uint32, error) {
+				return 4, 8, nil
+			}, func(ifName string, combined uint32) error {
+				Expect(ifName).To(Equal("eth0v0"))
+				set = append(set, combined)
+				return nil
+			})
+			defer restore()
+
+			Expect(h.SetCombinedChannels("0000:01:00.1", 2)).To(Succeed())
+			// the current count is not set again
+			Expect(h.SetCombinedChannels("0000:01:00.1", 4)).To(Succeed())
+			Expect(h.SetCombinedChannels("0000:01:00.1", 16)).To(MatchError("interface eth0v0 supports 1 to 8 combined channels, 16 requested"))
+			Expect(set).To(Equal([]uint32{2}))
+		})
+
+		It("should fail when ethtool fails", func() {
+			restore := host.SetEthtoolChannels(func(string) (uint32, uint32, error) {
+				return 4, 8, nil
+			}, func(string, uint32) error {
+				return unix.EOPNOTSUPP
+			})
+			defer restore()
+
+			Expect(h.SetCombinedChannels("0000:01:00.1", 2)).To(MatchError(unix.EOPNOTSUPP))
+		})
+
+		It("should return the ring sizes of the interface of the device", func() {
+			restore := host.SetEthtoolRingSizes(func(ifName string) (host.RingSizes, host.RingSizes, error) {
+				Expect(ifName).To(Equal("eth0v0"))
+				return host.RingSizes{RX: 1024, TX: 512}, host.RingSizes{RX: 8192, TX: 8192}, nil
+			}, nil)
+			defer restore()
+
+			sizes, err := h.GetRingSizes("0000:01:00.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sizes).To(Equal(&host.RingSizes{RX: 1024, TX: 512}))
+		})
+
+		It("should set the ring sizes within the maximum of the interface and keep the others", func() {
+			var set []host.RingSizes
+			restore := host.SetEthtoolRingSizes(func(string) (host.RingSizes, host.RingSizes, error) {
+				return host.RingSizes{RX: 1024, TX: 512}, host.RingSizes{RX: 8192, TX: 4096}, nil
+			}, func(ifName string, sizes host.RingSizes) error {
+				Expect(ifName).To(Equal("eth0v0"))
+				set = append(set, sizes)
+				return nil
+			})
+			defer restore()
+
+			Expect(h.SetRingSizes("0000:01:00.1", &host.RingSizes{RX: 4096})).To(Succeed())
+			// the current sizes are not set again
+			Expect(h.SetRingSizes("0000:01:00.1", &host.RingSizes{RX: 1024, TX: 512})).To(Succeed())
+			Expect(h.SetRingSizes("0000:01:00.1", &host.RingSizes{TX: 8192})).To(MatchError("interface eth0v0 supports TX rings of 1 to 4096 descriptors, 8192 requested"))
+			Expect(set).To(Equal([]host.RingSizes{{RX: 4096, TX: 512}}))
+		})
+
+		It("should fail for devices without network interface", func() {
+			_, err := h.GetCombinedChannels("0000:01:00.2")
+			Expect(err).To(MatchError("no network interface found for device 0000:01:00.2"))
+			Expect(h.SetCombinedChannels("0000:01:00.2", 2)).To(MatchError("no network interface found for device 0000:01:00.2"))
+			_, err = h.GetRingSizes("0000:01:00.2")
+			Expect(err).To(MatchError("no network interface found for device 0000:01:00.2"))
+			_, err = h.GetFeatures("0000:01:00.2")
+			Expect(err).To(MatchError("no network interface found for device 0000:01:00.2"))
+		})
+
+		It("should return the features of the interface of the device", func() {
+			restore := host.SetEthtoolGetFeatures(func(ifName string) (map[string]bool, error) {
+				Expect(ifName).To(Equal("eth0v0"))
+				return map[string]bool{"rx-gro": true, "rx-lro": false}, nil
+			})
+			defer restore()
+
+			features, err := h.GetFeatures("0000:01:00.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal(map[string]bool{"rx-gro": true, "rx-lro": false}))
+		})
+
+		It("should only set the features not in the requested state", func() {
+			restoreGet := host.SetEthtoolGetFeatures(func(string) (map[string]bool, error) {
+				return map[string]bool{"rx-gro": true, "rx-lro": true}, nil
+			})
+			defer restoreGet()
+			var set []string
+			restoreSet := host.SetEthtoolSetFeature(func(ifName, feature string, enable bool) error {
+				Expect(ifName).To(Equal("eth0v0"))
+				set = append(set, fmt.Sprintf("%s=%t", feature, enable))
+				return nil
+			})
+			defer restoreSet()
+
+			Expect(h.SetFeatures("0000:01:00.1", map[string]bool{"rx-gro": true, "rx-lro": false})).To(Succeed())
+			Expect(set).To(Equal([]string{"rx-lro=false"}))
+			Expect(h.SetFeatures("0000:01:00.1", map[string]bool{"tx-tcp-segmentation": false})).To(
+				MatchError("feature tx-tcp-segmentation not reported by interface eth0v0"))
+		})
+	})
+
+	Describe("SetNetnsSysctls", func() {
+		It("should set the sysctls in the network namespace", func() {
+			restore := host.SetWriteNetnsSysctls(func(netnsPath string, sysctls map[string]string) error {
+				Expect(netnsPath).To(Equal("/proc/123/ns/net"))
+				Expect(sysctls).To(Equal(map[string]string{"net/ipv4/conf/net1/rp_filter": "2"}))
+				return nil
+			})
+			defer restore()
+
+			Expect(h.SetNetnsSysctls("/proc/123/ns/net", map[string]string{"net/ipv4/conf/net1/rp_filter": "2"})).To(Succeed())
+		})
+
+		It("should not enter the network namespace without sysctls", func() {
+			restore := host.SetWriteNetnsSysctls(func(string, map[string]string) error {
+				Fail("no sysctl to set")
+				return nil
+			})
+			defer restore()
+
+			Expect(h.SetNetnsSysctls("/proc/123/ns/net", nil)).To(Succeed())
+		})
+
+		It("should fail when a sysctl cannot be set", func() {
+			restore := host.SetWriteNetnsSysctls(func(string, map[string]string) error {
+				return errors.New("failed to set sysctl net/ipv4/conf/net1/rp_filer: no such file or directory")
+			})
+			defer restore()
+
+			Expect(h.SetNetnsSysctls("/proc/123/ns/net", map[string]string{"net/ipv4/conf/net1/rp_filer": "2"})).To(
+				MatchError(ContainSubstring("failed to set sysctls in network namespace /proc/123/ns/net")))
+		})
+	})
+
+	Describe("doInNetns", func() {
+		It("should not run the function when the network namespace is missing", func() {
+			err := host.DoInNetns(filepath.Join(GinkgoT().TempDir(), "missing"), func() error {
+				Fail("the network namespace does not exist")
+				return nil
+			})
+			Expect(err).To(MatchError(ContainSubstring("failed to open network namespace")))
+		})
+	})
+
+	Describe("VPD Functions", func() {
+		vpd := func(readOnly ...[]byte) []byte {
+			data := append([]byte{0x82, 10, 0}, "ConnectX-6"...)
+			var keywords []byte
+			for _, keyword := range readOnly {
+				keywords = append(keywords, keyword...)
+			}
+			data = append(data, 0x90, byte(len(keywords)), 0)
+			data = append(data, keywords...)
+			return append(data, 0x78)
+		}
+		keyword := func(name, value string) []byte {
+			return append([]byte{name[0], name[1], byte(len(value))}, value...)
+		}
+
+		It("should return the read-only keywords", func() {
+			fs.Dirs = []string{"sys/bus/pci/devices/0000:01:00.0"}
+			fs.Files = map[string][]byte{
+				"sys/bus/pci/devices/0000:01:00.0/vpd": vpd(
+					keyword("PN", "MCX653106A-HDAT "),
+					keyword("V0", "PCIeGen4 x16"),
+					keyword("RV", "\x00"),
+				),
+			}
+			tearDown = fs.Use()
+
+			keywords, err := h.GetVPD("0000:01:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keywords).To(Equal(map[string]string{
+				host.VPDKeywordPartNumber: "MCX653106A-HDAT",
+				"V0":                      "PCIeGen4 x16",
+			}))
+		})
+
+		It("should fail on truncated VPD", func() {
+			data := vpd(keyword("PN", "MCX653106A-HDAT"))
+			fs.Dirs = []string{"sys/bus/pci/devices/0000:01:00.0"}
+			fs.Files = map[string][]byte{
+				"sys/bus/pci/devices/0000:01:00.0/vpd": data[:len(data)-5],
+			}
+			tearDown = fs.Use()
+
+			_, err := h.GetVPD("0000:01:00.0")
+			Expect(err).To(MatchError(ContainSubstring("truncated")))
+		})
+
+		It("should fail for a device without VPD", func() {
+			fs.Dirs = []string{"sys/bus/pci/devices/0000:01:00.0"}
+			tearDown = fs.Use()
+
+			_, err := h.GetVPD("0000:01:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return the board ID of the RDMA device", func() {
+			fs.Dirs = []string{"sys/bus/pci/devices/0000:01:00.0/infiniband/mlx5_0"}
+			fs.Files = map[string][]byte{
+				"sys/bus/pci/devices/0000:01:00.0/infiniband/mlx5_0/board_id": []byte("MT_0000000838\n"),
+			}
+			tearDown = fs.Use()
+
+			boardID, err := h.GetBoardID("0000:01:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(boardID).To(Equal("MT_0000000838"))
+		})
+
+		It("should fail to get the board ID without RDMA device", func() {
+			fs.Dirs = []string{"sys/bus/pci/devices/0000:01:00.0"}
+			tearDown = fs.Use()
+
+			_, err := h.GetBoardID("0000:01:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetSriovVFCounts", func() {
+		It("should return the total and current number of VFs", func() {
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.0",
+			}
+			fs.Files = map[string][]byte{
+				"sys/bus/pci/devices/0000:01:00.0/sriov_totalvfs": []byte("8\n"),
+				"sys/bus/pci/devices/0000:01:00.0/sriov_numvfs":   []byte("2\n"),
+			}
+			tearDown = fs.Use()
+
+			totalVFs, numVFs, err := h.GetSriovVFCounts("0000:01:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(totalVFs).To(Equal(8))
+			Expect(numVFs).To(Equal(2))
+		})
+
+		It("should fail for a device without SR-IOV capability", func() {
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.0",
+			}
+			tearDown = fs.Use()
+
+			_, _, err := h.GetSriovVFCounts("0000:01:00.0")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("sriov_totalvfs"))
+		})
+	})
+
 	Describe("Network Interface Functions", func() {
 		Context("TryGetInterfaceName", func() {
 			It("should return interface name when net directory exists", func() {
@@ -179,6 +537,32 @@ var _ = Describe("Host", func() {
 			})
 		})
 
+		Context("GetInterfaceMACAddress", func() {
+			It("should return the MAC address of the interface", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.1/net/eth0v0",
+				}
+				fs.Files = map[string][]byte{
+					"sys/bus/pci/devices/0000:01:00.1/net/eth0v0/address": []byte("aa:bb:cc:dd:ee:01\n"),
+				}
+				tearDown = fs.Use()
+
+				mac, err := h.GetInterfaceMACAddress("0000:01:00.1", "eth0v0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mac).To(Equal("aa:bb:cc:dd:ee:01"))
+			})
+
+			It("should fail when the interface does not exist", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.1",
+				}
+				tearDown = fs.Use()
+
+				_, err := h.GetInterfaceMACAddress("0000:01:00.1", "eth0v0")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
 		Context("GetLinkType", func() {
 			It("should return 'ethernet' for type ArphrdEther", func() {
 				fs.Dirs = []string{
@@ -312,6 +696,92 @@ var _ = Describe("Host", func() {
 			})
 		})
 
+		Context("GetIOMMUGroup", func() {
+			It("should return the IOMMU group of the device", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.0",
+					"sys/kernel/iommu_groups/17",
+				}
+				fs.Symlinks = map[string]string{
+					"sys/bus/pci/devices/0000:01:00.0/iommu_group": "../../../../kernel/iommu_groups/17",
+				}
+				tearDown = fs.Use()
+
+				group, err := h.GetIOMMUGroup("0000:01:00.0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(group).To(Equal(17))
+			})
+
+			It("should return -1 when the device has no IOMMU group", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.0",
+				}
+				tearDown = fs.Use()
+
+				group, err := h.GetIOMMUGroup("0000:01:00.0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(group).To(Equal(-1))
+			})
+		})
+
+		Context("GetNumaNodeCPUs", func() {
+			It("should return the cpulist of the NUMA node", func() {
+				fs.Dirs = []string{
+					"sys/devices/system/node/node1",
+				}
+				fs.Files = map[string][]byte{
+					"sys/devices/system/node/node1/cpulist": []byte("8-15,24-31\n"),
+				}
+				tearDown = fs.Use()
+
+				cpus, err := h.GetNumaNodeCPUs("1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cpus).To(Equal("8-15,24-31"))
+			})
+
+			It("should return error when the NUMA node does not exist", func() {
+				tearDown = fs.Use()
+
+				_, err := h.GetNumaNodeCPUs("3")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("EnableVFIONoIOMMU", func() {
+			It("should set the parameter when vfio is already loaded", func() {
+				fs.Dirs = []string{
+					"sys/module/vfio/parameters",
+				}
+				fs.Files = map[string][]byte{
+					"sys/module/vfio/parameters/enable_unsafe_noiommu_mode": []byte("N\n"),
+				}
+				tearDown = fs.Use()
+
+				Expect(h.EnableVFIONoIOMMU()).To(Succeed())
+				value, err := os.ReadFile(filepath.Join(host.RootDir, consts.SysModuleVFIONoIOMMU))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(value)).To(Equal("1"))
+			})
+
+			It("should classify a failed write of the parameter", func() {
+				fs.Dirs = []string{
+					"sys/module/vfio/parameters",
+				}
+				fs.Files = map[string][]byte{
+					"sys/module/vfio/parameters/enable_unsafe_noiommu_mode": []byte("N\n"),
+				}
+				tearDown = fs.Use()
+				restore := host.SetSysfsWriteFile(func(string, []byte, os.FileMode) error {
+					return syscall.EROFS
+				})
+				defer restore()
+
+				err := h.EnableVFIONoIOMMU()
+				Expect(errors.Is(err, host.ErrSysfsNotWritable)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("failed to enable vfio no-IOMMU mode"))
+			})
+		})
+
 		Context("GetPCIeRoot", func() {
 			It("should return error for invalid PCI address format", func() {
 				// Test with invalid format - this is validated by the upstream implementation
@@ -440,6 +910,57 @@ var _ = Describe("Host", func() {
 		})
 	})
 
+	Describe("Device Reset Functions", func() {
+		It("should reset the device through sysfs", func() {
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.1",
+			}
+			fs.Files = map[string][]byte{
+				"sys/bus/pci/devices/0000:01:00.1/reset": {},
+			}
+			tearDown = fs.Use()
+
+			Expect(h.ResetDevice("0000:01:00.1")).To(Succeed())
+			value, err := os.ReadFile(filepath.Join(host.RootDir, "sys/bus/pci/devices/0000:01:00.1/reset"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(value)).To(Equal("1"))
+		})
+
+		It("should skip devices without a reset method", func() {
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.1",
+			}
+			tearDown = fs.Use()
+
+			Expect(h.ResetDevice("0000:01:00.1")).To(Succeed())
+		})
+	})
+
+	Describe("UIO Device Functions", func() {
+		Context("GetUIODeviceFile", func() {
+			It("should return the uio device of the PCI device", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.1/uio/uio3",
+				}
+				tearDown = fs.Use()
+
+				devFile, err := h.GetUIODeviceFile("0000:01:00.1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(devFile).To(Equal("/dev/uio3"))
+			})
+
+			It("should return error when the device is not bound to a uio driver", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.1",
+				}
+				tearDown = fs.Use()
+
+				_, err := h.GetUIODeviceFile("0000:01:00.1")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("Kernel Module Management Functions", func() {
 		Context("IsKernelModuleLoaded", func() {
 			It("should return true when module is loaded", func() {
@@ -499,14 +1020,119 @@ vfio 32768 1 vfio_pci, Live 0xffffffffa0456000`),
 				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("should return error for unknown DPDK driver", func() {
+			It("should return nil when uio modules are already loaded", func() {
+				fs.Dirs = []string{
+					"proc",
+				}
+				fs.Files = map[string][]byte{
+					"proc/modules": []byte(`uio_pci_generic 16384 0 - Live 0xffffffffa0123000
+igb_uio 20480 0 - Live 0xffffffffa0124000
+uio 20480 2 uio_pci_generic,igb_uio, Live 0xffffffffa0456000`),
+				}
 				tearDown = fs.Use()
 
-				// Temporarily modify the IsDpdkDriver to consider this as DPDK driver
-				// by using a driver that would be recognized as DPDK but not supported
-				err := h.EnsureDpdkModuleLoaded("uio_pci_generic")
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("unknown DPDK driver"))
+				Expect(h.EnsureDpdkModuleLoaded("uio_pci_generic")).To(Succeed())
+				Expect(h.EnsureDpdkModuleLoaded("igb_uio")).To(Succeed())
+			})
+		})
+
+		Context("LoadKernelModule", func() {
+			var (
+				modulesDir   string
+				loaded       []string
+				params       []string
+				finitErr     map[string]error
+				restoreFinit func()
+			)
+
+			BeforeEach(func() {
+				var uname unix.Utsname
+				Expect(unix.Uname(&uname)).To(Succeed())
+				modulesDir = filepath.Join("proc/1/root/lib/modules", unix.ByteSliceToString(uname.Release[:]))
+				fs.Dirs = []string{
+					filepath.Join(modulesDir, "kernel/drivers/vfio/pci"),
+				}
+				fs.Files = map[string][]byte{
+					filepath.Join(modulesDir, "modules.dep"): []byte(`kernel/drivers/vfio/vfio.ko.xz: kernel/drivers/iommu/iommufd/iommufd.ko.xz
+kernel/drivers/vfio/pci/vfio-pci.ko.xz: kernel/drivers/vfio/pci/vfio-pci-core.ko.xz kernel/drivers/vfio/vfio.ko.xz kernel/drivers/iommu/iommufd/iommufd.ko.xz
+kernel/drivers/vfio/pci/vfio-pci-core.ko.xz: kernel/drivers/vfio/vfio.ko.xz kernel/drivers/iommu/iommufd/iommufd.ko.xz
+`),
+					filepath.Join(modulesDir, "modules.builtin"): []byte("kernel/drivers/uio/uio.ko\n"),
+				}
+				for _, module := range []string{"kernel/drivers/vfio/vfio.ko.xz", "kernel/drivers/vfio/pci/vfio-pci.ko.xz", "kernel/drivers/vfio/pci/vfio-pci-core.ko.xz", "kernel/drivers/iommu/iommufd/iommufd.ko.xz"} {
+					fs.Dirs = append(fs.Dirs, filepath.Join(modulesDir, filepath.Dir(module)))
+					fs.Files[filepath.Join(modulesDir, module)] = []byte("module")
+				}
+
+				loaded = nil
+				params = nil
+				finitErr = map[string]error{}
+				restoreFinit = host.SetFinitModule(func(fd int, moduleParams string, flags int) error {
+					path, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(flags & unix.MODULE_INIT_COMPRESSED_FILE).NotTo(BeZero())
+					name := filepath.Base(path)
+					loaded = append(loaded, name)
+					params = append(params, moduleParams)
+					return finitErr[name]
+				})
+			})
+
+			AfterEach(func() {
+				restoreFinit()
+			})
+
+			It("should load the dependencies of the module first", func() {
+				tearDown = fs.Use()
+
+				Expect(h.LoadKernelModule("vfio_pci")).To(Succeed())
+				Expect(loaded).To(Equal([]string{"iommufd.ko.xz", "vfio.ko.xz", "vfio-pci-core.ko.xz", "vfio-pci.ko.xz"}))
+			})
+
+			It("should ignore modules that are already loaded", func() {
+				finitErr["vfio.ko.xz"] = unix.EEXIST
+				tearDown = fs.Use()
+
+				Expect(h.LoadKernelModule("vfio-pci")).To(Succeed())
+				Expect(loaded).To(HaveLen(4))
+			})
+
+			It("should only pass the parameters to the requested module", func() {
+				fs.Dirs = append(fs.Dirs, "sys/module/vfio/parameters")
+				tearDown = fs.Use()
+				restore := host.SetFinitModule(func(fd int, moduleParams string, flags int) error {
+					params = append(params, moduleParams)
+					return os.WriteFile(filepath.Join(host.RootDir, consts.SysModuleVFIONoIOMMU), []byte("Y\n"), 0600)
+				})
+				defer restore()
+
+				Expect(h.EnableVFIONoIOMMU()).To(Succeed())
+				Expect(params).To(Equal([]string{"", "enable_unsafe_noiommu_mode=1"}))
+			})
+
+			It("should not load modules built into the kernel", func() {
+				tearDown = fs.Use()
+
+				Expect(h.LoadKernelModule("uio")).To(Succeed())
+				Expect(loaded).To(BeEmpty())
+			})
+
+			It("should report modules that are not installed", func() {
+				tearDown = fs.Use()
+
+				err := h.LoadKernelModule("igb_uio")
+				Expect(errors.Is(err, host.ErrKernelModuleNotFound)).To(BeTrue())
+				Expect(loaded).To(BeEmpty())
+			})
+
+			It("should report modules whose signature is rejected", func() {
+				finitErr["vfio-pci-core.ko.xz"] = unix.EKEYREJECTED
+				tearDown = fs.Use()
+
+				err := h.LoadKernelModule("vfio_pci")
+				Expect(errors.Is(err, host.ErrKernelModuleSignatureRejected)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("vfio_pci_core"))
+				Expect(loaded).To(Equal([]string{"iommufd.ko.xz", "vfio.ko.xz", "vfio-pci-core.ko.xz"}))
 			})
 		})
 
@@ -525,6 +1151,20 @@ vhost_net 32768 1 tun, Live 0xffffffffa0456000`),
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+
+		Context("EnsureRDMACMModuleLoaded", func() {
+			It("should return nil when rdma_ucm is already loaded", func() {
+				fs.Dirs = []string{
+					"proc",
+				}
+				fs.Files = map[string][]byte{
+					"proc/modules": []byte(`rdma_ucm 28672 0 - Live 0xffffffffc0a1c000`),
+				}
+				tearDown = fs.Use()
+
+				Expect(h.EnsureRDMACMModuleLoaded()).To(Succeed())
+			})
+		})
 	})
 
 	Describe("VFIO Device Functions", func() {
@@ -602,6 +1242,130 @@ vhost_net 32768 1 tun, Live 0xffffffffa0456000`),
 			})
 		})
 
+		Context("Sysfs Write Errors", func() {
+			It("should classify a missing sysfs attribute as not found", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.0",
+				}
+				fs.Symlinks = map[string]string{
+					"sys/bus/pci/devices/0000:01:00.0/driver": "../../drivers/ixgbe",
+				}
+				tearDown = fs.Use()
+
+				err := h.UnbindDriverByBusAndDevice("0000:01:00.0")
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Is(err, host.ErrSysfsNotFound)).To(BeTrue())
+				Expect(errors.Is(err, host.ErrSysfsNotWritable)).To(BeFalse())
+
+				var writeErr *host.SysfsWriteError
+				Expect(errors.As(err, &writeErr)).To(BeTrue())
+				Expect(writeErr.Path).To(HaveSuffix("sys/bus/pci/drivers/ixgbe/unbind"))
+			})
+
+			Context("when the device is busy", func() {
+				var (
+					config        host.Config
+					restoreWrites func()
+					writes        int
+				)
+
+				BeforeEach(func() {
+					config = host.DefaultConfig()
+					config.SysfsWriteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+					h = host.NewHostWithConfig(config)
+					fs.Dirs = []string{
+						"sys/bus/pci/devices/0000:01:00.0",
+					}
+					fs.Symlinks = map[string]string{
+						"sys/bus/pci/devices/0000:01:00.0/driver": "../../drivers/ixgbe",
+					}
+					writes = 0
+				})
+
+				AfterEach(func() {
+					restoreWrites()
+				})
+
+				It("should retry the write", func() {
+					restoreWrites = host.SetSysfsWriteFile(func(string, []byte, os.FileMode) error {
+						writes++
+						if writes < 3 {
+							return syscall.EBUSY
+						}
+						return nil
+					})
+					tearDown = fs.Use()
+
+					Expect(h.UnbindDriverByBusAndDevice("0000:01:00.0")).To(Succeed())
+					Expect(writes).To(Equal(3))
+				})
+
+				It("should report the device as busy once the attempts are exhausted", func() {
+					restoreWrites = host.SetSysfsWriteFile(func(string, []byte, os.FileMode) error {
+						writes++
+						return syscall.EAGAIN
+					})
+					tearDown = fs.Use()
+
+					err := h.UnbindDriverByBusAndDevice("0000:01:00.0")
+					Expect(errors.Is(err, host.ErrSysfsBusy)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("failed to unbind device 0000:01:00.0 from driver ixgbe"))
+					Expect(err.Error()).To(ContainSubstring("after 3 attempts"))
+					Expect(writes).To(Equal(3))
+				})
+
+				It("should give up on an unbind that does not complete", func() {
+					config.UnbindTimeout = 10 * time.Millisecond
+					h = host.NewHostWithConfig(config)
+					started := make(chan struct{})
+					unblock := make(chan struct{})
+					defer close(unblock)
+					restoreWrites = host.SetSysfsWriteFile(func(string, []byte, os.FileMode) error {
+						writes++
+						close(started)
+						<-unblock
+						return nil
+					})
+					tearDown = fs.Use()
+
+					err := h.UnbindDriverByBusAndDevice("0000:01:00.0")
+					Expect(errors.Is(err, host.ErrSysfsTimeout)).To(BeTrue())
+					Expect(errors.Is(err, host.ErrSysfsBusy)).To(BeFalse())
+					Eventually(started).Should(BeClosed())
+					Expect(writes).To(Equal(1))
+				})
+
+				It("should not retry other errors", func() {
+					restoreWrites = host.SetSysfsWriteFile(func(string, []byte, os.FileMode) error {
+						writes++
+						return syscall.EACCES
+					})
+					tearDown = fs.Use()
+
+					err := h.UnbindDriverByBusAndDevice("0000:01:00.0")
+					Expect(errors.Is(err, host.ErrSysfsNotWritable)).To(BeTrue())
+					Expect(writes).To(Equal(1))
+				})
+			})
+
+			It("should not classify unknown errors", func() {
+				fs.Dirs = []string{
+					"sys/bus/pci/devices/0000:01:00.0",
+					"sys/bus/pci/drivers/ixgbe/unbind",
+				}
+				fs.Symlinks = map[string]string{
+					"sys/bus/pci/devices/0000:01:00.0/driver": "../../drivers/ixgbe",
+				}
+				tearDown = fs.Use()
+
+				err := h.UnbindDriverByBusAndDevice("0000:01:00.0")
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Is(err, host.ErrSysfsNotFound)).To(BeFalse())
+				Expect(errors.Is(err, host.ErrSysfsNotWritable)).To(BeFalse())
+				Expect(errors.Is(err, host.ErrSysfsBusy)).To(BeFalse())
+			})
+		})
+
 		Context("Data Parsing", func() {
 			It("should handle VF ID parsing errors gracefully", func() {
 				fs.Dirs = []string{
@@ -636,6 +1400,686 @@ vhost_net 32768 1 tun, Live 0xffffffffa0456000`),
 		})
 	})
 
+	Describe("VF Settings Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			pfLink              *netlink.Device
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+
+			mac, _ := net.ParseMAC("02:00:00:00:00:01")
+			pfLink = &netlink.Device{LinkAttrs: netlink.LinkAttrs{
+				Name: "ens1f0",
+				Vfs: []netlink.VfInfo{
+					{ID: 0},
+					{ID: 1, Mac: mac, Vlan: 100, Qos: 2, Spoofchk: true, Trust: 1, MinTxRate: 10, MaxTxRate: 1000},
+				},
+			}}
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.0/net/ens1f0",
+				"sys/bus/pci/devices/0000:01:00.1",
+				"sys/bus/pci/devices/0000:01:00.2",
+			}
+			fs.Symlinks = map[string]string{
+				"sys/bus/pci/devices/0000:01:00.0/virtfn0": "../0000:01:00.1",
+				"sys/bus/pci/devices/0000:01:00.0/virtfn1": "../0000:01:00.2",
+				"sys/bus/pci/devices/0000:01:00.1/physfn":  "../0000:01:00.0",
+				"sys/bus/pci/devices/0000:01:00.2/physfn":  "../0000:01:00.0",
+			}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should read the settings of the VF from its PF", func() {
+			tearDown = fs.Use()
+			mockNetlinkProvider.EXPECT().LinkByName("ens1f0").Return(pfLink, nil)
+
+			settings, err := hostImpl.GetVFSettings("0000:01:00.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings).To(Equal(&host.VFSettings{
+				MAC: "02:00:00:00:00:01", VLAN: 100, VLANQoS: 2, SpoofChk: true, Trust: true, MinTxRate: 10, MaxTxRate: 1000,
+			}))
+		})
+
+		It("should apply the settings to the VF through its PF", func() {
+			tearDown = fs.Use()
+			mac, _ := net.ParseMAC("02:00:00:00:00:01")
+			mockNetlinkProvider.EXPECT().LinkByName("ens1f0").Return(pfLink, nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfHardwareAddr(pfLink, 1, mac).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfVlanQos(pfLink, 1, 100, 2).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfSpoofchk(pfLink, 1, true).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfTrust(pfLink, 1, true).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfRate(pfLink, 1, 10, 1000).Return(nil)
+
+			Expect(hostImpl.SetVFSettings("0000:01:00.2", &host.VFSettings{
+				MAC: "02:00:00:00:00:01", VLAN: 100, VLANQoS: 2, SpoofChk: true, Trust: true, MinTxRate: 10, MaxTxRate: 1000,
+			})).To(Succeed())
+		})
+
+		It("should attempt all the settings and report the failed ones", func() {
+			tearDown = fs.Use()
+			mockNetlinkProvider.EXPECT().LinkByName("ens1f0").Return(pfLink, nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfHardwareAddr(pfLink, 0, net.HardwareAddr{0, 0, 0, 0, 0, 0}).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfVlanQos(pfLink, 0, 0, 0).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfSpoofchk(pfLink, 0, false).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfTrust(pfLink, 0, false).Return(syscall.EOPNOTSUPP)
+			mockNetlinkProvider.EXPECT().LinkSetVfRate(pfLink, 0, 0, 0).Return(nil)
+
+			err := hostImpl.SetVFSettings("0000:01:00.1", &host.VFSettings{})
+			Expect(err).To(MatchError(ContainSubstring("failed to set trust")))
+			Expect(errors.Is(err, syscall.EOPNOTSUPP)).To(BeTrue())
+		})
+
+		It("should fail for devices that are not VFs", func() {
+			tearDown = fs.Use()
+
+			_, err := hostImpl.GetVFSettings("0000:01:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("InfiniBand Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			pfLink              *netlink.Device
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+			pfLink = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "ib0"}}
+
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.0/net/ib0",
+				"sys/bus/pci/devices/0000:01:00.0/sriov/1",
+				"sys/bus/pci/devices/0000:01:00.1",
+				"sys/bus/pci/devices/0000:01:00.2",
+				"sys/bus/pci/drivers/mlx5_core",
+			}
+			fs.Files = map[string][]byte{
+				"sys/bus/pci/devices/0000:01:00.0/sriov/1/node": []byte("02:00:00:00:00:00:00:0A\n"),
+				"sys/bus/pci/devices/0000:01:00.0/sriov/1/port": []byte("02:00:00:00:00:00:00:0B\n"),
+				"sys/bus/pci/drivers/mlx5_core/bind":            {},
+				"sys/bus/pci/drivers/mlx5_core/unbind":          {},
+			}
+			fs.Symlinks = map[string]string{
+				"sys/bus/pci/devices/0000:01:00.0/virtfn0": "../0000:01:00.1",
+				"sys/bus/pci/devices/0000:01:00.0/virtfn1": "../0000:01:00.2",
+				"sys/bus/pci/devices/0000:01:00.1/physfn":  "../0000:01:00.0",
+				"sys/bus/pci/devices/0000:01:00.2/physfn":  "../0000:01:00.0",
+				"sys/bus/pci/devices/0000:01:00.2/driver":  "../../../bus/pci/drivers/mlx5_core",
+			}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should read the GUIDs of the VF from the sriov directory of its PF", func() {
+			tearDown = fs.Use()
+
+			nodeGUID, portGUID, err := hostImpl.GetVFGUIDs("0000:01:00.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nodeGUID).To(Equal("02:00:00:00:00:00:00:0a"))
+			Expect(portGUID).To(Equal("02:00:00:00:00:00:00:0b"))
+
+			_, _, err = hostImpl.GetVFGUIDs("0000:01:00.1")
+			Expect(err).To(MatchError(ContainSubstring("failed to read node GUID of device 0000:01:00.1")))
+		})
+
+		It("should set the GUIDs through the PF and rebind the VF driver", func() {
+			tearDown = fs.Use()
+			nodeGUID, _ := net.ParseMAC("02:00:00:00:00:00:00:01")
+			portGUID, _ := net.ParseMAC("02:00:00:00:00:00:00:02")
+			mockNetlinkProvider.EXPECT().LinkByName("ib0").Return(pfLink, nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfNodeGUID(pfLink, 1, nodeGUID).Return(nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfPortGUID(pfLink, 1, portGUID).Return(nil)
+
+			Expect(hostImpl.SetVFGUIDs("0000:01:00.2", "02:00:00:00:00:00:00:01", "02:00:00:00:00:00:00:02")).To(Succeed())
+			unbind, err := os.ReadFile(filepath.Join(host.RootDir, "sys/bus/pci/drivers/mlx5_core/unbind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(unbind)).To(Equal("0000:01:00.2"))
+			bind, err := os.ReadFile(filepath.Join(host.RootDir, "sys/bus/pci/drivers/mlx5_core/bind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(bind)).To(Equal("0000:01:00.2"))
+		})
+
+		It("should report the GUIDs the PF failed to set", func() {
+			tearDown = fs.Use()
+			mockNetlinkProvider.EXPECT().LinkByName("ib0").Return(pfLink, nil)
+			mockNetlinkProvider.EXPECT().LinkSetVfNodeGUID(pfLink, 0, gomock.Any()).Return(syscall.EOPNOTSUPP)
+			mockNetlinkProvider.EXPECT().LinkSetVfPortGUID(pfLink, 0, gomock.Any()).Return(nil)
+
+			err := hostImpl.SetVFGUIDs("0000:01:00.1", "02:00:00:00:00:00:00:01", "02:00:00:00:00:00:00:01")
+			Expect(err).To(MatchError(ContainSubstring("failed to set node GUID")))
+			Expect(errors.Is(err, syscall.EOPNOTSUPP)).To(BeTrue())
+		})
+
+		It("should reject invalid GUIDs", func() {
+			tearDown = fs.Use()
+
+			Expect(hostImpl.SetVFGUIDs("0000:01:00.1", "not-a-guid", "02:00:00:00:00:00:00:01")).To(MatchError(ContainSubstring("invalid node GUID")))
+		})
+	})
+
+	Describe("Capability Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should report PFs with an eswitch mode as switchdev capable", func() {
+			mockNetlinkProvider.EXPECT().DevLinkGetDeviceByName("pci", "0000:01:00.0").Return(&netlink.DevlinkDevice{
+				Attrs: netlink.DevlinkDevAttrs{Eswitch: netlink.DevlinkDevEswitchAttr{Mode: "legacy"}},
+			}, nil)
+			mockNetlinkProvider.EXPECT().DevLinkGetDeviceByName("pci", "0000:02:00.0").Return(&netlink.DevlinkDevice{}, nil)
+			mockNetlinkProvider.EXPECT().DevLinkGetDeviceByName("pci", "0000:03:00.0").Return(nil, fmt.Errorf("no such device"))
+
+			Expect(hostImpl.IsSwitchdevCapable("0000:01:00.0")).To(BeTrue())
+			Expect(hostImpl.IsSwitchdevCapable("0000:02:00.0")).To(BeFalse())
+			Expect(hostImpl.IsSwitchdevCapable("0000:03:00.0")).To(BeFalse())
+		})
+
+		It("should report the eswitch mode of the PFs", func() {
+			mockNetlinkProvider.EXPECT().DevLinkGetDeviceByName("pci", "0000:01:00.0").Return(&netlink.DevlinkDevice{
+				Attrs: netlink.DevlinkDevAttrs{Eswitch: netlink.DevlinkDevEswitchAttr{Mode: "switchdev"}},
+			}, nil)
+			mockNetlinkProvider.EXPECT().DevLinkGetDeviceByName("pci", "0000:02:00.0").Return(&netlink.DevlinkDevice{}, nil)
+			mockNetlinkProvider.EXPECT().DevLinkGetDeviceByName("pci", "0000:03:00.0").Return(nil, fmt.Errorf("no such device"))
+
+			Expect(hostImpl.GetNicSriovMode("0000:01:00.0")).To(Equal("switchdev"))
+			Expect(hostImpl.GetNicSriovMode("0000:02:00.0")).To(Equal("legacy"))
+			Expect(hostImpl.GetNicSriovMode("0000:03:00.0")).To(Equal("legacy"))
+		})
+
+		It("should return the PCI vDPA management devices", func() {
+			mockNetlinkProvider.EXPECT().VDPAGetMGMTDevList().Return([]*netlink.VDPAMGMTDev{
+				{BusName: "pci", DevName: "0000:01:00.2"},
+				{DevName: "vdpasim_net"},
+			}, nil)
+
+			Expect(hostImpl.GetVDPAManagementDevices()).To(Equal([]string{"0000:01:00.2"}))
+		})
+	})
+
+	Describe("Primary Interface Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			links               []netlink.Link
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+			// eth0 and eth1 are the ports of bond0, bond0.100 a VLAN on it, eth2 is not used
+			links = []netlink.Link{
+				&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "lo"}},
+				&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 2, Name: "eth0", MasterIndex: 5}},
+				&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 3, Name: "eth1", MasterIndex: 5}},
+				&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 4, Name: "eth2"}},
+				&netlink.Bond{LinkAttrs: netlink.LinkAttrs{Index: 5, Name: "bond0"}},
+				&netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Index: 6, Name: "bond0.100", ParentIndex: 5}},
+			}
+			mockNetlinkProvider.EXPECT().LinkList().Return(links, nil)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should return the interface of the default route and its ports", func() {
+			_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+			mockNetlinkProvider.EXPECT().RouteList(nil, netlink.FAMILY_ALL).Return([]netlink.Route{
+				{LinkIndex: 4, Dst: subnet},
+				{LinkIndex: 5},
+			}, nil)
+
+			Expect(hostImpl.GetPrimaryInterfaces(nil)).To(Equal([]string{"bond0", "bond0.100", "eth0", "eth1"}))
+		})
+
+		It("should return the interface holding a node IP", func() {
+			mockNetlinkProvider.EXPECT().RouteList(nil, netlink.FAMILY_ALL).Return(nil, nil)
+			mockNetlinkProvider.EXPECT().AddrList(nil, netlink.FAMILY_ALL).Return([]netlink.Addr{
+				{IPNet: &net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)}, LinkIndex: 1},
+				{IPNet: &net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}, LinkIndex: 4},
+			}, nil)
+
+			Expect(hostImpl.GetPrimaryInterfaces([]string{"192.168.1.10"})).To(Equal([]string{"eth2"}))
+		})
+
+		It("should fail on an invalid node IP", func() {
+			mockNetlinkProvider.EXPECT().RouteList(nil, netlink.FAMILY_ALL).Return(nil, nil)
+			mockNetlinkProvider.EXPECT().AddrList(nil, netlink.FAMILY_ALL).Return(nil, nil)
+
+			_, err := hostImpl.GetPrimaryInterfaces([]string{"node1"})
+			Expect(err).To(MatchError(ContainSubstring("invalid node IP")))
+		})
+	})
+
+	Describe("RDMA netns Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should return the RDMA netns mode of the kernel", func() {
+			mockNetlinkProvider.EXPECT().RdmaSystemGetNetnsMode().Return(host.RDMANetnsModeExclusive, nil)
+
+			mode, err := hostImpl.GetRDMANetnsMode()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(host.RDMANetnsModeExclusive))
+		})
+
+		It("should move RDMA devices between the driver and the pod network namespaces", func() {
+			gomock.InOrder(
+				mockNetlinkProvider.EXPECT().RdmaLinkSetNetns("mlx5_2", "", "/proc/123/ns/net").Return(nil),
+				mockNetlinkProvider.EXPECT().RdmaLinkSetNetns("mlx5_2", "/proc/123/ns/net", "").Return(nil),
+			)
+
+			Expect(hostImpl.MoveRDMADeviceToNetns("mlx5_2", "/proc/123/ns/net")).To(Succeed())
+			Expect(hostImpl.MoveRDMADeviceFromNetns("mlx5_2", "/proc/123/ns/net")).To(Succeed())
+		})
+
+		It("should skip RDMA devices already moved", func() {
+			mockNetlinkProvider.EXPECT().RdmaLinkSetNetns("mlx5_2", "", "/proc/123/ns/net").
+				Return(fmt.Errorf("%w: mlx5_2", host.ErrRdmaLinkNotFound))
+
+			Expect(hostImpl.MoveRDMADeviceToNetns("mlx5_2", "/proc/123/ns/net")).To(Succeed())
+		})
+
+		It("should return other errors", func() {
+			mockNetlinkProvider.EXPECT().RdmaLinkSetNetns("mlx5_2", "/proc/123/ns/net", "").Return(errors.New("operation not permitted"))
+
+			err := hostImpl.MoveRDMADeviceFromNetns("mlx5_2", "/proc/123/ns/net")
+			Expect(err).To(MatchError(ContainSubstring("operation not permitted")))
+		})
+	})
+
+	Describe("Link Statistics Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should return the counters of a link in a network namespace", func() {
+			link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{
+				Name: "net1",
+				Statistics: &netlink.LinkStatistics{
+					RxBytes: 1500, TxBytes: 3000, RxPackets: 1, TxPackets: 2, RxDropped: 3, TxDropped: 4,
+				},
+			}}
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+
+			stats, err := hostImpl.GetLinkStatistics("/proc/123/ns/net", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*stats).To(Equal(host.LinkStatistics{
+				RxBytes: 1500, TxBytes: 3000, RxPackets: 1, TxPackets: 2, RxDropped: 3, TxDropped: 4,
+			}))
+		})
+
+		It("should fail when the link is not found", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(nil, errors.New("link not found"))
+
+			_, err := hostImpl.GetLinkStatistics("/proc/123/ns/net", "net1")
+			Expect(err).To(MatchError(ContainSubstring("failed to get link net1")))
+		})
+
+		It("should fail when the link reports no statistics", func() {
+			link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "net1"}}
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+
+			_, err := hostImpl.GetLinkStatistics("/proc/123/ns/net", "net1")
+			Expect(err).To(MatchError(ContainSubstring("no statistics reported")))
+		})
+	})
+
+	Describe("Netns Route Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			link                *netlink.Device
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+			link = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "net1", Index: 3}}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should add the routes through the link in the network namespace", func() {
+			_, dst, _ := net.ParseCIDR("10.10.0.0/16")
+			_, linkDst, _ := net.ParseCIDR("192.168.100.0/24")
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			mockNetlinkProvider.EXPECT().RouteReplaceAt("/proc/123/ns/net", &netlink.Route{
+				LinkIndex: 3, Dst: dst, Gw: net.ParseIP("192.168.100.1"), Priority: 100,
+			}).Return(nil)
+			mockNetlinkProvider.EXPECT().RouteReplaceAt("/proc/123/ns/net", &netlink.Route{
+				LinkIndex: 3, Dst: linkDst, Scope: netlink.SCOPE_LINK,
+			}).Return(nil)
+
+			Expect(hostImpl.AddNetnsRoutes("/proc/123/ns/net", "net1", []host.Route{
+				{Dst: dst, GW: net.ParseIP("192.168.100.1"), Metric: 100},
+				{Dst: linkDst},
+			})).To(Succeed())
+		})
+
+		It("should fail when a route cannot be added", func() {
+			_, dst, _ := net.ParseCIDR("10.10.0.0/16")
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			mockNetlinkProvider.EXPECT().RouteReplaceAt("/proc/123/ns/net", gomock.Any()).Return(errors.New("network is unreachable"))
+
+			Expect(hostImpl.AddNetnsRoutes("/proc/123/ns/net", "net1", []host.Route{{Dst: dst, GW: net.ParseIP("172.16.0.1")}})).To(
+				MatchError("failed to add route to 10.10.0.0/16 via 172.16.0.1 on net1 in network namespace /proc/123/ns/net: network is unreachable"))
+		})
+
+		It("should fail when the link is not found", func() {
+			_, dst, _ := net.ParseCIDR("10.10.0.0/16")
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(nil, errors.New("link not found"))
+
+			Expect(hostImpl.AddNetnsRoutes("/proc/123/ns/net", "net1", []host.Route{{Dst: dst}})).To(
+				MatchError(ContainSubstring("failed to get link net1")))
+		})
+	})
+
+	Describe("Netns Neighbor Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			link                *netlink.Device
+			mac                 net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+			link = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "net1", Index: 3}}
+			var err error
+			mac, err = net.ParseMAC("0a:1b:2c:3d:4e:5f")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should add permanent neighbors of both IP families to the link", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			mockNetlinkProvider.EXPECT().NeighSetAt("/proc/123/ns/net", &netlink.Neigh{
+				LinkIndex: 3, Family: netlink.FAMILY_V4, State: netlink.NUD_PERMANENT, IP: net.ParseIP("192.168.100.1"), HardwareAddr: mac,
+			}).Return(nil)
+			mockNetlinkProvider.EXPECT().NeighSetAt("/proc/123/ns/net", &netlink.Neigh{
+				LinkIndex: 3, Family: netlink.FAMILY_V6, State: netlink.NUD_PERMANENT, IP: net.ParseIP("fd00::1"), HardwareAddr: mac,
+			}).Return(nil)
+
+			Expect(hostImpl.AddNetnsNeighbors("/proc/123/ns/net", "net1", []host.Neighbor{
+				{IP: net.ParseIP("192.168.100.1"), MAC: mac},
+				{IP: net.ParseIP("fd00::1"), MAC: mac},
+			})).To(Succeed())
+		})
+
+		It("should fail when a neighbor cannot be added", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			mockNetlinkProvider.EXPECT().NeighSetAt("/proc/123/ns/net", gomock.Any()).Return(errors.New("operation not supported"))
+
+			Expect(hostImpl.AddNetnsNeighbors("/proc/123/ns/net", "net1", []host.Neighbor{{IP: net.ParseIP("192.168.100.1"), MAC: mac}})).To(
+				MatchError("failed to add neighbor 0a:1b:2c:3d:4e:5f of 192.168.100.1 on net1 in network namespace /proc/123/ns/net: operation not supported"))
+		})
+	})
+
+	Describe("Netns Interface Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			link                *netlink.Device
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+
+			mac, err := net.ParseMAC("0a:1b:2c:3d:4e:5f")
+			Expect(err).NotTo(HaveOccurred())
+			link = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "net1", HardwareAddr: mac}}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should return the MAC and the PCI address of an interface", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			restore := host.SetEthtoolDrvinfo(func(netnsPath, ifName string) (*unix.EthtoolDrvinfo, error) {
+				Expect(netnsPath).To(Equal("/proc/123/ns/net"))
+				Expect(ifName).To(Equal("net1"))
+				drvinfo := &unix.EthtoolDrvinfo{}
+				copy(drvinfo.Bus_info[:], "0000:3b:02.1")
+				return drvinfo, nil
+			})
+			defer restore()
+
+			iface, err := hostImpl.GetNetnsInterface("/proc/123/ns/net", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(iface).To(Equal(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:5f", PciAddress: "0000:3b:02.1"}))
+		})
+
+		It("should leave the PCI address empty for virtual interfaces", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			restore := host.SetEthtoolDrvinfo(func(string, string) (*unix.EthtoolDrvinfo, error) {
+				drvinfo := &unix.EthtoolDrvinfo{}
+				copy(drvinfo.Bus_info[:], "virtio0")
+				return drvinfo, nil
+			})
+			defer restore()
+
+			iface, err := hostImpl.GetNetnsInterface("/proc/123/ns/net", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(iface.PciAddress).To(BeEmpty())
+		})
+
+		It("should leave the PCI address empty when ethtool is not supported", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(link, nil)
+			restore := host.SetEthtoolDrvinfo(func(string, string) (*unix.EthtoolDrvinfo, error) {
+				return nil, unix.EOPNOTSUPP
+			})
+			defer restore()
+
+			iface, err := hostImpl.GetNetnsInterface("/proc/123/ns/net", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(iface).To(Equal(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:5f"}))
+		})
+
+		It("should fail when the interface is not found", func() {
+			mockNetlinkProvider.EXPECT().LinkByNameAt("/proc/123/ns/net", "net1").Return(nil, errors.New("link not found"))
+
+			_, err := hostImpl.GetNetnsInterface("/proc/123/ns/net", "net1")
+			Expect(err).To(MatchError(ContainSubstring("failed to get link net1 in network namespace /proc/123/ns/net")))
+		})
+	})
+
+	Describe("TC Offload Functions", func() {
+		var (
+			mockCtrl            *gomock.Controller
+			mockNetlinkProvider *mock_host.MockNetlinkProvider
+			hostImpl            *host.Host
+			repLink             *netlink.Device
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockNetlinkProvider = mock_host.NewMockNetlinkProvider(mockCtrl)
+			hostImpl = host.NewHost().(*host.Host)
+			hostImpl.SetNetlinkProvider(mockNetlinkProvider)
+			repLink = &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "pf0vf1", Index: 12}}
+
+			fs.Dirs = []string{
+				"sys/bus/pci/devices/0000:01:00.0/net/ens1f0",
+				"sys/bus/pci/devices/0000:01:00.1",
+				"sys/bus/pci/devices/0000:01:00.2",
+				"sys/class/net/ens1f0",
+				"sys/class/net/ens1f1",
+				"sys/class/net/pf0vf0",
+				"sys/class/net/pf0vf1",
+				"sys/class/net/pf1vf1",
+				"sys/class/net/eth0",
+			}
+			fs.Files = map[string][]byte{
+				"sys/class/net/ens1f0/phys_switch_id": []byte("a1b2c3\n"),
+				"sys/class/net/ens1f0/phys_port_name": []byte("p0\n"),
+				"sys/class/net/ens1f1/phys_switch_id": []byte("a1b2c3\n"),
+				"sys/class/net/ens1f1/phys_port_name": []byte("p1\n"),
+				"sys/class/net/pf0vf0/phys_switch_id": []byte("a1b2c3\n"),
+				"sys/class/net/pf0vf0/phys_port_name": []byte("pf0vf0\n"),
+				"sys/class/net/pf0vf1/phys_switch_id": []byte("a1b2c3\n"),
+				"sys/class/net/pf0vf1/phys_port_name": []byte("pf0vf1\n"),
+				"sys/class/net/pf1vf1/phys_switch_id": []byte("a1b2c3\n"),
+				"sys/class/net/pf1vf1/phys_port_name": []byte("pf1vf1\n"),
+			}
+			fs.Symlinks = map[string]string{
+				"sys/bus/pci/devices/0000:01:00.0/virtfn0": "../0000:01:00.1",
+				"sys/bus/pci/devices/0000:01:00.0/virtfn1": "../0000:01:00.2",
+				"sys/bus/pci/devices/0000:01:00.1/physfn":  "../0000:01:00.0",
+				"sys/bus/pci/devices/0000:01:00.2/physfn":  "../0000:01:00.0",
+			}
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should find the representor of a VF among the ports of the switch of its PF", func() {
+			tearDown = fs.Use()
+
+			representor, err := hostImpl.GetVFRepresentor("0000:01:00.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(representor).To(Equal("pf0vf1"))
+			representor, err = hostImpl.GetVFRepresentor("0000:01:00.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(representor).To(Equal("pf0vf0"))
+		})
+
+		It("should fail when the PF is not in switchdev mode", func() {
+			delete(fs.Files, "sys/class/net/ens1f0/phys_switch_id")
+			tearDown = fs.Use()
+
+			_, err := hostImpl.GetVFRepresentor("0000:01:00.2")
+			Expect(err).To(MatchError(ContainSubstring("is not in switchdev mode")))
+		})
+
+		It("should enable hw-tc-offload and add the clsact qdisc", func() {
+			restore := host.SetEthtoolSetFeature(func(ifName, feature string, enable bool) error {
+				Expect(ifName).To(Equal("pf0vf1"))
+				Expect(feature).To(Equal("hw-tc-offload"))
+				Expect(enable).To(BeTrue())
+				return nil
+			})
+			defer restore()
+			mockNetlinkProvider.EXPECT().LinkByName("pf0vf1").Return(repLink, nil)
+			mockNetlinkProvider.EXPECT().QdiscReplace(gomock.Any()).DoAndReturn(func(qdisc netlink.Qdisc) error {
+				Expect(qdisc.Type()).To(Equal("clsact"))
+				Expect(qdisc.Attrs().LinkIndex).To(Equal(12))
+				Expect(qdisc.Attrs().Parent).To(Equal(uint32(netlink.HANDLE_CLSACT)))
+				return nil
+			})
+
+			Expect(hostImpl.EnableTCOffload("pf0vf1", configapi.TCOffloadQdiscClsact)).To(Succeed())
+		})
+
+		It("should fail when the representor cannot offload TC filters", func() {
+			restore := host.SetEthtoolSetFeature(func(_, _ string, _ bool) error {
+				return errors.New("feature hw-tc-offload not reported by interface pf0vf1")
+			})
+			defer restore()
+
+			Expect(hostImpl.EnableTCOffload("pf0vf1", configapi.TCOffloadQdiscIngress)).To(MatchError(ContainSubstring("failed to enable hw-tc-offload on representor pf0vf1")))
+		})
+
+		It("should delete the ingress qdisc and ignore a qdisc already gone", func() {
+			mockNetlinkProvider.EXPECT().LinkByName("pf0vf1").Return(repLink, nil).Times(2)
+			gomock.InOrder(
+				mockNetlinkProvider.EXPECT().QdiscDel(gomock.Any()).DoAndReturn(func(qdisc netlink.Qdisc) error {
+					Expect(qdisc.Type()).To(Equal("ingress"))
+					return nil
+				}),
+				mockNetlinkProvider.EXPECT().QdiscDel(gomock.Any()).Return(unix.ENOENT),
+			)
+
+			Expect(hostImpl.DisableTCOffload("pf0vf1", configapi.TCOffloadQdiscIngress)).To(Succeed())
+			Expect(hostImpl.DisableTCOffload("pf0vf1", configapi.TCOffloadQdiscIngress)).To(Succeed())
+		})
+
+		It("should ignore a representor already gone", func() {
+			mockNetlinkProvider.EXPECT().LinkByName("pf0vf1").Return(nil, netlink.LinkNotFoundError{})
+
+			Expect(hostImpl.DisableTCOffload("pf0vf1", configapi.TCOffloadQdiscClsact)).To(Succeed())
+		})
+	})
+
 	Describe("RDMA Device Functions", func() {
 		var (
 			mockCtrl         *gomock.Controller
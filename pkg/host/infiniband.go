@@ -0,0 +1,85 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// vfGUIDPath returns the sysfs file holding the node or port GUID of a VF, exposed by the IB
+// driver of the PF, e.g. mlx5_ib, under its sriov directory.
+func vfGUIDPath(pfPciAddress string, vfID int, guid string) string {
+	return buildSysBusPciPath(pfPciAddress, "sriov/"+strconv.Itoa(vfID)+"/"+guid)
+}
+
+// GetVFGUIDs returns the node and port GUIDs of an InfiniBand VF, as reported by its PF.
+func (h *Host) GetVFGUIDs(pciAddress string) (nodeGUID, portGUID string, err error) {
+	pfPciAddress, vfID, err := getVFID(pciAddress)
+	if err != nil {
+		return "", "", err
+	}
+	if nodeGUID, err = readGUID(vfGUIDPath(pfPciAddress, vfID, "node")); err != nil {
+		return "", "", fmt.Errorf("failed to read node GUID of device %s: %w", pciAddress, err)
+	}
+	if portGUID, err = readGUID(vfGUIDPath(pfPciAddress, vfID, "port")); err != nil {
+		return "", "", fmt.Errorf("failed to read port GUID of device %s: %w", pciAddress, err)
+	}
+	return nodeGUID, portGUID, nil
+}
+
+func readGUID(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	guid, err := net.ParseMAC(strings.TrimSpace(string(content)))
+	if err != nil {
+		return "", err
+	}
+	return guid.String(), nil
+}
+
+// SetVFGUIDs sets the node and port GUIDs of an InfiniBand VF through its PF. The VF driver only
+// reads the GUIDs when it probes the VF, so a VF bound to a driver is rebound to it.
+func (h *Host) SetVFGUIDs(pciAddress, nodeGUID, portGUID string) error {
+	pfName, vfID, err := h.getVFIndex(pciAddress)
+	if err != nil {
+		return err
+	}
+	node, err := net.ParseMAC(nodeGUID)
+	if err != nil {
+		return fmt.Errorf("invalid node GUID %q: %w", nodeGUID, err)
+	}
+	port, err := net.ParseMAC(portGUID)
+	if err != nil {
+		return fmt.Errorf("invalid port GUID %q: %w", portGUID, err)
+	}
+	link, err := h.netlinkProvider.LinkByName(pfName)
+	if err != nil {
+		return fmt.Errorf("failed to get PF link %s: %w", pfName, err)
+	}
+	h.log.V(2).Info("SetVFGUIDs(): set VF GUIDs", "device", pciAddress, "pf", pfName, "vf", vfID, "nodeGUID", nodeGUID, "portGUID", portGUID)
+
+	var errs []error
+	if err := h.netlinkProvider.LinkSetVfNodeGUID(link, vfID, node); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set node GUID: %w", err))
+	}
+	if err := h.netlinkProvider.LinkSetVfPortGUID(link, vfID, port); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set port GUID: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to set GUIDs of VF %d of PF %s: %w", vfID, pfName, err)
+	}
+
+	driver, err := h.GetDriverByBusAndDevice(pciAddress)
+	if err != nil || driver == "" {
+		return err
+	}
+	if err := h.unbindDriver(pciAddress, driver); err != nil {
+		return err
+	}
+	return h.bindDriver(pciAddress, driver)
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// hostRootDir is where the host filesystem is reachable from the driver container.
+const hostRootDir = "/proc/1/root"
+
+var (
+	// ErrKernelModuleNotFound is returned when a module is neither installed for the running
+	// kernel nor built into it.
+	ErrKernelModuleNotFound = errors.New("kernel module not found")
+	// ErrKernelModuleSignatureRejected is returned when the kernel refuses the signature of a
+	// module, e.g. an unsigned out-of-tree module with enforced module signatures or secure boot.
+	ErrKernelModuleSignatureRejected = errors.New("kernel module signature rejected")
+	// ErrKernelModuleNotPermitted is returned when the driver is not allowed to load modules,
+	// because it lacks CAP_SYS_MODULE or the kernel is locked down.
+	ErrKernelModuleNotPermitted = errors.New("kernel module loading not permitted")
+)
+
+// finitModule loads a module from an open file, replaced in tests.
+var finitModule = unix.FinitModule
+
+// KernelModuleError describes a module that failed to load. It matches one of the
+// ErrKernelModule* sentinels with errors.Is when the failure could be classified.
+type KernelModuleError struct {
+	Module string
+	Reason error
+	Err    error
+}
+
+func (e *KernelModuleError) Error() string {
+	if e.Reason != nil {
+		return fmt.Sprintf("failed to load kernel module %s: %v: %v", e.Module, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("failed to load kernel module %s: %v", e.Module, e.Err)
+}
+
+func (e *KernelModuleError) Unwrap() []error {
+	if e.Reason != nil {
+		return []error{e.Reason, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// classifyKernelModuleError maps a finit_module error to one of the ErrKernelModule* sentinels,
+// or nil if unknown.
+func classifyKernelModuleError(err error) error {
+	switch {
+	case errors.Is(err, unix.ENOKEY), errors.Is(err, unix.EKEYREJECTED), errors.Is(err, unix.EBADMSG):
+		return ErrKernelModuleSignatureRejected
+	case errors.Is(err, unix.EPERM):
+		return ErrKernelModuleNotPermitted
+	case errors.Is(err, unix.ENOENT):
+		return ErrKernelModuleNotFound
+	default:
+		return nil
+	}
+}
+
+// loadKernelModule loads a module of the running kernel from the host filesystem, after the
+// modules it depends on, like modprobe does. The parameters only apply to the module itself.
+// Modules built into the kernel and modules already loaded are left alone.
+func loadKernelModule(moduleName string, params ...string) error {
+	modulesDir, err := kernelModulesDir()
+	if err != nil {
+		return &KernelModuleError{Module: moduleName, Reason: ErrKernelModuleNotFound, Err: err}
+	}
+	builtin, err := isBuiltinKernelModule(modulesDir, moduleName)
+	if err != nil {
+		return &KernelModuleError{Module: moduleName, Err: err}
+	}
+	if builtin {
+		return nil
+	}
+	modulePaths, err := resolveKernelModule(modulesDir, moduleName)
+	if err != nil {
+		return &KernelModuleError{Module: moduleName, Reason: classifyKernelModuleError(err), Err: err}
+	}
+
+	for i, modulePath := range modulePaths {
+		var moduleParams string
+		if i == len(modulePaths)-1 {
+			moduleParams = strings.Join(params, " ")
+		}
+		if err := insertKernelModule(filepath.Join(modulesDir, modulePath), moduleParams); err != nil {
+			return &KernelModuleError{Module: kernelModuleName(modulePath), Reason: classifyKernelModuleError(err), Err: err}
+		}
+	}
+	return nil
+}
+
+// kernelModulesDir returns the host directory holding the modules of the running kernel.
+func kernelModulesDir() (string, error) {
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return "", fmt.Errorf("failed to get kernel release: %w", err)
+	}
+	release := unix.ByteSliceToString(uname.Release[:])
+	// usrmerge distributions only have /usr/lib/modules, /lib may be an absolute symlink which
+	// would resolve in the container filesystem
+	for _, dir := range []string{"lib/modules", "usr/lib/modules"} {
+		modulesDir := filepath.Join(buildProcPath(hostRootDir), dir, release)
+		if _, err := os.Stat(filepath.Join(modulesDir, "modules.dep")); err == nil {
+			return modulesDir, nil
+		}
+	}
+	return "", fmt.Errorf("no modules.dep found for kernel %s on the host", release)
+}
+
+// kernelModuleName returns the name of a module from its path, with dashes normalized to
+// underscores as the kernel does.
+func kernelModuleName(modulePath string) string {
+	name := filepath.Base(modulePath)
+	if i := strings.Index(name, ".ko"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// isBuiltinKernelModule reports whether a module is built into the kernel, per modules.builtin.
+func isBuiltinKernelModule(modulesDir, moduleName string) (bool, error) {
+	file, err := os.Open(filepath.Join(modulesDir, "modules.builtin"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	name := kernelModuleName(moduleName)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if kernelModuleName(strings.TrimSpace(scanner.Text())) == name {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// resolveKernelModule returns the paths, relative to the modules directory, of a module and the
+// modules it depends on, per modules.dep, in loading order: dependencies first.
+func resolveKernelModule(modulesDir, moduleName string) ([]string, error) {
+	file, err := os.Open(filepath.Join(modulesDir, "modules.dep"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	name := kernelModuleName(moduleName)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		modulePath, deps, found := strings.Cut(scanner.Text(), ":")
+		if !found || kernelModuleName(modulePath) != name {
+			continue
+		}
+		// dependencies are listed from the closest to the deepest, load them the other way around
+		depPaths := strings.Fields(deps)
+		paths := make([]string, 0, len(depPaths)+1)
+		for i := len(depPaths) - 1; i >= 0; i-- {
+			paths = append(paths, depPaths[i])
+		}
+		return append(paths, modulePath), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: %s is not in modules.dep", ErrKernelModuleNotFound, moduleName)
+}
+
+// insertKernelModule loads a module file with finit_module, compressed modules are decompressed
+// by the kernel. A module that is already loaded is not an error.
+func insertKernelModule(path string, params string) error {
+	file, err := os.Open(path) /* #nosec G304 */
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var flags int
+	if ext := filepath.Ext(path); ext == ".xz" || ext == ".zst" || ext == ".gz" {
+		flags |= unix.MODULE_INIT_COMPRESSED_FILE
+	}
+	err = finitModule(int(file.Fd()), params, flags)
+	if errors.Is(err, unix.EEXIST) {
+		return nil
+	}
+	if errors.Is(err, unix.EOPNOTSUPP) && flags&unix.MODULE_INIT_COMPRESSED_FILE != 0 {
+		return fmt.Errorf("kernel cannot decompress module %s: %w", path, err)
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"fmt"
+)
+
+// LinkStatistics are the traffic counters of a network interface since it was created.
+type LinkStatistics struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+// GetLinkStatistics returns the traffic counters of a network interface in a network namespace,
+// e.g. the one of the pod a VF is attached to.
+func (h *Host) GetLinkStatistics(netnsPath, ifName string) (*LinkStatistics, error) {
+	link, err := h.netlinkProvider.LinkByNameAt(netnsPath, ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link %s in network namespace %s: %w", ifName, netnsPath, err)
+	}
+	stats := link.Attrs().Statistics
+	if stats == nil {
+		return nil, fmt.Errorf("no statistics reported for link %s in network namespace %s", ifName, netnsPath)
+	}
+	return &LinkStatistics{
+		RxBytes:   stats.RxBytes,
+		TxBytes:   stats.TxBytes,
+		RxPackets: stats.RxPackets,
+		TxPackets: stats.TxPackets,
+		RxDropped: stats.RxDropped,
+		TxDropped: stats.TxDropped,
+	}, nil
+}
@@ -42,6 +42,34 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AddNetnsNeighbors mocks base method.
+func (m *MockInterface) AddNetnsNeighbors(netnsPath, ifName string, neighbors []host.Neighbor) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNetnsNeighbors", netnsPath, ifName, neighbors)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNetnsNeighbors indicates an expected call of AddNetnsNeighbors.
+func (mr *MockInterfaceMockRecorder) AddNetnsNeighbors(netnsPath, ifName, neighbors any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNetnsNeighbors", reflect.TypeOf((*MockInterface)(nil).AddNetnsNeighbors), netnsPath, ifName, neighbors)
+}
+
+// AddNetnsRoutes mocks base method.
+func (m *MockInterface) AddNetnsRoutes(netnsPath, ifName string, routes []host.Route) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNetnsRoutes", netnsPath, ifName, routes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNetnsRoutes indicates an expected call of AddNetnsRoutes.
+func (mr *MockInterfaceMockRecorder) AddNetnsRoutes(netnsPath, ifName, routes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNetnsRoutes", reflect.TypeOf((*MockInterface)(nil).AddNetnsRoutes), netnsPath, ifName, routes)
+}
+
 // BindDefaultDriver mocks base method.
 func (m *MockInterface) BindDefaultDriver(pciAddress string) error {
 	m.ctrl.T.Helper()
@@ -85,6 +113,48 @@ func (mr *MockInterfaceMockRecorder) BindDriverByBusAndDevice(device, driver any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindDriverByBusAndDevice", reflect.TypeOf((*MockInterface)(nil).BindDriverByBusAndDevice), device, driver)
 }
 
+// DisableTCOffload mocks base method.
+func (m *MockInterface) DisableTCOffload(representor, qdisc string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTCOffload", representor, qdisc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTCOffload indicates an expected call of DisableTCOffload.
+func (mr *MockInterfaceMockRecorder) DisableTCOffload(representor, qdisc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTCOffload", reflect.TypeOf((*MockInterface)(nil).DisableTCOffload), representor, qdisc)
+}
+
+// EnableTCOffload mocks base method.
+func (m *MockInterface) EnableTCOffload(representor, qdisc string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTCOffload", representor, qdisc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTCOffload indicates an expected call of EnableTCOffload.
+func (mr *MockInterfaceMockRecorder) EnableTCOffload(representor, qdisc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTCOffload", reflect.TypeOf((*MockInterface)(nil).EnableTCOffload), representor, qdisc)
+}
+
+// EnableVFIONoIOMMU mocks base method.
+func (m *MockInterface) EnableVFIONoIOMMU() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableVFIONoIOMMU")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableVFIONoIOMMU indicates an expected call of EnableVFIONoIOMMU.
+func (mr *MockInterfaceMockRecorder) EnableVFIONoIOMMU() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableVFIONoIOMMU", reflect.TypeOf((*MockInterface)(nil).EnableVFIONoIOMMU))
+}
+
 // EnsureDpdkModuleLoaded mocks base method.
 func (m *MockInterface) EnsureDpdkModuleLoaded(driver string) error {
 	m.ctrl.T.Helper()
@@ -99,6 +169,20 @@ func (mr *MockInterfaceMockRecorder) EnsureDpdkModuleLoaded(driver any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureDpdkModuleLoaded", reflect.TypeOf((*MockInterface)(nil).EnsureDpdkModuleLoaded), driver)
 }
 
+// EnsureRDMACMModuleLoaded mocks base method.
+func (m *MockInterface) EnsureRDMACMModuleLoaded() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureRDMACMModuleLoaded")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureRDMACMModuleLoaded indicates an expected call of EnsureRDMACMModuleLoaded.
+func (mr *MockInterfaceMockRecorder) EnsureRDMACMModuleLoaded() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureRDMACMModuleLoaded", reflect.TypeOf((*MockInterface)(nil).EnsureRDMACMModuleLoaded))
+}
+
 // EnsureVhostModulesLoaded mocks base method.
 func (m *MockInterface) EnsureVhostModulesLoaded() error {
 	m.ctrl.T.Helper()
@@ -113,6 +197,36 @@ func (mr *MockInterfaceMockRecorder) EnsureVhostModulesLoaded() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureVhostModulesLoaded", reflect.TypeOf((*MockInterface)(nil).EnsureVhostModulesLoaded))
 }
 
+// GetBoardID mocks base method.
+func (m *MockInterface) GetBoardID(pciAddress string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBoardID", pciAddress)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBoardID indicates an expected call of GetBoardID.
+func (mr *MockInterfaceMockRecorder) GetBoardID(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBoardID", reflect.TypeOf((*MockInterface)(nil).GetBoardID), pciAddress)
+}
+
+// GetCombinedChannels mocks base method.
+func (m *MockInterface) GetCombinedChannels(pciAddress string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCombinedChannels", pciAddress)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCombinedChannels indicates an expected call of GetCombinedChannels.
+func (mr *MockInterfaceMockRecorder) GetCombinedChannels(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCombinedChannels", reflect.TypeOf((*MockInterface)(nil).GetCombinedChannels), pciAddress)
+}
+
 // GetDriverByBusAndDevice mocks base method.
 func (m *MockInterface) GetDriverByBusAndDevice(device string) (string, error) {
 	m.ctrl.T.Helper()
@@ -128,6 +242,96 @@ func (mr *MockInterfaceMockRecorder) GetDriverByBusAndDevice(device any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDriverByBusAndDevice", reflect.TypeOf((*MockInterface)(nil).GetDriverByBusAndDevice), device)
 }
 
+// GetDriverInfo mocks base method.
+func (m *MockInterface) GetDriverInfo(ifName string) (*host.DriverInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDriverInfo", ifName)
+	ret0, _ := ret[0].(*host.DriverInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDriverInfo indicates an expected call of GetDriverInfo.
+func (mr *MockInterfaceMockRecorder) GetDriverInfo(ifName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDriverInfo", reflect.TypeOf((*MockInterface)(nil).GetDriverInfo), ifName)
+}
+
+// GetEthtoolStats mocks base method.
+func (m *MockInterface) GetEthtoolStats(netnsPath, ifName string) (map[string]uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEthtoolStats", netnsPath, ifName)
+	ret0, _ := ret[0].(map[string]uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEthtoolStats indicates an expected call of GetEthtoolStats.
+func (mr *MockInterfaceMockRecorder) GetEthtoolStats(netnsPath, ifName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEthtoolStats", reflect.TypeOf((*MockInterface)(nil).GetEthtoolStats), netnsPath, ifName)
+}
+
+// GetFeatures mocks base method.
+func (m *MockInterface) GetFeatures(pciAddress string) (map[string]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatures", pciAddress)
+	ret0, _ := ret[0].(map[string]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFeatures indicates an expected call of GetFeatures.
+func (mr *MockInterfaceMockRecorder) GetFeatures(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatures", reflect.TypeOf((*MockInterface)(nil).GetFeatures), pciAddress)
+}
+
+// GetIOMMUGroup mocks base method.
+func (m *MockInterface) GetIOMMUGroup(pciAddress string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIOMMUGroup", pciAddress)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIOMMUGroup indicates an expected call of GetIOMMUGroup.
+func (mr *MockInterfaceMockRecorder) GetIOMMUGroup(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIOMMUGroup", reflect.TypeOf((*MockInterface)(nil).GetIOMMUGroup), pciAddress)
+}
+
+// GetInterfaceMACAddress mocks base method.
+func (m *MockInterface) GetInterfaceMACAddress(pciAddr, ifName string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInterfaceMACAddress", pciAddr, ifName)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInterfaceMACAddress indicates an expected call of GetInterfaceMACAddress.
+func (mr *MockInterfaceMockRecorder) GetInterfaceMACAddress(pciAddr, ifName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInterfaceMACAddress", reflect.TypeOf((*MockInterface)(nil).GetInterfaceMACAddress), pciAddr, ifName)
+}
+
+// GetLinkStatistics mocks base method.
+func (m *MockInterface) GetLinkStatistics(netnsPath, ifName string) (*host.LinkStatistics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLinkStatistics", netnsPath, ifName)
+	ret0, _ := ret[0].(*host.LinkStatistics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkStatistics indicates an expected call of GetLinkStatistics.
+func (mr *MockInterfaceMockRecorder) GetLinkStatistics(netnsPath, ifName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkStatistics", reflect.TypeOf((*MockInterface)(nil).GetLinkStatistics), netnsPath, ifName)
+}
+
 // GetLinkType mocks base method.
 func (m *MockInterface) GetLinkType(pciAddr string) (string, error) {
 	m.ctrl.T.Helper()
@@ -143,6 +347,21 @@ func (mr *MockInterfaceMockRecorder) GetLinkType(pciAddr any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkType", reflect.TypeOf((*MockInterface)(nil).GetLinkType), pciAddr)
 }
 
+// GetNetnsInterface mocks base method.
+func (m *MockInterface) GetNetnsInterface(netnsPath, ifName string) (*host.NetnsInterface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetnsInterface", netnsPath, ifName)
+	ret0, _ := ret[0].(*host.NetnsInterface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNetnsInterface indicates an expected call of GetNetnsInterface.
+func (mr *MockInterfaceMockRecorder) GetNetnsInterface(netnsPath, ifName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetnsInterface", reflect.TypeOf((*MockInterface)(nil).GetNetnsInterface), netnsPath, ifName)
+}
+
 // GetNicSriovMode mocks base method.
 func (m *MockInterface) GetNicSriovMode(pciAddr string) string {
 	m.ctrl.T.Helper()
@@ -172,6 +391,21 @@ func (mr *MockInterfaceMockRecorder) GetNumaNode(pciAddress any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNumaNode", reflect.TypeOf((*MockInterface)(nil).GetNumaNode), pciAddress)
 }
 
+// GetNumaNodeCPUs mocks base method.
+func (m *MockInterface) GetNumaNodeCPUs(numaNode string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNumaNodeCPUs", numaNode)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNumaNodeCPUs indicates an expected call of GetNumaNodeCPUs.
+func (mr *MockInterfaceMockRecorder) GetNumaNodeCPUs(numaNode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNumaNodeCPUs", reflect.TypeOf((*MockInterface)(nil).GetNumaNodeCPUs), numaNode)
+}
+
 // GetPCIeRoot mocks base method.
 func (m *MockInterface) GetPCIeRoot(pciAddress string) (string, error) {
 	m.ctrl.T.Helper()
@@ -187,6 +421,21 @@ func (mr *MockInterfaceMockRecorder) GetPCIeRoot(pciAddress any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPCIeRoot", reflect.TypeOf((*MockInterface)(nil).GetPCIeRoot), pciAddress)
 }
 
+// GetPrimaryInterfaces mocks base method.
+func (m *MockInterface) GetPrimaryInterfaces(nodeIPs []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrimaryInterfaces", nodeIPs)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrimaryInterfaces indicates an expected call of GetPrimaryInterfaces.
+func (mr *MockInterfaceMockRecorder) GetPrimaryInterfaces(nodeIPs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrimaryInterfaces", reflect.TypeOf((*MockInterface)(nil).GetPrimaryInterfaces), nodeIPs)
+}
+
 // GetRDMACharDevices mocks base method.
 func (m *MockInterface) GetRDMACharDevices(rdmaDeviceName string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -216,6 +465,98 @@ func (mr *MockInterfaceMockRecorder) GetRDMADevicesForPCI(pciAddr any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRDMADevicesForPCI", reflect.TypeOf((*MockInterface)(nil).GetRDMADevicesForPCI), pciAddr)
 }
 
+// GetRDMANetnsMode mocks base method.
+func (m *MockInterface) GetRDMANetnsMode() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRDMANetnsMode")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRDMANetnsMode indicates an expected call of GetRDMANetnsMode.
+func (mr *MockInterfaceMockRecorder) GetRDMANetnsMode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRDMANetnsMode", reflect.TypeOf((*MockInterface)(nil).GetRDMANetnsMode))
+}
+
+// GetRingSizes mocks base method.
+func (m *MockInterface) GetRingSizes(pciAddress string) (*host.RingSizes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRingSizes", pciAddress)
+	ret0, _ := ret[0].(*host.RingSizes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRingSizes indicates an expected call of GetRingSizes.
+func (mr *MockInterfaceMockRecorder) GetRingSizes(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRingSizes", reflect.TypeOf((*MockInterface)(nil).GetRingSizes), pciAddress)
+}
+
+// GetSriovVFCounts mocks base method.
+func (m *MockInterface) GetSriovVFCounts(pfPciAddress string) (int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSriovVFCounts", pfPciAddress)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetSriovVFCounts indicates an expected call of GetSriovVFCounts.
+func (mr *MockInterfaceMockRecorder) GetSriovVFCounts(pfPciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSriovVFCounts", reflect.TypeOf((*MockInterface)(nil).GetSriovVFCounts), pfPciAddress)
+}
+
+// GetUIODeviceFile mocks base method.
+func (m *MockInterface) GetUIODeviceFile(pciAddress string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUIODeviceFile", pciAddress)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUIODeviceFile indicates an expected call of GetUIODeviceFile.
+func (mr *MockInterfaceMockRecorder) GetUIODeviceFile(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUIODeviceFile", reflect.TypeOf((*MockInterface)(nil).GetUIODeviceFile), pciAddress)
+}
+
+// GetVDPAManagementDevices mocks base method.
+func (m *MockInterface) GetVDPAManagementDevices() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVDPAManagementDevices")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVDPAManagementDevices indicates an expected call of GetVDPAManagementDevices.
+func (mr *MockInterfaceMockRecorder) GetVDPAManagementDevices() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVDPAManagementDevices", reflect.TypeOf((*MockInterface)(nil).GetVDPAManagementDevices))
+}
+
+// GetVFGUIDs mocks base method.
+func (m *MockInterface) GetVFGUIDs(pciAddress string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVFGUIDs", pciAddress)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetVFGUIDs indicates an expected call of GetVFGUIDs.
+func (mr *MockInterfaceMockRecorder) GetVFGUIDs(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFGUIDs", reflect.TypeOf((*MockInterface)(nil).GetVFGUIDs), pciAddress)
+}
+
 // GetVFIODeviceFile mocks base method.
 func (m *MockInterface) GetVFIODeviceFile(pciAddress string) (string, string, error) {
 	m.ctrl.T.Helper()
@@ -247,6 +588,51 @@ func (mr *MockInterfaceMockRecorder) GetVFList(pfPciAddress any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFList", reflect.TypeOf((*MockInterface)(nil).GetVFList), pfPciAddress)
 }
 
+// GetVFRepresentor mocks base method.
+func (m *MockInterface) GetVFRepresentor(pciAddress string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVFRepresentor", pciAddress)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVFRepresentor indicates an expected call of GetVFRepresentor.
+func (mr *MockInterfaceMockRecorder) GetVFRepresentor(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFRepresentor", reflect.TypeOf((*MockInterface)(nil).GetVFRepresentor), pciAddress)
+}
+
+// GetVFSettings mocks base method.
+func (m *MockInterface) GetVFSettings(pciAddress string) (*host.VFSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVFSettings", pciAddress)
+	ret0, _ := ret[0].(*host.VFSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVFSettings indicates an expected call of GetVFSettings.
+func (mr *MockInterfaceMockRecorder) GetVFSettings(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVFSettings", reflect.TypeOf((*MockInterface)(nil).GetVFSettings), pciAddress)
+}
+
+// GetVPD mocks base method.
+func (m *MockInterface) GetVPD(pciAddress string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVPD", pciAddress)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVPD indicates an expected call of GetVPD.
+func (mr *MockInterfaceMockRecorder) GetVPD(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVPD", reflect.TypeOf((*MockInterface)(nil).GetVPD), pciAddress)
+}
+
 // IsDpdkDriver mocks base method.
 func (m *MockInterface) IsDpdkDriver(driver string) bool {
 	m.ctrl.T.Helper()
@@ -303,6 +689,20 @@ func (mr *MockInterfaceMockRecorder) IsSriovVF(pciAddress any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSriovVF", reflect.TypeOf((*MockInterface)(nil).IsSriovVF), pciAddress)
 }
 
+// IsSwitchdevCapable mocks base method.
+func (m *MockInterface) IsSwitchdevCapable(pfPciAddress string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSwitchdevCapable", pfPciAddress)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsSwitchdevCapable indicates an expected call of IsSwitchdevCapable.
+func (mr *MockInterfaceMockRecorder) IsSwitchdevCapable(pfPciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSwitchdevCapable", reflect.TypeOf((*MockInterface)(nil).IsSwitchdevCapable), pfPciAddress)
+}
+
 // LoadKernelModule mocks base method.
 func (m *MockInterface) LoadKernelModule(moduleName string) error {
 	m.ctrl.T.Helper()
@@ -317,6 +717,34 @@ func (mr *MockInterfaceMockRecorder) LoadKernelModule(moduleName any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadKernelModule", reflect.TypeOf((*MockInterface)(nil).LoadKernelModule), moduleName)
 }
 
+// MoveRDMADeviceFromNetns mocks base method.
+func (m *MockInterface) MoveRDMADeviceFromNetns(rdmaDeviceName, netnsPath string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveRDMADeviceFromNetns", rdmaDeviceName, netnsPath)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveRDMADeviceFromNetns indicates an expected call of MoveRDMADeviceFromNetns.
+func (mr *MockInterfaceMockRecorder) MoveRDMADeviceFromNetns(rdmaDeviceName, netnsPath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveRDMADeviceFromNetns", reflect.TypeOf((*MockInterface)(nil).MoveRDMADeviceFromNetns), rdmaDeviceName, netnsPath)
+}
+
+// MoveRDMADeviceToNetns mocks base method.
+func (m *MockInterface) MoveRDMADeviceToNetns(rdmaDeviceName, netnsPath string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveRDMADeviceToNetns", rdmaDeviceName, netnsPath)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveRDMADeviceToNetns indicates an expected call of MoveRDMADeviceToNetns.
+func (mr *MockInterfaceMockRecorder) MoveRDMADeviceToNetns(rdmaDeviceName, netnsPath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveRDMADeviceToNetns", reflect.TypeOf((*MockInterface)(nil).MoveRDMADeviceToNetns), rdmaDeviceName, netnsPath)
+}
+
 // PCI mocks base method.
 func (m *MockInterface) PCI() (*ghw.PCIInfo, error) {
 	m.ctrl.T.Helper()
@@ -332,6 +760,20 @@ func (mr *MockInterfaceMockRecorder) PCI() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PCI", reflect.TypeOf((*MockInterface)(nil).PCI))
 }
 
+// ResetDevice mocks base method.
+func (m *MockInterface) ResetDevice(pciAddress string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetDevice", pciAddress)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetDevice indicates an expected call of ResetDevice.
+func (mr *MockInterfaceMockRecorder) ResetDevice(pciAddress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetDevice", reflect.TypeOf((*MockInterface)(nil).ResetDevice), pciAddress)
+}
+
 // RestoreDeviceDriver mocks base method.
 func (m *MockInterface) RestoreDeviceDriver(pciAddress, originalDriver string) error {
 	m.ctrl.T.Helper()
@@ -346,6 +788,90 @@ func (mr *MockInterfaceMockRecorder) RestoreDeviceDriver(pciAddress, originalDri
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreDeviceDriver", reflect.TypeOf((*MockInterface)(nil).RestoreDeviceDriver), pciAddress, originalDriver)
 }
 
+// SetCombinedChannels mocks base method.
+func (m *MockInterface) SetCombinedChannels(pciAddress string, count int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCombinedChannels", pciAddress, count)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCombinedChannels indicates an expected call of SetCombinedChannels.
+func (mr *MockInterfaceMockRecorder) SetCombinedChannels(pciAddress, count any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCombinedChannels", reflect.TypeOf((*MockInterface)(nil).SetCombinedChannels), pciAddress, count)
+}
+
+// SetFeatures mocks base method.
+func (m *MockInterface) SetFeatures(pciAddress string, features map[string]bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFeatures", pciAddress, features)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFeatures indicates an expected call of SetFeatures.
+func (mr *MockInterfaceMockRecorder) SetFeatures(pciAddress, features any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFeatures", reflect.TypeOf((*MockInterface)(nil).SetFeatures), pciAddress, features)
+}
+
+// SetNetnsSysctls mocks base method.
+func (m *MockInterface) SetNetnsSysctls(netnsPath string, sysctls map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNetnsSysctls", netnsPath, sysctls)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNetnsSysctls indicates an expected call of SetNetnsSysctls.
+func (mr *MockInterfaceMockRecorder) SetNetnsSysctls(netnsPath, sysctls any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNetnsSysctls", reflect.TypeOf((*MockInterface)(nil).SetNetnsSysctls), netnsPath, sysctls)
+}
+
+// SetRingSizes mocks base method.
+func (m *MockInterface) SetRingSizes(pciAddress string, sizes *host.RingSizes) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRingSizes", pciAddress, sizes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRingSizes indicates an expected call of SetRingSizes.
+func (mr *MockInterfaceMockRecorder) SetRingSizes(pciAddress, sizes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRingSizes", reflect.TypeOf((*MockInterface)(nil).SetRingSizes), pciAddress, sizes)
+}
+
+// SetVFGUIDs mocks base method.
+func (m *MockInterface) SetVFGUIDs(pciAddress, nodeGUID, portGUID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVFGUIDs", pciAddress, nodeGUID, portGUID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVFGUIDs indicates an expected call of SetVFGUIDs.
+func (mr *MockInterfaceMockRecorder) SetVFGUIDs(pciAddress, nodeGUID, portGUID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVFGUIDs", reflect.TypeOf((*MockInterface)(nil).SetVFGUIDs), pciAddress, nodeGUID, portGUID)
+}
+
+// SetVFSettings mocks base method.
+func (m *MockInterface) SetVFSettings(pciAddress string, settings *host.VFSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVFSettings", pciAddress, settings)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVFSettings indicates an expected call of SetVFSettings.
+func (mr *MockInterfaceMockRecorder) SetVFSettings(pciAddress, settings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVFSettings", reflect.TypeOf((*MockInterface)(nil).SetVFSettings), pciAddress, settings)
+}
+
 // TryGetInterfaceName mocks base method.
 func (m *MockInterface) TryGetInterfaceName(pciAddr string) string {
 	m.ctrl.T.Helper()
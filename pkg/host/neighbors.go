@@ -0,0 +1,48 @@
+package host
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Neighbor is a permanent neighbor entry of a network interface, e.g. a static ARP entry.
+type Neighbor struct {
+	// IP is the address of the neighbor.
+	IP net.IP
+	// MAC is the hardware address of the neighbor.
+	MAC net.HardwareAddr
+}
+
+// AddNetnsNeighbors adds permanent neighbor entries to a network interface of a network namespace,
+// e.g. the one of a pod, replacing the entries of the same addresses, so adding them again is
+// harmless.
+func (h *Host) AddNetnsNeighbors(netnsPath, ifName string, neighbors []Neighbor) error {
+	if len(neighbors) == 0 {
+		return nil
+	}
+	link, err := h.netlinkProvider.LinkByNameAt(netnsPath, ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s in network namespace %s: %w", ifName, netnsPath, err)
+	}
+	for _, neighbor := range neighbors {
+		family := netlink.FAMILY_V6
+		if neighbor.IP.To4() != nil {
+			family = netlink.FAMILY_V4
+		}
+		neigh := &netlink.Neigh{
+			LinkIndex:    link.Attrs().Index,
+			Family:       family,
+			State:        netlink.NUD_PERMANENT,
+			IP:           neighbor.IP,
+			HardwareAddr: neighbor.MAC,
+		}
+		if err := h.netlinkProvider.NeighSetAt(netnsPath, neigh); err != nil {
+			return fmt.Errorf("failed to add neighbor %s of %s on %s in network namespace %s: %w", neighbor.MAC, neighbor.IP, ifName, netnsPath, err)
+		}
+		h.log.V(2).Info("AddNetnsNeighbors(): added neighbor", "netns", netnsPath, "ifName", ifName,
+			"ip", neighbor.IP.String(), "mac", neighbor.MAC.String())
+	}
+	return nil
+}
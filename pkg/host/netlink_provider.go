@@ -0,0 +1,264 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// ErrRdmaLinkNotFound is returned when moving an RDMA device that is not in the source network
+// namespace.
+var ErrRdmaLinkNotFound = errors.New("rdma link not found")
+
+// NetlinkProvider is a wrapper interface over the netlink library
+// This allows for easy mocking in unit tests
+//
+//go:generate mockgen -destination mock/mock_netlink_provider.go -source netlink_provider.go
+type NetlinkProvider interface {
+	LinkByName(name string) (netlink.Link, error)
+	LinkByNameAt(netnsPath, name string) (netlink.Link, error)
+	LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error
+	LinkSetVfVlanQos(link netlink.Link, vf, vlan, qos int) error
+	LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error
+	LinkSetVfTrust(link netlink.Link, vf int, state bool) error
+	LinkSetVfRate(link netlink.Link, vf, minRate, maxRate int) error
+	LinkSetVfNodeGUID(link netlink.Link, vf int, nodeGUID net.HardwareAddr) error
+	LinkSetVfPortGUID(link netlink.Link, vf int, portGUID net.HardwareAddr) error
+	RdmaSystemGetNetnsMode() (string, error)
+	RdmaLinkSetNetns(name, fromNetnsPath, toNetnsPath string) error
+	DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error)
+	VDPAGetMGMTDevList() ([]*netlink.VDPAMGMTDev, error)
+	LinkList() ([]netlink.Link, error)
+	RouteList(link netlink.Link, family int) ([]netlink.Route, error)
+	RouteReplaceAt(netnsPath string, route *netlink.Route) error
+	AddrList(link netlink.Link, family int) ([]netlink.Addr, error)
+	NeighSetAt(netnsPath string, neigh *netlink.Neigh) error
+	QdiscReplace(qdisc netlink.Qdisc) error
+	QdiscDel(qdisc netlink.Qdisc) error
+}
+
+type defaultNetlinkProvider struct{}
+
+// LinkByName returns the link with the given name
+func (defaultNetlinkProvider) LinkByName(name string) (netlink.Link, error) {
+	return netlink.LinkByName(name)
+}
+
+// LinkByNameAt returns the link with the given name in a network namespace, the one of the
+// driver when the path is empty
+func (defaultNetlinkProvider) LinkByNameAt(netnsPath, name string) (netlink.Link, error) {
+	ns, err := getNetns(netnsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create netlink handle in network namespace %q: %w", netnsPath, err)
+	}
+	defer handle.Close()
+	return handle.LinkByName(name)
+}
+
+// LinkSetVfHardwareAddr sets the MAC address of a VF
+func (defaultNetlinkProvider) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
+	return netlink.LinkSetVfHardwareAddr(link, vf, hwaddr)
+}
+
+// LinkSetVfVlanQos sets the VLAN and QoS of a VF
+func (defaultNetlinkProvider) LinkSetVfVlanQos(link netlink.Link, vf, vlan, qos int) error {
+	return netlink.LinkSetVfVlanQos(link, vf, vlan, qos)
+}
+
+// LinkSetVfSpoofchk enables or disables the spoof checking of a VF
+func (defaultNetlinkProvider) LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error {
+	return netlink.LinkSetVfSpoofchk(link, vf, check)
+}
+
+// LinkSetVfTrust enables or disables the trust mode of a VF
+func (defaultNetlinkProvider) LinkSetVfTrust(link netlink.Link, vf int, state bool) error {
+	return netlink.LinkSetVfTrust(link, vf, state)
+}
+
+// LinkSetVfRate sets the minimum and maximum transmit rates of a VF
+func (defaultNetlinkProvider) LinkSetVfRate(link netlink.Link, vf, minRate, maxRate int) error {
+	return netlink.LinkSetVfRate(link, vf, minRate, maxRate)
+}
+
+// LinkSetVfNodeGUID sets the node GUID of an InfiniBand VF of the link
+func (defaultNetlinkProvider) LinkSetVfNodeGUID(link netlink.Link, vf int, nodeGUID net.HardwareAddr) error {
+	return netlink.LinkSetVfNodeGUID(link, vf, nodeGUID)
+}
+
+// LinkSetVfPortGUID sets the port GUID of an InfiniBand VF of the link
+func (defaultNetlinkProvider) LinkSetVfPortGUID(link netlink.Link, vf int, portGUID net.HardwareAddr) error {
+	return netlink.LinkSetVfPortGUID(link, vf, portGUID)
+}
+
+// RdmaSystemGetNetnsMode returns the RDMA network namespace mode of the kernel, shared or exclusive
+func (defaultNetlinkProvider) RdmaSystemGetNetnsMode() (string, error) {
+	return netlink.RdmaSystemGetNetnsMode()
+}
+
+// RdmaLinkSetNetns moves an RDMA device between network namespaces, an empty path being the
+// network namespace of the driver
+func (defaultNetlinkProvider) RdmaLinkSetNetns(name, fromNetnsPath, toNetnsPath string) error {
+	from, err := getNetns(fromNetnsPath)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+	to, err := getNetns(toNetnsPath)
+	if err != nil {
+		return err
+	}
+	defer to.Close()
+
+	handle, err := netlink.NewHandleAt(from)
+	if err != nil {
+		return fmt.Errorf("failed to create netlink handle in network namespace %q: %w", fromNetnsPath, err)
+	}
+	defer handle.Close()
+	link, err := handle.RdmaLinkByName(name)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrRdmaLinkNotFound, name, err)
+	}
+	return handle.RdmaLinkSetNsFd(link, uint32(to)) // #nosec G115 -- file descriptors are not negative
+}
+
+// DevLinkGetDeviceByName returns a devlink device with its eswitch attributes
+func (defaultNetlinkProvider) DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error) {
+	return netlink.DevLinkGetDeviceByName(bus, device)
+}
+
+// VDPAGetMGMTDevList returns the vDPA management devices
+func (defaultNetlinkProvider) VDPAGetMGMTDevList() ([]*netlink.VDPAMGMTDev, error) {
+	return netlink.VDPAGetMGMTDevList()
+}
+
+// LinkList returns the links of the network namespace of the driver
+func (defaultNetlinkProvider) LinkList() ([]netlink.Link, error) {
+	return netlink.LinkList()
+}
+
+// RouteList returns the routes of the main table, of all the links when link is nil
+func (defaultNetlinkProvider) RouteList(link netlink.Link, family int) ([]netlink.Route, error) {
+	return netlink.RouteList(link, family)
+}
+
+// RouteReplaceAt adds a route in a network namespace, replacing the route to the same destination
+// with the same priority, the network namespace of the driver when the path is empty
+func (defaultNetlinkProvider) RouteReplaceAt(netnsPath string, route *netlink.Route) error {
+	ns, err := getNetns(netnsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return fmt.Errorf("failed to create netlink handle in network namespace %q: %w", netnsPath, err)
+	}
+	defer handle.Close()
+	return handle.RouteReplace(route)
+}
+
+// AddrList returns the addresses of a link, of all the links when link is nil
+func (defaultNetlinkProvider) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(link, family)
+}
+
+// NeighSetAt adds a neighbor entry in a network namespace, replacing the entry of the same address,
+// the network namespace of the driver when the path is empty
+func (defaultNetlinkProvider) NeighSetAt(netnsPath string, neigh *netlink.Neigh) error {
+	ns, err := getNetns(netnsPath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return fmt.Errorf("failed to create netlink handle in network namespace %q: %w", netnsPath, err)
+	}
+	defer handle.Close()
+	return handle.NeighSet(neigh)
+}
+
+// getNetns opens a network namespace, the one of the driver when the path is empty
+func getNetns(path string) (netns.NsHandle, error) {
+	if path == "" {
+		path = "/proc/self/ns/net"
+	}
+	handle, err := netns.GetFromPath(path)
+	if err != nil {
+		return netns.None(), fmt.Errorf("failed to open network namespace %q: %w", path, err)
+	}
+	return handle, nil
+}
+
+// doInNetns runs fn on a thread in a network namespace, for the operations acting on the network
+// namespace of the calling thread, e.g. opening sockets or files under /proc/sys/net.
+func doInNetns(path string, fn func() error) error {
+	target, err := getNetns(path)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	runtime.LockOSThread()
+	origin, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to get current network namespace: %w", err)
+	}
+	defer origin.Close()
+	if err := netns.Set(target); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter network namespace %q: %w", path, err)
+	}
+	fnErr := fn()
+	if err := netns.Set(origin); err != nil {
+		// the thread is left locked so it exits with the goroutine instead of running others in
+		// the network namespace of the pod
+		return fmt.Errorf("failed to restore network namespace: %w", err)
+	}
+	runtime.UnlockOSThread()
+	return fnErr
+}
+
+// newNetlinkProvider creates a new default netlink provider
+func newNetlinkProvider() NetlinkProvider {
+	return &defaultNetlinkProvider{}
+}
+
+// QdiscReplace adds a qdisc or replaces the one with the same parent
+func (defaultNetlinkProvider) QdiscReplace(qdisc netlink.Qdisc) error {
+	return netlink.QdiscReplace(qdisc)
+}
+
+// QdiscDel deletes a qdisc
+func (defaultNetlinkProvider) QdiscDel(qdisc netlink.Qdisc) error {
+	return netlink.QdiscDel(qdisc)
+}
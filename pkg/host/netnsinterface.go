@@ -0,0 +1,62 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// NetnsInterface describes a network interface as seen from its network namespace.
+type NetnsInterface struct {
+	// MAC is the hardware address of the interface.
+	MAC string
+	// PciAddress is the PCI address of the device backing the interface, as reported by ethtool
+	// GDRVINFO, empty when the driver does not report a PCI bus.
+	PciAddress string
+}
+
+// GetNetnsInterface returns the hardware address of a network interface in a network namespace,
+// e.g. the one of a pod, and the PCI address of the device backing it.
+func (h *Host) GetNetnsInterface(netnsPath, ifName string) (*NetnsInterface, error) {
+	link, err := h.netlinkProvider.LinkByNameAt(netnsPath, ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link %s in network namespace %s: %w", ifName, netnsPath, err)
+	}
+	iface := &NetnsInterface{MAC: link.Attrs().HardwareAddr.String()}
+
+	drvinfo, err := ethtoolDrvinfo(netnsPath, ifName)
+	if err != nil {
+		// virtual interfaces, e.g. a bond, do not implement GDRVINFO
+		h.log.V(2).Info("GetNetnsInterface(): failed to get driver info, PCI address unknown",
+			"ifName", ifName, "netns", netnsPath, "error", err.Error())
+		return iface, nil
+	}
+	busInfo := unix.ByteSliceToString(drvinfo.Bus_info[:])
+	if isPciAddress(busInfo) {
+		iface.PciAddress = busInfo
+	}
+	return iface, nil
+}
+
+// isPciAddress reports whether an ethtool bus info is a PCI address, e.g. 0000:3b:02.1, rather
+// than the bus of a virtual device.
+func isPciAddress(busInfo string) bool {
+	return strings.Count(busInfo, ":") == 2 && strings.Contains(busInfo, ".")
+}
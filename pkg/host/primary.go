@@ -0,0 +1,88 @@
+package host
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/vishvananda/netlink"
+)
+
+// GetPrimaryInterfaces returns the network interfaces carrying the primary networking of the node:
+// the interfaces of the default routes and the ones holding one of the node IPs, along with the
+// interfaces below them, e.g. the ports of a bond or a bridge and the parent of a VLAN.
+func (h *Host) GetPrimaryInterfaces(nodeIPs []string) ([]string, error) {
+	links, err := h.netlinkProvider.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	primary := map[int]bool{}
+
+	routes, err := h.netlinkProvider.RouteList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+	for _, route := range routes {
+		if !isDefaultRoute(route) {
+			continue
+		}
+		if route.LinkIndex > 0 {
+			primary[route.LinkIndex] = true
+		}
+		for _, nextHop := range route.MultiPath {
+			primary[nextHop.LinkIndex] = true
+		}
+	}
+
+	if len(nodeIPs) > 0 {
+		addrs, err := h.netlinkProvider.AddrList(nil, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses: %w", err)
+		}
+		for _, nodeIP := range nodeIPs {
+			ip := net.ParseIP(nodeIP)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid node IP %q", nodeIP)
+			}
+			for _, addr := range addrs {
+				if addr.IP.Equal(ip) {
+					primary[addr.LinkIndex] = true
+				}
+			}
+		}
+	}
+
+	// walk down the stacked devices until no new interface is found
+	for changed := true; changed; {
+		changed = false
+		for _, link := range links {
+			attrs := link.Attrs()
+			if primary[attrs.Index] {
+				continue
+			}
+			if primary[attrs.MasterIndex] || primary[attrs.ParentIndex] {
+				primary[attrs.Index] = true
+				changed = true
+			}
+		}
+	}
+
+	var names []string
+	for _, link := range links {
+		if primary[link.Attrs().Index] {
+			names = append(names, link.Attrs().Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// isDefaultRoute reports whether a route of the main table is a default route, which netlink
+// reports without destination or with a zero-length prefix.
+func isDefaultRoute(route netlink.Route) bool {
+	if route.Dst == nil {
+		return true
+	}
+	ones, _ := route.Dst.Mask.Size()
+	return ones == 0
+}
@@ -0,0 +1,199 @@
+package host
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ethtoolChannels is struct ethtool_channels, the maximum and current channel counts of an
+// interface.
+type ethtoolChannels struct {
+	cmd           uint32
+	maxRx         uint32
+	maxTx         uint32
+	maxOther      uint32
+	maxCombined   uint32
+	rxCount       uint32
+	txCount       uint32
+	otherCount    uint32
+	combinedCount uint32
+}
+
+// ethtoolGetChannels runs the ethtool GCHANNELS command on an interface, returning its current
+// and maximum counts of combined channels, replaced in tests.
+var ethtoolGetChannels = func(ifName string) (combined, maxCombined uint32, err error) {
+	fd, err := ethtoolSocketAt("")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unix.Close(fd)
+
+	channels := ethtoolChannels{cmd: unix.ETHTOOL_GCHANNELS}
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&channels)); err != nil {
+		return 0, 0, err
+	}
+	return channels.combinedCount, channels.maxCombined, nil
+}
+
+// ethtoolSetCombinedChannels runs the ethtool SCHANNELS command setting the count of combined
+// channels of an interface and keeping its other channels, replaced in tests.
+var ethtoolSetCombinedChannels = func(ifName string, combined uint32) error {
+	fd, err := ethtoolSocketAt("")
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	channels := ethtoolChannels{cmd: unix.ETHTOOL_GCHANNELS}
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&channels)); err != nil {
+		return err
+	}
+	channels.cmd = unix.ETHTOOL_SCHANNELS
+	channels.combinedCount = combined
+	return ethtoolIoctl(fd, ifName, unsafe.Pointer(&channels))
+}
+
+// GetCombinedChannels returns the count of combined channels, the RX and TX queue pairs, of the
+// network interface of a device, as reported by ethtool -l.
+func (h *Host) GetCombinedChannels(pciAddress string) (int, error) {
+	ifName := h.TryGetInterfaceName(pciAddress)
+	if ifName == "" {
+		return 0, fmt.Errorf("no network interface found for device %s", pciAddress)
+	}
+	combined, _, err := ethtoolGetChannels(ifName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get channels of interface %s: %w", ifName, err)
+	}
+	return int(combined), nil
+}
+
+// SetCombinedChannels sets the count of combined channels of the network interface of a device,
+// like ethtool -L combined. The driver spreads the received traffic over the queues with RSS.
+func (h *Host) SetCombinedChannels(pciAddress string, count int) error {
+	ifName := h.TryGetInterfaceName(pciAddress)
+	if ifName == "" {
+		return fmt.Errorf("no network interface found for device %s", pciAddress)
+	}
+	combined, maxCombined, err := ethtoolGetChannels(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get channels of interface %s: %w", ifName, err)
+	}
+	if count < 1 || count > int(maxCombined) {
+		return fmt.Errorf("interface %s supports 1 to %d combined channels, %d requested", ifName, maxCombined, count)
+	}
+	if count == int(combined) {
+		return nil
+	}
+	h.log.V(2).Info("SetCombinedChannels(): set combined channels", "device", pciAddress, "interface", ifName, "count", count, "previous", combined)
+	if err := ethtoolSetCombinedChannels(ifName, uint32(count)); err != nil { // #nosec G115 -- count is within maxCombined
+		return fmt.Errorf("failed to set %d combined channels on interface %s: %w", count, ifName, err)
+	}
+	return nil
+}
+
+// ethtoolRingparam is struct ethtool_ringparam, the maximum and current sizes of the rings of an
+// interface.
+type ethtoolRingparam struct {
+	cmd               uint32
+	rxMaxPending      uint32
+	rxMiniMaxPending  uint32
+	rxJumboMaxPending uint32
+	txMaxPending      uint32
+	rxPending         uint32
+	rxMiniPending     uint32
+	rxJumboPending    uint32
+	txPending         uint32
+}
+
+// RingSizes are the sizes of the RX and TX rings of an interface, in descriptors.
+type RingSizes struct {
+	RX int
+	TX int
+}
+
+// ethtoolGetRingSizes runs the ethtool GRINGPARAM command on an interface, returning the current
+// and maximum sizes of its rings, replaced in tests.
+var ethtoolGetRingSizes = func(ifName string) (current, maximum RingSizes, err error) {
+	fd, err := ethtoolSocketAt("")
+	if err != nil {
+		return RingSizes{}, RingSizes{}, err
+	}
+	defer unix.Close(fd)
+
+	ringparam := ethtoolRingparam{cmd: unix.ETHTOOL_GRINGPARAM}
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&ringparam)); err != nil {
+		return RingSizes{}, RingSizes{}, err
+	}
+	return RingSizes{RX: int(ringparam.rxPending), TX: int(ringparam.txPending)},
+		RingSizes{RX: int(ringparam.rxMaxPending), TX: int(ringparam.txMaxPending)}, nil
+}
+
+// ethtoolSetRingSizes runs the ethtool SRINGPARAM command setting the sizes of the RX and TX rings
+// of an interface and keeping its other rings, replaced in tests.
+var ethtoolSetRingSizes = func(ifName string, sizes RingSizes) error {
+	fd, err := ethtoolSocketAt("")
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ringparam := ethtoolRingparam{cmd: unix.ETHTOOL_GRINGPARAM}
+	if err := ethtoolIoctl(fd, ifName, unsafe.Pointer(&ringparam)); err != nil {
+		return err
+	}
+	ringparam.cmd = unix.ETHTOOL_SRINGPARAM
+	ringparam.rxPending = uint32(sizes.RX) // #nosec G115 -- sizes are within the maximum of the interface
+	ringparam.txPending = uint32(sizes.TX) // #nosec G115 -- sizes are within the maximum of the interface
+	return ethtoolIoctl(fd, ifName, unsafe.Pointer(&ringparam))
+}
+
+// GetRingSizes returns the sizes of the RX and TX rings of the network interface of a device, as
+// reported by ethtool -g.
+func (h *Host) GetRingSizes(pciAddress string) (*RingSizes, error) {
+	ifName := h.TryGetInterfaceName(pciAddress)
+	if ifName == "" {
+		return nil, fmt.Errorf("no network interface found for device %s", pciAddress)
+	}
+	current, _, err := ethtoolGetRingSizes(ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ring sizes of interface %s: %w", ifName, err)
+	}
+	return &current, nil
+}
+
+// SetRingSizes sets the sizes of the RX and TX rings of the network interface of a device, like
+// ethtool -G rx tx. A size of 0 keeps the current size of the ring.
+func (h *Host) SetRingSizes(pciAddress string, sizes *RingSizes) error {
+	ifName := h.TryGetInterfaceName(pciAddress)
+	if ifName == "" {
+		return fmt.Errorf("no network interface found for device %s", pciAddress)
+	}
+	current, maximum, err := ethtoolGetRingSizes(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get ring sizes of interface %s: %w", ifName, err)
+	}
+	target := current
+	if sizes.RX != 0 {
+		if sizes.RX < 0 || sizes.RX > maximum.RX {
+			return fmt.Errorf("interface %s supports RX rings of 1 to %d descriptors, %d requested", ifName, maximum.RX, sizes.RX)
+		}
+		target.RX = sizes.RX
+	}
+	if sizes.TX != 0 {
+		if sizes.TX < 0 || sizes.TX > maximum.TX {
+			return fmt.Errorf("interface %s supports TX rings of 1 to %d descriptors, %d requested", ifName, maximum.TX, sizes.TX)
+		}
+		target.TX = sizes.TX
+	}
+	if target == current {
+		return nil
+	}
+	h.log.V(2).Info("SetRingSizes(): set ring sizes", "device", pciAddress, "interface", ifName, "rx", target.RX, "tx", target.TX,
+		"previousRX", current.RX, "previousTX", current.TX)
+	if err := ethtoolSetRingSizes(ifName, target); err != nil {
+		return fmt.Errorf("failed to set ring sizes of interface %s: %w", ifName, err)
+	}
+	return nil
+}
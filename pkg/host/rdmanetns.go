@@ -0,0 +1,55 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RDMA network namespace modes of the kernel, see rdma-system(8)
+const (
+	// RDMANetnsModeShared makes the RDMA devices visible in every network namespace
+	RDMANetnsModeShared = "shared"
+	// RDMANetnsModeExclusive binds every RDMA device to a single network namespace
+	RDMANetnsModeExclusive = "exclusive"
+)
+
+// GetRDMANetnsMode returns the RDMA network namespace mode of the kernel.
+func (h *Host) GetRDMANetnsMode() (string, error) {
+	mode, err := h.netlinkProvider.RdmaSystemGetNetnsMode()
+	if err != nil {
+		return "", fmt.Errorf("failed to get RDMA netns mode: %w", err)
+	}
+	return mode, nil
+}
+
+// MoveRDMADeviceToNetns moves an RDMA device from the network namespace of the driver to the
+// network namespace of a pod. A device already moved, e.g. by the rdma CNI plugin, is left alone.
+func (h *Host) MoveRDMADeviceToNetns(rdmaDeviceName, netnsPath string) error {
+	err := h.netlinkProvider.RdmaLinkSetNetns(rdmaDeviceName, "", netnsPath)
+	if errors.Is(err, ErrRdmaLinkNotFound) {
+		h.log.Info("MoveRDMADeviceToNetns(): RDMA device is not in the driver network namespace, skipping it",
+			"rdmaDevice", rdmaDeviceName, "netns", netnsPath, "error", err.Error())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move RDMA device %s to network namespace %s: %w", rdmaDeviceName, netnsPath, err)
+	}
+	h.log.Info("MoveRDMADeviceToNetns(): moved RDMA device", "rdmaDevice", rdmaDeviceName, "netns", netnsPath)
+	return nil
+}
+
+// MoveRDMADeviceFromNetns moves an RDMA device from the network namespace of a pod back to the
+// network namespace of the driver. A device no longer in the pod network namespace is left alone.
+func (h *Host) MoveRDMADeviceFromNetns(rdmaDeviceName, netnsPath string) error {
+	err := h.netlinkProvider.RdmaLinkSetNetns(rdmaDeviceName, netnsPath, "")
+	if errors.Is(err, ErrRdmaLinkNotFound) {
+		h.log.Info("MoveRDMADeviceFromNetns(): RDMA device is not in the pod network namespace, skipping it",
+			"rdmaDevice", rdmaDeviceName, "netns", netnsPath, "error", err.Error())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move RDMA device %s back from network namespace %s: %w", rdmaDeviceName, netnsPath, err)
+	}
+	h.log.Info("MoveRDMADeviceFromNetns(): moved RDMA device back", "rdmaDevice", rdmaDeviceName, "netns", netnsPath)
+	return nil
+}
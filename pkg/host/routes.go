@@ -0,0 +1,48 @@
+package host
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// Route is a static route through a network interface.
+type Route struct {
+	// Dst is the destination of the route.
+	Dst *net.IPNet
+	// GW is the gateway of the route, nil for a destination reachable on the link.
+	GW net.IP
+	// Metric is the priority of the route, lower first, the default of the kernel when 0.
+	Metric int
+}
+
+// AddNetnsRoutes adds static routes through a network interface of a network namespace, e.g. the
+// one of a pod, replacing the routes to the same destinations and with the same metrics, so adding
+// them again is harmless.
+func (h *Host) AddNetnsRoutes(netnsPath, ifName string, routes []Route) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	link, err := h.netlinkProvider.LinkByNameAt(netnsPath, ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s in network namespace %s: %w", ifName, netnsPath, err)
+	}
+	for _, route := range routes {
+		nlRoute := &netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       route.Dst,
+			Gw:        route.GW,
+			Priority:  route.Metric,
+		}
+		if route.GW == nil {
+			nlRoute.Scope = netlink.SCOPE_LINK
+		}
+		if err := h.netlinkProvider.RouteReplaceAt(netnsPath, nlRoute); err != nil {
+			return fmt.Errorf("failed to add route to %s via %s on %s in network namespace %s: %w", route.Dst, route.GW, ifName, netnsPath, err)
+		}
+		h.log.V(2).Info("AddNetnsRoutes(): added route", "netns", netnsPath, "ifName", ifName,
+			"dst", route.Dst.String(), "gw", route.GW.String(), "metric", route.Metric)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package host
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// writeNetnsSysctls writes sysctls, keyed by their path under /proc/sys, from a network namespace,
+// replaced in tests.
+var writeNetnsSysctls = func(netnsPath string, sysctls map[string]string) error {
+	return doInNetns(netnsPath, func() error {
+		// /proc/sys/net shows the sysctls of the network namespace of the thread opening them
+		for _, path := range slices.Sorted(maps.Keys(sysctls)) {
+			if err := os.WriteFile(buildProcPath(filepath.Join("/proc/sys", path)), []byte(sysctls[path]), 0o644); err != nil {
+				return fmt.Errorf("failed to set sysctl %s: %w", path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// SetNetnsSysctls sets sysctls in a network namespace, e.g. the one of a pod, keyed by their path
+// under /proc/sys, e.g. net/ipv4/conf/net1/rp_filter. The sysctls are set in the order of their
+// paths, up to the first one failing.
+func (h *Host) SetNetnsSysctls(netnsPath string, sysctls map[string]string) error {
+	if len(sysctls) == 0 {
+		return nil
+	}
+	if err := writeNetnsSysctls(netnsPath, sysctls); err != nil {
+		return fmt.Errorf("failed to set sysctls in network namespace %s: %w", netnsPath, err)
+	}
+	h.log.V(2).Info("SetNetnsSysctls(): set sysctls", "netns", netnsPath, "sysctls", sysctls)
+	return nil
+}
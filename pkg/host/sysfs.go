@@ -0,0 +1,185 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+)
+
+var (
+	// ErrSysfsNotWritable is returned when sysfs rejects a write because of
+	// missing permissions or a read-only /sys mount.
+	ErrSysfsNotWritable = errors.New("sysfs is not writable")
+	// ErrSysfsNotFound is returned when the sysfs attribute does not exist.
+	ErrSysfsNotFound = errors.New("sysfs attribute not found")
+	// ErrSysfsBusy is returned when the kernel reports the device as busy.
+	ErrSysfsBusy = errors.New("sysfs device busy")
+	// ErrSysfsTimeout is returned when a write did not complete in time, e.g. a VF unbind stuck
+	// in the remove callback of its driver. The device must be considered unusable until the
+	// write completes.
+	ErrSysfsTimeout = errors.New("sysfs write timed out")
+)
+
+// Config configures the sysfs writes of a Host.
+type Config struct {
+	// SysfsWriteBackoff paces the retries of driver bind, unbind and probe writes failing because
+	// the device is busy, e.g. while udev or the PF driver is touching the VF. Steps is the maximum
+	// number of attempts, see --sysfs-write-retries.
+	SysfsWriteBackoff wait.Backoff
+	// UnbindTimeout bounds how long a driver unbind may take before it is reported as timed out,
+	// zero waits forever, see --unbind-timeout.
+	UnbindTimeout time.Duration
+}
+
+// DefaultConfig returns the configuration of the Host when none is given.
+func DefaultConfig() Config {
+	return Config{
+		SysfsWriteBackoff: wait.Backoff{
+			Duration: 100 * time.Millisecond,
+			Factor:   2.0,
+			Jitter:   0.1,
+			Steps:    5,
+			Cap:      2 * time.Second,
+		},
+		UnbindTimeout: 30 * time.Second,
+	}
+}
+
+// sysfsWriteFile writes a sysfs attribute, replaced in tests.
+var sysfsWriteFile = os.WriteFile
+
+// SysfsWriteError describes a failed sysfs write. It matches one of the
+// ErrSysfs* sentinels with errors.Is when the errno could be classified.
+type SysfsWriteError struct {
+	Path   string
+	Reason error
+	Err    error
+}
+
+func (e *SysfsWriteError) Error() string {
+	if e.Reason != nil {
+		return fmt.Sprintf("failed to write %s: %v: %v", e.Path, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("failed to write %s: %v", e.Path, e.Err)
+}
+
+func (e *SysfsWriteError) Unwrap() []error {
+	if e.Reason != nil {
+		return []error{e.Reason, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// classifySysfsError maps a write error to one of the ErrSysfs* sentinels, or nil if unknown.
+func classifySysfsError(err error) error {
+	switch {
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EROFS):
+		return ErrSysfsNotWritable
+	case errors.Is(err, syscall.ENOENT):
+		return ErrSysfsNotFound
+	case errors.Is(err, syscall.EBUSY), errors.Is(err, syscall.EAGAIN):
+		return ErrSysfsBusy
+	default:
+		return nil
+	}
+}
+
+// sysfsErrorReason returns the metrics label for a classified sysfs error.
+func sysfsErrorReason(reason error) string {
+	switch reason {
+	case ErrSysfsNotWritable:
+		return "not_writable"
+	case ErrSysfsNotFound:
+		return "not_found"
+	case ErrSysfsBusy:
+		return "busy"
+	case ErrSysfsTimeout:
+		return "timeout"
+	default:
+		return "other"
+	}
+}
+
+// writeSysfs writes data to a sysfs attribute and classifies failures by errno.
+func writeSysfs(path string, data []byte) error {
+	err := sysfsWriteFile(path, data, os.ModeAppend)
+	if err == nil {
+		metrics.SysfsWritable.Set(1)
+		return nil
+	}
+
+	reason := classifySysfsError(err)
+	metrics.SysfsWriteErrors.WithLabelValues(sysfsErrorReason(reason)).Inc()
+	if reason == ErrSysfsNotWritable {
+		metrics.SysfsWritable.Set(0)
+	}
+	return &SysfsWriteError{Path: path, Reason: reason, Err: err}
+}
+
+// writeSysfsWithTimeout writes data to a sysfs attribute like writeSysfs, giving up after timeout.
+// A write cannot be interrupted, so a timed out write is left running in the background.
+func writeSysfsWithTimeout(path string, data []byte, timeout time.Duration) error {
+	if timeout <= 0 {
+		return writeSysfs(path, data)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- writeSysfs(path, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		metrics.SysfsWriteErrors.WithLabelValues(sysfsErrorReason(ErrSysfsTimeout)).Inc()
+		return &SysfsWriteError{Path: path, Reason: ErrSysfsTimeout, Err: fmt.Errorf("no completion after %s", timeout)}
+	}
+}
+
+// writeSysfsWithRetry writes data to a sysfs attribute like writeSysfsWithTimeout, retrying with
+// backoff while the device is busy. The error of the last attempt is returned.
+func writeSysfsWithRetry(path string, data []byte, timeout time.Duration, backoff wait.Backoff) error {
+	var (
+		attempts int
+		writeErr error
+	)
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		attempts++
+		writeErr = writeSysfsWithTimeout(path, data, timeout)
+		if writeErr == nil {
+			return true, nil
+		}
+		if !errors.Is(writeErr, ErrSysfsBusy) {
+			return false, writeErr
+		}
+		return false, nil
+	})
+	if err == nil || writeErr == nil {
+		return err
+	}
+	if errors.Is(writeErr, ErrSysfsBusy) {
+		return fmt.Errorf("device still busy after %d attempts: %w", attempts, writeErr)
+	}
+	return writeErr
+}
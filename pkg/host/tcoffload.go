@@ -0,0 +1,137 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+)
+
+const (
+	// tcOffloadFeature is the ethtool feature offloading the TC filters of an interface to the NIC.
+	tcOffloadFeature = "hw-tc-offload"
+	// sysClassNet lists the network interfaces of the network namespace of the driver.
+	sysClassNet = "/sys/class/net"
+)
+
+var (
+	// pfPortNameRegexp matches the port name of the uplink of a PF in switchdev mode, e.g. p0.
+	pfPortNameRegexp = regexp.MustCompile(`^p(\d+)$`)
+	// vfRepresentorPortNameRegexp matches the port name of the representor of a VF, e.g. pf0vf3
+	// or c1pf0vf3, and vf3 with older kernels.
+	vfRepresentorPortNameRegexp = regexp.MustCompile(`^(?:c\d+)?(?:pf(\d+))?vf(\d+)$`)
+)
+
+// readNetAttribute returns an attribute of a network interface from sysfs, empty when it is not
+// reported.
+func readNetAttribute(ifName, attribute string) string {
+	value, err := os.ReadFile(buildSysPath(filepath.Join(sysClassNet, ifName, attribute)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(value))
+}
+
+// GetVFRepresentor returns the representor of a VF whose PF is in switchdev mode, the interface
+// sharing the switch ID of the PF whose port name designates the VF.
+func (h *Host) GetVFRepresentor(pciAddress string) (string, error) {
+	pfName, vfID, err := h.getVFIndex(pciAddress)
+	if err != nil {
+		return "", err
+	}
+	switchID := readNetAttribute(pfName, "phys_switch_id")
+	if switchID == "" {
+		return "", fmt.Errorf("PF %s of device %s is not in switchdev mode", pfName, pciAddress)
+	}
+	pfIndex := ""
+	if match := pfPortNameRegexp.FindStringSubmatch(readNetAttribute(pfName, "phys_port_name")); match != nil {
+		pfIndex = match[1]
+	}
+
+	entries, err := os.ReadDir(buildSysPath(sysClassNet))
+	if err != nil {
+		return "", fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	for _, entry := range entries {
+		ifName := entry.Name()
+		if ifName == pfName || readNetAttribute(ifName, "phys_switch_id") != switchID {
+			continue
+		}
+		match := vfRepresentorPortNameRegexp.FindStringSubmatch(readNetAttribute(ifName, "phys_port_name"))
+		if match == nil || (match[1] != "" && pfIndex != "" && match[1] != pfIndex) {
+			continue
+		}
+		if index, err := strconv.Atoi(match[2]); err == nil && index == vfID {
+			h.log.V(2).Info("GetVFRepresentor(): found representor", "device", pciAddress, "pf", pfName, "vf", vfID, "representor", ifName)
+			return ifName, nil
+		}
+	}
+	return "", fmt.Errorf("no representor found for VF %d of PF %s", vfID, pfName)
+}
+
+// representorQdisc returns the qdisc TC filters are attached to on a representor, clsact or
+// ingress.
+func (h *Host) representorQdisc(representor, qdisc string) (netlink.Qdisc, error) {
+	link, err := h.netlinkProvider.LinkByName(representor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get representor link %s: %w", representor, err)
+	}
+	attrs := netlink.QdiscAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    netlink.MakeHandle(0xffff, 0),
+		Parent:    netlink.HANDLE_INGRESS,
+	}
+	switch qdisc {
+	case configapi.TCOffloadQdiscClsact:
+		return &netlink.GenericQdisc{QdiscAttrs: attrs, QdiscType: qdisc}, nil
+	case configapi.TCOffloadQdiscIngress:
+		return &netlink.Ingress{QdiscAttrs: attrs}, nil
+	default:
+		return nil, fmt.Errorf("unsupported qdisc %q", qdisc)
+	}
+}
+
+// EnableTCOffload turns the TC hardware offload of a representor on and adds the qdisc TC filters
+// are attached to, so the flows OVS or TC offload on the representor are programmed in the
+// eswitch.
+func (h *Host) EnableTCOffload(representor, qdisc string) error {
+	if err := ethtoolSetFeature(representor, tcOffloadFeature, true); err != nil {
+		return fmt.Errorf("failed to enable %s on representor %s: %w", tcOffloadFeature, representor, err)
+	}
+	q, err := h.representorQdisc(representor, qdisc)
+	if err != nil {
+		return err
+	}
+	if err := h.netlinkProvider.QdiscReplace(q); err != nil {
+		return fmt.Errorf("failed to add %s qdisc to representor %s: %w", qdisc, representor, err)
+	}
+	h.log.V(2).Info("EnableTCOffload(): TC offload enabled", "representor", representor, "qdisc", qdisc)
+	return nil
+}
+
+// DisableTCOffload deletes the qdisc added by EnableTCOffload with the TC filters attached to it.
+// The hw-tc-offload feature is left on, other consumers of the representor, e.g. OVS, may rely on
+// it. A qdisc or a representor already gone is not an error.
+func (h *Host) DisableTCOffload(representor, qdisc string) error {
+	q, err := h.representorQdisc(representor, qdisc)
+	if err != nil {
+		var linkNotFound netlink.LinkNotFoundError
+		if errors.As(err, &linkNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := h.netlinkProvider.QdiscDel(q); err != nil && !errors.Is(err, unix.ENOENT) && !errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("failed to delete %s qdisc of representor %s: %w", qdisc, representor, err)
+	}
+	h.log.V(2).Info("DisableTCOffload(): TC offload qdisc deleted", "representor", representor, "qdisc", qdisc)
+	return nil
+}
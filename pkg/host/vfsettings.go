@@ -0,0 +1,140 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VFSettings are the administrative settings of a VF held by its PF, which a consumer of the VF,
+// e.g. sriov-cni, may change and which outlive the consumer.
+type VFSettings struct {
+	MAC       string `json:",omitempty"`
+	VLAN      int    `json:",omitempty"`
+	VLANQoS   int    `json:",omitempty"`
+	SpoofChk  bool   `json:",omitempty"`
+	Trust     bool   `json:",omitempty"`
+	MinTxRate int    `json:",omitempty"`
+	MaxTxRate int    `json:",omitempty"`
+}
+
+// getVFIndex returns the netdev name of the PF of a VF and the index of the VF on the PF.
+func (h *Host) getVFIndex(pciAddress string) (string, int, error) {
+	pfPciAddress, vfID, err := getVFID(pciAddress)
+	if err != nil {
+		return "", 0, err
+	}
+	pfName := h.TryGetInterfaceName(pfPciAddress)
+	if pfName == "" {
+		return "", 0, fmt.Errorf("PF %s of device %s has no network interface", pfPciAddress, pciAddress)
+	}
+	return pfName, vfID, nil
+}
+
+// getVFID returns the PCI address of the PF of a VF and the index of the VF on the PF.
+func getVFID(pciAddress string) (string, int, error) {
+	pfLink, err := os.Readlink(buildSysBusPciPath(pciAddress, "physfn"))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get PF of device %s: %w", pciAddress, err)
+	}
+	pfPciAddress := filepath.Base(pfLink)
+
+	entries, err := os.ReadDir(buildSysBusPciPath(pfPciAddress, ""))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read PF directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "virtfn") {
+			continue
+		}
+		target, err := os.Readlink(buildSysBusPciPath(pfPciAddress, entry.Name()))
+		if err != nil || filepath.Base(target) != pciAddress {
+			continue
+		}
+		vfID, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "virtfn"))
+		if err != nil {
+			continue
+		}
+		return pfPciAddress, vfID, nil
+	}
+	return "", 0, fmt.Errorf("device %s not found in the VFs of PF %s", pciAddress, pfPciAddress)
+}
+
+// GetVFSettings returns the administrative settings of a VF, as reported by its PF.
+func (h *Host) GetVFSettings(pciAddress string) (*VFSettings, error) {
+	pfName, vfID, err := h.getVFIndex(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	link, err := h.netlinkProvider.LinkByName(pfName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PF link %s: %w", pfName, err)
+	}
+	for _, vf := range link.Attrs().Vfs {
+		if vf.ID != vfID {
+			continue
+		}
+		settings := &VFSettings{
+			VLAN:      vf.Vlan,
+			VLANQoS:   vf.Qos,
+			SpoofChk:  vf.Spoofchk,
+			Trust:     vf.Trust != 0,
+			MinTxRate: int(vf.MinTxRate),
+			MaxTxRate: int(vf.MaxTxRate),
+		}
+		if len(vf.Mac) > 0 {
+			settings.MAC = vf.Mac.String()
+		}
+		h.log.V(2).Info("GetVFSettings(): VF settings", "device", pciAddress, "pf", pfName, "vf", vfID, "settings", settings)
+		return settings, nil
+	}
+	return nil, fmt.Errorf("VF %d not reported by PF %s", vfID, pfName)
+}
+
+// SetVFSettings applies administrative settings to a VF through its PF. All the settings are
+// attempted, settings not supported by the PF driver are reported with the others.
+func (h *Host) SetVFSettings(pciAddress string, settings *VFSettings) error {
+	pfName, vfID, err := h.getVFIndex(pciAddress)
+	if err != nil {
+		return err
+	}
+	link, err := h.netlinkProvider.LinkByName(pfName)
+	if err != nil {
+		return fmt.Errorf("failed to get PF link %s: %w", pfName, err)
+	}
+	h.log.V(2).Info("SetVFSettings(): set VF settings", "device", pciAddress, "pf", pfName, "vf", vfID, "settings", settings)
+
+	var errs []error
+	// an all-zero MAC lets the VF driver pick a random one, as when the VF was created
+	mac := net.HardwareAddr(make([]byte, 6))
+	if settings.MAC != "" {
+		if mac, err = net.ParseMAC(settings.MAC); err != nil {
+			errs = append(errs, fmt.Errorf("invalid MAC address %q: %w", settings.MAC, err))
+		}
+	}
+	if mac != nil {
+		if err := h.netlinkProvider.LinkSetVfHardwareAddr(link, vfID, mac); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set MAC address: %w", err))
+		}
+	}
+	if err := h.netlinkProvider.LinkSetVfVlanQos(link, vfID, settings.VLAN, settings.VLANQoS); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set VLAN: %w", err))
+	}
+	if err := h.netlinkProvider.LinkSetVfSpoofchk(link, vfID, settings.SpoofChk); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set spoof checking: %w", err))
+	}
+	if err := h.netlinkProvider.LinkSetVfTrust(link, vfID, settings.Trust); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set trust: %w", err))
+	}
+	if err := h.netlinkProvider.LinkSetVfRate(link, vfID, settings.MinTxRate, settings.MaxTxRate); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set rate: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to set settings of VF %d of PF %s: %w", vfID, pfName, err)
+	}
+	return nil
+}
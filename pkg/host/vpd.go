@@ -0,0 +1,131 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package host
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PCI VPD resource tags, see the PCI Local Bus Specification, Vital Product Data
+const (
+	vpdTagIdentifier = 0x82
+	vpdTagReadOnly   = 0x90
+	vpdTagReadWrite  = 0x91
+	vpdTagEnd        = 0x78
+)
+
+// VPD keywords of the part number and the serial number of the board.
+const (
+	VPDKeywordPartNumber   = "PN"
+	VPDKeywordSerialNumber = "SN"
+)
+
+// GetVPD returns the read-only keywords of the Vital Product Data of a PCI device, e.g. PN for
+// the part number or the vendor specific V0-VZ.
+func (h *Host) GetVPD(pciAddress string) (map[string]string, error) {
+	data, err := os.ReadFile(buildSysBusPciPath(pciAddress, "vpd")) /* #nosec G304 */
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VPD of device %s: %w", pciAddress, err)
+	}
+	keywords, err := parseVPD(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VPD of device %s: %w", pciAddress, err)
+	}
+	return keywords, nil
+}
+
+// parseVPD returns the keywords of the read-only section of VPD data.
+func parseVPD(data []byte) (map[string]string, error) {
+	keywords := map[string]string{}
+	for len(data) > 0 {
+		tag := data[0]
+		if tag == vpdTagEnd {
+			return keywords, nil
+		}
+		// small resources other than the end tag carry nothing of interest
+		if tag&0x80 == 0 {
+			length := int(tag & 0x07)
+			if len(data) < 1+length {
+				return nil, errors.New("truncated small resource")
+			}
+			data = data[1+length:]
+			continue
+		}
+		if len(data) < 3 {
+			return nil, errors.New("truncated large resource header")
+		}
+		length := int(binary.LittleEndian.Uint16(data[1:3]))
+		if len(data) < 3+length {
+			return nil, fmt.Errorf("truncated large resource 0x%x", tag)
+		}
+		content := data[3 : 3+length]
+		data = data[3+length:]
+
+		switch tag {
+		case vpdTagReadOnly:
+			if err := parseVPDKeywords(content, keywords); err != nil {
+				return nil, err
+			}
+		case vpdTagIdentifier, vpdTagReadWrite:
+		default:
+			return nil, fmt.Errorf("unknown resource 0x%x", tag)
+		}
+	}
+	// the kernel exposes the whole VPD area, devices without the end tag are tolerated
+	return keywords, nil
+}
+
+func parseVPDKeywords(content []byte, keywords map[string]string) error {
+	for len(content) > 0 {
+		if len(content) < 3 {
+			return errors.New("truncated keyword header")
+		}
+		keyword := string(content[0:2])
+		length := int(content[2])
+		if len(content) < 3+length {
+			return fmt.Errorf("truncated keyword %s", keyword)
+		}
+		// RV is the checksum and reserved space of the read-only section
+		if keyword != "RV" {
+			keywords[keyword] = strings.TrimRight(string(content[3:3+length]), " \x00")
+		}
+		content = content[3+length:]
+	}
+	return nil
+}
+
+// GetBoardID returns the board ID of a device with an RDMA device, the PSID of NVIDIA/Mellanox
+// NICs, e.g. MT_0000000838
+func (h *Host) GetBoardID(pciAddress string) (string, error) {
+	boardIDs, err := filepath.Glob(buildSysBusPciPath(pciAddress, "infiniband/*/board_id"))
+	if err != nil {
+		return "", err
+	}
+	if len(boardIDs) == 0 {
+		return "", fmt.Errorf("device %s has no RDMA device reporting a board ID", pciAddress)
+	}
+	boardID, err := os.ReadFile(boardIDs[0]) /* #nosec G304 */
+	if err != nil {
+		return "", fmt.Errorf("failed to read board ID of device %s: %w", pciAddress, err)
+	}
+	return strings.TrimSpace(string(boardID)), nil
+}
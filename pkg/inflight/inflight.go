@@ -0,0 +1,97 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inflight tracks the operations in progress so the driver can wait for them to
+// complete before shutting down.
+package inflight
+
+import (
+	"context"
+	"sync"
+)
+
+// Tracker counts the operations in progress. Once draining, new operations are refused by
+// Begin and Drain waits for the ones in progress to end.
+type Tracker struct {
+	mu       sync.Mutex
+	count    int
+	draining bool
+	// idle is closed once draining with no operation in progress.
+	idle chan struct{}
+}
+
+// Begin records the start of an operation. It returns false, without recording it, when the
+// tracker is draining, in which case the operation must not be started.
+func (t *Tracker) Begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.count++
+	return true
+}
+
+// Track records the start of an operation that must run even while draining, e.g. one
+// releasing resources.
+func (t *Tracker) Track() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining && t.count == 0 {
+		// idle was closed, later Drain calls must wait for this operation
+		t.idle = make(chan struct{})
+	}
+	t.count++
+}
+
+// End records the end of an operation started with Begin or Track.
+func (t *Tracker) End() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count--
+	if t.draining && t.count == 0 {
+		close(t.idle)
+	}
+}
+
+// Drain refuses new operations and waits for the ones in progress to end, or for ctx to be
+// done. It can be called several times.
+func (t *Tracker) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		t.idle = make(chan struct{})
+		if t.count == 0 {
+			close(t.idle)
+		}
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Draining reports whether Drain was called.
+func (t *Tracker) Draining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
@@ -0,0 +1,13 @@
+package inflight_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestInflight(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Inflight Suite")
+}
@@ -0,0 +1,65 @@
+package inflight_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/inflight"
+)
+
+var _ = Describe("Tracker", func() {
+	var tracker *inflight.Tracker
+
+	BeforeEach(func() {
+		tracker = &inflight.Tracker{}
+	})
+
+	It("should drain right away without operations in progress", func() {
+		Expect(tracker.Drain(context.Background())).To(Succeed())
+		Expect(tracker.Draining()).To(BeTrue())
+	})
+
+	It("should refuse new operations once draining", func() {
+		Expect(tracker.Begin()).To(BeTrue())
+		tracker.End()
+		Expect(tracker.Drain(context.Background())).To(Succeed())
+		Expect(tracker.Begin()).To(BeFalse())
+	})
+
+	It("should wait for the operations in progress", func() {
+		Expect(tracker.Begin()).To(BeTrue())
+		tracker.Track()
+
+		drained := make(chan error)
+		go func() {
+			drained <- tracker.Drain(context.Background())
+		}()
+		Consistently(drained, 100*time.Millisecond).ShouldNot(Receive())
+
+		tracker.End()
+		Consistently(drained, 100*time.Millisecond).ShouldNot(Receive())
+		tracker.End()
+		Eventually(drained).Should(Receive(BeNil()))
+	})
+
+	It("should keep tracking operations that must run while draining", func() {
+		Expect(tracker.Drain(context.Background())).To(Succeed())
+		tracker.Track()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(tracker.Drain(ctx)).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should give up when the context is done", func() {
+		Expect(tracker.Begin()).To(BeTrue())
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(tracker.Drain(ctx)).To(MatchError(context.DeadlineExceeded))
+
+		tracker.End()
+		Expect(tracker.Drain(context.Background())).To(Succeed())
+	})
+})
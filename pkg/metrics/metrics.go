@@ -0,0 +1,113 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics holds the Prometheus metrics exported by the driver.
+// All metrics are registered in the controller-runtime registry and served
+// by the controller manager's metrics endpoint.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "dra_driver_sriov"
+
+// Operations and phases of the claim duration metrics.
+const (
+	OperationPrepare   = "prepare"
+	OperationUnprepare = "unprepare"
+
+	// PhaseDriverBind is the bind of a device to the driver of its VfConfig, or its restore.
+	PhaseDriverBind = "driver_bind"
+	// PhaseCDI is the write, or the removal, of the CDI specs of a claim or a pod.
+	PhaseCDI = "cdi"
+	// PhaseCheckpoint is the sync of the prepared claims to the checkpoint.
+	PhaseCheckpoint = "checkpoint"
+)
+
+// durationBuckets cover 1ms to about 33s, the longest driver unbinds last seconds.
+var durationBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
+var (
+	// SysfsWriteErrors counts failed sysfs writes by classified reason.
+	SysfsWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sysfs_write_errors_total",
+		Help:      "Number of failed sysfs writes by reason (not_writable, not_found, busy, timeout, other).",
+	}, []string{"reason"})
+
+	// SysfsWritable reports whether the last sysfs write was rejected because /sys is not writable.
+	SysfsWritable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sysfs_writable",
+		Help:      "1 if sysfs writes are permitted, 0 if the last write failed with a permission or read-only error.",
+	})
+
+	// SriovOperatorConflicts reports the differences between the node and the spec of
+	// sriov-network-operator found at startup.
+	SriovOperatorConflicts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sriov_operator_conflicts",
+		Help:      "Number of differences between the node and the SriovNetworkNodeState of sriov-network-operator found at startup.",
+	})
+
+	// HealthcheckProbeStatus reports the result of the last run of each probe of the healthcheck
+	// service, see --healthcheck-probes.
+	HealthcheckProbeStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "healthcheck_probe_status",
+		Help:      "1 if the last run of the healthcheck probe passed, 0 if it failed.",
+	}, []string{"probe"})
+
+	// ClaimDuration observes the duration of the prepare and unprepare of each claim.
+	ClaimDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "claim_duration_seconds",
+		Help:      "Duration of the prepare or unprepare of a claim.",
+		Buckets:   durationBuckets,
+	}, []string{"operation"})
+
+	// PhaseDuration observes the duration of the phases of the prepare and unprepare of claims,
+	// per device for driver_bind and per claim or pod for cdi and checkpoint.
+	PhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "phase_duration_seconds",
+		Help:      "Duration of a phase (driver_bind, cdi, checkpoint) of the prepare or unprepare of a claim.",
+		Buckets:   durationBuckets,
+	}, []string{"operation", "phase"})
+
+	// VFEthtoolStat describes the ethtool statistics of the VFs attached to pods, collected at
+	// scrape time by the collector of the NRI plugin when --ethtool-metrics is set.
+	VFEthtoolStat = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "vf_ethtool_stat"),
+		"Ethtool statistic of a VF attached to a pod, as reported by ethtool -S in the pod network namespace.",
+		[]string{"pod_namespace", "pod_name", "claim_namespace", "claim_name", "device", "stat"}, nil,
+	)
+)
+
+//nolint:gochecknoinits // Required for Prometheus metrics registration
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		SysfsWriteErrors,
+		SysfsWritable,
+		SriovOperatorConflicts,
+		HealthcheckProbeStatus,
+		ClaimDuration,
+		PhaseDuration,
+	)
+	SysfsWritable.Set(1)
+}
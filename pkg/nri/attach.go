@@ -0,0 +1,213 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/containerd/nri/pkg/api"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// attachDevices runs CNI ADD for the devices of a pod with at most attachWorkers operations in
+// flight, each device being a distinct VF with its own interface. The network data of the attached
+// devices is returned in the order of the devices, even when some of them failed to attach, along
+// with the errors of the failed ones.
+func (p *Plugin) attachDevices(ctx context.Context, pod *api.PodSandbox, networkNamespace string, devices types.PreparedDevices) (types.NetworkDataChanStructList, error) {
+	logger := klog.FromContext(ctx)
+
+	workers := max(p.attachWorkers, 1)
+	results := make([]*types.NetworkDataChanStruct, len(devices))
+	errs := make([]error, len(devices))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		if !device.AttachesNetwork() {
+			logger.V(2).Info("Skipping network attachment of shared or admin access device", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = p.attachDevice(ctx, pod, networkNamespace, device)
+		}()
+	}
+	wg.Wait()
+
+	networkDevicesData := types.NetworkDataChanStructList{}
+	for _, result := range results {
+		if result != nil {
+			networkDevicesData = append(networkDevicesData, result)
+		}
+	}
+
+	// bonds are attached once the VFs they bond are in the pod, their network data follows the
+	// one of the VFs so the claim reports the bond on its first device
+	for _, device := range devices {
+		bond := device.BondDevice()
+		if bond == nil || !device.AttachesNetwork() {
+			continue
+		}
+		if !bondLinksAttached(devices, results, device) {
+			errs = append(errs, fmt.Errorf("bond %s of device %s not attached: some of its devices failed to attach", bond.IfName, device.Device.DeviceName))
+			continue
+		}
+		result, err := p.attachBond(ctx, pod, networkNamespace, device, bond)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		networkDevicesData = append(networkDevicesData, result)
+	}
+	return networkDevicesData, errors.Join(errs...)
+}
+
+// bondLinksAttached reports whether all the devices bonded by the bond kept on a device attached.
+func bondLinksAttached(devices types.PreparedDevices, results []*types.NetworkDataChanStruct, bondDevice *types.PreparedDevice) bool {
+	for i, device := range devices {
+		if device.ClaimNamespacedName.UID == bondDevice.ClaimNamespacedName.UID && device.BondIfName == bondDevice.BondIfName && results[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// attachBond runs CNI ADD for the bond kept on a device of a pod and records the attachment on
+// the device.
+func (p *Plugin) attachBond(ctx context.Context, pod *api.PodSandbox, networkNamespace string, device, bond *types.PreparedDevice) (*types.NetworkDataChanStruct, error) {
+	logger := klog.FromContext(ctx)
+
+	attachResult, err := p.attachNetworkWithRetry(ctx, pod, networkNamespace, bond)
+	if err != nil {
+		logger.Error(err, "Failed to attach bond", "bond", bond.IfName, "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+		return nil, fmt.Errorf("failed to attach bond %s of device %s: %w", bond.IfName, device.Device.DeviceName, err)
+	}
+	if err := p.podManager.SetBondCNIAttachment(k8stypes.UID(pod.Uid), device.ClaimNamespacedName.UID, device.Device.DeviceName, attachResult.NetConf, attachResult.RawCNIResult); err != nil {
+		logger.Error(err, "Failed to record bond CNI attachment", "bond", bond.IfName, "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+	}
+	if p.verifyInterfaces {
+		if err := verifyInterface(networkNamespace, bond, attachResult); err != nil {
+			logger.Error(err, "Bond attachment verification failed", "bond", bond.IfName, "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+			return nil, err
+		}
+	}
+
+	logger.Info("Attached bond", "bond", bond.IfName, "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "networkDeviceData", attachResult.NetworkDeviceData)
+	return networkData(ctx, bond, attachResult), nil
+}
+
+// detachBond runs CNI DEL for the bond kept on a device of a pod, before the VFs it bonds are
+// detached, and clears its record.
+func (p *Plugin) detachBond(ctx context.Context, pod *api.PodSandbox, networkNamespace string, device *types.PreparedDevice) error {
+	bond := device.BondDevice()
+	if bond == nil {
+		return nil
+	}
+	if err := p.cniRuntime.DetachNetwork(ctx, pod, networkNamespace, bond); err != nil {
+		return fmt.Errorf("failed to detach bond %s of device %s: %w", bond.IfName, device.Device.DeviceName, err)
+	}
+	if device.BondCNINetConf == "" {
+		return nil
+	}
+	return p.podManager.SetBondCNIAttachment(k8stypes.UID(pod.Uid), device.ClaimNamespacedName.UID, device.Device.DeviceName, "", "")
+}
+
+// attachDevice runs CNI ADD for a device of a pod and records the attachment.
+func (p *Plugin) attachDevice(ctx context.Context, pod *api.PodSandbox, networkNamespace string, device *types.PreparedDevice) (*types.NetworkDataChanStruct, error) {
+	logger := klog.FromContext(ctx)
+
+	attachResult, err := p.attachNetworkWithRetry(ctx, pod, networkNamespace, device)
+	if err != nil {
+		logger.Error(err, "Failed to attach network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return nil, fmt.Errorf("failed to attach network of device %s: %w", device.Device.DeviceName, err)
+	}
+	// keep what was attached so the device can still be detached if the NAD or the CNI cache changes
+	if err := p.podManager.SetCNIAttachment(k8stypes.UID(pod.Uid), device.ClaimNamespacedName.UID, device.Device.DeviceName, pod.Id, attachResult.NetConf, attachResult.RawCNIResult); err != nil {
+		logger.Error(err, "Failed to record CNI attachment", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+	}
+	// the attachment is recorded, StopPodSandbox detaches it when the verification, the sysctls, the
+	// neighbors or the routes fail
+	if p.verifyInterfaces {
+		if err := verifyInterface(networkNamespace, device, attachResult); err != nil {
+			logger.Error(err, "Network attachment verification failed", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+			return nil, err
+		}
+	}
+	if err := setSysctls(networkNamespace, device, attachResult); err != nil {
+		logger.Error(err, "Failed to set sysctls", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return nil, err
+	}
+	if err := addNeighbors(networkNamespace, device, attachResult); err != nil {
+		logger.Error(err, "Failed to add neighbors", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return nil, err
+	}
+	if err := addRoutes(networkNamespace, device, attachResult); err != nil {
+		logger.Error(err, "Failed to add routes", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return nil, err
+	}
+
+	logger.Info("Attached network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace, "networkDeviceData", attachResult.NetworkDeviceData)
+	return networkData(ctx, device, attachResult), nil
+}
+
+// networkData returns the network data of an attached device reported in its claim.
+func networkData(ctx context.Context, device *types.PreparedDevice, attachResult *cni.AttachResult) *types.NetworkDataChanStruct {
+	logger := klog.FromContext(ctx)
+
+	// Parse NetAttachDefConfig into map[string]interface{} for CNIConfig
+	cniConfigMap := map[string]interface{}{}
+	if device.NetAttachDefConfig != "" {
+		if err := json.Unmarshal([]byte(device.NetAttachDefConfig), &cniConfigMap); err != nil {
+			logger.V(2).Info("Failed to unmarshal NetAttachDefConfig, proceeding with empty CNIConfig", "error", err.Error())
+			cniConfigMap = map[string]interface{}{}
+		}
+	}
+
+	return &types.NetworkDataChanStruct{
+		PreparedDevice:    device,
+		NetworkDeviceData: attachResult.NetworkDeviceData,
+		CNIConfig:         cniConfigMap,
+		CNIResult:         attachResult.CNIResult,
+	}
+}
+
+// attachNetworkWithRetry runs CNI ADD for a device, retrying with backoff while it fails with
+// retryable errors, see cni.IsRetryable. A failed ADD is followed by a DEL, so the next attempt
+// does not trip over what the plugins set up before failing.
+func (p *Plugin) attachNetworkWithRetry(ctx context.Context, pod *api.PodSandbox, networkNamespace string, device *types.PreparedDevice) (*cni.AttachResult, error) {
+	logger := klog.FromContext(ctx)
+
+	var (
+		attachResult *cni.AttachResult
+		attachErr    error
+	)
+	err := wait.ExponentialBackoffWithContext(ctx, consts.Backoff, func(ctx context.Context) (bool, error) {
+		attachResult, attachErr = p.cniRuntime.AttachNetwork(ctx, pod, networkNamespace, device)
+		if attachErr == nil {
+			return true, nil
+		}
+		if !cni.IsRetryable(attachErr) {
+			return false, attachErr
+		}
+		logger.V(2).Info("Retrying network attachment", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "error", attachErr.Error())
+		if err := p.cniRuntime.DetachNetwork(ctx, pod, networkNamespace, device); err != nil {
+			logger.V(2).Info("Failed to clean up failed network attachment", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "error", err.Error())
+		}
+		return false, nil
+	})
+	if err != nil && attachErr != nil {
+		// report what the plugin failed with rather than the exhausted backoff
+		return nil, attachErr
+	}
+	return attachResult, err
+}
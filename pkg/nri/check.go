@@ -0,0 +1,128 @@
+package nri
+
+import (
+	"context"
+
+	"github.com/containerd/nri/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	// eventReasonNetworkCheckFailed is the reason of the events emitted when CNI CHECK fails for a device.
+	eventReasonNetworkCheckFailed = "NetworkCheckFailed"
+)
+
+// newEventRecorder returns a recorder emitting events on behalf of the driver, or nil when
+// no kubernetes client is configured.
+func newEventRecorder(config *types.Config) record.EventRecorder {
+	if config.K8sClient.Interface == nil {
+		return nil
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: config.K8sClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: consts.DriverName, Host: config.Flags.NodeName})
+}
+
+// trackAttachedPod remembers a sandbox whose networks were attached so it can be checked later.
+func (p *Plugin) trackAttachedPod(pod *api.PodSandbox) {
+	p.attachedPodsMu.Lock()
+	defer p.attachedPodsMu.Unlock()
+	if p.attachedPods == nil {
+		p.attachedPods = map[string]*api.PodSandbox{}
+	}
+	p.attachedPods[pod.Uid] = pod
+}
+
+// untrackAttachedPod forgets a sandbox once its networks are detached.
+func (p *Plugin) untrackAttachedPod(podUID string) {
+	p.attachedPodsMu.Lock()
+	defer p.attachedPodsMu.Unlock()
+	delete(p.attachedPods, podUID)
+}
+
+// untrackAttachedSandbox forgets a sandbox unless a newer sandbox of the pod is tracked.
+func (p *Plugin) untrackAttachedSandbox(pod *api.PodSandbox) {
+	p.attachedPodsMu.Lock()
+	defer p.attachedPodsMu.Unlock()
+	if tracked, ok := p.attachedPods[pod.Uid]; ok && tracked.Id == pod.Id {
+		delete(p.attachedPods, pod.Uid)
+	}
+}
+
+// listAttachedPods returns a snapshot of the tracked sandboxes.
+func (p *Plugin) listAttachedPods() []*api.PodSandbox {
+	p.attachedPodsMu.Lock()
+	defer p.attachedPodsMu.Unlock()
+	pods := make([]*api.PodSandbox, 0, len(p.attachedPods))
+	for _, pod := range p.attachedPods {
+		pods = append(pods, pod)
+	}
+	return pods
+}
+
+// networkCheckRunner periodically verifies the network attachments of the tracked pods.
+func (p *Plugin) networkCheckRunner(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		p.CheckNetworks(ctx)
+	}, p.cniCheckInterval)
+}
+
+// CheckNetworks runs CNI CHECK for every device attached by the plugin and reports failures
+// as warning events on the owning pod. It returns the number of failed checks.
+func (p *Plugin) CheckNetworks(ctx context.Context) int {
+	logger := klog.FromContext(ctx).WithName("NRI CheckNetworks")
+	failures := 0
+	for _, pod := range p.listAttachedPods() {
+		devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+		if !found {
+			// devices were unprepared in the meantime, nothing left to check
+			p.untrackAttachedPod(pod.Uid)
+			continue
+		}
+
+		networkNamespace := getNetworkNamespace(pod)
+		if networkNamespace == "" {
+			continue
+		}
+
+		for _, device := range devices {
+			if !device.AttachesNetwork() {
+				continue
+			}
+			err := p.cniRuntime.CheckNetwork(ctx, pod, networkNamespace, device)
+			if err == nil {
+				continue
+			}
+			failures++
+			logger.Error(err, "Network check failed", "deviceName", device.Device.DeviceName, "ifName", device.IfName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+			p.recordNetworkCheckFailure(pod, device, err)
+		}
+	}
+	return failures
+}
+
+// recordNetworkCheckFailure emits a warning event on the pod owning a device whose CNI CHECK failed.
+func (p *Plugin) recordNetworkCheckFailure(pod *api.PodSandbox, device *types.PreparedDevice, err error) {
+	if p.eventRecorder == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		UID:        k8stypes.UID(pod.Uid),
+	}
+	p.eventRecorder.Eventf(ref, corev1.EventTypeWarning, eventReasonNetworkCheckFailed,
+		"CNI CHECK failed for device %s (interface %s) of claim %s/%s: %v",
+		device.Device.DeviceName, device.IfName, device.ClaimNamespacedName.Namespace, device.ClaimNamespacedName.Name, err)
+}
@@ -0,0 +1,143 @@
+package nri
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	// podPCIAddressesEnv lists the PCI addresses of the VFs, set by the CDI spec of the pod.
+	podPCIAddressesEnv = "SRIOVNETWORK_PCI_ADDRESSES"
+)
+
+// CreateContainer adjusts a container using VFs of the pod. The CDI spec of the pod lists the VFs
+// of all its claims and is injected into every container using one of them, so the container gets
+// SRIOVNETWORK_PCI_ADDRESSES narrowed to the VFs of the claims it requests, and loses the
+// environment variables, device nodes and mounts of the other VFs of the pod. The container is
+// then aligned with the NUMA nodes of its VFs.
+func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
+	logger := klog.FromContext(ctx).WithName("NRI CreateContainer")
+
+	podDevices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+	if !found {
+		return nil, nil, nil
+	}
+	devices, reported := containerDevices(ctr, pod.Uid, podDevices)
+	if len(devices) == 0 {
+		return nil, nil, nil
+	}
+
+	adjustment := &api.ContainerAdjustment{}
+	adjusted := false
+	// devices are only narrowed when the runtime reports the CDI devices of the container
+	if reported && len(devices) < len(podDevices) {
+		pciAddresses := make([]string, 0, len(devices))
+		for _, device := range devices {
+			pciAddresses = append(pciAddresses, device.PciAddress)
+		}
+		adjustment.AddEnv(podPCIAddressesEnv, strings.Join(pciAddresses, ","))
+		logger.V(3).Info("Narrowing the PCI addresses of the container to its devices", "pod.UID", pod.Uid, "container", ctr.Name,
+			"pciAddresses", pciAddresses)
+		adjusted = true
+		if removed := removeOtherDeviceEdits(ctr, devices, podDevices, adjustment); len(removed) > 0 {
+			logger.V(3).Info("Removed the edits of the other devices of the pod from the container", "pod.UID", pod.Uid, "container", ctr.Name,
+				"removed", removed)
+		}
+	}
+	if p.alignContainerNUMA(ctx, pod, ctr, devices, adjustment) {
+		adjusted = true
+	}
+	if !adjusted {
+		return nil, nil, nil
+	}
+	return adjustment, nil, nil
+}
+
+// containerDevices returns the devices of the pod used by a container, matched on the CDI devices
+// of their claims, and whether the runtime reported the CDI devices of the container. When it does
+// not, all the devices of the pod are returned.
+func containerDevices(ctr *api.Container, podUID string, devices types.PreparedDevices) (types.PreparedDevices, bool) {
+	if len(ctr.GetCDIDevices()) == 0 {
+		return devices, false
+	}
+
+	cdiDevices := make(map[string]bool, len(ctr.GetCDIDevices()))
+	for _, cdiDevice := range ctr.GetCDIDevices() {
+		// the device of the pod spec is injected along with every device of the pod
+		if _, _, name := cdiparser.ParseDevice(cdiDevice.GetName()); name == podUID {
+			continue
+		}
+		cdiDevices[cdiDevice.GetName()] = true
+	}
+
+	var ctrDevices types.PreparedDevices
+	for _, device := range devices {
+		if slices.ContainsFunc(device.Device.CDIDeviceIDs, func(id string) bool { return cdiDevices[id] }) {
+			ctrDevices = append(ctrDevices, device)
+		}
+	}
+	return ctrDevices, true
+}
+
+// removeOtherDeviceEdits removes from a container the environment variables, device nodes and mounts
+// of the CDI edits of the devices of the pod it does not use, unless one of its own devices has
+// them too, and returns their names.
+func removeOtherDeviceEdits(ctr *api.Container, devices, podDevices types.PreparedDevices, adjustment *api.ContainerAdjustment) []string {
+	ownEnv, ownDeviceNodes, ownMounts := deviceEdits(devices)
+	otherEnv, otherDeviceNodes, otherMounts := deviceEdits(slices.DeleteFunc(slices.Clone(podDevices), func(device *types.PreparedDevice) bool {
+		return slices.Contains(devices, device)
+	}))
+
+	var removed []string
+	for _, env := range ctr.GetEnv() {
+		key, _, _ := strings.Cut(env, "=")
+		if otherEnv.Has(key) && !ownEnv.Has(key) {
+			adjustment.RemoveEnv(key)
+			removed = append(removed, key)
+		}
+	}
+	for _, device := range ctr.GetLinux().GetDevices() {
+		if otherDeviceNodes.Has(device.Path) && !ownDeviceNodes.Has(device.Path) {
+			adjustment.RemoveDevice(device.Path)
+			removed = append(removed, device.Path)
+		}
+	}
+	for _, mount := range ctr.GetMounts() {
+		if otherMounts.Has(mount.Destination) && !ownMounts.Has(mount.Destination) {
+			adjustment.RemoveMount(mount.Destination)
+			removed = append(removed, mount.Destination)
+		}
+	}
+	return removed
+}
+
+// deviceEdits returns the names of the environment variables, and the container paths of the
+// device nodes and mounts, of the CDI edits of devices.
+func deviceEdits(devices types.PreparedDevices) (env, deviceNodes, mounts sets.Set[string]) {
+	env, deviceNodes, mounts = sets.New[string](), sets.New[string](), sets.New[string]()
+	for _, device := range devices {
+		if device.ContainerEdits == nil || device.ContainerEdits.ContainerEdits == nil {
+			continue
+		}
+		for _, e := range device.ContainerEdits.Env {
+			key, _, _ := strings.Cut(e, "=")
+			env.Insert(key)
+		}
+		for _, deviceNode := range device.ContainerEdits.DeviceNodes {
+			deviceNodes.Insert(deviceNode.Path)
+		}
+		for _, mount := range device.ContainerEdits.Mounts {
+			mounts.Insert(mount.ContainerPath)
+		}
+	}
+	return env, deviceNodes, mounts
+}
@@ -0,0 +1,60 @@
+package nri
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/metrics"
+)
+
+// ethtoolCollector exposes the ethtool statistics of the kernel bound VFs attached to the tracked
+// pods as Prometheus metrics. The statistics are read from the pod network namespaces at scrape
+// time, so they are as fresh as the scrape and cost nothing between scrapes.
+type ethtoolCollector struct {
+	plugin *Plugin
+}
+
+var _ prometheus.Collector = &ethtoolCollector{}
+
+// EthtoolCollector returns the collector of the ethtool statistics of the attached VFs, to be
+// registered in the metrics registry, see --ethtool-metrics.
+func (p *Plugin) EthtoolCollector() prometheus.Collector {
+	return &ethtoolCollector{plugin: p}
+}
+
+// Describe sends the description of the ethtool statistics metric.
+func (c *ethtoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metrics.VFEthtoolStat
+}
+
+// Collect reads the ethtool statistics of the attached VFs. The statistics depend on the driver
+// of the VFs, some are counters and some gauges, so they are exported untyped.
+func (c *ethtoolCollector) Collect(ch chan<- prometheus.Metric) {
+	logger := klog.LoggerWithName(klog.Background(), "NRI ethtoolCollector")
+
+	for _, pod := range c.plugin.listAttachedPods() {
+		devices, found := c.plugin.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+		if !found {
+			continue
+		}
+		networkNamespace := getNetworkNamespace(pod)
+		if networkNamespace == "" {
+			continue
+		}
+		for _, device := range kernelNetworkDevices(devices) {
+			stats, err := host.GetHelpers().GetEthtoolStats(networkNamespace, device.IfName)
+			if err != nil {
+				logger.V(2).Info("Failed to read ethtool statistics", "deviceName", device.Device.DeviceName, "ifName", device.IfName,
+					"pod.UID", pod.Uid, "error", err.Error())
+				continue
+			}
+			for name, value := range stats {
+				ch <- prometheus.MustNewConstMetric(metrics.VFEthtoolStat, prometheus.UntypedValue, float64(value),
+					pod.Namespace, pod.Name, device.ClaimNamespacedName.Namespace, device.ClaimNamespacedName.Name,
+					device.Device.DeviceName, name)
+			}
+		}
+	}
+}
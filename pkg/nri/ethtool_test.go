@@ -0,0 +1,108 @@
+package nri
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI ethtool metrics", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockHost    *hostmock.MockInterface
+		origHelpers host.Interface
+		plugin      *Plugin
+		pod         *api.PodSandbox
+		prepared    types.PreparedDevices
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(ctrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+		mockHost.EXPECT().IsDpdkDriver(gomock.Any()).DoAndReturn(func(driver string) bool {
+			return driver == "vfio-pci"
+		}).AnyTimes()
+
+		podManager, err := podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+		plugin = &Plugin{podManager: podManager}
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-id",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "uid-1",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+			},
+		}
+		claim := kubeletplugin.NamespacedObject{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"}, UID: "claim-1"}
+		prepared = types.PreparedDevices{
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "vf-1"},
+				ClaimNamespacedName: claim,
+				IfName:              "net1",
+				PodUID:              pod.Uid,
+			},
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "vf-2"},
+				ClaimNamespacedName: claim,
+				Config:              &configapi.VfConfig{Driver: "vfio-pci"},
+				IfName:              "net2",
+				PodUID:              pod.Uid,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+		plugin.trackAttachedPod(pod)
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		ctrl.Finish()
+	})
+
+	It("exports the ethtool statistics of the kernel bound VFs", func() {
+		mockHost.EXPECT().GetEthtoolStats("/proc/123/ns/net", "net1").Return(map[string]uint64{
+			"rx_packets":       10,
+			"rx_out_of_buffer": 3,
+		}, nil)
+
+		expected := `
+# HELP dra_driver_sriov_vf_ethtool_stat Ethtool statistic of a VF attached to a pod, as reported by ethtool -S in the pod network namespace.
+# TYPE dra_driver_sriov_vf_ethtool_stat untyped
+dra_driver_sriov_vf_ethtool_stat{claim_name="claim",claim_namespace="default",device="vf-1",pod_name="pod-name",pod_namespace="default",stat="rx_out_of_buffer"} 3
+dra_driver_sriov_vf_ethtool_stat{claim_name="claim",claim_namespace="default",device="vf-1",pod_name="pod-name",pod_namespace="default",stat="rx_packets"} 10
+`
+		Expect(testutil.CollectAndCompare(plugin.EthtoolCollector(), strings.NewReader(expected))).To(Succeed())
+	})
+
+	It("skips the VFs whose statistics cannot be read", func() {
+		mockHost.EXPECT().GetEthtoolStats("/proc/123/ns/net", "net1").Return(nil, errors.New("operation not supported"))
+
+		Expect(testutil.CollectAndCount(plugin.EthtoolCollector())).To(Equal(0))
+	})
+
+	It("exports nothing for pods whose devices were unprepared", func() {
+		Expect(plugin.podManager.DeletePod(k8stypes.UID(pod.Uid))).To(Succeed())
+
+		// no GetEthtoolStats expectation: the mock fails on any call
+		Expect(testutil.CollectAndCount(plugin.EthtoolCollector())).To(Equal(0))
+	})
+})
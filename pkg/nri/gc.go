@@ -0,0 +1,134 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/containerd/nri/pkg/api"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// SetStalePodCallback sets the function called for every pod whose sandbox is gone once its
+// orphaned network attachments were removed, so the driver can restore its devices. It must be
+// set before the plugin is started.
+func (p *Plugin) SetStalePodCallback(callback func(ctx context.Context, podUID k8stypes.UID) error) {
+	p.stalePodCallback = callback
+}
+
+// Synchronize is called by the runtime when the plugin connects or reconnects, with the sandboxes
+// that are currently running. Devices of pods without a running sandbox were attached before a node crash
+// or a driver restart and never detached, they get a CNI DEL so their IPs and VFs are not leaked.
+func (p *Plugin) Synchronize(ctx context.Context, pods []*api.PodSandbox, _ []*api.Container) ([]*api.ContainerUpdate, error) {
+	logger := klog.FromContext(ctx).WithName("NRI Synchronize")
+	if p.containersOnly {
+		return nil, nil
+	}
+	logger.Info("Synchronize", "pods", len(pods))
+
+	p.inflight.Track()
+	defer p.inflight.End()
+
+	running := make(map[k8stypes.UID]*api.PodSandbox, len(pods))
+	for _, pod := range pods {
+		running[k8stypes.UID(pod.Uid)] = pod
+	}
+	// after a reconnection, sandboxes stopped while the plugin was disconnected are still tracked
+	for _, pod := range p.listAttachedPods() {
+		if runningPod, ok := running[k8stypes.UID(pod.Uid)]; !ok || runningPod.Id != pod.Id {
+			p.untrackAttachedSandbox(pod)
+		}
+	}
+
+	for _, podUID := range p.podManager.GetPodUIDs() {
+		if pod, ok := running[podUID]; ok {
+			// keep verifying the attachments made before the restart
+			if p.hasCNIAttachment(podUID, pod.Id) {
+				p.trackAttachedPod(pod)
+			}
+			continue
+		}
+		if err := p.collectOrphanedAttachments(ctx, podUID); err != nil {
+			// a failure must not prevent the plugin from registering, the stale pod collector
+			// still restores the devices once the pod is deleted
+			logger.Error(err, "Failed to collect orphaned network attachments", "pod.UID", podUID)
+		}
+	}
+	return nil, nil
+}
+
+// hasCNIAttachment reports whether a device of the pod was attached to the given sandbox.
+func (p *Plugin) hasCNIAttachment(podUID k8stypes.UID, sandboxID string) bool {
+	devices, _ := p.podManager.GetDevicesByPodUID(podUID)
+	for _, device := range devices {
+		if device.AttachesNetwork() && device.CNISandboxID == sandboxID {
+			return true
+		}
+	}
+	return false
+}
+
+// collectOrphanedAttachments runs CNI DEL for the recorded attachments of a pod without a running
+// sandbox and then hands the pod over to the stale pod callback. Pods without recorded attachments,
+// e.g. prepared pods whose sandbox is not created yet, are left alone.
+func (p *Plugin) collectOrphanedAttachments(ctx context.Context, podUID k8stypes.UID) error {
+	detached, err := p.detachRecordedAttachments(ctx, podUID, nil)
+	if err != nil {
+		return err
+	}
+
+	if detached > 0 && p.stalePodCallback != nil {
+		if err := p.stalePodCallback(ctx, podUID); err != nil {
+			return fmt.Errorf("failed to restore devices of pod %s: %w", podUID, err)
+		}
+	}
+	return nil
+}
+
+// detachRecordedAttachments runs CNI DEL for the recorded attachments of a pod whose network
+// namespace is gone and clears the records, returning the number of detached devices. With a
+// sandbox, only the attachments made to it are detached, otherwise all of them are.
+func (p *Plugin) detachRecordedAttachments(ctx context.Context, podUID k8stypes.UID, sandbox *api.PodSandbox) (int, error) {
+	logger := klog.FromContext(ctx)
+	devices, found := p.podManager.GetDevicesByPodUID(podUID)
+	if !found {
+		return 0, nil
+	}
+
+	var errs []error
+	detached := 0
+	for _, device := range devices {
+		if !device.AttachesNetwork() || device.CNISandboxID == "" {
+			continue
+		}
+		deviceSandbox := sandbox
+		if deviceSandbox == nil {
+			deviceSandbox = &api.PodSandbox{
+				Id:        device.CNISandboxID,
+				Uid:       string(podUID),
+				Namespace: device.ClaimNamespacedName.Namespace,
+			}
+		} else if device.CNISandboxID != sandbox.Id {
+			continue
+		}
+		// the network namespace is gone with the sandbox, CNI DEL only releases the IPAM
+		// allocation and the VF kept by the plugin
+		logger.Info("Detaching orphaned network", "deviceName", device.Device.DeviceName, "pod.UID", podUID, "sandbox", device.CNISandboxID)
+		if device.BondCNINetConf != "" {
+			if err := p.detachBond(ctx, deviceSandbox, "", device); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := p.cniRuntime.DetachNetwork(ctx, deviceSandbox, "", device); err != nil {
+			errs = append(errs, fmt.Errorf("failed to detach device %s of pod %s: %w", device.Device.DeviceName, podUID, err))
+			continue
+		}
+		if err := p.podManager.SetCNIAttachment(podUID, device.ClaimNamespacedName.UID, device.Device.DeviceName, "", "", ""); err != nil {
+			errs = append(errs, err)
+		}
+		detached++
+	}
+	return detached, errors.Join(errs...)
+}
@@ -0,0 +1,32 @@
+package nri
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// addNeighbors adds the permanent neighbor entries of the config of a device to its interface in
+// the pod network namespace once CNI ADD attached it.
+func addNeighbors(networkNamespace string, device *types.PreparedDevice, attachResult *cni.AttachResult) error {
+	if device.Config == nil || len(device.Config.Neighbors) == 0 {
+		return nil
+	}
+	neighbors := make([]host.Neighbor, 0, len(device.Config.Neighbors))
+	for _, neighbor := range device.Config.Neighbors {
+		// the config was validated at prepare time
+		mac, err := net.ParseMAC(neighbor.MAC)
+		if err != nil {
+			return fmt.Errorf("invalid neighbor MAC address %q of device %s: %w", neighbor.MAC, device.Device.DeviceName, err)
+		}
+		neighbors = append(neighbors, host.Neighbor{IP: net.ParseIP(neighbor.IP), MAC: mac})
+	}
+	ifName := attachedIfName(device, attachResult)
+	if err := host.GetHelpers().AddNetnsNeighbors(networkNamespace, ifName, neighbors); err != nil {
+		return fmt.Errorf("failed to add neighbors of device %s: %w", device.Device.DeviceName, err)
+	}
+	return nil
+}
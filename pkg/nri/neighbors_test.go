@@ -0,0 +1,67 @@
+package nri
+
+import (
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI neighbors", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockHost    *hostmock.MockInterface
+		origHelpers host.Interface
+		device      *types.PreparedDevice
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(ctrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+
+		device = &types.PreparedDevice{
+			Device: drapbv1.Device{DeviceName: "vf-1"},
+			IfName: "net1",
+			Config: &configapi.VfConfig{Neighbors: []configapi.NeighborConfig{{IP: "192.168.100.1", MAC: "0a:1b:2c:3d:4e:5f"}}},
+		}
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		ctrl.Finish()
+	})
+
+	It("adds the neighbors to the interface of the device", func() {
+		mac, err := net.ParseMAC("0a:1b:2c:3d:4e:5f")
+		Expect(err).NotTo(HaveOccurred())
+		mockHost.EXPECT().AddNetnsNeighbors("/proc/123/ns/net", "net1", []host.Neighbor{
+			{IP: net.ParseIP("192.168.100.1"), MAC: mac},
+		}).Return(nil)
+
+		Expect(addNeighbors("/proc/123/ns/net", device, nil)).To(Succeed())
+	})
+
+	It("fails when a neighbor cannot be added", func() {
+		mockHost.EXPECT().AddNetnsNeighbors("/proc/123/ns/net", "net1", gomock.Any()).Return(errors.New("operation not supported"))
+
+		Expect(addNeighbors("/proc/123/ns/net", device, nil)).To(MatchError("failed to add neighbors of device vf-1: operation not supported"))
+	})
+
+	It("does nothing without neighbors", func() {
+		device.Config = &configapi.VfConfig{}
+
+		// no AddNetnsNeighbors expectation: the mock fails on any call
+		Expect(addNeighbors("/proc/123/ns/net", device, nil)).To(Succeed())
+	})
+})
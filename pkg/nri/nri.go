@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
 	"github.com/containerd/nri/pkg/stub"
@@ -13,26 +15,70 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/inflight"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
-// Plugin represents a NRI plugin catching RunPodSandbox and StopPodSandbox events to
-// call CNI ADD/DEL based on ResourceClaim attached to pods.
+// Plugin represents a NRI plugin catching RunPodSandbox, StopPodSandbox and RemovePodSandbox
+// events to call CNI ADD/DEL based on ResourceClaim attached to pods, and CreateContainer events
+// to narrow the devices of each container.
 type Plugin struct {
-	stub       stub.Stub
+	// stub is replaced by a new one when reconnecting to the runtime, stubMu guards it, stopped
+	// and connected.
+	stub      stub.Stub
+	stubMu    sync.Mutex
+	stopped   bool
+	connected bool
+	// newStub creates the stub connecting the plugin to the runtime.
+	newStub func() (stub.Stub, error)
+
 	podManager *podmanager.PodManager
 	cniRuntime cni.Interface
 
 	k8sClient                   flags.ClientSets
 	networkDeviceDataUpdateChan chan types.NetworkDataChanStructList
 	interfacePrefix             string
+
+	// attachedPods holds the sandboxes whose networks were attached, keyed by pod UID,
+	// so their attachments can be verified with CNI CHECK.
+	attachedPods     map[string]*api.PodSandbox
+	attachedPodsMu   sync.Mutex
+	cniCheckInterval time.Duration
+	eventRecorder    record.EventRecorder
+	// statisticsInterval is the interval between publications of the traffic counters of the
+	// attached VFs in the status of their claim, see PublishStatistics.
+	statisticsInterval time.Duration
+	// attachWorkers bounds the number of devices of a pod attached concurrently.
+	attachWorkers int
+	// verifyInterfaces verifies the interfaces of the devices in the pod network namespace after
+	// CNI ADD, see verifyInterface.
+	verifyInterfaces bool
+
+	// numaAlignment selects how containers not NUMA aligned with their VFs are handled.
+	numaAlignment consts.NUMAAlignment
+
+	// containersOnly limits the plugin to the containers in MULTUS mode, where Multus attaches the
+	// networks: the sandbox events are ignored.
+	containersOnly bool
+
+	// registrationTimeout and requestTimeout are the NRI timeouts the plugin needs, the runtime
+	// configures the actual ones, see checkRuntimeTimeouts.
+	registrationTimeout time.Duration
+	requestTimeout      time.Duration
+
+	// stalePodCallback reverts the devices of a pod whose sandbox is gone, see SetStalePodCallback.
+	stalePodCallback func(ctx context.Context, podUID k8stypes.UID) error
+
+	// inflight tracks the sandbox events in progress running CNI operations, see Drain.
+	inflight inflight.Tracker
 }
 
 // NewNRIPlugin creates a new NRI plugin.
@@ -43,19 +89,30 @@ func NewNRIPlugin(config *types.Config, podManager *podmanager.PodManager, cniRu
 		k8sClient:                   config.K8sClient,
 		interfacePrefix:             config.Flags.DefaultInterfacePrefix,
 		networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 100),
+		attachedPods:                map[string]*api.PodSandbox{},
+		cniCheckInterval:            config.Flags.CNICheckInterval,
+		statisticsInterval:          config.Flags.VFStatisticsInterval,
+		attachWorkers:               config.Flags.CNIAttachWorkers,
+		verifyInterfaces:            config.Flags.VerifyInterfaces,
+		eventRecorder:               newEventRecorder(config),
+		numaAlignment:               consts.NUMAAlignment(config.Flags.NUMAAlignment),
+		containersOnly:              consts.ConfigurationMode(config.Flags.ConfigurationMode) == consts.ConfigurationModeMultus,
+		registrationTimeout:         config.Flags.NRIRegistrationTimeout,
+		requestTimeout:              config.Flags.NRIRequestTimeout,
 	}
-	var err error
-	// register the NRI plugin
-	nriOpts := []stub.Option{
-		// https://github.com/containerd/nri/pull/173
-		// Otherwise it silently exits the program
-		stub.WithOnClose(func() {
-			klog.Infof("%s NRI plugin closed canceling context", consts.DriverName)
-			config.CancelMainCtx(fmt.Errorf("NRI plugin closed"))
-		}),
+	p.newStub = func() (stub.Stub, error) {
+		return stub.New(p,
+			// https://github.com/containerd/nri/pull/173
+			// Otherwise it silently exits the program. The connection is lost when the runtime
+			// restarts, reconnectRunner then registers a new stub.
+			stub.WithOnClose(func() {
+				klog.Infof("%s NRI plugin connection closed", consts.DriverName)
+			}),
+		)
 	}
 
-	p.stub, err = stub.New(p, nriOpts...)
+	var err error
+	p.stub, err = p.newStub()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create plugin stub: %w", err)
 	}
@@ -72,22 +129,69 @@ func (p *Plugin) Start(ctx context.Context) error {
 		logger.Error(err, "Failed to start NRI plugin")
 		return fmt.Errorf("failed to start NRI plugin: %w", err)
 	}
+	p.setConnected(true)
+	p.checkRuntimeTimeouts(logger)
 
+	go p.reconnectRunner(ctx)
 	go p.updateNetworkDeviceDataRunner(ctx)
+	if p.containersOnly {
+		return nil
+	}
+	if p.cniCheckInterval > 0 {
+		go p.networkCheckRunner(ctx)
+	}
+	if p.statisticsInterval > 0 {
+		go p.statisticsRunner(ctx)
+	}
 	return nil
 }
 
+// checkRuntimeTimeouts warns when the runtime configured NRI timeouts shorter than the ones the
+// plugin needs. NRI plugins cannot set their own timeouts, the runtime sends them when configuring
+// the plugin, so they have to be raised in the runtime configuration (e.g. plugin_request_timeout
+// and plugin_registration_timeout in the containerd NRI section).
+func (p *Plugin) checkRuntimeTimeouts(logger klog.Logger) {
+	if actual := p.currentStub().RegistrationTimeout(); p.registrationTimeout > 0 && actual < p.registrationTimeout {
+		logger.Error(nil, "Runtime NRI registration timeout is shorter than required, raise plugin_registration_timeout in the runtime NRI configuration",
+			"runtimeTimeout", actual, "requiredTimeout", p.registrationTimeout)
+	}
+	// a RunPodSandbox outlasting the request timeout fails the pod sandbox creation
+	if actual := p.currentStub().RequestTimeout(); p.requestTimeout > 0 && actual < p.requestTimeout {
+		logger.Error(nil, "Runtime NRI request timeout is shorter than required, CNI ADD with slow IPAM may fail RunPodSandbox; raise plugin_request_timeout in the runtime NRI configuration",
+			"runtimeTimeout", actual, "requiredTimeout", p.requestTimeout)
+	}
+}
+
+// Drain stops attaching the networks of new sandboxes and waits for the CNI operations in progress
+// to complete, or for ctx to be done. Sandboxes keep being detached until Stop.
+func (p *Plugin) Drain(ctx context.Context) error {
+	return p.inflight.Drain(ctx)
+}
+
 // Stop stops the NRI plugin.
 func (p *Plugin) Stop() {
+	p.stubMu.Lock()
+	p.stopped = true
 	p.stub.Stop()
+	p.stubMu.Unlock()
 	close(p.networkDeviceDataUpdateChan)
 }
 
-// RunPodSandbox runs the CNI ADD operation for each device in the devices list.
+// RunPodSandbox runs the CNI ADD operation for each device in the devices list, attaching up to
+// attachWorkers devices concurrently. In the exclusive RDMA netns mode, the RDMA devices of the VFs
+// are then moved to the pod network namespace.
 func (p *Plugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 	logger := klog.FromContext(ctx).WithName("NRI RunPodSandbox")
+	if p.containersOnly {
+		return nil
+	}
 	logger.Info("RunPodSandbox", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
 
+	if !p.inflight.Begin() {
+		return fmt.Errorf("NRI plugin is shutting down, not attaching networks of pod %s", pod.Uid)
+	}
+	defer p.inflight.End()
+
 	devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
 	if !found {
 		logger.Info("No prepared devices found for pod", "pod.UID", pod.Uid)
@@ -102,40 +206,34 @@ func (p *Plugin) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 		return nil
 	}
 
-	networkDevicesData := types.NetworkDataChanStructList{}
-	for _, device := range devices {
-		networkDeviceData, cniResultMap, err := p.cniRuntime.AttachNetwork(ctx, pod, networkNamespace, device)
-		if err != nil {
-			logger.Error(err, "Failed to attach network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
-			return fmt.Errorf("failed to attach network: %w", err)
-		}
-		// Parse NetAttachDefConfig into map[string]interface{} for CNIConfig
-		cniConfigMap := map[string]interface{}{}
-		if device.NetAttachDefConfig != "" {
-			if err := json.Unmarshal([]byte(device.NetAttachDefConfig), &cniConfigMap); err != nil {
-				logger.V(2).Info("Failed to unmarshal NetAttachDefConfig, proceeding with empty CNIConfig", "error", err.Error())
-				cniConfigMap = map[string]interface{}{}
-			}
-		}
-
-		networkDevicesData = append(networkDevicesData, &types.NetworkDataChanStruct{
-			PreparedDevice:    device,
-			NetworkDeviceData: networkDeviceData,
-			CNIConfig:         cniConfigMap,
-			CNIResult:         cniResultMap,
-		})
-		logger.Info("Attached network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace, "networkDeviceData", networkDeviceData)
+	networkDevicesData, err := p.attachDevices(klog.NewContext(ctx, logger), pod, networkNamespace, devices)
+	if err != nil {
+		return fmt.Errorf("failed to attach network: %w", err)
+	}
+	// the attachments are recorded, StopPodSandbox detaches them when the RDMA devices fail to move
+	if err := p.moveRDMADevices(klog.NewContext(ctx, logger), pod, networkNamespace, devices); err != nil {
+		return fmt.Errorf("failed to move RDMA devices: %w", err)
 	}
 
+	if len(networkDevicesData) > 0 {
+		p.trackAttachedPod(pod)
+	}
 	p.networkDeviceDataUpdateChan <- networkDevicesData
 	return nil
 }
 
-// StopPodSandbox runs the CNI DEL operation for each device in the devices list.
+// StopPodSandbox moves the RDMA devices of the VFs back to the host network namespace and runs
+// the CNI DEL operation for each device in the devices list.
 func (p *Plugin) StopPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
 	logger := klog.FromContext(ctx).WithName("NRI StopPodSandbox")
+	if p.containersOnly {
+		return nil
+	}
 	logger.Info("StopPodSandbox", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
 
+	p.inflight.Track()
+	defer p.inflight.End()
+
 	devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
 	if !found {
 		logger.Info("No prepared devices found for pod", "pod.UID", pod.Uid)
@@ -147,13 +245,67 @@ func (p *Plugin) StopPodSandbox(ctx context.Context, pod *api.PodSandbox) error
 		return fmt.Errorf("error getting network namespace for pod '%s' in namespace '%s'", pod.Name, pod.Namespace)
 	}
 
+	p.restoreRDMADevices(klog.NewContext(ctx, logger), pod, networkNamespace, devices)
+	// bonds go first, the VFs they bond are their links
+	for _, device := range devices {
+		if !device.AttachesNetwork() {
+			continue
+		}
+		if err := p.detachBond(ctx, pod, networkNamespace, device); err != nil {
+			logger.Error(err, "Failed to detach bond", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+			return fmt.Errorf("error CNI.DetachNetwork for pod '%s' (uid: %s) in namespace '%s': %v", pod.Name, pod.Uid, pod.Namespace, err)
+		}
+	}
 	for _, device := range devices {
+		if !device.AttachesNetwork() {
+			continue
+		}
 		logger.Info("Detaching network", "device", device)
 		err := p.cniRuntime.DetachNetwork(ctx, pod, networkNamespace, device)
 		if err != nil {
 			logger.Error(err, "Failed to detach network", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
 			return fmt.Errorf("error CNI.DetachNetwork for pod '%s' (uid: %s) in namespace '%s': %v", pod.Name, pod.Uid, pod.Namespace, err)
 		}
+		// the device is detached, RemovePodSandbox has nothing left to clean up for it
+		if device.CNISandboxID == pod.Id {
+			if err := p.podManager.SetCNIAttachment(k8stypes.UID(pod.Uid), device.ClaimNamespacedName.UID, device.Device.DeviceName, "", "", ""); err != nil {
+				logger.Error(err, "Failed to clear CNI attachment", "deviceName", device.Device.DeviceName, "pod.UID", pod.Uid)
+			}
+		}
+	}
+	p.untrackAttachedPod(pod.Uid)
+	return nil
+}
+
+// RemovePodSandbox runs the CNI DEL operation for the devices still attached to the removed
+// sandbox, e.g. when StopPodSandbox was missed while the container runtime restarted, and hands
+// the pod over to the stale pod callback so its claims and CDI specs are released once the pod
+// no longer exists.
+func (p *Plugin) RemovePodSandbox(ctx context.Context, pod *api.PodSandbox) error {
+	logger := klog.FromContext(ctx).WithName("NRI RemovePodSandbox")
+	if p.containersOnly {
+		return nil
+	}
+	logger.Info("RemovePodSandbox", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+
+	p.inflight.Track()
+	defer p.inflight.End()
+
+	podUID := k8stypes.UID(pod.Uid)
+	if _, found := p.podManager.GetDevicesByPodUID(podUID); !found {
+		logger.Info("No prepared devices found for pod", "pod.UID", pod.Uid)
+		return nil
+	}
+
+	p.untrackAttachedSandbox(pod)
+	if _, err := p.detachRecordedAttachments(ctx, podUID, pod); err != nil {
+		logger.Error(err, "Failed to detach networks of removed sandbox", "pod.UID", pod.Uid, "pod.Name", pod.Name, "pod.Namespace", pod.Namespace)
+		return fmt.Errorf("error detaching networks of removed sandbox of pod '%s' (uid: %s) in namespace '%s': %w", pod.Name, pod.Uid, pod.Namespace, err)
+	}
+	if p.stalePodCallback != nil {
+		if err := p.stalePodCallback(ctx, podUID); err != nil {
+			return fmt.Errorf("failed to release devices of pod %s: %w", pod.Uid, err)
+		}
 	}
 	return nil
 }
@@ -196,12 +348,12 @@ func (p *Plugin) updateNetworkDeviceData(ctx context.Context, networkDataChanStr
 			}
 			claim.Status.Devices[idx].NetworkData = networkDataChanStruct.NetworkDeviceData
 
-			// Build combined Data: { vfConfig, cniConfig, cniResult }
-			combined := map[string]interface{}{
-				"vfConfig":  networkDataChanStruct.PreparedDevice.Config,
-				"cniConfig": networkDataChanStruct.CNIConfig,
-				"cniResult": networkDataChanStruct.CNIResult,
-			}
+			// Build combined Data: { ifName, vfConfig, cniConfig, cniResult, pfName, vfID, boundDriver }
+			combined := networkDataChanStruct.PreparedDevice.StatusData()
+			combined["ifName"] = ifNameForDevice(networkDataChanStruct)
+			combined["vfConfig"] = networkDataChanStruct.PreparedDevice.Config
+			combined["cniConfig"] = networkDataChanStruct.CNIConfig
+			combined["cniResult"] = networkDataChanStruct.CNIResult
 			raw, err := json.Marshal(combined)
 			if err != nil {
 				logger.V(2).Info("Failed to marshal combined Data, skipping Data update", "error", err.Error())
@@ -218,6 +370,15 @@ func (p *Plugin) updateNetworkDeviceData(ctx context.Context, networkDataChanStr
 	}
 }
 
+// ifNameForDevice returns the final interface name of a device inside the pod, as reported by CNI,
+// falling back to the interface name requested at prepare time.
+func ifNameForDevice(networkDataChanStruct *types.NetworkDataChanStruct) string {
+	if networkDataChanStruct.NetworkDeviceData != nil && networkDataChanStruct.NetworkDeviceData.InterfaceName != "" {
+		return networkDataChanStruct.NetworkDeviceData.InterfaceName
+	}
+	return networkDataChanStruct.PreparedDevice.IfName
+}
+
 // updateClaimNetworkDataWithRetry updates the network device data for a claim with retries.
 func (p *Plugin) updateClaimNetworkDataWithRetry(ctx context.Context, claim *resourceapi.ResourceClaim) error {
 	logger := klog.FromContext(ctx).WithName("updateClaimNetworkDataWithRetry")
@@ -3,6 +3,9 @@ package nri
 import (
 	"context"
 	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -10,9 +13,24 @@ import (
 	"go.uber.org/mock/gomock"
 
 	"github.com/containerd/nri/pkg/api"
+	"github.com/containerd/nri/pkg/stub"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	resourcev1 "k8s.io/api/resource/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
 	cnimock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
@@ -81,12 +99,54 @@ var _ = Describe("NRI Plugin", func() {
 
 		mockCNI.EXPECT().
 			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
-			Return(nil, map[string]interface{}{"dummy": true}, nil)
+			Return(&cni.AttachResult{CNIResult: map[string]interface{}{"dummy": true}}, nil)
 
 		// The goroutine uses a channel to update claim status; we don't rely on it here
 		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
 	})
 
+	It("records the CNI netconf and result of attached devices", func() {
+		prepared := types.PreparedDevices{
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "dev-1"},
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+				IfName:              "vfnet0",
+				NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+				PciAddress:          "0000:00:00.1",
+				PodUID:              pod.Uid,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+
+		mockCNI.EXPECT().
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			Return(&cni.AttachResult{NetConf: `{"type":"sriov","name":"net1","deviceID":"0000:00:00.1"}`, RawCNIResult: `{"cniVersion":"1.0.0"}`}, nil)
+
+		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+
+		devices, found := podManager.Get(k8stypes.UID(pod.Uid), "claim-1")
+		Expect(found).To(BeTrue())
+		Expect(devices[0].CNINetConf).To(ContainSubstring(`"deviceID":"0000:00:00.1"`))
+		Expect(devices[0].CNIResult).To(Equal(`{"cniVersion":"1.0.0"}`))
+	})
+
+	It("skips network attachment of devices of shared claims", func() {
+		prepared := types.PreparedDevices{
+			&types.PreparedDevice{
+				IfName:             "vfnet0",
+				NetAttachDefConfig: `{"type":"sriov","name":"net1"}`,
+				PciAddress:         "0000:00:00.1",
+				PodUID:             pod.Uid,
+				Shared:             true,
+			},
+		}
+		Expect(podManager.SetShared([]k8stypes.UID{k8stypes.UID(pod.Uid), "uid-2"}, k8stypes.UID("claim-1"), prepared)).To(Succeed())
+
+		// no AttachNetwork/DetachNetwork expectations: the mock fails on any call
+		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+		Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+	})
+
 	It("returns error when CNI attach fails", func() {
 		prepared := types.PreparedDevices{
 			&types.PreparedDevice{
@@ -100,12 +160,208 @@ var _ = Describe("NRI Plugin", func() {
 
 		mockCNI.EXPECT().
 			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
-			Return(nil, nil, errors.New("boom"))
+			Return(nil, errors.New("boom"))
 
 		err := plugin.RunPodSandbox(ctx, pod)
 		Expect(err).To(HaveOccurred())
 	})
 
+	It("attaches the devices of a pod concurrently within the worker limit", func() {
+		plugin.attachWorkers = 2
+		prepared := types.PreparedDevices{}
+		for _, name := range []string{"dev-1", "dev-2", "dev-3", "dev-4"} {
+			prepared = append(prepared, &types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: name},
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+				NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+				PodUID:              pod.Uid,
+			})
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+
+		var (
+			mu       sync.Mutex
+			inFlight int
+			maxSeen  int
+		)
+		mockCNI.EXPECT().
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ *api.PodSandbox, _ string, device *types.PreparedDevice) (*cni.AttachResult, error) {
+				mu.Lock()
+				inFlight++
+				maxSeen = max(maxSeen, inFlight)
+				mu.Unlock()
+				time.Sleep(20 * time.Millisecond)
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				return &cni.AttachResult{NetworkDeviceData: &resourcev1.NetworkDeviceData{InterfaceName: device.Device.DeviceName}}, nil
+			}).Times(4)
+
+		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+		Expect(maxSeen).To(Equal(2))
+
+		var networkDevicesData types.NetworkDataChanStructList
+		Expect(plugin.networkDeviceDataUpdateChan).To(Receive(&networkDevicesData))
+		Expect(networkDevicesData).To(HaveLen(4))
+		for i, data := range networkDevicesData {
+			Expect(data.PreparedDevice).To(Equal(prepared[i]))
+			Expect(data.NetworkDeviceData.InterfaceName).To(Equal(prepared[i].Device.DeviceName))
+		}
+	})
+
+	It("records the attached devices when another device of the pod fails to attach", func() {
+		plugin.attachWorkers = 2
+		prepared := types.PreparedDevices{
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "dev-1"},
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+				NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+				PodUID:              pod.Uid,
+			},
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "dev-2"},
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+				NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+				PodUID:              pod.Uid,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+
+		mockCNI.EXPECT().
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+			Return(&cni.AttachResult{NetConf: `{"type":"sriov"}`}, nil)
+		mockCNI.EXPECT().
+			AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[1]).
+			Return(nil, errors.New("boom"))
+
+		err := plugin.RunPodSandbox(ctx, pod)
+		Expect(err).To(MatchError(ContainSubstring("device dev-2: boom")))
+
+		devices, found := podManager.Get(k8stypes.UID(pod.Uid), "claim-1")
+		Expect(found).To(BeTrue())
+		Expect(devices[0].CNISandboxID).To(Equal(pod.Id))
+		Expect(devices[1].CNISandboxID).To(BeEmpty())
+	})
+
+	Context("with bonded devices", func() {
+		var prepared types.PreparedDevices
+
+		isBond := gomock.Cond(func(device *types.PreparedDevice) bool { return device.IfName == "bond0" })
+
+		BeforeEach(func() {
+			prepared = types.PreparedDevices{
+				&types.PreparedDevice{
+					Device:              drapbv1.Device{DeviceName: "dev-1"},
+					ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+					IfName:              "vfnet0",
+					NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+					PodUID:              pod.Uid,
+					BondIfName:          "bond0",
+					BondNetConf:         `{"type":"bond","name":"net1"}`,
+				},
+				&types.PreparedDevice{
+					Device:              drapbv1.Device{DeviceName: "dev-2"},
+					ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+					IfName:              "vfnet1",
+					NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+					PodUID:              pod.Uid,
+					BondIfName:          "bond0",
+				},
+			}
+			Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+		})
+
+		It("attaches the bond after its devices and detaches it before them", func() {
+			vfAttach := mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", gomock.Not(isBond)).
+				Return(&cni.AttachResult{}, nil).Times(2)
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", isBond).
+				Return(&cni.AttachResult{NetConf: `{"type":"bond"}`, RawCNIResult: `{"cniVersion":"1.0.0"}`}, nil).After(vfAttach)
+
+			Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+
+			devices, found := podManager.Get(k8stypes.UID(pod.Uid), "claim-1")
+			Expect(found).To(BeTrue())
+			Expect(devices[0].BondCNINetConf).To(Equal(`{"type":"bond"}`))
+			Expect(devices[0].BondCNIResult).To(Equal(`{"cniVersion":"1.0.0"}`))
+
+			var networkDevicesData types.NetworkDataChanStructList
+			Expect(plugin.networkDeviceDataUpdateChan).To(Receive(&networkDevicesData))
+			Expect(networkDevicesData).To(HaveLen(3))
+			Expect(networkDevicesData[2].PreparedDevice.IfName).To(Equal("bond0"))
+			Expect(networkDevicesData[2].PreparedDevice.Device.DeviceName).To(Equal("dev-1"))
+
+			bondDetach := mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", isBond).Return(nil)
+			mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", gomock.Not(isBond)).
+				Return(nil).Times(2).After(bondDetach)
+
+			Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+			devices, _ = podManager.Get(k8stypes.UID(pod.Uid), "claim-1")
+			Expect(devices[0].BondCNINetConf).To(BeEmpty())
+			Expect(devices[0].BondCNIResult).To(BeEmpty())
+		})
+
+		It("does not attach the bond when one of its devices fails to attach", func() {
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(&cni.AttachResult{}, nil)
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[1]).Return(nil, errors.New("boom"))
+
+			err := plugin.RunPodSandbox(ctx, pod)
+			Expect(err).To(MatchError(ContainSubstring("bond bond0 of device dev-1 not attached")))
+		})
+	})
+
+	Context("with transient CNI attach failures", func() {
+		var (
+			prepared    types.PreparedDevices
+			origBackoff wait.Backoff
+		)
+
+		BeforeEach(func() {
+			origBackoff = consts.Backoff
+			consts.Backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+			prepared = types.PreparedDevices{
+				&types.PreparedDevice{
+					Device:              drapbv1.Device{DeviceName: "dev-1"},
+					ClaimNamespacedName: kubeletplugin.NamespacedObject{UID: "claim-1"},
+					NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+					PodUID:              pod.Uid,
+				},
+			}
+			Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			consts.Backoff = origBackoff
+		})
+
+		It("cleans up and retries retryable errors", func() {
+			gomock.InOrder(
+				mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+					Return(nil, &cnitypes.Error{Code: cnitypes.ErrTryAgainLater, Msg: "lease held"}),
+				mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil),
+				mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+					Return(&cni.AttachResult{}, nil),
+			)
+
+			Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+		})
+
+		It("does not retry other errors", func() {
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+				Return(nil, errors.New("invalid netconf"))
+
+			Expect(plugin.RunPodSandbox(ctx, pod)).To(MatchError(ContainSubstring("invalid netconf")))
+		})
+
+		It("fails with the plugin error once the retries are exhausted", func() {
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).
+				Return(nil, errors.New("netlink: device or resource busy")).Times(3)
+			mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil).Times(3)
+
+			Expect(plugin.RunPodSandbox(ctx, pod)).To(MatchError(ContainSubstring("device or resource busy")))
+		})
+	})
+
 	It("detaches networks on StopPodSandbox", func() {
 		prepared := types.PreparedDevices{
 			&types.PreparedDevice{
@@ -124,6 +380,72 @@ var _ = Describe("NRI Plugin", func() {
 		Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
 	})
 
+	Context("with RDMA devices", func() {
+		var (
+			mockHost    *hostmock.MockInterface
+			origHelpers host.Interface
+			prepared    types.PreparedDevices
+		)
+
+		BeforeEach(func() {
+			mockHost = hostmock.NewMockInterface(ctrl)
+			_ = host.GetHelpers()
+			origHelpers = host.Helpers
+			host.Helpers = mockHost
+
+			prepared = types.PreparedDevices{
+				&types.PreparedDevice{
+					Device:             drapbv1.Device{DeviceName: "dev-1"},
+					IfName:             "vfnet0",
+					NetAttachDefConfig: `{"type":"sriov","name":"net1"}`,
+					PciAddress:         "0000:00:00.1",
+					PodUID:             pod.Uid,
+					RDMADevice:         "mlx5_2",
+				},
+			}
+			Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(&cni.AttachResult{}, nil).AnyTimes()
+			mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil).AnyTimes()
+		})
+
+		AfterEach(func() {
+			host.Helpers = origHelpers
+		})
+
+		It("moves the RDMA devices to the pod network namespace and back in exclusive mode", func() {
+			mockHost.EXPECT().GetRDMANetnsMode().Return(host.RDMANetnsModeExclusive, nil).Times(2)
+			gomock.InOrder(
+				mockHost.EXPECT().MoveRDMADeviceToNetns("mlx5_2", "/proc/123/ns/net").Return(nil),
+				mockHost.EXPECT().MoveRDMADeviceFromNetns("mlx5_2", "/proc/123/ns/net").Return(nil),
+			)
+
+			Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+			Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+		})
+
+		It("leaves the RDMA devices alone in shared mode", func() {
+			mockHost.EXPECT().GetRDMANetnsMode().Return(host.RDMANetnsModeShared, nil).Times(2)
+
+			Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+			Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+		})
+
+		It("fails the sandbox when an RDMA device cannot be moved", func() {
+			mockHost.EXPECT().GetRDMANetnsMode().Return(host.RDMANetnsModeExclusive, nil)
+			mockHost.EXPECT().MoveRDMADeviceToNetns("mlx5_2", "/proc/123/ns/net").Return(errors.New("device busy"))
+
+			err := plugin.RunPodSandbox(ctx, pod)
+			Expect(err).To(MatchError(ContainSubstring("failed to move RDMA devices")))
+		})
+
+		It("does not fail the sandbox stop when an RDMA device cannot be moved back", func() {
+			mockHost.EXPECT().GetRDMANetnsMode().Return(host.RDMANetnsModeExclusive, nil)
+			mockHost.EXPECT().MoveRDMADeviceFromNetns("mlx5_2", "/proc/123/ns/net").Return(errors.New("netns gone"))
+
+			Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+		})
+	})
+
 	It("handles pod without network namespace in RunPodSandbox", func() {
 		prepared := types.PreparedDevices{
 			&types.PreparedDevice{
@@ -183,6 +505,573 @@ var _ = Describe("NRI Plugin", func() {
 	})
 })
 
+var _ = Describe("NRI Network Checks", func() {
+	var (
+		ctrl       *gomock.Controller
+		mockCNI    *cnimock.MockInterface
+		podManager *podmanager.PodManager
+		recorder   *record.FakeRecorder
+		plugin     *Plugin
+		ctx        context.Context
+		pod        *api.PodSandbox
+		prepared   types.PreparedDevices
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockCNI = cnimock.NewMockInterface(ctrl)
+		ctx = context.Background()
+
+		var err error
+		podManager, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+
+		recorder = record.NewFakeRecorder(10)
+		plugin = &Plugin{
+			podManager:                  podManager,
+			cniRuntime:                  mockCNI,
+			networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+			eventRecorder:               recorder,
+		}
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-id",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "uid-1",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+			},
+		}
+		prepared = types.PreparedDevices{
+			&types.PreparedDevice{
+				IfName:              "vfnet0",
+				NetAttachDefConfig:  `{"type":"sriov","name":"net1"}`,
+				PciAddress:          "0000:00:00.1",
+				PodUID:              pod.Uid,
+				ClaimNamespacedName: kubeletplugin.NamespacedObject{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"}},
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("tracks pods between RunPodSandbox and StopPodSandbox", func() {
+		mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(&cni.AttachResult{}, nil)
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil)
+
+		Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+		Expect(plugin.listAttachedPods()).To(ConsistOf(pod))
+
+		Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+		Expect(plugin.listAttachedPods()).To(BeEmpty())
+	})
+
+	It("does not emit events when checks succeed", func() {
+		plugin.trackAttachedPod(pod)
+		mockCNI.EXPECT().CheckNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(nil)
+
+		Expect(plugin.CheckNetworks(ctx)).To(Equal(0))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("emits a warning event on the pod when a check fails", func() {
+		plugin.trackAttachedPod(pod)
+		mockCNI.EXPECT().CheckNetwork(gomock.Any(), pod, "/proc/123/ns/net", prepared[0]).Return(errors.New("interface vfnet0 not found"))
+
+		Expect(plugin.CheckNetworks(ctx)).To(Equal(1))
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(ContainSubstring("Warning NetworkCheckFailed"))
+		Expect(event).To(ContainSubstring("vfnet0"))
+		Expect(event).To(ContainSubstring("default/claim"))
+	})
+
+	It("skips devices of shared claims", func() {
+		prepared[0].Shared = true
+		plugin.trackAttachedPod(pod)
+
+		// no CheckNetwork expectation: the mock fails on any call
+		Expect(plugin.CheckNetworks(ctx)).To(Equal(0))
+	})
+
+	It("stops tracking pods whose devices were unprepared", func() {
+		plugin.trackAttachedPod(pod)
+		Expect(podManager.DeletePod(k8stypes.UID(pod.Uid))).To(Succeed())
+
+		Expect(plugin.CheckNetworks(ctx)).To(Equal(0))
+		Expect(plugin.listAttachedPods()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("NRI Synchronize", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockCNI     *cnimock.MockInterface
+		podManager  *podmanager.PodManager
+		plugin      *Plugin
+		ctx         context.Context
+		pod         *api.PodSandbox
+		stalePods   []k8stypes.UID
+		stalePodErr error
+	)
+
+	claimUID := k8stypes.UID("claim-1")
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockCNI = cnimock.NewMockInterface(ctrl)
+		ctx = context.Background()
+
+		var err error
+		podManager, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+
+		stalePods = nil
+		stalePodErr = nil
+		plugin = &Plugin{
+			podManager:                  podManager,
+			cniRuntime:                  mockCNI,
+			networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+		}
+		plugin.SetStalePodCallback(func(_ context.Context, podUID k8stypes.UID) error {
+			stalePods = append(stalePods, podUID)
+			return stalePodErr
+		})
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-id",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "uid-1",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	prepare := func(podUID string, devices ...*types.PreparedDevice) {
+		for _, device := range devices {
+			device.PodUID = podUID
+			device.ClaimNamespacedName = kubeletplugin.NamespacedObject{
+				NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"},
+				UID:            claimUID,
+			}
+		}
+		Expect(podManager.Set(k8stypes.UID(podUID), claimUID, devices)).To(Succeed())
+	}
+
+	It("detaches recorded attachments of pods without a running sandbox", func() {
+		device := &types.PreparedDevice{
+			Device:       drapbv1.Device{DeviceName: "dev-1"},
+			IfName:       "vfnet0",
+			CNISandboxID: "old-sandbox",
+			CNINetConf:   `{"type":"sriov"}`,
+			CNIResult:    `{"cniVersion":"1.0.0"}`,
+		}
+		prepare("uid-gone", device)
+
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), gomock.Any(), "", device).DoAndReturn(
+			func(_ context.Context, sandbox *api.PodSandbox, _ string, _ *types.PreparedDevice) error {
+				Expect(sandbox.Id).To(Equal("old-sandbox"))
+				Expect(sandbox.Uid).To(Equal("uid-gone"))
+				Expect(sandbox.Namespace).To(Equal("default"))
+				return nil
+			})
+
+		updates, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updates).To(BeEmpty())
+		Expect(stalePods).To(ConsistOf(k8stypes.UID("uid-gone")))
+
+		devices, found := podManager.Get(k8stypes.UID("uid-gone"), claimUID)
+		Expect(found).To(BeTrue())
+		Expect(devices[0].CNISandboxID).To(BeEmpty())
+		Expect(devices[0].CNINetConf).To(BeEmpty())
+		Expect(devices[0].CNIResult).To(BeEmpty())
+	})
+
+	It("keeps attachments of running pods and tracks them for network checks", func() {
+		prepare(pod.Uid, &types.PreparedDevice{
+			Device:       drapbv1.Device{DeviceName: "dev-1"},
+			CNISandboxID: pod.Id,
+			CNINetConf:   `{"type":"sriov"}`,
+		})
+
+		_, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stalePods).To(BeEmpty())
+		Expect(plugin.listAttachedPods()).To(ConsistOf(pod))
+	})
+
+	It("stops tracking sandboxes that stopped while the plugin was disconnected", func() {
+		plugin.trackAttachedPod(pod)
+		plugin.trackAttachedPod(&api.PodSandbox{Id: "sandbox-2", Uid: "uid-2"})
+
+		_, err := plugin.Synchronize(ctx, []*api.PodSandbox{pod}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plugin.listAttachedPods()).To(ConsistOf(pod))
+	})
+
+	It("leaves pods without recorded attachments alone", func() {
+		prepare("uid-pending", &types.PreparedDevice{Device: drapbv1.Device{DeviceName: "dev-1"}})
+		prepare("uid-shared", &types.PreparedDevice{Device: drapbv1.Device{DeviceName: "dev-2"}, Shared: true, CNISandboxID: "other"})
+
+		_, err := plugin.Synchronize(ctx, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stalePods).To(BeEmpty())
+	})
+
+	It("keeps the attachment and does not restore the devices when CNI DEL fails", func() {
+		prepare("uid-gone", &types.PreparedDevice{
+			Device:       drapbv1.Device{DeviceName: "dev-1"},
+			CNISandboxID: "old-sandbox",
+		})
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), gomock.Any(), "", gomock.Any()).Return(errors.New("del failed"))
+
+		_, err := plugin.Synchronize(ctx, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stalePods).To(BeEmpty())
+
+		devices, _ := podManager.Get(k8stypes.UID("uid-gone"), claimUID)
+		Expect(devices[0].CNISandboxID).To(Equal("old-sandbox"))
+	})
+
+	It("does not fail synchronization when restoring the devices fails", func() {
+		prepare("uid-gone", &types.PreparedDevice{
+			Device:       drapbv1.Device{DeviceName: "dev-1"},
+			CNISandboxID: "old-sandbox",
+		})
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), gomock.Any(), "", gomock.Any()).Return(nil)
+		stalePodErr = errors.New("unprepare failed")
+
+		_, err := plugin.Synchronize(ctx, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stalePods).To(ConsistOf(k8stypes.UID("uid-gone")))
+	})
+})
+
+var _ = Describe("NRI RemovePodSandbox", func() {
+	var (
+		ctrl       *gomock.Controller
+		mockCNI    *cnimock.MockInterface
+		podManager *podmanager.PodManager
+		plugin     *Plugin
+		ctx        context.Context
+		pod        *api.PodSandbox
+		stalePods  []k8stypes.UID
+		device     *types.PreparedDevice
+	)
+
+	claimUID := k8stypes.UID("claim-1")
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockCNI = cnimock.NewMockInterface(ctrl)
+		ctx = context.Background()
+
+		var err error
+		podManager, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+
+		stalePods = nil
+		plugin = &Plugin{
+			podManager:                  podManager,
+			cniRuntime:                  mockCNI,
+			networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+		}
+		plugin.SetStalePodCallback(func(_ context.Context, podUID k8stypes.UID) error {
+			stalePods = append(stalePods, podUID)
+			return nil
+		})
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-id",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "uid-1",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+			},
+		}
+		device = &types.PreparedDevice{
+			Device:       drapbv1.Device{DeviceName: "dev-1"},
+			IfName:       "vfnet0",
+			PodUID:       pod.Uid,
+			CNISandboxID: pod.Id,
+			CNINetConf:   `{"type":"sriov"}`,
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{
+				NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"},
+				UID:            claimUID,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), claimUID, types.PreparedDevices{device})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("detaches networks left attached when StopPodSandbox was missed", func() {
+		plugin.trackAttachedPod(pod)
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "", device).Return(nil)
+
+		Expect(plugin.RemovePodSandbox(ctx, pod)).To(Succeed())
+		Expect(stalePods).To(ConsistOf(k8stypes.UID(pod.Uid)))
+		Expect(plugin.listAttachedPods()).To(BeEmpty())
+
+		devices, _ := podManager.Get(k8stypes.UID(pod.Uid), claimUID)
+		Expect(devices[0].CNISandboxID).To(BeEmpty())
+	})
+
+	It("only releases the pod when StopPodSandbox already detached its networks", func() {
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "/proc/123/ns/net", device).Return(nil)
+		Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+
+		// no further DetachNetwork expectation: the mock fails on any call
+		Expect(plugin.RemovePodSandbox(ctx, pod)).To(Succeed())
+		Expect(stalePods).To(ConsistOf(k8stypes.UID(pod.Uid)))
+	})
+
+	It("leaves the attachments of a newer sandbox of the pod alone", func() {
+		newSandbox := &api.PodSandbox{Id: "new-sandbox-id", Uid: pod.Uid, Name: pod.Name, Namespace: pod.Namespace}
+		Expect(podManager.SetCNIAttachment(k8stypes.UID(pod.Uid), claimUID, "dev-1", newSandbox.Id, `{"type":"sriov"}`, "")).To(Succeed())
+		plugin.trackAttachedPod(newSandbox)
+
+		Expect(plugin.RemovePodSandbox(ctx, pod)).To(Succeed())
+		Expect(plugin.listAttachedPods()).To(ConsistOf(newSandbox))
+
+		devices, _ := podManager.Get(k8stypes.UID(pod.Uid), claimUID)
+		Expect(devices[0].CNISandboxID).To(Equal(newSandbox.Id))
+	})
+
+	It("does not release the pod when CNI DEL fails", func() {
+		mockCNI.EXPECT().DetachNetwork(gomock.Any(), pod, "", device).Return(errors.New("del failed"))
+
+		Expect(plugin.RemovePodSandbox(ctx, pod)).NotTo(Succeed())
+		Expect(stalePods).To(BeEmpty())
+	})
+})
+
+var _ = Describe("NRI CreateContainer", func() {
+	var (
+		mockCtrl    *gomock.Controller
+		mockHost    *hostmock.MockInterface
+		origHelpers host.Interface
+		podManager  *podmanager.PodManager
+		plugin      *Plugin
+		recorder    *record.FakeRecorder
+		ctx         context.Context
+		pod         *api.PodSandbox
+	)
+
+	container := func(cpus string, cdiDevices ...string) *api.Container {
+		ctr := &api.Container{
+			Name: "dpdk",
+			Linux: &api.LinuxContainer{
+				Resources: &api.LinuxResources{Cpu: &api.LinuxCPU{Cpus: cpus}},
+			},
+		}
+		for _, name := range cdiDevices {
+			ctr.CDIDevices = append(ctr.CDIDevices, &api.CDIDevice{Name: name})
+		}
+		return ctr
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(mockCtrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+		ctx = context.Background()
+
+		var err error
+		podManager, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+
+		recorder = record.NewFakeRecorder(10)
+		plugin = &Plugin{
+			podManager:    podManager,
+			eventRecorder: recorder,
+			numaAlignment: consts.NUMAAlignmentWarn,
+		}
+		pod = &api.PodSandbox{Id: "sandbox-id", Name: "pod-name", Namespace: "default", Uid: "uid-1"}
+
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), "claim-1", types.PreparedDevices{
+			&types.PreparedDevice{
+				Device:     drapbv1.Device{DeviceName: "dev-1", CDIDeviceIDs: []string{consts.DriverName + "/vf=claim-1-dev-1"}},
+				PciAddress: "0000:00:00.1",
+				PodUID:     pod.Uid,
+			},
+		})).To(Succeed())
+		mockHost.EXPECT().GetNumaNode("0000:00:00.1").Return("1", nil).AnyTimes()
+		mockHost.EXPECT().GetNumaNodeCPUs("1").Return("8-15", nil).AnyTimes()
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		mockCtrl.Finish()
+	})
+
+	It("does nothing when NUMA alignment is disabled", func() {
+		plugin.numaAlignment = consts.NUMAAlignmentNone
+		adjustment, updates, err := plugin.CreateContainer(ctx, pod, container("0-3"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment).To(BeNil())
+		Expect(updates).To(BeNil())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("leaves aligned containers alone", func() {
+		adjustment, _, err := plugin.CreateContainer(ctx, pod, container("8-11"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment).To(BeNil())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("warns about misaligned containers", func() {
+		adjustment, _, err := plugin.CreateContainer(ctx, pod, container("0-3"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment).To(BeNil())
+		Expect(recorder.Events).To(Receive(ContainSubstring("NUMAMisaligned")))
+	})
+
+	It("pins containers to the CPUs and memory of the NUMA node of their devices", func() {
+		plugin.numaAlignment = consts.NUMAAlignmentPin
+		adjustment, _, err := plugin.CreateContainer(ctx, pod, container("4-11"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment.GetLinux().GetResources().GetCpu().GetCpus()).To(Equal("8-11"))
+		Expect(adjustment.GetLinux().GetResources().GetCpu().GetMems()).To(Equal("1"))
+
+		// a container without cpuset gets all the CPUs of the NUMA node
+		adjustment, _, err = plugin.CreateContainer(ctx, pod, container(""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment.GetLinux().GetResources().GetCpu().GetCpus()).To(Equal("8-15"))
+	})
+
+	It("warns instead of pinning when the container has no CPU on the NUMA node", func() {
+		plugin.numaAlignment = consts.NUMAAlignmentPin
+		adjustment, _, err := plugin.CreateContainer(ctx, pod, container("0-3"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment).To(BeNil())
+		Expect(recorder.Events).To(Receive(ContainSubstring("NUMAMisaligned")))
+	})
+
+	It("ignores containers not using the devices of the pod", func() {
+		plugin.numaAlignment = consts.NUMAAlignmentPin
+		adjustment, _, err := plugin.CreateContainer(ctx, pod, container("0-3", "vendor.com/gpu=gpu0"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment).To(BeNil())
+
+		adjustment, _, err = plugin.CreateContainer(ctx, pod, container("0-11", consts.DriverName+"/vf=claim-1-dev-1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adjustment.GetLinux().GetResources().GetCpu().GetCpus()).To(Equal("8-11"))
+	})
+
+	Context("with containers using different claims", func() {
+		podSpecDevice := consts.DriverName + "/vf=uid-1"
+
+		BeforeEach(func() {
+			plugin.numaAlignment = consts.NUMAAlignmentNone
+			Expect(podManager.Set(k8stypes.UID(pod.Uid), "claim-2", types.PreparedDevices{
+				&types.PreparedDevice{
+					Device:     drapbv1.Device{DeviceName: "dev-2", CDIDeviceIDs: []string{consts.DriverName + "/vf=claim-2-dev-2", podSpecDevice}},
+					PciAddress: "0000:00:00.2",
+					PodUID:     pod.Uid,
+				},
+			})).To(Succeed())
+			mockHost.EXPECT().GetNumaNode("0000:00:00.2").Return("0", nil).AnyTimes()
+			mockHost.EXPECT().GetNumaNodeCPUs("0").Return("0-7", nil).AnyTimes()
+		})
+
+		It("narrows the PCI addresses of the pod to the devices of the container", func() {
+			adjustment, _, err := plugin.CreateContainer(ctx, pod, container("0-3", consts.DriverName+"/vf=claim-2-dev-2", podSpecDevice))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adjustment.GetEnv()).To(ConsistOf(&api.KeyValue{Key: "SRIOVNETWORK_PCI_ADDRESSES", Value: "0000:00:00.2"}))
+		})
+
+		It("keeps the PCI addresses of a container using all the devices of the pod", func() {
+			adjustment, _, err := plugin.CreateContainer(ctx, pod,
+				container("0-3", consts.DriverName+"/vf=claim-1-dev-1", consts.DriverName+"/vf=claim-2-dev-2", podSpecDevice))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adjustment).To(BeNil())
+		})
+
+		Context("with the CDI edits of the devices", func() {
+			var ctr *api.Container
+
+			BeforeEach(func() {
+				edits := func(name, deviceNode, mount string) *cdiapi.ContainerEdits {
+					return &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{
+						Env:         []string{"SRIOVNETWORK_VF_DEVICE_" + name + "=x", "SRIOVNETWORK_SHARED_ENV=x"},
+						DeviceNodes: []*cdispec.DeviceNode{{Path: deviceNode}},
+						Mounts:      []*cdispec.Mount{{ContainerPath: mount}, {ContainerPath: "/var/run/vhost-user"}},
+					}}
+				}
+				devices, _ := podManager.Get(k8stypes.UID(pod.Uid), "claim-1")
+				devices[0].ContainerEdits = edits("dev_1", "/dev/infiniband/uverbs1", "/mnt/dev-1")
+				Expect(podManager.Set(k8stypes.UID(pod.Uid), "claim-1", devices)).To(Succeed())
+				devices, _ = podManager.Get(k8stypes.UID(pod.Uid), "claim-2")
+				devices[0].ContainerEdits = edits("dev_2", "/dev/infiniband/uverbs2", "/mnt/dev-2")
+				Expect(podManager.Set(k8stypes.UID(pod.Uid), "claim-2", devices)).To(Succeed())
+
+				// the edits of the other device reached the container through another CDI device
+				ctr = container("0-3", consts.DriverName+"/vf=claim-2-dev-2", podSpecDevice)
+				ctr.Env = []string{"SRIOVNETWORK_VF_DEVICE_dev_1=x", "SRIOVNETWORK_VF_DEVICE_dev_2=x", "SRIOVNETWORK_SHARED_ENV=x", "PATH=/bin"}
+				ctr.Linux.Devices = []*api.LinuxDevice{{Path: "/dev/infiniband/uverbs1"}, {Path: "/dev/infiniband/uverbs2"}}
+				ctr.Mounts = []*api.Mount{{Destination: "/mnt/dev-1"}, {Destination: "/mnt/dev-2"}, {Destination: "/var/run/vhost-user"}}
+			})
+
+			expectNarrowed := func(adjustment *api.ContainerAdjustment) {
+				Expect(adjustment.GetEnv()).To(ConsistOf(
+					&api.KeyValue{Key: "SRIOVNETWORK_PCI_ADDRESSES", Value: "0000:00:00.2"},
+					&api.KeyValue{Key: api.MarkForRemoval("SRIOVNETWORK_VF_DEVICE_dev_1")},
+				))
+				Expect(adjustment.GetLinux().GetDevices()).To(ConsistOf(&api.LinuxDevice{Path: api.MarkForRemoval("/dev/infiniband/uverbs1")}))
+				Expect(adjustment.GetMounts()).To(ConsistOf(&api.Mount{Destination: api.MarkForRemoval("/mnt/dev-1")}))
+			}
+
+			It("removes the environment variables, device nodes and mounts of the other devices", func() {
+				adjustment, _, err := plugin.CreateContainer(ctx, pod, ctr)
+				Expect(err).ToNot(HaveOccurred())
+				expectNarrowed(adjustment)
+			})
+
+			It("narrows the containers without attaching networks in MULTUS mode", func() {
+				plugin.containersOnly = true
+				// no CNI runtime: attaching the networks would fail
+				Expect(plugin.RunPodSandbox(ctx, pod)).To(Succeed())
+
+				adjustment, _, err := plugin.CreateContainer(ctx, pod, ctr)
+				Expect(err).ToNot(HaveOccurred())
+				expectNarrowed(adjustment)
+
+				Expect(plugin.StopPodSandbox(ctx, pod)).To(Succeed())
+				Expect(plugin.RemovePodSandbox(ctx, pod)).To(Succeed())
+				_, found := podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+				Expect(found).To(BeTrue())
+			})
+		})
+
+		It("aligns the container with the NUMA nodes of its own devices only", func() {
+			plugin.numaAlignment = consts.NUMAAlignmentPin
+			adjustment, _, err := plugin.CreateContainer(ctx, pod, container("0-11", consts.DriverName+"/vf=claim-2-dev-2", podSpecDevice))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adjustment.GetLinux().GetResources().GetCpu().GetCpus()).To(Equal("0-7"))
+			Expect(adjustment.GetLinux().GetResources().GetCpu().GetMems()).To(Equal("0"))
+			Expect(adjustment.GetEnv()).To(HaveLen(1))
+		})
+	})
+})
+
 var _ = Describe("NRI Plugin Creation", func() {
 	It("creates a new NRI plugin successfully", func() {
 		flags := &types.Flags{
@@ -212,6 +1101,8 @@ var _ = Describe("NRI Plugin Creation", func() {
 			Expect(plugin.cniRuntime).To(Equal(mockCNI))
 			Expect(plugin.interfacePrefix).To(Equal("net"))
 			Expect(plugin.networkDeviceDataUpdateChan).ToNot(BeNil())
+			Expect(plugin.registrationTimeout).To(Equal(flags.NRIRegistrationTimeout))
+			Expect(plugin.requestTimeout).To(Equal(flags.NRIRequestTimeout))
 		} else {
 			// Expected to fail without NRI runtime - could fail for various reasons
 			// (e.g., invalid plugin name in test, no NRI socket, etc.)
@@ -220,6 +1111,150 @@ var _ = Describe("NRI Plugin Creation", func() {
 	})
 })
 
+// fakeStub reports the NRI timeouts configured by the runtime and simulates the connection to it:
+// Start fails with startErr and Wait returns once closed is closed.
+type fakeStub struct {
+	stub.Stub
+	registrationTimeout time.Duration
+	requestTimeout      time.Duration
+	startErr            error
+	closed              chan struct{}
+	stops               atomic.Int32
+}
+
+func (s *fakeStub) RegistrationTimeout() time.Duration { return s.registrationTimeout }
+func (s *fakeStub) RequestTimeout() time.Duration      { return s.requestTimeout }
+func (s *fakeStub) Start(context.Context) error        { return s.startErr }
+func (s *fakeStub) Stop()                              { s.stops.Add(1) }
+func (s *fakeStub) Wait()                              { <-s.closed }
+
+var _ = Describe("NRI reconnection", func() {
+	var (
+		ctx         context.Context
+		cancel      context.CancelFunc
+		plugin      *Plugin
+		first       *fakeStub
+		newStubs    []*fakeStub
+		created     atomic.Int32
+		done        chan struct{}
+		origBackoff wait.Backoff
+	)
+
+	BeforeEach(func() {
+		origBackoff = reconnectBackoff
+		reconnectBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: math.MaxInt32}
+		ctx, cancel = context.WithCancel(context.Background())
+
+		first = &fakeStub{closed: make(chan struct{})}
+		newStubs = []*fakeStub{
+			{startErr: errors.New("connection refused"), closed: make(chan struct{})},
+			{closed: make(chan struct{})},
+		}
+		created.Store(0)
+		plugin = &Plugin{
+			stub:                        first,
+			networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+		}
+		plugin.newStub = func() (stub.Stub, error) {
+			return newStubs[created.Add(1)-1], nil
+		}
+
+		done = make(chan struct{})
+		go func() {
+			plugin.reconnectRunner(ctx)
+			close(done)
+		}()
+	})
+
+	AfterEach(func() {
+		cancel()
+		for _, s := range newStubs {
+			select {
+			case <-s.closed:
+			default:
+				close(s.closed)
+			}
+		}
+		Eventually(done).Should(BeClosed())
+		reconnectBackoff = origBackoff
+	})
+
+	It("registers a new stub once the connection is lost", func() {
+		close(first.closed)
+
+		Eventually(plugin.currentStub).Should(BeIdenticalTo(newStubs[1]))
+		Expect(created.Load()).To(Equal(int32(2)))
+		// the stub that failed to start is discarded
+		Expect(newStubs[0].stops.Load()).To(Equal(int32(1)))
+		Expect(plugin.isStopped()).To(BeFalse())
+	})
+
+	It("reports the connection to the healthcheck", func() {
+		Expect(plugin.Status(ctx)).To(MatchError("NRI plugin is not connected to the runtime"))
+		plugin.setConnected(true)
+		Expect(plugin.Status(ctx)).To(Succeed())
+
+		close(first.closed)
+		Eventually(plugin.currentStub).Should(BeIdenticalTo(newStubs[1]))
+		Expect(plugin.Status(ctx)).To(Succeed())
+	})
+
+	It("does not reconnect once stopped", func() {
+		plugin.Stop()
+		close(first.closed)
+
+		Eventually(done).Should(BeClosed())
+		Expect(created.Load()).To(BeZero())
+		Expect(first.stops.Load()).To(Equal(int32(1)))
+	})
+})
+
+var _ = Describe("NRI runtime timeouts", func() {
+	var (
+		messages []string
+		logger   logr.Logger
+	)
+
+	BeforeEach(func() {
+		messages = nil
+		logger = funcr.New(func(_, args string) { messages = append(messages, args) }, funcr.Options{})
+	})
+
+	It("warns when the runtime timeouts are shorter than required", func() {
+		plugin := &Plugin{
+			stub:                &fakeStub{registrationTimeout: 5 * time.Second, requestTimeout: 2 * time.Second},
+			registrationTimeout: 10 * time.Second,
+			requestTimeout:      time.Minute,
+		}
+		plugin.checkRuntimeTimeouts(logger)
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[0]).To(ContainSubstring("plugin_registration_timeout"))
+		Expect(messages[1]).To(ContainSubstring("plugin_request_timeout"))
+		Expect(messages[1]).To(ContainSubstring(`"runtimeTimeout"="2s"`))
+		for _, message := range messages {
+			Expect(message).To(ContainSubstring(`"error"=`))
+		}
+	})
+
+	It("does not warn when the runtime timeouts are long enough", func() {
+		plugin := &Plugin{
+			stub:                &fakeStub{registrationTimeout: 15 * time.Second, requestTimeout: time.Minute},
+			registrationTimeout: 10 * time.Second,
+			requestTimeout:      time.Minute,
+		}
+		plugin.checkRuntimeTimeouts(logger)
+		Expect(messages).To(BeEmpty())
+	})
+
+	It("skips the check of zero timeouts", func() {
+		plugin := &Plugin{
+			stub: &fakeStub{registrationTimeout: time.Second, requestTimeout: time.Second},
+		}
+		plugin.checkRuntimeTimeouts(logger)
+		Expect(messages).To(BeEmpty())
+	})
+})
+
 var _ = Describe("NRI Update Network Device Data Runner", func() {
 	It("stops when context is cancelled", func() {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -241,3 +1276,20 @@ var _ = Describe("NRI Update Network Device Data Runner", func() {
 		Eventually(done, time.Second).Should(Receive())
 	})
 })
+
+var _ = Describe("ifNameForDevice", func() {
+	It("prefers the interface name reported by CNI", func() {
+		data := &types.NetworkDataChanStruct{
+			PreparedDevice:    &types.PreparedDevice{IfName: "net1"},
+			NetworkDeviceData: &resourcev1.NetworkDeviceData{InterfaceName: "sriov0"},
+		}
+		Expect(ifNameForDevice(data)).To(Equal("sriov0"))
+	})
+
+	It("falls back to the requested interface name", func() {
+		data := &types.NetworkDataChanStruct{
+			PreparedDevice: &types.PreparedDevice{IfName: "net1"},
+		}
+		Expect(ifNameForDevice(data)).To(Equal("net1"))
+	})
+})
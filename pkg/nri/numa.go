@@ -0,0 +1,138 @@
+package nri
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/cpuset"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	// eventReasonNUMAMisaligned is the reason of the events emitted for containers whose CPUs are
+	// not on the NUMA node of their VFs.
+	eventReasonNUMAMisaligned = "NUMAMisaligned"
+)
+
+// ValidateNUMAAlignment checks that the requested NUMA alignment mode is supported.
+func ValidateNUMAAlignment(mode string) error {
+	switch consts.NUMAAlignment(mode) {
+	case consts.NUMAAlignmentNone, consts.NUMAAlignmentWarn, consts.NUMAAlignmentPin:
+		return nil
+	default:
+		return fmt.Errorf("unsupported NUMA alignment %q, expected %q, %q or %q",
+			mode, consts.NUMAAlignmentNone, consts.NUMAAlignmentWarn, consts.NUMAAlignmentPin)
+	}
+}
+
+// alignContainerNUMA compares the cpuset of a container with the NUMA nodes of its VFs. Depending
+// on the configured NUMA alignment, a misaligned container gets a warning or its cpuset restricted
+// to the CPUs and memory of the NUMA nodes of its VFs, so latency-sensitive (e.g. DPDK) workloads
+// do not cross the inter-socket link. It returns whether the adjustment was changed.
+func (p *Plugin) alignContainerNUMA(ctx context.Context, pod *api.PodSandbox, ctr *api.Container, devices types.PreparedDevices, adjustment *api.ContainerAdjustment) bool {
+	if p.numaAlignment == "" || p.numaAlignment == consts.NUMAAlignmentNone {
+		return false
+	}
+	logger := klog.FromContext(ctx).WithName("NRI CreateContainer")
+
+	numaNodes, numaCPUs, err := containerNUMATopology(devices)
+	if err != nil {
+		// alignment is best effort, the container must still start
+		logger.Error(err, "Failed to get the NUMA topology of the container devices", "pod.UID", pod.Uid, "container", ctr.Name)
+		return false
+	}
+	if len(numaNodes) == 0 {
+		return false
+	}
+
+	// no cpuset means the container can run on any CPU
+	cpus, err := cpuset.Parse(ctr.GetLinux().GetResources().GetCpu().GetCpus())
+	if err != nil {
+		logger.Error(err, "Failed to parse the cpuset of the container", "pod.UID", pod.Uid, "container", ctr.Name)
+		return false
+	}
+	if !cpus.IsEmpty() && cpus.IsSubsetOf(numaCPUs) {
+		logger.V(3).Info("Container is NUMA aligned with its devices", "pod.UID", pod.Uid, "container", ctr.Name, "numaNodes", numaNodes)
+		return false
+	}
+
+	if p.numaAlignment == consts.NUMAAlignmentPin {
+		pinned := numaCPUs
+		if !cpus.IsEmpty() {
+			pinned = cpus.Intersection(numaCPUs)
+		}
+		if !pinned.IsEmpty() {
+			adjustment.SetLinuxCPUSetCPUs(pinned.String())
+			adjustment.SetLinuxCPUSetMems(strings.Join(numaNodes, ","))
+			logger.Info("Pinning container to the NUMA nodes of its devices", "pod.UID", pod.Uid, "container", ctr.Name,
+				"numaNodes", numaNodes, "cpus", pinned.String())
+			return true
+		}
+	}
+
+	// none of the CPUs of the container are on the NUMA nodes of its devices, narrowing its
+	// cpuset would take CPUs it was not allocated
+	logger.Info("Container is not NUMA aligned with its devices", "pod.UID", pod.Uid, "container", ctr.Name,
+		"numaNodes", numaNodes, "cpus", cpus.String())
+	p.recordNUMAMisalignment(pod, ctr, numaNodes, cpus)
+	return false
+}
+
+// containerNUMATopology returns the NUMA nodes of the VFs of a container and their CPUs. Devices
+// without NUMA affinity are ignored.
+func containerNUMATopology(devices types.PreparedDevices) ([]string, cpuset.CPUSet, error) {
+	var numaNodes []string
+	numaCPUs := cpuset.New()
+	for _, device := range devices {
+		numaNode, err := host.GetHelpers().GetNumaNode(device.PciAddress)
+		if err != nil {
+			return nil, cpuset.CPUSet{}, err
+		}
+		if numaNode == "-1" || slices.Contains(numaNodes, numaNode) {
+			continue
+		}
+		cpuList, err := host.GetHelpers().GetNumaNodeCPUs(numaNode)
+		if err != nil {
+			return nil, cpuset.CPUSet{}, err
+		}
+		cpus, err := cpuset.Parse(cpuList)
+		if err != nil {
+			return nil, cpuset.CPUSet{}, fmt.Errorf("failed to parse cpulist of NUMA node %s: %w", numaNode, err)
+		}
+		numaNodes = append(numaNodes, numaNode)
+		numaCPUs = numaCPUs.Union(cpus)
+	}
+	slices.Sort(numaNodes)
+	return numaNodes, numaCPUs, nil
+}
+
+// recordNUMAMisalignment emits a warning event on the pod owning a container that is not NUMA
+// aligned with its devices.
+func (p *Plugin) recordNUMAMisalignment(pod *api.PodSandbox, ctr *api.Container, numaNodes []string, cpus cpuset.CPUSet) {
+	if p.eventRecorder == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		UID:        k8stypes.UID(pod.Uid),
+	}
+	cpuList := cpus.String()
+	if cpus.IsEmpty() {
+		cpuList = "all"
+	}
+	p.eventRecorder.Eventf(ref, corev1.EventTypeWarning, eventReasonNUMAMisaligned,
+		"Container %s runs on CPUs %s, outside of NUMA node(s) %s of its SR-IOV devices",
+		ctr.Name, cpuList, strings.Join(numaNodes, ","))
+}
@@ -0,0 +1,69 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/containerd/nri/pkg/api"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// rdmaDevices returns the devices of a pod with an RDMA device to move to its network namespace,
+// none unless the kernel RDMA netns mode is exclusive. In shared mode the RDMA devices are usable
+// from every network namespace, in exclusive mode only from the one they belong to.
+func rdmaDevices(logger klog.Logger, devices types.PreparedDevices) types.PreparedDevices {
+	var rdmaDevices types.PreparedDevices
+	for _, device := range devices {
+		// the VFs of shared and admin access claims are not attached to the pod network
+		if device.RDMADevice != "" && device.AttachesNetwork() {
+			rdmaDevices = append(rdmaDevices, device)
+		}
+	}
+	if len(rdmaDevices) == 0 {
+		return nil
+	}
+
+	mode, err := host.GetHelpers().GetRDMANetnsMode()
+	if err != nil {
+		logger.Error(err, "Failed to get the RDMA netns mode, RDMA devices are left in the host network namespace")
+		return nil
+	}
+	if mode != host.RDMANetnsModeExclusive {
+		return nil
+	}
+	return rdmaDevices
+}
+
+// moveRDMADevices moves the RDMA devices of the VFs of a pod to its network namespace when the
+// kernel RDMA netns mode is exclusive, so each pod only sees the RDMA devices of its own VFs.
+func (p *Plugin) moveRDMADevices(ctx context.Context, pod *api.PodSandbox, networkNamespace string, devices types.PreparedDevices) error {
+	logger := klog.FromContext(ctx)
+
+	var errs []error
+	for _, device := range rdmaDevices(logger, devices) {
+		logger.Info("Moving RDMA device to the pod network namespace", "deviceName", device.Device.DeviceName,
+			"rdmaDevice", device.RDMADevice, "pod.UID", pod.Uid)
+		if err := host.GetHelpers().MoveRDMADeviceToNetns(device.RDMADevice, networkNamespace); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move RDMA device of device %s: %w", device.Device.DeviceName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restoreRDMADevices moves the RDMA devices of the VFs of a pod back to the host network namespace.
+// The kernel also moves them back when the pod network namespace is destroyed, so failures are
+// only logged.
+func (p *Plugin) restoreRDMADevices(ctx context.Context, pod *api.PodSandbox, networkNamespace string, devices types.PreparedDevices) {
+	logger := klog.FromContext(ctx)
+
+	for _, device := range rdmaDevices(logger, devices) {
+		if err := host.GetHelpers().MoveRDMADeviceFromNetns(device.RDMADevice, networkNamespace); err != nil {
+			logger.Error(err, "Failed to move RDMA device back to the host network namespace", "deviceName", device.Device.DeviceName,
+				"rdmaDevice", device.RDMADevice, "pod.UID", pod.Uid)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package nri
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/containerd/nri/pkg/stub"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// reconnectBackoff paces the attempts to reconnect to the runtime, which can take a while to
+// come back after a restart. Attempts go on until the plugin is stopped.
+var reconnectBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2.0,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      30 * time.Second,
+}
+
+// currentStub returns the stub currently connecting the plugin to the runtime.
+func (p *Plugin) currentStub() stub.Stub {
+	p.stubMu.Lock()
+	defer p.stubMu.Unlock()
+	return p.stub
+}
+
+// isStopped reports whether the plugin was stopped.
+func (p *Plugin) isStopped() bool {
+	p.stubMu.Lock()
+	defer p.stubMu.Unlock()
+	return p.stopped
+}
+
+// setConnected records whether the plugin is connected to the runtime.
+func (p *Plugin) setConnected(connected bool) {
+	p.stubMu.Lock()
+	defer p.stubMu.Unlock()
+	p.connected = connected
+}
+
+// Status fails while the plugin is not connected to the runtime, e.g. while containerd restarts,
+// as sandboxes then start without their networks. It is the nri probe of the healthcheck.
+func (p *Plugin) Status(_ context.Context) error {
+	p.stubMu.Lock()
+	defer p.stubMu.Unlock()
+	if !p.connected {
+		return fmt.Errorf("NRI plugin is not connected to the runtime")
+	}
+	return nil
+}
+
+// reconnectRunner waits for the connection to the runtime to be lost, e.g. when containerd
+// restarts, and reconnects with backoff, so the DRA plugin keeps serving claims in the meantime.
+// Once registered again, the runtime synchronizes the running sandboxes, see Synchronize.
+func (p *Plugin) reconnectRunner(ctx context.Context) {
+	logger := klog.FromContext(ctx).WithName("NRI reconnect")
+	for {
+		p.currentStub().Wait()
+		p.setConnected(false)
+		if ctx.Err() != nil || p.isStopped() {
+			return
+		}
+		logger.Info("Lost the connection to the runtime, reconnecting")
+		err := wait.ExponentialBackoffWithContext(ctx, reconnectBackoff, func(ctx context.Context) (bool, error) {
+			return p.reconnect(ctx, logger), nil
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// reconnect registers a new stub with the runtime. A stub that failed to start keeps its
+// connection, so every attempt starts from a new one.
+func (p *Plugin) reconnect(ctx context.Context, logger klog.Logger) bool {
+	newStub, err := p.newStub()
+	if err != nil {
+		logger.Error(err, "Failed to create plugin stub")
+		return false
+	}
+	if err := newStub.Start(ctx); err != nil {
+		logger.Info("Failed to reconnect to the runtime, retrying", "error", err.Error())
+		newStub.Stop()
+		return false
+	}
+
+	p.stubMu.Lock()
+	if p.stopped {
+		p.stubMu.Unlock()
+		newStub.Stop()
+		return true
+	}
+	p.stub = newStub
+	p.connected = true
+	p.stubMu.Unlock()
+
+	logger.Info("Reconnected to the runtime")
+	p.checkRuntimeTimeouts(logger)
+	return true
+}
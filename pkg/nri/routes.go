@@ -0,0 +1,32 @@
+package nri
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// addRoutes adds the routes of the config of a device through its interface in the pod network
+// namespace once CNI ADD attached it.
+func addRoutes(networkNamespace string, device *types.PreparedDevice, attachResult *cni.AttachResult) error {
+	if device.Config == nil || len(device.Config.Routes) == 0 {
+		return nil
+	}
+	routes := make([]host.Route, 0, len(device.Config.Routes))
+	for _, route := range device.Config.Routes {
+		// the config was validated at prepare time
+		_, dst, err := net.ParseCIDR(route.Dst)
+		if err != nil {
+			return fmt.Errorf("invalid route destination %q of device %s: %w", route.Dst, device.Device.DeviceName, err)
+		}
+		routes = append(routes, host.Route{Dst: dst, GW: net.ParseIP(route.GW), Metric: int(route.Metric)})
+	}
+	ifName := attachedIfName(device, attachResult)
+	if err := host.GetHelpers().AddNetnsRoutes(networkNamespace, ifName, routes); err != nil {
+		return fmt.Errorf("failed to add routes of device %s: %w", device.Device.DeviceName, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package nri
+
+import (
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourcev1 "k8s.io/api/resource/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI routes", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockHost    *hostmock.MockInterface
+		origHelpers host.Interface
+		device      *types.PreparedDevice
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(ctrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+
+		device = &types.PreparedDevice{
+			Device: drapbv1.Device{DeviceName: "vf-1"},
+			IfName: "net1",
+			Config: &configapi.VfConfig{Routes: []configapi.RouteConfig{
+				{Dst: "10.10.1.0/16", GW: "192.168.100.1", Metric: 100},
+				{Dst: "192.168.200.0/24"},
+			}},
+		}
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		ctrl.Finish()
+	})
+
+	It("adds the routes through the interface reported by CNI", func() {
+		attachResult := &cni.AttachResult{NetworkDeviceData: &resourcev1.NetworkDeviceData{InterfaceName: "net2"}}
+		_, dst, _ := net.ParseCIDR("10.10.0.0/16")
+		_, linkDst, _ := net.ParseCIDR("192.168.200.0/24")
+		mockHost.EXPECT().AddNetnsRoutes("/proc/123/ns/net", "net2", []host.Route{
+			{Dst: dst, GW: net.ParseIP("192.168.100.1"), Metric: 100},
+			{Dst: linkDst},
+		}).Return(nil)
+
+		Expect(addRoutes("/proc/123/ns/net", device, attachResult)).To(Succeed())
+	})
+
+	It("fails when a route cannot be added", func() {
+		mockHost.EXPECT().AddNetnsRoutes("/proc/123/ns/net", "net1", gomock.Any()).Return(errors.New("network is unreachable"))
+
+		Expect(addRoutes("/proc/123/ns/net", device, nil)).To(MatchError("failed to add routes of device vf-1: network is unreachable"))
+	})
+
+	It("does nothing without routes", func() {
+		device.Config = &configapi.VfConfig{}
+
+		// no AddNetnsRoutes expectation: the mock fails on any call
+		Expect(addRoutes("/proc/123/ns/net", device, nil)).To(Succeed())
+	})
+})
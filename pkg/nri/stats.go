@@ -0,0 +1,161 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/containerd/nri/pkg/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// statisticsDataKey is the key of the traffic counters in the Data of the devices in the status
+// of their claim, next to the ones written by updateNetworkDeviceData.
+const statisticsDataKey = "statistics"
+
+// deviceStatistics are the traffic counters of a VF published in the status of its claim.
+type deviceStatistics struct {
+	host.LinkStatistics
+	// Timestamp is the time the counters were read at.
+	Timestamp metav1.Time `json:"timestamp"`
+}
+
+// deviceStatisticsEntry holds the counters read for a prepared device.
+type deviceStatisticsEntry struct {
+	device     *types.PreparedDevice
+	statistics deviceStatistics
+}
+
+// statisticsRunner periodically publishes the traffic counters of the attached VFs.
+func (p *Plugin) statisticsRunner(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		p.PublishStatistics(ctx)
+	}, p.statisticsInterval)
+}
+
+// PublishStatistics reads the traffic counters of the kernel bound VFs attached to the tracked
+// pods from their network namespace and publishes them in the Data of the devices in the status
+// of their claim, so users can follow the traffic of a VF without access to the node. It returns
+// the number of devices whose counters were published.
+func (p *Plugin) PublishStatistics(ctx context.Context) int {
+	logger := klog.FromContext(ctx).WithName("NRI PublishStatistics")
+
+	var claims []k8stypes.NamespacedName
+	entriesByClaim := map[k8stypes.NamespacedName][]deviceStatisticsEntry{}
+	for _, pod := range p.listAttachedPods() {
+		devices, found := p.podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+		if !found {
+			continue
+		}
+		for _, entry := range p.readStatistics(logger, pod, devices) {
+			claim := entry.device.ClaimNamespacedName.NamespacedName
+			if _, ok := entriesByClaim[claim]; !ok {
+				claims = append(claims, claim)
+			}
+			entriesByClaim[claim] = append(entriesByClaim[claim], entry)
+		}
+	}
+
+	published := 0
+	for _, claim := range claims {
+		updated, err := p.updateClaimStatistics(ctx, claim, entriesByClaim[claim])
+		if err != nil {
+			logger.Error(err, "Failed to publish device statistics", "claim", claim)
+			continue
+		}
+		published += updated
+	}
+	return published
+}
+
+// kernelNetworkDevices returns the devices of a pod with a network interface in its network
+// namespace. Devices bound to a DPDK driver have none, nor do the devices not attached to the pod
+// network.
+func kernelNetworkDevices(devices types.PreparedDevices) types.PreparedDevices {
+	var kernelDevices types.PreparedDevices
+	for _, device := range devices {
+		if !device.AttachesNetwork() || device.IfName == "" {
+			continue
+		}
+		if device.Config != nil && host.GetHelpers().IsDpdkDriver(device.Config.Driver) {
+			continue
+		}
+		kernelDevices = append(kernelDevices, device)
+	}
+	return kernelDevices
+}
+
+// readStatistics reads the traffic counters of the kernel bound devices of a pod. Devices whose
+// interface cannot be read, e.g. while the sandbox is torn down, are skipped.
+func (p *Plugin) readStatistics(logger klog.Logger, pod *api.PodSandbox, devices types.PreparedDevices) []deviceStatisticsEntry {
+	networkNamespace := getNetworkNamespace(pod)
+	if networkNamespace == "" {
+		return nil
+	}
+
+	var entries []deviceStatisticsEntry
+	for _, device := range kernelNetworkDevices(devices) {
+		stats, err := host.GetHelpers().GetLinkStatistics(networkNamespace, device.IfName)
+		if err != nil {
+			logger.V(2).Info("Failed to read device statistics", "deviceName", device.Device.DeviceName, "ifName", device.IfName,
+				"pod.UID", pod.Uid, "error", err.Error())
+			continue
+		}
+		entries = append(entries, deviceStatisticsEntry{
+			device:     device,
+			statistics: deviceStatistics{LinkStatistics: *stats, Timestamp: metav1.Now()},
+		})
+	}
+	return entries
+}
+
+// updateClaimStatistics merges the traffic counters of the devices of a claim into the Data of
+// their device in the status of the claim, keeping the other keys of the Data. It returns the
+// number of devices updated.
+func (p *Plugin) updateClaimStatistics(ctx context.Context, claimName k8stypes.NamespacedName, entries []deviceStatisticsEntry) (int, error) {
+	claim, err := p.k8sClient.ResourceV1().ResourceClaims(claimName.Namespace).Get(ctx, claimName.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get claim %s: %w", claimName, err)
+	}
+
+	updated := 0
+	for idx, status := range claim.Status.Devices {
+		if status.Driver != consts.DriverName {
+			continue
+		}
+		for _, entry := range entries {
+			if status.Device != entry.device.Device.DeviceName || status.Pool != entry.device.Device.PoolName {
+				continue
+			}
+			data := map[string]interface{}{}
+			if status.Data != nil && len(status.Data.Raw) > 0 {
+				if err := json.Unmarshal(status.Data.Raw, &data); err != nil {
+					return 0, fmt.Errorf("failed to unmarshal data of device %s: %w", status.Device, err)
+				}
+			}
+			data[statisticsDataKey] = entry.statistics
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return 0, fmt.Errorf("failed to marshal data of device %s: %w", status.Device, err)
+			}
+			claim.Status.Devices[idx].Data = &runtime.RawExtension{Raw: raw}
+			updated++
+		}
+	}
+	if updated == 0 {
+		// the status of the devices is not written yet, see updateNetworkDeviceData
+		return 0, nil
+	}
+	if err := p.updateClaimNetworkDataWithRetry(ctx, claim); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,160 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI Statistics", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockHost    *hostmock.MockInterface
+		origHelpers host.Interface
+		clientset   *k8sfake.Clientset
+		plugin      *Plugin
+		ctx         context.Context
+		pod         *api.PodSandbox
+		prepared    types.PreparedDevices
+	)
+
+	claimData := func(deviceName string) map[string]interface{} {
+		claim, err := clientset.ResourceV1().ResourceClaims("default").Get(ctx, "claim", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		for _, device := range claim.Status.Devices {
+			if device.Device != deviceName {
+				continue
+			}
+			data := map[string]interface{}{}
+			if device.Data != nil {
+				Expect(json.Unmarshal(device.Data.Raw, &data)).To(Succeed())
+			}
+			return data
+		}
+		Fail("device " + deviceName + " not found in the claim status")
+		return nil
+	}
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(ctrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+		mockHost.EXPECT().IsDpdkDriver(gomock.Any()).DoAndReturn(func(driver string) bool {
+			return driver == "vfio-pci"
+		}).AnyTimes()
+		ctx = context.Background()
+
+		podManager, err := podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+		Expect(err).ToNot(HaveOccurred())
+
+		clientset = k8sfake.NewSimpleClientset(&resourcev1.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "default"},
+			Status: resourcev1.ResourceClaimStatus{Devices: []resourcev1.AllocatedDeviceStatus{
+				{Driver: consts.DriverName, Pool: "node-1", Device: "vf-1", Data: &runtime.RawExtension{Raw: []byte(`{"ifName":"net1"}`)}},
+				{Driver: consts.DriverName, Pool: "node-1", Device: "vf-2"},
+			}},
+		})
+		plugin = &Plugin{
+			podManager: podManager,
+			k8sClient:  flags.ClientSets{Interface: clientset},
+		}
+
+		pod = &api.PodSandbox{
+			Id:        "sandbox-id",
+			Name:      "pod-name",
+			Namespace: "default",
+			Uid:       "uid-1",
+			Linux: &api.LinuxPodSandbox{
+				Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+			},
+		}
+		claim := kubeletplugin.NamespacedObject{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"}, UID: "claim-1"}
+		prepared = types.PreparedDevices{
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "vf-1", PoolName: "node-1"},
+				ClaimNamespacedName: claim,
+				Config:              &configapi.VfConfig{Driver: "mlx5_core"},
+				IfName:              "net1",
+				PodUID:              pod.Uid,
+			},
+			&types.PreparedDevice{
+				Device:              drapbv1.Device{DeviceName: "vf-2", PoolName: "node-1"},
+				ClaimNamespacedName: claim,
+				Config:              &configapi.VfConfig{Driver: "vfio-pci"},
+				IfName:              "net2",
+				PodUID:              pod.Uid,
+			},
+		}
+		Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), prepared)).To(Succeed())
+		plugin.trackAttachedPod(pod)
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		ctrl.Finish()
+	})
+
+	It("publishes the counters of kernel bound VFs in the claim status", func() {
+		mockHost.EXPECT().GetLinkStatistics("/proc/123/ns/net", "net1").Return(&host.LinkStatistics{
+			RxBytes: 1500, TxBytes: 3000, RxPackets: 1, TxPackets: 2, RxDropped: 3, TxDropped: 4,
+		}, nil)
+
+		Expect(plugin.PublishStatistics(ctx)).To(Equal(1))
+
+		data := claimData("vf-1")
+		Expect(data).To(HaveKeyWithValue("ifName", "net1"))
+		Expect(data).To(HaveKey(statisticsDataKey))
+		statistics := data[statisticsDataKey].(map[string]interface{})
+		Expect(statistics).To(HaveKeyWithValue("rxBytes", BeNumerically("==", 1500)))
+		Expect(statistics).To(HaveKeyWithValue("txDropped", BeNumerically("==", 4)))
+		Expect(statistics).To(HaveKey("timestamp"))
+		// the DPDK bound VF has no network interface to read
+		Expect(claimData("vf-2")).ToNot(HaveKey(statisticsDataKey))
+	})
+
+	It("skips devices whose interface cannot be read", func() {
+		mockHost.EXPECT().GetLinkStatistics("/proc/123/ns/net", "net1").Return(nil, errors.New("link not found"))
+
+		Expect(plugin.PublishStatistics(ctx)).To(Equal(0))
+		Expect(claimData("vf-1")).ToNot(HaveKey(statisticsDataKey))
+	})
+
+	It("skips devices of shared claims", func() {
+		prepared[0].Shared = true
+
+		// no GetLinkStatistics expectation: the mock fails on any call
+		Expect(plugin.PublishStatistics(ctx)).To(Equal(0))
+	})
+
+	It("does not publish counters of devices missing from the claim status", func() {
+		prepared[0].Device.DeviceName = "vf-3"
+		mockHost.EXPECT().GetLinkStatistics("/proc/123/ns/net", "net1").Return(&host.LinkStatistics{RxBytes: 1}, nil)
+
+		Expect(plugin.PublishStatistics(ctx)).To(Equal(0))
+		Expect(clientset.Actions()).To(HaveLen(1))
+		Expect(clientset.Actions()[0].GetVerb()).To(Equal("get"))
+		Expect(claimData("vf-1")).ToNot(HaveKey(statisticsDataKey))
+	})
+})
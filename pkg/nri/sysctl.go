@@ -0,0 +1,31 @@
+package nri
+
+import (
+	"fmt"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// attachedIfName returns the interface of a device in the pod, as reported by CNI ADD, falling back
+// to the interface name requested at prepare time.
+func attachedIfName(device *types.PreparedDevice, attachResult *cni.AttachResult) string {
+	if attachResult != nil && attachResult.NetworkDeviceData != nil && attachResult.NetworkDeviceData.InterfaceName != "" {
+		return attachResult.NetworkDeviceData.InterfaceName
+	}
+	return device.IfName
+}
+
+// setSysctls sets the sysctls of the config of a device in the pod network namespace once CNI ADD
+// attached it, the IFNAME component of their names being the interface of the device in the pod.
+func setSysctls(networkNamespace string, device *types.PreparedDevice, attachResult *cni.AttachResult) error {
+	if device.Config == nil || len(device.Config.Sysctls) == 0 {
+		return nil
+	}
+	ifName := attachedIfName(device, attachResult)
+	if err := host.GetHelpers().SetNetnsSysctls(networkNamespace, device.Config.SysctlPaths(ifName)); err != nil {
+		return fmt.Errorf("failed to set sysctls of device %s: %w", device.Device.DeviceName, err)
+	}
+	return nil
+}
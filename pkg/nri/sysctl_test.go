@@ -0,0 +1,71 @@
+package nri
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourcev1 "k8s.io/api/resource/v1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI sysctls", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockHost    *hostmock.MockInterface
+		origHelpers host.Interface
+		device      *types.PreparedDevice
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(ctrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+
+		device = &types.PreparedDevice{
+			Device: drapbv1.Device{DeviceName: "vf-1"},
+			IfName: "net1",
+			Config: &configapi.VfConfig{Sysctls: map[string]string{"net.ipv4.conf.IFNAME.rp_filter": "2"}},
+		}
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		ctrl.Finish()
+	})
+
+	It("sets the sysctls for the interface reported by CNI", func() {
+		attachResult := &cni.AttachResult{NetworkDeviceData: &resourcev1.NetworkDeviceData{InterfaceName: "net2"}}
+		mockHost.EXPECT().SetNetnsSysctls("/proc/123/ns/net", map[string]string{"net/ipv4/conf/net2/rp_filter": "2"}).Return(nil)
+
+		Expect(setSysctls("/proc/123/ns/net", device, attachResult)).To(Succeed())
+	})
+
+	It("falls back to the interface requested at prepare time", func() {
+		mockHost.EXPECT().SetNetnsSysctls("/proc/123/ns/net", map[string]string{"net/ipv4/conf/net1/rp_filter": "2"}).Return(nil)
+
+		Expect(setSysctls("/proc/123/ns/net", device, &cni.AttachResult{})).To(Succeed())
+	})
+
+	It("fails when a sysctl cannot be set", func() {
+		mockHost.EXPECT().SetNetnsSysctls("/proc/123/ns/net", gomock.Any()).Return(errors.New("permission denied"))
+
+		Expect(setSysctls("/proc/123/ns/net", device, nil)).To(MatchError("failed to set sysctls of device vf-1: permission denied"))
+	})
+
+	It("does nothing without sysctls", func() {
+		device.Config = &configapi.VfConfig{}
+
+		// no SetNetnsSysctls expectation: the mock fails on any call
+		Expect(setSysctls("/proc/123/ns/net", device, nil)).To(Succeed())
+	})
+})
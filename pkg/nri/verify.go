@@ -0,0 +1,37 @@
+package nri
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// verifyInterface checks that CNI ADD left the interface of a device in the pod network namespace,
+// with the MAC address reported by CNI and backed by the VF of the device, so a CNI plugin
+// succeeding without moving the VF, or moving another one, fails the sandbox instead of leaving
+// the pod without its network. Devices bound to a DPDK driver have no interface to verify.
+func verifyInterface(networkNamespace string, device *types.PreparedDevice, attachResult *cni.AttachResult) error {
+	if device.IfName == "" {
+		return nil
+	}
+	if device.Config != nil && host.GetHelpers().IsDpdkDriver(device.Config.Driver) {
+		return nil
+	}
+
+	iface, err := host.GetHelpers().GetNetnsInterface(networkNamespace, device.IfName)
+	if err != nil {
+		return fmt.Errorf("interface %s of device %s not found in the pod network namespace after CNI ADD: %w", device.IfName, device.Device.DeviceName, err)
+	}
+	if attachResult != nil && attachResult.NetworkDeviceData != nil {
+		if mac := attachResult.NetworkDeviceData.HardwareAddress; mac != "" && !strings.EqualFold(mac, iface.MAC) {
+			return fmt.Errorf("interface %s of device %s has MAC address %s but CNI ADD reported %s", device.IfName, device.Device.DeviceName, iface.MAC, mac)
+		}
+	}
+	if device.PciAddress != "" && iface.PciAddress != "" && iface.PciAddress != device.PciAddress {
+		return fmt.Errorf("interface %s of device %s is backed by PCI device %s instead of %s", device.IfName, device.Device.DeviceName, iface.PciAddress, device.PciAddress)
+	}
+	return nil
+}
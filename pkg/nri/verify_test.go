@@ -0,0 +1,163 @@
+package nri
+
+import (
+	"context"
+	"errors"
+
+	"github.com/containerd/nri/pkg/api"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	resourcev1 "k8s.io/api/resource/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni"
+	cnimock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cni/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	hostmock "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host/mock"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+var _ = Describe("NRI interface verification", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockHost     *hostmock.MockInterface
+		origHelpers  host.Interface
+		device       *types.PreparedDevice
+		attachResult *cni.AttachResult
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockHost = hostmock.NewMockInterface(ctrl)
+		_ = host.GetHelpers()
+		origHelpers = host.Helpers
+		host.Helpers = mockHost
+		mockHost.EXPECT().IsDpdkDriver(gomock.Any()).DoAndReturn(func(driver string) bool {
+			return driver == "vfio-pci"
+		}).AnyTimes()
+
+		device = &types.PreparedDevice{
+			Device:     drapbv1.Device{DeviceName: "vf-1"},
+			IfName:     "net1",
+			PciAddress: "0000:3b:02.1",
+		}
+		attachResult = &cni.AttachResult{NetworkDeviceData: &resourcev1.NetworkDeviceData{
+			InterfaceName:   "net1",
+			HardwareAddress: "0A:1B:2C:3D:4E:5F",
+		}}
+	})
+
+	AfterEach(func() {
+		host.Helpers = origHelpers
+		ctrl.Finish()
+	})
+
+	Context("verifyInterface", func() {
+		It("accepts the interface of the VF with the reported MAC address", func() {
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").
+				Return(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:5f", PciAddress: "0000:3b:02.1"}, nil)
+
+			Expect(verifyInterface("/proc/123/ns/net", device, attachResult)).To(Succeed())
+		})
+
+		It("fails when the interface is missing", func() {
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").Return(nil, errors.New("link not found"))
+
+			Expect(verifyInterface("/proc/123/ns/net", device, attachResult)).
+				To(MatchError(ContainSubstring("interface net1 of device vf-1 not found in the pod network namespace after CNI ADD")))
+		})
+
+		It("fails when the MAC address differs from the CNI result", func() {
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").
+				Return(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:60", PciAddress: "0000:3b:02.1"}, nil)
+
+			Expect(verifyInterface("/proc/123/ns/net", device, attachResult)).
+				To(MatchError(ContainSubstring("has MAC address 0a:1b:2c:3d:4e:60 but CNI ADD reported 0A:1B:2C:3D:4E:5F")))
+		})
+
+		It("fails when the interface is backed by another VF", func() {
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").
+				Return(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:5f", PciAddress: "0000:3b:02.2"}, nil)
+
+			Expect(verifyInterface("/proc/123/ns/net", device, attachResult)).
+				To(MatchError(ContainSubstring("is backed by PCI device 0000:3b:02.2 instead of 0000:3b:02.1")))
+		})
+
+		It("does not check the PCI address of interfaces without one", func() {
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").
+				Return(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:5f"}, nil)
+
+			Expect(verifyInterface("/proc/123/ns/net", device, attachResult)).To(Succeed())
+		})
+
+		It("skips DPDK bound VFs", func() {
+			device.Config = &configapi.VfConfig{Driver: "vfio-pci"}
+
+			// no GetNetnsInterface expectation: the mock fails on any call
+			Expect(verifyInterface("/proc/123/ns/net", device, attachResult)).To(Succeed())
+		})
+	})
+
+	Context("RunPodSandbox", func() {
+		var (
+			mockCNI    *cnimock.MockInterface
+			podManager *podmanager.PodManager
+			plugin     *Plugin
+			pod        *api.PodSandbox
+		)
+
+		BeforeEach(func() {
+			mockCNI = cnimock.NewMockInterface(ctrl)
+			var err error
+			podManager, err = podmanager.NewPodManager(&types.Config{Flags: &types.Flags{KubeletPluginsDirectoryPath: GinkgoT().TempDir()}})
+			Expect(err).ToNot(HaveOccurred())
+			plugin = &Plugin{
+				podManager:                  podManager,
+				cniRuntime:                  mockCNI,
+				networkDeviceDataUpdateChan: make(chan types.NetworkDataChanStructList, 10),
+				verifyInterfaces:            true,
+			}
+			pod = &api.PodSandbox{
+				Id:        "sandbox-id",
+				Name:      "pod-name",
+				Namespace: "default",
+				Uid:       "uid-1",
+				Linux: &api.LinuxPodSandbox{
+					Namespaces: []*api.LinuxNamespace{{Type: "network", Path: "/proc/123/ns/net"}},
+				},
+			}
+			device.ClaimNamespacedName = kubeletplugin.NamespacedObject{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "claim"}, UID: "claim-1"}
+			device.PodUID = pod.Uid
+			device.NetAttachDefConfig = `{"type":"sriov","name":"net1"}`
+			Expect(podManager.Set(k8stypes.UID(pod.Uid), k8stypes.UID("claim-1"), types.PreparedDevices{device})).To(Succeed())
+			attachResult.NetConf = `{"type":"sriov"}`
+			mockCNI.EXPECT().AttachNetwork(gomock.Any(), pod, "/proc/123/ns/net", device).Return(attachResult, nil)
+		})
+
+		It("fails the sandbox when the CNI plugin did not move the VF, keeping the attachment to detach", func() {
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").Return(nil, errors.New("link not found"))
+
+			err := plugin.RunPodSandbox(context.Background(), pod)
+			Expect(err).To(MatchError(ContainSubstring("interface net1 of device vf-1 not found in the pod network namespace")))
+			Expect(plugin.networkDeviceDataUpdateChan).To(BeEmpty())
+
+			devices, found := podManager.GetDevicesByPodUID(k8stypes.UID(pod.Uid))
+			Expect(found).To(BeTrue())
+			Expect(devices[0].CNISandboxID).To(Equal("sandbox-id"))
+		})
+
+		It("attaches the device when the interface is verified", func() {
+			mockHost.EXPECT().GetRDMANetnsMode().Return(host.RDMANetnsModeShared, nil).AnyTimes()
+			mockHost.EXPECT().GetNetnsInterface("/proc/123/ns/net", "net1").
+				Return(&host.NetnsInterface{MAC: "0a:1b:2c:3d:4e:5f", PciAddress: "0000:3b:02.1"}, nil)
+
+			Expect(plugin.RunPodSandbox(context.Background(), pod)).To(Succeed())
+			Expect(plugin.networkDeviceDataUpdateChan).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package ovs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOVS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OVS Suite")
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ovs plugs the representors of switchdev VFs into the bridges of the Open vSwitch of the
+// node. It talks to the database server of Open vSwitch with the OVSDB management protocol
+// (RFC 7047), so the driver image needs neither ovs-vsctl nor an OVSDB library.
+package ovs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds each transaction with the database server.
+	DefaultTimeout = 10 * time.Second
+
+	databaseName = "Open_vSwitch"
+)
+
+// Bridges adds and deletes the ports of the bridges of Open vSwitch, implemented by Client.
+type Bridges interface {
+	// AddPort adds a port with a single interface of the same name to a bridge, e.g. a
+	// representor. Adding a port already on the bridge succeeds, a port on another bridge fails.
+	AddPort(ctx context.Context, bridge, port string, externalIDs map[string]string) error
+	// DeletePort deletes a port from a bridge. Deleting a port or a bridge already gone succeeds.
+	DeletePort(ctx context.Context, bridge, port string) error
+}
+
+// Client runs transactions on the database server of Open vSwitch, connecting for each of them.
+type Client struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+var _ Bridges = &Client{}
+
+// NewClient creates a client of the database server listening at endpoint, in the syntax of
+// ovs-vsctl --db: unix:/var/run/openvswitch/db.sock or tcp:127.0.0.1:6640. A path is a unix
+// socket.
+func NewClient(endpoint string) (*Client, error) {
+	network, address, found := strings.Cut(endpoint, ":")
+	switch {
+	case !found && strings.HasPrefix(endpoint, "/"):
+		network, address = "unix", endpoint
+	case found && (network == "unix" || network == "tcp"):
+	default:
+		return nil, fmt.Errorf("invalid OVSDB endpoint %q, expected unix:<path> or tcp:<host>:<port>", endpoint)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("invalid OVSDB endpoint %q, empty address", endpoint)
+	}
+	return &Client{network: network, address: address, timeout: DefaultTimeout}, nil
+}
+
+// operation is an operation of an OVSDB transaction.
+type operation map[string]any
+
+// operationResult is the result of an operation of an OVSDB transaction.
+type operationResult struct {
+	Count   int                          `json:"count"`
+	Rows    []map[string]json.RawMessage `json:"rows"`
+	Error   string                       `json:"error"`
+	Details string                       `json:"details"`
+}
+
+// rpcMessage is a JSON-RPC request, response or notification of the OVSDB protocol.
+type rpcMessage struct {
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// transact runs a transaction and returns the results of its operations, failing when one of
+// them failed, in which case none was committed.
+func (c *Client) transact(ctx context.Context, ops ...operation) ([]operationResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to OVSDB %s:%s: %w", c.network, c.address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	params := append([]any{databaseName}, toAny(ops)...)
+	if err := json.NewEncoder(conn).Encode(map[string]any{"method": "transact", "params": params, "id": 1}); err != nil {
+		return nil, fmt.Errorf("failed to send OVSDB transaction: %w", err)
+	}
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg rpcMessage
+		if err := decoder.Decode(&msg); err != nil {
+			return nil, fmt.Errorf("failed to read OVSDB reply: %w", err)
+		}
+		// the server probes idle connections with echo requests
+		if msg.Method == "echo" {
+			if err := json.NewEncoder(conn).Encode(map[string]any{"result": msg.Params, "error": nil, "id": msg.ID}); err != nil {
+				return nil, fmt.Errorf("failed to reply to OVSDB echo: %w", err)
+			}
+			continue
+		}
+		if string(msg.ID) != "1" {
+			continue
+		}
+		if len(msg.Error) > 0 && string(msg.Error) != "null" {
+			return nil, fmt.Errorf("OVSDB transaction failed: %s", msg.Error)
+		}
+		// operations after a failed one are not run and have a null result
+		var results []*operationResult
+		if err := json.Unmarshal(msg.Result, &results); err != nil {
+			return nil, fmt.Errorf("invalid OVSDB transaction result: %w", err)
+		}
+		var errs []error
+		for _, result := range results {
+			if result != nil && result.Error != "" {
+				errs = append(errs, fmt.Errorf("%s: %s", result.Error, result.Details))
+			}
+		}
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("OVSDB transaction failed: %w", errors.Join(errs...))
+		}
+		if len(results) < len(ops) {
+			return nil, fmt.Errorf("OVSDB transaction returned %d results for %d operations", len(results), len(ops))
+		}
+		out := make([]operationResult, len(ops))
+		for i := range ops {
+			out[i] = *results[i]
+		}
+		return out, nil
+	}
+}
+
+func toAny(ops []operation) []any {
+	out := make([]any, len(ops))
+	for i, op := range ops {
+		out[i] = op
+	}
+	return out
+}
+
+// selectByName selects the rows of a table with a name.
+func selectByName(table, name string, columns ...string) operation {
+	return operation{"op": "select", "table": table, "where": []any{[]any{"name", "==", name}}, "columns": columns}
+}
+
+// rowUUID returns the _uuid column of a row, an OVSDB ["uuid", "<uuid>"] atom.
+func rowUUID(row map[string]json.RawMessage) string {
+	uuids := parseUUIDs(row["_uuid"])
+	if len(uuids) == 0 {
+		return ""
+	}
+	return uuids[0]
+}
+
+// parseUUIDs returns the UUIDs of an OVSDB UUID atom or set of UUIDs.
+func parseUUIDs(raw json.RawMessage) []string {
+	var value []json.RawMessage
+	if err := json.Unmarshal(raw, &value); err != nil || len(value) != 2 {
+		return nil
+	}
+	var kind string
+	if err := json.Unmarshal(value[0], &kind); err != nil {
+		return nil
+	}
+	switch kind {
+	case "uuid":
+		var uuid string
+		if err := json.Unmarshal(value[1], &uuid); err != nil {
+			return nil
+		}
+		return []string{uuid}
+	case "set":
+		var atoms []json.RawMessage
+		if err := json.Unmarshal(value[1], &atoms); err != nil {
+			return nil
+		}
+		var uuids []string
+		for _, atom := range atoms {
+			uuids = append(uuids, parseUUIDs(atom)...)
+		}
+		return uuids
+	}
+	return nil
+}
+
+// ovsdbMap encodes a map of strings as an OVSDB map, with sorted keys.
+func ovsdbMap(m map[string]string) []any {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]any, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, []any{key, m[key]})
+	}
+	return []any{"map", pairs}
+}
+
+// AddPort adds a port with a single interface of the same name to a bridge.
+func (c *Client) AddPort(ctx context.Context, bridge, port string, externalIDs map[string]string) error {
+	results, err := c.transact(ctx,
+		selectByName("Bridge", bridge, "ports"),
+		selectByName("Port", port, "_uuid"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to look up port %s of bridge %s: %w", port, bridge, err)
+	}
+	if len(results[0].Rows) == 0 {
+		return fmt.Errorf("bridge %s not found", bridge)
+	}
+	if len(results[1].Rows) > 0 {
+		if slices.Contains(parseUUIDs(results[0].Rows[0]["ports"]), rowUUID(results[1].Rows[0])) {
+			return nil
+		}
+		return fmt.Errorf("port %s already exists on another bridge than %s", port, bridge)
+	}
+
+	_, err = c.transact(ctx,
+		// fails the transaction if the bridge was deleted in the meantime
+		operation{"op": "wait", "table": "Bridge", "where": []any{[]any{"name", "==", bridge}},
+			"columns": []string{"name"}, "until": "==", "rows": []any{map[string]string{"name": bridge}}, "timeout": 0},
+		operation{"op": "insert", "table": "Interface", "uuid-name": "iface",
+			"row": map[string]any{"name": port}},
+		operation{"op": "insert", "table": "Port", "uuid-name": "port",
+			"row": map[string]any{"name": port, "interfaces": []any{"named-uuid", "iface"}, "external_ids": ovsdbMap(externalIDs)}},
+		operation{"op": "mutate", "table": "Bridge", "where": []any{[]any{"name", "==", bridge}},
+			"mutations": []any{[]any{"ports", "insert", []any{"set", []any{[]any{"named-uuid", "port"}}}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add port %s to bridge %s: %w", port, bridge, err)
+	}
+	return nil
+}
+
+// DeletePort deletes a port from a bridge, its interfaces are deleted with it.
+func (c *Client) DeletePort(ctx context.Context, bridge, port string) error {
+	results, err := c.transact(ctx, selectByName("Port", port, "_uuid"))
+	if err != nil {
+		return fmt.Errorf("failed to look up port %s of bridge %s: %w", port, bridge, err)
+	}
+	if len(results[0].Rows) == 0 {
+		return nil
+	}
+	// the port, no longer referenced by a bridge, is garbage collected with its interfaces
+	_, err = c.transact(ctx,
+		operation{"op": "mutate", "table": "Bridge", "where": []any{[]any{"name", "==", bridge}},
+			"mutations": []any{[]any{"ports", "delete", []any{"set", []any{[]any{"uuid", rowUUID(results[0].Rows[0])}}}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete port %s from bridge %s: %w", port, bridge, err)
+	}
+	return nil
+}
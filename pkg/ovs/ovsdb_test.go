@@ -0,0 +1,215 @@
+package ovs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/ovs"
+)
+
+// fakeOVSDB is a database server holding bridges and the ports on them, running the operations
+// of the transactions of Client.
+type fakeOVSDB struct {
+	mu          sync.Mutex
+	bridges     map[string][]string
+	ports       map[string]string
+	externalIDs map[string]any
+	nextUUID    int
+	// echo sends an echo request before each reply
+	echo bool
+}
+
+func (db *fakeOVSDB) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go db.handle(conn)
+	}
+}
+
+func (db *fakeOVSDB) handle(conn net.Conn) {
+	defer conn.Close()
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+			ID     json.RawMessage   `json:"id"`
+		}
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+		if req.Method != "transact" {
+			continue
+		}
+		if db.echo {
+			_ = encoder.Encode(map[string]any{"method": "echo", "params": []any{}, "id": "echo"})
+			var reply map[string]any
+			if err := decoder.Decode(&reply); err != nil || reply["id"] != "echo" {
+				return
+			}
+		}
+		_ = encoder.Encode(map[string]any{"result": db.transact(req.Params[1:]), "error": nil, "id": req.ID})
+	}
+}
+
+func (db *fakeOVSDB) transact(rawOps []json.RawMessage) []any {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	named := map[string]string{}
+	results := make([]any, 0, len(rawOps))
+	for _, rawOp := range rawOps {
+		var op struct {
+			Op        string              `json:"op"`
+			Table     string              `json:"table"`
+			Where     [][]string          `json:"where"`
+			UUIDName  string              `json:"uuid-name"`
+			Row       map[string]any      `json:"row"`
+			Mutations [][]json.RawMessage `json:"mutations"`
+		}
+		Expect(json.Unmarshal(rawOp, &op)).To(Succeed())
+		name := ""
+		if len(op.Where) > 0 {
+			name = op.Where[0][2]
+		}
+		switch {
+		case op.Op == "select" && op.Table == "Bridge":
+			ports, ok := db.bridges[name]
+			if !ok {
+				results = append(results, map[string]any{"rows": []any{}})
+				continue
+			}
+			set := []any{}
+			for _, uuid := range ports {
+				set = append(set, []any{"uuid", uuid})
+			}
+			results = append(results, map[string]any{"rows": []any{map[string]any{"ports": []any{"set", set}}}})
+		case op.Op == "select" && op.Table == "Port":
+			uuid, ok := db.ports[name]
+			if !ok {
+				results = append(results, map[string]any{"rows": []any{}})
+				continue
+			}
+			results = append(results, map[string]any{"rows": []any{map[string]any{"_uuid": []any{"uuid", uuid}}}})
+		case op.Op == "wait":
+			if _, ok := db.bridges[name]; !ok {
+				return append(results, map[string]any{"error": "timed out", "details": "wait timed out"})
+			}
+			results = append(results, map[string]any{})
+		case op.Op == "insert":
+			db.nextUUID++
+			uuid := fmt.Sprintf("uuid-%d", db.nextUUID)
+			named[op.UUIDName] = uuid
+			if op.Table == "Port" {
+				db.ports[op.Row["name"].(string)] = uuid
+				db.externalIDs[op.Row["name"].(string)] = op.Row["external_ids"]
+			}
+			results = append(results, map[string]any{"uuid": []any{"uuid", uuid}})
+		case op.Op == "mutate" && op.Table == "Bridge":
+			ports, ok := db.bridges[name]
+			if !ok {
+				results = append(results, map[string]any{"count": 0})
+				continue
+			}
+			var mutator string
+			var set []any
+			Expect(json.Unmarshal(op.Mutations[0][1], &mutator)).To(Succeed())
+			Expect(json.Unmarshal(op.Mutations[0][2], &set)).To(Succeed())
+			atom := set[1].([]any)[0].([]any)
+			uuid := atom[1].(string)
+			if atom[0] == "named-uuid" {
+				uuid = named[uuid]
+			}
+			if mutator == "insert" {
+				db.bridges[name] = append(ports, uuid)
+			} else {
+				db.bridges[name] = slices.DeleteFunc(ports, func(u string) bool { return u == uuid })
+				for port, portUUID := range db.ports {
+					if portUUID == uuid {
+						delete(db.ports, port)
+					}
+				}
+			}
+			results = append(results, map[string]any{"count": 1})
+		default:
+			Fail("unexpected operation " + string(rawOp))
+		}
+	}
+	return results
+}
+
+var _ = Describe("Client", func() {
+	var (
+		db     *fakeOVSDB
+		client *ovs.Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		db = &fakeOVSDB{
+			bridges:     map[string][]string{"br-ex": {"uuid-uplink"}, "br-int": {}},
+			ports:       map[string]string{"p0": "uuid-uplink"},
+			externalIDs: map[string]any{},
+		}
+		socket := filepath.Join(GinkgoT().TempDir(), "db.sock")
+		listener, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(listener.Close)
+		go db.serve(listener)
+
+		client, err = ovs.NewClient("unix:" + socket)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("adds and deletes the port of a representor", func() {
+		Expect(client.AddPort(ctx, "br-ex", "pf0vf1", map[string]string{"claim": "default/claim", "device": "vf-1"})).To(Succeed())
+		Expect(db.ports).To(HaveKey("pf0vf1"))
+		Expect(db.bridges["br-ex"]).To(ConsistOf("uuid-uplink", db.ports["pf0vf1"]))
+		Expect(db.externalIDs["pf0vf1"]).To(Equal([]any{"map", []any{[]any{"claim", "default/claim"}, []any{"device", "vf-1"}}}))
+
+		// adding a port already on the bridge succeeds, e.g. after a restart
+		Expect(client.AddPort(ctx, "br-ex", "pf0vf1", nil)).To(Succeed())
+		Expect(db.bridges["br-ex"]).To(HaveLen(2))
+
+		Expect(client.DeletePort(ctx, "br-ex", "pf0vf1")).To(Succeed())
+		Expect(db.ports).NotTo(HaveKey("pf0vf1"))
+		Expect(db.bridges["br-ex"]).To(ConsistOf("uuid-uplink"))
+
+		// deleting a port already gone succeeds
+		Expect(client.DeletePort(ctx, "br-ex", "pf0vf1")).To(Succeed())
+	})
+
+	It("fails for a bridge not found", func() {
+		Expect(client.AddPort(ctx, "br-missing", "pf0vf1", nil)).To(MatchError("bridge br-missing not found"))
+		Expect(db.ports).NotTo(HaveKey("pf0vf1"))
+	})
+
+	It("fails for a port on another bridge", func() {
+		Expect(client.AddPort(ctx, "br-int", "p0", nil)).To(MatchError(ContainSubstring("already exists on another bridge than br-int")))
+	})
+
+	It("replies to the echo requests of the server", func() {
+		db.echo = true
+		Expect(client.AddPort(ctx, "br-int", "pf0vf1", nil)).To(Succeed())
+		Expect(db.bridges["br-int"]).To(HaveLen(1))
+	})
+
+	It("rejects endpoints of other kinds", func() {
+		_, err := ovs.NewClient("ssl:127.0.0.1:6640")
+		Expect(err).To(MatchError(ContainSubstring("invalid OVSDB endpoint")))
+		_, err = ovs.NewClient("/var/run/openvswitch/db.sock")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
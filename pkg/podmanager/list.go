@@ -0,0 +1,99 @@
+package podmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+// ListFilter restricts the entries returned by List. Empty fields match everything.
+type ListFilter struct {
+	PodUID     types.UID
+	ClaimUID   types.UID
+	PciAddress string
+}
+
+// PreparedClaim is a single claim prepared for a pod, as tracked by the PodManager.
+type PreparedClaim struct {
+	PodUID         types.UID                     `json:"podUID"`
+	ClaimUID       types.UID                     `json:"claimUID"`
+	ClaimNamespace string                        `json:"claimNamespace,omitempty"`
+	ClaimName      string                        `json:"claimName,omitempty"`
+	Devices        drasriovtypes.PreparedDevices `json:"devices"`
+}
+
+func (f ListFilter) matches(podUID, claimUID types.UID, devices drasriovtypes.PreparedDevices) bool {
+	if f.PodUID != "" && f.PodUID != podUID {
+		return false
+	}
+	if f.ClaimUID != "" && f.ClaimUID != claimUID {
+		return false
+	}
+	if f.PciAddress == "" {
+		return true
+	}
+	for _, device := range devices {
+		if device.PciAddress == f.PciAddress {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the prepared claims matching the filter, sorted by pod UID and claim UID.
+func (s *PodManager) List(filter ListFilter) []PreparedClaim {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	claims := []PreparedClaim{}
+	for podUID, preparedDevicesByClaimID := range s.preparedClaimsByPodUID {
+		for claimUID, devices := range preparedDevicesByClaimID {
+			if !filter.matches(podUID, claimUID, devices) {
+				continue
+			}
+			claim := PreparedClaim{
+				PodUID:   podUID,
+				ClaimUID: claimUID,
+				Devices:  append(drasriovtypes.PreparedDevices{}, devices...),
+			}
+			if len(devices) > 0 {
+				claim.ClaimNamespace = devices[0].ClaimNamespacedName.Namespace
+				claim.ClaimName = devices[0].ClaimNamespacedName.Name
+			}
+			claims = append(claims, claim)
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		if claims[i].PodUID != claims[j].PodUID {
+			return claims[i].PodUID < claims[j].PodUID
+		}
+		return claims[i].ClaimUID < claims[j].ClaimUID
+	})
+	return claims
+}
+
+// DebugHandler serves the prepared claims as JSON. The pod, claim and pciAddress query
+// parameters map to the corresponding ListFilter fields.
+func (s *PodManager) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		claims := s.List(ListFilter{
+			PodUID:     types.UID(query.Get("pod")),
+			ClaimUID:   types.UID(query.Get("claim")),
+			PciAddress: query.Get("pciAddress"),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(claims); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package podmanager
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -43,11 +44,26 @@ func NewPodManager(config *drasriovtypes.Config) (*PodManager, error) {
 		if c == consts.DriverPluginCheckpointFile {
 			klog.Infof("Found checkpoint: %s", c)
 			checkpoint := drasriovtypes.NewCheckpoint()
-			if err := checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, checkpoint); err != nil {
+			err := checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, checkpoint)
+			if err == nil {
+				podmManager.preparedClaimsByPodUID = checkpoint.V1.PreparedClaimsByPodUID
+				klog.Infof("Loaded checkpoint with %d pods", len(podmManager.preparedClaimsByPodUID))
+				return podmManager, nil
+			}
+			if !isCorruptCheckpoint(err) {
 				return nil, fmt.Errorf("unable to load checkpoint: %v", err)
 			}
-			podmManager.preparedClaimsByPodUID = checkpoint.V1.PreparedClaimsByPodUID
-			klog.Infof("Loaded checkpoint with %d pods", len(podmManager.preparedClaimsByPodUID))
+
+			backupPath, backupErr := backupCorruptCheckpoint(config)
+			if backupErr != nil {
+				return nil, backupErr
+			}
+			klog.Errorf("Checkpoint is corrupt, backed up to %s and starting from reconstructed state: %v", backupPath, err)
+			podmManager.preparedClaimsByPodUID = reconstructPreparedClaims(config)
+			if err := podmManager.syncToCheckpoint(); err != nil {
+				return nil, err
+			}
+			klog.Infof("Reconstructed checkpoint with %d pods", len(podmManager.preparedClaimsByPodUID))
 			return podmManager, nil
 		}
 	}
@@ -102,6 +118,33 @@ func (s *PodManager) GetDevicesByPodUID(podUID types.UID) (drasriovtypes.Prepare
 	return preparedDevices, true
 }
 
+// GetPodUIDs returns the UIDs of all pods that currently have prepared claims.
+func (s *PodManager) GetPodUIDs() []types.UID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	podUIDs := make([]types.UID, 0, len(s.preparedClaimsByPodUID))
+	for podUID := range s.preparedClaimsByPodUID {
+		podUIDs = append(podUIDs, podUID)
+	}
+	return podUIDs
+}
+
+// GetClaimsByPodUID retrieves the prepared devices of a pod grouped by claim ID.
+// The returned map is a copy and can be safely used without holding the lock.
+func (s *PodManager) GetClaimsByPodUID(podUID types.UID) (drasriovtypes.PreparedDevicesByClaimID, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	claims, exists := s.preparedClaimsByPodUID[podUID]
+	if !exists {
+		return drasriovtypes.PreparedDevicesByClaimID{}, false
+	}
+	result := make(drasriovtypes.PreparedDevicesByClaimID, len(claims))
+	for claimID, devices := range claims {
+		result[claimID] = devices
+	}
+	return result, true
+}
+
 // DeletePod removes all configurations associated with a given Pod UID.
 func (s *PodManager) DeletePod(podUID types.UID) error {
 	s.mu.Lock()
@@ -125,29 +168,177 @@ func (s *PodManager) GetByClaim(claim kubeletplugin.NamespacedObject) (drasriovt
 	return preparedDevices, false
 }
 
-// DeleteClaim removes all configurations associated with a given claim.
-// NOTE: for now we only support one pod per claim as VFs are not shared between pods
+// DeleteClaim removes a claim from every pod referencing it. Pods left without claims are removed.
 func (s *PodManager) DeleteClaim(claim kubeletplugin.NamespacedObject) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	podsToDelete := []types.UID{}
+	found := false
 	for uid, preparedDevicesByClaimID := range s.preparedClaimsByPodUID {
-		_, found := preparedDevicesByClaimID[claim.UID]
-		if found {
-			podsToDelete = append(podsToDelete, uid)
-			break
+		if _, ok := preparedDevicesByClaimID[claim.UID]; !ok {
+			continue
+		}
+		found = true
+		delete(preparedDevicesByClaimID, claim.UID)
+		if len(preparedDevicesByClaimID) == 0 {
+			delete(s.preparedClaimsByPodUID, uid)
 		}
 	}
 
-	if len(podsToDelete) > 0 {
-		for _, uid := range podsToDelete {
-			delete(s.preparedClaimsByPodUID, uid)
+	if found {
+		return s.syncToCheckpoint()
+	}
+	return nil
+}
+
+// SetShared stores copies of the prepared devices of a claim shared by several pods under each of
+// the pods, replacing the pods referencing it before, so the consumers of the claim can be
+// refreshed on each prepare. The number of pods referencing the claim acts as its reference count.
+func (s *PodManager) SetShared(podUIDs []types.UID, claimID types.UID, preparedDevices drasriovtypes.PreparedDevices) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for podUID, preparedDevicesByClaimID := range s.preparedClaimsByPodUID {
+		if _, ok := preparedDevicesByClaimID[claimID]; !ok || slices.Contains(podUIDs, podUID) {
+			continue
+		}
+		delete(preparedDevicesByClaimID, claimID)
+		if len(preparedDevicesByClaimID) == 0 {
+			delete(s.preparedClaimsByPodUID, podUID)
 		}
+	}
+	for _, podUID := range podUIDs {
+		if _, ok := s.preparedClaimsByPodUID[podUID]; !ok {
+			s.preparedClaimsByPodUID[podUID] = make(drasriovtypes.PreparedDevicesByClaimID)
+		}
+		s.preparedClaimsByPodUID[podUID][claimID] = podSharedDevices(preparedDevices, s.preparedClaimsByPodUID[podUID][claimID])
+	}
+	return s.syncToCheckpoint()
+}
+
+// podSharedDevices returns the copies of the devices of a shared claim stored for one of its pods,
+// with the CNI records of the devices previously stored for that pod, so the records of a pod
+// never leak to the others.
+func podSharedDevices(preparedDevices drasriovtypes.PreparedDevices, previousDevices drasriovtypes.PreparedDevices) drasriovtypes.PreparedDevices {
+	podDevices := make(drasriovtypes.PreparedDevices, 0, len(preparedDevices))
+	for _, preparedDevice := range preparedDevices {
+		podDevice := *preparedDevice
+		podDevice.CNISandboxID, podDevice.CNINetConf, podDevice.CNIResult = "", "", ""
+		podDevice.BondCNINetConf, podDevice.BondCNIResult = "", ""
+		for _, previousDevice := range previousDevices {
+			if previousDevice.Device.DeviceName == podDevice.Device.DeviceName {
+				podDevice.CNISandboxID, podDevice.CNINetConf, podDevice.CNIResult = previousDevice.CNISandboxID, previousDevice.CNINetConf, previousDevice.CNIResult
+				podDevice.BondCNINetConf, podDevice.BondCNIResult = previousDevice.BondCNINetConf, previousDevice.BondCNIResult
+			}
+		}
+		podDevices = append(podDevices, &podDevice)
+	}
+	return podDevices
+}
+
+// ClaimRefCount returns the number of pods referencing a claim.
+func (s *PodManager) ClaimRefCount(claimID types.UID) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, preparedDevicesByClaimID := range s.preparedClaimsByPodUID {
+		if _, ok := preparedDevicesByClaimID[claimID]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// ReleaseClaim drops the reference of a pod to a claim without touching the other pods, unless
+// it is the last reference to the claim, and reports whether it is. The last reference is kept,
+// so the caller can unprepare the claim before deleting it with DeleteClaim.
+func (s *PodManager) ReleaseClaim(podUID types.UID, claimID types.UID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	preparedDevicesByClaimID, ok := s.preparedClaimsByPodUID[podUID]
+	if !ok {
+		return false, nil
+	}
+	if _, ok := preparedDevicesByClaimID[claimID]; !ok {
+		return false, nil
+	}
+	for otherPodUID, otherPreparedDevicesByClaimID := range s.preparedClaimsByPodUID {
+		if _, ok := otherPreparedDevicesByClaimID[claimID]; ok && otherPodUID != podUID {
+			delete(preparedDevicesByClaimID, claimID)
+			if len(preparedDevicesByClaimID) == 0 {
+				delete(s.preparedClaimsByPodUID, podUID)
+			}
+			return false, s.syncToCheckpoint()
+		}
+	}
+	return true, nil
+}
+
+// SetCNIAttachment records the sandbox, netconf and result of the CNI ADD of a device of a pod
+// and persists them, so the device can be detached after a restart of the driver. Empty values
+// clear the record once the device is detached.
+func (s *PodManager) SetCNIAttachment(podUID types.UID, claimID types.UID, deviceName string, sandboxID string, netConf string, cniResult string) error {
+	return s.updateDevice(podUID, claimID, deviceName, func(preparedDevice *drasriovtypes.PreparedDevice) {
+		preparedDevice.CNISandboxID = sandboxID
+		preparedDevice.CNINetConf = netConf
+		preparedDevice.CNIResult = cniResult
+	})
+}
+
+// SetBondCNIAttachment records the netconf and result of the CNI ADD of the bond kept on a device
+// of a pod, see PreparedDevice.BondNetConf. Empty values clear the record once the bond is
+// detached.
+func (s *PodManager) SetBondCNIAttachment(podUID types.UID, claimID types.UID, deviceName string, netConf string, cniResult string) error {
+	return s.updateDevice(podUID, claimID, deviceName, func(preparedDevice *drasriovtypes.PreparedDevice) {
+		preparedDevice.BondCNINetConf = netConf
+		preparedDevice.BondCNIResult = cniResult
+	})
+}
+
+// updateDevice replaces a device of a claim of a pod with a copy changed by update and persists
+// it. The devices handed out by the getters are read without the lock, so they are never changed
+// in place.
+func (s *PodManager) updateDevice(podUID types.UID, claimID types.UID, deviceName string, update func(*drasriovtypes.PreparedDevice)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	preparedDevices := s.preparedClaimsByPodUID[podUID][claimID]
+	for i, preparedDevice := range preparedDevices {
+		if preparedDevice.Device.DeviceName != deviceName {
+			continue
+		}
+		updatedDevice := *preparedDevice
+		update(&updatedDevice)
+		updatedDevices := slices.Clone(preparedDevices)
+		updatedDevices[i] = &updatedDevice
+		s.preparedClaimsByPodUID[podUID][claimID] = updatedDevices
 		return s.syncToCheckpoint()
 	}
+	return fmt.Errorf("device %s of claim %s not found for pod %s", deviceName, claimID, podUID)
+}
+
+// Reload replaces the in-memory state with the content of the checkpoint, picking up the claims
+// prepared or unprepared by another driver instance sharing the plugin data directory during a
+// rolling update.
+func (s *PodManager) Reload() error {
+	checkpoint := drasriovtypes.NewCheckpoint()
+	if err := s.checkpointManager.GetCheckpoint(consts.DriverPluginCheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("unable to reload checkpoint: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preparedClaimsByPodUID = checkpoint.V1.PreparedClaimsByPodUID
+	if s.preparedClaimsByPodUID == nil {
+		s.preparedClaimsByPodUID = make(drasriovtypes.PreparedClaimsByPodUID)
+	}
 	return nil
 }
 
+// Flush writes the in-memory state to the checkpoint, e.g. on shutdown once the operations in
+// progress completed.
+func (s *PodManager) Flush() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.syncToCheckpoint()
+}
+
 func (s *PodManager) syncToCheckpoint() error {
 	checkpoint := drasriovtypes.NewCheckpoint()
 	checkpoint.V1.PreparedClaimsByPodUID = s.preparedClaimsByPodUID
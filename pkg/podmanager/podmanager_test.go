@@ -1,15 +1,25 @@
 package podmanager_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
 	draTypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
@@ -121,6 +131,216 @@ var _ = Describe("PodManager", func() {
 		})
 	})
 
+	Context("Corrupt checkpoint recovery", func() {
+		var checkpointPath string
+
+		BeforeEach(func() {
+			Expect(os.MkdirAll(config.DriverPluginPath(), 0o755)).To(Succeed())
+			checkpointPath = filepath.Join(config.DriverPluginPath(), "checkpoint.json")
+		})
+
+		It("should back up an undecodable checkpoint and start empty", func() {
+			Expect(os.WriteFile(checkpointPath, []byte("{not json"), 0o600)).To(Succeed())
+
+			pm, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pm.GetPodUIDs()).To(BeEmpty())
+
+			backups, err := filepath.Glob(checkpointPath + ".corrupt-*")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(1))
+			content, err := os.ReadFile(backups[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("{not json"))
+
+			// a fresh checkpoint replaces the corrupt one
+			_, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should back up a checkpoint with a wrong checksum", func() {
+			pm, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+
+			content, err := os.ReadFile(checkpointPath)
+			Expect(err).NotTo(HaveOccurred())
+			tampered := strings.Replace(string(content), "0000:01:00.0", "0000:02:00.0", 1)
+			Expect(os.WriteFile(checkpointPath, []byte(tampered), 0o600)).To(Succeed())
+
+			pm, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pm.GetPodUIDs()).To(BeEmpty())
+
+			backups, err := filepath.Glob(checkpointPath + ".corrupt-*")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(1))
+		})
+
+		It("should reconstruct prepared claims from the recovery data of the CDI spec files", func() {
+			config.Flags.CdiRoot = filepath.Join(tempDir, "cdi")
+			config.Flags.NodeName = "node1"
+			Expect(os.MkdirAll(config.Flags.CdiRoot, 0o755)).To(Succeed())
+
+			cdiHandler, err := cdi.NewHandler(config.Flags.CdiRoot)
+			Expect(err).NotTo(HaveOccurred())
+			for _, device := range devices {
+				device.ClaimNamespacedName.Name = "test-claim"
+				device.ClaimNamespacedName.Namespace = "test-ns"
+				device.Device.RequestNames = []string{"vf"}
+				device.Config = &configapi.VfConfig{NetAttachDefName: "sriov-net", NetAttachDefNamespace: "net-ns"}
+				device.ContainerEdits = &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{Env: []string{"TEST_ENV=test_value"}}}
+			}
+			Expect(cdiHandler.CreateClaimSpecFile(devices)).To(Succeed())
+			Expect(cdiHandler.CreateGlobalPodSpecFile(string(podUID), []string{"0000:01:00.0", "0000:01:00.1"})).To(Succeed())
+
+			Expect(os.WriteFile(checkpointPath, []byte("{not json"), 0o600)).To(Succeed())
+
+			pm, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			recovered, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			Expect(recovered).To(HaveLen(2))
+			pciAddresses := []string{}
+			for _, device := range recovered {
+				pciAddresses = append(pciAddresses, device.PciAddress)
+				Expect(device.ClaimNamespacedName.Name).To(Equal("test-claim"))
+				Expect(device.ClaimNamespacedName.Namespace).To(Equal("test-ns"))
+				Expect(device.Device.RequestNames).To(Equal([]string{"vf"}))
+				Expect(device.Config.NetAttachDefName).To(Equal("sriov-net"))
+				Expect(device.Config.NetAttachDefNamespace).To(Equal("net-ns"))
+			}
+			Expect(pciAddresses).To(ConsistOf("0000:01:00.0", "0000:01:00.1"))
+		})
+
+		It("should fall back to the environment variables of CDI spec files without recovery data", func() {
+			config.Flags.CdiRoot = filepath.Join(tempDir, "cdi")
+			config.Flags.NodeName = "node1"
+			Expect(os.MkdirAll(config.Flags.CdiRoot, 0o755)).To(Succeed())
+
+			cdiHandler, err := cdi.NewHandler(config.Flags.CdiRoot)
+			Expect(err).NotTo(HaveOccurred())
+			for _, device := range devices {
+				device.ContainerEdits = &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{
+					Env: []string{
+						fmt.Sprintf("SRIOVNETWORK_VF_DEVICE_%s=%s", strings.ReplaceAll(device.Device.DeviceName, "-", "_"), device.PciAddress),
+						"SRIOVNETWORK_NET_ATTACH_DEF_NAME=sriov-net",
+					},
+				}}
+			}
+			Expect(cdiHandler.CreateClaimSpecFile(devices)).To(Succeed())
+			removeRecoveryData(config.Flags.CdiRoot)
+			Expect(cdiHandler.CreateGlobalPodSpecFile(string(podUID), []string{"0000:01:00.0", "0000:01:00.1"})).To(Succeed())
+			// a pod spec that does not reference the claim devices
+			Expect(cdiHandler.CreateGlobalPodSpecFile("other-pod", []string{"0000:03:00.0"})).To(Succeed())
+
+			Expect(os.WriteFile(checkpointPath, []byte("{not json"), 0o600)).To(Succeed())
+
+			pm, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pm.GetPodUIDs()).To(ConsistOf(podUID))
+
+			recovered, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			Expect(recovered).To(HaveLen(2))
+			pciAddresses := []string{}
+			for _, device := range recovered {
+				pciAddresses = append(pciAddresses, device.PciAddress)
+				Expect(device.PodUID).To(Equal(string(podUID)))
+				Expect(device.ClaimNamespacedName.UID).To(Equal(claimUID))
+				Expect(device.Config.NetAttachDefName).To(Equal("sriov-net"))
+				Expect(device.Device.PoolName).To(Equal("node1"))
+				Expect(device.Device.CDIDeviceIDs).To(ContainElement(cdiHandler.GetPodSpecName(string(podUID))))
+			}
+			Expect(pciAddresses).To(ConsistOf("0000:01:00.0", "0000:01:00.1"))
+
+			// the reconstructed state is persisted
+			pm2, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			_, found = pm2.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+		})
+		It("should reconstruct a claim shared by several pods as shared", func() {
+			config.Flags.CdiRoot = filepath.Join(tempDir, "cdi")
+			Expect(os.MkdirAll(config.Flags.CdiRoot, 0o755)).To(Succeed())
+
+			cdiHandler, err := cdi.NewHandler(config.Flags.CdiRoot)
+			Expect(err).NotTo(HaveOccurred())
+			for _, device := range devices {
+				device.ContainerEdits = &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{
+					Env: []string{fmt.Sprintf("SRIOVNETWORK_VF_DEVICE_%s=%s", strings.ReplaceAll(device.Device.DeviceName, "-", "_"), device.PciAddress)},
+				}}
+			}
+			Expect(cdiHandler.CreateClaimSpecFile(devices)).To(Succeed())
+			Expect(cdiHandler.CreateGlobalPodSpecFile(string(podUID), []string{"0000:01:00.0", "0000:01:00.1"})).To(Succeed())
+			Expect(cdiHandler.CreateGlobalPodSpecFile("other-pod", []string{"0000:01:00.0", "0000:01:00.1"})).To(Succeed())
+
+			Expect(os.WriteFile(checkpointPath, []byte("{not json"), 0o600)).To(Succeed())
+
+			pm, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pm.ClaimRefCount(claimUID)).To(Equal(2))
+			recovered, found := pm.Get("other-pod", claimUID)
+			Expect(found).To(BeTrue())
+			for _, device := range recovered {
+				Expect(device.Shared).To(BeTrue())
+				Expect(device.PodUID).To(BeEmpty())
+				Expect(device.Device.CDIDeviceIDs).NotTo(ContainElement(cdiHandler.GetPodSpecName("other-pod")))
+			}
+		})
+	})
+
+	Context("List and DebugHandler", func() {
+		BeforeEach(func() {
+			var err error
+			pm, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			devices[0].ClaimNamespacedName.Namespace = "default"
+			devices[0].ClaimNamespacedName.Name = "claim-a"
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.Set("other-pod", "other-claim", draTypes.PreparedDevices{{PciAddress: "0000:03:00.0"}})).To(Succeed())
+		})
+
+		It("should list all prepared claims sorted by pod UID", func() {
+			claims := pm.List(podmanager.ListFilter{})
+			Expect(claims).To(HaveLen(2))
+			Expect(claims[0].PodUID).To(Equal(types.UID("other-pod")))
+			Expect(claims[1].PodUID).To(Equal(podUID))
+			Expect(claims[1].ClaimUID).To(Equal(claimUID))
+			Expect(claims[1].ClaimNamespace).To(Equal("default"))
+			Expect(claims[1].ClaimName).To(Equal("claim-a"))
+			Expect(claims[1].Devices).To(HaveLen(2))
+		})
+
+		It("should filter by pod, claim and PCI address", func() {
+			Expect(pm.List(podmanager.ListFilter{PodUID: podUID})).To(HaveLen(1))
+			Expect(pm.List(podmanager.ListFilter{ClaimUID: "other-claim"})).To(HaveLen(1))
+			Expect(pm.List(podmanager.ListFilter{PciAddress: "0000:01:00.1"})).To(HaveLen(1))
+			Expect(pm.List(podmanager.ListFilter{PodUID: podUID, ClaimUID: "other-claim"})).To(BeEmpty())
+			Expect(pm.List(podmanager.ListFilter{PciAddress: "0000:09:00.0"})).To(BeEmpty())
+		})
+
+		It("should serve the filtered claims as JSON", func() {
+			recorder := httptest.NewRecorder()
+			pm.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/prepared-claims?pciAddress=0000:03:00.0", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var claims []podmanager.PreparedClaim
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &claims)).To(Succeed())
+			Expect(claims).To(HaveLen(1))
+			Expect(claims[0].ClaimUID).To(Equal(types.UID("other-claim")))
+		})
+
+		It("should reject non-GET requests", func() {
+			recorder := httptest.NewRecorder()
+			pm.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/prepared-claims", nil))
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
 	Context("Set and Get operations", func() {
 		BeforeEach(func() {
 			var err error
@@ -357,7 +577,7 @@ var _ = Describe("PodManager", func() {
 			_, found = pm.GetByClaim(claim)
 			Expect(found).To(BeFalse())
 
-			// Verify entire pod was deleted (current implementation deletes whole pod)
+			// Verify the pod was deleted as it has no claims left
 			_, found = pm.GetDevicesByPodUID(podUID)
 			Expect(found).To(BeFalse())
 		})
@@ -385,6 +605,104 @@ var _ = Describe("PodManager", func() {
 		})
 	})
 
+	Context("Shared claims", func() {
+		var otherPodUID types.UID
+
+		BeforeEach(func() {
+			var err error
+			pm, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			otherPodUID = types.UID("other-pod")
+			Expect(pm.SetShared([]types.UID{podUID, otherPodUID}, claimUID, devices)).To(Succeed())
+		})
+
+		It("should reference the claim from every pod", func() {
+			Expect(pm.ClaimRefCount(claimUID)).To(Equal(2))
+			_, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			_, found = pm.Get(otherPodUID, claimUID)
+			Expect(found).To(BeTrue())
+		})
+
+		It("should release a single pod reference and keep the last one", func() {
+			last, err := pm.ReleaseClaim(podUID, claimUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(last).To(BeFalse())
+			Expect(pm.GetPodUIDs()).To(ConsistOf(otherPodUID))
+
+			last, err = pm.ReleaseClaim(otherPodUID, claimUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(last).To(BeTrue())
+			Expect(pm.GetPodUIDs()).To(ConsistOf(otherPodUID))
+			Expect(pm.ClaimRefCount(claimUID)).To(Equal(1))
+
+			// a pod without the claim holds no reference
+			last, err = pm.ReleaseClaim(podUID, claimUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(last).To(BeFalse())
+		})
+
+		It("should replace the pods referencing the claim", func() {
+			thirdPodUID := types.UID("third-pod")
+			Expect(pm.Set(podUID, types.UID("own-claim"), devices[:1])).To(Succeed())
+
+			Expect(pm.SetShared([]types.UID{otherPodUID, thirdPodUID}, claimUID, devices)).To(Succeed())
+			Expect(pm.ClaimRefCount(claimUID)).To(Equal(2))
+			_, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeFalse())
+			_, found = pm.Get(thirdPodUID, claimUID)
+			Expect(found).To(BeTrue())
+			// the other claims of the pods are kept
+			_, found = pm.Get(podUID, types.UID("own-claim"))
+			Expect(found).To(BeTrue())
+		})
+
+		It("should remove the claim from all pods on DeleteClaim and keep their other claims", func() {
+			Expect(pm.Set(otherPodUID, types.UID("own-claim"), devices[:1])).To(Succeed())
+
+			Expect(pm.DeleteClaim(kubeletplugin.NamespacedObject{UID: claimUID})).To(Succeed())
+			Expect(pm.ClaimRefCount(claimUID)).To(Equal(0))
+			Expect(pm.GetPodUIDs()).To(ConsistOf(otherPodUID))
+			_, found := pm.Get(otherPodUID, types.UID("own-claim"))
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Context("GetPodUIDs and GetClaimsByPodUID", func() {
+		BeforeEach(func() {
+			var err error
+			pm, err = podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should list all tracked pods", func() {
+			Expect(pm.GetPodUIDs()).To(BeEmpty())
+
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.Set(types.UID("other-pod"), types.UID("other-claim"), devices[:1])).To(Succeed())
+
+			Expect(pm.GetPodUIDs()).To(ConsistOf(podUID, types.UID("other-pod")))
+		})
+
+		It("should return a copy of the claims of a pod", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+
+			claims, found := pm.GetClaimsByPodUID(podUID)
+			Expect(found).To(BeTrue())
+			Expect(claims).To(HaveKey(claimUID))
+			Expect(claims[claimUID]).To(HaveLen(2))
+
+			delete(claims, claimUID)
+			_, found = pm.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+		})
+
+		It("should return false for non-existent pod", func() {
+			_, found := pm.GetClaimsByPodUID(types.UID("non-existent-pod"))
+			Expect(found).To(BeFalse())
+		})
+	})
+
 	Context("Checkpoint synchronization", func() {
 		BeforeEach(func() {
 			var err error
@@ -418,6 +736,100 @@ var _ = Describe("PodManager", func() {
 			Expect(found).To(BeFalse())
 		})
 
+		It("should reload the claims written by another instance", func() {
+			pm2, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pm2.Set(podUID, claimUID, devices)).To(Succeed())
+
+			_, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeFalse())
+			Expect(pm.Reload()).To(Succeed())
+			retrievedDevices, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			Expect(retrievedDevices).To(HaveLen(2))
+
+			Expect(pm2.DeletePod(podUID)).To(Succeed())
+			Expect(pm.Reload()).To(Succeed())
+			_, found = pm.Get(podUID, claimUID)
+			Expect(found).To(BeFalse())
+		})
+
+		It("should persist the CNI attachment of a device", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.SetCNIAttachment(podUID, claimUID, "test-device-2", "sandbox-1", `{"type":"sriov"}`, `{"cniVersion":"1.0.0"}`)).To(Succeed())
+
+			pm2, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			retrievedDevices, found := pm2.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			Expect(retrievedDevices[0].CNINetConf).To(BeEmpty())
+			Expect(retrievedDevices[1].CNISandboxID).To(Equal("sandbox-1"))
+			Expect(retrievedDevices[1].CNINetConf).To(Equal(`{"type":"sriov"}`))
+			Expect(retrievedDevices[1].CNIResult).To(Equal(`{"cniVersion":"1.0.0"}`))
+		})
+
+		It("should record the CNI attachment on a copy of the device", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			handedOut, found := pm.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+
+			Expect(pm.SetCNIAttachment(podUID, claimUID, "test-device", "sandbox-1", "{}", "{}")).To(Succeed())
+			// the devices handed out before are read without the lock and left untouched
+			Expect(handedOut[0].CNISandboxID).To(BeEmpty())
+			Expect(devices[0].CNISandboxID).To(BeEmpty())
+			retrievedDevices, _ := pm.Get(podUID, claimUID)
+			Expect(retrievedDevices[0].CNISandboxID).To(Equal("sandbox-1"))
+		})
+
+		It("should keep the CNI attachment of a pod out of the other pods sharing the claim", func() {
+			Expect(pm.SetShared([]types.UID{podUID, "other-pod"}, claimUID, devices)).To(Succeed())
+			Expect(pm.SetCNIAttachment(podUID, claimUID, "test-device", "sandbox-1", "{}", "{}")).To(Succeed())
+
+			otherDevices, found := pm.Get("other-pod", claimUID)
+			Expect(found).To(BeTrue())
+			Expect(otherDevices[0].CNISandboxID).To(BeEmpty())
+
+			// refreshing the pods of the claim keeps the attachment of each pod
+			Expect(pm.SetShared([]types.UID{podUID, "other-pod", "third-pod"}, claimUID, otherDevices)).To(Succeed())
+			retrievedDevices, _ := pm.Get(podUID, claimUID)
+			Expect(retrievedDevices[0].CNISandboxID).To(Equal("sandbox-1"))
+			thirdDevices, _ := pm.Get("third-pod", claimUID)
+			Expect(thirdDevices[0].CNISandboxID).To(BeEmpty())
+		})
+
+		It("should fail to record the CNI attachment of an unknown device", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.SetCNIAttachment(podUID, claimUID, "missing", "sandbox-1", "{}", "{}")).NotTo(Succeed())
+		})
+
+		It("should persist and clear the bond CNI attachment of a device", func() {
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			Expect(pm.SetBondCNIAttachment(podUID, claimUID, "test-device", `{"type":"bond"}`, `{"cniVersion":"1.0.0"}`)).To(Succeed())
+
+			pm2, err := podmanager.NewPodManager(config)
+			Expect(err).NotTo(HaveOccurred())
+			retrievedDevices, found := pm2.Get(podUID, claimUID)
+			Expect(found).To(BeTrue())
+			Expect(retrievedDevices[0].BondCNINetConf).To(Equal(`{"type":"bond"}`))
+			Expect(retrievedDevices[0].BondCNIResult).To(Equal(`{"cniVersion":"1.0.0"}`))
+
+			Expect(pm.SetBondCNIAttachment(podUID, claimUID, "test-device", "", "")).To(Succeed())
+			retrievedDevices, _ = pm.Get(podUID, claimUID)
+			Expect(retrievedDevices[0].BondCNINetConf).To(BeEmpty())
+			Expect(pm.SetBondCNIAttachment(podUID, claimUID, "missing", "{}", "{}")).NotTo(Succeed())
+		})
+
+		It("should keep the checkpoint format of devices without optional fields", func() {
+			// older checkpoints don't know the optional fields, their checksum must stay valid
+			Expect(pm.Set(podUID, claimUID, devices)).To(Succeed())
+			data, err := os.ReadFile(filepath.Join(config.DriverPluginPath(), consts.DriverPluginCheckpointFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("Shared"))
+			Expect(string(data)).NotTo(ContainSubstring("CNISandboxID"))
+			Expect(string(data)).NotTo(ContainSubstring("CNINetConf"))
+			Expect(string(data)).NotTo(ContainSubstring("CNIResult"))
+		})
+
 		It("should handle checkpoint sync errors gracefully", func() {
 			// This is hard to test without mocking the checkpoint manager
 			// For now, we'll test that normal operations work
@@ -506,3 +918,21 @@ var _ = Describe("PodManager", func() {
 		})
 	})
 })
+
+// removeRecoveryData removes the recovery data from the CDI specs of a directory, as written by
+// older versions of the driver.
+func removeRecoveryData(cdiRoot string) {
+	specFiles, err := filepath.Glob(filepath.Join(cdiRoot, "*"))
+	Expect(err).NotTo(HaveOccurred())
+	for _, specFile := range specFiles {
+		data, err := os.ReadFile(specFile)
+		Expect(err).NotTo(HaveOccurred())
+		lines := []string{}
+		for _, line := range strings.Split(string(data), "\n") {
+			if !strings.Contains(line, consts.CDIAnnotationRecovery) {
+				lines = append(lines, line)
+			}
+		}
+		Expect(os.WriteFile(specFile, []byte(strings.Join(lines, "\n")), 0o600)).To(Succeed())
+	}
+}
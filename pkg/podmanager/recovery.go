@@ -0,0 +1,255 @@
+package podmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/klog/v2"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	cmerrors "k8s.io/kubernetes/pkg/kubelet/checkpointmanager/errors"
+	drastate "k8s.io/kubernetes/pkg/kubelet/cm/dra/state"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	drasriovtypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	podSpecPciAddressesEnv = "SRIOVNETWORK_PCI_ADDRESSES="
+	// the environment variables of the claim specs are only parsed for the devices without
+	// recovery data, written by older versions of the driver
+	vfDeviceEnvPrefix   = "SRIOVNETWORK_VF_DEVICE_"
+	netAttachDefNameEnv = "SRIOVNETWORK_NET_ATTACH_DEF_NAME="
+)
+
+// backupCorruptCheckpoint moves a checkpoint that failed to load aside so the driver can start with an
+// empty state. The backup is kept next to the checkpoint for later inspection.
+func backupCorruptCheckpoint(config *drasriovtypes.Config) (string, error) {
+	checkpointPath := filepath.Join(config.DriverPluginPath(), consts.DriverPluginCheckpointFile)
+	backupPath := fmt.Sprintf("%s.corrupt-%d", checkpointPath, time.Now().Unix())
+	if err := os.Rename(checkpointPath, backupPath); err != nil {
+		return "", fmt.Errorf("unable to back up corrupt checkpoint: %v", err)
+	}
+	return backupPath, nil
+}
+
+// isCorruptCheckpoint reports whether err means the checkpoint content could not be decoded or verified.
+func isCorruptCheckpoint(err error) bool {
+	if errors.Is(err, cmerrors.CorruptCheckpointError{}) {
+		return true
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// reconstructPreparedClaims rebuilds a best-effort view of the prepared claims from the CDI spec files
+// written by the driver and, when readable, the kubelet DRA manager checkpoint.
+// Information that is only kept in the driver checkpoint (original driver and VF settings, interface
+// name, full VF config) cannot be recovered.
+func reconstructPreparedClaims(config *drasriovtypes.Config) drasriovtypes.PreparedClaimsByPodUID {
+	preparedClaims := make(drasriovtypes.PreparedClaimsByPodUID)
+	if config.Flags.CdiRoot == "" {
+		return preparedClaims
+	}
+
+	cdiHandler, err := cdi.NewHandler(config.Flags.CdiRoot)
+	if err != nil {
+		klog.Errorf("Unable to read CDI specs for checkpoint reconstruction: %v", err)
+		return preparedClaims
+	}
+	claimStates := readKubeletClaimStates(config)
+
+	claimDevices := make(map[types.UID]drasriovtypes.PreparedDevices)
+	podPciAddresses := make(map[types.UID][]string)
+	for uid, devices := range cdiHandler.ListTransientSpecs() {
+		if pciAddresses, ok := podSpecPciAddresses(uid, devices); ok {
+			podPciAddresses[types.UID(uid)] = pciAddresses
+			continue
+		}
+		claimDevices[types.UID(uid)] = claimSpecDevices(config, cdiHandler, types.UID(uid), devices, claimStates[types.UID(uid)])
+	}
+
+	for claimUID, preparedDevices := range claimDevices {
+		if len(preparedDevices) == 0 {
+			continue
+		}
+		podUIDs := claimPodUIDs(claimUID, preparedDevices, podPciAddresses, claimStates)
+		// the devices of a claim shared by several pods belong to none of them
+		shared := len(podUIDs) > 1
+		for _, podUID := range podUIDs {
+			devices := make(drasriovtypes.PreparedDevices, 0, len(preparedDevices))
+			for _, device := range preparedDevices {
+				deviceCopy := *device
+				deviceCopy.Shared = shared
+				if !shared {
+					deviceCopy.PodUID = string(podUID)
+					deviceCopy.Device.CDIDeviceIDs = append(slices.Clone(device.Device.CDIDeviceIDs),
+						cdiHandler.GetPodSpecName(string(podUID)))
+				}
+				devices = append(devices, &deviceCopy)
+			}
+			if _, ok := preparedClaims[podUID]; !ok {
+				preparedClaims[podUID] = make(drasriovtypes.PreparedDevicesByClaimID)
+			}
+			preparedClaims[podUID][claimUID] = devices
+		}
+	}
+	return preparedClaims
+}
+
+// readKubeletClaimStates returns the claims of this driver known to the kubelet, keyed by claim UID.
+func readKubeletClaimStates(config *drasriovtypes.Config) map[types.UID]drastate.ClaimInfoState {
+	claimStates := make(map[types.UID]drastate.ClaimInfoState)
+	statePath := filepath.Join(filepath.Dir(config.Flags.KubeletPluginsDirectoryPath), consts.KubeletDRAStateFile)
+	blob, err := os.ReadFile(statePath) /* #nosec G304 */
+	if err != nil {
+		klog.V(2).Infof("Kubelet DRA state not available for checkpoint reconstruction: %v", err)
+		return claimStates
+	}
+	checkpoint := &drastate.Checkpoint{}
+	if err := checkpoint.UnmarshalCheckpoint(blob); err != nil {
+		klog.Errorf("Unable to decode kubelet DRA state %s: %v", statePath, err)
+		return claimStates
+	}
+	stateList, err := checkpoint.GetClaimInfoStateList()
+	if err != nil {
+		klog.Errorf("Unable to decode kubelet DRA claim state %s: %v", statePath, err)
+		return claimStates
+	}
+	for _, claimState := range stateList {
+		if _, ok := claimState.DriverState[consts.DriverName]; ok {
+			claimStates[claimState.ClaimUID] = claimState
+		}
+	}
+	return claimStates
+}
+
+// podSpecPciAddresses returns the PCI addresses of a pod spec created by CreateGlobalPodSpecFile.
+func podSpecPciAddresses(uid string, devices []cdispec.Device) ([]string, bool) {
+	if len(devices) != 1 || devices[0].Name != uid {
+		return nil, false
+	}
+	for _, env := range devices[0].ContainerEdits.Env {
+		if value, ok := strings.CutPrefix(env, podSpecPciAddressesEnv); ok {
+			return strings.Split(value, ","), true
+		}
+	}
+	return nil, false
+}
+
+// claimSpecDevices rebuilds the prepared devices of a claim spec created by CreateClaimSpecFile,
+// from the recovery data of its devices or, without it, from their environment variables.
+func claimSpecDevices(config *drasriovtypes.Config, cdiHandler *cdi.Handler, claimUID types.UID, devices []cdispec.Device,
+	claimState drastate.ClaimInfoState) drasriovtypes.PreparedDevices {
+	kubeletDevices := make(map[string]drastate.Device)
+	for _, device := range claimState.DriverState[consts.DriverName].Devices {
+		kubeletDevices[device.DeviceName] = device
+	}
+
+	preparedDevices := drasriovtypes.PreparedDevices{}
+	for _, device := range devices {
+		deviceName, ok := strings.CutPrefix(device.Name, string(claimUID)+"-")
+		if !ok {
+			continue
+		}
+		preparedDevice := &drasriovtypes.PreparedDevice{
+			Device: drapbv1.Device{
+				PoolName:     config.Flags.NodeName,
+				DeviceName:   deviceName,
+				CDIDeviceIDs: []string{cdiHandler.GetClaimDevices(string(claimUID), deviceName)},
+			},
+			ClaimNamespacedName: kubeletplugin.NamespacedObject{
+				NamespacedName: types.NamespacedName{
+					Namespace: claimState.Namespace,
+					Name:      claimState.ClaimName,
+				},
+				UID: claimUID,
+			},
+			ContainerEdits: &cdiapi.ContainerEdits{ContainerEdits: &device.ContainerEdits},
+			Config:         &configapi.VfConfig{},
+		}
+		if kubeletDevice, ok := kubeletDevices[deviceName]; ok {
+			preparedDevice.Device.PoolName = kubeletDevice.PoolName
+			preparedDevice.Device.RequestNames = kubeletDevice.RequestNames
+		}
+
+		recoveryData, err := cdi.DeviceRecoveryData(device)
+		if err != nil {
+			klog.Errorf("Unable to read recovery data of CDI device %s: %v", device.Name, err)
+		}
+		if recoveryData != nil {
+			applyRecoveryData(preparedDevice, recoveryData)
+		} else {
+			klog.Infof("No recovery data for CDI device %s, falling back to its environment variables", device.Name)
+			applyDeviceEnv(preparedDevice, device.ContainerEdits.Env)
+		}
+		preparedDevices = append(preparedDevices, preparedDevice)
+	}
+	return preparedDevices
+}
+
+// applyRecoveryData sets the claim, device and net attach def data recorded in the CDI device of a
+// prepared device.
+func applyRecoveryData(preparedDevice *drasriovtypes.PreparedDevice, recoveryData *cdi.RecoveryData) {
+	preparedDevice.ClaimNamespacedName.Namespace = recoveryData.ClaimNamespace
+	preparedDevice.ClaimNamespacedName.Name = recoveryData.ClaimName
+	if recoveryData.PoolName != "" {
+		preparedDevice.Device.PoolName = recoveryData.PoolName
+	}
+	if len(recoveryData.RequestNames) > 0 {
+		preparedDevice.Device.RequestNames = recoveryData.RequestNames
+	}
+	preparedDevice.PciAddress = recoveryData.PciAddress
+	preparedDevice.Config.NetAttachDefName = recoveryData.NetAttachDefName
+	preparedDevice.Config.NetAttachDefNamespace = recoveryData.NetAttachDefNamespace
+}
+
+// applyDeviceEnv sets the PCI address and net attach def name of a prepared device from the
+// environment variables of its CDI device.
+func applyDeviceEnv(preparedDevice *drasriovtypes.PreparedDevice, envs []string) {
+	pciAddressEnv := vfDeviceEnvPrefix + strings.ReplaceAll(preparedDevice.Device.DeviceName, "-", "_") + "="
+	for _, env := range envs {
+		if value, ok := strings.CutPrefix(env, pciAddressEnv); ok {
+			preparedDevice.PciAddress = value
+		}
+		if value, ok := strings.CutPrefix(env, netAttachDefNameEnv); ok {
+			preparedDevice.Config.NetAttachDefName = value
+		}
+	}
+}
+
+// claimPodUIDs returns the pods that consume a claim. The kubelet state is authoritative when available,
+// otherwise pods are matched through the PCI addresses listed in their pod spec.
+func claimPodUIDs(claimUID types.UID, preparedDevices drasriovtypes.PreparedDevices,
+	podPciAddresses map[types.UID][]string, claimStates map[types.UID]drastate.ClaimInfoState) []types.UID {
+	if claimState, ok := claimStates[claimUID]; ok && claimState.PodUIDs.Len() > 0 {
+		podUIDs := make([]types.UID, 0, claimState.PodUIDs.Len())
+		for podUID := range claimState.PodUIDs {
+			podUIDs = append(podUIDs, types.UID(podUID))
+		}
+		return podUIDs
+	}
+
+	podUIDs := []types.UID{}
+	for podUID, pciAddresses := range podPciAddresses {
+		for _, device := range preparedDevices {
+			if device.PciAddress != "" && slices.Contains(pciAddresses, device.PciAddress) {
+				podUIDs = append(podUIDs, podUID)
+				break
+			}
+		}
+	}
+	return podUIDs
+}
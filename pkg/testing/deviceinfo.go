@@ -0,0 +1,48 @@
+package testing
+
+import (
+	"sync"
+
+	nettypes "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+)
+
+// FakeDeviceInfoStore keeps the device-info written for Multus in memory instead of on disk.
+type FakeDeviceInfoStore struct {
+	mu    sync.Mutex
+	infos map[string]*nettypes.DeviceInfo
+}
+
+var _ devicestate.DeviceInfoStore = (*FakeDeviceInfoStore)(nil)
+
+// NewFakeDeviceInfoStore returns an empty FakeDeviceInfoStore.
+func NewFakeDeviceInfoStore() *FakeDeviceInfoStore {
+	return &FakeDeviceInfoStore{infos: map[string]*nettypes.DeviceInfo{}}
+}
+
+func deviceInfoKey(resourceName, deviceID string) string {
+	return resourceName + "/" + deviceID
+}
+
+func (s *FakeDeviceInfoStore) SaveDeviceInfoForDP(resourceName, deviceID string, devInfo *nettypes.DeviceInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.infos[deviceInfoKey(resourceName, deviceID)] = devInfo
+	return nil
+}
+
+func (s *FakeDeviceInfoStore) CleanDeviceInfoForDP(resourceName, deviceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.infos, deviceInfoKey(resourceName, deviceID))
+	return nil
+}
+
+// Get returns the device-info saved for a resource name and device ID.
+func (s *FakeDeviceInfoStore) Get(resourceName, deviceID string) (*nettypes.DeviceInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.infos[deviceInfoKey(resourceName, deviceID)]
+	return info, ok
+}
@@ -0,0 +1,302 @@
+// Package testing provides an in-memory environment running the driver without a node.
+//
+// The Harness wires the device state Manager, the PodManager and the DRA driver to a fake
+// host, a CDI handler writing to a temporary directory and fake Kubernetes clients, so
+// downstream projects (operators, CI of NetworkAttachmentDefinition authors, ...) can check
+// how the driver discovers, advertises, prepares and unprepares devices.
+//
+// The driver reads host information through the process wide host.Helpers, so only one
+// Harness can be active at a time and tests using it must not run in parallel.
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	netattdefv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/cdi"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/devicestate"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/driver"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/podmanager"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
+)
+
+const (
+	// DefaultNodeName is the node the Harness runs on when Options.NodeName is empty.
+	DefaultNodeName = "test-node"
+	// DefaultRequestName is the request name used by NewAllocatedClaim.
+	DefaultRequestName = "vf"
+)
+
+// Options configures a Harness.
+type Options struct {
+	// NodeName is the name of the node, also used as the pool name of the devices.
+	NodeName string
+	// PFs are the SR-IOV Physical Functions of the fake host.
+	PFs []FakePF
+	// ConfigurationMode is STANDALONE (default) or MULTUS.
+	ConfigurationMode string
+	// AllowSharedClaims allows preparing claims reserved by several pods.
+	AllowSharedClaims bool
+	// ExcludePrimaryPFs leaves out the VFs of the PFs with Primary set, like the driver does by
+	// default.
+	ExcludePrimaryPFs bool
+	// Objects are stored in the fake API server, e.g. the NetworkAttachmentDefinitions
+	// referenced by VfConfigs.
+	Objects []client.Object
+}
+
+// Harness runs the driver against a fake host and fake Kubernetes clients.
+type Harness struct {
+	Config      *types.Config
+	Host        *FakeHost
+	CDI         *cdi.Handler
+	DeviceState *devicestate.Manager
+	PodManager  *podmanager.PodManager
+	// Driver implements kubeletplugin.DRAPlugin, like the kubelet sees it.
+	Driver *driver.Driver
+	// KubeClient is the fake clientset holding the ResourceClaims updated by the driver.
+	KubeClient *k8sfake.Clientset
+	// DeviceInfo records the device-info files written in MULTUS mode.
+	DeviceInfo *FakeDeviceInfoStore
+
+	rootDir     string
+	origHelpers host.Interface
+}
+
+// NewHarness creates a Harness. Close must be called to release it.
+func NewHarness(opts Options) (*Harness, error) {
+	if opts.NodeName == "" {
+		opts.NodeName = DefaultNodeName
+	}
+	if opts.ConfigurationMode == "" {
+		opts.ConfigurationMode = string(consts.ConfigurationModeStandalone)
+	}
+
+	rootDir, err := os.MkdirTemp("", "dra-driver-sriov-harness")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create harness directory: %w", err)
+	}
+
+	h := &Harness{
+		Host:       NewFakeHost(opts.PFs...),
+		KubeClient: k8sfake.NewSimpleClientset(),
+		DeviceInfo: NewFakeDeviceInfoStore(),
+		rootDir:    rootDir,
+	}
+	h.Config = &types.Config{
+		Flags: &types.Flags{
+			NodeName:                      opts.NodeName,
+			CdiRoot:                       filepath.Join(rootDir, "cdi"),
+			KubeletRegistrarDirectoryPath: filepath.Join(rootDir, "plugins_registry"),
+			KubeletPluginsDirectoryPath:   filepath.Join(rootDir, "plugins"),
+			DefaultInterfacePrefix:        "vfnet",
+			ConfigurationMode:             opts.ConfigurationMode,
+			Mode:                          string(consts.DriverModeFull),
+			AllowSharedClaims:             opts.AllowSharedClaims,
+			ExcludePrimaryPFs:             opts.ExcludePrimaryPFs,
+			AttributeSchema:               string(consts.AttributeSchemaDual),
+		},
+		K8sClient: flags.ClientSets{
+			Interface: h.KubeClient,
+			Client:    crfake.NewClientBuilder().WithScheme(flags.Scheme).WithObjects(opts.Objects...).Build(),
+		},
+		CancelMainCtx: func(error) {},
+	}
+
+	// force the initialization of the real helpers so they are not created over the fake later
+	h.origHelpers = host.GetHelpers()
+	host.Helpers = h.Host
+
+	if err := h.start(); err != nil {
+		_ = h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) start() error {
+	for _, dir := range []string{h.Config.Flags.CdiRoot, h.Config.DriverPluginPath()} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	var err error
+	h.CDI, err = cdi.NewHandler(h.Config.Flags.CdiRoot)
+	if err != nil {
+		return fmt.Errorf("failed to create CDI handler: %w", err)
+	}
+	h.DeviceState, err = devicestate.NewManager(h.Config, h.CDI, h.DeviceInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create device state manager: %w", err)
+	}
+	h.PodManager, err = podmanager.NewPodManager(h.Config)
+	if err != nil {
+		return fmt.Errorf("failed to create pod manager: %w", err)
+	}
+	h.Driver = driver.New(h.Config, h.DeviceState, h.PodManager, h.CDI)
+	return nil
+}
+
+// Close restores the host helpers and removes the files written by the driver.
+func (h *Harness) Close() error {
+	if h.origHelpers != nil {
+		host.Helpers = h.origHelpers
+		h.origHelpers = nil
+	}
+	return os.RemoveAll(h.rootDir)
+}
+
+// Restart recreates the driver components from the state persisted on disk, like a restart
+// of the driver pod does. The fake host and API server are kept; devices have to be advertised
+// again, as the SriovResourcePolicy controller would do after a restart.
+func (h *Harness) Restart() error {
+	return h.start()
+}
+
+// Advertise applies a policy selecting the given devices, with optional extra attributes,
+// as the SriovResourcePolicy controller does. Devices not in the map are not advertised.
+func (h *Harness) Advertise(ctx context.Context, policyDevices map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute) error {
+	return h.DeviceState.UpdatePolicyDevices(ctx, policyDevices)
+}
+
+// AdvertiseAll advertises every discovered device without extra attributes.
+func (h *Harness) AdvertiseAll(ctx context.Context) error {
+	policyDevices := map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+	for name := range h.DeviceState.GetAllocatableDevices() {
+		policyDevices[name] = nil
+	}
+	return h.Advertise(ctx, policyDevices)
+}
+
+// AdvertisedDevices returns the devices the driver publishes in its ResourceSlice, sorted by name.
+func (h *Harness) AdvertisedDevices() []resourceapi.Device {
+	advertised := h.DeviceState.GetAdvertisedDevices()
+	devices := make([]resourceapi.Device, 0, len(advertised))
+	for _, device := range advertised {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+	return devices
+}
+
+// DeviceName returns the name of the device published for a VF.
+func DeviceName(pciAddress string) string {
+	return devicestate.DeviceNameFromPciAddress(pciAddress)
+}
+
+// NewAllocatedClaim returns a ResourceClaim allocated to the given devices of the harness node
+// and reserved for the given pods. A nil config uses the driver defaults.
+func (h *Harness) NewAllocatedClaim(namespace, name string, config *configapi.VfConfig, podUIDs []k8stypes.UID, deviceNames ...string) (*resourceapi.ResourceClaim, error) {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			UID:       k8stypes.UID(namespace + "-" + name),
+		},
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{},
+		},
+	}
+	for _, deviceName := range deviceNames {
+		claim.Status.Allocation.Devices.Results = append(claim.Status.Allocation.Devices.Results, resourceapi.DeviceRequestAllocationResult{
+			Request: DefaultRequestName,
+			Driver:  consts.DriverName,
+			Pool:    h.Config.Flags.NodeName,
+			Device:  deviceName,
+		})
+	}
+	if config != nil {
+		config.APIVersion = configapi.GroupName + "/" + configapi.Version
+		config.Kind = configapi.VfConfigKind
+		raw, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal VfConfig: %w", err)
+		}
+		claim.Status.Allocation.Devices.Config = []resourceapi.DeviceAllocationConfiguration{{
+			Source:   resourceapi.AllocationConfigSourceClaim,
+			Requests: []string{DefaultRequestName},
+			DeviceConfiguration: resourceapi.DeviceConfiguration{
+				Opaque: &resourceapi.OpaqueDeviceConfiguration{
+					Driver:     consts.DriverName,
+					Parameters: runtime.RawExtension{Raw: raw},
+				},
+			},
+		}}
+	}
+	for _, podUID := range podUIDs {
+		claim.Status.ReservedFor = append(claim.Status.ReservedFor, resourceapi.ResourceClaimConsumerReference{
+			Resource: "pods",
+			Name:     string(podUID),
+			UID:      podUID,
+		})
+	}
+	return claim, nil
+}
+
+// Prepare calls the driver the way the kubelet does before starting the pods of the claims.
+// Claims missing from the fake API server are created first, so the driver can update their status.
+func (h *Harness) Prepare(ctx context.Context, claims ...*resourceapi.ResourceClaim) (map[k8stypes.UID]kubeletplugin.PrepareResult, error) {
+	for _, claim := range claims {
+		_, err := h.KubeClient.ResourceV1().ResourceClaims(claim.Namespace).Create(ctx, claim, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create claim %s/%s: %w", claim.Namespace, claim.Name, err)
+		}
+	}
+	return h.Driver.PrepareResourceClaims(ctx, claims)
+}
+
+// Unprepare calls the driver the way the kubelet does once the pods of the claims are gone.
+func (h *Harness) Unprepare(ctx context.Context, claims ...*resourceapi.ResourceClaim) (map[k8stypes.UID]error, error) {
+	objects := make([]kubeletplugin.NamespacedObject, 0, len(claims))
+	for _, claim := range claims {
+		objects = append(objects, kubeletplugin.NamespacedObject{
+			NamespacedName: k8stypes.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+			UID:            claim.UID,
+		})
+	}
+	return h.Driver.UnprepareResourceClaims(ctx, objects)
+}
+
+// CDISpecs returns the devices of the CDI specs written by the driver, keyed by claim or pod UID.
+func (h *Harness) CDISpecs() (map[string][]cdispec.Device, error) {
+	// use a fresh cache so specs written or removed since the last read are seen
+	handler, err := cdi.NewHandler(h.Config.Flags.CdiRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CDI handler: %w", err)
+	}
+	return handler.ListTransientSpecs(), nil
+}
+
+// NewNetworkAttachmentDefinition returns a NetworkAttachmentDefinition delegating to the sriov CNI,
+// to be passed in Options.Objects.
+func NewNetworkAttachmentDefinition(namespace, name string) *netattdefv1.NetworkAttachmentDefinition {
+	return &netattdefv1.NetworkAttachmentDefinition{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: netattdefv1.NetworkAttachmentDefinitionSpec{
+			Config: fmt.Sprintf(`{"cniVersion":"1.0.0","name":%q,"type":"sriov","ipam":{}}`, name),
+		},
+	}
+}
@@ -0,0 +1,204 @@
+package testing_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+	drasriovtesting "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/testing"
+)
+
+var _ = Describe("Harness", Serial, func() {
+	var (
+		ctx     context.Context
+		harness *drasriovtesting.Harness
+		vf0     string
+		vf1     string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		harness, err = drasriovtesting.NewHarness(drasriovtesting.Options{
+			PFs: []drasriovtesting.FakePF{
+				{PciAddress: "0000:08:00.0", NetName: "eth0", NumVFs: 2, NumaNode: 1},
+			},
+			Objects: []client.Object{drasriovtesting.NewNetworkAttachmentDefinition("default", "sriov-net")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(harness.Close)
+
+		vf0 = drasriovtesting.DeviceName("0000:08:00.1")
+		vf1 = drasriovtesting.DeviceName("0000:08:00.2")
+	})
+
+	It("discovers the VFs of the fake host", func() {
+		Expect(harness.Host.VFPciAddresses()).To(Equal([]string{"0000:08:00.1", "0000:08:00.2"}))
+		Expect(harness.DeviceState.GetAllocatableDevices()).To(HaveLen(2))
+		Expect(harness.DeviceState.GetAllocatableDevices()).To(HaveKey(vf0))
+	})
+
+	It("excludes the VFs of the PFs of the primary network", func() {
+		Expect(harness.Close()).To(Succeed())
+		var err error
+		harness, err = drasriovtesting.NewHarness(drasriovtesting.Options{
+			PFs: []drasriovtesting.FakePF{
+				{PciAddress: "0000:08:00.0", NetName: "eth0", NumVFs: 2},
+				{PciAddress: "0000:09:00.0", NetName: "eth1", NumVFs: 2, Primary: true},
+			},
+			ExcludePrimaryPFs: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(harness.Close)
+
+		Expect(harness.DeviceState.GetAllocatableDevices()).To(HaveLen(2))
+		Expect(harness.DeviceState.GetAllocatableDevices()).To(HaveKey(vf0))
+	})
+
+	It("only publishes advertised devices", func() {
+		Expect(harness.AdvertisedDevices()).To(BeEmpty())
+
+		Expect(harness.Advertise(ctx, map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{vf1: nil})).To(Succeed())
+		devices := harness.AdvertisedDevices()
+		Expect(devices).To(HaveLen(1))
+		Expect(devices[0].Name).To(Equal(vf1))
+		Expect(*devices[0].Attributes[consts.AttributeNUMANode].IntValue).To(Equal(int64(1)))
+
+		Expect(harness.AdvertiseAll(ctx)).To(Succeed())
+		Expect(harness.AdvertisedDevices()).To(HaveLen(2))
+	})
+
+	It("prepares and unprepares a claim", func() {
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "sriov-net"}, []k8stypes.UID{"pod-1"}, vf0)
+		Expect(err).ToNot(HaveOccurred())
+
+		results, err := harness.Prepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results[claim.UID].Err).ToNot(HaveOccurred())
+		Expect(results[claim.UID].Devices).To(HaveLen(1))
+		Expect(results[claim.UID].Devices[0].DeviceName).To(Equal(vf0))
+
+		devices, found := harness.PodManager.GetDevicesByPodUID("pod-1")
+		Expect(found).To(BeTrue())
+		Expect(devices[0].PciAddress).To(Equal("0000:08:00.1"))
+		Expect(devices[0].NetAttachDefConfig).To(ContainSubstring(`"deviceID":"0000:08:00.1"`))
+
+		specs, err := harness.CDISpecs()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(specs).To(HaveKey(string(claim.UID)))
+		Expect(specs).To(HaveKey("pod-1"))
+
+		updated, err := harness.KubeClient.ResourceV1().ResourceClaims("default").Get(ctx, "claim", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated.Status.Devices).To(HaveLen(1))
+
+		unprepareResults, err := harness.Unprepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unprepareResults[claim.UID]).ToNot(HaveOccurred())
+		_, found = harness.PodManager.GetDevicesByPodUID("pod-1")
+		Expect(found).To(BeFalse())
+
+		specs, err = harness.CDISpecs()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(specs).ToNot(HaveKey(string(claim.UID)))
+	})
+
+	It("binds the requested driver and restores the original one", func() {
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "sriov-net", Driver: "vfio-pci"}, []k8stypes.UID{"pod-1"}, vf1)
+		Expect(err).ToNot(HaveOccurred())
+
+		results, err := harness.Prepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results[claim.UID].Err).ToNot(HaveOccurred())
+		Expect(harness.Host.Driver("0000:08:00.2")).To(Equal("vfio-pci"))
+
+		_, err = harness.Unprepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(harness.Host.Driver("0000:08:00.2")).To(Equal("mlx5_core"))
+	})
+
+	It("publishes the driver the VFs are currently bound to", func() {
+		Expect(harness.Advertise(ctx, map[string]map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{vf1: nil})).To(Succeed())
+		advertisedDriver := func() string {
+			devices := harness.AdvertisedDevices()
+			Expect(devices).To(HaveLen(1))
+			return *devices[0].Attributes[consts.AttributeDriver].StringValue
+		}
+		Expect(advertisedDriver()).To(Equal("mlx5_core"))
+
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "sriov-net", Driver: "vfio-pci"}, []k8stypes.UID{"pod-1"}, vf1)
+		Expect(err).ToNot(HaveOccurred())
+		results, err := harness.Prepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results[claim.UID].Err).ToNot(HaveOccurred())
+		Expect(advertisedDriver()).To(Equal("vfio-pci"))
+
+		_, err = harness.Unprepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(advertisedDriver()).To(Equal("mlx5_core"))
+	})
+
+	It("restores the VF settings changed while the claim was prepared", func() {
+		original := &host.VFSettings{MAC: "02:00:00:00:00:02", SpoofChk: true}
+		Expect(harness.Host.SetVFSettings("0000:08:00.2", original)).To(Succeed())
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "sriov-net"}, []k8stypes.UID{"pod-1"}, vf1)
+		Expect(err).ToNot(HaveOccurred())
+
+		results, err := harness.Prepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results[claim.UID].Err).ToNot(HaveOccurred())
+		// what sriov-cni does with the mac and vlan of the netconf
+		Expect(harness.Host.SetVFSettings("0000:08:00.2", &host.VFSettings{MAC: "02:00:00:00:00:99", VLAN: 100, Trust: true})).To(Succeed())
+
+		_, err = harness.Unprepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(harness.Host.GetVFSettings("0000:08:00.2")).To(Equal(original))
+	})
+
+	It("restores the original driver and VF settings after a restart", func() {
+		original := &host.VFSettings{MAC: "02:00:00:00:00:02", SpoofChk: true}
+		Expect(harness.Host.SetVFSettings("0000:08:00.2", original)).To(Succeed())
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "sriov-net", Driver: "vfio-pci"}, []k8stypes.UID{"pod-1"}, vf1)
+		Expect(err).ToNot(HaveOccurred())
+
+		results, err := harness.Prepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results[claim.UID].Err).ToNot(HaveOccurred())
+		Expect(harness.Host.SetVFSettings("0000:08:00.2", &host.VFSettings{MAC: "02:00:00:00:00:99", VLAN: 100})).To(Succeed())
+
+		Expect(harness.Restart()).To(Succeed())
+
+		_, err = harness.Unprepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(harness.Host.Driver("0000:08:00.2")).To(Equal("mlx5_core"))
+		Expect(harness.Host.GetVFSettings("0000:08:00.2")).To(Equal(original))
+	})
+
+	It("reports prepare failures of claims referencing unknown networks", func() {
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "missing"}, []k8stypes.UID{"pod-1"}, vf0)
+		Expect(err).ToNot(HaveOccurred())
+
+		results, _ := harness.Prepare(ctx, claim)
+		Expect(results[claim.UID].Err).To(HaveOccurred())
+	})
+
+	It("keeps prepared claims across restarts", func() {
+		claim, err := harness.NewAllocatedClaim("default", "claim", &configapi.VfConfig{NetAttachDefName: "sriov-net"}, []k8stypes.UID{"pod-1"}, vf0)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = harness.Prepare(ctx, claim)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(harness.Restart()).To(Succeed())
+
+		_, found := harness.PodManager.Get("pod-1", claim.UID)
+		Expect(found).To(BeTrue())
+	})
+})
@@ -0,0 +1,831 @@
+package testing
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+
+	"github.com/jaypipes/ghw"
+	"github.com/jaypipes/ghw/pkg/pci"
+	"github.com/jaypipes/pcidb"
+
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
+)
+
+const (
+	defaultVendorID    = "15b3"
+	defaultPFDeviceID  = "101d"
+	defaultVFDeviceID  = "101e"
+	defaultVFDriver    = "mlx5_core"
+	defaultEswitchMode = consts.EswitchModeLegacy
+)
+
+// FakePF describes an SR-IOV Physical Function exposed by FakeHost.
+// Zero values are replaced by sensible defaults of a Mellanox ConnectX NIC.
+type FakePF struct {
+	// PciAddress of the PF, e.g. 0000:08:00.0
+	PciAddress string
+	// NetName is the PF netdev name
+	NetName string
+	// NumVFs is the number of VFs created on the PF. VF n gets the PCI function n+1 of the PF address.
+	NumVFs int
+	// TotalVFs is the sriov_totalvfs of the PF, NumVFs when zero
+	TotalVFs    int
+	VendorID    string
+	DeviceID    string
+	VFDeviceID  string
+	VFDriver    string
+	EswitchMode string
+	LinkType    string
+	NumaNode    int
+	// NumaCPUs is the cpulist of the NUMA node of the PF, e.g. 0-7
+	NumaCPUs string
+	PCIeRoot string
+	// FirmwareVersion and DriverVersion are the versions reported by ethtool for the PF
+	FirmwareVersion string
+	DriverVersion   string
+	// PartNumber is the PN of the VPD of the PF, BoardID its PSID
+	PartNumber string
+	BoardID    string
+	// RDMA reports all VFs of the PF as RDMA capable
+	RDMA bool
+	// SwitchdevCapable reports the eswitch of the PF as switchdev capable
+	SwitchdevCapable bool
+	// VDPA reports all VFs of the PF as vDPA capable
+	VDPA bool
+	// Primary reports the PF netdev as carrying the primary networking of the node
+	Primary bool
+}
+
+// FakeHost is an in-memory implementation of host.Interface. It tracks the driver each VF
+// is bound to, so tests can observe the effect of VfConfig.Driver on prepare and unprepare.
+type FakeHost struct {
+	mu      sync.Mutex
+	pfs     []FakePF
+	vfs     map[string]host.VFInfo
+	vfPF    map[string]*FakePF
+	drivers map[string]string
+	modules map[string]bool
+	resets  map[string]int
+	// vfSettings holds the administrative settings set on VFs, VFs start with zero settings
+	vfSettings map[string]host.VFSettings
+	// channels holds the combined channels set on VFs, VFs start with defaultVFChannels
+	channels map[string]int
+	// ringSizes holds the ring sizes set on VFs, VFs start with defaultVFRingSize
+	ringSizes map[string]host.RingSizes
+	// features holds the features turned on or off on VFs, VFs start with defaultVFFeatures
+	features map[string]map[string]bool
+	// vfGUIDs holds the node and port GUIDs set on InfiniBand VFs, VFs start with zero GUIDs
+	vfGUIDs map[string][2]string
+	// iommuGroups holds the IOMMU group of each VF, every VF gets its own group
+	iommuGroups map[string]int
+	// tcOffload holds the qdisc added by EnableTCOffload to each representor
+	tcOffload map[string]string
+	// rdmaNetnsMode is the RDMA netns mode of the kernel, rdmaNetns the network namespaces the
+	// RDMA devices were moved to
+	rdmaNetnsMode string
+	rdmaNetns     map[string]string
+}
+
+var _ host.Interface = (*FakeHost)(nil)
+
+// NewFakeHost returns a FakeHost exposing the given PFs and their VFs.
+func NewFakeHost(pfs ...FakePF) *FakeHost {
+	h := &FakeHost{
+		vfs:     map[string]host.VFInfo{},
+		vfPF:    map[string]*FakePF{},
+		drivers: map[string]string{},
+		modules: map[string]bool{},
+		resets:  map[string]int{},
+
+		vfSettings:    map[string]host.VFSettings{},
+		channels:      map[string]int{},
+		ringSizes:     map[string]host.RingSizes{},
+		features:      map[string]map[string]bool{},
+		vfGUIDs:       map[string][2]string{},
+		iommuGroups:   map[string]int{},
+		tcOffload:     map[string]string{},
+		rdmaNetnsMode: host.RDMANetnsModeShared,
+		rdmaNetns:     map[string]string{},
+	}
+	for _, pf := range pfs {
+		h.pfs = append(h.pfs, withPFDefaults(pf))
+	}
+	for i := range h.pfs {
+		pf := &h.pfs[i]
+		for vfID := range pf.NumVFs {
+			vf := host.VFInfo{
+				PciAddress: vfPciAddress(pf.PciAddress, vfID),
+				VFID:       vfID,
+				DeviceID:   pf.VFDeviceID,
+			}
+			h.vfs[vf.PciAddress] = vf
+			h.vfPF[vf.PciAddress] = pf
+			h.drivers[vf.PciAddress] = pf.VFDriver
+			h.iommuGroups[vf.PciAddress] = len(h.iommuGroups)
+		}
+	}
+	return h
+}
+
+func withPFDefaults(pf FakePF) FakePF {
+	if pf.VendorID == "" {
+		pf.VendorID = defaultVendorID
+	}
+	if pf.DeviceID == "" {
+		pf.DeviceID = defaultPFDeviceID
+	}
+	if pf.VFDeviceID == "" {
+		pf.VFDeviceID = defaultVFDeviceID
+	}
+	if pf.VFDriver == "" {
+		pf.VFDriver = defaultVFDriver
+	}
+	if pf.EswitchMode == "" {
+		pf.EswitchMode = defaultEswitchMode
+	}
+	if pf.TotalVFs == 0 {
+		pf.TotalVFs = pf.NumVFs
+	}
+	if pf.LinkType == "" {
+		pf.LinkType = consts.LinkTypeEthernet
+	}
+	return pf
+}
+
+// vfPciAddress derives the PCI address of a VF from its PF address, using the functions
+// following the PF function on the same bus, e.g. 0000:08:00.0 -> 0000:08:00.1 for VF 0.
+func vfPciAddress(pfPciAddress string, vfID int) string {
+	base := pfPciAddress[:len(pfPciAddress)-1]
+	function, err := strconv.Atoi(pfPciAddress[len(pfPciAddress)-1:])
+	if err != nil {
+		function = 0
+	}
+	offset := function + vfID + 1
+	if offset < 8 {
+		return fmt.Sprintf("%s%d", base, offset)
+	}
+	// spill over to the next device numbers, like multi-function NICs with many VFs do
+	return fmt.Sprintf("%s%02x.%d", pfPciAddress[:len(pfPciAddress)-4], offset/8, offset%8)
+}
+
+// VFPciAddresses returns the PCI addresses of all VFs, in PF order.
+func (h *FakeHost) VFPciAddresses() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var addresses []string
+	for _, pf := range h.pfs {
+		for vfID := range pf.NumVFs {
+			addresses = append(addresses, vfPciAddress(pf.PciAddress, vfID))
+		}
+	}
+	return addresses
+}
+
+// Driver returns the driver a device is currently bound to.
+func (h *FakeHost) Driver(pciAddress string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.drivers[pciAddress]
+}
+
+// Resets returns the number of times a device was reset.
+func (h *FakeHost) Resets(pciAddress string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.resets[pciAddress]
+}
+
+// SetRDMANetnsMode sets the RDMA netns mode of the kernel, shared by default.
+func (h *FakeHost) SetRDMANetnsMode(mode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rdmaNetnsMode = mode
+}
+
+// RDMANetns returns the network namespace an RDMA device was moved to, empty when it is in the
+// network namespace of the driver.
+func (h *FakeHost) RDMANetns(rdmaDeviceName string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rdmaNetns[rdmaDeviceName]
+}
+
+func (h *FakeHost) pf(pciAddress string) (*FakePF, bool) {
+	for i := range h.pfs {
+		if h.pfs[i].PciAddress == pciAddress {
+			return &h.pfs[i], true
+		}
+	}
+	return nil, false
+}
+
+func (h *FakeHost) IsSriovVF(pciAddress string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.vfs[pciAddress]
+	return ok
+}
+
+func (h *FakeHost) IsSriovPF(pciAddress string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.pf(pciAddress)
+	return ok
+}
+
+func (h *FakeHost) GetVFList(pfPciAddress string) ([]host.VFInfo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.pf(pfPciAddress)
+	if !ok {
+		return nil, fmt.Errorf("device %s is not a PF", pfPciAddress)
+	}
+	vfs := make([]host.VFInfo, 0, pf.NumVFs)
+	for vfID := range pf.NumVFs {
+		vfs = append(vfs, h.vfs[vfPciAddress(pf.PciAddress, vfID)])
+	}
+	return vfs, nil
+}
+
+func (h *FakeHost) GetSriovVFCounts(pfPciAddress string) (int, int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.pf(pfPciAddress)
+	if !ok {
+		return 0, 0, fmt.Errorf("device %s is not a PF", pfPciAddress)
+	}
+	return pf.TotalVFs, pf.NumVFs, nil
+}
+
+func (h *FakeHost) PCI() (*ghw.PCIInfo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	info := &pci.Info{}
+	for _, pf := range h.pfs {
+		info.Devices = append(info.Devices, &pci.Device{
+			Address: pf.PciAddress,
+			Class:   &pcidb.Class{ID: fmt.Sprintf("%02x", consts.NetClass)},
+			Vendor:  &pcidb.Vendor{ID: pf.VendorID},
+			Product: &pcidb.Product{ID: pf.DeviceID},
+		})
+	}
+	for _, pf := range h.pfs {
+		for vfID := range pf.NumVFs {
+			info.Devices = append(info.Devices, &pci.Device{
+				Address: vfPciAddress(pf.PciAddress, vfID),
+				Class:   &pcidb.Class{ID: fmt.Sprintf("%02x", consts.NetClass)},
+				Vendor:  &pcidb.Vendor{ID: pf.VendorID},
+				Product: &pcidb.Product{ID: pf.VFDeviceID},
+			})
+		}
+	}
+	return info, nil
+}
+
+func (h *FakeHost) TryGetInterfaceName(pciAddr string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pf, ok := h.pf(pciAddr); ok {
+		return pf.NetName
+	}
+	return ""
+}
+
+// GetDriverInfo returns the driver info of a PF netdev.
+func (h *FakeHost) GetDriverInfo(ifName string) (*host.DriverInfo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, pf := range h.pfs {
+		if pf.NetName == ifName {
+			return &host.DriverInfo{Driver: pf.VFDriver, Version: pf.DriverVersion, FirmwareVersion: pf.FirmwareVersion}, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s not found", ifName)
+}
+
+// GetVPD returns the part number of a PF as its VPD.
+func (h *FakeHost) GetVPD(pciAddress string) (map[string]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.pf(pciAddress)
+	if !ok || pf.PartNumber == "" {
+		return nil, fmt.Errorf("device %s has no VPD", pciAddress)
+	}
+	return map[string]string{host.VPDKeywordPartNumber: pf.PartNumber}, nil
+}
+
+// GetBoardID returns the board ID of a PF.
+func (h *FakeHost) GetBoardID(pciAddress string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.pf(pciAddress)
+	if !ok || pf.BoardID == "" {
+		return "", fmt.Errorf("device %s has no board ID", pciAddress)
+	}
+	return pf.BoardID, nil
+}
+
+func (h *FakeHost) IsSwitchdevCapable(pfPciAddress string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.pf(pfPciAddress)
+	return ok && pf.SwitchdevCapable
+}
+
+// GetVDPAManagementDevices returns the VFs of the PFs with VDPA set.
+func (h *FakeHost) GetVDPAManagementDevices() ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var pciAddresses []string
+	for _, pf := range h.pfs {
+		if !pf.VDPA {
+			continue
+		}
+		for vfID := range pf.NumVFs {
+			pciAddresses = append(pciAddresses, vfPciAddress(pf.PciAddress, vfID))
+		}
+	}
+	return pciAddresses, nil
+}
+
+// GetPrimaryInterfaces returns the netdevs of the PFs with Primary set.
+func (h *FakeHost) GetPrimaryInterfaces(nodeIPs []string) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var names []string
+	for _, pf := range h.pfs {
+		if pf.Primary {
+			names = append(names, pf.NetName)
+		}
+	}
+	return names, nil
+}
+
+// GetInterfaceMACAddress fails, the VFs of FakeHost have no netdev.
+func (h *FakeHost) GetInterfaceMACAddress(pciAddr, ifName string) (string, error) {
+	return "", fmt.Errorf("device %s has no interface %s", pciAddr, ifName)
+}
+
+func (h *FakeHost) GetNicSriovMode(pciAddr string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pf, ok := h.pf(pciAddr); ok {
+		return pf.EswitchMode
+	}
+	return ""
+}
+
+func (h *FakeHost) GetLinkType(pciAddr string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pf, ok := h.pf(pciAddr); ok {
+		return pf.LinkType, nil
+	}
+	return consts.LinkTypeUnknown, fmt.Errorf("device %s is not a PF", pciAddr)
+}
+
+func (h *FakeHost) GetNumaNode(pciAddress string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pf, ok := h.pf(pciAddress); ok {
+		return strconv.Itoa(pf.NumaNode), nil
+	}
+	if pf, ok := h.vfPF[pciAddress]; ok {
+		return strconv.Itoa(pf.NumaNode), nil
+	}
+	return "", fmt.Errorf("device %s not found", pciAddress)
+}
+
+func (h *FakeHost) GetNumaNodeCPUs(numaNode string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, pf := range h.pfs {
+		if strconv.Itoa(pf.NumaNode) == numaNode && pf.NumaCPUs != "" {
+			return pf.NumaCPUs, nil
+		}
+	}
+	return "", fmt.Errorf("NUMA node %s not found", numaNode)
+}
+
+func (h *FakeHost) GetPCIeRoot(pciAddress string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pf, ok := h.pf(pciAddress); ok {
+		return pf.PCIeRoot, nil
+	}
+	if pf, ok := h.vfPF[pciAddress]; ok {
+		return pf.PCIeRoot, nil
+	}
+	return "", fmt.Errorf("device %s not found", pciAddress)
+}
+
+func (h *FakeHost) GetIOMMUGroup(pciAddress string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if group, ok := h.iommuGroups[pciAddress]; ok {
+		return group, nil
+	}
+	if _, ok := h.pf(pciAddress); ok {
+		return -1, nil
+	}
+	return -1, fmt.Errorf("device %s not found", pciAddress)
+}
+
+func (h *FakeHost) BindDeviceDriver(pciAddress string, config *configapi.VfConfig) (string, error) {
+	if config.Driver == "" {
+		return "", nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	currentDriver := h.drivers[pciAddress]
+	if config.Driver == "default" {
+		h.drivers[pciAddress] = h.defaultDriver(pciAddress)
+	} else {
+		h.drivers[pciAddress] = config.Driver
+	}
+	return currentDriver, nil
+}
+
+func (h *FakeHost) RestoreDeviceDriver(pciAddress string, originalDriver string) error {
+	if originalDriver == "" {
+		return h.BindDefaultDriver(pciAddress)
+	}
+	return h.BindDriverByBusAndDevice(pciAddress, originalDriver)
+}
+
+func (h *FakeHost) GetDriverByBusAndDevice(device string) (string, error) {
+	return h.Driver(device), nil
+}
+
+func (h *FakeHost) BindDriverByBusAndDevice(device, driver string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drivers[device] = driver
+	return nil
+}
+
+func (h *FakeHost) UnbindDriverByBusAndDevice(device string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drivers[device] = ""
+	return nil
+}
+
+func (h *FakeHost) BindDefaultDriver(pciAddress string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drivers[pciAddress] = h.defaultDriver(pciAddress)
+	return nil
+}
+
+func (h *FakeHost) ResetDevice(pciAddress string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.resets[pciAddress]++
+	return nil
+}
+
+func (h *FakeHost) GetVFSettings(pciAddress string) (*host.VFSettings, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return nil, fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	settings := h.vfSettings[pciAddress]
+	return &settings, nil
+}
+
+func (h *FakeHost) SetVFSettings(pciAddress string, settings *host.VFSettings) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	h.vfSettings[pciAddress] = *settings
+	return nil
+}
+
+const (
+	// defaultVFChannels and maxVFChannels are the current and maximum combined channels of VFs.
+	defaultVFChannels = 4
+	maxVFChannels     = 8
+	// defaultVFRingSize and maxVFRingSize are the current and maximum sizes of the rings of VFs.
+	defaultVFRingSize = 1024
+	maxVFRingSize     = 8192
+)
+
+func (h *FakeHost) GetCombinedChannels(pciAddress string) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return 0, fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	if channels, ok := h.channels[pciAddress]; ok {
+		return channels, nil
+	}
+	return defaultVFChannels, nil
+}
+
+func (h *FakeHost) SetCombinedChannels(pciAddress string, count int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	if count < 1 || count > maxVFChannels {
+		return fmt.Errorf("device %s supports 1 to %d combined channels, %d requested", pciAddress, maxVFChannels, count)
+	}
+	h.channels[pciAddress] = count
+	return nil
+}
+
+func (h *FakeHost) GetRingSizes(pciAddress string) (*host.RingSizes, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return nil, fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	sizes, ok := h.ringSizes[pciAddress]
+	if !ok {
+		sizes = host.RingSizes{RX: defaultVFRingSize, TX: defaultVFRingSize}
+	}
+	return &sizes, nil
+}
+
+func (h *FakeHost) SetRingSizes(pciAddress string, sizes *host.RingSizes) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	current, ok := h.ringSizes[pciAddress]
+	if !ok {
+		current = host.RingSizes{RX: defaultVFRingSize, TX: defaultVFRingSize}
+	}
+	for _, size := range []int{sizes.RX, sizes.TX} {
+		if size < 0 || size > maxVFRingSize {
+			return fmt.Errorf("device %s supports rings of 1 to %d descriptors, %d requested", pciAddress, maxVFRingSize, size)
+		}
+	}
+	if sizes.RX != 0 {
+		current.RX = sizes.RX
+	}
+	if sizes.TX != 0 {
+		current.TX = sizes.TX
+	}
+	h.ringSizes[pciAddress] = current
+	return nil
+}
+
+// defaultVFFeatures are the features of VFs whose features were never set.
+var defaultVFFeatures = map[string]bool{
+	"rx-gro":               true,
+	"rx-lro":               false,
+	"tx-tcp-segmentation":  true,
+	"tx-tcp6-segmentation": true,
+}
+
+func (h *FakeHost) GetFeatures(pciAddress string) (map[string]bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return nil, fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	features := maps.Clone(defaultVFFeatures)
+	maps.Copy(features, h.features[pciAddress])
+	return features, nil
+}
+
+func (h *FakeHost) SetFeatures(pciAddress string, features map[string]bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.vfs[pciAddress]; !ok {
+		return fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	for feature := range features {
+		if _, ok := defaultVFFeatures[feature]; !ok {
+			return fmt.Errorf("feature %s not reported by device %s", feature, pciAddress)
+		}
+	}
+	if h.features[pciAddress] == nil {
+		h.features[pciAddress] = map[string]bool{}
+	}
+	maps.Copy(h.features[pciAddress], features)
+	return nil
+}
+
+// zeroGUID is the GUID of the InfiniBand VFs whose GUIDs were never set.
+const zeroGUID = "00:00:00:00:00:00:00:00"
+
+func (h *FakeHost) GetVFGUIDs(pciAddress string) (string, string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.infiniBandVF(pciAddress); err != nil {
+		return "", "", err
+	}
+	guids, ok := h.vfGUIDs[pciAddress]
+	if !ok {
+		return zeroGUID, zeroGUID, nil
+	}
+	return guids[0], guids[1], nil
+}
+
+func (h *FakeHost) SetVFGUIDs(pciAddress, nodeGUID, portGUID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.infiniBandVF(pciAddress); err != nil {
+		return err
+	}
+	h.vfGUIDs[pciAddress] = [2]string{nodeGUID, portGUID}
+	return nil
+}
+
+// infiniBandVF checks that a device is a VF of a PF with the infiniband link type.
+func (h *FakeHost) infiniBandVF(pciAddress string) error {
+	pf, ok := h.vfPF[pciAddress]
+	if !ok {
+		return fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	if pf.LinkType != consts.LinkTypeInfiniband {
+		return fmt.Errorf("PF %s of device %s is not an InfiniBand PF", pf.NetName, pciAddress)
+	}
+	return nil
+}
+
+// GetVFRepresentor returns <PF netdev>_<VF index>, the name udev gives the representors of mlx5
+// VFs, for the VFs of PFs in switchdev eswitch mode.
+func (h *FakeHost) GetVFRepresentor(pciAddress string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.vfPF[pciAddress]
+	if !ok {
+		return "", fmt.Errorf("device %s is not a VF", pciAddress)
+	}
+	if pf.EswitchMode != consts.EswitchModeSwitchdev {
+		return "", fmt.Errorf("PF %s of device %s is not in switchdev mode", pf.NetName, pciAddress)
+	}
+	return fmt.Sprintf("%s_%d", pf.NetName, h.vfs[pciAddress].VFID), nil
+}
+
+func (h *FakeHost) EnableTCOffload(representor, qdisc string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tcOffload[representor] = qdisc
+	return nil
+}
+
+func (h *FakeHost) DisableTCOffload(representor, _ string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.tcOffload, representor)
+	return nil
+}
+
+// TCOffload returns the qdisc added by EnableTCOffload to a representor, empty when the TC
+// offload is not enabled.
+func (h *FakeHost) TCOffload(representor string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.tcOffload[representor]
+}
+
+func (h *FakeHost) defaultDriver(pciAddress string) string {
+	if pf, ok := h.vfPF[pciAddress]; ok {
+		return pf.VFDriver
+	}
+	return ""
+}
+
+func (h *FakeHost) IsDpdkDriver(driver string) bool {
+	switch driver {
+	case "vfio-pci", "uio_pci_generic", "igb_uio":
+		return true
+	default:
+		return false
+	}
+}
+
+// GetVFIODeviceFile returns a VFIO group file named after the VF PCI address, no file is created.
+func (h *FakeHost) GetVFIODeviceFile(pciAddress string) (devFileHost, devFileContainer string, err error) {
+	if h.Driver(pciAddress) != "vfio-pci" {
+		return "", "", fmt.Errorf("device %s is not bound to vfio-pci", pciAddress)
+	}
+	devFile := "/dev/vfio/" + pciAddress
+	return devFile, devFile, nil
+}
+
+// GetUIODeviceFile returns a UIO device file named after the VF PCI address, no file is created.
+func (h *FakeHost) GetUIODeviceFile(pciAddress string) (string, error) {
+	switch h.Driver(pciAddress) {
+	case "uio_pci_generic", "igb_uio":
+		return "/dev/uio-" + pciAddress, nil
+	default:
+		return "", fmt.Errorf("device %s is not bound to a uio driver", pciAddress)
+	}
+}
+
+func (h *FakeHost) IsKernelModuleLoaded(moduleName string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.modules[moduleName]
+}
+
+func (h *FakeHost) LoadKernelModule(moduleName string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.modules[moduleName] = true
+	return nil
+}
+
+func (h *FakeHost) EnsureDpdkModuleLoaded(driver string) error {
+	return h.LoadKernelModule(driver)
+}
+
+func (h *FakeHost) EnsureVhostModulesLoaded() error {
+	if err := h.LoadKernelModule("vhost_net"); err != nil {
+		return err
+	}
+	return h.LoadKernelModule("tun")
+}
+
+func (h *FakeHost) EnsureRDMACMModuleLoaded() error {
+	return h.LoadKernelModule("rdma_ucm")
+}
+
+// EnableVFIONoIOMMU records vfio as loaded.
+func (h *FakeHost) EnableVFIONoIOMMU() error {
+	return h.LoadKernelModule("vfio")
+}
+
+// GetRDMADevicesForPCI returns a single RDMA device per RDMA capable VF, named after its VF ID.
+func (h *FakeHost) GetRDMADevicesForPCI(pciAddr string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pf, ok := h.vfPF[pciAddr]
+	if !ok || !pf.RDMA {
+		return nil
+	}
+	return []string{fmt.Sprintf("mlx5_%d", h.vfs[pciAddr].VFID)}
+}
+
+func (h *FakeHost) VerifyRDMACapability(pciAddr string) bool {
+	return len(h.GetRDMADevicesForPCI(pciAddr)) > 0
+}
+
+func (h *FakeHost) GetRDMACharDevices(rdmaDeviceName string) ([]string, error) {
+	return []string{"/dev/infiniband/uverbs_" + rdmaDeviceName, "/dev/infiniband/rdma_cm"}, nil
+}
+
+func (h *FakeHost) GetRDMANetnsMode() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rdmaNetnsMode, nil
+}
+
+// GetEthtoolStats reports no statistics, the fake host has no network namespaces.
+func (h *FakeHost) GetEthtoolStats(netnsPath, ifName string) (map[string]uint64, error) {
+	return map[string]uint64{}, nil
+}
+
+// GetNetnsInterface fails, the fake host has no network namespaces.
+func (h *FakeHost) GetNetnsInterface(netnsPath, ifName string) (*host.NetnsInterface, error) {
+	return nil, fmt.Errorf("interface %s not found in network namespace %s", ifName, netnsPath)
+}
+
+// SetNetnsSysctls does nothing, the fake host has no network namespaces.
+func (h *FakeHost) SetNetnsSysctls(netnsPath string, sysctls map[string]string) error {
+	return nil
+}
+
+// AddNetnsRoutes does nothing, the fake host has no network namespaces.
+func (h *FakeHost) AddNetnsRoutes(netnsPath, ifName string, routes []host.Route) error {
+	return nil
+}
+
+// AddNetnsNeighbors does nothing, the fake host has no network namespaces.
+func (h *FakeHost) AddNetnsNeighbors(netnsPath, ifName string, neighbors []host.Neighbor) error {
+	return nil
+}
+
+// GetLinkStatistics reports no traffic, the fake host has no network namespaces.
+func (h *FakeHost) GetLinkStatistics(netnsPath, ifName string) (*host.LinkStatistics, error) {
+	return &host.LinkStatistics{}, nil
+}
+
+func (h *FakeHost) MoveRDMADeviceToNetns(rdmaDeviceName, netnsPath string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rdmaNetns[rdmaDeviceName] = netnsPath
+	return nil
+}
+
+func (h *FakeHost) MoveRDMADeviceFromNetns(rdmaDeviceName, netnsPath string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.rdmaNetns, rdmaDeviceName)
+	return nil
+}
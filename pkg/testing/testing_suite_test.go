@@ -0,0 +1,13 @@
+package testing_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTesting(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Testing Harness Suite")
+}
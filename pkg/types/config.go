@@ -2,6 +2,7 @@ package types
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/consts"
 	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/flags"
@@ -17,8 +18,52 @@ type Flags struct {
 	KubeletRegistrarDirectoryPath string
 	KubeletPluginsDirectoryPath   string
 	HealthcheckPort               int
+	HealthcheckProbes             []string
 	DefaultInterfacePrefix        string
 	ConfigurationMode             string
+	Mode                          string
+	StalePodGCInterval            time.Duration
+	EnableDebugEndpoints          bool
+	PprofBindAddress              string
+	DebugSocketPath               string
+	AllowSharedClaims             bool
+	CNICheckInterval              time.Duration
+	VFStatisticsInterval          time.Duration
+	EthtoolMetrics                bool
+	CNIBinDirs                    []string
+	CNITimeout                    time.Duration
+	CNIAttachWorkers              int
+	VerifyInterfaces              bool
+	DHCPSocketPath                string
+	DefaultNetAttachDefNamespace  string
+	NUMAAlignment                 string
+	EnableVFIONoIOMMU             bool
+	VFIODeviceMode                string
+	VFIODeviceUID                 int
+	VFIODeviceGID                 int
+	AllowedVFDrivers              []string
+	SysfsWriteRetries             int
+	UnbindTimeout                 time.Duration
+	ResetVFOnUnprepare            bool
+	NRIRegistrationTimeout        time.Duration
+	NRIRequestTimeout             time.Duration
+	AttributeSchema               string
+	EnvTemplates                  string
+	ExcludePrimaryPFs             bool
+	NodeIPs                       []string
+	ExcludedDevices               []string
+	PodUID                        string
+	ShutdownTimeout               time.Duration
+	DriverName                    string
+	DeviceNamingScheme            string
+	VhostUserSocketRoot           string
+	SriovOperatorCoexistence      string
+	DevicePluginCheckpoint        string
+	NodeLabels                    bool
+	ExtendedResources             bool
+	ExtendedResourcePrefix        string
+	DPUAgentEndpoint              string
+	OVSDBEndpoint                 string
 }
 
 type Config struct {
@@ -30,3 +75,19 @@ type Config struct {
 func (c Config) DriverPluginPath() string {
 	return filepath.Join(c.Flags.KubeletPluginsDirectoryPath, consts.DriverName)
 }
+
+// IsInventoryMode reports whether the driver only publishes devices without preparing them.
+func (c Config) IsInventoryMode() bool {
+	return c.Flags != nil && consts.DriverMode(c.Flags.Mode) == consts.DriverModeInventory
+}
+
+// RollingUpdateEnabled reports whether a new driver pod may register with the kubelet while the
+// previous one is still running, see --pod-uid.
+func (c Config) RollingUpdateEnabled() bool {
+	return c.Flags != nil && c.Flags.PodUID != ""
+}
+
+// AllowsSharedClaims reports whether claims reserved by several pods can be prepared.
+func (c Config) AllowsSharedClaims() bool {
+	return c.Flags != nil && c.Flags.AllowSharedClaims
+}
@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -13,6 +15,7 @@ import (
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 
 	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
 )
 
 // AllocatableDevices is a map of device pci address to dra device objects
@@ -35,7 +38,86 @@ type NetworkDataChanStruct struct {
 }
 type NetworkDataChanStructList []*NetworkDataChanStruct
 
-// AddDeviceIDToNetConf adds the deviceID (PCI address) to the netconf
+// ValidateNetConf checks that a raw netconf is a JSON object describing a single plugin or a
+// non-empty plugin list, each plugin having a type.
+func ValidateNetConf(rawNetConf string) error {
+	var rawConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(rawNetConf), &rawConfig); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	plugins, err := netConfPlugins(rawConfig)
+	if err != nil {
+		return err
+	}
+	for i, rawPlugin := range plugins {
+		plugin, ok := rawPlugin.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid plugin %d", i)
+		}
+		if pluginType, _ := plugin["type"].(string); pluginType == "" {
+			return fmt.Errorf("no type set for plugin %d", i)
+		}
+	}
+	return nil
+}
+
+// ChainNetConfs returns a plugin list (conflist) running the plugins of the primary netconf
+// followed, in order, by the plugins of the chained netconfs. Each netconf can be a single
+// plugin or a plugin list. The list keeps the name and cniVersion of the primary netconf.
+func ChainNetConfs(primaryConfig string, chainedConfigs ...string) (string, error) {
+	if len(chainedConfigs) == 0 {
+		return primaryConfig, nil
+	}
+
+	var rawPrimary map[string]interface{}
+	if err := json.Unmarshal([]byte(primaryConfig), &rawPrimary); err != nil {
+		return "", fmt.Errorf("failed to unmarshal primary config: %w", err)
+	}
+	plugins, err := netConfPlugins(rawPrimary)
+	if err != nil {
+		return "", fmt.Errorf("invalid primary config: %w", err)
+	}
+	for i, chainedConfig := range chainedConfigs {
+		var rawChained map[string]interface{}
+		if err := json.Unmarshal([]byte(chainedConfig), &rawChained); err != nil {
+			return "", fmt.Errorf("failed to unmarshal chained config %d: %w", i, err)
+		}
+		chainedPlugins, err := netConfPlugins(rawChained)
+		if err != nil {
+			return "", fmt.Errorf("invalid chained config %d: %w", i, err)
+		}
+		plugins = append(plugins, chainedPlugins...)
+	}
+
+	rawList := map[string]interface{}{"plugins": plugins}
+	for _, key := range []string{"name", "cniVersion"} {
+		if value, ok := rawPrimary[key]; ok {
+			rawList[key] = value
+		}
+	}
+	chainedConfig, err := json.Marshal(rawList)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chained config: %w", err)
+	}
+	return string(chainedConfig), nil
+}
+
+// netConfPlugins returns the plugins of a raw netconf, the netconf itself for a single plugin.
+func netConfPlugins(rawConfig map[string]interface{}) ([]interface{}, error) {
+	rawPlugins, isList := rawConfig["plugins"]
+	if !isList {
+		return []interface{}{rawConfig}, nil
+	}
+	plugins, ok := rawPlugins.([]interface{})
+	if !ok || len(plugins) == 0 {
+		return nil, fmt.Errorf("invalid plugins in config list")
+	}
+	return plugins, nil
+}
+
+// AddDeviceIDToNetConf adds the deviceID (PCI address) to the netconf. For a plugin list
+// (conflist) it is added to the first plugin, which is the one attaching the device, the same
+// way Multus does.
 func AddDeviceIDToNetConf(originalConfig, deviceID string) (string, error) {
 	// Unmarshal the existing configuration into a raw map
 	var rawConfig map[string]interface{}
@@ -44,7 +126,15 @@ func AddDeviceIDToNetConf(originalConfig, deviceID string) (string, error) {
 	}
 
 	// Set the deviceID (PCI address)
-	rawConfig["deviceID"] = deviceID
+	plugins, err := netConfPlugins(rawConfig)
+	if err != nil {
+		return "", err
+	}
+	plugin, ok := plugins[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid first plugin in config list")
+	}
+	plugin["deviceID"] = deviceID
 
 	// Marshal the modified configuration back to a JSON string
 	modifiedConfig, err := json.Marshal(rawConfig)
@@ -55,6 +145,196 @@ func AddDeviceIDToNetConf(originalConfig, deviceID string) (string, error) {
 	return string(modifiedConfig), nil
 }
 
+// switchdevOffloadPluginTypes are the CNI plugins offloading the traffic of a VF whose PF is in
+// switchdev mode: given the deviceID of the VF, they plug its representor into their bridge and
+// move the VF into the pod, instead of creating a veth pair.
+var switchdevOffloadPluginTypes = map[string]bool{
+	"ovs":                true,
+	"accelerated-bridge": true,
+}
+
+// AddSwitchdevOffloadToNetConf adds the deviceID (PCI address) of a VF whose PF is in switchdev
+// mode to all the plugins of the netconf offloading its traffic, e.g. an ovs-cni plugin chained
+// after the plugin attaching the device, which AddDeviceIDToNetConf leaves as they are. The same
+// netconf then serves nodes with PFs in legacy and in switchdev mode.
+func AddSwitchdevOffloadToNetConf(originalConfig, deviceID string) (string, error) {
+	var rawConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(originalConfig), &rawConfig); err != nil {
+		return "", fmt.Errorf("failed to unmarshal existing config: %w", err)
+	}
+	plugins, err := netConfPlugins(rawConfig)
+	if err != nil {
+		return "", err
+	}
+	for _, rawPlugin := range plugins {
+		plugin, ok := rawPlugin.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("invalid plugin in config list")
+		}
+		if pluginType, _ := plugin["type"].(string); switchdevOffloadPluginTypes[pluginType] {
+			plugin["deviceID"] = deviceID
+		}
+	}
+
+	modifiedConfig, err := json.Marshal(rawConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal modified config: %w", err)
+	}
+	return string(modifiedConfig), nil
+}
+
+// MergeIPAMIntoNetConf merges the IPAM override of a claim into the IPAM of the first plugin of
+// the netconf, the one attaching the device. Static addresses replace the IPAM of the netconf by
+// a static one, keeping its routes and dns. Routes are appended to the routes of the IPAM.
+func MergeIPAMIntoNetConf(originalConfig string, override *configapi.IPAMOverride) (string, error) {
+	if override == nil {
+		return originalConfig, nil
+	}
+
+	var rawConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(originalConfig), &rawConfig); err != nil {
+		return "", fmt.Errorf("failed to unmarshal existing config: %w", err)
+	}
+	plugins, err := netConfPlugins(rawConfig)
+	if err != nil {
+		return "", err
+	}
+	plugin, ok := plugins[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid first plugin in config list")
+	}
+	ipam, _ := plugin["ipam"].(map[string]interface{})
+	if ipam == nil {
+		ipam = map[string]interface{}{}
+	}
+
+	if len(override.Addresses) > 0 {
+		staticIPAM := map[string]interface{}{"type": "static"}
+		for _, key := range []string{"routes", "dns"} {
+			if value, ok := ipam[key]; ok {
+				staticIPAM[key] = value
+			}
+		}
+		gateway := net.ParseIP(override.Gateway)
+		addresses := make([]interface{}, 0, len(override.Addresses))
+		for _, address := range override.Addresses {
+			ip, _, err := net.ParseCIDR(address)
+			if err != nil {
+				return "", fmt.Errorf("invalid address %q: %w", address, err)
+			}
+			staticAddress := map[string]interface{}{"address": address}
+			// the gateway only applies to the addresses of its IP family
+			if gateway != nil && (ip.To4() == nil) == (gateway.To4() == nil) {
+				staticAddress["gateway"] = override.Gateway
+			}
+			addresses = append(addresses, staticAddress)
+		}
+		staticIPAM["addresses"] = addresses
+		ipam = staticIPAM
+	}
+
+	if len(override.Routes) > 0 {
+		routes, _ := ipam["routes"].([]interface{})
+		for _, route := range override.Routes {
+			rawRoute := map[string]interface{}{"dst": route.Dst}
+			if route.GW != "" {
+				rawRoute["gw"] = route.GW
+			}
+			routes = append(routes, rawRoute)
+		}
+		ipam["routes"] = routes
+	}
+	plugin["ipam"] = ipam
+
+	modifiedConfig, err := json.Marshal(rawConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal modified config: %w", err)
+	}
+	return string(modifiedConfig), nil
+}
+
+// bondMiimon is the default link monitoring interval of bonds in milliseconds.
+const bondMiimon = 100
+
+// BondNetConf returns the netconf of the bond CNI plugin bonding the links of the pod, the IfNames
+// of the VFs of a request, see VfConfig.Bond. The bond takes the name, CNI version and IPAM of the
+// first plugin of the netconf of a member VF, which must then be attached without IPAM, see
+// RemoveIPAMFromNetConf.
+func BondNetConf(memberConfig string, bond *configapi.BondConfig, links []string) (string, error) {
+	var rawConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(memberConfig), &rawConfig); err != nil {
+		return "", fmt.Errorf("failed to unmarshal existing config: %w", err)
+	}
+	plugins, err := netConfPlugins(rawConfig)
+	if err != nil {
+		return "", err
+	}
+	plugin, ok := plugins[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid first plugin in config list")
+	}
+
+	mode := bond.Mode
+	if mode == "" {
+		mode = "active-backup"
+	}
+	miimon := int32(bondMiimon)
+	if bond.Miimon != nil {
+		miimon = *bond.Miimon
+	}
+	rawLinks := make([]interface{}, 0, len(links))
+	for _, link := range links {
+		rawLinks = append(rawLinks, map[string]interface{}{"name": link})
+	}
+	bondConfig := map[string]interface{}{
+		"cniVersion":       rawConfig["cniVersion"],
+		"name":             rawConfig["name"],
+		"type":             "bond",
+		"mode":             mode,
+		"miimon":           strconv.Itoa(int(miimon)),
+		"linksInContainer": true,
+		"links":            rawLinks,
+	}
+	// VFs only receive the traffic of their own MAC address, the bond follows the MAC address
+	// of the active VF
+	if mode == "active-backup" {
+		bondConfig["failOverMac"] = 1
+	}
+	if ipam, ok := plugin["ipam"]; ok {
+		bondConfig["ipam"] = ipam
+	}
+
+	modifiedConfig, err := json.Marshal(bondConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal bond config: %w", err)
+	}
+	return string(modifiedConfig), nil
+}
+
+// RemoveIPAMFromNetConf removes the IPAM of the first plugin of the netconf, for the VFs of a
+// bond which carries their addresses, see BondNetConf.
+func RemoveIPAMFromNetConf(originalConfig string) (string, error) {
+	var rawConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(originalConfig), &rawConfig); err != nil {
+		return "", fmt.Errorf("failed to unmarshal existing config: %w", err)
+	}
+	plugins, err := netConfPlugins(rawConfig)
+	if err != nil {
+		return "", err
+	}
+	plugin, ok := plugins[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid first plugin in config list")
+	}
+	delete(plugin, "ipam")
+
+	modifiedConfig, err := json.Marshal(rawConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal modified config: %w", err)
+	}
+	return string(modifiedConfig), nil
+}
+
 type OpaqueDeviceConfig struct {
 	Requests []string
 	Config   runtime.Object
@@ -69,9 +349,110 @@ type PreparedDevice struct {
 	PciAddress          string
 	MultusDeviceID      string
 	MultusResourceName  string
-	PodUID              string
+	PodUID              string // Pod the claim is reserved for, empty when Shared
 	NetAttachDefConfig  string
 	OriginalDriver      string // Store original driver for restoration during unprepare
+	Shared              bool   `json:",omitempty"` // Claim is reserved by several pods, devices are not attached to pod networks
+	// CNISandboxID, CNINetConf and CNIResult record the sandbox the device was attached to, the
+	// netconf passed to CNI ADD and its result, so CNI DEL does not depend on the sandbox, the
+	// NetworkAttachmentDefinition or the CNI cache still being there.
+	// Fields added after the first checkpoint version are omitted when empty to keep the checksum
+	// of older checkpoints valid.
+	CNISandboxID string `json:",omitempty"`
+	CNINetConf   string `json:",omitempty"`
+	CNIResult    string `json:",omitempty"`
+	// OriginalVFSettings are the administrative settings of the VF before it was prepared,
+	// restored on unprepare like OriginalDriver. Nil when they could not be read. Both are kept in
+	// the checkpoint so a driver restarted between prepare and unprepare can still restore them.
+	OriginalVFSettings *host.VFSettings `json:",omitempty"`
+	// OriginalNodeGUID and OriginalPortGUID are the GUIDs of an InfiniBand VF before the GUIDs of
+	// VfConfig.InfiniBand were set, restored on unprepare. Empty when the GUIDs were not changed.
+	OriginalNodeGUID string `json:",omitempty"`
+	OriginalPortGUID string `json:",omitempty"`
+	// OriginalNumQueues is the count of combined channels of the VF before VfConfig.NumQueues was
+	// applied, restored on unprepare. 0 when the count was not changed.
+	OriginalNumQueues int `json:",omitempty"`
+	// OriginalRingSizes are the ring sizes of the VF before VfConfig.RingSize was applied,
+	// restored on unprepare. Nil when the sizes were not changed.
+	OriginalRingSizes *host.RingSizes `json:",omitempty"`
+	// OriginalFeatures are the states of the features of the VF changed by VfConfig.Offloads,
+	// restored on unprepare. Empty when no feature was changed.
+	OriginalFeatures map[string]bool `json:",omitempty"`
+	// ResourceName is the resource name attribute of the device, empty when no policy sets it.
+	ResourceName string `json:",omitempty"`
+	// PFName, VFID and BoundDriver are the parent PF interface of the VF, its ID and the driver
+	// it is bound to once prepared, reported in the claim, see StatusData.
+	PFName      string `json:",omitempty"`
+	VFID        *int64 `json:",omitempty"`
+	BoundDriver string `json:",omitempty"`
+	// RDMADevice is the RDMA device of an RDMA capable VF, moved to the network namespace of the
+	// pod when the kernel RDMA netns mode is exclusive.
+	RDMADevice string `json:",omitempty"`
+	// AdminAccess marks the devices of an admin access claim, used to inspect devices possibly
+	// allocated to other workloads: they are not rebound, reset nor attached to pod networks.
+	AdminAccess bool `json:",omitempty"`
+	// VhostUserSocketDir is the host directory of the vhost-user sockets of the pod, removed on
+	// unprepare, empty when the VfConfig does not request it.
+	VhostUserSocketDir string `json:",omitempty"`
+	// BondIfName is the interface of the pod bonding the devices of the request of the device,
+	// see VfConfig.Bond. The first device of the bond keeps its netconf in BondNetConf and
+	// records its CNI attachment in BondCNINetConf and BondCNIResult.
+	BondIfName     string `json:",omitempty"`
+	BondNetConf    string `json:",omitempty"`
+	BondCNINetConf string `json:",omitempty"`
+	BondCNIResult  string `json:",omitempty"`
+	// DPURepresentor is the representor of the VF the DPU agent programmed in the split-driver
+	// mode, released on unprepare.
+	DPURepresentor string `json:",omitempty"`
+	// TCOffloadRepresentor is the representor of the VF whose TC hardware offload was enabled,
+	// see VfConfig.TCOffload, its qdisc is deleted on unprepare.
+	TCOffloadRepresentor string `json:",omitempty"`
+	// OVSBridge and OVSPort are the bridge of Open vSwitch the representor of the VF was plugged
+	// into and its port, see VfConfig.OVSBridge, deleted on unprepare.
+	OVSBridge string `json:",omitempty"`
+	OVSPort   string `json:",omitempty"`
+}
+
+// StatusData returns the details of the VF of the device merged into the Data of its status in the
+// claim, so a pod can be correlated with the physical wiring without node access. Unknown details
+// are left out.
+func (d *PreparedDevice) StatusData() map[string]interface{} {
+	data := map[string]interface{}{}
+	if d.PFName != "" {
+		data["pfName"] = d.PFName
+	}
+	if d.VFID != nil {
+		data["vfID"] = *d.VFID
+	}
+	if d.BoundDriver != "" {
+		data["boundDriver"] = d.BoundDriver
+	}
+	return data
+}
+
+// BondDevice returns the bond kept on the device as a device attached and detached by CNI like
+// the VFs it bonds, nil when the device does not keep a bond.
+func (d *PreparedDevice) BondDevice() *PreparedDevice {
+	if d.BondNetConf == "" {
+		return nil
+	}
+	return &PreparedDevice{
+		Device:              d.Device,
+		ClaimNamespacedName: d.ClaimNamespacedName,
+		Config:              d.Config,
+		IfName:              d.BondIfName,
+		PodUID:              d.PodUID,
+		NetAttachDefConfig:  d.BondNetConf,
+		CNISandboxID:        d.CNISandboxID,
+		CNINetConf:          d.BondCNINetConf,
+		CNIResult:           d.BondCNIResult,
+	}
+}
+
+// AttachesNetwork reports whether the device is attached to the network of its pod, which is not
+// the case of the devices of shared and admin access claims.
+func (d *PreparedDevice) AttachesNetwork() bool {
+	return !d.Shared && !d.AdminAccess
 }
 
 type Checkpoint struct {
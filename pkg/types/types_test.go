@@ -6,7 +6,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 
+	configapi "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/api/virtualfunction/v1alpha1"
+	"github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/host"
 	draTypes "github.com/k8snetworkplumbingwg/dra-driver-sriov/pkg/types"
 )
 
@@ -93,6 +96,28 @@ var _ = Describe("Types", func() {
 			Expect(capabilities["ips"]).To(BeTrue())
 		})
 
+		It("should add deviceID to the first plugin of a plugin list", func() {
+			originalConfig := `{"cniVersion": "1.0.0", "name": "mynet", "plugins": [{"type": "sriov"}, {"type": "tuning"}]}`
+			deviceID := "0000:01:00.0"
+
+			result, err := draTypes.AddDeviceIDToNetConf(originalConfig, deviceID)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			err = json.Unmarshal([]byte(result), &config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).NotTo(HaveKey("deviceID"))
+			plugins := config["plugins"].([]interface{})
+			Expect(plugins).To(HaveLen(2))
+			Expect(plugins[0]).To(HaveKeyWithValue("deviceID", deviceID))
+			Expect(plugins[1]).NotTo(HaveKey("deviceID"))
+		})
+
+		It("should return error for a plugin list without plugins", func() {
+			_, err := draTypes.AddDeviceIDToNetConf(`{"name": "mynet", "plugins": []}`, "0000:01:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+
 		It("should return error for invalid JSON", func() {
 			originalConfig := `invalid json`
 			deviceID := "0000:01:00.0"
@@ -117,6 +142,209 @@ var _ = Describe("Types", func() {
 		})
 	})
 
+	Context("ValidateNetConf", func() {
+		It("should accept single plugin and plugin list configs", func() {
+			Expect(draTypes.ValidateNetConf(`{"cniVersion": "1.0.0", "type": "sriov"}`)).To(Succeed())
+			Expect(draTypes.ValidateNetConf(`{"cniVersion": "1.0.0", "plugins": [{"type": "sriov"}, {"type": "tuning"}]}`)).To(Succeed())
+		})
+
+		It("should reject invalid configs", func() {
+			Expect(draTypes.ValidateNetConf(`invalid json`)).NotTo(Succeed())
+			Expect(draTypes.ValidateNetConf(`{"cniVersion": "1.0.0"}`)).NotTo(Succeed())
+			Expect(draTypes.ValidateNetConf(`{"plugins": []}`)).NotTo(Succeed())
+			Expect(draTypes.ValidateNetConf(`{"plugins": [{"type": "sriov"}, {"mtu": 9000}]}`)).To(MatchError("no type set for plugin 1"))
+		})
+	})
+
+	Context("ChainNetConfs", func() {
+		It("should return the primary config when nothing is chained", func() {
+			primary := `{"type": "sriov", "name": "mynet"}`
+			result, err := draTypes.ChainNetConfs(primary)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(primary))
+		})
+
+		It("should append the plugins of single and list configs in order", func() {
+			primary := `{"cniVersion": "1.0.0", "name": "mynet", "type": "sriov"}`
+			routes := `{"cniVersion": "1.0.0", "name": "routes", "type": "route-override"}`
+			tuning := `{"cniVersion": "1.0.0", "name": "tuning", "plugins": [{"type": "tuning"}, {"type": "sbr"}]}`
+
+			result, err := draTypes.ChainNetConfs(primary, routes, tuning)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			Expect(config["name"]).To(Equal("mynet"))
+			Expect(config["cniVersion"]).To(Equal("1.0.0"))
+			plugins := config["plugins"].([]interface{})
+			Expect(plugins).To(HaveLen(4))
+			types := make([]interface{}, 0, len(plugins))
+			for _, plugin := range plugins {
+				types = append(types, plugin.(map[string]interface{})["type"])
+			}
+			Expect(types).To(Equal([]interface{}{"sriov", "route-override", "tuning", "sbr"}))
+		})
+
+		It("should return error for an invalid chained config", func() {
+			_, err := draTypes.ChainNetConfs(`{"type": "sriov"}`, `invalid json`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("chained config 0"))
+		})
+	})
+
+	Context("AddSwitchdevOffloadToNetConf", func() {
+		It("should add the deviceID to the offloading plugins of a plugin list", func() {
+			original := `{"cniVersion": "1.0.0", "name": "mynet", "plugins": [{"type": "sriov"}, {"type": "ovs", "bridge": "br-ex"}, {"type": "tuning"}]}`
+
+			result, err := draTypes.AddSwitchdevOffloadToNetConf(original, "0000:01:00.2")
+			Expect(err).NotTo(HaveOccurred())
+
+			var config struct {
+				Plugins []map[string]interface{} `json:"plugins"`
+			}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			Expect(config.Plugins[0]).NotTo(HaveKey("deviceID"))
+			Expect(config.Plugins[1]).To(HaveKeyWithValue("deviceID", "0000:01:00.2"))
+			Expect(config.Plugins[1]).To(HaveKeyWithValue("bridge", "br-ex"))
+			Expect(config.Plugins[2]).NotTo(HaveKey("deviceID"))
+		})
+
+		It("should add the deviceID to a single offloading plugin", func() {
+			result, err := draTypes.AddSwitchdevOffloadToNetConf(`{"type": "accelerated-bridge", "name": "mynet"}`, "0000:01:00.2")
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			Expect(config).To(HaveKeyWithValue("deviceID", "0000:01:00.2"))
+		})
+
+		It("should return error for invalid JSON", func() {
+			_, err := draTypes.AddSwitchdevOffloadToNetConf(`invalid json`, "0000:01:00.2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("MergeIPAMIntoNetConf", func() {
+		It("should return the config unchanged without override", func() {
+			original := `{"type": "sriov", "ipam": {"type": "host-local"}}`
+			result, err := draTypes.MergeIPAMIntoNetConf(original, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(original))
+		})
+
+		It("should switch to static IPAM with the gateway of the matching family", func() {
+			original := `{"type": "sriov", "ipam": {"type": "host-local", "subnet": "10.0.0.0/24", "routes": [{"dst": "0.0.0.0/0"}]}}`
+			override := &configapi.IPAMOverride{
+				Addresses: []string{"10.0.0.5/24", "fd00::5/64"},
+				Gateway:   "10.0.0.1",
+				Routes:    []configapi.IPAMRoute{{Dst: "192.168.0.0/16", GW: "10.0.0.254"}},
+			}
+			result, err := draTypes.MergeIPAMIntoNetConf(original, override)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			ipam := config["ipam"].(map[string]interface{})
+			Expect(ipam["type"]).To(Equal("static"))
+			Expect(ipam).NotTo(HaveKey("subnet"))
+			Expect(ipam["addresses"]).To(Equal([]interface{}{
+				map[string]interface{}{"address": "10.0.0.5/24", "gateway": "10.0.0.1"},
+				map[string]interface{}{"address": "fd00::5/64"},
+			}))
+			Expect(ipam["routes"]).To(Equal([]interface{}{
+				map[string]interface{}{"dst": "0.0.0.0/0"},
+				map[string]interface{}{"dst": "192.168.0.0/16", "gw": "10.0.0.254"},
+			}))
+		})
+
+		It("should only add routes to the IPAM of the first plugin of a list", func() {
+			original := `{"plugins": [{"type": "sriov", "ipam": {"type": "whereabouts"}}, {"type": "tuning"}]}`
+			override := &configapi.IPAMOverride{Routes: []configapi.IPAMRoute{{Dst: "192.168.0.0/16"}}}
+			result, err := draTypes.MergeIPAMIntoNetConf(original, override)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			plugins := config["plugins"].([]interface{})
+			ipam := plugins[0].(map[string]interface{})["ipam"].(map[string]interface{})
+			Expect(ipam["type"]).To(Equal("whereabouts"))
+			Expect(ipam["routes"]).To(Equal([]interface{}{map[string]interface{}{"dst": "192.168.0.0/16"}}))
+			Expect(plugins[1]).NotTo(HaveKey("ipam"))
+		})
+	})
+
+	Context("BondNetConf", func() {
+		It("should bond the links with the IPAM of the first plugin", func() {
+			member := `{"cniVersion": "1.0.0", "name": "sriov-net", "plugins": [{"type": "sriov", "ipam": {"type": "whereabouts"}}, {"type": "tuning"}]}`
+			result, err := draTypes.BondNetConf(member, &configapi.BondConfig{}, []string{"net1", "net2"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			Expect(config).To(And(
+				HaveKeyWithValue("cniVersion", "1.0.0"),
+				HaveKeyWithValue("name", "sriov-net"),
+				HaveKeyWithValue("type", "bond"),
+				HaveKeyWithValue("mode", "active-backup"),
+				HaveKeyWithValue("miimon", "100"),
+				HaveKeyWithValue("failOverMac", float64(1)),
+				HaveKeyWithValue("linksInContainer", true),
+			))
+			Expect(config["links"]).To(Equal([]interface{}{
+				map[string]interface{}{"name": "net1"},
+				map[string]interface{}{"name": "net2"},
+			}))
+			Expect(config["ipam"]).To(Equal(map[string]interface{}{"type": "whereabouts"}))
+		})
+
+		It("should use the mode and miimon of the bond config", func() {
+			member := `{"cniVersion": "0.4.0", "name": "sriov-net", "type": "sriov"}`
+			miimon := int32(50)
+			result, err := draTypes.BondNetConf(member, &configapi.BondConfig{Mode: "802.3ad", Miimon: &miimon}, []string{"net1", "net2"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			Expect(config).To(HaveKeyWithValue("mode", "802.3ad"))
+			Expect(config).To(HaveKeyWithValue("miimon", "50"))
+			Expect(config).NotTo(HaveKey("failOverMac"))
+			Expect(config).NotTo(HaveKey("ipam"))
+		})
+
+		It("should remove the IPAM of the first plugin only", func() {
+			member := `{"plugins": [{"type": "sriov", "ipam": {"type": "whereabouts"}}, {"type": "tuning", "ipam": {}}]}`
+			result, err := draTypes.RemoveIPAMFromNetConf(member)
+			Expect(err).NotTo(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal([]byte(result), &config)).To(Succeed())
+			plugins := config["plugins"].([]interface{})
+			Expect(plugins[0]).NotTo(HaveKey("ipam"))
+			Expect(plugins[1]).To(HaveKey("ipam"))
+		})
+
+		It("should describe the bond kept on a device as a device", func() {
+			device := &draTypes.PreparedDevice{IfName: "net1", BondIfName: "net3", PodUID: "pod", CNISandboxID: "sandbox"}
+			Expect(device.BondDevice()).To(BeNil())
+
+			device.BondNetConf = `{"type": "bond"}`
+			device.BondCNIResult = `{"cniVersion": "1.0.0"}`
+			bond := device.BondDevice()
+			Expect(bond.IfName).To(Equal("net3"))
+			Expect(bond.NetAttachDefConfig).To(Equal(`{"type": "bond"}`))
+			Expect(bond.CNISandboxID).To(Equal("sandbox"))
+			Expect(bond.CNIResult).To(Equal(`{"cniVersion": "1.0.0"}`))
+		})
+
+		It("should report the wiring of the VF of a device in its status data", func() {
+			device := &draTypes.PreparedDevice{PFName: "eth0", VFID: ptr.To(int64(0)), BoundDriver: "iavf"}
+			Expect(device.StatusData()).To(Equal(map[string]interface{}{"pfName": "eth0", "vfID": int64(0), "boundDriver": "iavf"}))
+
+			// details the driver could not read are left out
+			Expect((&draTypes.PreparedDevice{PFName: "eth0"}).StatusData()).To(Equal(map[string]interface{}{"pfName": "eth0"}))
+		})
+	})
+
 	Context("Checkpoint operations", func() {
 		var checkpoint *draTypes.Checkpoint
 
@@ -155,6 +383,48 @@ var _ = Describe("Types", func() {
 			Expect(newCheckpoint.V1.PreparedClaimsByPodUID[podUID]).To(HaveKey(claimUID))
 		})
 
+		It("should preserve the original device state across marshal/unmarshal", func() {
+			podUID := types.UID("test-pod-uid")
+			claimUID := types.UID("test-claim-uid")
+
+			checkpoint.V1.PreparedClaimsByPodUID[podUID] = draTypes.PreparedDevicesByClaimID{
+				claimUID: draTypes.PreparedDevices{
+					{PciAddress: "0000:01:00.1", OriginalDriver: "iavf", OriginalVFSettings: &host.VFSettings{MAC: "02:00:00:00:00:01", VLAN: 100, SpoofChk: true}},
+					// settings all at their defaults still have to be restored
+					{PciAddress: "0000:01:00.2", OriginalDriver: "iavf", OriginalVFSettings: &host.VFSettings{}},
+				},
+			}
+
+			data, err := checkpoint.MarshalCheckpoint()
+			Expect(err).NotTo(HaveOccurred())
+
+			newCheckpoint := &draTypes.Checkpoint{}
+			Expect(newCheckpoint.UnmarshalCheckpoint(data)).To(Succeed())
+			Expect(newCheckpoint.VerifyChecksum()).To(Succeed())
+			devices := newCheckpoint.V1.PreparedClaimsByPodUID[podUID][claimUID]
+			Expect(devices).To(HaveLen(2))
+			Expect(devices[0].OriginalDriver).To(Equal("iavf"))
+			Expect(devices[0].OriginalVFSettings).To(Equal(&host.VFSettings{MAC: "02:00:00:00:00:01", VLAN: 100, SpoofChk: true}))
+			Expect(devices[1].OriginalVFSettings).To(Equal(&host.VFSettings{}))
+		})
+
+		It("should verify the checksum of checkpoints written without the original VF settings", func() {
+			podUID := types.UID("test-pod-uid")
+			claimUID := types.UID("test-claim-uid")
+
+			checkpoint.V1.PreparedClaimsByPodUID[podUID] = draTypes.PreparedDevicesByClaimID{
+				claimUID: draTypes.PreparedDevices{{PciAddress: "0000:01:00.1", OriginalDriver: "iavf"}},
+			}
+			data, err := checkpoint.MarshalCheckpoint()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("OriginalVFSettings"))
+
+			newCheckpoint := &draTypes.Checkpoint{}
+			Expect(newCheckpoint.UnmarshalCheckpoint(data)).To(Succeed())
+			Expect(newCheckpoint.VerifyChecksum()).To(Succeed())
+			Expect(newCheckpoint.V1.PreparedClaimsByPodUID[podUID][claimUID][0].OriginalVFSettings).To(BeNil())
+		})
+
 		It("should verify checksum correctly", func() {
 			// Add some test data
 			podUID := types.UID("test-pod-uid")